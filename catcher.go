@@ -0,0 +1,577 @@
+// Package catcher embeds catcher's durable URL-processing queue in other Go
+// programs. It wires the SQLite repository, job service, processor registry,
+// and worker without the HTTP server.
+package catcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/adapter/connectivity"
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/adapter/redirect"
+	"github.com/cwygoda/catcher/internal/adapter/sqlite"
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/worker"
+)
+
+// Job represents a URL processing job.
+type Job = domain.Job
+
+// JobStatus represents the processing state of a job.
+type JobStatus = domain.JobStatus
+
+// URLProcessor handles jobs whose URL it matches.
+type URLProcessor = domain.URLProcessor
+
+// ProcessResult is what URLProcessor.Process reports about a run.
+type ProcessResult = domain.ProcessResult
+
+// ResultFile is a file a job produced.
+type ResultFile = domain.ResultFile
+
+// MatchScorer is implemented by processors that rate how well they match a
+// URL; the registry picks the highest score.
+type MatchScorer = domain.MatchScorer
+
+// Prioritizer is implemented by processors that break ties between equal
+// match scores; the higher priority wins.
+type Prioritizer = domain.Prioritizer
+
+// Coster is implemented by processors whose jobs take more than 1 of
+// Options.Budget while running.
+type Coster = domain.Coster
+
+// ResubmitPolicy says what to do with a URL whose earlier job completed.
+// Processors choose one by implementing ResubmitPolicer; without it,
+// resubmissions are allowed.
+type ResubmitPolicy = domain.ResubmitPolicy
+
+// ResubmitPolicer is implemented by processors with a resubmit policy.
+type ResubmitPolicer = domain.ResubmitPolicer
+
+const (
+	ResubmitAllow   = domain.ResubmitAllow
+	ResubmitReject  = domain.ResubmitReject
+	ResubmitReplace = domain.ResubmitReplace
+)
+
+// OversizePolicy says what to do with a job estimated to be larger than its
+// processor allows.
+type OversizePolicy = domain.OversizePolicy
+
+// SizeProber is implemented by processors that estimate a job's size before
+// processing it. Oversized jobs are sent for approval or failed, as its
+// SizeLimit says.
+type SizeProber = domain.SizeProber
+
+// LibraryChecker is implemented by processors that can find a job's media
+// already in a library. Such jobs complete with the library's files
+// without being processed.
+type LibraryChecker = domain.LibraryChecker
+
+// BookmarkSaver is implemented by processors that save bookmark jobs
+// instead of downloading them. Bookmarks of URLs whose processor isn't
+// one are rejected with ErrBookmarkUnsupported.
+type BookmarkSaver = domain.BookmarkSaver
+
+// MediaVerifier checks a job's files before it completes; see
+// Options.Verifier.
+type MediaVerifier = domain.MediaVerifier
+
+// TooLargeError describes a job estimated to exceed its processor's size
+// limit; its message becomes the job's error.
+type TooLargeError = domain.TooLargeError
+
+// QuotaError describes a run stopped for filling its work dir past a
+// quota. Processors return it to fail the job without further attempts.
+type QuotaError = domain.QuotaError
+
+const (
+	OversizeHold = domain.OversizeHold
+	OversizeFail = domain.OversizeFail
+)
+
+// LegacyProcessor is the earlier processor contract, whose Process returned
+// only an error. Register one with AdaptLegacy.
+type LegacyProcessor = domain.LegacyProcessor
+
+// AdaptLegacy makes a LegacyProcessor usable as a URLProcessor, reporting
+// the job.Bytes and job.Files it sets as the result.
+func AdaptLegacy(p LegacyProcessor) URLProcessor {
+	return domain.AdaptLegacy(p)
+}
+
+// URLValidator checks a submitted URL before a job is created.
+type URLValidator = domain.URLValidator
+
+// URLRewriter rewrites a submitted URL before validation and processor
+// matching. The job keeps the submitted URL as OriginalURL.
+type URLRewriter = domain.URLRewriter
+
+// ValidationError reports a URL rejected by a validator.
+type ValidationError = domain.ValidationError
+
+// DuplicateError reports a URL submitted again within Options.DedupeWindow,
+// or already completed when its processor uses ResubmitReject.
+type DuplicateError = domain.DuplicateError
+
+// Attempt records one processor run. Processors can fill in the one
+// returned by AttemptFrom to make it part of the job's history.
+type Attempt = domain.Attempt
+
+// AttemptFrom returns the attempt being recorded for the current Process call.
+func AttemptFrom(ctx context.Context) *Attempt {
+	return domain.AttemptFrom(ctx)
+}
+
+// WorkDirFrom returns the job's working directory for the current Process
+// call, or "" if Options.WorkDir is unset. It survives failed attempts and
+// is removed once the job is done.
+func WorkDirFrom(ctx context.Context) string {
+	return domain.WorkDirFrom(ctx)
+}
+
+// Beat records progress of the job being processed in ctx, holding off
+// the watchdog Options.StallTimeout enables.
+func Beat(ctx context.Context) {
+	domain.HeartbeatFrom(ctx).Beat()
+}
+
+// CompletedFilter selects completed jobs by when they completed and their
+// host.
+type CompletedFilter = domain.CompletedFilter
+
+// FailureReport counts recent failures by cause, most frequent first.
+type FailureReport = domain.FailureReport
+
+// FailureCount sums the failed attempts and jobs of one cause.
+type FailureCount = domain.FailureCount
+
+// FailureCause buckets a failure message: network, extractor, auth, disk,
+// timeout, or unknown.
+type FailureCause = domain.FailureCause
+
+// FileCheck is the outcome of checking recently completed jobs' files
+// against the disk.
+type FileCheck = domain.FileCheck
+
+// FileIssue is a recorded file found missing or with another size.
+type FileIssue = domain.FileIssue
+
+// LibraryFile is a file a job delivered, as kept in the library index.
+type LibraryFile = domain.LibraryFile
+
+// LibraryQuery selects library files; the zero value selects all.
+type LibraryQuery = domain.LibraryQuery
+
+// LibraryRescan is the outcome of checking the library index against the
+// disk.
+type LibraryRescan = domain.LibraryRescan
+
+// BulkFilter selects the jobs a bulk operation applies to. The zero value
+// selects every job.
+type BulkFilter = domain.BulkFilter
+
+// SchedulerState is what the worker is doing at one moment, as returned by
+// Catcher.Scheduler.
+type SchedulerState = domain.SchedulerState
+
+// RunningJob is a job the worker is running.
+type RunningJob = domain.RunningJob
+
+// Cooldown is a host the worker postpones jobs for after it rate limited
+// them.
+type Cooldown = domain.Cooldown
+
+// BudgetState is how much of Options.Budget running jobs take.
+type BudgetState = domain.BudgetState
+
+// OutageState is a network outage the worker waits out, as Options.Outage
+// detects.
+type OutageState = domain.OutageState
+
+// OutagePolicy says when the network counts as down, holding new jobs
+// until a probe gets through, and how fast the jobs that failed meanwhile
+// are requeued after.
+type OutagePolicy = worker.OutagePolicy
+
+// Clock tells the time to time-based logic such as retry delays, host
+// cooldowns, stale recovery, and polling. See Options.Clock.
+type Clock = domain.Clock
+
+// Ticker delivers a Clock's ticks like a time.Ticker.
+type Ticker = domain.Ticker
+
+// ManualClock is a Clock that only moves when advanced, for tests and
+// simulations.
+type ManualClock = domain.ManualClock
+
+// NewManualClock returns a ManualClock stopped at now.
+func NewManualClock(now time.Time) *ManualClock {
+	return domain.NewManualClock(now)
+}
+
+// WithSource returns a context submitting jobs on behalf of source, such as
+// a user or device. Pending jobs are taken in turn from each source.
+func WithSource(ctx context.Context, source string) context.Context {
+	return domain.WithSource(ctx, source)
+}
+
+// WithNotes returns a context submitting jobs with notes, free text such as
+// why the job was queued, of up to MaxNotesLength characters.
+func WithNotes(ctx context.Context, notes string) context.Context {
+	return domain.WithNotes(ctx, notes)
+}
+
+// WithBookmark returns a context submitting jobs as bookmarks: instead of
+// downloading, processors save a .strm file pointing at the URL, with an
+// .nfo, for a media server such as Jellyfin to stream on demand.
+func WithBookmark(ctx context.Context) context.Context {
+	return domain.WithBookmark(ctx)
+}
+
+// WithUserAgent returns a context submitting jobs whose processors send ua
+// as the User-Agent instead of their default.
+func WithUserAgent(ctx context.Context, ua string) context.Context {
+	return domain.WithUserAgent(ctx, ua)
+}
+
+// MaxNotesLength is the most characters a job's notes may have.
+const MaxNotesLength = domain.MaxNotesLength
+
+const (
+	StatusNeedsApproval = domain.StatusNeedsApproval
+	StatusPending       = domain.StatusPending
+	StatusProcessing    = domain.StatusProcessing
+	StatusCompleted     = domain.StatusCompleted
+	StatusFailed        = domain.StatusFailed
+)
+
+var (
+	ErrInvalidURL  = domain.ErrInvalidURL
+	ErrJobNotFound = domain.ErrJobNotFound
+	// ErrJobState reports an action the job's status doesn't allow, such as
+	// holding a job that isn't pending.
+	ErrJobState = domain.ErrJobState
+	// ErrNotesTooLong reports notes over MaxNotesLength characters.
+	ErrNotesTooLong = domain.ErrNotesTooLong
+	// ErrInvalidUserAgent reports a user agent given to WithUserAgent that
+	// is too long, has control characters, or starts with "-".
+	ErrInvalidUserAgent = domain.ErrInvalidUserAgent
+	// ErrBookmarkUnsupported reports a bookmark of a URL whose processor
+	// isn't a BookmarkSaver.
+	ErrBookmarkUnsupported = domain.ErrBookmarkUnsupported
+	// ErrNoFileCheck reports that no file check has run yet.
+	ErrNoFileCheck = domain.ErrNoFileCheck
+	// ErrBadMedia is wrapped by MediaVerifier errors for broken files.
+	ErrBadMedia = domain.ErrBadMedia
+	// ErrKeyRequired and ErrWrongKey report an encrypted database opened
+	// without its DBKey.
+	ErrKeyRequired = sqlite.ErrKeyRequired
+	ErrWrongKey    = sqlite.ErrWrongKey
+)
+
+// Options configures an embedded Catcher.
+type Options struct {
+	// DBPath is the SQLite database path. Required.
+	DBPath string
+	// PollInterval is how often the worker checks for pending jobs. Defaults to 5s.
+	PollInterval time.Duration
+	// MaxRetries is the maximum number of attempts per job. Defaults to 3.
+	MaxRetries int
+	// Rewriters run on every submission, in order, before Validators.
+	Rewriters []URLRewriter
+	// RedirectHosts makes submissions for these hosts and their subdomains
+	// follow HTTP redirects, after Rewriters, so shortened URLs are matched
+	// by their destination. "*" matches every host.
+	RedirectHosts []string
+	// Validators run on every submission, in order.
+	Validators []URLValidator
+	// DedupeWindow rejects a URL submitted again this soon after an earlier
+	// job for it that hasn't failed, with a *DuplicateError. Zero disables it.
+	DedupeWindow time.Duration
+	// DBKey encrypts job URLs, errors, and attempt details in the database.
+	// Other columns, such as statuses and timestamps, stay plaintext. Once
+	// set, the same key is required to open the database.
+	DBKey []byte
+	// ApprovalHosts makes submissions for these hosts and their subdomains
+	// wait in StatusNeedsApproval until Approve or Reject. "*" matches every
+	// host.
+	ApprovalHosts []string
+	// Budget caps the total cost of the jobs running at once, where a job
+	// costs what its processor's Cost method returns, or 1. Zero disables it.
+	Budget int
+	// StallTimeout warns about a running job that hasn't called Beat for
+	// this long, and after twice as long cancels its context and retries
+	// it. Zero disables it.
+	StallTimeout time.Duration
+	// VerifyFiles makes Run check that the files of jobs completed within
+	// this long are still on disk, for LastFileCheck. Zero skips it.
+	VerifyFiles time.Duration
+	// Checksums records a SHA-256 digest of each result file in its
+	// ResultFile and writes it beside the file as <name>.sha256.
+	Checksums bool
+	// Provenance records the source URL, job ID, and download date on each
+	// result file, as extended attributes or a hidden sidecar.
+	Provenance bool
+	// Verifier checks each file of a successful run before the job
+	// completes. Files it finds broken, with an error wrapping ErrBadMedia,
+	// are removed and the job is retried.
+	Verifier MediaVerifier
+	// Outage holds new jobs while the network seems down and requeues
+	// those that failed meanwhile once it is back. Outage.After of zero
+	// disables it.
+	Outage OutagePolicy
+	// ConnectivityURL is sent a HEAD request every 30 seconds while Run
+	// runs. While none gets a response, the worker starts no jobs, and
+	// jobs failing with network errors or timeouts are retried once it
+	// is back without using up an attempt. Empty disables the check.
+	ConnectivityURL string
+	// WorkDir holds a working directory per job, kept across retries and
+	// available to processors via WorkDirFrom. If empty, processors manage
+	// their own scratch space.
+	WorkDir string
+	// Clock drives polling, retry delays, host cooldowns, and the
+	// timestamps stored with jobs, so tests can advance time with a
+	// ManualClock instead of sleeping. Defaults to the system clock.
+	Clock Clock
+}
+
+// Catcher is an embedded job queue with its own worker.
+type Catcher struct {
+	repo     *sqlite.Repository
+	svc      *domain.JobService
+	registry *processor.Registry
+	worker   *worker.Worker
+	verify   time.Duration
+	uplink   *connectivity.Monitor // nil without Options.ConnectivityURL
+	clock    domain.Clock
+}
+
+// New opens the database and prepares the queue. Register processors, then
+// call Run to start processing.
+func New(opts Options) (*Catcher, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.Clock == nil {
+		opts.Clock = domain.SystemClock
+	}
+
+	repo, err := sqlite.New(opts.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.Unlock(context.Background(), opts.DBKey); err != nil {
+		repo.Close()
+		return nil, err
+	}
+	repo.SetClock(opts.Clock)
+
+	svc := domain.NewJobService(repo)
+	svc.SetClock(opts.Clock)
+	svc.SetAttemptRepository(repo)
+	svc.SetRetryScheduler(repo)
+	for _, r := range opts.Rewriters {
+		svc.AddRewriter(r)
+	}
+	if len(opts.RedirectHosts) > 0 {
+		svc.SetRedirectResolver(redirect.New(5, 5*time.Second).Resolve, domain.MatchHosts(opts.RedirectHosts...))
+	}
+	for _, v := range opts.Validators {
+		svc.AddValidator(v)
+	}
+	svc.SetDedupeWindow(repo, opts.DedupeWindow)
+	svc.SetJobHolder(repo)
+	svc.SetUIDResolver(repo)
+	svc.SetBulkRepository(repo)
+	svc.SetPostponer(repo)
+	svc.SetNoteEditor(repo)
+	svc.SetCompletedLister(repo)
+	svc.SetFailureLister(repo)
+	svc.SetCooldownRepository(repo)
+	svc.SetOutageRepository(repo)
+	svc.SetFileCheckRepository(repo)
+	svc.SetLibraryIndex(repo)
+	svc.SetRedownloadFinder(repo)
+	svc.SetApproval(repo, domain.MatchHosts(opts.ApprovalHosts...))
+	registry := processor.NewRegistry()
+	svc.SetResubmitPolicy(repo, registry.ResubmitPolicy)
+	svc.SetBookmarkSupport(registry.SavesBookmarks)
+	svc.SetQueues(repo, registry.Queue)
+	svc.SetProcessorMatcher(registry.ProcessorName)
+	w := worker.New(svc, registry, opts.PollInterval, opts.MaxRetries)
+	w.SetWorkDir(opts.WorkDir)
+	w.SetBudget(opts.Budget)
+	w.SetStallTimeout(opts.StallTimeout)
+	w.SetChecksums(opts.Checksums)
+	w.SetProvenance(opts.Provenance)
+	w.SetOutagePolicy(opts.Outage)
+	if opts.Verifier != nil {
+		w.SetVerifier(opts.Verifier)
+	}
+	w.SetClock(opts.Clock)
+	var uplink *connectivity.Monitor
+	if opts.ConnectivityURL != "" {
+		uplink = connectivity.New(opts.ConnectivityURL, 0, 0)
+		uplink.SetClock(opts.Clock)
+		w.SetConnectivity(uplink)
+	}
+
+	return &Catcher{
+		repo:     repo,
+		svc:      svc,
+		registry: registry,
+		worker:   w,
+		verify:   opts.VerifyFiles,
+		uplink:   uplink,
+		clock:    opts.Clock,
+	}, nil
+}
+
+// Register adds a processor. Of the processors matching a URL, the one with
+// the highest MatchScore handles the job, then the highest Priority, then the
+// first registered. Register all processors before calling Run.
+func (c *Catcher) Register(p URLProcessor) {
+	c.registry.Register(p)
+}
+
+// Submit queues a URL for processing.
+func (c *Catcher) Submit(ctx context.Context, url string) (*Job, error) {
+	return c.svc.Submit(ctx, url)
+}
+
+// SubmitHeld queues a URL held back from processing until Release.
+func (c *Catcher) SubmitHeld(ctx context.Context, url string) (*Job, error) {
+	return c.svc.SubmitHeld(ctx, url)
+}
+
+// Hold keeps a pending job from being processed until it is released.
+func (c *Catcher) Hold(ctx context.Context, id int64) (*Job, error) {
+	return c.svc.Hold(ctx, id)
+}
+
+// Release lets a held job be processed.
+func (c *Catcher) Release(ctx context.Context, id int64) (*Job, error) {
+	return c.svc.Release(ctx, id)
+}
+
+// Approve queues a job awaiting approval for processing.
+func (c *Catcher) Approve(ctx context.Context, id int64) (*Job, error) {
+	return c.svc.Approve(ctx, id)
+}
+
+// Reject fails a job awaiting approval without processing it.
+func (c *Catcher) Reject(ctx context.Context, id int64, reason string) (*Job, error) {
+	return c.svc.Reject(ctx, id, reason)
+}
+
+// RequeueFailed queues the failed jobs f selects for a fresh set of
+// attempts and returns how many there were.
+func (c *Catcher) RequeueFailed(ctx context.Context, f BulkFilter) (int64, error) {
+	return c.svc.RequeueFailed(ctx, f)
+}
+
+// CancelPending fails the pending jobs f selects, held ones included, and
+// returns how many there were.
+func (c *Catcher) CancelPending(ctx context.Context, f BulkFilter) (int64, error) {
+	return c.svc.CancelPending(ctx, f)
+}
+
+// Redownload queues a completed job again as a new job linked to it by
+// RedownloadOf, e.g. to restore files deleted since.
+func (c *Catcher) Redownload(ctx context.Context, id int64) (*Job, error) {
+	return c.svc.Redownload(ctx, id)
+}
+
+// RedownloadMissing runs Redownload for every job the last file check
+// found files missing from, and returns the new jobs.
+func (c *Catcher) RedownloadMissing(ctx context.Context) ([]*Job, error) {
+	return c.svc.RedownloadMissing(ctx)
+}
+
+// SetNotes replaces a job's notes. Empty notes clear them.
+func (c *Catcher) SetNotes(ctx context.Context, id int64, notes string) (*Job, error) {
+	return c.svc.SetNotes(ctx, id, notes)
+}
+
+// Get retrieves a job by ID.
+func (c *Catcher) Get(ctx context.Context, id int64) (*Job, error) {
+	return c.svc.Get(ctx, id)
+}
+
+// Completed returns the completed jobs f selects, with the files they
+// produced, in the order they completed.
+func (c *Catcher) Completed(ctx context.Context, f CompletedFilter) ([]Job, error) {
+	return c.svc.Completed(ctx, f)
+}
+
+// FailureReport classifies the failed attempts and jobs since since by
+// cause, so the most common problems can be fixed first.
+func (c *Catcher) FailureReport(ctx context.Context, since time.Time) (*FailureReport, error) {
+	return c.svc.FailureReport(ctx, since)
+}
+
+// LastFileCheck returns the latest check run for Options.VerifyFiles, or
+// ErrNoFileCheck if none has run.
+func (c *Catcher) LastFileCheck(ctx context.Context) (*FileCheck, error) {
+	return c.svc.LastFileCheck(ctx)
+}
+
+// SearchLibrary returns the delivered files q selects, newest first,
+// including those of jobs since pruned.
+func (c *Catcher) SearchLibrary(ctx context.Context, q LibraryQuery) ([]LibraryFile, error) {
+	return c.svc.SearchLibrary(ctx, q)
+}
+
+// RescanLibrary checks every delivered file against the disk, recording
+// which are missing; with hash, it also recomputes their digests.
+func (c *Catcher) RescanLibrary(ctx context.Context, hash bool) (*LibraryRescan, error) {
+	return worker.RescanLibrary(ctx, c.svc, c.clock, hash)
+}
+
+// Attempts returns a job's processing history, oldest first.
+func (c *Catcher) Attempts(ctx context.Context, id int64) ([]Attempt, error) {
+	return c.svc.Attempts(ctx, id)
+}
+
+// Run recovers jobs left in processing by a previous crash and removes work
+// directories of finished jobs, then processes jobs until ctx is cancelled
+// or Shutdown is called.
+func (c *Catcher) Run(ctx context.Context) error {
+	if _, err := c.svc.RecoverStale(ctx); err != nil {
+		return err
+	}
+	if _, err := c.worker.CleanWorkDirs(ctx); err != nil {
+		return err
+	}
+	if c.verify > 0 {
+		go c.worker.VerifyFilesAtStart(ctx, c.verify)
+	}
+	if c.uplink != nil {
+		go c.uplink.Run(ctx)
+	}
+	c.worker.Run(ctx)
+	return nil
+}
+
+// Scheduler returns what the worker is doing right now: its running jobs,
+// when it polls next, the hosts cooling down, and the budget.
+func (c *Catcher) Scheduler() SchedulerState {
+	return c.worker.State()
+}
+
+// Shutdown stops polling and waits for in-flight jobs to finish. If ctx
+// expires first, in-flight jobs are cancelled.
+func (c *Catcher) Shutdown(ctx context.Context) error {
+	return c.worker.Shutdown(ctx)
+}
+
+// Close closes the database. Call after Run has returned.
+func (c *Catcher) Close() error {
+	return c.repo.Close()
+}
@@ -0,0 +1,135 @@
+package catcher
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordingProcessor is a native Go processor that records handled URLs.
+// It keeps the legacy contract to cover AdaptLegacy.
+type recordingProcessor struct {
+	done chan string
+}
+
+func (p *recordingProcessor) Name() string          { return "recording" }
+func (p *recordingProcessor) TargetDir() string     { return "" }
+func (p *recordingProcessor) Match(url string) bool { return true }
+func (p *recordingProcessor) Process(ctx context.Context, job *Job) error {
+	AttemptFrom(ctx).Command = "record " + job.URL
+	p.done <- job.URL
+	return nil
+}
+
+func TestCatcher_EndToEnd(t *testing.T) {
+	c, err := New(Options{
+		DBPath:       filepath.Join(t.TempDir(), "jobs.db"),
+		PollInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer c.Close()
+
+	proc := &recordingProcessor{done: make(chan string, 1)}
+	c.Register(AdaptLegacy(proc))
+
+	ctx := context.Background()
+	job, err := c.Submit(ctx, "https://example.com/video")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- c.Run(ctx) }()
+
+	select {
+	case url := <-proc.done:
+		if url != job.URL {
+			t.Errorf("processed URL = %q, want %q", url, job.URL)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not processed")
+	}
+
+	if err := c.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+
+	attempts, err := c.Attempts(ctx, job.ID)
+	if err != nil || len(attempts) != 1 || attempts[0].Command != "record "+job.URL {
+		t.Errorf("Attempts() = %+v, %v; want one attempt with the processor's command", attempts, err)
+	}
+
+	got, err := c.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Status != StatusCompleted {
+		t.Errorf("status = %q, want %q", got.Status, StatusCompleted)
+	}
+}
+
+func TestCatcher_SubmitInvalidURL(t *testing.T) {
+	c, err := New(Options{DBPath: filepath.Join(t.TempDir(), "jobs.db")})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Submit(context.Background(), "not a url"); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("Submit() error = %v, want %v", err, ErrInvalidURL)
+	}
+}
+
+func TestCatcher_ManualClock(t *testing.T) {
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	c, err := New(Options{
+		DBPath:       filepath.Join(t.TempDir(), "jobs.db"),
+		PollInterval: time.Hour,
+		Clock:        clock,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer c.Close()
+
+	proc := &recordingProcessor{done: make(chan string, 1)}
+	c.Register(AdaptLegacy(proc))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	job, err := c.Submit(ctx, "https://example.com/video")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	go c.Run(ctx)
+
+	// The worker only polls as the clock passes its interval
+	deadline := time.After(2 * time.Second)
+	for processed := false; !processed; {
+		select {
+		case <-proc.done:
+			processed = true
+		case <-time.After(10 * time.Millisecond):
+			clock.Advance(time.Hour)
+		case <-deadline:
+			t.Fatal("job was not processed")
+		}
+	}
+	c.Shutdown(ctx)
+
+	attempts, err := c.Attempts(ctx, job.ID)
+	if err != nil || len(attempts) != 1 {
+		t.Fatalf("Attempts() = %+v, %v; want one attempt", attempts, err)
+	}
+	if at := attempts[0].StartedAt; at.Before(start.Add(time.Hour)) || at.After(clock.Now()) {
+		t.Errorf("attempt started at %s, want on the clock after the first poll", at)
+	}
+}
@@ -0,0 +1,274 @@
+// Package catchertest runs a throwaway catcher for integration tests: a
+// fresh in-memory database, fake processors, the worker, and the HTTP API on a random
+// local port, all torn down when the test ends. Tests talk to it over the
+// real API, the way clients and automations do.
+package catchertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	httpAdapter "github.com/cwygoda/catcher/internal/adapter/http"
+	"github.com/cwygoda/catcher/internal/adapter/metrics"
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/adapter/sqlite"
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/event"
+	"github.com/cwygoda/catcher/internal/worker"
+)
+
+// Job is a job as the API returns it.
+type Job = event.Job
+
+// AdminToken is the admin token of every instance.
+const AdminToken = "catchertest-admin"
+
+// Fake configures a fake processor, which runs no command: it waits Delay,
+// fails a FailureRate share of runs, and otherwise writes a file of Size
+// zero bytes, e.g. "1MB", to the instance's TargetDir.
+type Fake struct {
+	Name string
+	// Pattern is the regex of URLs it handles. Empty matches every URL.
+	Pattern     string
+	Delay       time.Duration
+	FailureRate float64
+	Size        string
+	// Concurrency gives it a queue of its own running this many jobs at
+	// once. Zero shares the default queue.
+	Concurrency int
+	// RateLimitDelay makes failures report HTTP 429 and wait this long.
+	RateLimitDelay time.Duration
+}
+
+// Options configures an instance. The zero value runs one fake processor
+// named "fake" that completes every URL at once.
+type Options struct {
+	// Fakes are the fake processors, tried in order.
+	Fakes []Fake
+	// Processors are native processors, registered after Fakes.
+	Processors []domain.URLProcessor
+	// PollInterval is how often the worker polls. Defaults to 10ms.
+	PollInterval time.Duration
+	// MaxRetries is the maximum number of attempts per job. Defaults to 3.
+	MaxRetries int
+	// Clock drives polling, retry delays, cooldowns, and stored
+	// timestamps; with a domain.ManualClock the worker only polls as the
+	// test advances it. Fake processor delays still take real time.
+	// Defaults to the system clock.
+	Clock domain.Clock
+	// DedupeWindow rejects URLs submitted again within it, like
+	// validation.dedupe_window. Zero disables the check.
+	DedupeWindow time.Duration
+}
+
+// Instance is a running catcher.
+type Instance struct {
+	// URL is the base URL of the API, e.g. "http://127.0.0.1:41234".
+	URL string
+	// TargetDir is where fake processors write their files.
+	TargetDir string
+	// Client sends requests to the API.
+	Client *http.Client
+
+	t testing.TB
+}
+
+// Start runs an instance until the test ends. It fails the test if the
+// instance can't start.
+func Start(t testing.TB, opts Options) *Instance {
+	t.Helper()
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 10 * time.Millisecond
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.Clock == nil {
+		opts.Clock = domain.SystemClock
+	}
+	if len(opts.Fakes) == 0 && len(opts.Processors) == 0 {
+		opts.Fakes = []Fake{{Name: "fake"}}
+	}
+
+	// The temp dir is unique, so it names the database too
+	dir := t.TempDir()
+	repo, err := sqlite.NewMemory(dir)
+	if err != nil {
+		t.Fatalf("catchertest: open database: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	repo.SetClock(opts.Clock)
+
+	// Wired like cmd/catcher, less what only its config turns on
+	svc := domain.NewJobService(repo)
+	svc.SetClock(opts.Clock)
+	svc.SetAttemptRepository(repo)
+	svc.SetRetryScheduler(repo)
+	svc.SetDedupeWindow(repo, opts.DedupeWindow)
+	svc.SetManualCompleter(repo)
+	svc.SetJobHolder(repo)
+	svc.SetUIDResolver(repo)
+	svc.SetBulkRepository(repo)
+	svc.SetPostponer(repo)
+	svc.SetNoteEditor(repo)
+	svc.SetViewRepository(repo)
+	svc.SetCompletedLister(repo)
+	svc.SetFailureLister(repo)
+	svc.SetCooldownRepository(repo)
+	svc.SetFileCheckRepository(repo)
+	svc.SetAnnouncementRepository(repo)
+	svc.SetLibraryIndex(repo)
+	svc.SetRedownloadFinder(repo)
+	svc.SetApproval(repo, domain.MatchHosts())
+
+	targetDir := filepath.Join(dir, "target")
+	registry := processor.NewRegistry()
+	for _, f := range opts.Fakes {
+		p, err := processor.NewCommandProcessor(config.ProcessorConfig{
+			Name:           f.Name,
+			Pattern:        f.Pattern,
+			TargetDir:      targetDir,
+			Concurrency:    f.Concurrency,
+			RateLimitDelay: f.RateLimitDelay,
+			Fake:           &config.FakeConfig{Delay: f.Delay, FailureRate: f.FailureRate, Size: f.Size},
+		})
+		if err != nil {
+			t.Fatalf("catchertest: fake processor %q: %v", f.Name, err)
+		}
+		p.SetWorkDir(filepath.Join(dir, "work"))
+		p.SetClock(opts.Clock)
+		registry.Register(p)
+	}
+	for _, p := range opts.Processors {
+		registry.Register(p)
+	}
+	svc.SetResubmitPolicy(repo, registry.ResubmitPolicy)
+	svc.SetBookmarkSupport(registry.SavesBookmarks)
+	svc.SetQueues(repo, registry.Queue)
+	svc.SetProcessorMatcher(registry.ProcessorName)
+
+	m := metrics.New(nil)
+	repo.SetRetryObserver(m)
+	w := worker.New(svc, registry, opts.PollInterval, opts.MaxRetries)
+	w.SetObserver(m)
+	w.SetWorkDir(filepath.Join(dir, "work"))
+	w.SetClock(opts.Clock)
+
+	srv := httpAdapter.NewServer(svc, "", "")
+	srv.SetAdminToken(AdminToken)
+	srv.SetClock(opts.Clock)
+	srv.SetMetrics(m)
+	stats := domain.NewStatsService(repo, 0)
+	stats.SetClock(opts.Clock)
+	srv.SetStats(stats)
+	srv.SetProcessorTester(registry)
+	srv.SetInFlight(w.InFlight)
+	srv.SetScheduler(w.State)
+	ts := httptest.NewServer(srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+		<-done
+	})
+
+	return &Instance{URL: ts.URL, TargetDir: targetDir, Client: ts.Client(), t: t}
+}
+
+// Do sends a request with a JSON body, unless body is nil, to the API path
+// and returns the response, failing the test if it can't be sent. Admin
+// requests carry the admin token.
+func (i *Instance) Do(method, path string, body any, admin bool) *http.Response {
+	i.t.Helper()
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			i.t.Fatalf("catchertest: encode body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, i.URL+path, r)
+	if err != nil {
+		i.t.Fatalf("catchertest: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if admin {
+		req.Header.Set("Authorization", "Bearer "+AdminToken)
+	}
+	resp, err := i.Client.Do(req)
+	if err != nil {
+		i.t.Fatalf("catchertest: %s %s: %v", method, path, err)
+	}
+	return resp
+}
+
+// Submit submits url through the webhook and returns the job created,
+// failing the test unless the webhook accepts it.
+func (i *Instance) Submit(url string) *Job {
+	i.t.Helper()
+	resp := i.Do(http.MethodPost, "/webhook", map[string]string{"url": url}, false)
+	var job Job
+	i.decode(resp, http.StatusCreated, &job)
+	return &job
+}
+
+// Job returns the job with ID id, failing the test if there is none.
+func (i *Instance) Job(id int64) *Job {
+	i.t.Helper()
+	resp := i.Do(http.MethodGet, fmt.Sprintf("/jobs/%d", id), nil, false)
+	var job Job
+	i.decode(resp, http.StatusOK, &job)
+	return &job
+}
+
+// WaitForStatus polls the job with ID id until it has status, and returns
+// it. It fails the test if the job doesn't get there within timeout.
+func (i *Instance) WaitForStatus(id int64, status domain.JobStatus, timeout time.Duration) *Job {
+	i.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		job := i.Job(id)
+		if job.Status == string(status) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			i.t.Fatalf("catchertest: job %d is %s after %s, want %s (error %q)", id, job.Status, timeout, status, job.Error)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// decode reads resp's JSON body into v, failing the test unless resp has
+// status.
+func (i *Instance) decode(resp *http.Response, status int, v any) {
+	i.t.Helper()
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		i.t.Fatalf("catchertest: read response: %v", err)
+	}
+	if resp.StatusCode != status {
+		i.t.Fatalf("catchertest: %s %s: status %d, want %d: %s", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, status, body)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		i.t.Fatalf("catchertest: decode response: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package catchertest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestStart_Default(t *testing.T) {
+	inst := Start(t, Options{})
+
+	job := inst.Submit("https://fake.test/1")
+	if job.Status != string(domain.StatusPending) {
+		t.Errorf("submitted status = %q, want pending", job.Status)
+	}
+	done := inst.WaitForStatus(job.ID, domain.StatusCompleted, 5*time.Second)
+	if done.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1", done.Attempts)
+	}
+
+	resp := inst.Do(http.MethodGet, "/admin/scheduler", nil, true)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /admin/scheduler status = %d, want 200", resp.StatusCode)
+	}
+	resp = inst.Do(http.MethodGet, "/admin/scheduler", nil, false)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /admin/scheduler without token status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestStart_Fakes(t *testing.T) {
+	inst := Start(t, Options{
+		Fakes: []Fake{
+			{Name: "broken", Pattern: `^https://broken\.test/`, FailureRate: 1},
+			{Name: "files", Size: "1KB", Concurrency: 2},
+		},
+		MaxRetries: 2,
+	})
+
+	failed := inst.Submit("https://broken.test/a")
+	job := inst.WaitForStatus(failed.ID, domain.StatusFailed, 5*time.Second)
+	if job.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", job.Attempts)
+	}
+
+	ok := inst.Submit("https://files.test/a")
+	inst.WaitForStatus(ok.ID, domain.StatusCompleted, 5*time.Second)
+	info, err := os.Stat(filepath.Join(inst.TargetDir, "fake-"+strconv.FormatInt(ok.ID, 10)+".bin"))
+	if err != nil || info.Size() != 1000 {
+		t.Errorf("fake file: %v, %v", info, err)
+	}
+}
+
+func TestStart_WiredLikeCatcher(t *testing.T) {
+	inst := Start(t, Options{DedupeWindow: time.Hour})
+
+	first := inst.Submit("https://fake.test/dup")
+	inst.WaitForStatus(first.ID, domain.StatusCompleted, 5*time.Second)
+	resp := inst.Do(http.MethodPost, "/webhook", map[string]string{"url": "https://fake.test/dup"}, false)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("resubmission within the dedupe window status = %d, want 409", resp.StatusCode)
+	}
+
+	for _, path := range []string{"/library/search", "/announcement"} {
+		resp := inst.Do(http.MethodGet, path, nil, true)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotImplemented {
+			t.Errorf("GET %s status = 501, want it wired", path)
+		}
+	}
+}
+
+// nativeProcessor handles every URL without doing anything.
+type nativeProcessor struct{}
+
+func (nativeProcessor) Name() string          { return "native" }
+func (nativeProcessor) TargetDir() string     { return "" }
+func (nativeProcessor) Match(url string) bool { return true }
+func (nativeProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
+	return &domain.ProcessResult{Title: "native"}, nil
+}
+
+func TestStart_NativeProcessor(t *testing.T) {
+	inst := Start(t, Options{Processors: []domain.URLProcessor{nativeProcessor{}}})
+	job := inst.Submit("https://example.com/v")
+	if got := inst.WaitForStatus(job.ID, domain.StatusCompleted, 5*time.Second); got.Title != "native" {
+		t.Errorf("title = %q, want native", got.Title)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// runDigest handles "catcher digest": it prints a summary of the last days
+// for a scheduled job to mail or post, so the system can be seen to be
+// healthy without opening the dashboard.
+func runDigest(args []string) {
+	var configPath, dbPath string
+	var days int
+	fs := flag.NewFlagSet("catcher digest", flag.ExitOnError)
+	fs.IntVar(&days, "days", 7, "Summarize this many days")
+	fs.StringVar(&configPath, "config", config.DefaultConfigPath(), "Config file path")
+	fs.StringVar(&dbPath, "db", "", "SQLite database path (default from config)")
+	fs.Parse(args)
+	if days < 1 {
+		fmt.Fprintf(os.Stderr, "catcher digest: --days must be at least 1\n")
+		os.Exit(2)
+	}
+
+	cfg := loadCommandConfig(configPath, dbPath)
+	repo, svc := openConfiguredDatabase(cfg)
+	defer repo.Close()
+	svc.SetFailureLister(repo)
+	stats := domain.NewStatsService(repo, cfg.Maintenance.HourlyStatsRetention)
+	stats.SetTimeout(cfg.DBTimeout)
+
+	ctx := context.Background()
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+	buckets, err := stats.History(ctx, domain.StatsQuery{Period: domain.PeriodDay, Since: since})
+	if err != nil {
+		log.Fatalf("digest: %v", err)
+	}
+	var total domain.StatsBucket
+	for _, b := range buckets {
+		total.Submitted += b.Submitted
+		total.Completed += b.Completed
+		total.Failed += b.Failed
+		total.Bytes += b.Bytes
+	}
+	counts, err := repo.CountByStatus(ctx)
+	if err != nil {
+		log.Fatalf("digest: %v", err)
+	}
+	report, err := svc.FailureReport(ctx, since)
+	if err != nil {
+		log.Fatalf("digest: %v", err)
+	}
+
+	fmt.Printf("catcher digest, %s to %s\n\n", since.Format("2006-01-02"), now.Format("2006-01-02"))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Completed\t%d job(s), %s\n", total.Completed, domain.FormatBytes(total.Bytes))
+	fmt.Fprintf(tw, "Submitted\t%d job(s)\n", total.Submitted)
+	fmt.Fprintf(tw, "Failed\t%d job(s)\n", total.Failed)
+	fmt.Fprintf(tw, "Queued\t%d pending, %d processing\n", counts[domain.StatusPending], counts[domain.StatusProcessing])
+	tw.Flush()
+
+	fmt.Println("\nNeeds attention")
+	attention := false
+	if n := counts[domain.StatusFailed]; n > 0 {
+		fmt.Printf("  %d failed job(s), see catcher failures or catcher list --status failed\n", n)
+		attention = true
+	}
+	if n := counts[domain.StatusNeedsApproval]; n > 0 {
+		fmt.Printf("  %d job(s) awaiting approval\n", n)
+		attention = true
+	}
+	for _, c := range report.Causes {
+		if c.Jobs > 0 {
+			fmt.Printf("  %s: %d job(s) failed for good, latest: %s\n", c.Cause, c.Jobs, firstLine(c.Example))
+			attention = true
+		}
+	}
+	if !attention {
+		fmt.Println("  nothing")
+	}
+
+	fmt.Println("\nDisk")
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, dir := range targetRoots(cfg) {
+		free, size, err := diskSpace(dir)
+		if err != nil {
+			fmt.Fprintf(tw, "  %s\t%v\n", dir, err)
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%s free of %s\n", dir, domain.FormatBytes(int64(free)), domain.FormatBytes(int64(size)))
+	}
+	tw.Flush()
+}
+
+// targetRoots returns the processors' target directories, cut before any
+// placeholder and walked up to a directory that exists, without repeats.
+func targetRoots(cfg *config.Config) []string {
+	var roots []string
+	for _, pc := range cfg.Processors {
+		dir := config.DefaultTargetDir()
+		if pc.TargetDir != "" {
+			dir = config.ExpandPath(pc.TargetDir)
+		}
+		if i := strings.IndexByte(dir, '{'); i >= 0 {
+			dir = filepath.Dir(dir[:i] + "x")
+		}
+		for {
+			if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		if !slices.Contains(roots, dir) {
+			roots = append(roots, dir)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// diskSpace is unsupported where statfs doesn't exist.
+func diskSpace(path string) (free, size uint64, err error) {
+	return 0, 0, errors.ErrUnsupported
+}
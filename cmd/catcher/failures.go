@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+)
+
+// runFailures handles "catcher failures": it prints the failed attempts and
+// jobs of the last days by cause, most frequent first.
+func runFailures(args []string) {
+	var configPath, dbPath string
+	var days int
+	fs := flag.NewFlagSet("catcher failures", flag.ExitOnError)
+	fs.IntVar(&days, "days", 7, "Report failures of this many days")
+	fs.StringVar(&configPath, "config", config.DefaultConfigPath(), "Config file path")
+	fs.StringVar(&dbPath, "db", "", "SQLite database path (default from config)")
+	fs.Parse(args)
+	if days < 1 {
+		fmt.Fprintf(os.Stderr, "catcher failures: --days must be at least 1\n")
+		os.Exit(2)
+	}
+
+	repo, svc := openDatabase(configPath, dbPath)
+	defer repo.Close()
+	svc.SetFailureLister(repo)
+
+	report, err := svc.FailureReport(context.Background(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Fatalf("failure report: %v", err)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+	fmt.Fprintln(tw, "CAUSE\tJOBS\tATTEMPTS\tLATEST")
+	for _, c := range report.Causes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", c.Cause, c.Jobs, c.Attempts, firstLine(c.Example))
+	}
+}
+
+// firstLine returns s up to its first line break, as command output often
+// runs over several.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/setup"
+)
+
+// runInit handles "catcher init": it writes a starter config, asking for
+// each setting when run interactively.
+func runInit(args []string) {
+	ytdlp := "yt-dlp"
+	if path, err := exec.LookPath(ytdlp); err == nil {
+		ytdlp = path
+	}
+
+	o := setup.Options{StateDir: config.DefaultStateDir()}
+	var service, yes bool
+	fs := flag.NewFlagSet("catcher init", flag.ExitOnError)
+	fs.StringVar(&o.ConfigPath, "config", config.DefaultConfigPath(), "Config file to write")
+	fs.StringVar(&o.TargetDir, "target-dir", config.DefaultTargetDir(), "Where downloaded videos go")
+	fs.StringVar(&o.Command, "command", ytdlp, "yt-dlp command")
+	fs.BoolVar(&service, "service", false, "Install and start a user service (systemd or launchd)")
+	fs.BoolVar(&o.Force, "force", false, "Overwrite an existing config")
+	fs.BoolVar(&yes, "yes", false, "Don't ask; use flags and defaults")
+	fs.Parse(args)
+
+	if !yes && isTerminal(os.Stdin) {
+		p := setup.NewPrompter(os.Stdin, os.Stdout)
+		o.ConfigPath = p.Ask("Config file", o.ConfigPath)
+		o.TargetDir = p.Ask("Save videos to", o.TargetDir)
+		o.Command = p.Ask("yt-dlp command", o.Command)
+		service = p.Confirm("Install and start a user service", service)
+	}
+	o.ConfigPath = config.ExpandPath(o.ConfigPath)
+	o.TargetDir = config.ExpandPath(o.TargetDir)
+
+	err := setup.Write(&o)
+	if errors.Is(err, setup.ErrExists) {
+		fmt.Fprintf(os.Stderr, "catcher init: %v\n", err)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("init failed: %v", err)
+	}
+	fmt.Printf("wrote %s\n", o.ConfigPath)
+	if _, err := exec.LookPath(o.Command); err != nil {
+		fmt.Printf("warning: %s not found; install yt-dlp before submitting YouTube URLs\n", o.Command)
+	}
+
+	if !service {
+		fmt.Printf("start with: catcher --config %s\n", o.ConfigPath)
+		return
+	}
+	cfg, err := config.Load([]string{"--config", o.ConfigPath})
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := installService(cfg); err != nil {
+		log.Fatalf("install service: %v", err)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
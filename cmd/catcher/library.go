@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/worker"
+)
+
+// runLibrary handles "catcher library": it rescans the index of delivered
+// files against the disk, or searches it.
+func runLibrary(args []string) {
+	var configPath, dbPath string
+	var hash, missing bool
+	var sha string
+	var jobID int64
+	var limit int
+	usage := "usage: catcher library rescan [--hash] | search [--missing] [--sha256 S] [--job ID] [--limit N] [TEXT]"
+	if len(args) == 0 || (args[0] != "rescan" && args[0] != "search") {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("catcher library "+cmd, flag.ExitOnError)
+	if cmd == "rescan" {
+		fs.BoolVar(&hash, "hash", false, "Also recompute each file's SHA-256")
+	} else {
+		fs.BoolVar(&missing, "missing", false, "Only files the last rescan found missing")
+		fs.StringVar(&sha, "sha256", "", "Only files with this SHA-256")
+		fs.Int64Var(&jobID, "job", 0, "Only files of this job")
+		fs.IntVar(&limit, "limit", 50, "Maximum files to print")
+	}
+	fs.StringVar(&configPath, "config", config.DefaultConfigPath(), "Config file path")
+	fs.StringVar(&dbPath, "db", "", "SQLite database path (default from config)")
+	fs.Parse(args[1:])
+
+	repo, svc := openDatabase(configPath, dbPath)
+	defer repo.Close()
+	svc.SetLibraryIndex(repo)
+	ctx := context.Background()
+
+	if cmd == "rescan" {
+		scan, err := worker.RescanLibrary(ctx, svc, domain.SystemClock, hash)
+		if err != nil {
+			log.Fatalf("library rescan: %v", err)
+		}
+		fmt.Printf("checked %d file(s): %d missing, %d changed, %d found again\n", scan.Files, scan.Missing, scan.Changed, scan.Returned)
+		return
+	}
+
+	q := domain.LibraryQuery{Text: strings.Join(fs.Args(), " "), SHA256: sha, JobID: jobID, Limit: limit}
+	if missing {
+		q.Missing = &missing
+	}
+	files, err := svc.SearchLibrary(ctx, q)
+	if err != nil {
+		log.Fatalf("library search: %v", err)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+	fmt.Fprintln(tw, "JOB\tDELIVERED\tBYTES\tPATH\tURL")
+	for _, f := range files {
+		path := f.Path
+		if f.Missing {
+			path += " (missing)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", f.JobID, f.DeliveredAt.Local().Format("2006-01-02 15:04"), f.Bytes, path, f.URL)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cwygoda/catcher/internal/adapter/sqlite"
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// runList handles "catcher list": it prints jobs from the database, newest
+// first, optionally through a saved view, or with --views the saved views.
+func runList(args []string) {
+	var view, status, host, configPath, dbPath string
+	var limit int
+	var listViews bool
+	fs := flag.NewFlagSet("catcher list", flag.ExitOnError)
+	fs.StringVar(&view, "view", "", "Saved view to list jobs through")
+	fs.StringVar(&status, "status", "", "Only jobs in this status")
+	fs.StringVar(&host, "host", "", "Only URLs of this host and its subdomains")
+	fs.IntVar(&limit, "limit", 50, "Maximum jobs to print")
+	fs.BoolVar(&listViews, "views", false, "Print the saved views instead of jobs")
+	fs.StringVar(&configPath, "config", config.DefaultConfigPath(), "Config file path")
+	fs.StringVar(&dbPath, "db", "", "SQLite database path (default from config)")
+	fs.Parse(args)
+
+	repo, svc := openDatabase(configPath, dbPath)
+	defer repo.Close()
+	svc.SetViewRepository(repo)
+	ctx := context.Background()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+	if listViews {
+		views, err := svc.Views(ctx)
+		if err != nil {
+			log.Fatalf("list views: %v", err)
+		}
+		fmt.Fprintln(tw, "NAME\tSTATUS\tHOST")
+		for _, v := range views {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Name, orAny(string(v.Status)), orAny(v.Host))
+		}
+		return
+	}
+
+	filter := domain.JobFilter{Limit: limit}
+	if view != "" {
+		v, err := svc.View(ctx, view)
+		if errors.Is(err, domain.ErrViewNotFound) {
+			fmt.Fprintf(os.Stderr, "catcher list: no view named %q\n", view)
+			os.Exit(1)
+		}
+		if err != nil {
+			log.Fatalf("load view: %v", err)
+		}
+		filter = v.Filter(limit)
+	}
+	if status != "" {
+		if !domain.JobStatus(status).Valid() {
+			fmt.Fprintf(os.Stderr, "catcher list: invalid status %q\n", status)
+			os.Exit(2)
+		}
+		filter.Status = domain.JobStatus(status)
+	}
+	if host != "" {
+		filter.Host = host
+	}
+
+	jobs, err := svc.List(ctx, filter)
+	if err != nil {
+		log.Fatalf("list jobs: %v", err)
+	}
+	fmt.Fprintln(tw, "ID\tSTATUS\tCREATED\tURL\tNOTES")
+	for _, job := range jobs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", job.ID, job.Status, job.CreatedAt.Local().Format("2006-01-02 15:04"), job.URL, job.Notes)
+	}
+}
+
+// openDatabase opens and unlocks the database the config at configPath
+// names, or the one at dbPath, for a command reading it. It exits on error.
+func openDatabase(configPath, dbPath string) (*sqlite.Repository, *domain.JobService) {
+	return openConfiguredDatabase(loadCommandConfig(configPath, dbPath))
+}
+
+// loadCommandConfig loads the config at configPath for a command, with
+// dbPath, if set, overriding its database. It exits on error.
+func loadCommandConfig(configPath, dbPath string) *config.Config {
+	loadArgs := []string{"--config", configPath}
+	if dbPath != "" {
+		loadArgs = append(loadArgs, "--db", dbPath)
+	}
+	cfg, err := config.Load(loadArgs)
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	return cfg
+}
+
+// openConfiguredDatabase is openDatabase for a config already loaded. A
+// database left in the old cache location is moved first, as on startup,
+// so the command doesn't create an empty one in its place.
+func openConfiguredDatabase(cfg *config.Config) (*sqlite.Repository, *domain.JobService) {
+	cfg.MigrateLegacyDB()
+	repo, err := sqlite.New(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+	dbKey, err := cfg.DBKey()
+	if err != nil {
+		repo.Close()
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := repo.Unlock(context.Background(), dbKey); err != nil {
+		repo.Close()
+		log.Fatalf("failed to unlock database: %v", err)
+	}
+	svc := domain.NewJobService(repo)
+	svc.SetTimeout(cfg.DBTimeout)
+	return repo, svc
+}
+
+func orAny(s string) string {
+	if s == "" {
+		return "any"
+	}
+	return s
+}
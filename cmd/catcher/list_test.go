@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/adapter/sqlite"
+)
+
+func TestOpenConfiguredDatabase_Legacy(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	ctx := context.Background()
+
+	// A database only in the old cache location, as before the move
+	legacy := filepath.Join(dir, "cache", "catcher", "jobs.db")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old, err := sqlite.New(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := old.Create(ctx, "https://example.com/v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+
+	configPath := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(configPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, _ := openConfiguredDatabase(loadCommandConfig(configPath, ""))
+	defer repo.Close()
+	if got, err := repo.Get(ctx, job.ID); err != nil || got.URL != job.URL {
+		t.Errorf("Get(%d) = %+v, %v; want the legacy job", job.ID, got, err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy database still present: %v", err)
+	}
+}
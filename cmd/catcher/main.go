@@ -2,25 +2,98 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"os/exec"
 	"os/signal"
+	"regexp"
 	"syscall"
 	"time"
 
+	"github.com/cwygoda/catcher/internal/adapter/connectivity"
 	httpAdapter "github.com/cwygoda/catcher/internal/adapter/http"
+	"github.com/cwygoda/catcher/internal/adapter/mdns"
+	"github.com/cwygoda/catcher/internal/adapter/metrics"
 	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/adapter/redirect"
+	"github.com/cwygoda/catcher/internal/adapter/replication"
 	"github.com/cwygoda/catcher/internal/adapter/sqlite"
+	"github.com/cwygoda/catcher/internal/adapter/watch"
 	"github.com/cwygoda/catcher/internal/config"
 	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/event"
+	"github.com/cwygoda/catcher/internal/logging"
+	"github.com/cwygoda/catcher/internal/maintenance"
+	"github.com/cwygoda/catcher/internal/netaddr"
 	"github.com/cwygoda/catcher/internal/worker"
 )
 
 func main() {
-	cfg := config.Load()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "config":
+			runConfigCommand(os.Args[2:])
+			return
+		case "init":
+			runInit(os.Args[2:])
+			return
+		case "list":
+			runList(os.Args[2:])
+			return
+		case "failures":
+			runFailures(os.Args[2:])
+			return
+		case "digest":
+			runDigest(os.Args[2:])
+			return
+		case "library":
+			runLibrary(os.Args[2:])
+			return
+		case "export-state":
+			runExportState(os.Args[2:])
+			return
+		case "import-state":
+			runImportState(os.Args[2:])
+			return
+		case "context":
+			runContext(os.Args[2:])
+			return
+		case "submit":
+			runSubmit(os.Args[2:])
+			return
+		case "watch-clipboard":
+			runWatchClipboard(os.Args[2:])
+			return
+		case "install-service":
+			runInstallService(os.Args[2:])
+			return
+		}
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	applyServerDefaults(cfg)
+	logging.SetDebug(cfg.Debug)
+	logging.SetRedactURLs(cfg.Logging.RedactURLs)
+	log.SetOutput(logging.Writer(os.Stderr))
+	started := time.Now()
 
-	log.Printf("starting catcher on port %d", cfg.Port)
+	if !config.ValidMode(cfg.Mode) {
+		log.Fatalf("invalid mode %q: must be api, worker, or all", cfg.Mode)
+	}
+
+	log.Printf("starting catcher in %s mode", cfg.Mode)
+	// catcher's own lookups, e.g. connectivity checks, follow [dns] too
+	if r := netaddr.NameResolver(cfg.DNS.Servers, 5*time.Second); r != nil {
+		net.DefaultResolver = r
+		log.Printf("asking %d name server(s) from [dns]", len(cfg.DNS.Servers))
+	}
+	cfg.MigrateLegacyDB()
 	log.Printf("database: %s", cfg.DBPath)
 
 	// Initialize SQLite repository
@@ -29,76 +102,486 @@ func main() {
 		log.Fatalf("failed to initialize database: %v", err)
 	}
 	defer repo.Close()
+	dbKey, err := cfg.DBKey()
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := repo.Unlock(context.Background(), dbKey); err != nil {
+		log.Fatalf("failed to unlock database: %v", err)
+	}
+	if dbKey != nil {
+		log.Println("database encryption enabled")
+	}
+	var repl *replication.Monitor
+	if rc := cfg.Replication; rc.Enabled() {
+		if err := repo.EnableWAL(); err != nil {
+			log.Fatalf("failed to enable WAL for replication: %v", err)
+		}
+		repl = replication.New(config.ExpandPath(rc.SyncFile), config.ExpandPath(rc.BackupFile), rc.MaxLag)
+		log.Println("watching database replication")
+	}
+	if n := cfg.Database.ReadPool; n > 0 && cfg.RunsAPI() {
+		if err := repo.OpenReadPool(n); err != nil {
+			log.Fatalf("failed to open read pool: %v", err)
+		}
+		log.Printf("serving listings and stats from %d read-only connection(s)", n)
+	}
 
 	// Initialize domain service
 	svc := domain.NewJobService(repo)
+	svc.SetAttemptRepository(repo)
+	svc.SetRetryScheduler(repo)
+	svc.SetTimeout(cfg.DBTimeout)
+	addRewriters(svc, cfg.Rewrites)
+	if rc := cfg.Redirects; len(rc.Hosts) > 0 {
+		resolver := redirect.New(rc.MaxHops, rc.Timeout)
+		resolver.SetDNS(cfg.DNS.Servers, cfg.DNS.Hosts)
+		resolver.SetUserAgent(rc.UserAgent)
+		resolver.SetSourceAddress(rc.SourceAddress)
+		resolver.SetForceIP(rc.ForceIP)
+		svc.SetRedirectResolver(resolver.Resolve, domain.MatchHosts(rc.Hosts...))
+		log.Printf("following redirects for %d host pattern(s)", len(rc.Hosts))
+	}
+	addValidators(svc, cfg.Validation)
+	svc.SetDedupeWindow(repo, cfg.Validation.DedupeWindow)
+	svc.SetManualCompleter(repo)
+	svc.SetJobHolder(repo)
+	svc.SetUIDResolver(repo)
+	svc.SetBulkRepository(repo)
+	svc.SetPostponer(repo)
+	svc.SetNoteEditor(repo)
+	svc.SetViewRepository(repo)
+	svc.SetCompletedLister(repo)
+	svc.SetFailureLister(repo)
+	svc.SetCooldownRepository(repo)
+	svc.SetOutageRepository(repo)
+	svc.SetFileCheckRepository(repo)
+	svc.SetAnnouncementRepository(repo)
+	svc.SetLibraryIndex(repo)
+	svc.SetRedownloadFinder(repo)
+	svc.SetRetention(repo, cfg.Maintenance.JobRetention, cfg.Maintenance.ArchiveJobs)
+	svc.SetApproval(repo, domain.MatchHosts(cfg.Approval.Hosts...))
+	if hosts := cfg.Approval.Hosts; len(hosts) > 0 {
+		log.Printf("submissions from %d host pattern(s) need approval", len(hosts))
+	}
+	stats := domain.NewStatsService(repo, cfg.Maintenance.HourlyStatsRetention)
+	stats.SetTimeout(cfg.DBTimeout)
 
-	// Recover stale jobs from previous crash
-	if recovered, err := svc.RecoverStale(context.Background()); err != nil {
-		log.Printf("warning: failed to recover stale jobs: %v", err)
-	} else if recovered > 0 {
-		log.Printf("recovered %d stale jobs", recovered)
+	// Graceful shutdown setup
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	m := metrics.New(cfg.Metrics.Hosts)
+	repo.SetRetryObserver(m)
+	masker := logging.NewMasker(cfg.Secrets()...)
+	logging.SetMasker(masker)
+	registry := newRegistry(cfg.Processors, cfg.DNS, cfg.WorkDir(), masker)
+	for _, p := range registry.Processors() {
+		if cp, ok := p.(*processor.CommandProcessor); ok {
+			cp.SetLibraryIndex(repo)
+		}
+	}
+	if ttl := cfg.Worker.ProbeCacheTTL; ttl > 0 {
+		svc.SetProbeCache(repo, ttl)
+		for _, p := range registry.Processors() {
+			if cp, ok := p.(*processor.CommandProcessor); ok {
+				cp.SetProbeCache(repo, ttl)
+			}
+		}
+	}
+	svc.SetResubmitPolicy(repo, registry.ResubmitPolicy)
+	svc.SetBookmarkSupport(registry.SavesBookmarks)
+	svc.SetQueues(repo, registry.Queue)
+	svc.SetProcessorMatcher(registry.ProcessorName)
+
+	var uplink domain.ConnectivityMonitor
+	if cc := cfg.Connectivity; cc.URL != "" {
+		mon := connectivity.New(cc.URL, cc.Interval, cc.Timeout)
+		go mon.Run(ctx)
+		uplink = mon
+	}
+
+	var w *worker.Worker
+	if cfg.RunsWorker() {
+		w = startWorker(ctx, cfg, svc, registry, m, uplink)
+		go newMaintenance(cfg.Maintenance, svc, stats, w, repl).Run(ctx)
+	}
+
+	var srv *httpAdapter.Server
+	if cfg.RunsAPI() {
+		srv = startServer(cfg, svc)
+		srv.SetMetrics(m)
+		srv.SetStats(stats)
+		srv.SetProcessorTester(registry)
+		srv.SetEffectiveConfig(cfg.WriteEffective)
+		if repl != nil {
+			srv.SetReplication(repl)
+		}
+		if uplink != nil {
+			srv.SetConnectivity(uplink)
+		}
+		if w != nil {
+			srv.SetInFlight(w.InFlight)
+			srv.SetScheduler(w.State)
+		}
+	}
+
+	var adv *mdns.Advertiser
+	if cfg.RunsAPI() && cfg.MDNS.Enabled {
+		adv = startMDNS(cfg)
+	}
+
+	// Like the API, only one process of a deployment should watch
+	if cfg.RunsAPI() && cfg.Watch.Dir != "" {
+		folder := watch.New(config.ExpandPath(cfg.Watch.Dir), svc.Submit, cfg.Watch.Interval, cfg.Watch.Source)
+		if n := cfg.HTTP.MaxBatch; n > 0 {
+			var hold watch.SubmitFunc
+			if cfg.HTTP.BatchOverflow == "hold" {
+				hold = svc.SubmitHeld
+			}
+			folder.SetMaxBatch(n, hold)
+		}
+		go func() {
+			if err := folder.Run(ctx); err != nil {
+				log.Printf("watch: %v", err)
+			}
+		}()
+	}
+
+	watchControlSignals(ctx, func() {
+		statusCtx, cancel := context.WithTimeout(ctx, cfg.DBTimeout)
+		defer cancel()
+		logStatus(statusCtx, repo, svc, w, started)
+	})
+
+	// Wait for shutdown signal
+	sig := <-sigCh
+	log.Printf("received signal %v, shutting down (grace %s)", sig, cfg.ShutdownGrace)
+
+	// Fail readiness first so load balancers stop routing new requests
+	if srv != nil {
+		srv.Drain()
+	}
+
+	// Withdraw the advertisement so apps stop offering this instance
+	if adv != nil {
+		adv.Close()
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownGrace)
+	defer shutdownCancel()
+
+	// Let in-flight jobs finish within the grace period
+	if w != nil {
+		if err := w.Shutdown(shutdownCtx); err != nil {
+			log.Printf("worker drain incomplete: %v", err)
+		}
+	}
+
+	if srv != nil {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("HTTP server shutdown error: %v", err)
+		}
 	}
 
-	// Initialize processor registry from config
+	log.Println("shutdown complete")
+}
+
+// addRewriters registers the configured URL rewrite rules, in order.
+func addRewriters(svc *domain.JobService, rules []config.RewriteConfig) {
+	for i, rc := range rules {
+		var re *regexp.Regexp
+		if rc.Pattern != "" {
+			var err error
+			if re, err = regexp.Compile(rc.Pattern); err != nil {
+				log.Fatalf("invalid rewrite #%d: %v", i+1, err)
+			}
+		}
+		switch {
+		case rc.Replace != nil:
+			svc.AddRewriter(domain.RegexRewrite(re, *rc.Replace))
+		case rc.Unwrap != "":
+			svc.AddRewriter(domain.UnwrapParam(re, rc.Unwrap))
+		default:
+			svc.AddRewriter(domain.StripParams(re, rc.StripParams...))
+		}
+	}
+	if len(rules) > 0 {
+		log.Printf("registered %d URL rewrite rule(s)", len(rules))
+	}
+}
+
+// addValidators registers the configured submission checks.
+func addValidators(svc *domain.JobService, vc config.ValidationConfig) {
+	if len(vc.AllowedSchemes) > 0 {
+		svc.AddValidator(domain.SchemeAllowlist(vc.AllowedSchemes...))
+	}
+	if vc.MaxURLLength > 0 {
+		svc.AddValidator(domain.MaxLength(vc.MaxURLLength))
+	}
+	if vc.ResolveHosts {
+		svc.AddValidator(domain.HostResolves(net.DefaultResolver.LookupHost))
+	}
+}
+
+// newMaintenance builds the periodic housekeeping runner. Pruning and
+// compaction are heavy, so they wait out backups when repl is set.
+func newMaintenance(mc config.MaintenanceConfig, svc *domain.JobService, stats *domain.StatsService, w *worker.Worker, repl *replication.Monitor) *maintenance.Runner {
+	r := maintenance.New(mc.Interval)
+	if repl != nil {
+		r.SetPause(repl.BackupInProgress)
+	}
+	if mc.JobRetention > 0 {
+		verb := "deleted"
+		if mc.ArchiveJobs {
+			verb = "archived"
+		}
+		r.AddHeavy("prune-jobs", func(ctx context.Context) error {
+			pruned, err := svc.Prune(ctx)
+			if pruned > 0 {
+				log.Printf("%s %d job(s) finished more than %s ago", verb, pruned, mc.JobRetention)
+			}
+			return err
+		})
+	}
+	r.Add("prune-probe-cache", func(ctx context.Context) error {
+		pruned, err := svc.PruneProbeCache(ctx)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil
+		}
+		if pruned > 0 {
+			log.Printf("removed %d expired probe result(s)", pruned)
+		}
+		return err
+	})
+	r.AddHeavy("compact-stats", func(ctx context.Context) error {
+		folded, err := stats.Compact(ctx)
+		if folded > 0 {
+			log.Printf("compacted %d hourly stats bucket(s) into daily totals", folded)
+		}
+		return err
+	})
+	r.Add("clean-work-dirs", func(ctx context.Context) error {
+		removed, err := w.CleanWorkDirs(ctx)
+		if removed > 0 {
+			log.Printf("removed %d leftover job work dir(s)", removed)
+		}
+		return err
+	})
+	return r
+}
+
+// newRegistry builds the processor registry from config. Secrets known to
+// masker are hidden in the command lines and output the processors record.
+func newRegistry(processors []config.ProcessorConfig, dns config.DNSConfig, workDir string, masker *logging.Masker) *processor.Registry {
 	registry := processor.NewRegistry()
-	for _, pc := range cfg.Processors {
+	for _, pc := range processors {
+		pc.DNS = dns.Merge(pc.DNS)
 		p, err := processor.NewCommandProcessor(pc)
 		if err != nil {
 			log.Fatalf("invalid processor %q: %v", pc.Name, err)
 		}
+		p.SetWorkDir(workDir)
+		p.SetMasker(masker)
 		registry.Register(p)
-		log.Printf("registered processor: %s (pattern: %s, target: %s)", pc.Name, pc.Pattern, p.TargetDir())
+		log.Printf("registered processor: %s (patterns: %q, exclude: %q, target: %s)", pc.Name, pc.AllPatterns(), pc.Exclude, p.TargetDir())
 	}
 
-	if len(cfg.Processors) == 0 {
+	if len(processors) == 0 {
 		log.Println("warning: no processors configured")
 	}
+	return registry
+}
+
+// startWorker recovers stale jobs and starts the worker loop in the
+// background. uplink may be nil.
+func startWorker(ctx context.Context, cfg *config.Config, svc *domain.JobService, registry *processor.Registry, obs worker.Observer, uplink domain.ConnectivityMonitor) *worker.Worker {
+	// Recover stale jobs from previous crash
+	if recovered, err := svc.RecoverStale(context.Background()); err != nil {
+		log.Printf("warning: failed to recover stale jobs: %v", err)
+	} else if recovered > 0 {
+		log.Printf("recovered %d stale jobs", recovered)
+	}
+
+	w := worker.New(svc, registry, cfg.PollInterval, cfg.MaxRetries)
+	w.SetObserver(obs)
+	w.SetWorkDir(cfg.WorkDir())
+	w.SetBudget(cfg.Worker.Budget)
+	w.SetStallTimeout(cfg.Worker.StallTimeout)
+	w.SetChecksums(cfg.Worker.Checksums)
+	w.SetProvenance(cfg.Worker.Provenance)
+	w.SetOutagePolicy(worker.OutagePolicy{
+		After:         cfg.Worker.OutageAfter,
+		Probe:         cfg.Worker.OutageProbe,
+		ProbeInterval: cfg.Worker.OutageProbeInterval,
+		SweepRate:     cfg.Worker.OutageSweepRate,
+		MaxRequeues:   cfg.Worker.OutageMaxRequeues,
+	})
+	if uplink != nil {
+		w.SetConnectivity(uplink)
+	}
+	if cfg.Worker.VerifyMedia {
+		v := processor.NewFFProbe(cfg.Worker.FFProbe)
+		if _, err := exec.LookPath(v.Command()); err != nil {
+			log.Fatalf("worker.verify_media is on but %s was not found: %v", v.Command(), err)
+		}
+		w.SetVerifier(v)
+		log.Printf("verifying media files with %s", v.Command())
+	}
+	if window := cfg.Worker.VerifyFiles; window > 0 {
+		go w.VerifyFilesAtStart(ctx, window)
+	}
+	go w.Run(ctx)
+	return w
+}
 
-	// Initialize HTTP server
+// startServer creates the HTTP server and starts listening in the background.
+func startServer(cfg *config.Config, svc *domain.JobService) *httpAdapter.Server {
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	srv := httpAdapter.NewServer(svc, addr, cfg.Secret)
+	srv.SetLimits(httpAdapter.Limits{
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		MaxBodyBytes:      cfg.HTTP.MaxBodyBytes,
+	})
+	srv.SetSecurityHeaders(httpAdapter.SecurityHeaders{
+		ContentSecurityPolicy: cfg.Headers.ContentSecurityPolicy,
+		FrameAncestors:        cfg.Headers.FrameAncestors,
+		ReferrerPolicy:        cfg.Headers.ReferrerPolicy,
+	})
+	if cfg.BasePath != "" {
+		srv.SetBasePath(cfg.BasePath)
+		log.Printf("serving under base path %s", httpAdapter.NormalizeBasePath(cfg.BasePath))
+	}
+	if cfg.HTTP.RequireUID {
+		srv.SetRequireUID(true)
+		log.Println("jobs are only reachable by UID")
+	}
+	if n := cfg.HTTP.MaxBatch; n > 0 {
+		srv.SetMaxBatch(n, cfg.HTTP.BatchOverflow == "hold")
+	}
+	if ttl := cfg.HTTP.ShareTTL; ttl > 0 {
+		srv.SetShare(ttl)
+		log.Printf("share page enabled at %s/share/qr, links valid for %s", httpAdapter.NormalizeBasePath(cfg.BasePath), ttl)
+	}
+	if cfg.AdminToken != "" {
+		srv.SetAdminToken(cfg.AdminToken)
+		log.Println("admin endpoints enabled")
+	}
+	if len(cfg.Tokens) > 0 {
+		grants := make(map[string]*domain.Grant, len(cfg.Tokens))
+		for _, t := range cfg.Tokens {
+			grants[t.Token] = &domain.Grant{Name: t.Name, Processors: t.Processors, Hosts: t.Hosts}
+		}
+		srv.SetGrants(grants)
+		if cfg.Secret == "" {
+			log.Printf("warning: %d token(s) configured but no secret, so submissions are not limited by them", len(cfg.Tokens))
+		} else {
+			log.Printf("%d submission token(s) enabled", len(cfg.Tokens))
+		}
+	}
+	if err := srv.LoadAnnouncement(context.Background()); err != nil {
+		log.Printf("failed to load announcement: %v", err)
+	}
 	if cfg.Secret != "" {
 		log.Println("webhook signature verification enabled")
 	} else {
 		log.Println("warning: no secret configured, webhook verification disabled")
 	}
 
-	// Initialize worker
-	w := worker.New(svc, registry, cfg.PollInterval, cfg.MaxRetries)
-
-	// Graceful shutdown setup
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start worker
-	go w.Run(ctx)
-
-	// Start HTTP server
 	go func() {
 		log.Printf("HTTP server listening on %s", addr)
 		if err := srv.ListenAndServe(); err != nil && err.Error() != "http: Server closed" {
 			log.Printf("HTTP server error: %v", err)
 		}
 	}()
+	return srv
+}
 
-	// Wait for shutdown signal
-	sig := <-sigCh
-	log.Printf("received signal %v, shutting down", sig)
-
-	// Cancel worker context
-	cancel()
-
-	// Shutdown HTTP server with timeout
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
+// startMDNS advertises the API on the local network, returning nil if the
+// mDNS group can't be joined.
+func startMDNS(cfg *config.Config) *mdns.Advertiser {
+	hostname, _ := os.Hostname()
+	host := mdns.HostLabel(hostname)
+	svc := mdns.Service{
+		Instance: cfg.MDNS.Name,
+		Host:     host,
+		Port:     cfg.Port,
+		TXT:      []string{fmt.Sprintf("api=%d", event.SchemaVersion)},
+	}
+	if svc.Instance == "" {
+		svc.Instance = "catcher on " + host
+	}
+	if cfg.BasePath != "" {
+		svc.TXT = append(svc.TXT, "path="+httpAdapter.NormalizeBasePath(cfg.BasePath))
+	}
+	adv := mdns.New(svc)
+	if err := adv.Listen(); err != nil {
+		log.Printf("warning: mDNS advertisement disabled: %v", err)
+		return nil
+	}
+	go func() {
+		if err := adv.Serve(); err != nil {
+			log.Printf("mDNS error: %v", err)
+		}
+	}()
+	log.Printf("advertising %q as %s on %s.local", svc.Instance, mdns.ServiceType, host)
+	return adv
+}
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("HTTP server shutdown error: %v", err)
+// applyServerDefaults fills unset HTTP limits and security headers with the
+// server defaults, so cfg holds the values actually in effect.
+func applyServerDefaults(cfg *config.Config) {
+	l := httpAdapter.DefaultLimits().Override(httpAdapter.Limits{
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		MaxBodyBytes:      cfg.HTTP.MaxBodyBytes,
+	})
+	cfg.HTTP = config.HTTPConfig{
+		ReadHeaderTimeout: l.ReadHeaderTimeout,
+		ReadTimeout:       l.ReadTimeout,
+		WriteTimeout:      l.WriteTimeout,
+		IdleTimeout:       l.IdleTimeout,
+		MaxHeaderBytes:    l.MaxHeaderBytes,
+		MaxBodyBytes:      l.MaxBodyBytes,
+		RequireUID:        cfg.HTTP.RequireUID,
+		ShareTTL:          cfg.HTTP.ShareTTL,
+		MaxBatch:          cfg.HTTP.MaxBatch,
+		BatchOverflow:     cfg.HTTP.BatchOverflow,
+	}
+	h := httpAdapter.DefaultSecurityHeaders().Override(httpAdapter.SecurityHeaders{
+		ContentSecurityPolicy: cfg.Headers.ContentSecurityPolicy,
+		FrameAncestors:        cfg.Headers.FrameAncestors,
+		ReferrerPolicy:        cfg.Headers.ReferrerPolicy,
+	})
+	cfg.Headers = config.HeadersConfig{
+		ContentSecurityPolicy: h.ContentSecurityPolicy,
+		FrameAncestors:        h.FrameAncestors,
+		ReferrerPolicy:        h.ReferrerPolicy,
 	}
+}
 
-	log.Println("shutdown complete")
+// runConfigCommand handles "catcher config <subcommand> [flags]".
+func runConfigCommand(args []string) {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "usage: catcher config show [flags]")
+		os.Exit(2)
+	}
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	applyServerDefaults(cfg)
+	if err := cfg.WriteEffective(os.Stdout); err != nil {
+		log.Fatalf("write config: %v", err)
+	}
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/remote"
+)
+
+// runContext handles "catcher context": it manages the named servers that
+// remote commands such as "catcher submit" talk to.
+func runContext(args []string) {
+	var path string
+	fs := flag.NewFlagSet("catcher context", flag.ExitOnError)
+	fs.StringVar(&path, "file", remote.DefaultPath(), "Contexts file path")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: catcher context [--file path] list | add NAME URL [--secret S] [--source S] [--clipboard REGEXP] | use NAME | remove NAME")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	args = fs.Args()
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	contexts, err := remote.Load(path)
+	if err != nil {
+		log.Fatalf("load contexts: %v", err)
+	}
+	switch cmd := args[0]; {
+	case cmd == "list" && len(args) == 1:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer tw.Flush()
+		fmt.Fprintln(tw, "CURRENT\tNAME\tURL\tSOURCE")
+		for _, name := range contexts.Names() {
+			mark := ""
+			if name == contexts.Current {
+				mark = "*"
+			}
+			c := contexts.Contexts[name]
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", mark, name, c.URL, c.Source)
+		}
+		return
+	case cmd == "add" && len(args) >= 3:
+		var c remote.Context
+		add := flag.NewFlagSet("catcher context add", flag.ExitOnError)
+		add.StringVar(&c.Secret, "secret", "", "Webhook secret of the server")
+		add.StringVar(&c.Source, "source", "", "Source of jobs submitted through the context")
+		add.StringVar(&c.Clipboard, "clipboard", "", "Pattern of the copied URLs watch-clipboard submits (default every URL)")
+		add.Parse(args[3:])
+		c.URL = args[2]
+		err = contexts.Set(args[1], c)
+	case cmd == "use" && len(args) == 2:
+		err = contexts.Use(args[1])
+	case cmd == "remove" && len(args) == 2:
+		err = contexts.Remove(args[1])
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "catcher context: %v\n", err)
+		os.Exit(1)
+	}
+	if err := contexts.Save(path); err != nil {
+		log.Fatalf("save contexts: %v", err)
+	}
+	if contexts.Current == "" {
+		fmt.Println("no current context")
+		return
+	}
+	fmt.Printf("current context: %s\n", contexts.Current)
+}
+
+// resolveContext returns the context name, or the current one, exiting
+// with usage help if there is none.
+func resolveContext(cmd, path, name string) (string, remote.Context) {
+	contexts, err := remote.Load(path)
+	if err != nil {
+		log.Fatalf("load contexts: %v", err)
+	}
+	name, c, err := contexts.Resolve(name)
+	if errors.Is(err, remote.ErrNoContext) {
+		fmt.Fprintf(os.Stderr, "%s: no context selected; add one with \"catcher context add\" or pass --context\n", cmd)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd, err)
+		os.Exit(2)
+	}
+	return name, c
+}
+
+// runSubmit handles "catcher submit": it queues URLs on the server of a
+// context, the current one unless --context or CATCHER_CONTEXT names
+// another.
+func runSubmit(args []string) {
+	var path, name string
+	var s remote.Submission
+	fs := flag.NewFlagSet("catcher submit", flag.ExitOnError)
+	fs.StringVar(&name, "context", os.Getenv("CATCHER_CONTEXT"), "Context to submit to (default the current one)")
+	fs.StringVar(&path, "file", remote.DefaultPath(), "Contexts file path")
+	fs.StringVar(&s.Source, "source", "", "Source of the jobs (default the context's)")
+	fs.StringVar(&s.Notes, "notes", "", "Notes to remember the jobs by")
+	fs.BoolVar(&s.Hold, "hold", false, "Queue the jobs held")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: catcher submit [--context name] URL...")
+		os.Exit(2)
+	}
+
+	name, c := resolveContext("catcher submit", path, name)
+	client := remote.NewClient(c)
+	failed := false
+	for _, url := range fs.Args() {
+		s.URL = url
+		job, err := client.Submit(context.Background(), s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s: %v\n", name, url, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: queued %s as job %d\n", name, url, job.ID)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runWatchClipboard handles "catcher watch-clipboard": it submits the URLs
+// copied on this machine that match the context's clipboard pattern to its
+// server, asking first for each unless --auto is given.
+func runWatchClipboard(args []string) {
+	var path, name, pattern string
+	var auto bool
+	var interval time.Duration
+	var s remote.Submission
+	fs := flag.NewFlagSet("catcher watch-clipboard", flag.ExitOnError)
+	fs.StringVar(&name, "context", os.Getenv("CATCHER_CONTEXT"), "Context to submit to (default the current one)")
+	fs.StringVar(&path, "file", remote.DefaultPath(), "Contexts file path")
+	fs.StringVar(&pattern, "match", "", "Pattern of the URLs to submit (default the context's clipboard pattern)")
+	fs.BoolVar(&auto, "auto", false, "Submit matching URLs without asking")
+	fs.DurationVar(&interval, "interval", time.Second, "How often to check the clipboard")
+	fs.StringVar(&s.Source, "source", "", "Source of the jobs (default the context's, else clipboard)")
+	fs.BoolVar(&s.Hold, "hold", false, "Queue the jobs held")
+	fs.Parse(args)
+	if fs.NArg() != 0 || interval <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: catcher watch-clipboard [--context name] [--match REGEXP] [--auto] [--interval 1s]")
+		os.Exit(2)
+	}
+
+	name, c := resolveContext("catcher watch-clipboard", path, name)
+	if pattern == "" {
+		pattern = c.Clipboard
+	}
+	if s.Source == "" && c.Source == "" {
+		s.Source = "clipboard"
+	}
+	read, err := remote.SystemClipboard()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "catcher watch-clipboard: %v\n", err)
+		os.Exit(1)
+	}
+	watcher, err := remote.NewClipboardWatcher(read, interval, pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "catcher watch-clipboard: invalid pattern: %v\n", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client := remote.NewClient(c)
+	// Answers are read apart so Ctrl-C works at the prompt
+	answers := make(chan string)
+	if !auto {
+		go func() {
+			sc := bufio.NewScanner(os.Stdin)
+			for sc.Scan() {
+				answers <- sc.Text()
+			}
+			close(answers)
+		}()
+	}
+	if pattern == "" {
+		pattern = "any URL"
+	}
+	fmt.Printf("%s: watching the clipboard for %s; Ctrl-C stops\n", name, pattern)
+	watcher.Watch(ctx, func(url string) {
+		if !auto {
+			fmt.Printf("submit %s to %s? [Y/n] ", url, name)
+			var answer string
+			select {
+			case a, ok := <-answers:
+				if !ok {
+					stop() // stdin closed
+					return
+				}
+				answer = strings.ToLower(strings.TrimSpace(a))
+			case <-ctx.Done():
+				fmt.Println()
+				return
+			}
+			if answer != "" && answer != "y" && answer != "yes" {
+				return
+			}
+		}
+		s.URL = url
+		job, err := client.Submit(ctx, s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s: %v\n", name, url, err)
+			return
+		}
+		fmt.Printf("%s: queued %s as job %d\n", name, url, job.ID)
+	}, func(err error) {
+		fmt.Fprintf(os.Stderr, "read clipboard: %v\n", err)
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/setup"
+)
+
+// runInstallService handles "catcher install-service [flags]": it installs
+// and starts a user service running catcher with the same flags.
+func runInstallService(args []string) {
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := installService(cfg); err != nil {
+		log.Fatalf("install service: %v", err)
+	}
+}
+
+// installService writes a systemd unit or launchd agent reproducing cfg's
+// runtime settings, then starts it.
+func installService(cfg *config.Config) error {
+	bin, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate catcher binary: %w", err)
+	}
+	args, err := cfg.ServiceArgs()
+	if err != nil {
+		return err
+	}
+	// Services start with a minimal PATH, which would hide processor commands
+	env := map[string]string{"PATH": os.Getenv("PATH")}
+	svc, err := setup.NewService(runtime.GOOS, append([]string{bin}, args...), env)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.Write(); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", svc.Path)
+	for _, key := range cfg.EnvOnlySettings() {
+		setting := key
+		if key == "db_key" {
+			setting = "db_key_file"
+		}
+		fmt.Printf("warning: %s is set by CATCHER_%s, which the service won't see; set %s in %s\n",
+			key, strings.ToUpper(key), setting, cfg.ConfigPath)
+	}
+
+	if err := svc.Start(); err != nil {
+		fmt.Printf("could not start the service (%v); run:\n", err)
+		for _, cmd := range svc.StartCommands() {
+			fmt.Printf("  %s\n", strings.Join(cmd, " "))
+		}
+		return nil
+	}
+	fmt.Printf("started catcher %s service; %s\n", svc.Manager, svc.LogHint())
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "context"
+
+// watchControlSignals is a no-op where SIGUSR1 and SIGUSR2 don't exist.
+func watchControlSignals(ctx context.Context, status func()) {}
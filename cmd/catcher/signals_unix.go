@@ -0,0 +1,36 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/cwygoda/catcher/internal/logging"
+)
+
+// watchControlSignals handles operational signals until ctx is done:
+// SIGUSR1 logs a status summary, SIGUSR2 toggles debug logging.
+func watchControlSignals(ctx context.Context, status func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				switch sig {
+				case syscall.SIGUSR1:
+					status()
+				case syscall.SIGUSR2:
+					log.Printf("debug logging %s", onOff(logging.ToggleDebug()))
+				}
+			}
+		}
+	}()
+}
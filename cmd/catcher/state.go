@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/cwygoda/catcher/internal/adapter/sqlite"
+	"github.com/cwygoda/catcher/internal/config"
+)
+
+// runExportState handles "catcher export-state": it writes the queue and
+// everything kept about it, but no media files, as one JSON snapshot.
+func runExportState(args []string) {
+	var configPath, dbPath, out string
+	fs := flag.NewFlagSet("catcher export-state", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", config.DefaultConfigPath(), "Config file path")
+	fs.StringVar(&dbPath, "db", "", "SQLite database path (default from config)")
+	fs.StringVar(&out, "o", "-", "File to write the snapshot to, - for stdout")
+	fs.Parse(args)
+
+	repo, _ := openDatabase(configPath, dbPath)
+	defer repo.Close()
+
+	w := io.Writer(os.Stdout)
+	var f *os.File
+	if out != "-" {
+		var err error
+		if f, err = os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600); err != nil {
+			log.Fatalf("export-state: %v", err)
+		}
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	err := repo.ExportState(context.Background(), bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if f != nil {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(out)
+		}
+	}
+	if err != nil {
+		log.Fatalf("export-state: %v", err)
+	}
+	if f != nil {
+		fmt.Fprintf(os.Stderr, "wrote %s\n", out)
+	}
+}
+
+// runImportState handles "catcher import-state": it loads a snapshot from
+// export-state into a new database, keeping job IDs.
+func runImportState(args []string) {
+	var configPath, dbPath string
+	fs := flag.NewFlagSet("catcher import-state", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", config.DefaultConfigPath(), "Config file path")
+	fs.StringVar(&dbPath, "db", "", "SQLite database path (default from config)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: catcher import-state [--config PATH] [--db PATH] FILE|-")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	r := io.Reader(os.Stdin)
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatalf("import-state: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	repo, _ := openDatabase(configPath, dbPath)
+	defer repo.Close()
+	n, err := repo.ImportState(context.Background(), bufio.NewReader(r))
+	switch {
+	case errors.Is(err, sqlite.ErrNotEmpty):
+		log.Fatalf("import-state: %v; import into a new database", err)
+	case errors.Is(err, sqlite.ErrKeyRequired), errors.Is(err, sqlite.ErrWrongKey):
+		log.Fatalf("import-state: %v; configure the key the snapshot's database used", err)
+	case err != nil:
+		log.Fatalf("import-state: %v", err)
+	}
+	fmt.Printf("imported %d job(s)\n", n)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/logging"
+	"github.com/cwygoda/catcher/internal/worker"
+)
+
+// maxStatusJobs caps how many processing jobs a status summary lists.
+const maxStatusJobs = 20
+
+// logStatus writes a summary of the queue and process to the log. w is nil
+// when this process runs no worker.
+func logStatus(ctx context.Context, counter domain.JobCounter, svc *domain.JobService, w *worker.Worker, started time.Time) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	log.Printf("status: uptime %s, %d goroutine(s), heap %d MiB, debug logging %s",
+		time.Since(started).Round(time.Second), runtime.NumGoroutine(), mem.HeapAlloc>>20, onOff(logging.Debug()))
+
+	if w != nil {
+		log.Printf("status: %d job(s) in flight in this worker", w.InFlight())
+	}
+
+	counts, err := counter.CountByStatus(ctx)
+	if err != nil {
+		log.Printf("status: count jobs: %v", err)
+		return
+	}
+	log.Printf("status: queue %d awaiting approval, %d pending, %d processing, %d completed, %d failed",
+		counts[domain.StatusNeedsApproval], counts[domain.StatusPending], counts[domain.StatusProcessing], counts[domain.StatusCompleted], counts[domain.StatusFailed])
+
+	jobs, err := svc.List(ctx, domain.JobFilter{Status: domain.StatusProcessing, Limit: maxStatusJobs})
+	if err != nil {
+		log.Printf("status: list processing jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		log.Printf("status: job %d processing for %s (attempt %d): %s",
+			job.ID, time.Since(job.UpdatedAt).Round(time.Second), job.Attempts, job.URL)
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,124 @@
+// Package connectivity checks the uplink by sending HEAD requests to a
+// known endpoint.
+package connectivity
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// Defaults for zero Monitor settings.
+const (
+	DefaultInterval = 30 * time.Second
+	DefaultTimeout  = 10 * time.Second
+)
+
+// Monitor is a domain.ConnectivityMonitor. Any response from its target
+// counts as online, whatever its status, since getting one takes a
+// working uplink; only failed requests count as offline.
+type Monitor struct {
+	target   string
+	interval time.Duration
+	client   *http.Client
+	clock    domain.Clock
+
+	mu    sync.Mutex
+	state domain.Connectivity
+}
+
+// New returns a Monitor sending HEAD requests to target every interval,
+// each given timeout to answer. Zero durations take the defaults. Until
+// the first check, it reports online.
+func New(target string, interval, timeout time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Monitor{
+		target:   target,
+		interval: interval,
+		client:   &http.Client{Timeout: timeout},
+		clock:    domain.SystemClock,
+		state:    domain.Connectivity{Online: true, Target: target},
+	}
+}
+
+// SetClock makes check times follow c, for tests. Call before Run.
+func (m *Monitor) SetClock(c domain.Clock) {
+	m.clock = c
+}
+
+// Run checks now and then every interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	log.Printf("checking connectivity to %s every %s", m.target, m.interval)
+	ticker := m.clock.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		m.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Connectivity implements domain.ConnectivityMonitor.
+func (m *Monitor) Connectivity() domain.Connectivity {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// Check implements domain.ConnectivityMonitor. A check cut short by ctx
+// leaves the state as it was.
+func (m *Monitor) Check(ctx context.Context) domain.Connectivity {
+	err := m.head(ctx)
+	if ctx.Err() != nil {
+		return m.Connectivity()
+	}
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	online := err == nil
+	switch {
+	case m.state.CheckedAt.IsZero():
+		m.state.Since = now
+		if !online {
+			log.Printf("connectivity: offline: %v", err)
+		}
+	case online != m.state.Online:
+		if online {
+			log.Printf("connectivity: back online after %s", now.Sub(m.state.Since).Round(time.Second))
+		} else {
+			log.Printf("connectivity: offline: %v", err)
+		}
+		m.state.Since = now
+	}
+	m.state.Online, m.state.CheckedAt, m.state.Error = online, now, ""
+	if err != nil {
+		m.state.Error = err.Error()
+	}
+	return m.state
+}
+
+// head sends one HEAD request to the target.
+func (m *Monitor) head(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, m.target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
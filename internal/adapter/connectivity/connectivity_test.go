@@ -0,0 +1,60 @@
+package connectivity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestMonitor_Check(t *testing.T) {
+	var methods []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := New(ts.URL, 0, time.Second)
+	m.SetClock(clock)
+	ctx := context.Background()
+
+	if st := m.Connectivity(); !st.Online || !st.CheckedAt.IsZero() {
+		t.Errorf("before the first check = %+v, want online and unchecked", st)
+	}
+	st := m.Check(ctx)
+	start := clock.Now()
+	if !st.Online || !st.Since.Equal(start) || !st.CheckedAt.Equal(start) || st.Target != ts.URL {
+		t.Errorf("Check() = %+v, want online since now", st)
+	}
+	if len(methods) != 1 || methods[0] != http.MethodHead {
+		t.Errorf("requests = %v, want one HEAD", methods)
+	}
+
+	// Staying online keeps the time it came online
+	clock.Advance(time.Minute)
+	if st := m.Check(ctx); !st.Since.Equal(start) || !st.CheckedAt.Equal(clock.Now()) {
+		t.Errorf("Check() = %+v, want online since %s", st, start)
+	}
+
+	ts.Close()
+	clock.Advance(time.Minute)
+	st = m.Check(ctx)
+	if st.Online || !st.Since.Equal(clock.Now()) || st.Error == "" {
+		t.Errorf("Check() with the target down = %+v, want offline since now, with the error", st)
+	}
+	if got := m.Connectivity(); got != st {
+		t.Errorf("Connectivity() = %+v, want the last check %+v", got, st)
+	}
+}
+
+func TestMonitor_CheckCancelled(t *testing.T) {
+	m := New("http://127.0.0.1:1", time.Second, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if st := m.Check(ctx); !st.Online || !st.CheckedAt.IsZero() {
+		t.Errorf("cancelled Check() = %+v, want the state left as it was", st)
+	}
+}
@@ -0,0 +1,348 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// ProcessorTester runs a processor against a URL without persisting a job.
+// name selects the processor; empty means the first one matching url.
+type ProcessorTester interface {
+	TestProcessor(ctx context.Context, name, url string, out io.Writer) (string, error)
+}
+
+// maxTestDuration bounds a test run so a hung command can't pin a request.
+const maxTestDuration = 10 * time.Minute
+
+// SetAdminToken enables /admin endpoints for requests bearing token.
+// Without a token, admin endpoints are refused.
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SetProcessorTester serves POST /admin/test-processor using t.
+func (s *Server) SetProcessorTester(t ProcessorTester) {
+	s.mux.Handle("POST /admin/test-processor", s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		s.handleTestProcessor(w, r, t)
+	}))
+}
+
+// SetEffectiveConfig serves GET /admin/config, the merged configuration as
+// written by write. write must redact secrets.
+func (s *Server) SetEffectiveConfig(write func(io.Writer) error) {
+	s.mux.Handle("GET /admin/config", s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := write(&buf); err != nil {
+			log.Printf("failed to render config: %v", err)
+			s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+			return
+		}
+		w.Header().Set("Content-Type", "application/toml; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(buf.Bytes())
+	}))
+}
+
+// requireAdmin rejects requests without the admin bearer token.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			s.writeError(w, http.StatusForbidden, CodeForbidden, "admin endpoints are disabled")
+			return
+		}
+		if !s.isAdmin(r) {
+			s.writeError(w, http.StatusUnauthorized, CodeUnauthorized, "admin token required")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// isAdmin reports whether r carries the admin token.
+func (s *Server) isAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// completeJobRequest is the optional request body for POST /jobs/{id}/complete.
+type completeJobRequest struct {
+	By   string `json:"by"`
+	Note string `json:"note"`
+}
+
+// handleCompleteJob marks a job done without processing it, e.g. after it
+// was downloaded by hand, so it stops being retried.
+func (s *Server) handleCompleteJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+	var req completeJobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)).Decode(&req); err != nil && err != io.EOF {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+		return
+	}
+	if req.By == "" {
+		req.By = "admin"
+	}
+
+	job, err := s.svc.CompleteManually(r.Context(), id, req.By, req.Note)
+	switch {
+	case errors.Is(err, domain.ErrJobNotFound):
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+		return
+	case errors.Is(err, domain.ErrJobState):
+		s.writeError(w, http.StatusConflict, CodeConflict, "only pending, failed, or unapproved jobs can be completed manually")
+		return
+	case err != nil && job == nil:
+		log.Printf("complete job error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	case err != nil:
+		log.Printf("job %d: record manual completion: %v", id, err)
+	}
+
+	log.Printf("job %d: completed manually by %s (request %s)", id, req.By, requestIDFrom(r.Context()))
+	s.writeJSON(w, http.StatusOK, s.jobToResponse(job))
+}
+
+// handleHoldJob holds a pending job back from the worker, or releases it.
+func (s *Server) handleHoldJob(hold bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := s.jobID(w, r)
+		if !ok {
+			return
+		}
+
+		setHeld, action := s.svc.Release, "released"
+		if hold {
+			setHeld, action = s.svc.Hold, "held"
+		}
+		job, err := setHeld(r.Context(), id)
+		switch {
+		case errors.Is(err, domain.ErrJobNotFound):
+			s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+			return
+		case errors.Is(err, domain.ErrJobState):
+			s.writeError(w, http.StatusConflict, CodeConflict, "only pending jobs can be held or released")
+			return
+		case err != nil:
+			log.Printf("set held error: %v", err)
+			s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+			return
+		}
+
+		log.Printf("job %d: %s (request %s)", id, action, requestIDFrom(r.Context()))
+		s.writeJSON(w, http.StatusOK, s.jobToResponse(job))
+	}
+}
+
+// editJobRequest is the request body for PATCH /jobs/{id}. Notes are the
+// only editable field.
+type editJobRequest struct {
+	Notes *string `json:"notes"`
+}
+
+// handleEditJob changes a job's notes, in any state.
+func (s *Server) handleEditJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+	var req editJobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+		return
+	}
+	if req.Notes == nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "notes is required")
+		return
+	}
+
+	job, err := s.svc.SetNotes(r.Context(), id, *req.Notes)
+	switch {
+	case errors.Is(err, domain.ErrJobNotFound):
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+		return
+	case errors.Is(err, domain.ErrNotesTooLong):
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("notes must be at most %d characters", domain.MaxNotesLength))
+		return
+	case err != nil:
+		log.Printf("set notes error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	log.Printf("job %d: notes edited (request %s)", id, requestIDFrom(r.Context()))
+	s.writeJSON(w, http.StatusOK, s.jobToResponse(job))
+}
+
+// rejectJobRequest is the optional request body for POST /jobs/{id}/reject.
+type rejectJobRequest struct {
+	Reason string `json:"reason"`
+}
+
+// handleApproveJob queues a job that was awaiting approval.
+func (s *Server) handleApproveJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+	job, err := s.svc.Approve(r.Context(), id)
+	if !s.writeApprovalError(w, err) {
+		return
+	}
+	log.Printf("job %d: approved (request %s)", id, requestIDFrom(r.Context()))
+	s.writeJSON(w, http.StatusOK, s.jobToResponse(job))
+}
+
+// handleRejectJob fails a job that was awaiting approval without processing it.
+func (s *Server) handleRejectJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+	var req rejectJobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)).Decode(&req); err != nil && err != io.EOF {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+		return
+	}
+	job, err := s.svc.Reject(r.Context(), id, req.Reason)
+	if !s.writeApprovalError(w, err) {
+		return
+	}
+	log.Printf("job %d: rejected (request %s)", id, requestIDFrom(r.Context()))
+	s.writeJSON(w, http.StatusOK, s.jobToResponse(job))
+}
+
+// writeApprovalError writes the response for a failed approve or reject and
+// reports whether err was nil.
+func (s *Server) writeApprovalError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, domain.ErrJobNotFound):
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+	case errors.Is(err, domain.ErrJobState):
+		s.writeError(w, http.StatusConflict, CodeConflict, "job is not awaiting approval")
+	default:
+		log.Printf("approval error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+	}
+	return false
+}
+
+// bulkRequest is the optional request body for POST /jobs/requeue and
+// POST /jobs/cancel.
+type bulkRequest struct {
+	Host string `json:"host"` // empty selects every job
+}
+
+// bulkResponse reports how many jobs a bulk transition changed.
+type bulkResponse struct {
+	Count int64 `json:"count"`
+}
+
+// handleBulk moves every job the request selects with transition, such as
+// requeueing failed jobs. action describes it in logs.
+func (s *Server) handleBulk(action string, transition func(context.Context, domain.BulkFilter) (int64, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req bulkRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)).Decode(&req); err != nil && err != io.EOF {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+			return
+		}
+		n, err := transition(r.Context(), domain.BulkFilter{Host: req.Host})
+		if err != nil {
+			log.Printf("bulk transition error: %v", err)
+			s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+			return
+		}
+		scope := "all hosts"
+		if req.Host != "" {
+			scope = "host " + req.Host
+		}
+		log.Printf("%s %d job(s) for %s (request %s)", action, n, scope, requestIDFrom(r.Context()))
+		s.writeJSON(w, http.StatusOK, bulkResponse{Count: n})
+	}
+}
+
+// testProcessorRequest is the request body for POST /admin/test-processor.
+type testProcessorRequest struct {
+	Processor string `json:"processor"`
+	URL       string `json:"url"`
+}
+
+// handleTestProcessor streams a processor's output as plain text. Once
+// output starts the status is 200; the last line reports the result.
+func (s *Server) handleTestProcessor(w http.ResponseWriter, r *http.Request, t ProcessorTester) {
+	var req testProcessorRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+		return
+	}
+	if req.URL == "" {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "url is required")
+		return
+	}
+
+	// Test runs outlive the server write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	ctx, cancel := context.WithTimeout(r.Context(), maxTestDuration)
+	defer cancel()
+
+	out := &streamWriter{w: w, rc: rc}
+	name, err := t.TestProcessor(ctx, req.Processor, req.URL, out)
+	if !out.started {
+		switch err {
+		case nil:
+		case domain.ErrUnknownProcessor:
+			s.writeError(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("unknown processor %q", req.Processor))
+			return
+		case domain.ErrNoProcessor:
+			s.writeError(w, http.StatusUnprocessableEntity, CodeURLRejected, err.Error())
+			return
+		case domain.ErrNotTestable:
+			s.writeError(w, http.StatusConflict, CodeConflict, fmt.Sprintf("processor %q does not support test runs", name))
+			return
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(out, "--- %s: error: %v\n", name, err)
+		return
+	}
+	fmt.Fprintf(out, "--- %s: ok\n", name)
+}
+
+// streamWriter sends each write to the client immediately.
+type streamWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	if !sw.started {
+		sw.started = true
+		h := sw.w.Header()
+		h.Set("Content-Type", "text/plain; charset=utf-8")
+		h.Set("Cache-Control", "no-cache, no-transform")
+		sw.w.WriteHeader(http.StatusOK)
+	}
+	n, err := sw.w.Write(p)
+	sw.rc.Flush()
+	return n, err
+}
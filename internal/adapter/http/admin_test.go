@@ -0,0 +1,384 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// stubTester mimics a registry with a single "echo" processor.
+type stubTester struct{}
+
+func (stubTester) TestProcessor(ctx context.Context, name, url string, out io.Writer) (string, error) {
+	switch {
+	case name == "legacy":
+		return name, domain.ErrNotTestable
+	case name != "" && name != "echo":
+		return "", domain.ErrUnknownProcessor
+	case !strings.HasPrefix(url, "https://"):
+		return "", domain.ErrNoProcessor
+	}
+	fmt.Fprintf(out, "fetching %s\n", url)
+	if strings.HasSuffix(url, "/broken") {
+		return "echo", errors.New("exit status 1")
+	}
+	return "echo", nil
+}
+
+func TestServer_TestProcessor(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		auth       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantBody   string
+	}{
+		{name: "admin disabled", body: `{"url":"https://x"}`, wantStatus: http.StatusForbidden, wantCode: CodeForbidden},
+		{name: "missing token", adminToken: "s3cret", body: `{"url":"https://x"}`, wantStatus: http.StatusUnauthorized, wantCode: CodeUnauthorized},
+		{name: "wrong token", adminToken: "s3cret", auth: "Bearer nope", body: `{"url":"https://x"}`, wantStatus: http.StatusUnauthorized, wantCode: CodeUnauthorized},
+		{name: "missing url", adminToken: "s3cret", auth: "Bearer s3cret", body: `{}`, wantStatus: http.StatusBadRequest, wantCode: CodeBadRequest},
+		{name: "unknown processor", adminToken: "s3cret", auth: "Bearer s3cret", body: `{"processor":"nope","url":"https://x"}`, wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
+		{name: "no match", adminToken: "s3cret", auth: "Bearer s3cret", body: `{"url":"ftp://x"}`, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeURLRejected},
+		{name: "not testable", adminToken: "s3cret", auth: "Bearer s3cret", body: `{"processor":"legacy","url":"https://x"}`, wantStatus: http.StatusConflict, wantCode: CodeConflict},
+		{name: "success", adminToken: "s3cret", auth: "Bearer s3cret", body: `{"url":"https://x/ok"}`, wantStatus: http.StatusOK, wantBody: "fetching https://x/ok\n--- echo: ok\n"},
+		{name: "command failure", adminToken: "s3cret", auth: "Bearer s3cret", body: `{"processor":"echo","url":"https://x/broken"}`, wantStatus: http.StatusOK, wantBody: "fetching https://x/broken\n--- echo: error: exit status 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := setupTestServer()
+			srv.SetAdminToken(tt.adminToken)
+			srv.SetProcessorTester(stubTester{})
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/test-processor", bytes.NewBufferString(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" {
+				assertErrorCode(t, rec, tt.wantCode)
+				return
+			}
+			if rec.Header().Get("Content-Encoding") != "" {
+				t.Error("streamed output must not be compressed")
+			}
+			if !rec.Flushed {
+				t.Error("output was not flushed while streaming")
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_EffectiveConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       string
+		write      func(io.Writer) error
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "missing token", write: func(w io.Writer) error { return nil }, wantStatus: http.StatusUnauthorized},
+		{name: "success", auth: "Bearer s3cret", write: func(w io.Writer) error {
+			_, err := io.WriteString(w, "port = 8080\n")
+			return err
+		}, wantStatus: http.StatusOK, wantBody: "port = 8080\n"},
+		{name: "render error", auth: "Bearer s3cret", write: func(w io.Writer) error {
+			io.WriteString(w, "partial")
+			return errors.New("boom")
+		}, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := setupTestServer()
+			srv.SetAdminToken("s3cret")
+			srv.SetEffectiveConfig(tt.write)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+// recordingAttempts keeps the attempts added to it.
+type recordingAttempts struct {
+	added []domain.Attempt
+}
+
+func (r *recordingAttempts) AddAttempt(ctx context.Context, jobID int64, a domain.Attempt) error {
+	r.added = append(r.added, a)
+	return nil
+}
+
+func (r *recordingAttempts) Attempts(ctx context.Context, jobID int64) ([]domain.Attempt, error) {
+	return r.added, nil
+}
+
+func TestServer_CompleteJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       string
+		path       string
+		body       string
+		status     domain.JobStatus
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{name: "missing token", path: "/jobs/1/complete", wantStatus: http.StatusUnauthorized, wantCode: CodeUnauthorized},
+		{name: "bad id", auth: "Bearer s3cret", path: "/jobs/x/complete", wantStatus: http.StatusBadRequest, wantCode: CodeBadRequest},
+		{name: "not found", auth: "Bearer s3cret", path: "/jobs/9/complete", wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
+		{name: "processing", auth: "Bearer s3cret", path: "/jobs/1/complete", status: domain.StatusProcessing, wantStatus: http.StatusConflict, wantCode: CodeConflict},
+		{name: "pending without body", auth: "Bearer s3cret", path: "/jobs/1/complete", wantStatus: http.StatusOK, wantDetail: "completed manually by admin"},
+		{name: "failed with note", auth: "Bearer s3cret", path: "/jobs/1/complete", body: `{"by":"chris","note":"downloaded by hand"}`, status: domain.StatusFailed, wantStatus: http.StatusOK, wantDetail: "completed manually by chris: downloaded by hand"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			svc.SetManualCompleter(repo)
+			attempts := &recordingAttempts{}
+			svc.SetAttemptRepository(attempts)
+			srv := NewServer(svc, ":8080", "")
+			srv.SetAdminToken("s3cret")
+
+			job, _ := repo.Create(context.Background(), "https://example.com/video")
+			if tt.status != "" {
+				job.Status = tt.status
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" {
+				assertErrorCode(t, rec, tt.wantCode)
+				return
+			}
+			if job.Status != domain.StatusCompleted {
+				t.Errorf("job status = %q, want completed", job.Status)
+			}
+			if len(attempts.added) != 1 || attempts.added[0].Processor != domain.ManualProcessor || attempts.added[0].Output != tt.wantDetail {
+				t.Errorf("attempts = %+v, want one manual attempt saying %q", attempts.added, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestServer_HoldJob(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetJobHolder(repo)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/webhook", `{"url":"https://example.com/big","hold":true}`); rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"held":true`) {
+		t.Fatalf("webhook with hold = %d %s, want a held job", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/jobs/1/release", ""); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"held"`) {
+		t.Errorf("release = %d %s, want the job no longer held", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/jobs/1/hold", ""); rec.Code != http.StatusOK || !repo.jobs[1].Held {
+		t.Errorf("hold = %d %s, want the job held", rec.Code, rec.Body)
+	}
+
+	repo.jobs[1].Status = domain.StatusCompleted
+	rec := do(http.MethodPost, "/jobs/1/release", "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("release of a completed job = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	assertErrorCode(t, rec, CodeConflict)
+	rec = do(http.MethodPost, "/jobs/9/hold", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("hold of a missing job = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestServer_EditJob(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetNoteEditor(repo)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/webhook", `{"url":"https://example.com/talk","notes":"for mum"}`); rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"notes":"for mum"`) {
+		t.Fatalf("webhook with notes = %d %s, want the notes shown", rec.Code, rec.Body)
+	}
+	repo.jobs[1].Status = domain.StatusCompleted
+	if rec := do(http.MethodPatch, "/jobs/1", `{"notes":"conference talk about X"}`); rec.Code != http.StatusOK || repo.jobs[1].Notes != "conference talk about X" {
+		t.Errorf("edit notes = %d %s, want them changed", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, "/jobs?fields=id,notes", ""); !strings.Contains(rec.Body.String(), `"notes":"conference talk about X"`) {
+		t.Errorf("listing = %s, want the notes shown", rec.Body)
+	}
+	if rec := do(http.MethodPatch, "/jobs/1", `{"notes":""}`); rec.Code != http.StatusOK || repo.jobs[1].Notes != "" {
+		t.Errorf("clear notes = %d %s, want them cleared", rec.Code, rec.Body)
+	}
+
+	long := `"` + strings.Repeat("x", domain.MaxNotesLength+1) + `"`
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+	}{
+		{http.MethodPatch, "/jobs/1", `{}`, http.StatusBadRequest},
+		{http.MethodPatch, "/jobs/1", `{"notes":` + long + `}`, http.StatusBadRequest},
+		{http.MethodPatch, "/jobs/9", `{"notes":"x"}`, http.StatusNotFound},
+		{http.MethodPost, "/webhook", `{"url":"https://example.com/other","notes":` + long + `}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := do(tt.method, tt.path, tt.body); rec.Code != tt.wantStatus {
+			t.Errorf("%s %s %.20s = %d, want %d", tt.method, tt.path, tt.body, rec.Code, tt.wantStatus)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/jobs/1", strings.NewReader(`{"notes":"x"}`))
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("edit without token = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestServer_BulkTransitions(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetBulkRepository(repo)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	do := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, u := range []string{"https://a.example/1", "https://a.example/2", "https://b.example/3"} {
+		repo.Create(context.Background(), u)
+	}
+	if rec := do("/jobs/cancel", `{"host":"a.example"}`); rec.Code != http.StatusOK || rec.Body.String() != `{"count":2}`+"\n" {
+		t.Errorf("cancel a.example = %d %s, want a count of 2", rec.Code, rec.Body)
+	}
+	if job := repo.jobs[1]; job.Status != domain.StatusFailed || job.Error != "cancelled" {
+		t.Errorf("job 1 = %s, %q, want failed as cancelled", job.Status, job.Error)
+	}
+	if repo.jobs[3].Status != domain.StatusPending {
+		t.Errorf("job 3 = %s, want pending", repo.jobs[3].Status)
+	}
+
+	if rec := do("/jobs/requeue", ""); rec.Code != http.StatusOK || rec.Body.String() != `{"count":2}`+"\n" {
+		t.Errorf("requeue = %d %s, want a count of 2", rec.Code, rec.Body)
+	}
+	if repo.jobs[2].Status != domain.StatusPending {
+		t.Errorf("job 2 = %s, want pending", repo.jobs[2].Status)
+	}
+
+	rec := do("/jobs/requeue", `{"host":`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("requeue with bad JSON = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	assertErrorCode(t, rec, CodeBadRequest)
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/cancel", nil)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("cancel without token = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestServer_ApproveRejectJob(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetApproval(repo, domain.MatchHosts("untrusted.example"))
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, u := range []string{"https://untrusted.example/a", "https://untrusted.example/b", "https://example.com/c"} {
+		if rec := do(http.MethodPost, "/webhook", `{"url":"`+u+`"}`); rec.Code != http.StatusCreated {
+			t.Fatalf("webhook %s = %d %s", u, rec.Code, rec.Body)
+		}
+	}
+	if rec := do(http.MethodGet, "/jobs?status=needs_approval", ""); rec.Code != http.StatusOK || strings.Count(rec.Body.String(), `"needs_approval"`) != 2 {
+		t.Errorf("list needs_approval = %d %s, want 2 jobs", rec.Code, rec.Body)
+	}
+
+	if rec := do(http.MethodPost, "/jobs/1/approve", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"pending"`) {
+		t.Errorf("approve = %d %s, want a pending job", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/jobs/2/reject", `{"reason":"not ours"}`); rec.Code != http.StatusOK || repo.jobs[2].Error != "rejected: not ours" {
+		t.Errorf("reject = %d %s, error %q", rec.Code, rec.Body, repo.jobs[2].Error)
+	}
+
+	rec := do(http.MethodPost, "/jobs/3/approve", "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("approve of a pending job = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	assertErrorCode(t, rec, CodeConflict)
+	rec = do(http.MethodPost, "/jobs/9/reject", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("reject of a missing job = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// announcementRequest is the request body for PUT /admin/announcement.
+type announcementRequest struct {
+	Message  string     `json:"message"`
+	ReadOnly bool       `json:"read_only"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// announcementResponse is the JSON form of an announcement, for GET
+// /announcement and /health.
+type announcementResponse struct {
+	Message  string `json:"message"`
+	ReadOnly bool   `json:"read_only"`
+	Active   bool   `json:"active"`
+	StartsAt string `json:"starts_at,omitempty"`
+	EndsAt   string `json:"ends_at,omitempty"`
+	SetAt    string `json:"set_at"`
+}
+
+func (s *Server) announcementToResponse(a *domain.Announcement) *announcementResponse {
+	resp := &announcementResponse{
+		Message:  a.Message,
+		ReadOnly: a.ReadOnly,
+		Active:   a.Active(s.clock.Now()),
+		SetAt:    a.SetAt.UTC().Format(time.RFC3339),
+	}
+	if !a.Start.IsZero() {
+		resp.StartsAt = a.Start.UTC().Format(time.RFC3339)
+	}
+	if !a.End.IsZero() {
+		resp.EndsAt = a.End.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+// LoadAnnouncement picks up the announcement stored before a restart.
+// Without announcements enabled in the service, there is none to load.
+func (s *Server) LoadAnnouncement(ctx context.Context) error {
+	a, err := s.svc.Announcement(ctx)
+	if errors.Is(err, domain.ErrNoAnnouncement) || errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.announcement.Store(a)
+	return nil
+}
+
+// currentAnnouncement returns the announcement, or nil if none is set or
+// its window has ended.
+func (s *Server) currentAnnouncement() *domain.Announcement {
+	a := s.announcement.Load()
+	if a == nil || a.Ended(s.clock.Now()) {
+		return nil
+	}
+	return a
+}
+
+// announce sends the announcement's message as X-Announcement with every
+// response. While a read-only window is active, it refuses requests that
+// could change anything, except those lifting the announcement.
+func (s *Server) announce(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := s.currentAnnouncement()
+		if a == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-Announcement", a.Message)
+		now := s.clock.Now()
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if a.ReadOnly && a.Active(now) && r.URL.Path != "/admin/announcement" {
+				if !a.End.IsZero() {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(a.End.Sub(now).Seconds()))))
+				}
+				s.writeError(w, http.StatusServiceUnavailable, CodeReadOnly, "read-only for maintenance: "+a.Message)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleGetAnnouncement serves the announcement, if any.
+func (s *Server) handleGetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	a := s.currentAnnouncement()
+	if a == nil {
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "no announcement")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.announcementToResponse(a))
+}
+
+// handleSetAnnouncement stores a new announcement in place of any other.
+func (s *Server) handleSetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var req announcementRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+		return
+	}
+	a := &domain.Announcement{Message: req.Message, ReadOnly: req.ReadOnly}
+	if req.StartsAt != nil {
+		a.Start = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		a.End = *req.EndsAt
+	}
+	if err := s.svc.Announce(r.Context(), a); err != nil {
+		if errors.Is(err, domain.ErrInvalidAnnouncement) {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+			return
+		}
+		log.Printf("announce error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+	s.announcement.Store(a)
+	log.Printf("announcement set: %s", a.Message)
+	s.writeJSON(w, http.StatusOK, s.announcementToResponse(a))
+}
+
+// handleClearAnnouncement removes the announcement.
+func (s *Server) handleClearAnnouncement(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.ClearAnnouncement(r.Context()); err != nil {
+		log.Printf("clear announcement error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+	s.announcement.Store(nil)
+	w.WriteHeader(http.StatusNoContent)
+}
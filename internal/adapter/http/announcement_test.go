@@ -0,0 +1,110 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// memAnnouncements keeps an announcement in memory.
+type memAnnouncements struct {
+	a *domain.Announcement
+}
+
+func (m *memAnnouncements) SaveAnnouncement(ctx context.Context, a *domain.Announcement) error {
+	m.a = a
+	return nil
+}
+
+func (m *memAnnouncements) Announcement(ctx context.Context) (*domain.Announcement, error) {
+	if m.a == nil {
+		return nil, domain.ErrNoAnnouncement
+	}
+	return m.a, nil
+}
+
+func (m *memAnnouncements) ClearAnnouncement(ctx context.Context) error {
+	m.a = nil
+	return nil
+}
+
+func TestServer_Announcement(t *testing.T) {
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := &memAnnouncements{}
+	svc := domain.NewJobService(newMockRepo())
+	svc.SetClock(clock)
+	svc.SetAnnouncementRepository(store)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetClock(clock)
+	srv.SetAdminToken("admin")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer admin")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/announcement", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /announcement before any = %d, want 404", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/admin/announcement", `{"message":""}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT without a message = %d, want 400", rec.Code)
+	}
+
+	rec := do(http.MethodPut, "/admin/announcement", `{"message":"NAS upgrade","read_only":true,"starts_at":"2030-01-01T13:00:00Z","ends_at":"2030-01-01T14:00:00Z"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT /admin/announcement = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+
+	// Scheduled: announced, but not yet read-only
+	rec = do(http.MethodPost, "/webhook", `{"url":"https://example.com/a"}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("X-Announcement") != "NAS upgrade" {
+		t.Errorf("POST /webhook before the window = %d, X-Announcement %q", rec.Code, rec.Header().Get("X-Announcement"))
+	}
+
+	clock.Advance(90 * time.Minute)
+	rec = do(http.MethodPost, "/webhook", `{"url":"https://example.com/b"}`)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1800" {
+		t.Errorf("POST /webhook in the window = %d, Retry-After %q, want 503 and 1800", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	assertErrorCode(t, rec, CodeReadOnly)
+
+	rec = do(http.MethodGet, "/health", "")
+	var health healthResponse
+	json.NewDecoder(rec.Body).Decode(&health)
+	if a := health.Announcement; a == nil || !a.Active || !a.ReadOnly || a.EndsAt != "2030-01-01T14:00:00Z" {
+		t.Errorf("health announcement = %+v, want the active window", a)
+	}
+
+	// A restarted server picks it up again
+	restarted := NewServer(svc, ":8080", "")
+	restarted.SetClock(clock)
+	if err := restarted.LoadAnnouncement(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/announcement", nil)
+	rec = httptest.NewRecorder()
+	restarted.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /announcement after restart = %d, want 200", rec.Code)
+	}
+
+	if rec := do(http.MethodDelete, "/admin/announcement", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE /admin/announcement = %d, want 204", rec.Code)
+	}
+	rec = do(http.MethodPost, "/webhook", `{"url":"https://example.com/c"}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("X-Announcement") != "" {
+		t.Errorf("POST /webhook after clearing = %d, X-Announcement %q", rec.Code, rec.Header().Get("X-Announcement"))
+	}
+	if store.a != nil {
+		t.Errorf("stored announcement = %+v, want it cleared", store.a)
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// attemptResponse describes one attempt in a diagnostic bundle.
+type attemptResponse struct {
+	Attempt    int    `json:"attempt"`
+	Processor  string `json:"processor"`
+	Command    string `json:"command,omitempty"`
+	Error      string `json:"error,omitempty"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at"`
+	Output     string `json:"output_file,omitempty"`
+}
+
+// handleJobBundle serves a zip of everything known about a job, for
+// attaching to upstream bug reports:
+//
+//	job.json                   the job record
+//	attempts.json              attempt history
+//	attempts/<n>/command.txt   rendered command line
+//	attempts/<n>/output.txt    captured command output
+func (s *Server) handleJobBundle(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := s.svc.Get(r.Context(), id)
+	if err != nil {
+		if err == domain.ErrJobNotFound {
+			s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+			return
+		}
+		log.Printf("bundle job error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+	attempts, err := s.svc.Attempts(r.Context(), id)
+	if err != nil {
+		log.Printf("bundle attempts error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catcher-job-%d.zip"`, id))
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	history := make([]attemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		dir := fmt.Sprintf("attempts/%d/", a.Number)
+		ar := attemptResponse{
+			Attempt:    a.Number,
+			Processor:  a.Processor,
+			Command:    a.Command,
+			Error:      a.Error,
+			StartedAt:  a.StartedAt.UTC().Format(time.RFC3339),
+			FinishedAt: a.FinishedAt.UTC().Format(time.RFC3339),
+		}
+		if a.Command != "" {
+			writeZipFile(zw, dir+"command.txt", []byte(a.Command+"\n"))
+		}
+		if a.Output != "" {
+			ar.Output = dir + "output.txt"
+			writeZipFile(zw, ar.Output, []byte(a.Output))
+		}
+		history = append(history, ar)
+	}
+
+	writeZipJSON(zw, "job.json", s.jobToResponse(job))
+	writeZipJSON(zw, "attempts.json", history)
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	writeZipFile(zw, name, append(data, '\n'))
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return
+	}
+	f.Write(data)
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+type stubAttempts []domain.Attempt
+
+func (s stubAttempts) AddAttempt(ctx context.Context, jobID int64, a domain.Attempt) error {
+	return nil
+}
+
+func (s stubAttempts) Attempts(ctx context.Context, jobID int64) ([]domain.Attempt, error) {
+	return s, nil
+}
+
+func TestServer_JobBundle(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetAttemptRepository(stubAttempts{
+		{Number: 1, Processor: "youtube", Command: "yt-dlp https://youtube.com/watch?v=x", Output: "ERROR: unavailable\n", Error: "exit status 1", StartedAt: time.Now(), FinishedAt: time.Now()},
+		{Number: 2, Processor: "youtube", StartedAt: time.Now(), FinishedAt: time.Now()},
+	})
+	srv := NewServer(svc, ":8080", "")
+	job, _ := repo.Create(context.Background(), "https://youtube.com/watch?v=x")
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/1/bundle", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, zip must not be compressed again", got)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	if files["attempts/1/output.txt"] != "ERROR: unavailable\n" {
+		t.Errorf("output.txt = %q", files["attempts/1/output.txt"])
+	}
+	if files["attempts/1/command.txt"] != "yt-dlp https://youtube.com/watch?v=x\n" {
+		t.Errorf("command.txt = %q", files["attempts/1/command.txt"])
+	}
+	if _, ok := files["attempts/2/output.txt"]; ok {
+		t.Error("attempt without output has an output.txt")
+	}
+
+	var gotJob jobResponse
+	if err := json.Unmarshal([]byte(files["job.json"]), &gotJob); err != nil || gotJob.ID != job.ID {
+		t.Errorf("job.json = %q (%v)", files["job.json"], err)
+	}
+	var history []attemptResponse
+	if err := json.Unmarshal([]byte(files["attempts.json"]), &history); err != nil || len(history) != 2 {
+		t.Fatalf("attempts.json = %q (%v)", files["attempts.json"], err)
+	}
+	if history[0].Output != "attempts/1/output.txt" || history[0].Error != "exit status 1" {
+		t.Errorf("attempts[0] = %+v", history[0])
+	}
+}
+
+func TestServer_JobBundle_NotFound(t *testing.T) {
+	srv := setupTestServer()
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/99/bundle", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	assertErrorCode(t, rec, CodeNotFound)
+}
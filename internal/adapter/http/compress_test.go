@@ -0,0 +1,104 @@
+package http
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip", want: "gzip"},
+		{header: "deflate, gzip", want: "gzip"},
+		{header: "deflate", want: "deflate"},
+		{header: "gzip;q=0, deflate", want: "deflate"},
+		{header: "br", want: ""},
+		{header: "*", want: "gzip"},
+		{header: "gzip;q=0, *", want: "deflate"},
+		{header: "identity", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"url":"https://example.com/some/long/path"}`, 100)
+	small := `{"status":"ok"}`
+
+	handler := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/large":
+			io.WriteString(w, large)
+		case "/small":
+			io.WriteString(w, small)
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		}
+	}))
+
+	tests := []struct {
+		name         string
+		path         string
+		accept       string
+		wantEncoding string
+		wantBody     string
+	}{
+		{name: "gzip large", path: "/large", accept: "gzip", wantEncoding: "gzip", wantBody: large},
+		{name: "deflate large", path: "/large", accept: "deflate", wantEncoding: "deflate", wantBody: large},
+		{name: "small uncompressed", path: "/small", accept: "gzip", wantEncoding: "", wantBody: small},
+		{name: "no accept-encoding", path: "/large", accept: "", wantEncoding: "", wantBody: large},
+		{name: "not modified", path: "/not-modified", accept: "gzip", wantEncoding: "", wantBody: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want %q", got, "Accept-Encoding")
+			}
+
+			var body io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				gr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("gzip.NewReader() error = %v", err)
+				}
+				body = gr
+			case "deflate":
+				body = flate.NewReader(rec.Body)
+			}
+			data, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("read body error = %v", err)
+			}
+			if string(data) != tt.wantBody {
+				t.Errorf("body length = %d, want %d", len(data), len(tt.wantBody))
+			}
+		})
+	}
+}
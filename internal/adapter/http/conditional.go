@@ -0,0 +1,68 @@
+package http
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// jobETag derives a weak validator from a job's identity and last update.
+func jobETag(id int64, updatedAt time.Time) string {
+	return fmt.Sprintf(`W/"%d-%d"`, id, updatedAt.UnixNano())
+}
+
+// listETag derives a weak validator for a job listing. It changes whenever
+// a job enters, leaves, or changes within the page. Listings get no
+// Last-Modified: the newest updated_at can go backwards when jobs leave.
+func listETag(jobs []domain.Job) string {
+	h := fnv.New64a()
+	for _, job := range jobs {
+		fmt.Fprintf(h, "%d-%d;", job.ID, job.UpdatedAt.UnixNano())
+	}
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+// notModified sets caching headers and reports whether the client's cached
+// copy is still current, in which case a 304 has already been written.
+// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2).
+func notModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "no-cache")
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err == nil && !modified.Truncate(time.Second).After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches performs weak comparison of etag against an If-None-Match list.
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2024, 1, 15, 10, 30, 0, 500, time.UTC)
+	etag := jobETag(1, modified)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "no validators", want: false},
+		{name: "matching etag", headers: map[string]string{"If-None-Match": etag}, want: true},
+		{name: "strong form of weak etag", headers: map[string]string{"If-None-Match": etag[2:]}, want: true},
+		{name: "etag in list", headers: map[string]string{"If-None-Match": `"other", ` + etag}, want: true},
+		{name: "wildcard", headers: map[string]string{"If-None-Match": "*"}, want: true},
+		{name: "stale etag", headers: map[string]string{"If-None-Match": `W/"1-0"`}, want: false},
+		{
+			name:    "etag takes precedence over date",
+			headers: map[string]string{"If-None-Match": `W/"1-0"`, "If-Modified-Since": modified.Format(http.TimeFormat)},
+			want:    false,
+		},
+		{name: "not modified since", headers: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, want: true},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": modified.Add(-time.Minute).Format(http.TimeFormat)}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/jobs/1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			if got := notModified(rec, req, etag, modified); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+			if rec.Header().Get("ETag") != etag {
+				t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), etag)
+			}
+			if tt.want && rec.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNotModified)
+			}
+		})
+	}
+}
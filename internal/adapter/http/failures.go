@@ -0,0 +1,64 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default and largest window, in days, for GET /stats/failures.
+const (
+	defaultFailureDays = 7
+	maxFailureDays     = 365
+)
+
+// failuresResponse is the JSON response for GET /stats/failures.
+type failuresResponse struct {
+	Since  string         `json:"since"`
+	Days   int            `json:"days"`
+	Causes []failureCount `json:"causes"`
+}
+
+type failureCount struct {
+	Cause    string `json:"cause"`
+	Attempts int64  `json:"attempts"`
+	Jobs     int64  `json:"jobs"`
+	Example  string `json:"example,omitempty"`
+}
+
+// handleFailures reports failed attempts and jobs of the last days days by
+// cause, most frequent first.
+func (s *Server) handleFailures(w http.ResponseWriter, r *http.Request) {
+	days := defaultFailureDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxFailureDays {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid days: must be 1 to "+strconv.Itoa(maxFailureDays))
+			return
+		}
+		days = n
+	}
+
+	report, err := s.svc.FailureReport(r.Context(), s.clock.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("failure report error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	resp := failuresResponse{
+		Since:  report.Since.UTC().Format(time.RFC3339),
+		Days:   days,
+		Causes: make([]failureCount, 0, len(report.Causes)),
+	}
+	for _, c := range report.Causes {
+		resp.Causes = append(resp.Causes, failureCount{
+			Cause:    string(c.Cause),
+			Attempts: c.Attempts,
+			Jobs:     c.Jobs,
+			Example:  c.Example,
+		})
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
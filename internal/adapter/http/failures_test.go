@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// failuresStub lists fixed failures, leaving the window to the caller.
+type failuresStub []domain.Failure
+
+func (f failuresStub) ListFailures(ctx context.Context, since time.Time) ([]domain.Failure, error) {
+	var failures []domain.Failure
+	for _, fail := range f {
+		if !fail.At.Before(since) {
+			failures = append(failures, fail)
+		}
+	}
+	return failures, nil
+}
+
+func setupFailuresServer() *Server {
+	svc := domain.NewJobService(newMockRepo())
+	svc.SetFailureLister(failuresStub{
+		{JobID: 1, Error: "connection reset by peer", At: time.Now().Add(-time.Hour)},
+		{JobID: 2, Error: "HTTP Error 403: Forbidden", At: time.Now().Add(-10 * 24 * time.Hour), Final: true},
+	})
+	return NewServer(svc, ":8080", "")
+}
+
+func TestServer_Failures(t *testing.T) {
+	srv := setupFailuresServer()
+	tests := []struct {
+		query string
+		first failureCount
+	}{
+		{"", failureCount{Cause: "network", Attempts: 1, Example: "connection reset by peer"}},
+		{"?days=30", failureCount{Cause: "auth", Jobs: 1, Example: "HTTP Error 403: Forbidden"}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/failures"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, want %d", tt.query, rec.Code, http.StatusOK)
+		}
+		var resp failuresResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if len(resp.Causes) != len(domain.FailureCauses) || resp.Causes[0] != tt.first {
+			t.Errorf("%q: causes = %+v, want all causes led by %+v", tt.query, resp.Causes, tt.first)
+		}
+	}
+}
+
+func TestServer_Failures_InvalidDays(t *testing.T) {
+	srv := setupFailuresServer()
+	for _, days := range []string{"0", "-1", "366", "week"} {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/failures?days="+days, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("days=%s: status = %d, want %d", days, rec.Code, http.StatusBadRequest)
+		}
+		assertErrorCode(t, rec, CodeBadRequest)
+	}
+}
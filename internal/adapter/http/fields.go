@@ -0,0 +1,57 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// jobFields lists the selectable JSON fields of jobResponse.
+var jobFields = []string{"id", "uid", "url", "original_url", "status", "attempts", "error", "title", "bytes", "duration_ms", "duration_seconds", "files", "held", "approved", "queue", "source", "notes", "bookmark", "redownload_of", "user_agent", "next_attempt_at", "created_at", "updated_at", "age_seconds"}
+
+// compactFields is the field set used by ?compact=true.
+var compactFields = []string{"id", "url", "status", "attempts"}
+
+// parseFields reads ?fields= and ?compact= from the query. A nil result means
+// all fields.
+func parseFields(q url.Values) ([]string, error) {
+	if raw := q.Get("fields"); raw != "" {
+		var fields []string
+		for _, f := range strings.Split(raw, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			if !slices.Contains(jobFields, f) {
+				return nil, fmt.Errorf("unknown field %q", f)
+			}
+			fields = append(fields, f)
+		}
+		return fields, nil
+	}
+	if q.Get("compact") == "true" || q.Get("compact") == "1" {
+		return compactFields, nil
+	}
+	return nil, nil
+}
+
+// selectFields projects a job response onto the given fields.
+// With nil fields the response is returned unchanged.
+func selectFields(resp jobResponse, fields []string) any {
+	if fields == nil {
+		return resp
+	}
+	data, _ := json.Marshal(resp)
+	var all map[string]any
+	json.Unmarshal(data, &all)
+
+	selected := make(map[string]any, len(fields))
+	for _, f := range fields {
+		if v, ok := all[f]; ok {
+			selected[f] = v
+		}
+	}
+	return selected
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/event"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "all fields", query: "", want: nil},
+		{name: "selected", query: "fields=id,status,url", want: []string{"id", "status", "url"}},
+		{name: "whitespace and empties", query: "fields=id,+status,", want: []string{"id", "status"}},
+		{name: "compact", query: "compact=true", want: compactFields},
+		{name: "fields override compact", query: "compact=true&fields=id", want: []string{"id"}},
+		{name: "unknown field", query: "fields=id,secret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := parseFields(q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	resp := jobResponse{Job: event.Job{ID: 1, URL: "https://example.com", Status: "failed", Error: "boom"}}
+
+	got, ok := selectFields(resp, []string{"id", "status"}).(map[string]any)
+	if !ok {
+		t.Fatal("selectFields() did not return a map")
+	}
+	if len(got) != 2 {
+		t.Errorf("len = %d, want 2: %v", len(got), got)
+	}
+	if got["status"] != "failed" {
+		t.Errorf("status = %v, want %q", got["status"], "failed")
+	}
+	if _, ok := got["error"]; ok {
+		t.Error("unselected field error present")
+	}
+
+	if _, ok := selectFields(resp, nil).(jobResponse); !ok {
+		t.Error("selectFields(nil) should return the full response")
+	}
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// fileCheckResponse is the JSON response for GET /integrity.
+type fileCheckResponse struct {
+	CheckedAt string      `json:"checked_at"`
+	Since     string      `json:"since"`
+	Jobs      int         `json:"jobs"`
+	Files     int         `json:"files"`
+	Issues    []fileIssue `json:"issues"`
+}
+
+type fileIssue struct {
+	JobID         int64  `json:"job_id"`
+	Path          string `json:"path"`
+	Problem       string `json:"problem"`
+	RecordedBytes int64  `json:"recorded_bytes"`
+	Bytes         int64  `json:"bytes"`
+	Error         string `json:"error,omitempty"`
+}
+
+// handleFileCheck serves the latest check of completed jobs' files
+// against the disk.
+func (s *Server) handleFileCheck(w http.ResponseWriter, r *http.Request) {
+	check, err := s.svc.LastFileCheck(r.Context())
+	if errors.Is(err, domain.ErrNoFileCheck) {
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "no file check has run; set worker.verify_files")
+		return
+	}
+	if err != nil {
+		log.Printf("file check error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	resp := fileCheckResponse{
+		CheckedAt: check.CheckedAt.UTC().Format(time.RFC3339),
+		Since:     check.Since.UTC().Format(time.RFC3339),
+		Jobs:      check.Jobs,
+		Files:     check.Files,
+		Issues:    make([]fileIssue, 0, len(check.Issues)),
+	}
+	for _, issue := range check.Issues {
+		resp.Issues = append(resp.Issues, fileIssue{
+			JobID:         issue.JobID,
+			Path:          issue.Path,
+			Problem:       string(issue.Problem),
+			RecordedBytes: issue.WantBytes,
+			Bytes:         issue.GotBytes,
+			Error:         issue.Error,
+		})
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// fileCheckStub holds one stored file check.
+type fileCheckStub struct{ check *domain.FileCheck }
+
+func (s *fileCheckStub) SaveFileCheck(ctx context.Context, c *domain.FileCheck) error {
+	s.check = c
+	return nil
+}
+
+func (s *fileCheckStub) LastFileCheck(ctx context.Context) (*domain.FileCheck, error) {
+	if s.check == nil {
+		return nil, domain.ErrNoFileCheck
+	}
+	return s.check, nil
+}
+
+func TestServer_FileCheck(t *testing.T) {
+	stub := &fileCheckStub{}
+	svc := domain.NewJobService(newMockRepo())
+	svc.SetFileCheckRepository(stub)
+	srv := NewServer(svc, ":8080", "")
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/integrity", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("before any check: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	assertErrorCode(t, rec, CodeNotFound)
+
+	now := time.Now()
+	stub.check = &domain.FileCheck{CheckedAt: now, Since: now.Add(-24 * time.Hour), Jobs: 2, Files: 3, Issues: []domain.FileIssue{
+		{JobID: 7, Path: "/v/a.mp4", Problem: domain.FileSizeMismatch, WantBytes: 100, GotBytes: 40},
+	}}
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/integrity", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp fileCheckResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	want := fileIssue{JobID: 7, Path: "/v/a.mp4", Problem: "size_mismatch", RecordedBytes: 100, Bytes: 40}
+	if resp.Jobs != 2 || resp.Files != 3 || len(resp.Issues) != 1 || resp.Issues[0] != want {
+		t.Errorf("response = %+v, want 3 files of 2 jobs with %+v", resp, want)
+	}
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// File link lifetimes: the default when a request doesn't ask, and the
+// longest one may.
+const (
+	defaultFileLinkTTL = 24 * time.Hour
+	maxFileLinkTTL     = 7 * 24 * time.Hour
+)
+
+// fileLinkRequest is the optional request body for POST /jobs/{id}/share.
+type fileLinkRequest struct {
+	TTLSeconds int64 `json:"ttl_seconds"`
+}
+
+// fileLinkResponse is the JSON response for POST /jobs/{id}/share.
+type fileLinkResponse struct {
+	ExpiresAt string     `json:"expires_at"`
+	Files     []fileLink `json:"files"`
+	// ZipURL downloads every file at once, from GET /jobs/{id}/files.zip.
+	ZipURL string `json:"zip_url"`
+}
+
+// fileLink is a signed download URL for one of a job's files.
+type fileLink struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	URL   string `json:"url"`
+}
+
+// fileLinkKey signs file links. It is derived from the admin token, which
+// creating links requires anyway, so links survive restarts and changing
+// the token revokes them all.
+func (s *Server) fileLinkKey() []byte {
+	key := sha256.Sum256([]byte("file links\n" + s.adminToken))
+	return key[:]
+}
+
+// fileLinkSig signs the link to file n of job id, which is at path, until
+// exp. Signing the path keeps a link from serving whatever a later download
+// puts in the job's place.
+func (s *Server) fileLinkSig(id int64, n int, path, exp string) string {
+	mac := hmac.New(sha256.New, s.fileLinkKey())
+	fmt.Fprintf(mac, "file\n%d\n%d\n%s\n%s", id, n, path, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// zipLinkSig signs the link to the zip of all of job's files until exp.
+func (s *Server) zipLinkSig(job *domain.Job, exp string) string {
+	paths := make([]string, len(job.Files))
+	for i, f := range job.Files {
+		paths[i] = f.Path
+	}
+	return s.fileLinkSig(job.ID, -1, strings.Join(paths, "\n"), exp)
+}
+
+// validLink reports whether r's expires and sig parameters are a link
+// signature from sign that has not expired.
+func (s *Server) validLink(r *http.Request, sign func(exp string) string) bool {
+	exp, sig := r.URL.Query().Get("expires"), r.URL.Query().Get("sig")
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !s.clock.Now().Before(time.Unix(unix, 0)) {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(sign(exp)))
+}
+
+// handleShareFiles returns signed, expiring download URLs for a completed
+// job's files, to send to someone without an admin token.
+func (s *Server) handleShareFiles(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+	var req fileLinkRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)).Decode(&req); err != nil && err != io.EOF {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+		return
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	switch {
+	case req.TTLSeconds == 0:
+		ttl = defaultFileLinkTTL
+	case req.TTLSeconds < 0 || ttl > maxFileLinkTTL:
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("ttl_seconds must be between 1 and %d", int64(maxFileLinkTTL/time.Second)))
+		return
+	}
+
+	job, err := s.svc.Get(r.Context(), id)
+	switch {
+	case errors.Is(err, domain.ErrJobNotFound):
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+		return
+	case err != nil:
+		log.Printf("share files error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	case job.Status != domain.StatusCompleted || len(job.Files) == 0:
+		s.writeError(w, http.StatusConflict, CodeConflict, "only completed jobs with files can be shared")
+		return
+	}
+
+	expires := s.clock.Now().Add(ttl).Truncate(time.Second)
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	ref := job.UID
+	if ref == "" {
+		ref = strconv.FormatInt(job.ID, 10)
+	}
+	resp := fileLinkResponse{ExpiresAt: expires.UTC().Format(time.RFC3339), Files: make([]fileLink, 0, len(job.Files))}
+	for n, f := range job.Files {
+		path := fmt.Sprintf("/jobs/%s/files/%d?expires=%s&sig=%s", ref, n, exp, s.fileLinkSig(job.ID, n, f.Path, exp))
+		resp.Files = append(resp.Files, fileLink{Path: f.Path, Bytes: f.Bytes, URL: s.absoluteURL(r, path)})
+	}
+	resp.ZipURL = s.absoluteURL(r, fmt.Sprintf("/jobs/%s/files.zip?expires=%s&sig=%s", ref, exp, s.zipLinkSig(job, exp)))
+	log.Printf("job %d: shared %d files until %s (request %s)", id, len(job.Files), resp.ExpiresAt, requestIDFrom(r.Context()))
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSharedFile serves a job's file to the holder of a link from
+// POST /jobs/{id}/share.
+func (s *Server) handleSharedFile(w http.ResponseWriter, r *http.Request) {
+	// Without an admin token no links were made, and the key is guessable
+	if s.adminToken == "" {
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "file not found")
+		return
+	}
+	denied := func() {
+		s.writeError(w, http.StatusForbidden, CodeForbidden, "link is invalid or has expired")
+	}
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || n < 0 {
+		denied()
+		return
+	}
+	id, err := s.svc.ResolveID(r.Context(), r.PathValue("id"), !s.requireUID)
+	if err != nil {
+		denied()
+		return
+	}
+	job, err := s.svc.Get(r.Context(), id)
+	if err != nil || n >= len(job.Files) {
+		denied()
+		return
+	}
+	path := job.Files[n].Path
+	if !s.validLink(r, func(exp string) string { return s.fileLinkSig(job.ID, n, path, exp) }) {
+		denied()
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		s.writeError(w, http.StatusGone, CodeNotFound, "file no longer exists")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		s.writeError(w, http.StatusGone, CodeNotFound, "file no longer exists")
+		return
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(path)}))
+	// no-transform keeps the compression middleware off media and ranges
+	w.Header().Set("Cache-Control", "private, no-store, no-transform")
+	// Large files take longer than the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	http.ServeContent(w, r, filepath.Base(path), info.ModTime(), f)
+}
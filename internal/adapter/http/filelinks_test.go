@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestServer_ShareFiles(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetUIDResolver(repo)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+	srv.SetBasePath("/catcher")
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	srv.SetClock(clock)
+
+	path := filepath.Join(t.TempDir(), "clip one.mp4")
+	if err := os.WriteFile(path, []byte("video bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	repo.Create(ctx, "https://example.com/pending")
+	job, _ := repo.Create(ctx, "https://example.com/done")
+	job.Status = domain.StatusCompleted
+	job.Files = []domain.ResultFile{{Path: path, Bytes: 11}}
+
+	share := func(path, auth, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	tests := []struct {
+		name, path, auth, body string
+		wantStatus             int
+	}{
+		{"missing token", "/catcher/jobs/2/share", "", "", http.StatusUnauthorized},
+		{"not found", "/catcher/jobs/9/share", "Bearer s3cret", "", http.StatusNotFound},
+		{"not completed", "/catcher/jobs/1/share", "Bearer s3cret", "", http.StatusConflict},
+		{"ttl too long", "/catcher/jobs/2/share", "Bearer s3cret", `{"ttl_seconds": 604801}`, http.StatusBadRequest},
+		{"negative ttl", "/catcher/jobs/2/share", "Bearer s3cret", `{"ttl_seconds": -1}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := share(tt.path, tt.auth, tt.body); rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.wantStatus, rec.Body)
+		}
+	}
+
+	rec := share("/catcher/jobs/2/share", "Bearer s3cret", `{"ttl_seconds": 3600}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var resp fileLinkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ExpiresAt != "2030-01-01T13:00:00Z" || len(resp.Files) != 1 || resp.Files[0].Path != path {
+		t.Fatalf("response = %+v, want one link expiring in an hour", resp)
+	}
+	link, err := url.Parse(resp.Files[0].URL)
+	if err != nil || link.Host != "example.com" || !strings.HasPrefix(link.Path, "/catcher/jobs/"+job.UID+"/files/0") {
+		t.Fatalf("link = %q, want an absolute URL under the base path", resp.Files[0].URL)
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	rec = get(link.RequestURI())
+	if rec.Code != http.StatusOK || rec.Body.String() != "video bytes" {
+		t.Fatalf("GET link = %d %q, want the file", rec.Code, rec.Body)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="clip one.mp4"` {
+		t.Errorf("Content-Disposition = %q, want an attachment named after the file", cd)
+	}
+
+	q := link.Query()
+	q.Set("expires", "1999999999")
+	if rec := get(link.Path + "?" + q.Encode()); rec.Code != http.StatusForbidden {
+		t.Errorf("extended link status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := get(strings.Replace(link.RequestURI(), "/files/0", "/files/1", 1)); rec.Code != http.StatusForbidden {
+		t.Errorf("other file status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	// Moving the file elsewhere, as a new download would, voids the link
+	job.Files[0].Path = path + ".new"
+	if rec := get(link.RequestURI()); rec.Code != http.StatusForbidden {
+		t.Errorf("link after the file moved status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	job.Files[0].Path = path
+
+	os.Remove(path)
+	if rec := get(link.RequestURI()); rec.Code != http.StatusGone {
+		t.Errorf("deleted file status = %d, want %d", rec.Code, http.StatusGone)
+	}
+	clock.Advance(time.Hour)
+	if rec := get(link.RequestURI()); rec.Code != http.StatusForbidden {
+		t.Errorf("expired link status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestServer_SharedFileOutlivesWriteTimeout(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetUIDResolver(repo)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	path := filepath.Join(t.TempDir(), "big.mp4")
+	if err := os.WriteFile(path, make([]byte, slowDownloadBytes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job, _ := repo.Create(context.Background(), "https://example.com/big")
+	job.Status = domain.StatusCompleted
+	job.Files = []domain.ResultFile{{Path: path, Bytes: slowDownloadBytes}}
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/1/share", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	var resp fileLinkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Files) != 1 {
+		t.Fatalf("share = %d %s, want a link", rec.Code, rec.Body)
+	}
+	link, _ := url.Parse(resp.Files[0].URL)
+	if n := slowDownload(t, srv, link.RequestURI(), ""); n != slowDownloadBytes {
+		t.Errorf("downloaded %d bytes, want %d", n, slowDownloadBytes)
+	}
+}
+
+// slowDownloadBytes is more than loopback socket buffers hold, so a
+// download that isn't read stalls the server's writes.
+const slowDownloadBytes = 32 << 20
+
+// slowDownload GETs target from srv under a short write timeout, waiting
+// past it before reading, and returns how many bytes arrived.
+func slowDownload(t *testing.T, srv *Server, target, auth string) int64 {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(srv)
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+target, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d", target, resp.StatusCode, http.StatusOK)
+	}
+	time.Sleep(300 * time.Millisecond)
+	n, _ := io.Copy(io.Discard, resp.Body)
+	return n
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// handleFilesZip streams a zip of every file a completed job produced,
+// written as it is read so nothing is staged on disk. It takes the admin
+// token or a zip link from POST /jobs/{id}/share. Files are stored rather
+// than compressed, as media rarely shrinks.
+func (s *Server) handleFilesZip(w http.ResponseWriter, r *http.Request) {
+	if s.adminToken == "" {
+		s.writeError(w, http.StatusForbidden, CodeForbidden, "admin endpoints are disabled")
+		return
+	}
+	linked := r.URL.Query().Has("sig")
+	if !linked && !s.isAdmin(r) {
+		s.writeError(w, http.StatusUnauthorized, CodeUnauthorized, "admin token required")
+		return
+	}
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+	job, err := s.svc.Get(r.Context(), id)
+	switch {
+	case errors.Is(err, domain.ErrJobNotFound):
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+		return
+	case err != nil:
+		log.Printf("files zip error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+	if linked && !s.validLink(r, func(exp string) string { return s.zipLinkSig(job, exp) }) {
+		s.writeError(w, http.StatusForbidden, CodeForbidden, "link is invalid or has expired")
+		return
+	}
+	if job.Status != domain.StatusCompleted || len(job.Files) == 0 {
+		s.writeError(w, http.StatusConflict, CodeConflict, "only completed jobs with files can be downloaded")
+		return
+	}
+
+	// Check the files up front, while an error can still be a status
+	var files []domain.ResultFile
+	var infos []fs.FileInfo
+	for _, f := range job.Files {
+		info, err := os.Stat(f.Path)
+		if err != nil || !info.Mode().IsRegular() {
+			log.Printf("job %d: leaving %s out of zip: missing or not a file", id, f.Path)
+			continue
+		}
+		files = append(files, f)
+		infos = append(infos, info)
+	}
+	if len(files) == 0 {
+		s.writeError(w, http.StatusGone, CodeNotFound, "the job's files no longer exist")
+		return
+	}
+
+	ref := job.UID
+	if ref == "" {
+		ref = strconv.FormatInt(job.ID, 10)
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catcher-job-%s-files.zip"`, ref))
+	w.Header().Set("Cache-Control", "private, no-store")
+	// Large zips take longer than the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	names := zipNames(files)
+	for i, f := range files {
+		if err := writeZipEntry(zw, names[i], f.Path, infos[i]); err != nil {
+			// Too late for an error status; the client sees a truncated zip
+			log.Printf("job %d: zip %s: %v", id, f.Path, err)
+			return
+		}
+	}
+}
+
+// writeZipEntry copies the file at path into zw as name.
+func writeZipEntry(zw *zip.Writer, name, path string, info fs.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hdr := &zip.FileHeader{Name: name, Method: zip.Store, Modified: info.ModTime()}
+	hdr.SetMode(info.Mode())
+	dst, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, f)
+	return err
+}
+
+// zipNames returns the entry name of each file: its base name, numbered
+// when several files share one, as "clip (2).mp4".
+func zipNames(files []domain.ResultFile) []string {
+	names := make([]string, len(files))
+	taken := map[string]bool{}
+	for i, f := range files {
+		base := filepath.Base(f.Path)
+		ext := filepath.Ext(base)
+		name := base
+		for n := 2; taken[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(base, ext), n, ext)
+		}
+		taken[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
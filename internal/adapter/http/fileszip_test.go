@@ -0,0 +1,170 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestServer_FilesZip(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetUIDResolver(repo)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ctx := context.Background()
+	repo.Create(ctx, "https://example.com/pending")
+	job, _ := repo.Create(ctx, "https://example.com/gallery")
+	job.Status = domain.StatusCompleted
+	job.Files = []domain.ResultFile{
+		{Path: write("a/cover.jpg", "one")},
+		{Path: write("b/cover.jpg", "two")},
+		{Path: filepath.Join(dir, "gone.jpg")},
+		{Path: write("notes.txt", "three")},
+	}
+
+	get := func(target, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	tests := []struct {
+		name, path, auth string
+		wantStatus       int
+	}{
+		{"missing token", "/jobs/2/files.zip", "", http.StatusUnauthorized},
+		{"wrong token", "/jobs/2/files.zip", "Bearer nope", http.StatusUnauthorized},
+		{"forged link", "/jobs/2/files.zip?expires=1999999999&sig=x", "", http.StatusForbidden},
+		{"not found", "/jobs/9/files.zip", "Bearer s3cret", http.StatusNotFound},
+		{"not completed", "/jobs/1/files.zip", "Bearer s3cret", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if rec := get(tt.path, tt.auth); rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.wantStatus, rec.Body)
+		}
+	}
+
+	want := map[string]string{"cover.jpg": "one", "cover (2).jpg": "two", "notes.txt": "three"}
+	rec := get("/jobs/2/files.zip", "Bearer s3cret")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" || rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("status = %d, headers %v; want an uncompressed zip", rec.Code, rec.Header())
+	}
+	if got := readZip(t, rec.Body.Bytes()); !maps.Equal(got, want) {
+		t.Errorf("zip = %v, want %v without the missing file", got, want)
+	}
+
+	// The link from POST /jobs/{id}/share works without the token
+	req := httptest.NewRequest(http.MethodPost, "/jobs/2/share", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	var resp fileLinkResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	link, err := url.Parse(resp.ZipURL)
+	if err != nil || resp.ZipURL == "" {
+		t.Fatalf("zip_url = %q, want a link", resp.ZipURL)
+	}
+	rec = get(link.RequestURI(), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET zip_url status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := readZip(t, rec.Body.Bytes()); !maps.Equal(got, want) {
+		t.Errorf("linked zip = %v, want %v", got, want)
+	}
+	if rec := get("/jobs/1/files.zip?"+link.RawQuery, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("link on another job status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	for _, f := range job.Files {
+		os.Remove(f.Path)
+	}
+	if rec := get("/jobs/2/files.zip", "Bearer s3cret"); rec.Code != http.StatusGone {
+		t.Errorf("all files deleted status = %d, want %d", rec.Code, http.StatusGone)
+	}
+}
+
+func TestServer_FilesZipOutlivesWriteTimeout(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	path := filepath.Join(t.TempDir(), "big.mp4")
+	if err := os.WriteFile(path, make([]byte, slowDownloadBytes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job, _ := repo.Create(context.Background(), "https://example.com/big")
+	job.Status = domain.StatusCompleted
+	job.Files = []domain.ResultFile{{Path: path}}
+
+	if n := slowDownload(t, srv, "/jobs/1/files.zip", "Bearer s3cret"); n <= slowDownloadBytes {
+		t.Errorf("downloaded %d bytes, want the whole zip of a %d-byte file", n, slowDownloadBytes)
+	}
+}
+
+func TestServer_FilesZipDisabled(t *testing.T) {
+	srv := setupTestServer()
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/1/files.zip", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d without an admin token", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestZipNames(t *testing.T) {
+	files := []domain.ResultFile{{Path: "/a/clip.mp4"}, {Path: "/b/clip.mp4"}, {Path: "/c/Clip.mp4"}, {Path: "/d/clip (2).mp4"}, {Path: "/e/README"}}
+	want := []string{"clip.mp4", "clip (2).mp4", "Clip (3).mp4", "clip (2) (2).mp4", "README"}
+	if got := zipNames(files); !slices.Equal(got, want) {
+		t.Errorf("zipNames() = %q, want %q", got, want)
+	}
+}
+
+// readZip returns the zip's entries by name.
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	entries := map[string]string{}
+	for _, f := range zr.File {
+		if f.Method != zip.Store {
+			t.Errorf("%s is compressed, want stored", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		entries[f.Name] = string(b)
+	}
+	return entries
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// SetGrants accepts each token in grants in place of the webhook secret,
+// for submissions limited to what its grant allows. Tokens only matter
+// when a secret is set, as submissions are otherwise not authenticated.
+func (s *Server) SetGrants(grants map[string]*domain.Grant) {
+	s.grants = grants
+}
+
+// withGrant returns r with the grant of the token it carries, and true, or
+// r and false if it carries none.
+func (s *Server) withGrant(r *http.Request) (*http.Request, bool) {
+	token := credential(r)
+	if token == "" {
+		return r, false
+	}
+	var match *domain.Grant
+	for t, g := range s.grants {
+		// Compare every token so timing doesn't tell which one is close
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			match = g
+		}
+	}
+	if match == nil {
+		return r, false
+	}
+	return r.WithContext(domain.WithGrant(r.Context(), match)), true
+}
+
+// credential returns the token r carries as a bearer token, a basic auth
+// password, or the token parameter, or "".
+func credential(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	if _, token, ok := r.BasicAuth(); ok {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestServer_Grants(t *testing.T) {
+	svc := domain.NewJobService(newMockRepo())
+	svc.SetProcessorMatcher(func(url string) string {
+		if strings.HasPrefix(url, "magnet:") {
+			return "torrent"
+		}
+		return "youtube"
+	})
+	srv := NewServer(svc, ":8080", "s3cret")
+	srv.SetGrants(map[string]*domain.Grant{
+		"guest-token": {Name: "guest", Processors: []string{"youtube"}},
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		token    string
+		wantCode int
+		wantErr  string
+	}{
+		{"allowed processor", "/webhook", `{"url":"https://youtube.com/watch?v=1"}`, "guest-token", http.StatusCreated, ""},
+		{"refused processor", "/webhook", `{"url":"magnet:?xt=urn:btih:abc"}`, "guest-token", http.StatusForbidden, CodeNotPermitted},
+		{"unknown token", "/webhook", `{"url":"https://youtube.com/watch?v=2"}`, "nope", http.StatusUnauthorized, CodeUnauthorized},
+		{"shim allowed", "/webhook/ntfy", `{"click":"https://youtube.com/watch?v=3"}`, "guest-token", http.StatusCreated, ""},
+		{"shim refused", "/webhook/ntfy", `{"click":"magnet:?xt=urn:btih:def"}`, "guest-token", http.StatusForbidden, CodeNotPermitted},
+		{"shim secret unrestricted", "/webhook/ntfy", `{"click":"magnet:?xt=urn:btih:ghi"}`, "s3cret", http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantErr == CodeNotPermitted {
+				var resp struct {
+					Error struct {
+						Code    string            `json:"code"`
+						Details map[string]string `json:"details"`
+					} `json:"error"`
+				}
+				json.NewDecoder(rec.Body).Decode(&resp)
+				if resp.Error.Code != CodeNotPermitted || resp.Error.Details["token"] != "guest" || resp.Error.Details["reason"] != `processor "torrent" not allowed` {
+					t.Errorf("error = %+v, want not_permitted for guest", resp.Error)
+				}
+			} else if tt.wantErr != "" {
+				assertErrorCode(t, rec, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// librarySearchResponse is the JSON response for GET /library/search.
+type librarySearchResponse struct {
+	Files []libraryFile `json:"files"`
+}
+
+type libraryFile struct {
+	JobID       int64  `json:"job_id"`
+	URL         string `json:"url"`
+	Path        string `json:"path"`
+	Bytes       int64  `json:"bytes"`
+	SHA256      string `json:"sha256,omitempty"`
+	DeliveredAt string `json:"delivered_at"`
+	Missing     bool   `json:"missing"`
+	CheckedAt   string `json:"checked_at,omitempty"`
+}
+
+// handleLibrarySearch searches the index of every file delivered, newest
+// first, including those of jobs since pruned.
+func (s *Server) handleLibrarySearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := domain.LibraryQuery{Text: q.Get("q"), URL: q.Get("url"), SHA256: q.Get("sha256"), Limit: defaultListLimit}
+	if v := q.Get("job_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid job_id")
+			return
+		}
+		query.JobID = n
+	}
+	if v := q.Get("missing"); v != "" {
+		missing, err := strconv.ParseBool(v)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid missing: must be true or false")
+			return
+		}
+		query.Missing = &missing
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid limit")
+			return
+		}
+		query.Limit = min(n, maxListLimit)
+	}
+
+	files, err := s.svc.SearchLibrary(r.Context(), query)
+	if errors.Is(err, errors.ErrUnsupported) {
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "library index is disabled")
+		return
+	}
+	if err != nil {
+		log.Printf("library search error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	resp := librarySearchResponse{Files: make([]libraryFile, 0, len(files))}
+	for _, f := range files {
+		lf := libraryFile{
+			JobID:       f.JobID,
+			URL:         f.URL,
+			Path:        f.Path,
+			Bytes:       f.Bytes,
+			SHA256:      f.SHA256,
+			DeliveredAt: f.DeliveredAt.UTC().Format(time.RFC3339),
+			Missing:     f.Missing,
+		}
+		if !f.CheckedAt.IsZero() {
+			lf.CheckedAt = f.CheckedAt.UTC().Format(time.RFC3339)
+		}
+		resp.Files = append(resp.Files, lf)
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
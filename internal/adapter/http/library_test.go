@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// libraryStub holds the indexed files.
+type libraryStub struct{ files []domain.LibraryFile }
+
+func (s *libraryStub) LibraryFiles(ctx context.Context) ([]domain.LibraryFile, error) {
+	return s.files, nil
+}
+
+func (s *libraryStub) SearchLibrary(ctx context.Context, q domain.LibraryQuery) ([]domain.LibraryFile, error) {
+	var found []domain.LibraryFile
+	for i := range slices.Backward(s.files) {
+		if q.Match(&s.files[i]) && len(found) != q.Limit {
+			found = append(found, s.files[i])
+		}
+	}
+	return found, nil
+}
+
+func (s *libraryStub) UpdateLibraryFiles(ctx context.Context, files []domain.LibraryFile) error {
+	return nil
+}
+
+func TestServer_LibrarySearch(t *testing.T) {
+	now := time.Now()
+	svc := domain.NewJobService(newMockRepo())
+	svc.SetLibraryIndex(&libraryStub{files: []domain.LibraryFile{
+		{ID: 1, JobID: 3, URL: "https://example.com/cats", Path: "/v/cats.mp4", Bytes: 100, SHA256: "abc", DeliveredAt: now},
+		{ID: 2, JobID: 4, URL: "https://example.com/dogs", Path: "/v/dogs.mp4", Bytes: 50, DeliveredAt: now, Missing: true, CheckedAt: now},
+	}})
+	srv := NewServer(svc, ":8080", "")
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantJobs []int64
+	}{
+		{"", http.StatusOK, []int64{4, 3}},
+		{"?q=CATS", http.StatusOK, []int64{3}},
+		{"?sha256=abc", http.StatusOK, []int64{3}},
+		{"?url=https%3A%2F%2Fexample.com%2Fdogs", http.StatusOK, []int64{4}},
+		{"?missing=true", http.StatusOK, []int64{4}},
+		{"?job_id=3", http.StatusOK, []int64{3}},
+		{"?limit=1", http.StatusOK, []int64{4}},
+		{"?q=horses", http.StatusOK, nil},
+		{"?missing=maybe", http.StatusBadRequest, nil},
+		{"?job_id=x", http.StatusBadRequest, nil},
+		{"?limit=0", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/library/search"+tt.query, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				assertErrorCode(t, rec, CodeBadRequest)
+				return
+			}
+			var resp librarySearchResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if resp.Files == nil {
+				t.Fatal("files = null, want an array")
+			}
+			var got []int64
+			for _, f := range resp.Files {
+				got = append(got, f.JobID)
+			}
+			if !slices.Equal(got, tt.wantJobs) {
+				t.Fatalf("jobs = %v, want %v", got, tt.wantJobs)
+			}
+			if tt.query == "?missing=true" && (resp.Files[0].CheckedAt == "" || !resp.Files[0].Missing) {
+				t.Errorf("file = %+v, want missing with its check time", resp.Files[0])
+			}
+		})
+	}
+}
+
+func TestServer_LibrarySearchDisabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	setupTestServer().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/library/search", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	assertErrorCode(t, rec, CodeNotFound)
+}
@@ -0,0 +1,59 @@
+package http
+
+import "time"
+
+// Limits bounds how long and how much a client may send or hold a
+// connection, protecting against slowloris-style and oversized requests.
+type Limits struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64
+}
+
+// DefaultLimits returns conservative limits suitable for a webhook API.
+func DefaultLimits() Limits {
+	return Limits{
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    64 << 10,
+		MaxBodyBytes:      1 << 20,
+	}
+}
+
+// Override returns l with every non-zero field of o applied.
+func (l Limits) Override(o Limits) Limits {
+	if o.ReadHeaderTimeout > 0 {
+		l.ReadHeaderTimeout = o.ReadHeaderTimeout
+	}
+	if o.ReadTimeout > 0 {
+		l.ReadTimeout = o.ReadTimeout
+	}
+	if o.WriteTimeout > 0 {
+		l.WriteTimeout = o.WriteTimeout
+	}
+	if o.IdleTimeout > 0 {
+		l.IdleTimeout = o.IdleTimeout
+	}
+	if o.MaxHeaderBytes > 0 {
+		l.MaxHeaderBytes = o.MaxHeaderBytes
+	}
+	if o.MaxBodyBytes > 0 {
+		l.MaxBodyBytes = o.MaxBodyBytes
+	}
+	return l
+}
+
+// SetLimits applies connection and request limits. Call before ListenAndServe.
+func (s *Server) SetLimits(l Limits) {
+	s.limits = l
+	s.server.ReadHeaderTimeout = l.ReadHeaderTimeout
+	s.server.ReadTimeout = l.ReadTimeout
+	s.server.WriteTimeout = l.WriteTimeout
+	s.server.IdleTimeout = l.IdleTimeout
+	s.server.MaxHeaderBytes = l.MaxHeaderBytes
+}
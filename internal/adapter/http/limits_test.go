@@ -0,0 +1,92 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestServer_DefaultLimits(t *testing.T) {
+	srv := setupTestServer()
+	want := DefaultLimits()
+
+	if srv.server.ReadHeaderTimeout != want.ReadHeaderTimeout {
+		t.Errorf("ReadHeaderTimeout = %v, want %v", srv.server.ReadHeaderTimeout, want.ReadHeaderTimeout)
+	}
+	if srv.server.ReadTimeout != want.ReadTimeout {
+		t.Errorf("ReadTimeout = %v, want %v", srv.server.ReadTimeout, want.ReadTimeout)
+	}
+	if srv.server.WriteTimeout != want.WriteTimeout {
+		t.Errorf("WriteTimeout = %v, want %v", srv.server.WriteTimeout, want.WriteTimeout)
+	}
+	if srv.server.IdleTimeout != want.IdleTimeout {
+		t.Errorf("IdleTimeout = %v, want %v", srv.server.IdleTimeout, want.IdleTimeout)
+	}
+	if srv.server.MaxHeaderBytes != want.MaxHeaderBytes {
+		t.Errorf("MaxHeaderBytes = %d, want %d", srv.server.MaxHeaderBytes, want.MaxHeaderBytes)
+	}
+}
+
+func TestLimits_Override(t *testing.T) {
+	got := DefaultLimits().Override(Limits{ReadTimeout: time.Minute, MaxBodyBytes: 10})
+
+	if got.ReadTimeout != time.Minute {
+		t.Errorf("ReadTimeout = %v, want %v", got.ReadTimeout, time.Minute)
+	}
+	if got.MaxBodyBytes != 10 {
+		t.Errorf("MaxBodyBytes = %d, want 10", got.MaxBodyBytes)
+	}
+	if got.ReadHeaderTimeout != DefaultLimits().ReadHeaderTimeout {
+		t.Errorf("ReadHeaderTimeout = %v, want default", got.ReadHeaderTimeout)
+	}
+}
+
+func TestServer_Webhook_BodyTooLarge(t *testing.T) {
+	srv := NewServer(domain.NewJobService(newMockRepo()), ":8080", "")
+	srv.SetLimits(DefaultLimits().Override(Limits{MaxBodyBytes: 64}))
+
+	body := `{"url":"https://example.com/` + strings.Repeat("a", 100) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	assertErrorCode(t, rec, CodeTooLarge)
+}
+
+func TestServer_ReadHeaderTimeout(t *testing.T) {
+	srv := setupTestServer()
+	srv.SetLimits(DefaultLimits().Override(Limits{ReadHeaderTimeout: 100 * time.Millisecond}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	go srv.server.Serve(ln)
+	defer srv.server.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial error: %v", err)
+	}
+	defer conn.Close()
+
+	// Send an incomplete request line and never finish the headers
+	conn.Write([]byte("GET /health HTTP/1.1\r\nHost: x\r\n"))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	start := time.Now()
+	io.ReadAll(conn)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("slow client held connection for %v, want it closed after ReadHeaderTimeout", elapsed)
+	}
+}
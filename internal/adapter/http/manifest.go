@@ -0,0 +1,121 @@
+package http
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// defaultManifestWindow is how far back GET /jobs/manifest looks when since
+// is omitted.
+const defaultManifestWindow = 7 * 24 * time.Hour
+
+// sidecarExts are files processors write beside the media, such as
+// subtitles and thumbnails, which are left out of playlists.
+var sidecarExts = map[string]bool{
+	".srt": true, ".vtt": true, ".ass": true, ".lrc": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true,
+	".json": true, ".description": true, ".nfo": true, ".txt": true,
+}
+
+// handleManifest serves the files of completed jobs as an M3U playlist or
+// a CSV manifest.
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	format := q.Get("format")
+	if format == "" {
+		format = "m3u"
+	}
+	if format != "m3u" && format != "csv" {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid format: must be m3u or csv")
+		return
+	}
+
+	f := domain.CompletedFilter{Since: s.clock.Now().Add(-defaultManifestWindow)}
+	if name := q.Get("view"); name != "" {
+		view, err := s.svc.View(r.Context(), name)
+		if err != nil {
+			s.writeViewError(w, err)
+			return
+		}
+		f.Host = view.Host
+	}
+	if host := q.Get("host"); host != "" {
+		f.Host = host
+	}
+	for _, bound := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(bound.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid "+bound.name+": must be RFC3339")
+				return
+			}
+			*bound.t = t
+		}
+	}
+
+	jobs, err := s.svc.Completed(r.Context(), f)
+	if err != nil {
+		log.Printf("manifest error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="catcher-manifest.csv"`)
+		writeManifestCSV(w, jobs)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="catcher-manifest.m3u8"`)
+	writeM3U(w, jobs)
+}
+
+// writeM3U writes an extended M3U playlist of the jobs' media files, in
+// order. Titles fall back to file names.
+func writeM3U(w io.Writer, jobs []domain.Job) {
+	fmt.Fprintln(w, "#EXTM3U")
+	for _, job := range jobs {
+		for _, file := range job.Files {
+			if sidecarExts[strings.ToLower(filepath.Ext(file.Path))] {
+				continue
+			}
+			title := job.Title
+			if title == "" {
+				title = filepath.Base(file.Path)
+			}
+			// One entry per line, so titles can't break out of theirs
+			title = strings.Join(strings.Fields(title), " ")
+			fmt.Fprintf(w, "#EXTINF:-1,%s\n%s\n", title, file.Path)
+		}
+	}
+}
+
+// writeManifestCSV writes one row per file the jobs produced.
+func writeManifestCSV(w io.Writer, jobs []domain.Job) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"job_id", "uid", "url", "title", "notes", "completed_at", "path", "bytes"})
+	for _, job := range jobs {
+		completed := job.CompletedAt.UTC().Format(time.RFC3339)
+		for _, file := range job.Files {
+			cw.Write([]string{
+				strconv.FormatInt(job.ID, 10), job.UID, job.URL, job.Title, job.Notes, completed,
+				file.Path, strconv.FormatInt(file.Bytes, 10),
+			})
+		}
+	}
+	cw.Flush()
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func setupManifestServer() *Server {
+	repo := newMockRepo()
+	ctx := context.Background()
+	complete := func(url, title string, completed time.Time, paths ...string) {
+		job, _ := repo.Create(ctx, url)
+		job.Status, job.Title, job.CompletedAt = domain.StatusCompleted, title, completed
+		for _, p := range paths {
+			job.Files = append(job.Files, domain.ResultFile{Path: p, Bytes: 10})
+		}
+	}
+	complete("https://youtube.com/old", "Old", time.Now().Add(-30*24*time.Hour), "/v/old.mp4")
+	complete("https://youtube.com/a", "Talk\nabout X", time.Now().Add(-time.Hour), "/v/talk.mp4", "/v/talk.en.vtt", "/v/talk.info.json")
+	complete("https://vimeo.com/b", "", time.Now(), "/v/b.mkv")
+	repo.Create(ctx, "https://youtube.com/pending")
+
+	svc := domain.NewJobService(repo)
+	svc.SetCompletedLister(repo)
+	return NewServer(svc, ":8080", "")
+}
+
+func TestServer_Manifest_M3U(t *testing.T) {
+	srv := setupManifestServer()
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/manifest", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/x-mpegurl; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	want := "#EXTM3U\n" +
+		"#EXTINF:-1,Talk about X\n/v/talk.mp4\n" +
+		"#EXTINF:-1,b.mkv\n/v/b.mkv\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("playlist =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestServer_Manifest_CSV(t *testing.T) {
+	srv := setupManifestServer()
+
+	since := time.Now().Add(-365 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/manifest?format=csv&host=youtube.com&since="+since, nil))
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	// Header, the old job, then every file of the talk, sidecars included
+	if len(records) != 5 {
+		t.Fatalf("got %d rows, want 5: %v", len(records), records)
+	}
+	if records[0][6] != "path" || records[1][3] != "Old" || records[2][6] != "/v/talk.mp4" || records[3][6] != "/v/talk.en.vtt" {
+		t.Errorf("rows = %v", records)
+	}
+}
+
+func TestServer_Manifest_BadQuery(t *testing.T) {
+	srv := setupManifestServer()
+
+	for _, query := range []string{"format=pls", "since=yesterday", "until=2026-13-01"} {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/manifest?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	"github.com/cwygoda/catcher/internal/logging"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// validRequestID limits accepted client-supplied IDs to safe log material.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestID tags each request with an ID, reusing a well-formed incoming
+// X-Request-ID, and echoes it in the response.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		logging.Debugf("request %s: %s %s", id, r.Method, r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// requestIDFrom returns the request ID stored in ctx, if any.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// recoverPanic turns handler panics into a logged stack trace and a clean
+// 500 JSON error instead of a dropped connection.
+func (s *Server) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &headerTracker{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			id := requestIDFrom(r.Context())
+			log.Printf("panic [request %s] %s %s: %v\n%s", id, r.Method, r.URL.Path, v, debug.Stack())
+			if !tw.wroteHeader {
+				s.writeErrorDetails(w, http.StatusInternalServerError, CodeInternal, "internal error", map[string]string{"request_id": id})
+			}
+		}()
+		next.ServeHTTP(tw, r)
+	})
+}
+
+// headerTracker records whether the response status has been sent.
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(status int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *headerTracker) Write(p []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (t *headerTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// NormalizeBasePath cleans a configured path prefix to the form "/prefix",
+// returning "" when no prefix is wanted.
+func NormalizeBasePath(p string) string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// stripBasePath serves the API under s.basePath, rejecting requests outside it.
+func (s *Server) stripBasePath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.basePath == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rest, ok := strings.CutPrefix(r.URL.Path, s.basePath)
+		if !ok || (rest != "" && rest[0] != '/') {
+			s.writeError(w, http.StatusNotFound, CodeNotFound, "not found")
+			return
+		}
+		if rest == "" {
+			rest = "/"
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
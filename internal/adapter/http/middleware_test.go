@@ -0,0 +1,114 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFrom(r.Context())
+	}))
+
+	// Generated when absent
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if seen == "" {
+		t.Error("request ID not set in context")
+	}
+	if rec.Header().Get("X-Request-ID") != seen {
+		t.Errorf("X-Request-ID = %q, want %q", rec.Header().Get("X-Request-ID"), seen)
+	}
+
+	// Reused when well-formed
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != "abc-123" {
+		t.Errorf("request ID = %q, want %q", seen, "abc-123")
+	}
+
+	// Replaced when malformed
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen == "bad id\nwith newline" {
+		t.Error("malformed request ID was accepted")
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	srv := setupTestServer()
+	srv.mux.HandleFunc("GET /boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	var resp struct {
+		Error struct {
+			Code    string            `json:"code"`
+			Details map[string]string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.Error.Code != CodeInternal {
+		t.Errorf("error code = %q, want %q", resp.Error.Code, CodeInternal)
+	}
+	if resp.Error.Details["request_id"] != "req-1" {
+		t.Errorf("request_id = %q, want %q", resp.Error.Details["request_id"], "req-1")
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := map[string]string{
+		"":           "",
+		"/":          "",
+		"catcher":    "/catcher",
+		"/catcher/":  "/catcher",
+		"/a/b/":      "/a/b",
+		"//catcher/": "/catcher",
+	}
+	for in, want := range tests {
+		if got := NormalizeBasePath(in); got != want {
+			t.Errorf("NormalizeBasePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestServer_BasePath(t *testing.T) {
+	srv := setupTestServer()
+	srv.SetBasePath("/catcher/")
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/catcher/health", want: http.StatusOK},
+		{path: "/health", want: http.StatusNotFound},
+		{path: "/catcherx/health", want: http.StatusNotFound},
+		{path: "/catcher/jobs/999", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// redownloadResponse is the JSON response for POST /jobs/redownload.
+type redownloadResponse struct {
+	Count int           `json:"count"`
+	Jobs  []jobResponse `json:"jobs"`
+}
+
+// handleRedownloadJob queues a completed job again as a new job linked to
+// it, e.g. after its files were deleted.
+func (s *Server) handleRedownloadJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := s.svc.Redownload(r.Context(), id)
+	var de *domain.DuplicateError
+	switch {
+	case errors.Is(err, domain.ErrJobNotFound):
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+		return
+	case errors.Is(err, domain.ErrJobState):
+		s.writeError(w, http.StatusConflict, CodeConflict, "only completed jobs can be downloaded again")
+		return
+	case errors.As(err, &de):
+		s.writeErrorDetails(w, http.StatusConflict, CodeDuplicate, fmt.Sprintf("job is already being downloaded again as job %d", de.Job.ID),
+			map[string]string{"job_id": strconv.FormatInt(de.Job.ID, 10)})
+		return
+	case err != nil:
+		log.Printf("redownload error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	log.Printf("job %d: downloading again as job %d (request %s)", id, job.ID, requestIDFrom(r.Context()))
+	links := s.jobLinks(job)
+	w.Header().Set("Location", links.Self)
+	s.writeJSON(w, http.StatusCreated, s.jobToResponse(job))
+}
+
+// handleRedownloadMissing queues every job the last file check found
+// files missing from again.
+func (s *Server) handleRedownloadMissing(w http.ResponseWriter, r *http.Request) {
+	jobs, err := s.svc.RedownloadMissing(r.Context())
+	if errors.Is(err, domain.ErrNoFileCheck) {
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "no file check has run; set worker.verify_files")
+		return
+	}
+	if err != nil && len(jobs) == 0 {
+		log.Printf("redownload missing error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+	if err != nil {
+		log.Printf("redownload missing: stopped after %d job(s): %v", len(jobs), err)
+	}
+
+	resp := redownloadResponse{Count: len(jobs), Jobs: make([]jobResponse, 0, len(jobs))}
+	for _, job := range jobs {
+		log.Printf("job %d: downloading again as job %d (request %s)", job.RedownloadOf, job.ID, requestIDFrom(r.Context()))
+		resp.Jobs = append(resp.Jobs, s.jobToResponse(job))
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func (m *mockRepo) ActiveRedownload(ctx context.Context, id int64) (*domain.Job, error) {
+	for i := m.nextID - 1; i > 0; i-- {
+		if job, ok := m.jobs[i]; ok && job.RedownloadOf == id && job.Status == domain.StatusPending {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestServer_RedownloadJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       string
+		path       string
+		status     domain.JobStatus
+		cloned     bool
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "missing token", path: "/jobs/1/redownload", wantStatus: http.StatusUnauthorized, wantCode: CodeUnauthorized},
+		{name: "not found", auth: "Bearer s3cret", path: "/jobs/9/redownload", wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
+		{name: "pending", auth: "Bearer s3cret", path: "/jobs/1/redownload", status: domain.StatusPending, wantStatus: http.StatusConflict, wantCode: CodeConflict},
+		{name: "already cloned", auth: "Bearer s3cret", path: "/jobs/1/redownload", status: domain.StatusCompleted, cloned: true, wantStatus: http.StatusConflict, wantCode: CodeDuplicate},
+		{name: "completed", auth: "Bearer s3cret", path: "/jobs/1/redownload", status: domain.StatusCompleted, wantStatus: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			svc.SetRedownloadFinder(repo)
+			srv := NewServer(svc, ":8080", "")
+			srv.SetAdminToken("s3cret")
+
+			ctx := context.Background()
+			job, _ := repo.Create(domain.WithNotes(ctx, "for mum"), "https://example.com/video")
+			if tt.status != "" {
+				job.Status = tt.status
+			}
+			if tt.cloned {
+				repo.Create(domain.WithRedownloadOf(ctx, job.ID), job.URL)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" {
+				assertErrorCode(t, rec, tt.wantCode)
+				return
+			}
+
+			var resp jobResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if resp.ID == job.ID || resp.Status != "pending" || resp.RedownloadOf != job.ID || resp.Notes != "for mum" {
+				t.Errorf("response = %+v, want a pending clone of job %d", resp, job.ID)
+			}
+			if loc := rec.Header().Get("Location"); loc != "/jobs/"+resp.UID {
+				t.Errorf("Location = %q, want /jobs/%s", loc, resp.UID)
+			}
+		})
+	}
+}
+
+func TestServer_RedownloadMissing(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetRedownloadFinder(repo)
+	checks := &fileCheckStub{}
+	svc.SetFileCheckRepository(checks)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/jobs/redownload", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do()
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("before any check: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	assertErrorCode(t, rec, CodeNotFound)
+
+	ctx := context.Background()
+	missing, _ := repo.Create(ctx, "https://example.com/a")
+	changed, _ := repo.Create(ctx, "https://example.com/b")
+	missing.Status, changed.Status = domain.StatusCompleted, domain.StatusCompleted
+	checks.check = &domain.FileCheck{Issues: []domain.FileIssue{
+		{JobID: missing.ID, Path: "/v/a.mp4", Problem: domain.FileMissing, WantBytes: 100},
+		{JobID: changed.ID, Path: "/v/b.mp4", Problem: domain.FileSizeMismatch, WantBytes: 100, GotBytes: 40},
+	}}
+
+	for _, want := range []int{1, 0} {
+		rec := do()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var resp redownloadResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if resp.Count != want || len(resp.Jobs) != want {
+			t.Fatalf("response = %+v, want %d job(s)", resp, want)
+		}
+		if want == 1 && resp.Jobs[0].RedownloadOf != missing.ID {
+			t.Errorf("redownloaded job %d, want %d", resp.Jobs[0].RedownloadOf, missing.ID)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// schedulerResponse is the JSON response for GET /admin/scheduler.
+type schedulerResponse struct {
+	State               string           `json:"state"`
+	PollIntervalSeconds float64          `json:"poll_interval_seconds"`
+	Polling             bool             `json:"polling"`
+	LastPollAt          string           `json:"last_poll_at,omitempty"`
+	NextPollAt          string           `json:"next_poll_at,omitempty"`
+	Running             []runningJob     `json:"running"`
+	Cooldowns           []hostCooldown   `json:"cooldowns"`
+	Budget              *schedulerBudget `json:"budget,omitempty"`
+	Outage              *schedulerOutage `json:"outage,omitempty"`
+}
+
+type runningJob struct {
+	JobID          int64   `json:"job_id"`
+	URL            string  `json:"url"`
+	Processor      string  `json:"processor"`
+	Queue          string  `json:"queue,omitempty"`
+	StartedAt      string  `json:"started_at"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+type hostCooldown struct {
+	Host             string  `json:"host"`
+	Until            string  `json:"until"`
+	RemainingSeconds float64 `json:"remaining_seconds"`
+	Reason           string  `json:"reason,omitempty"`
+}
+
+type schedulerBudget struct {
+	Total   int `json:"total"`
+	Used    int `json:"used"`
+	Waiting int `json:"waiting"`
+}
+
+type schedulerOutage struct {
+	Down        bool   `json:"down"`
+	Since       string `json:"since"`
+	Probe       string `json:"probe,omitempty"`
+	NextProbeAt string `json:"next_probe_at,omitempty"`
+	Failed      int    `json:"failed"`
+	Requeueing  int    `json:"requeueing"`
+}
+
+// SetScheduler serves GET /admin/scheduler, what the worker is doing right
+// now as reported by state.
+func (s *Server) SetScheduler(state func() domain.SchedulerState) {
+	s.mux.Handle("GET /admin/scheduler", s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		s.writeJSON(w, http.StatusOK, schedulerToResponse(state(), s.clock.Now()))
+	}))
+}
+
+func schedulerToResponse(st domain.SchedulerState, now time.Time) schedulerResponse {
+	resp := schedulerResponse{
+		State:               st.State,
+		PollIntervalSeconds: st.PollInterval.Seconds(),
+		Polling:             st.Polling,
+		LastPollAt:          formatTime(st.LastPoll),
+		NextPollAt:          formatTime(st.NextPoll),
+		Running:             make([]runningJob, 0, len(st.Running)),
+		Cooldowns:           make([]hostCooldown, 0, len(st.Cooldowns)),
+	}
+	for _, j := range st.Running {
+		resp.Running = append(resp.Running, runningJob{
+			JobID:          j.JobID,
+			URL:            j.URL,
+			Processor:      j.Processor,
+			Queue:          j.Queue,
+			StartedAt:      formatTime(j.StartedAt),
+			ElapsedSeconds: max(now.Sub(j.StartedAt).Seconds(), 0),
+		})
+	}
+	for _, c := range st.Cooldowns {
+		resp.Cooldowns = append(resp.Cooldowns, hostCooldown{
+			Host:             c.Host,
+			Until:            formatTime(c.Until),
+			RemainingSeconds: max(c.Until.Sub(now).Seconds(), 0),
+			Reason:           c.Reason,
+		})
+	}
+	if b := st.Budget; b != nil {
+		resp.Budget = &schedulerBudget{Total: b.Total, Used: b.Used, Waiting: b.Waiting}
+	}
+	if o := st.Outage; o != nil {
+		resp.Outage = &schedulerOutage{
+			Down:        o.Down,
+			Since:       formatTime(o.Since),
+			Probe:       o.Probe,
+			NextProbeAt: formatTime(o.NextProbe),
+			Failed:      o.Failed,
+			Requeueing:  o.Requeueing,
+		}
+	}
+	return resp
+}
+
+// formatTime formats t in UTC as RFC 3339, or returns "" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestServer_Scheduler(t *testing.T) {
+	now := time.Now()
+	state := domain.SchedulerState{
+		State:        domain.WorkerRunning,
+		PollInterval: 5 * time.Second,
+		LastPoll:     now.Add(-2 * time.Second),
+		NextPoll:     now.Add(3 * time.Second),
+		Running: []domain.RunningJob{
+			{JobID: 7, URL: "https://youtube.com/watch?v=a", Processor: "youtube", StartedAt: now.Add(-90 * time.Second)},
+		},
+		Cooldowns: []domain.Cooldown{{Host: "youtube.com", Until: now.Add(10 * time.Minute), Reason: "HTTP 429"}},
+		Budget:    &domain.BudgetState{Total: 4, Used: 3, Waiting: 1},
+		Outage:    &domain.OutageState{Down: true, Since: now.Add(-time.Minute), Probe: "youtube.com:443", NextProbe: now.Add(30 * time.Second), Failed: 2},
+	}
+
+	srv := setupTestServer()
+	srv.SetAdminToken("s3cret")
+	srv.SetScheduler(func() domain.SchedulerState { return state })
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var resp schedulerResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.State != "running" || resp.PollIntervalSeconds != 5 || resp.NextPollAt == "" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.Running) != 1 || resp.Running[0].JobID != 7 || resp.Running[0].Processor != "youtube" || resp.Running[0].ElapsedSeconds < 90 {
+		t.Errorf("running = %+v", resp.Running)
+	}
+	if len(resp.Cooldowns) != 1 || resp.Cooldowns[0].Host != "youtube.com" || resp.Cooldowns[0].RemainingSeconds > 600 {
+		t.Errorf("cooldowns = %+v", resp.Cooldowns)
+	}
+	if resp.Budget == nil || resp.Budget.Used != 3 || resp.Budget.Waiting != 1 {
+		t.Errorf("budget = %+v", resp.Budget)
+	}
+	if o := resp.Outage; o == nil || !o.Down || o.Probe != "youtube.com:443" || o.NextProbeAt == "" || o.Failed != 2 {
+		t.Errorf("outage = %+v", resp.Outage)
+	}
+}
+
+func TestSchedulerToResponse_Idle(t *testing.T) {
+	resp := schedulerToResponse(domain.SchedulerState{State: domain.WorkerIdle, PollInterval: time.Second}, time.Now())
+	if resp.LastPollAt != "" || resp.NextPollAt != "" || resp.Budget != nil || resp.Outage != nil {
+		t.Errorf("response = %+v, want no poll times, budget, or outage", resp)
+	}
+	if resp.Running == nil || resp.Cooldowns == nil {
+		t.Error("running and cooldowns should be empty lists, not null")
+	}
+}
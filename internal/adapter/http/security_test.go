@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders_CSP(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers SecurityHeaders
+		want    string
+	}{
+		{name: "defaults", headers: DefaultSecurityHeaders(), want: "default-src 'none'; frame-ancestors 'none'"},
+		{
+			name:    "proxy frame ancestors",
+			headers: DefaultSecurityHeaders().Override(SecurityHeaders{FrameAncestors: []string{"'self'", "https://proxy.example.com"}}),
+			want:    "default-src 'none'; frame-ancestors 'self' https://proxy.example.com",
+		},
+		{
+			name:    "custom policy with trailing semicolon",
+			headers: SecurityHeaders{ContentSecurityPolicy: "default-src 'self';", FrameAncestors: []string{"'self'"}},
+			want:    "default-src 'self'; frame-ancestors 'self'",
+		},
+		{name: "frame ancestors only", headers: SecurityHeaders{FrameAncestors: []string{"'none'"}}, want: "frame-ancestors 'none'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.headers.csp(); got != tt.want {
+				t.Errorf("csp() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_SecurityHeaders(t *testing.T) {
+	srv := setupTestServer()
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	want := map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+		"Referrer-Policy":         "no-referrer",
+		"X-Frame-Options":         "DENY",
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	// Allowing a proxy origin to frame drops the legacy DENY header
+	srv.SetSecurityHeaders(DefaultSecurityHeaders().Override(SecurityHeaders{FrameAncestors: []string{"'self'"}}))
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if got := rec.Header().Get("X-Frame-Options"); got != "" {
+		t.Errorf("X-Frame-Options = %q, want empty", got)
+	}
+}
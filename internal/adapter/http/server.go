@@ -6,107 +6,286 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/event"
 )
 
 // Server is the HTTP adapter for the webhook service.
 type Server struct {
-	svc    *domain.JobService
-	mux    *http.ServeMux
-	server *http.Server
-	secret string
+	svc        *domain.JobService
+	mux        *http.ServeMux
+	handler    http.Handler
+	server     *http.Server
+	secret     string
+	adminToken string
+	limits     Limits
+	basePath   string
+	security   SecurityHeaders
+	clock      domain.Clock
+	shareKey   []byte // signs /share tokens, once SetShare is called
+	shareTTL   time.Duration
+	grants     map[string]*domain.Grant // by token, once SetGrants is called
+
+	draining     atomic.Bool
+	inFlight     func() int
+	replication  ReplicationMonitor
+	connectivity domain.ConnectivityMonitor
+	requireUID   bool
+	maxBatch     int  // URLs one payload may submit without confirm, 0 for any number
+	holdOverflow bool // hold URLs past maxBatch rather than refusing the payload
+	announcement atomic.Pointer[domain.Announcement]
+}
+
+// ReplicationMonitor reports on continuous replication of the database.
+type ReplicationMonitor interface {
+	// Lag returns how long ago the replica last caught up, or false if it
+	// never has.
+	Lag() (time.Duration, bool)
+	Lagging() bool
+	BackupInProgress() bool
 }
 
 // NewServer creates a new HTTP server.
 func NewServer(svc *domain.JobService, addr string, secret string) *Server {
 	s := &Server{
-		svc:    svc,
-		mux:    http.NewServeMux(),
-		secret: secret,
+		svc:      svc,
+		mux:      http.NewServeMux(),
+		secret:   secret,
+		security: DefaultSecurityHeaders(),
+		clock:    domain.SystemClock,
 	}
 	s.routes()
+	s.handler = requestID(s.securityHeaders(compress(s.recoverPanic(s.stripBasePath(s.announce(s.mux))))))
 	s.server = &http.Server{
 		Addr:    addr,
-		Handler: s.mux,
+		Handler: s.handler,
 	}
+	s.SetLimits(DefaultLimits())
 	return s
 }
 
 func (s *Server) routes() {
 	s.mux.HandleFunc("POST /webhook", s.handleWebhook)
+	s.mux.HandleFunc("POST /webhook/{format}", s.handleShim)
+	s.mux.Handle("GET /jobs", s.listing(s.handleListJobs))
 	s.mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	s.mux.Handle("GET /jobs/manifest", s.listing(s.handleManifest))
+	s.mux.HandleFunc("GET /jobs/{id}/bundle", s.handleJobBundle)
+	s.mux.Handle("POST /jobs/requeue", s.requireAdmin(s.handleBulk("requeued failed", s.svc.RequeueFailed)))
+	s.mux.Handle("POST /jobs/cancel", s.requireAdmin(s.handleBulk("cancelled pending", s.svc.CancelPending)))
+	s.mux.Handle("POST /jobs/redownload", s.requireAdmin(s.handleRedownloadMissing))
+	s.mux.Handle("PATCH /jobs/{id}", s.requireAdmin(s.handleEditJob))
+	s.mux.HandleFunc("GET /stats/failures", s.handleFailures)
+	s.mux.Handle("GET /integrity", s.listing(s.handleFileCheck))
+	s.mux.Handle("GET /library/search", s.listing(s.handleLibrarySearch))
+	s.mux.HandleFunc("GET /views", s.handleListViews)
+	s.mux.Handle("PUT /views/{name}", s.requireAdmin(s.handleSaveView))
+	s.mux.Handle("DELETE /views/{name}", s.requireAdmin(s.handleDeleteView))
+	s.mux.Handle("POST /jobs/{id}/complete", s.requireAdmin(s.handleCompleteJob))
+	s.mux.Handle("POST /jobs/{id}/hold", s.requireAdmin(s.handleHoldJob(true)))
+	s.mux.Handle("POST /jobs/{id}/release", s.requireAdmin(s.handleHoldJob(false)))
+	s.mux.Handle("POST /jobs/{id}/approve", s.requireAdmin(s.handleApproveJob))
+	s.mux.Handle("POST /jobs/{id}/reject", s.requireAdmin(s.handleRejectJob))
+	s.mux.Handle("POST /jobs/{id}/redownload", s.requireAdmin(s.handleRedownloadJob))
+	s.mux.Handle("POST /jobs/{id}/share", s.requireAdmin(s.handleShareFiles))
+	s.mux.HandleFunc("GET /jobs/{id}/files/{n}", s.handleSharedFile)
+	s.mux.HandleFunc("GET /jobs/{id}/files.zip", s.handleFilesZip)
 	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /ready", s.handleReady)
+	s.mux.HandleFunc("GET /announcement", s.handleGetAnnouncement)
+	s.mux.Handle("PUT /admin/announcement", s.requireAdmin(s.handleSetAnnouncement))
+	s.mux.Handle("DELETE /admin/announcement", s.requireAdmin(s.handleClearAnnouncement))
 }
 
 // webhookRequest is the request body for POST /webhook.
 type webhookRequest struct {
-	URL string `json:"url"`
+	URL    string `json:"url"`
+	Hold   bool   `json:"hold"`   // queue the job held, to be released later
+	Source string `json:"source"` // who submitted it, for taking turns
+	Notes  string `json:"notes"`  // free text to remember the job by
+	// Bookmark has the processor save a .strm pointing at the URL for a
+	// media server to stream, instead of downloading it.
+	Bookmark bool `json:"bookmark"`
+	// UserAgent replaces the processor's default User-Agent for the job.
+	UserAgent string `json:"user_agent"`
 }
 
-// jobResponse is the JSON response for job endpoints.
+// validSource limits submission sources to short names safe to log.
+var validSource = regexp.MustCompile(`^[A-Za-z0-9._@-]{1,64}$`)
+
+// jobResponse is the JSON response for job endpoints: the job as in
+// published events, plus convenience fields for clients that would rather
+// not do date math.
 type jobResponse struct {
-	ID        int64  `json:"id"`
-	URL       string `json:"url"`
-	Status    string `json:"status"`
-	Attempts  int    `json:"attempts"`
-	Error     string `json:"error,omitempty"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	event.Job
+	// AgeSeconds is the time since the job was created, as of the response.
+	AgeSeconds int64 `json:"age_seconds"`
+	// DurationSeconds is DurationMS in seconds.
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
 }
 
-// errorResponse is the JSON error response.
+// Error codes returned in API error responses. Clients should match on
+// these rather than on messages, which may change.
+const (
+	CodeBadRequest   = "bad_request"
+	CodeInvalidURL   = "invalid_url"
+	CodeURLRejected  = "url_rejected"
+	CodeDuplicate    = "duplicate"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotPermitted = "not_permitted"
+	CodeRateLimited  = "rate_limited"
+	CodeTooLarge     = "payload_too_large"
+	CodeBatch        = "batch_too_large"
+	CodeReadOnly     = "read_only"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeInternal     = "internal"
+)
+
+// errorResponse is the JSON error envelope.
 type errorResponse struct {
-	Error string `json:"error"`
+	Error apiError `json:"error"`
+}
+
+// apiError describes a failed request.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
 }
 
 func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	// Read body for verification and parsing
-	body, err := io.ReadAll(r.Body)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes))
 	if err != nil {
-		s.writeError(w, http.StatusBadRequest, "failed to read request body")
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			s.writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+			return
+		}
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "failed to read request body")
 		return
 	}
 
-	// Verify signature if secret is configured
-	if s.secret != "" {
+	// Verify signature if secret is configured, unless r carries a token
+	var granted bool
+	if s.secret != "" && r.Header.Get("X-Signature") == "" {
+		r, granted = s.withGrant(r)
+	}
+	if s.secret != "" && !granted {
 		if err := s.verifySignature(r, body); err != nil {
 			log.Printf("webhook verification failed: %v", err)
-			s.writeError(w, http.StatusUnauthorized, err.Error())
+			s.writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 			return
 		}
 	}
 
 	var req webhookRequest
 	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid JSON")
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
 		return
 	}
 
 	if req.URL == "" {
-		s.writeError(w, http.StatusBadRequest, "url is required")
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "url is required")
+		return
+	}
+	if req.Source != "" && !validSource.MatchString(req.Source) {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "source must be 1-64 letters, digits, or ._@-")
 		return
 	}
 
-	job, err := s.svc.Submit(r.Context(), req.URL)
+	submit := s.svc.Submit
+	if req.Hold {
+		submit = s.svc.SubmitHeld
+	}
+	ctx := domain.WithNotes(domain.WithSource(r.Context(), req.Source), req.Notes)
+	if req.Bookmark {
+		ctx = domain.WithBookmark(ctx)
+	}
+	if req.UserAgent != "" {
+		ctx = domain.WithUserAgent(ctx, req.UserAgent)
+	}
+	job, err := submit(ctx, req.URL)
 	if err != nil {
-		if err == domain.ErrInvalidURL {
-			s.writeError(w, http.StatusBadRequest, "invalid URL")
-			return
-		}
-		log.Printf("submit error: %v", err)
-		s.writeError(w, http.StatusInternalServerError, "internal error")
+		status, apiErr := submitError(err)
+		s.writeErrorDetails(w, status, apiErr.Code, apiErr.Message, apiErr.Details)
 		return
 	}
 
-	s.writeJSON(w, http.StatusCreated, jobToResponse(job))
+	links := s.jobLinks(job)
+	w.Header().Set("Location", links.Self)
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="self", <%s>; rel="log"`, links.Self, links.Log))
+	s.writeJSON(w, http.StatusCreated, webhookResponse{jobResponse: s.jobToResponse(job), Links: links})
+}
+
+// submitError maps an error submitting a URL to a response status and
+// error.
+func submitError(err error) (int, apiError) {
+	if err == domain.ErrInvalidURL {
+		return http.StatusBadRequest, apiError{Code: CodeInvalidURL, Message: "invalid URL"}
+	}
+	if err == domain.ErrNotesTooLong {
+		return http.StatusBadRequest, apiError{Code: CodeBadRequest, Message: fmt.Sprintf("notes must be at most %d characters", domain.MaxNotesLength)}
+	}
+	if err == domain.ErrBookmarkUnsupported {
+		return http.StatusUnprocessableEntity, apiError{Code: CodeURLRejected, Message: "the processor for this URL can't save bookmarks", Details: map[string]string{"reason": err.Error()}}
+	}
+	if err == domain.ErrInvalidUserAgent {
+		return http.StatusBadRequest, apiError{Code: CodeBadRequest, Message: fmt.Sprintf("user_agent must be at most %d characters, without control characters or a leading -", domain.MaxUserAgentLength)}
+	}
+	var ve *domain.ValidationError
+	if errors.As(err, &ve) {
+		return http.StatusUnprocessableEntity, apiError{Code: CodeURLRejected, Message: ve.Error(), Details: map[string]string{"reason": ve.Reason}}
+	}
+	var pe *domain.PermissionError
+	if errors.As(err, &pe) {
+		return http.StatusForbidden, apiError{Code: CodeNotPermitted, Message: pe.Error(), Details: map[string]string{"token": pe.Grant, "reason": pe.Reason}}
+	}
+	var de *domain.DuplicateError
+	if errors.As(err, &de) {
+		return http.StatusConflict, apiError{Code: CodeDuplicate, Message: de.Error(), Details: map[string]string{"job_id": strconv.FormatInt(de.Job.ID, 10)}}
+	}
+	log.Printf("submit error: %v", err)
+	return http.StatusInternalServerError, apiError{Code: CodeInternal, Message: "internal error"}
+}
+
+// webhookResponse is the JSON response for POST /webhook: the new job and
+// where to follow up on it.
+type webhookResponse struct {
+	jobResponse
+	Links jobLinks `json:"links"`
+}
+
+// jobLinks are the URLs of a job's resources, also sent as Location and
+// Link headers.
+type jobLinks struct {
+	Self string `json:"self"`
+	// Log is the diagnostic bundle, holding each attempt's command output.
+	Log string `json:"log"`
+}
+
+// jobLinks returns the links for job under the base path. They use the UID,
+// which every /jobs/{id} route accepts even when numeric IDs are refused.
+func (s *Server) jobLinks(job *domain.Job) jobLinks {
+	ref := job.UID
+	if ref == "" {
+		ref = strconv.FormatInt(job.ID, 10)
+	}
+	self := s.basePath + "/jobs/" + ref
+	return jobLinks{Self: self, Log: self + "/bundle"}
 }
 
 const maxTimestampSkew = 5 * time.Minute
@@ -123,7 +302,7 @@ func (s *Server) verifySignature(r *http.Request, body []byte) error {
 		return fmt.Errorf("invalid X-Timestamp: must be ISO8601/RFC3339 format")
 	}
 
-	skew := time.Since(ts)
+	skew := s.clock.Now().Sub(ts)
 	if skew < 0 {
 		skew = -skew
 	}
@@ -149,30 +328,206 @@ func (s *Server) verifySignature(r *http.Request, body []byte) error {
 	return nil
 }
 
+// jobID resolves the {id} path value, a numeric ID or a UID. If it can't,
+// it writes the error response and returns false.
+func (s *Server) jobID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := s.svc.ResolveID(r.Context(), r.PathValue("id"), !s.requireUID)
+	switch {
+	case err == nil:
+		return id, true
+	case errors.Is(err, domain.ErrInvalidJobID):
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid job ID")
+	case errors.Is(err, domain.ErrJobNotFound):
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+	default:
+		log.Printf("resolve job ID error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+	}
+	return 0, false
+}
+
 func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	id, ok := s.jobID(w, r)
+	if !ok {
+		return
+	}
+
+	fields, err := parseFields(r.URL.Query())
 	if err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid job ID")
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
 		return
 	}
 
 	job, err := s.svc.Get(r.Context(), id)
 	if err != nil {
 		if err == domain.ErrJobNotFound {
-			s.writeError(w, http.StatusNotFound, "job not found")
+			s.writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
 			return
 		}
 		log.Printf("get job error: %v", err)
-		s.writeError(w, http.StatusInternalServerError, "internal error")
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	if notModified(w, r, jobETag(job.ID, job.UpdatedAt), job.UpdatedAt) {
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, selectFields(s.jobToResponse(job), fields))
+}
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// listResponse is the JSON response for GET /jobs.
+type listResponse struct {
+	Jobs []any `json:"jobs"`
+	// NextCursor fetches the following page; absent on the last one.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := domain.JobFilter{Limit: defaultListLimit}
+	if name := q.Get("view"); name != "" {
+		view, err := s.svc.View(r.Context(), name)
+		if err != nil {
+			s.writeViewError(w, err)
+			return
+		}
+		filter = view.Filter(defaultListLimit)
+	}
+	if status := q.Get("status"); status != "" {
+		if !domain.JobStatus(status).Valid() {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid status")
+			return
+		}
+		filter.Status = domain.JobStatus(status)
+	}
+	if host := q.Get("host"); host != "" {
+		filter.Host = host
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid limit")
+			return
+		}
+		filter.Limit = min(n, maxListLimit)
+	}
+	if cursor := q.Get("cursor"); cursor != "" {
+		n, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || n < 1 {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid cursor")
+			return
+		}
+		filter.After = n
+	}
+
+	fields, err := parseFields(q)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	jobs, err := s.svc.List(r.Context(), filter)
+	if err != nil {
+		log.Printf("list jobs error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	if notModified(w, r, listETag(jobs), time.Time{}) {
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, jobToResponse(job))
+	resp := listResponse{Jobs: make([]any, 0, len(jobs))}
+	for i := range jobs {
+		resp.Jobs = append(resp.Jobs, selectFields(s.jobToResponse(&jobs[i]), fields))
+	}
+	if len(jobs) == filter.Limit {
+		resp.NextCursor = strconv.FormatInt(jobs[len(jobs)-1].ID, 10)
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// healthResponse is the JSON response for GET /health.
+type healthResponse struct {
+	Status       string                `json:"status"`
+	Replication  *replicationHealth    `json:"replication,omitempty"`
+	Connectivity *connectivityHealth   `json:"connectivity,omitempty"`
+	Announcement *announcementResponse `json:"announcement,omitempty"`
+}
+
+// replicationHealth reports replication in GET /health.
+type replicationHealth struct {
+	LagSeconds       *float64 `json:"lag_seconds"` // null before the first sync
+	Lagging          bool     `json:"lagging"`
+	BackupInProgress bool     `json:"backup_in_progress"`
+}
+
+// connectivityHealth reports the uplink in GET /health.
+type connectivityHealth struct {
+	Online    bool       `json:"online"`
+	Target    string     `json:"target"`
+	Since     *time.Time `json:"since"`      // null before the first check
+	CheckedAt *time.Time `json:"checked_at"` // null before the first check
+	Error     string     `json:"error,omitempty"`
 }
 
+// handleHealth reports "degraded" while replication is lagging or the
+// uplink is down, still with 200, as restarting catcher wouldn't help
+// either.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	resp := healthResponse{Status: "ok"}
+	if m := s.replication; m != nil {
+		rh := &replicationHealth{Lagging: m.Lagging(), BackupInProgress: m.BackupInProgress()}
+		if lag, ok := m.Lag(); ok {
+			secs := lag.Seconds()
+			rh.LagSeconds = &secs
+		}
+		if rh.Lagging {
+			resp.Status = "degraded"
+		}
+		resp.Replication = rh
+	}
+	if m := s.connectivity; m != nil {
+		c := m.Connectivity()
+		ch := &connectivityHealth{Online: c.Online, Target: c.Target, Error: c.Error}
+		if !c.CheckedAt.IsZero() {
+			ch.Since, ch.CheckedAt = &c.Since, &c.CheckedAt
+		}
+		if !c.Online {
+			resp.Status = "degraded"
+		}
+		resp.Connectivity = ch
+	}
+	if a := s.currentAnnouncement(); a != nil {
+		resp.Announcement = s.announcementToResponse(a)
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// readyResponse is the JSON response for GET /ready.
+type readyResponse struct {
+	Status   string `json:"status"`
+	InFlight int    `json:"in_flight"`
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	resp := readyResponse{Status: "ready"}
+	if s.inFlight != nil {
+		resp.InFlight = s.inFlight()
+	}
+	if s.draining.Load() {
+		resp.Status = "draining"
+		s.writeJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
@@ -181,19 +536,19 @@ func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
 	json.NewEncoder(w).Encode(v)
 }
 
-func (s *Server) writeError(w http.ResponseWriter, status int, msg string) {
-	s.writeJSON(w, status, errorResponse{Error: msg})
+func (s *Server) writeError(w http.ResponseWriter, status int, code, msg string) {
+	s.writeErrorDetails(w, status, code, msg, nil)
 }
 
-func jobToResponse(job *domain.Job) jobResponse {
+func (s *Server) writeErrorDetails(w http.ResponseWriter, status int, code, msg string, details any) {
+	s.writeJSON(w, status, errorResponse{Error: apiError{Code: code, Message: msg, Details: details}})
+}
+
+func (s *Server) jobToResponse(job *domain.Job) jobResponse {
 	return jobResponse{
-		ID:        job.ID,
-		URL:       job.URL,
-		Status:    string(job.Status),
-		Attempts:  job.Attempts,
-		Error:     job.Error,
-		CreatedAt: job.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: job.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		Job:             event.FromJob(job),
+		AgeSeconds:      max(int64(s.clock.Now().Sub(job.CreatedAt)/time.Second), 0),
+		DurationSeconds: job.Duration.Seconds(),
 	}
 }
 
@@ -202,6 +557,64 @@ func (s *Server) ListenAndServe() error {
 	return s.server.ListenAndServe()
 }
 
+// SetInFlight sets the source of the in-flight job count reported by /ready.
+func (s *Server) SetInFlight(f func() int) {
+	s.inFlight = f
+}
+
+// SetReplication adds replication lag and backups to /health.
+func (s *Server) SetReplication(m ReplicationMonitor) {
+	s.replication = m
+}
+
+// SetConnectivity adds the uplink's state to /health.
+func (s *Server) SetConnectivity(m domain.ConnectivityMonitor) {
+	s.connectivity = m
+}
+
+// SetRequireUID makes /jobs/{id} routes accept only job UIDs, so jobs on
+// an exposed instance can't be found by counting IDs. The routes listing
+// jobs with their UIDs then need the admin token.
+func (s *Server) SetRequireUID(require bool) {
+	s.requireUID = require
+}
+
+// listing guards a route that lists jobs with their UIDs, URLs, or files.
+// With require_uid, only the admin may use it, as anyone else could
+// collect every UID from it.
+func (s *Server) listing(next http.HandlerFunc) http.Handler {
+	admin := s.requireAdmin(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.requireUID {
+			admin.ServeHTTP(w, r)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// SetMetrics serves h at GET /metrics.
+func (s *Server) SetMetrics(h http.Handler) {
+	s.mux.Handle("GET /metrics", h)
+}
+
+// SetClock makes signature timestamp checks, job ages, report windows,
+// and announcement windows follow c.
+func (s *Server) SetClock(c domain.Clock) {
+	s.clock = c
+}
+
+// SetBasePath mounts all routes under prefix (e.g. "/catcher") for serving
+// behind a reverse proxy. Call before ListenAndServe.
+func (s *Server) SetBasePath(prefix string) {
+	s.basePath = NormalizeBasePath(prefix)
+}
+
+// Drain marks the server as shutting down so /ready returns 503.
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
@@ -209,7 +622,7 @@ func (s *Server) Shutdown(ctx context.Context) error {
 
 // ServeHTTP implements http.Handler for testing.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.mux.ServeHTTP(w, r)
+	s.handler.ServeHTTP(w, r)
 }
 
 // Addr returns the server address.
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,11 +29,17 @@ func newMockRepo() *mockRepo {
 
 func (m *mockRepo) Create(ctx context.Context, url string) (*domain.Job, error) {
 	job := &domain.Job{
-		ID:        m.nextID,
-		URL:       url,
-		Status:    domain.StatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:           m.nextID,
+		UID:          domain.NewUID(),
+		URL:          url,
+		Status:       domain.StatusPending,
+		Source:       domain.SourceFrom(ctx),
+		Notes:        domain.NotesFrom(ctx),
+		Bookmark:     domain.BookmarkFrom(ctx),
+		RedownloadOf: domain.RedownloadOfFrom(ctx),
+		UserAgent:    domain.UserAgentFrom(ctx),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 	m.jobs[m.nextID] = job
 	m.nextID++
@@ -46,18 +54,156 @@ func (m *mockRepo) Get(ctx context.Context, id int64) (*domain.Job, error) {
 	return job, nil
 }
 
+func (m *mockRepo) ResolveUID(ctx context.Context, uid string) (int64, error) {
+	for id, job := range m.jobs {
+		if job.UID == uid {
+			return id, nil
+		}
+	}
+	return 0, domain.ErrJobNotFound
+}
+
 func (m *mockRepo) FindPending(ctx context.Context, limit int) ([]domain.Job, error) {
 	return nil, nil
 }
+func (m *mockRepo) CompleteManually(ctx context.Context, id int64) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	if job.Status != domain.StatusPending && job.Status != domain.StatusFailed {
+		return domain.ErrJobState
+	}
+	job.Status = domain.StatusCompleted
+	return nil
+}
+func (m *mockRepo) CreateHeld(ctx context.Context, url string) (*domain.Job, error) {
+	job, _ := m.Create(ctx, url)
+	job.Held = true
+	return job, nil
+}
+func (m *mockRepo) SetHeld(ctx context.Context, id int64, held bool) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	if job.Status != domain.StatusPending {
+		return domain.ErrJobState
+	}
+	job.Held = held
+	return nil
+}
+func (m *mockRepo) SetNotes(ctx context.Context, id int64, notes string) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	job.Notes = notes
+	return nil
+}
+func (m *mockRepo) ListCompleted(ctx context.Context, f domain.CompletedFilter) ([]domain.Job, error) {
+	var result []domain.Job
+	for id := int64(1); id < m.nextID; id++ {
+		job, ok := m.jobs[id]
+		if ok && job.Status == domain.StatusCompleted && f.Match(job) {
+			result = append(result, *job)
+		}
+	}
+	return result, nil
+}
+func (m *mockRepo) CreateForApproval(ctx context.Context, url string) (*domain.Job, error) {
+	job, _ := m.Create(ctx, url)
+	job.Status = domain.StatusNeedsApproval
+	return job, nil
+}
+func (m *mockRepo) Approve(ctx context.Context, id int64) error {
+	return m.decide(id, domain.StatusPending, "")
+}
+func (m *mockRepo) RequestApproval(ctx context.Context, id int64, reason string) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	job.Status, job.Error = domain.StatusNeedsApproval, reason
+	return nil
+}
+func (m *mockRepo) Reject(ctx context.Context, id int64, reason string) error {
+	return m.decide(id, domain.StatusFailed, reason)
+}
+func (m *mockRepo) decide(id int64, status domain.JobStatus, reason string) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	if job.Status != domain.StatusNeedsApproval {
+		return domain.ErrJobState
+	}
+	job.Status, job.Error = status, reason
+	return nil
+}
+func (m *mockRepo) RequeueFailed(ctx context.Context, f domain.BulkFilter) (int64, error) {
+	return m.bulk(domain.StatusFailed, domain.StatusPending, f, ""), nil
+}
+func (m *mockRepo) CancelPending(ctx context.Context, f domain.BulkFilter, reason string) (int64, error) {
+	return m.bulk(domain.StatusPending, domain.StatusFailed, f, reason), nil
+}
+func (m *mockRepo) bulk(from, to domain.JobStatus, f domain.BulkFilter, reason string) int64 {
+	var n int64
+	for _, job := range m.jobs {
+		if job.Status == from && f.Match(job.URL) {
+			job.Status, job.Error = to, reason
+			n++
+		}
+	}
+	return n
+}
+func (m *mockRepo) FindRecent(ctx context.Context, url string, since time.Time) (*domain.Job, error) {
+	for _, job := range m.jobs {
+		if job.URL == url && !job.CreatedAt.Before(since) {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockRepo) List(ctx context.Context, filter domain.JobFilter) ([]domain.Job, error) {
+	var result []domain.Job
+	start := m.nextID - 1
+	if filter.After != 0 {
+		start = min(start, filter.After-1)
+	}
+	for id := start; id > 0 && len(result) < filter.Limit; id-- {
+		job, ok := m.jobs[id]
+		if ok && (filter.Status == "" || job.Status == filter.Status) && (domain.BulkFilter{Host: filter.Host}).Match(job.URL) {
+			result = append(result, *job)
+		}
+	}
+	return result, nil
+}
 func (m *mockRepo) Claim(ctx context.Context, id int64) error                   { return nil }
-func (m *mockRepo) Complete(ctx context.Context, id int64) error                { return nil }
+func (m *mockRepo) Complete(ctx context.Context, id int64, c domain.Completion) error { return nil }
 func (m *mockRepo) Fail(ctx context.Context, id int64, reason string) error     { return nil }
 func (m *mockRepo) Retry(ctx context.Context, id int64, reason string) error    { return nil }
 func (m *mockRepo) RecoverStale(ctx context.Context) (int64, error)             { return 0, nil }
 
+// assertErrorCode decodes an error envelope and checks its code.
+func assertErrorCode(t *testing.T, rec *httptest.ResponseRecorder, code string) {
+	t.Helper()
+	var resp errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.Error.Code != code {
+		t.Errorf("error code = %q, want %q", resp.Error.Code, code)
+	}
+	if resp.Error.Message == "" {
+		t.Error("error message is empty")
+	}
+}
+
 func setupTestServer() *Server {
 	repo := newMockRepo()
 	svc := domain.NewJobService(repo)
+	svc.SetUIDResolver(repo)
 	return NewServer(svc, ":8080", "")
 }
 
@@ -91,6 +237,75 @@ func TestServer_Webhook_Success(t *testing.T) {
 	}
 }
 
+func TestServer_Webhook_Links(t *testing.T) {
+	srv := setupTestServer()
+	srv.SetBasePath("/catcher")
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/catcher/webhook", bytes.NewBufferString(`{"url":"https://example.com"}`)))
+
+	var resp struct {
+		UID   string   `json:"uid"`
+		Links jobLinks `json:"links"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	self := "/catcher/jobs/" + resp.UID
+	if resp.Links.Self != self || resp.Links.Log != self+"/bundle" {
+		t.Errorf("links = %+v, want self %q and its bundle", resp.Links, self)
+	}
+	if got := rec.Header().Get("Location"); got != self {
+		t.Errorf("Location = %q, want %q", got, self)
+	}
+	if got, want := rec.Header().Get("Link"), `<`+self+`>; rel="self", <`+self+`/bundle>; rel="log"`; got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+
+	// The links work even when numeric IDs are refused
+	srv.SetRequireUID(true)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, self, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET Location = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestServer_RequireUIDListing(t *testing.T) {
+	tests := []struct {
+		name       string
+		requireUID bool
+		adminToken string
+		auth       string
+		wantCode   int // 0 for any but 401 and 403
+	}{
+		{"open without require_uid", false, "", "", 0},
+		{"no admin token", true, "", "", http.StatusForbidden},
+		{"unauthenticated", true, "secret", "", http.StatusUnauthorized},
+		{"wrong token", true, "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"admin", true, "secret", "Bearer secret", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := setupTestServer()
+			srv.SetRequireUID(tt.requireUID)
+			srv.SetAdminToken(tt.adminToken)
+			for _, path := range []string{"/jobs", "/jobs/manifest", "/library/search?q=a", "/integrity"} {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.auth != "" {
+					req.Header.Set("Authorization", tt.auth)
+				}
+				rec := httptest.NewRecorder()
+				srv.ServeHTTP(rec, req)
+				denied := rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden
+				if tt.wantCode == 0 && denied || tt.wantCode != 0 && rec.Code != tt.wantCode {
+					t.Errorf("GET %s = %d, want %d (0 for allowed)", path, rec.Code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
+
 func TestServer_Webhook_MissingURL(t *testing.T) {
 	srv := setupTestServer()
 
@@ -104,6 +319,7 @@ func TestServer_Webhook_MissingURL(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
+	assertErrorCode(t, rec, CodeBadRequest)
 }
 
 func TestServer_Webhook_InvalidURL(t *testing.T) {
@@ -119,6 +335,104 @@ func TestServer_Webhook_InvalidURL(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
+	assertErrorCode(t, rec, CodeInvalidURL)
+}
+
+func TestServer_Webhook_Source(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		wantStatus int
+	}{
+		{"none", "", http.StatusCreated},
+		{"user", "alice@phone", http.StatusCreated},
+		{"bad characters", "alice phone", http.StatusBadRequest},
+		{"too long", strings.Repeat("a", 65), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := setupTestServer()
+			body, _ := json.Marshal(webhookRequest{URL: "https://example.com/v", Source: tt.source})
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Code != http.StatusCreated {
+				assertErrorCode(t, rec, CodeBadRequest)
+				return
+			}
+			var resp jobResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if resp.Source != tt.source {
+				t.Errorf("response source = %q, want %q", resp.Source, tt.source)
+			}
+		})
+	}
+}
+
+func TestServer_Webhook_Bookmark(t *testing.T) {
+	for _, bookmark := range []bool{false, true} {
+		srv := setupTestServer()
+		body, _ := json.Marshal(webhookRequest{URL: "https://example.com/v", Bookmark: bookmark})
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+		}
+		var resp jobResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if resp.Bookmark != bookmark {
+			t.Errorf("response bookmark = %v, want %v", resp.Bookmark, bookmark)
+		}
+	}
+}
+
+func TestServer_Webhook_UserAgent(t *testing.T) {
+	tests := []struct {
+		name       string
+		userAgent  string
+		wantStatus int
+	}{
+		{name: "none", wantStatus: http.StatusCreated},
+		{name: "set", userAgent: "Mozilla/5.0 (iPhone)", wantStatus: http.StatusCreated},
+		{name: "flag", userAgent: "--exec=rm", wantStatus: http.StatusBadRequest},
+		{name: "newline", userAgent: "a\nb", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := setupTestServer()
+			body, _ := json.Marshal(webhookRequest{URL: "https://example.com/v", UserAgent: tt.userAgent})
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusCreated {
+				assertErrorCode(t, rec, CodeBadRequest)
+				return
+			}
+			var resp jobResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if resp.UserAgent != tt.userAgent {
+				t.Errorf("response user_agent = %q, want %q", resp.UserAgent, tt.userAgent)
+			}
+		})
+	}
 }
 
 func TestServer_Webhook_InvalidJSON(t *testing.T) {
@@ -168,6 +482,31 @@ func TestServer_GetJob_Success(t *testing.T) {
 	}
 }
 
+func TestServer_GetJob_Convenience(t *testing.T) {
+	repo := newMockRepo()
+	job, _ := repo.Create(context.Background(), "https://example.com")
+	job.CreatedAt = time.Now().Add(-90 * time.Second)
+	job.Duration = 1500 * time.Millisecond
+	srv := NewServer(domain.NewJobService(repo), ":8080", "")
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/1", nil))
+
+	var resp jobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.AgeSeconds < 90 || resp.AgeSeconds > 95 {
+		t.Errorf("age_seconds = %d, want about 90", resp.AgeSeconds)
+	}
+	if resp.DurationSeconds != 1.5 {
+		t.Errorf("duration_seconds = %v, want 1.5", resp.DurationSeconds)
+	}
+	if resp.CreatedAt == "" {
+		t.Error("created_at missing alongside age_seconds")
+	}
+}
+
 func TestServer_GetJob_NotFound(t *testing.T) {
 	srv := setupTestServer()
 
@@ -178,6 +517,7 @@ func TestServer_GetJob_NotFound(t *testing.T) {
 	if rec.Code != http.StatusNotFound {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
 	}
+	assertErrorCode(t, rec, CodeNotFound)
 }
 
 func TestServer_GetJob_InvalidID(t *testing.T) {
@@ -192,6 +532,42 @@ func TestServer_GetJob_InvalidID(t *testing.T) {
 	}
 }
 
+func TestServer_GetJob_UID(t *testing.T) {
+	srv := setupTestServer()
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(`{"url":"https://example.com"}`)))
+	var created jobResponse
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.UID == "" {
+		t.Fatal("created job has no uid")
+	}
+
+	get := func(ref string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+ref, nil))
+		return rec
+	}
+	for _, requireUID := range []bool{false, true} {
+		srv.SetRequireUID(requireUID)
+		rec := get(created.UID)
+		var resp jobResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != http.StatusOK || resp.ID != created.ID {
+			t.Errorf("require %v: get by UID = %d, job %d, want job %d", requireUID, rec.Code, resp.ID, created.ID)
+		}
+		if rec := get(domain.NewUID()); rec.Code != http.StatusNotFound {
+			t.Errorf("require %v: get by unknown UID = %d, want %d", requireUID, rec.Code, http.StatusNotFound)
+		}
+	}
+
+	// Numeric IDs look like missing jobs, so they give nothing away
+	rec = get("1")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get by ID with UIDs required = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	assertErrorCode(t, rec, CodeNotFound)
+}
+
 func TestServer_Health(t *testing.T) {
 	srv := setupTestServer()
 
@@ -213,6 +589,88 @@ func TestServer_Health(t *testing.T) {
 	}
 }
 
+// stubReplication is a fixed ReplicationMonitor.
+type stubReplication struct {
+	lag     time.Duration
+	synced  bool
+	lagging bool
+	backup  bool
+}
+
+func (s stubReplication) Lag() (time.Duration, bool) { return s.lag, s.synced }
+func (s stubReplication) Lagging() bool              { return s.lagging }
+func (s stubReplication) BackupInProgress() bool     { return s.backup }
+
+func TestServer_Health_Replication(t *testing.T) {
+	tests := []struct {
+		name string
+		repl stubReplication
+		want string
+	}{
+		{"synced", stubReplication{lag: 1500 * time.Millisecond, synced: true, backup: true},
+			`{"status":"ok","replication":{"lag_seconds":1.5,"lagging":false,"backup_in_progress":true}}`},
+		{"lagging", stubReplication{lag: 10 * time.Minute, synced: true, lagging: true},
+			`{"status":"degraded","replication":{"lag_seconds":600,"lagging":true,"backup_in_progress":false}}`},
+		{"never synced", stubReplication{lagging: true},
+			`{"status":"degraded","replication":{"lag_seconds":null,"lagging":true,"backup_in_progress":false}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := setupTestServer()
+			srv.SetReplication(tt.repl)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// stubConnectivity is a fixed domain.ConnectivityMonitor.
+type stubConnectivity domain.Connectivity
+
+func (s stubConnectivity) Connectivity() domain.Connectivity { return domain.Connectivity(s) }
+func (s stubConnectivity) Check(context.Context) domain.Connectivity {
+	return domain.Connectivity(s)
+}
+
+func TestServer_Health_Connectivity(t *testing.T) {
+	since := time.Date(2030, 1, 1, 2, 0, 0, 0, time.UTC)
+	checked := since.Add(time.Minute)
+	tests := []struct {
+		name string
+		conn stubConnectivity
+		want string
+	}{
+		{"unchecked", stubConnectivity{Online: true, Target: "https://example.com"},
+			`{"status":"ok","connectivity":{"online":true,"target":"https://example.com","since":null,"checked_at":null}}`},
+		{"online", stubConnectivity{Online: true, Target: "https://example.com", Since: since, CheckedAt: checked},
+			`{"status":"ok","connectivity":{"online":true,"target":"https://example.com","since":"2030-01-01T02:00:00Z","checked_at":"2030-01-01T02:01:00Z"}}`},
+		{"offline", stubConnectivity{Target: "https://example.com", Since: since, CheckedAt: checked, Error: "no route to host"},
+			`{"status":"degraded","connectivity":{"online":false,"target":"https://example.com","since":"2030-01-01T02:00:00Z","checked_at":"2030-01-01T02:01:00Z","error":"no route to host"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := setupTestServer()
+			srv.SetConnectivity(tt.conn)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestServer_ContentType(t *testing.T) {
 	srv := setupTestServer()
 
@@ -314,6 +772,33 @@ func TestServer_Webhook_TimestampTooOld(t *testing.T) {
 	}
 }
 
+func TestServer_Webhook_TimestampFollowsClock(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	srv := NewServer(svc, ":8080", "test-secret")
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	srv.SetClock(clock)
+
+	timestamp := clock.Now().Format(time.RFC3339)
+	send := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Timestamp", timestamp)
+		req.Header.Set("X-Signature", computeSignature(timestamp, body, "test-secret"))
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send(`{"url":"https://example.com/a"}`); code != http.StatusCreated {
+		t.Errorf("status = %d, want %d for a timestamp matching the clock", code, http.StatusCreated)
+	}
+	clock.Advance(maxTimestampSkew + time.Second)
+	if code := send(`{"url":"https://example.com/b"}`); code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d once the clock has moved past the skew", code, http.StatusUnauthorized)
+	}
+}
+
 func TestServer_Webhook_MissingSignature(t *testing.T) {
 	repo := newMockRepo()
 	svc := domain.NewJobService(repo)
@@ -353,6 +838,7 @@ func TestServer_Webhook_InvalidSignature(t *testing.T) {
 	if rec.Code != http.StatusUnauthorized {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
 	}
+	assertErrorCode(t, rec, CodeUnauthorized)
 }
 
 func TestServer_Webhook_NoSecretConfigured(t *testing.T) {
@@ -371,3 +857,236 @@ func TestServer_Webhook_NoSecretConfigured(t *testing.T) {
 		t.Errorf("status = %d, want %d (no secret = no verification)", rec.Code, http.StatusCreated)
 	}
 }
+
+func TestServer_Ready(t *testing.T) {
+	srv := setupTestServer()
+	srv.SetInFlight(func() int { return 2 })
+
+	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	srv.Drain()
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	var resp readyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.Status != "draining" {
+		t.Errorf("status = %q, want %q", resp.Status, "draining")
+	}
+	if resp.InFlight != 2 {
+		t.Errorf("in_flight = %d, want 2", resp.InFlight)
+	}
+}
+
+func TestServer_Webhook_ValidationRejected(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.AddValidator(domain.SchemeAllowlist("http", "https"))
+	srv := NewServer(svc, ":8080", "")
+
+	body := `{"url":"javascript:alert(1)"}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	assertErrorCode(t, rec, CodeURLRejected)
+}
+
+func TestServer_Webhook_Duplicate(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetDedupeWindow(repo, time.Minute)
+	srv := NewServer(svc, ":8080", "")
+
+	submit := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(`{"url":"https://example.com/video"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := submit(); rec.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	rec := submit()
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if !strings.Contains(rec.Body.String(), `"job_id":"1"`) {
+		t.Errorf("body = %s, want details.job_id of the first job", rec.Body.String())
+	}
+	assertErrorCode(t, rec, CodeDuplicate)
+	if len(repo.jobs) != 1 {
+		t.Errorf("created %d jobs, want 1", len(repo.jobs))
+	}
+}
+
+func TestServer_GetJob_NotModified(t *testing.T) {
+	srv := setupTestServer()
+
+	createReq := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(`{"url":"https://example.com"}`))
+	srv.ServeHTTP(httptest.NewRecorder(), createReq)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/1", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag header missing")
+	}
+	if rec.Header().Get("Last-Modified") == "" {
+		t.Error("Last-Modified header missing")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/jobs/1", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body length = %d, want 0", rec.Body.Len())
+	}
+}
+
+func TestServer_ListJobs(t *testing.T) {
+	srv := setupTestServer()
+	for _, u := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(`{"url":"`+u+`"}`))
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs?limit=2&compact=true", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Jobs []map[string]any `json:"jobs"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp.Jobs) != 2 {
+		t.Fatalf("len(jobs) = %d, want 2", len(resp.Jobs))
+	}
+	if resp.Jobs[0]["url"] != "https://example.com/3" {
+		t.Errorf("first job url = %v, want newest first", resp.Jobs[0]["url"])
+	}
+	if _, ok := resp.Jobs[0]["created_at"]; ok {
+		t.Error("compact listing includes created_at")
+	}
+
+	// Unchanged listing revalidates to 304
+	etag := rec.Header().Get("ETag")
+	req = httptest.NewRequest(http.MethodGet, "/jobs?limit=2&compact=true", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+}
+
+func TestServer_ListJobs_Cursor(t *testing.T) {
+	srv := setupTestServer()
+	for _, u := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(`{"url":"`+u+`"}`))
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var urls []any
+	query := "/jobs?limit=2"
+	for pages := 0; query != ""; pages++ {
+		if pages > 2 {
+			t.Fatal("listing never ends")
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", query, rec.Code, http.StatusOK)
+		}
+
+		var resp struct {
+			Jobs       []map[string]any `json:"jobs"`
+			NextCursor string           `json:"next_cursor"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		for _, job := range resp.Jobs {
+			urls = append(urls, job["url"])
+		}
+		query = ""
+		if resp.NextCursor != "" {
+			query = "/jobs?limit=2&cursor=" + resp.NextCursor
+		}
+
+		// Jobs arriving mid-scroll don't shift later pages
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(`{"url":"https://example.com/new"}`))
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	want := []any{"https://example.com/3", "https://example.com/2", "https://example.com/1"}
+	if !slices.Equal(urls, want) {
+		t.Errorf("paged urls = %v, want %v", urls, want)
+	}
+}
+
+func TestServer_ListJobs_BadQuery(t *testing.T) {
+	srv := setupTestServer()
+
+	for _, query := range []string{"status=bogus", "limit=0", "limit=abc", "fields=nope", "cursor=0", "cursor=abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/jobs?"+query, nil)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServer_GetJob_Fields(t *testing.T) {
+	srv := setupTestServer()
+	createReq := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(`{"url":"https://example.com"}`))
+	srv.ServeHTTP(httptest.NewRecorder(), createReq)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/1?fields=id,status", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(resp) != 2 || resp["status"] != "pending" {
+		t.Errorf("response = %v, want id and status only", resp)
+	}
+}
@@ -0,0 +1,294 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/qr"
+)
+
+// shareSource is the source of jobs submitted through /share.
+const shareSource = "share"
+
+// SetShare serves GET /share/qr, a page for a TV or other screen showing a
+// QR code, and GET and POST /share, the form the code opens on a phone.
+// The code's link carries a token valid for ttl, so the form submits
+// without the webhook secret; the page renews it before it expires.
+// With an admin token or webhook secret set, /share/qr needs one of them
+// once, after which a session cookie keeps the screen unlocked. Tokens
+// and sessions are signed with a key made here, so they don't outlive the
+// process.
+func (s *Server) SetShare(ttl time.Duration) {
+	s.shareKey = make([]byte, 32)
+	rand.Read(s.shareKey)
+	s.shareTTL = ttl
+	s.mux.HandleFunc("GET /share/qr", s.handleShareQR)
+	s.mux.HandleFunc("GET /share", s.handleShareForm)
+	s.mux.HandleFunc("POST /share", s.handleShareSubmit)
+}
+
+// Share token purposes, so a token for one can't pass for the other.
+const (
+	shareForm    = "share"   // opens the form on a phone
+	shareSession = "session" // unlocks /share/qr on a screen
+)
+
+// shareSessionTTL is how long a screen stays unlocked without loading
+// /share/qr. Every load renews it.
+const shareSessionTTL = 30 * 24 * time.Hour
+
+// shareCookie holds the session of a screen showing /share/qr.
+const shareCookie = "catcher_share"
+
+// shareToken returns a token for purpose valid until expires.
+func (s *Server) shareToken(purpose string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + s.shareMAC(purpose, exp)
+}
+
+func (s *Server) shareMAC(purpose, exp string) string {
+	mac := hmac.New(sha256.New, s.shareKey)
+	mac.Write([]byte(purpose + "\n" + exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// validShareToken reports whether token was made by this server for
+// purpose and has not expired.
+func (s *Server) validShareToken(purpose, token string) bool {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !s.clock.Now().Before(time.Unix(unix, 0)) {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(s.shareMAC(purpose, exp)))
+}
+
+// shareKeyValid reports whether key is the admin token or the webhook
+// secret.
+func (s *Server) shareKeyValid(key string) bool {
+	for _, want := range []string{s.adminToken, s.secret} {
+		if want != "" && subtle.ConstantTimeCompare([]byte(key), []byte(want)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// shareUnlocked reports whether r may load /share/qr: anyone may without
+// an admin token or webhook secret to check, else the admin token or a
+// session cookie is needed.
+func (s *Server) shareUnlocked(r *http.Request) bool {
+	if s.adminToken == "" && s.secret == "" {
+		return true
+	}
+	if s.isAdmin(r) {
+		return true
+	}
+	c, err := r.Cookie(shareCookie)
+	return err == nil && s.validShareToken(shareSession, c.Value)
+}
+
+// setShareSession gives the screen making r a session valid for
+// shareSessionTTL.
+func (s *Server) setShareSession(w http.ResponseWriter, r *http.Request) {
+	expires := s.clock.Now().Add(shareSessionTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     shareCookie,
+		Value:    s.shareToken(shareSession, expires),
+		Path:     s.basePath + "/share",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// shareLink returns the absolute /share URL for token.
+func (s *Server) shareLink(r *http.Request, token string) string {
+	return s.absoluteURL(r, "/share?token="+url.QueryEscape(token))
+}
+
+// absoluteURL returns the URL of path under the base path, on the host the
+// request came in on, for links that leave the browser.
+func (s *Server) absoluteURL(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + s.basePath + path
+}
+
+// sharePage is what the share templates render.
+type sharePage struct {
+	Title   string
+	Refresh int // seconds until the page reloads itself; zero never
+	QR      template.HTML
+	Link    string
+	Expires string
+	Token   string
+	URL     string
+	Queued  string
+	Error   string
+}
+
+var shareTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+{{- if .Refresh}}
+<meta http-equiv="refresh" content="{{.Refresh}}">
+{{- end}}
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- if .QR}}
+<p>Scan with a phone to send a URL to catcher.</p>
+<p>{{.QR}}</p>
+<p><a href="{{.Link}}">{{.Link}}</a></p>
+<p>Valid until {{.Expires}}; this page shows a new code before then.</p>
+{{- end}}
+{{- if .Error}}
+<p><strong>{{.Error}}</strong></p>
+{{- end}}
+{{- if .Queued}}
+<p>Queued as job {{.Queued}}.</p>
+{{- end}}
+{{- if .Token}}
+<form method="post" action="share">
+<input type="hidden" name="token" value="{{.Token}}">
+<p><label>URL <input type="url" name="url" value="{{.URL}}" required autofocus></label></p>
+<p><button type="submit">Send</button></p>
+</form>
+{{- end}}
+</body>
+</html>
+`))
+
+func (s *Server) writeSharePage(w http.ResponseWriter, status int, page sharePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := shareTemplate.Execute(w, page); err != nil {
+		log.Printf("failed to render share page: %v", err)
+	}
+}
+
+// lockedShare is shown on a screen that hasn't been unlocked.
+var lockedShare = sharePage{Title: "Share", Error: "Open this page once as /share/qr?key= followed by the admin token or webhook secret to unlock this screen."}
+
+// handleShareQR shows a QR code linking to the share form with a fresh
+// token. A key parameter holding the admin token or webhook secret
+// unlocks the screen, then redirects to drop the key from the address.
+func (s *Server) handleShareQR(w http.ResponseWriter, r *http.Request) {
+	if key := r.URL.Query().Get("key"); key != "" {
+		if !s.shareKeyValid(key) {
+			s.writeSharePage(w, http.StatusUnauthorized, lockedShare)
+			return
+		}
+		s.setShareSession(w, r)
+		http.Redirect(w, r, s.basePath+"/share/qr", http.StatusSeeOther)
+		return
+	}
+	if !s.shareUnlocked(r) {
+		s.writeSharePage(w, http.StatusUnauthorized, lockedShare)
+		return
+	}
+	if _, err := r.Cookie(shareCookie); err == nil {
+		s.setShareSession(w, r)
+	}
+
+	expires := s.clock.Now().Add(s.shareTTL)
+	link := s.shareLink(r, s.shareToken(shareForm, expires))
+	code, err := qr.Encode(link)
+	if err != nil {
+		log.Printf("failed to encode share link: %v", err)
+		s.writeSharePage(w, http.StatusInternalServerError, sharePage{Title: "Share", Error: "The share link is too long for a QR code."})
+		return
+	}
+	s.writeSharePage(w, http.StatusOK, sharePage{
+		Title:   "Share",
+		Refresh: max(int(s.shareTTL/2/time.Second), 1),
+		QR:      template.HTML(code.SVG(8)),
+		Link:    link,
+		Expires: expires.Format(time.Kitchen),
+	})
+}
+
+// expiredShare is shown for a missing, forged, or expired token.
+var expiredShare = sharePage{Title: "Share", Error: "This link has expired. Scan the code again."}
+
+// handleShareForm shows the form for submitting a URL, prefilled from the
+// url parameter, and the job queued by the last submission, if any.
+func (s *Server) handleShareForm(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if !s.validShareToken(shareForm, token) {
+		s.writeSharePage(w, http.StatusUnauthorized, expiredShare)
+		return
+	}
+	s.writeSharePage(w, http.StatusOK, sharePage{
+		Title:  "Send to catcher",
+		Token:  token,
+		URL:    r.URL.Query().Get("url"),
+		Queued: r.URL.Query().Get("queued"),
+	})
+}
+
+// handleShareSubmit queues the form's URL, then redirects back to the
+// form so reloading doesn't submit it again.
+func (s *Server) handleShareSubmit(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		s.writeSharePage(w, http.StatusBadRequest, sharePage{Title: "Send to catcher", Error: "The form could not be read."})
+		return
+	}
+	token, rawURL := r.PostForm.Get("token"), strings.TrimSpace(r.PostForm.Get("url"))
+	if !s.validShareToken(shareForm, token) {
+		s.writeSharePage(w, http.StatusUnauthorized, expiredShare)
+		return
+	}
+	page := sharePage{Title: "Send to catcher", Token: token, URL: rawURL}
+
+	job, err := s.svc.Submit(domain.WithSource(r.Context(), shareSource), rawURL)
+	var ve *domain.ValidationError
+	var de *domain.DuplicateError
+	switch {
+	case err == nil:
+		ref := job.UID
+		if ref == "" {
+			ref = strconv.FormatInt(job.ID, 10)
+		}
+		http.Redirect(w, r, fmt.Sprintf("%s/share?token=%s&queued=%s", s.basePath, url.QueryEscape(token), url.QueryEscape(ref)), http.StatusSeeOther)
+		return
+	case errors.Is(err, domain.ErrInvalidURL):
+		page.Error = "That is not a valid URL."
+		s.writeSharePage(w, http.StatusBadRequest, page)
+	case errors.As(err, &ve):
+		page.Error = "That URL was rejected: " + ve.Error()
+		s.writeSharePage(w, http.StatusUnprocessableEntity, page)
+	case errors.As(err, &de):
+		page.Error = fmt.Sprintf("That URL is already job %d.", de.Job.ID)
+		s.writeSharePage(w, http.StatusConflict, page)
+	default:
+		log.Printf("share submit error: %v", err)
+		page.Error = "Something went wrong; try again."
+		s.writeSharePage(w, http.StatusInternalServerError, page)
+	}
+}
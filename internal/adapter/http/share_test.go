@@ -0,0 +1,158 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// shareLinkPattern finds the link a QR page shows.
+var shareLinkPattern = regexp.MustCompile(`href="(http://catcher\.lan/catcher/share\?token=[^"]+)"`)
+
+func TestServer_Share(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	srv := NewServer(svc, ":8080", "webhook-secret")
+	srv.SetBasePath("/catcher")
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	srv.SetClock(clock)
+	srv.SetShare(10 * time.Minute)
+
+	// The webhook secret unlocks the screen once, then the cookie does
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://catcher.lan/catcher/share/qr?key=webhook-secret", nil))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/catcher/share/qr" {
+		t.Fatalf("GET /share/qr?key= = %d to %q, want a redirect dropping the key", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != shareCookie || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %+v, want an HttpOnly session cookie", cookies)
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://catcher.lan/catcher/share/qr", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /share/qr status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	page := rec.Body.String()
+	if !strings.Contains(page, "<svg") || !strings.Contains(page, `<meta http-equiv="refresh" content="300">`) {
+		t.Errorf("QR page lacks the code or its refresh:\n%s", page)
+	}
+	m := shareLinkPattern.FindStringSubmatch(page)
+	if m == nil {
+		t.Fatalf("QR page lacks the share link:\n%s", page)
+	}
+	link, _ := url.Parse(strings.ReplaceAll(m[1], "&amp;", "&"))
+	token := link.Query().Get("token")
+
+	// The form opens with the token and submits without the webhook secret
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catcher/share?token="+url.QueryEscape(token)+"&url=https%3A%2F%2Fexample.com%2Fv", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `value="https://example.com/v"`) {
+		t.Fatalf("GET /share = %d, want the form prefilled:\n%s", rec.Code, rec.Body)
+	}
+
+	submit := func(token, target string) *httptest.ResponseRecorder {
+		form := url.Values{"token": {token}, "url": {target}}
+		req := httptest.NewRequest(http.MethodPost, "/catcher/share", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	rec = submit(token, "https://example.com/v")
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("POST /share status = %d, want %d:\n%s", rec.Code, http.StatusSeeOther, rec.Body)
+	}
+	job := repo.jobs[1]
+	if job == nil || job.URL != "https://example.com/v" || job.Source != shareSource {
+		t.Fatalf("queued job = %+v, want the URL from %s", job, shareSource)
+	}
+	if loc := rec.Header().Get("Location"); !strings.HasPrefix(loc, "/catcher/share?token=") || !strings.HasSuffix(loc, "&queued="+job.UID) {
+		t.Errorf("Location = %q, want the form noting the queued job", loc)
+	}
+
+	if rec := submit(token, "not a url"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid URL status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := submit(token[:len(token)-1]+"x", "https://example.com/w"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("forged token status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	clock.Advance(10 * time.Minute)
+	if rec := submit(token, "https://example.com/w"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired token status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if len(repo.jobs) != 1 {
+		t.Errorf("%d jobs queued, want only the valid submission", len(repo.jobs))
+	}
+}
+
+func TestServer_ShareQRAuth(t *testing.T) {
+	newServer := func(secret, admin string) *Server {
+		srv := NewServer(domain.NewJobService(newMockRepo()), ":8080", secret)
+		srv.SetAdminToken(admin)
+		srv.SetShare(10 * time.Minute)
+		return srv
+	}
+	srv := newServer("webhook-secret", "admin-token")
+	session := &http.Cookie{Name: shareCookie, Value: srv.shareToken(shareSession, time.Now().Add(time.Hour))}
+	formToken := &http.Cookie{Name: shareCookie, Value: srv.shareToken(shareForm, time.Now().Add(time.Hour))}
+	expired := &http.Cookie{Name: shareCookie, Value: srv.shareToken(shareSession, time.Now().Add(-time.Hour))}
+
+	tests := []struct {
+		name   string
+		srv    *Server
+		path   string
+		auth   string
+		cookie *http.Cookie
+		want   int
+	}{
+		{name: "unauthenticated", srv: srv, path: "/share/qr", want: http.StatusUnauthorized},
+		{name: "wrong key", srv: srv, path: "/share/qr?key=guess", want: http.StatusUnauthorized},
+		{name: "admin key", srv: srv, path: "/share/qr?key=admin-token", want: http.StatusSeeOther},
+		{name: "admin token", srv: srv, path: "/share/qr", auth: "Bearer admin-token", want: http.StatusOK},
+		{name: "wrong admin token", srv: srv, path: "/share/qr", auth: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "session", srv: srv, path: "/share/qr", cookie: session, want: http.StatusOK},
+		{name: "expired session", srv: srv, path: "/share/qr", cookie: expired, want: http.StatusUnauthorized},
+		{name: "form token as session", srv: srv, path: "/share/qr", cookie: formToken, want: http.StatusUnauthorized},
+		{name: "session from another process", srv: newServer("webhook-secret", ""), path: "/share/qr", cookie: session, want: http.StatusUnauthorized},
+		{name: "nothing to check", srv: newServer("", ""), path: "/share/qr", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			rec := httptest.NewRecorder()
+			tt.srv.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("GET %s status = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && strings.Contains(rec.Body.String(), "token=") {
+				t.Errorf("locked page leaks a share token:\n%s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestServer_ShareDisabled(t *testing.T) {
+	srv := setupTestServer()
+	for _, path := range []string{"/share/qr", "/share?token=x"} {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s status = %d, want %d", path, rec.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/urlscan"
+)
+
+// shimItem is something a third-party payload asks to download.
+type shimItem struct {
+	URL   string
+	Title string // kept as the job's notes
+}
+
+// shims parse the payloads of services that can't send POST /webhook's,
+// by the format in POST /webhook/{format}. They return no items when the
+// payload holds no URL.
+var shims = map[string]func(body []byte) ([]shimItem, error){
+	"ntfy":   parseNtfy,
+	"gotify": parseGotify,
+	"rss":    parseFeed,
+}
+
+// shimResponse is the JSON response for POST /webhook/{format}.
+type shimResponse struct {
+	Jobs []webhookResponse `json:"jobs"`
+	// Errors are the URLs that couldn't be submitted, with why.
+	Errors []shimError `json:"errors,omitempty"`
+}
+
+type shimError struct {
+	URL   string   `json:"url"`
+	Error apiError `json:"error"`
+}
+
+// handleShim submits the URLs in a payload from a service such as ntfy,
+// Gotify, or an RSS-to-webhook bridge. With a webhook secret, the request
+// must carry it or a granted token, as these services can't sign requests;
+// signed ones are accepted too. The query sets what the payload can't:
+// source, which defaults to the format, hold, bookmark, and confirm. A
+// payload with more URLs than max_batch is refused unless confirm or hold
+// is set, or with the overflow held, submitted with the URLs past max_batch
+// held.
+func (s *Server) handleShim(w http.ResponseWriter, r *http.Request) {
+	format := r.PathValue("format")
+	parse, ok := shims[format]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("unknown webhook format %q", format))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			s.writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+			return
+		}
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "failed to read request body")
+		return
+	}
+	var granted bool
+	if s.secret != "" && r.Header.Get("X-Signature") == "" {
+		r, granted = s.withGrant(r)
+	}
+	if s.secret != "" && !granted {
+		if err := s.verifyShim(r, body); err != nil {
+			s.writeError(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+			return
+		}
+	}
+
+	q := r.URL.Query()
+	source := q.Get("source")
+	if source == "" {
+		source = format
+	} else if !validSource.MatchString(source) {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "source must be 1-64 letters, digits, or ._@-")
+		return
+	}
+	var hold, bookmark, confirm bool
+	for name, v := range map[string]*bool{"hold": &hold, "bookmark": &bookmark, "confirm": &confirm} {
+		if q.Has(name) {
+			if *v, err = strconv.ParseBool(q.Get(name)); err != nil {
+				s.writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid %s: must be true or false", name))
+				return
+			}
+		}
+	}
+
+	items, err := parse(body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+		return
+	}
+	if len(items) == 0 {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "no URL found in the payload")
+		return
+	}
+
+	overflow := !confirm && !hold && s.maxBatch > 0 && len(items) > s.maxBatch
+	if overflow && !s.holdOverflow {
+		s.writeErrorDetails(w, http.StatusUnprocessableEntity, CodeBatch,
+			fmt.Sprintf("payload has %d URLs, more than the %d submitted at once: send it again with confirm=true to submit them all", len(items), s.maxBatch),
+			map[string]string{"count": strconv.Itoa(len(items)), "max_batch": strconv.Itoa(s.maxBatch)})
+		return
+	}
+
+	submit := s.svc.Submit
+	if hold {
+		submit = s.svc.SubmitHeld
+	}
+	resp := shimResponse{Jobs: []webhookResponse{}}
+	status, first := 0, apiError{}
+	for i, item := range items {
+		ctx := domain.WithNotes(domain.WithSource(r.Context(), source), domain.TruncateNotes(item.Title))
+		if bookmark {
+			ctx = domain.WithBookmark(ctx)
+		}
+		if overflow && i == s.maxBatch {
+			// The rest wait to be released, so a whole channel pasted by
+			// mistake doesn't start downloading
+			submit = s.svc.SubmitHeld
+		}
+		job, err := submit(ctx, item.URL)
+		if err != nil {
+			st, apiErr := submitError(err)
+			if status == 0 {
+				status, first = st, apiErr
+			}
+			resp.Errors = append(resp.Errors, shimError{URL: item.URL, Error: apiErr})
+			continue
+		}
+		resp.Jobs = append(resp.Jobs, webhookResponse{jobResponse: s.jobToResponse(job), Links: s.jobLinks(job)})
+	}
+	if len(resp.Jobs) == 0 {
+		// Nothing submitted: fail as POST /webhook would for the first URL
+		s.writeErrorDetails(w, status, first.Code, first.Message, first.Details)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, resp)
+}
+
+// SetMaxBatch limits how many URLs one payload to POST /webhook/{format}
+// may submit unless the request says confirm=true. Larger payloads are
+// refused, or with hold, submitted with the URLs past max held. Zero
+// allows any number.
+func (s *Server) SetMaxBatch(max int, hold bool) {
+	s.maxBatch = max
+	s.holdOverflow = hold
+}
+
+// verifyShim checks that r carries the webhook secret: as a bearer token,
+// a basic auth password, or the token parameter. Requests signed as for
+// POST /webhook are checked as such instead.
+func (s *Server) verifyShim(r *http.Request, body []byte) error {
+	if r.Header.Get("X-Signature") != "" {
+		return s.verifySignature(r, body)
+	}
+	token := credential(r)
+	if token == "" {
+		return errors.New("missing webhook secret: send it as a bearer token, basic auth password, or token parameter")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.secret)) != 1 {
+		return errors.New("invalid webhook secret")
+	}
+	return nil
+}
+
+// firstURL returns the first non-empty of candidates, then of the URLs in
+// text, as the item to download, or none.
+func firstURL(title, text string, candidates ...string) []shimItem {
+	for _, u := range candidates {
+		if u != "" {
+			return []shimItem{{URL: u, Title: title}}
+		}
+	}
+	if urls := urlscan.Find(text); len(urls) > 0 {
+		return []shimItem{{URL: urls[0], Title: title}}
+	}
+	return nil
+}
+
+// parseNtfy reads an ntfy message, as forwarded by a subscriber or sent
+// as an http action's body. Its click URL is taken, else that of its
+// first view action, else the first URL in its text.
+func parseNtfy(body []byte) ([]shimItem, error) {
+	var msg struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+		Click   string `json:"click"`
+		Actions []struct {
+			Action string `json:"action"`
+			URL    string `json:"url"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	candidates := []string{msg.Click}
+	for _, a := range msg.Actions {
+		if a.Action == "view" {
+			candidates = append(candidates, a.URL)
+		}
+	}
+	return firstURL(msg.Title, msg.Message, candidates...), nil
+}
+
+// parseGotify reads a Gotify message. Its notification click URL is
+// taken, else the first URL in its text.
+func parseGotify(body []byte) ([]shimItem, error) {
+	var msg struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+		Extras  struct {
+			Notification struct {
+				Click struct {
+					URL string `json:"url"`
+				} `json:"click"`
+			} `json:"client::notification"`
+		} `json:"extras"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return firstURL(msg.Title, msg.Message, msg.Extras.Notification.Click.URL), nil
+}
+
+// feedItem is a feed entry as RSS-to-webhook bridges and JSON Feed send
+// them. Atom-style bridges send link as an object.
+type feedItem struct {
+	Title       string          `json:"title"`
+	Link        json.RawMessage `json:"link"`
+	URL         string          `json:"url"`
+	ExternalURL string          `json:"external_url"`
+}
+
+// url returns the item's link, url, or external_url, in that order.
+func (it feedItem) url() string {
+	var link string
+	if json.Unmarshal(it.Link, &link) != nil {
+		var atom struct {
+			Href string `json:"href"`
+		}
+		json.Unmarshal(it.Link, &atom)
+		link = atom.Href
+	}
+	for _, u := range []string{link, it.URL, it.ExternalURL} {
+		if u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+// parseFeed reads feed entries: one item, an array of them, or a feed
+// holding them as items or entries. Each item with a link is submitted.
+func parseFeed(body []byte) ([]shimItem, error) {
+	var feed struct {
+		feedItem
+		Items   []feedItem `json:"items"`
+		Entries []feedItem `json:"entries"`
+	}
+	var list []feedItem
+	if err := json.Unmarshal(body, &list); err != nil {
+		if err := json.Unmarshal(body, &feed); err != nil {
+			return nil, err
+		}
+		// A feed's own link is its site, not an entry
+		list = append(feed.Items, feed.Entries...)
+		if feed.Items == nil && feed.Entries == nil {
+			list = []feedItem{feed.feedItem}
+		}
+	}
+	var items []shimItem
+	for _, it := range list {
+		if u := it.url(); u != "" {
+			items = append(items, shimItem{URL: u, Title: it.Title})
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestParseShims(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		body   string
+		want   []shimItem
+	}{
+		{"ntfy click", "ntfy", `{"title":"New video","message":"see https://a.test/1","click":"https://a.test/click"}`,
+			[]shimItem{{URL: "https://a.test/click", Title: "New video"}}},
+		{"ntfy view action", "ntfy", `{"message":"x","actions":[{"action":"http","url":"https://hook.test"},{"action":"view","url":"https://a.test/view"}]}`,
+			[]shimItem{{URL: "https://a.test/view"}}},
+		{"ntfy message text", "ntfy", `{"message":"Watch this (https://a.test/watch?v=1)."}`,
+			[]shimItem{{URL: "https://a.test/watch?v=1"}}},
+		{"ntfy without URL", "ntfy", `{"message":"hello"}`, nil},
+		{"gotify click", "gotify", `{"title":"T","message":"m","extras":{"client::notification":{"click":{"url":"https://a.test/g"}}}}`,
+			[]shimItem{{URL: "https://a.test/g", Title: "T"}}},
+		{"gotify message text", "gotify", `{"message":"https://a.test/m and https://a.test/n"}`,
+			[]shimItem{{URL: "https://a.test/m"}}},
+		{"rss item", "rss", `{"title":"Episode 1","link":"https://a.test/e1"}`,
+			[]shimItem{{URL: "https://a.test/e1", Title: "Episode 1"}}},
+		{"rss atom link", "rss", `{"title":"E","link":{"href":"https://a.test/atom"}}`,
+			[]shimItem{{URL: "https://a.test/atom", Title: "E"}}},
+		{"rss array", "rss", `[{"link":"https://a.test/1"},{"title":"no link"},{"url":"https://a.test/2"}]`,
+			[]shimItem{{URL: "https://a.test/1"}, {URL: "https://a.test/2"}}},
+		{"json feed", "rss", `{"title":"Feed","home_page_url":"https://a.test","items":[{"title":"P","url":"https://a.test/p"}]}`,
+			[]shimItem{{URL: "https://a.test/p", Title: "P"}}},
+		{"feed link is not an entry", "rss", `{"title":"Feed","link":"https://a.test","entries":[]}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shims[tt.format]([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("items = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Shim(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetJobHolder(repo)
+	srv := NewServer(svc, ":8080", "s3cret")
+
+	post := func(path, body string, auth func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		if auth != nil {
+			auth(req)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") }
+
+	rec := post("/webhook/rss?source=feeds&hold=true", `[{"title":"One","link":"https://a.test/1"},{"link":"https://a.test/2"}]`, bearer)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	var resp shimResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Jobs) != 2 || resp.Jobs[0].URL != "https://a.test/1" || resp.Jobs[0].Source != "feeds" || resp.Jobs[0].Notes != "One" {
+		t.Errorf("jobs = %+v, want both items from source feeds", resp.Jobs)
+	}
+	if !resp.Jobs[0].Held {
+		t.Errorf("job = %+v, want it held", resp.Jobs[0])
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		auth     func(*http.Request)
+		wantCode int
+		wantErr  string
+	}{
+		{"token parameter", "/webhook/ntfy?token=s3cret", `{"click":"https://a.test/n"}`, nil, http.StatusCreated, ""},
+		{"basic auth", "/webhook/gotify", `{"message":"https://a.test/g"}`, func(r *http.Request) { r.SetBasicAuth("gotify", "s3cret") }, http.StatusCreated, ""},
+		{"default source", "/webhook/ntfy", `{"click":"https://a.test/src"}`, bearer, http.StatusCreated, ""},
+		{"no secret", "/webhook/ntfy", `{"click":"https://a.test/x"}`, nil, http.StatusUnauthorized, CodeUnauthorized},
+		{"wrong secret", "/webhook/ntfy?token=nope", `{"click":"https://a.test/x"}`, nil, http.StatusUnauthorized, CodeUnauthorized},
+		{"unknown format", "/webhook/sonarr", `{}`, bearer, http.StatusNotFound, CodeNotFound},
+		{"no URL", "/webhook/ntfy", `{"message":"hi"}`, bearer, http.StatusBadRequest, CodeBadRequest},
+		{"invalid JSON", "/webhook/rss", `{`, bearer, http.StatusBadRequest, CodeBadRequest},
+		{"invalid URL", "/webhook/ntfy", `{"click":"not a url"}`, bearer, http.StatusBadRequest, CodeInvalidURL},
+		{"invalid hold", "/webhook/ntfy?hold=maybe", `{"click":"https://a.test/x"}`, bearer, http.StatusBadRequest, CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(tt.path, tt.body, tt.auth)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantErr != "" {
+				assertErrorCode(t, rec, tt.wantErr)
+			}
+			if tt.name == "default source" {
+				var resp shimResponse
+				json.NewDecoder(rec.Body).Decode(&resp)
+				if resp.Jobs[0].Source != "ntfy" {
+					t.Errorf("source = %q, want the format", resp.Jobs[0].Source)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_ShimMaxBatch(t *testing.T) {
+	feed := func(prefix string, n int) string {
+		var items []string
+		for i := range n {
+			items = append(items, fmt.Sprintf(`{"link":"https://a.test/%s/%d"}`, prefix, i))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	}
+	tests := []struct {
+		name     string
+		hold     bool
+		query    string
+		items    int
+		wantCode int
+		wantHeld int
+	}{
+		{"within the limit", false, "", 3, http.StatusCreated, 0},
+		{"over the limit", false, "", 4, http.StatusUnprocessableEntity, 0},
+		{"over the limit, confirmed", false, "?confirm=true", 4, http.StatusCreated, 0},
+		{"over the limit, held anyway", false, "?hold=true", 4, http.StatusCreated, 4},
+		{"overflow held", true, "", 5, http.StatusCreated, 2},
+		{"overflow held, confirmed", true, "?confirm=true", 5, http.StatusCreated, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			svc.SetJobHolder(repo)
+			srv := NewServer(svc, ":8080", "")
+			srv.SetMaxBatch(3, tt.hold)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook/rss"+tt.query, bytes.NewBufferString(feed(tt.name, tt.items)))
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if rec.Code != http.StatusCreated {
+				assertErrorCode(t, rec, CodeBatch)
+				if n := len(repo.jobs); n != 0 {
+					t.Errorf("%d job(s) created, want none", n)
+				}
+				return
+			}
+			var resp shimResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if len(resp.Jobs) != tt.items {
+				t.Fatalf("%d job(s) created, want %d", len(resp.Jobs), tt.items)
+			}
+			held := 0
+			for i, job := range resp.Jobs {
+				if job.Held {
+					held++
+					if tt.wantHeld < tt.items && i < 3 {
+						t.Errorf("job %d held, want the first 3 to run", i)
+					}
+				}
+			}
+			if held != tt.wantHeld {
+				t.Errorf("%d job(s) held, want %d", held, tt.wantHeld)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// Default history windows for GET /stats when since is omitted.
+const (
+	defaultHourlyWindow = 24 * time.Hour
+	defaultDailyWindow  = 30 * 24 * time.Hour
+)
+
+// statsResponse is the JSON response for GET /stats.
+type statsResponse struct {
+	Period  string        `json:"period"`
+	Totals  statsTotals   `json:"totals"`
+	Buckets []statsBucket `json:"buckets"`
+}
+
+// statsTotals sums all buckets in the response window.
+type statsTotals struct {
+	Submitted int64 `json:"submitted"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+	Bytes     int64 `json:"bytes"`
+}
+
+type statsBucket struct {
+	Start     string `json:"start"`
+	Processor string `json:"processor,omitempty"`
+	Submitted int64  `json:"submitted"`
+	Completed int64  `json:"completed"`
+	Failed    int64  `json:"failed"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// SetStats serves throughput history from svc at GET /stats.
+func (s *Server) SetStats(svc *domain.StatsService) {
+	s.mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		s.handleStats(w, r, svc)
+	})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request, svc *domain.StatsService) {
+	q := r.URL.Query()
+
+	period := domain.PeriodHour
+	if p := q.Get("period"); p != "" {
+		period = domain.StatsPeriod(p)
+	}
+	window := defaultHourlyWindow
+	if period == domain.PeriodDay {
+		window = defaultDailyWindow
+	}
+	since := s.clock.Now().Add(-window)
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid since: must be RFC3339")
+			return
+		}
+		since = t
+	}
+
+	query := domain.StatsQuery{Period: period, Since: since}
+	switch by := q.Get("by"); by {
+	case "":
+	case "processor":
+		query.ByProcessor = true
+	default:
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid by: must be processor")
+		return
+	}
+
+	buckets, err := svc.History(r.Context(), query)
+	if err != nil {
+		if err == domain.ErrInvalidPeriod {
+			s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid period: must be hour or day")
+			return
+		}
+		log.Printf("stats error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	resp := statsResponse{Period: string(period), Buckets: make([]statsBucket, 0, len(buckets))}
+	for _, b := range buckets {
+		resp.Totals.Submitted += b.Submitted
+		resp.Totals.Completed += b.Completed
+		resp.Totals.Failed += b.Failed
+		resp.Totals.Bytes += b.Bytes
+		resp.Buckets = append(resp.Buckets, statsBucket{
+			Start:     b.Start.UTC().Format(time.RFC3339),
+			Processor: b.Processor,
+			Submitted: b.Submitted,
+			Completed: b.Completed,
+			Failed:    b.Failed,
+			Bytes:     b.Bytes,
+		})
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+type stubStatsRepo struct {
+	buckets []domain.StatsBucket
+}
+
+func (s *stubStatsRepo) Stats(ctx context.Context, q domain.StatsQuery) ([]domain.StatsBucket, error) {
+	return s.buckets, nil
+}
+
+func (s *stubStatsRepo) CompactStats(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestServer_Stats(t *testing.T) {
+	start := time.Date(2026, 3, 1, 14, 0, 0, 0, time.UTC)
+	srv := setupTestServer()
+	srv.SetStats(domain.NewStatsService(&stubStatsRepo{buckets: []domain.StatsBucket{
+		{Start: start, Submitted: 5, Failed: 1},
+		{Start: start, Processor: "youtube", Completed: 3, Bytes: 4096},
+	}}, time.Hour))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantPeriod string
+	}{
+		{name: "default period", query: "", wantStatus: http.StatusOK, wantPeriod: "hour"},
+		{name: "daily", query: "?period=day&since=2026-02-01T00:00:00Z", wantStatus: http.StatusOK, wantPeriod: "day"},
+		{name: "by processor", query: "?by=processor", wantStatus: http.StatusOK, wantPeriod: "hour"},
+		{name: "invalid by", query: "?by=host", wantStatus: http.StatusBadRequest},
+		{name: "invalid period", query: "?period=week", wantStatus: http.StatusBadRequest},
+		{name: "invalid since", query: "?since=yesterday", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				assertErrorCode(t, rec, CodeBadRequest)
+				return
+			}
+
+			var resp statsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if resp.Period != tt.wantPeriod {
+				t.Errorf("period = %q, want %q", resp.Period, tt.wantPeriod)
+			}
+			if len(resp.Buckets) != 2 || resp.Buckets[0].Start != "2026-03-01T14:00:00Z" || resp.Buckets[1].Processor != "youtube" {
+				t.Errorf("buckets = %+v", resp.Buckets)
+			}
+			want := statsTotals{Submitted: 5, Completed: 3, Failed: 1, Bytes: 4096}
+			if resp.Totals != want {
+				t.Errorf("totals = %+v, want %+v", resp.Totals, want)
+			}
+		})
+	}
+}
+
+func TestServer_StatsNotConfigured(t *testing.T) {
+	srv := setupTestServer()
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
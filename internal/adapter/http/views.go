@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// viewResponse is the JSON form of a saved view.
+type viewResponse struct {
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
+	Host   string `json:"host,omitempty"`
+}
+
+// viewsResponse is the JSON response for GET /views.
+type viewsResponse struct {
+	Views []viewResponse `json:"views"`
+}
+
+// saveViewRequest is the request body for PUT /views/{name}.
+type saveViewRequest struct {
+	Status string `json:"status"`
+	Host   string `json:"host"`
+}
+
+func viewToResponse(v *domain.View) viewResponse {
+	return viewResponse{Name: v.Name, Status: string(v.Status), Host: v.Host}
+}
+
+func (s *Server) handleListViews(w http.ResponseWriter, r *http.Request) {
+	views, err := s.svc.Views(r.Context())
+	if err != nil {
+		s.writeViewError(w, err)
+		return
+	}
+	resp := viewsResponse{Views: make([]viewResponse, 0, len(views))}
+	for i := range views {
+		resp.Views = append(resp.Views, viewToResponse(&views[i]))
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSaveView saves a named job filter, replacing any with that name.
+func (s *Server) handleSaveView(w http.ResponseWriter, r *http.Request) {
+	var req saveViewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON")
+		return
+	}
+	view := domain.View{Name: r.PathValue("name"), Status: domain.JobStatus(req.Status), Host: req.Host}
+	if err := s.svc.SaveView(r.Context(), view); err != nil {
+		s.writeViewError(w, err)
+		return
+	}
+	log.Printf("view %s: saved (request %s)", view.Name, requestIDFrom(r.Context()))
+	s.writeJSON(w, http.StatusOK, viewToResponse(&view))
+}
+
+func (s *Server) handleDeleteView(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := s.svc.DeleteView(r.Context(), name); err != nil {
+		s.writeViewError(w, err)
+		return
+	}
+	log.Printf("view %s: deleted (request %s)", name, requestIDFrom(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeViewError writes the response for a failed view operation.
+func (s *Server) writeViewError(w http.ResponseWriter, err error) {
+	var ive *domain.InvalidViewError
+	switch {
+	case errors.Is(err, domain.ErrViewNotFound):
+		s.writeError(w, http.StatusNotFound, CodeNotFound, "view not found")
+	case errors.As(err, &ive):
+		s.writeError(w, http.StatusBadRequest, CodeBadRequest, ive.Error())
+	default:
+		log.Printf("view error: %v", err)
+		s.writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+	}
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// mockViews is an in-memory domain.ViewRepository.
+type mockViews map[string]domain.View
+
+func (m mockViews) SaveView(ctx context.Context, v domain.View) error {
+	m[v.Name] = v
+	return nil
+}
+func (m mockViews) View(ctx context.Context, name string) (*domain.View, error) {
+	v, ok := m[name]
+	if !ok {
+		return nil, domain.ErrViewNotFound
+	}
+	return &v, nil
+}
+func (m mockViews) Views(ctx context.Context) ([]domain.View, error) {
+	var views []domain.View
+	for _, v := range m {
+		views = append(views, v)
+	}
+	return views, nil
+}
+func (m mockViews) DeleteView(ctx context.Context, name string) error {
+	if _, ok := m[name]; !ok {
+		return domain.ErrViewNotFound
+	}
+	delete(m, name)
+	return nil
+}
+
+func TestServer_Views(t *testing.T) {
+	repo := newMockRepo()
+	ctx := context.Background()
+	for _, u := range []string{"https://youtube.com/a", "https://vimeo.com/b", "https://m.youtube.com/c"} {
+		repo.Create(ctx, u)
+	}
+	repo.jobs[3].Status = domain.StatusProcessing
+	svc := domain.NewJobService(repo)
+	views := mockViews{}
+	svc.SetViewRepository(views)
+	srv := NewServer(svc, ":8080", "")
+	srv.SetAdminToken("s3cret")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	listURLs := func(query string) []any {
+		t.Helper()
+		rec := do(http.MethodGet, "/jobs?"+query, "")
+		var resp struct {
+			Jobs []map[string]any `json:"jobs"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		var urls []any
+		for _, job := range resp.Jobs {
+			urls = append(urls, job["url"])
+		}
+		return urls
+	}
+
+	if rec := do(http.MethodPut, "/views/stuck-youtube", `{"status":"processing","host":"youtube.com"}`); rec.Code != http.StatusOK {
+		t.Fatalf("save view = %d %s", rec.Code, rec.Body)
+	}
+	if urls := listURLs("view=stuck-youtube"); len(urls) != 1 || urls[0] != "https://m.youtube.com/c" {
+		t.Errorf("listing the view = %v, want the processing YouTube job", urls)
+	}
+	// Query parameters override the view's
+	if urls := listURLs("view=stuck-youtube&status=pending"); len(urls) != 1 || urls[0] != "https://youtube.com/a" {
+		t.Errorf("listing the view as pending = %v, want the pending YouTube job", urls)
+	}
+	if urls := listURLs("host=vimeo.com"); len(urls) != 1 || urls[0] != "https://vimeo.com/b" {
+		t.Errorf("listing by host = %v, want the Vimeo job", urls)
+	}
+
+	rec := do(http.MethodGet, "/views", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `{"name":"stuck-youtube","status":"processing","host":"youtube.com"}`) {
+		t.Errorf("list views = %d %s, want the saved view", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+	}{
+		{http.MethodPut, "/views/Bad", `{}`, http.StatusBadRequest},
+		{http.MethodPut, "/views/odd", `{"status":"stuck"}`, http.StatusBadRequest},
+		{http.MethodPut, "/views/odd", `not json`, http.StatusBadRequest},
+		{http.MethodGet, "/jobs?view=missing", "", http.StatusNotFound},
+		{http.MethodDelete, "/views/stuck-youtube", "", http.StatusNoContent},
+		{http.MethodDelete, "/views/stuck-youtube", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := do(tt.method, tt.path, tt.body); rec.Code != tt.wantStatus {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.wantStatus)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/views/x", strings.NewReader(`{}`))
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("save view without token = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
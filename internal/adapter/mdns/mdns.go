@@ -0,0 +1,280 @@
+// Package mdns advertises catcher on the local network with multicast DNS
+// service discovery (RFC 6762 and 6763), so companion apps can find it
+// without being told its address.
+package mdns
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceType is the DNS-SD service catcher is advertised as.
+const ServiceType = "_catcher._tcp"
+
+// groupAddr is the IPv4 mDNS multicast group and port.
+var groupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Record TTLs, as RFC 6762 recommends: host records expire sooner, since
+// addresses change more often than services.
+const (
+	hostTTL    = 120
+	serviceTTL = 4500
+	// legacyTTL caps TTLs for resolvers that don't speak mDNS.
+	legacyTTL = 10
+)
+
+// Service is what gets advertised.
+type Service struct {
+	// Instance names this catcher among others, e.g. "catcher on nas".
+	Instance string
+	// Host is the host name, without ".local".
+	Host string
+	Port int
+	// TXT holds key=value pairs, such as the API version.
+	TXT []string
+}
+
+// Advertiser answers mDNS queries for a Service, over IPv4.
+type Advertiser struct {
+	svc   Service
+	addrs func() []net.IP // IPv4 addresses to advertise for the host
+
+	conn      *net.UDPConn
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// New creates an Advertiser for svc, advertising the addresses of the
+// network interfaces that are up.
+func New(svc Service) *Advertiser {
+	return &Advertiser{svc: svc, addrs: interfaceAddrs, done: make(chan struct{})}
+}
+
+// Listen joins the mDNS group and announces the service. Call Serve to
+// answer queries.
+func (a *Advertiser) Listen() error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, groupAddr)
+	if err != nil {
+		return err
+	}
+	a.conn = conn
+	go a.announce()
+	return nil
+}
+
+// announce sends the records unasked twice, a second apart, so listening
+// browsers see the service straight away.
+func (a *Advertiser) announce() {
+	for i := range 2 {
+		if i > 0 {
+			select {
+			case <-a.done:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		a.send(a.message(0, nil, a.records(1), nil), groupAddr)
+	}
+}
+
+// Serve answers queries until Close.
+func (a *Advertiser) Serve() error {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-a.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		resp, unicast := a.answer(buf[:n], from.Port != groupAddr.Port)
+		if resp == nil {
+			continue
+		}
+		to := groupAddr
+		if unicast {
+			to = from
+		}
+		a.send(resp, to)
+	}
+}
+
+// Close withdraws the service with records of zero TTL and stops serving.
+func (a *Advertiser) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		close(a.done)
+		if a.conn == nil {
+			return
+		}
+		a.send(a.message(0, nil, a.records(0)[:3], nil), groupAddr) // not the host's addresses, which it may share
+		err = a.conn.Close()
+	})
+	return err
+}
+
+func (a *Advertiser) send(msg []byte, to *net.UDPAddr) {
+	if _, err := a.conn.WriteToUDP(msg, to); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("mdns: send to %s: %v", to, err)
+	}
+}
+
+// typeName returns the service type's name, as labels, like the other
+// names below.
+func (a *Advertiser) typeName() []string {
+	return append(strings.Split(ServiceType, "."), "local")
+}
+
+func (a *Advertiser) instanceName() []string {
+	return append([]string{a.svc.Instance}, a.typeName()...)
+}
+
+func (a *Advertiser) hostName() []string {
+	return []string{a.svc.Host, "local"}
+}
+
+var enumerationName = []string{"_services", "_dns-sd", "_udp", "local"}
+
+// records returns the service's PTR, SRV, TXT, and A records, their TTLs
+// scaled by ttl: 1 for full, 0 for goodbye.
+func (a *Advertiser) records(ttl uint32) []record {
+	rs := []record{
+		{name: a.typeName(), typ: typePTR, ttl: serviceTTL * ttl, data: encodeName(a.instanceName())},
+		{name: a.instanceName(), typ: typeSRV, unique: true, ttl: hostTTL * ttl, data: srvData(uint16(a.svc.Port), a.hostName())},
+		{name: a.instanceName(), typ: typeTXT, unique: true, ttl: serviceTTL * ttl, data: txtData(a.svc.TXT)},
+	}
+	for _, ip := range a.addrs() {
+		rs = append(rs, record{name: a.hostName(), typ: typeA, unique: true, ttl: hostTTL * ttl, data: ip.To4()})
+	}
+	return rs
+}
+
+// answer returns the response to query, or nil if nothing in it is asked
+// of this service. legacy marks a query from a plain DNS resolver, which
+// gets a unicast reply echoing its ID and questions; unicast reports
+// whether the reply goes to the sender only.
+func (a *Advertiser) answer(query []byte, legacy bool) (resp []byte, unicast bool) {
+	msg, err := parseMessage(query)
+	if err != nil || msg.response {
+		return nil, false
+	}
+	all := a.records(1)
+	ptr, srv, txt, hosts := all[0], all[1], all[2], all[3:]
+
+	var answers, additional []record
+	add := func(rs []record, r ...record) []record {
+		for _, n := range r {
+			if !containsRecord(answers, n) && !containsRecord(additional, n) {
+				rs = append(rs, n)
+			}
+		}
+		return rs
+	}
+	unicast = legacy
+	for _, q := range msg.questions {
+		if q.unicast {
+			unicast = true
+		}
+		switch {
+		case sameName(q.name, enumerationName) && matches(q.typ, typePTR):
+			answers = add(answers, record{name: enumerationName, typ: typePTR, ttl: serviceTTL, data: encodeName(a.typeName())})
+		case sameName(q.name, ptr.name) && matches(q.typ, typePTR):
+			if !msg.knows(ptr) {
+				answers = add(answers, ptr)
+			}
+		case sameName(q.name, srv.name) && matches(q.typ, typeSRV, typeTXT):
+			if matches(q.typ, typeSRV) {
+				answers = add(answers, srv)
+			}
+			if matches(q.typ, typeTXT) {
+				answers = add(answers, txt)
+			}
+		case sameName(q.name, a.hostName()) && matches(q.typ, typeA):
+			answers = add(answers, hosts...)
+		}
+	}
+	if len(answers) == 0 {
+		return nil, false
+	}
+	// Save a browser the follow-up queries
+	if containsRecord(answers, ptr) {
+		additional = add(additional, srv, txt)
+		additional = add(additional, hosts...)
+	} else if containsRecord(answers, srv) {
+		additional = add(additional, hosts...)
+	}
+
+	if !legacy {
+		return a.message(0, nil, answers, additional), unicast
+	}
+	for _, rs := range [][]record{answers, additional} {
+		for i := range rs {
+			rs[i].ttl = min(rs[i].ttl, legacyTTL)
+			rs[i].unique = false
+		}
+	}
+	return a.message(msg.id, msg.questions, answers, additional), true
+}
+
+// matches reports whether a question of type q asks for any of types.
+func matches(q uint16, types ...uint16) bool {
+	for _, t := range types {
+		if q == t || q == typeANY {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceAddrs returns the IPv4 addresses of the interfaces that are up,
+// loopback aside.
+func interfaceAddrs() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				ips = append(ips, ipnet.IP.To4())
+			}
+		}
+	}
+	return ips
+}
+
+// HostLabel turns a host name into a single DNS label: the part before
+// the first dot, lowercased, with anything but letters, digits, and
+// hyphens replaced by hyphens.
+func HostLabel(hostname string) string {
+	hostname, _, _ = strings.Cut(strings.ToLower(hostname), ".")
+	label := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, hostname)
+	label = strings.Trim(label, "-")
+	if len(label) > maxLabel {
+		label = label[:maxLabel]
+	}
+	if label == "" {
+		return "catcher"
+	}
+	return label
+}
@@ -0,0 +1,195 @@
+package mdns
+
+import (
+	"encoding/binary"
+	"net"
+	"slices"
+	"testing"
+)
+
+func testAdvertiser() *Advertiser {
+	a := New(Service{Instance: "catcher on nas", Host: "nas", Port: 8080, TXT: []string{"api=1", "path=/catcher"}})
+	a.addrs = func() []net.IP { return []net.IP{net.IPv4(192, 168, 1, 20)} }
+	return a
+}
+
+// query builds a query asking questions, listing known as known answers.
+func query(id uint16, questions []question, known ...record) []byte {
+	a := &Advertiser{}
+	b := a.message(id, questions, known, nil)
+	binary.BigEndian.PutUint16(b[2:], 0) // a query, not a response
+	for i, q := range questions {
+		if q.unicast {
+			// Set the QU bit in the i-th question's class
+			off := 12
+			for range i {
+				_, next, _ := readName(b, off)
+				off = next + 4
+			}
+			_, next, _ := readName(b, off)
+			b[next+2] |= 0x80
+		}
+	}
+	return b
+}
+
+// parseResponse reads every record in a response, additional ones included.
+func parseResponse(t *testing.T, b []byte) (*message, []record) {
+	t.Helper()
+	m, err := parseMessage(b)
+	if err != nil {
+		t.Fatalf("parseMessage() error = %v", err)
+	}
+	if !m.response || binary.BigEndian.Uint16(b[2:])&0x0400 == 0 {
+		t.Errorf("flags = %#04x, want an authoritative response", binary.BigEndian.Uint16(b[2:]))
+	}
+	off := 12
+	for range m.questions {
+		_, next, _ := readName(b, off)
+		off = next + 4
+	}
+	var all []record
+	for range len(m.answers) + int(binary.BigEndian.Uint16(b[10:])) {
+		r, next, err := readRecord(b, off)
+		if err != nil {
+			t.Fatalf("readRecord() error = %v", err)
+		}
+		all = append(all, r)
+		off = next
+	}
+	return m, all
+}
+
+func types(rs []record) []uint16 {
+	var ts []uint16
+	for _, r := range rs {
+		ts = append(ts, r.typ)
+	}
+	return ts
+}
+
+func TestAdvertiser_Browse(t *testing.T) {
+	a := testAdvertiser()
+	resp, unicast := a.answer(query(0, []question{{name: []string{"_catcher", "_tcp", "local"}, typ: typePTR}}), false)
+	if resp == nil || unicast {
+		t.Fatalf("answer() = %v, unicast %v; want a multicast response", resp, unicast)
+	}
+	m, all := parseResponse(t, resp)
+	if m.id != 0 || len(m.questions) != 0 || len(m.answers) != 1 {
+		t.Errorf("response has ID %d, %d question(s), %d answer(s); want 0, none, and the PTR", m.id, len(m.questions), len(m.answers))
+	}
+	if got := types(all); !slices.Equal(got, []uint16{typePTR, typeSRV, typeTXT, typeA}) {
+		t.Fatalf("record types = %v, want PTR with SRV, TXT, and A", got)
+	}
+
+	instance, _, err := readName(all[0].data, 0)
+	if err != nil || !sameName(instance, []string{"catcher on nas", "_catcher", "_tcp", "local"}) || all[0].unique || all[0].ttl != serviceTTL {
+		t.Errorf("PTR = %q (unique %v, ttl %d), want the shared instance name", instance, all[0].unique, all[0].ttl)
+	}
+	srv := all[1]
+	target, _, _ := readName(srv.data, 6)
+	if port := binary.BigEndian.Uint16(srv.data[4:]); port != 8080 || !sameName(target, []string{"nas", "local"}) || !srv.unique {
+		t.Errorf("SRV = port %d on %q, want 8080 on nas.local with cache flush", port, target)
+	}
+	if want := "\x05api=1\x0dpath=/catcher"; string(all[2].data) != want {
+		t.Errorf("TXT = %q, want %q", all[2].data, want)
+	}
+	if ip := net.IP(all[3].data); !ip.Equal(net.IPv4(192, 168, 1, 20)) {
+		t.Errorf("A = %s, want 192.168.1.20", ip)
+	}
+}
+
+func TestAdvertiser_Answer(t *testing.T) {
+	a := testAdvertiser()
+	ptr := a.records(1)[0]
+	tests := []struct {
+		name      string
+		questions []question
+		known     []record
+		legacy    bool
+		want      []uint16 // record types in the response; nil for none
+		unicast   bool
+	}{
+		{
+			name:      "service enumeration",
+			questions: []question{{name: enumerationName, typ: typePTR}},
+			want:      []uint16{typePTR},
+		},
+		{
+			name:      "instance, any type, case-insensitive",
+			questions: []question{{name: []string{"Catcher On NAS", "_catcher", "_tcp", "local"}, typ: typeANY}},
+			want:      []uint16{typeSRV, typeTXT, typeA},
+		},
+		{
+			name:      "host address with unicast reply",
+			questions: []question{{name: []string{"nas", "local"}, typ: typeA, unicast: true}},
+			want:      []uint16{typeA},
+			unicast:   true,
+		},
+		{
+			name:      "known answer suppressed",
+			questions: []question{{name: []string{"_catcher", "_tcp", "local"}, typ: typePTR}},
+			known:     []record{ptr},
+		},
+		{
+			name:      "other service",
+			questions: []question{{name: []string{"_http", "_tcp", "local"}, typ: typePTR}},
+		},
+		{
+			name:      "legacy resolver",
+			questions: []question{{name: []string{"nas", "local"}, typ: typeA}},
+			legacy:    true,
+			want:      []uint16{typeA},
+			unicast:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, unicast := a.answer(query(42, tt.questions, tt.known...), tt.legacy)
+			if tt.want == nil {
+				if resp != nil {
+					t.Errorf("answer() = %v, want no response", resp)
+				}
+				return
+			}
+			if resp == nil {
+				t.Fatal("answer() = nil, want a response")
+			}
+			if unicast != tt.unicast {
+				t.Errorf("unicast = %v, want %v", unicast, tt.unicast)
+			}
+			m, all := parseResponse(t, resp)
+			if got := types(all); !slices.Equal(got, tt.want) {
+				t.Errorf("record types = %v, want %v", got, tt.want)
+			}
+			if tt.legacy {
+				if m.id != 42 || len(m.questions) != 1 {
+					t.Errorf("legacy response has ID %d and %d question(s), want the query's", m.id, len(m.questions))
+				}
+				for _, r := range all {
+					if r.ttl > legacyTTL || r.unique {
+						t.Errorf("legacy record ttl %d, unique %v; want at most %d without cache flush", r.ttl, r.unique, legacyTTL)
+					}
+				}
+			}
+		})
+	}
+
+	if resp, _ := a.answer(a.message(0, nil, a.records(1), nil), false); resp != nil {
+		t.Error("answered a response")
+	}
+}
+
+func TestHostLabel(t *testing.T) {
+	tests := map[string]string{
+		"nas":                 "nas",
+		"Media-Box.fritz.box": "media-box",
+		"my_host":             "my-host",
+		"...":                 "catcher",
+	}
+	for in, want := range tests {
+		if got := HostLabel(in); got != want {
+			t.Errorf("HostLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
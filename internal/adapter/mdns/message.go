@@ -0,0 +1,237 @@
+package mdns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// DNS record types and classes used here.
+const (
+	typeA   uint16 = 1
+	typePTR uint16 = 12
+	typeTXT uint16 = 16
+	typeSRV uint16 = 33
+	typeANY uint16 = 255
+
+	classIN uint16 = 1
+	// topBit of a record's class asks caches to flush other records of
+	// its name and type; of a question's class, asks for a unicast reply.
+	topBit uint16 = 1 << 15
+
+	maxLabel = 63
+)
+
+var errMalformed = errors.New("mdns: malformed message")
+
+// record is a resource record, its name as labels.
+type record struct {
+	name   []string
+	typ    uint16
+	unique bool // sets the cache-flush bit
+	ttl    uint32
+	data   []byte
+}
+
+// question is a query's question.
+type question struct {
+	name    []string
+	typ     uint16
+	unicast bool
+}
+
+// message is a parsed DNS message.
+type message struct {
+	id        uint16
+	response  bool
+	questions []question
+	answers   []record // in a query, the answers the asker already knows
+}
+
+// knows reports whether the query lists r as a known answer with at least
+// half its TTL left, so r needn't be sent again.
+func (m *message) knows(r record) bool {
+	for _, k := range m.answers {
+		if k.typ == r.typ && sameName(k.name, r.name) && bytes.Equal(k.data, r.data) && k.ttl >= r.ttl/2 {
+			return true
+		}
+	}
+	return false
+}
+
+// message builds an authoritative response.
+func (a *Advertiser) message(id uint16, questions []question, answers, additional []record) []byte {
+	b := binary.BigEndian.AppendUint16(nil, id)
+	b = binary.BigEndian.AppendUint16(b, 0x8400) // response, authoritative
+	b = binary.BigEndian.AppendUint16(b, uint16(len(questions)))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(answers)))
+	b = binary.BigEndian.AppendUint16(b, 0)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(additional)))
+	for _, q := range questions {
+		b = append(b, encodeName(q.name)...)
+		b = binary.BigEndian.AppendUint16(b, q.typ)
+		b = binary.BigEndian.AppendUint16(b, classIN)
+	}
+	for _, rs := range [][]record{answers, additional} {
+		for _, r := range rs {
+			class := classIN
+			if r.unique {
+				class |= topBit
+			}
+			b = append(b, encodeName(r.name)...)
+			b = binary.BigEndian.AppendUint16(b, r.typ)
+			b = binary.BigEndian.AppendUint16(b, class)
+			b = binary.BigEndian.AppendUint32(b, r.ttl)
+			b = binary.BigEndian.AppendUint16(b, uint16(len(r.data)))
+			b = append(b, r.data...)
+		}
+	}
+	return b
+}
+
+// parseMessage reads a message's header, questions, and answers.
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < 12 {
+		return nil, errMalformed
+	}
+	m := &message{id: binary.BigEndian.Uint16(b), response: b[2]&0x80 != 0}
+	qd, an := int(binary.BigEndian.Uint16(b[4:])), int(binary.BigEndian.Uint16(b[6:]))
+	off := 12
+	for range qd {
+		name, next, err := readName(b, off)
+		if err != nil || next+4 > len(b) {
+			return nil, errMalformed
+		}
+		class := binary.BigEndian.Uint16(b[next+2:])
+		m.questions = append(m.questions, question{name: name, typ: binary.BigEndian.Uint16(b[next:]), unicast: class&topBit != 0})
+		off = next + 4
+	}
+	for range an {
+		r, next, err := readRecord(b, off)
+		if err != nil {
+			return nil, err
+		}
+		m.answers = append(m.answers, r)
+		off = next
+	}
+	return m, nil
+}
+
+// readRecord reads the resource record at off, returning the offset after
+// it. A PTR record's target is stored uncompressed, for comparison.
+func readRecord(b []byte, off int) (record, int, error) {
+	name, next, err := readName(b, off)
+	if err != nil || next+10 > len(b) {
+		return record{}, 0, errMalformed
+	}
+	r := record{
+		name:   name,
+		typ:    binary.BigEndian.Uint16(b[next:]),
+		unique: binary.BigEndian.Uint16(b[next+2:])&topBit != 0,
+		ttl:    binary.BigEndian.Uint32(b[next+4:]),
+	}
+	start := next + 10
+	end := start + int(binary.BigEndian.Uint16(b[next+8:]))
+	if end > len(b) {
+		return record{}, 0, errMalformed
+	}
+	r.data = b[start:end]
+	if r.typ == typePTR {
+		target, _, err := readName(b, start)
+		if err != nil {
+			return record{}, 0, err
+		}
+		r.data = encodeName(target)
+	}
+	return r, end, nil
+}
+
+// readName reads the possibly compressed name at off, returning the
+// offset after it.
+func readName(b []byte, off int) ([]string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(b) {
+			return nil, 0, errMalformed
+		}
+		l := int(b[off])
+		switch {
+		case l == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return labels, end, nil
+		case l&0xC0 == 0xC0:
+			if off+2 > len(b) || jumps > 16 {
+				return nil, 0, errMalformed
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3FFF)
+			jumps++
+		case l > maxLabel || off+1+l > len(b):
+			return nil, 0, errMalformed
+		default:
+			labels = append(labels, string(b[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
+
+// encodeName writes a name without compression.
+func encodeName(labels []string) []byte {
+	var b []byte
+	for _, l := range labels {
+		if len(l) > maxLabel {
+			l = l[:maxLabel]
+		}
+		b = append(b, byte(len(l)))
+		b = append(b, l...)
+	}
+	return append(b, 0)
+}
+
+func srvData(port uint16, target []string) []byte {
+	b := make([]byte, 4, 6) // priority and weight 0
+	b = binary.BigEndian.AppendUint16(b, port)
+	return append(b, encodeName(target)...)
+}
+
+func txtData(pairs []string) []byte {
+	if len(pairs) == 0 {
+		return []byte{0}
+	}
+	var b []byte
+	for _, p := range pairs {
+		if len(p) > 255 {
+			p = p[:255]
+		}
+		b = append(b, byte(len(p)))
+		b = append(b, p...)
+	}
+	return b
+}
+
+func sameName(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsRecord(rs []record, r record) bool {
+	for _, o := range rs {
+		if o.typ == r.typ && sameName(o.name, r.name) && bytes.Equal(o.data, r.data) {
+			return true
+		}
+	}
+	return false
+}
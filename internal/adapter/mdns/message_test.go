@@ -0,0 +1,37 @@
+package mdns
+
+import (
+	"testing"
+)
+
+func TestReadName(t *testing.T) {
+	// "_catcher._tcp.local" at 12, then "nas" pointing back to ".local"
+	b := make([]byte, 12)
+	b = append(b, encodeName([]string{"_catcher", "_tcp", "local"})...)
+	ptrAt := len(b)
+	b = append(b, 3, 'n', 'a', 's', 0xC0, 12+9+5)
+
+	name, next, err := readName(b, 12)
+	if err != nil || !sameName(name, []string{"_catcher", "_tcp", "local"}) || next != ptrAt {
+		t.Errorf("readName(12) = %q, %d, %v", name, next, err)
+	}
+	name, next, err = readName(b, ptrAt)
+	if err != nil || !sameName(name, []string{"nas", "local"}) || next != len(b) {
+		t.Errorf("readName(compressed) = %q, %d, %v", name, next, err)
+	}
+}
+
+func TestParseMessage_Malformed(t *testing.T) {
+	loop := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xC0, 12, 0, 12, 0, 1}
+	tests := map[string][]byte{
+		"short header":   {0, 0, 0},
+		"truncated name": {0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 5, 'a'},
+		"pointer loop":   loop,
+		"no question":    {0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0},
+	}
+	for name, b := range tests {
+		if _, err := parseMessage(b); err == nil {
+			t.Errorf("%s: parseMessage() error = nil, want malformed", name)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// Package metrics exposes job outcome metrics in Prometheus text and
+// OpenMetrics formats.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// OtherHost is the host label for URLs not in the allowlist.
+const OtherHost = "other"
+
+// noProcessor is the processor label for jobs no processor matched.
+const noProcessor = "none"
+
+// defaultQueue is the queue label for domain.DefaultQueue.
+const defaultQueue = "default"
+
+// durationBuckets are histogram upper bounds in seconds, sized for downloads.
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+type labels struct {
+	processor string
+	status    string
+	host      string
+}
+
+// exemplar links a sample to the job that produced it.
+type exemplar struct {
+	jobID int64
+	value float64
+	ts    time.Time
+}
+
+type counter struct {
+	value    float64
+	exemplar exemplar
+}
+
+type histogram struct {
+	counts    []uint64 // per bucket, non-cumulative; last is +Inf
+	exemplars []exemplar
+	sum       float64
+	count     uint64
+}
+
+// Metrics records job outcomes and serves them over HTTP.
+type Metrics struct {
+	hosts []string
+
+	mu        sync.Mutex
+	jobs      map[labels]*counter
+	durations map[labels]*histogram
+	bytes     map[labels]*counter // keyed without status
+	retries   map[string]float64  // by repository operation
+	queues    map[string]queueDepth
+}
+
+// queueDepth is a queue's state as of the last poll.
+type queueDepth struct {
+	pending int64
+	running int
+}
+
+// New creates Metrics that label hosts from the allowlist and fold all
+// others into OtherHost, keeping label cardinality bounded.
+func New(hostAllowlist []string) *Metrics {
+	hosts := make([]string, len(hostAllowlist))
+	for i, h := range hostAllowlist {
+		hosts[i] = strings.ToLower(strings.TrimPrefix(h, "."))
+	}
+	return &Metrics{
+		hosts:     hosts,
+		jobs:      make(map[labels]*counter),
+		durations: make(map[labels]*histogram),
+		bytes:     make(map[labels]*counter),
+		retries:   make(map[string]float64),
+		queues:    make(map[string]queueDepth),
+	}
+}
+
+// HostLabel maps a URL to its allowlisted host (matching subdomains) or OtherHost.
+func (m *Metrics) HostLabel(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return OtherHost
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range m.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return h
+		}
+	}
+	return OtherHost
+}
+
+// JobFinished implements worker.Observer.
+func (m *Metrics) JobFinished(job *domain.Job, processor, outcome string, elapsed time.Duration) {
+	if processor == "" {
+		processor = noProcessor
+	}
+	l := labels{processor: processor, status: outcome, host: m.HostLabel(job.URL)}
+	now := time.Now()
+	secs := elapsed.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.jobs[l]
+	if !ok {
+		c = &counter{}
+		m.jobs[l] = c
+	}
+	c.value++
+	c.exemplar = exemplar{jobID: job.ID, value: 1, ts: now}
+
+	h, ok := m.durations[l]
+	if !ok {
+		h = &histogram{
+			counts:    make([]uint64, len(durationBuckets)+1),
+			exemplars: make([]exemplar, len(durationBuckets)+1),
+		}
+		m.durations[l] = h
+	}
+	i := sort.SearchFloat64s(durationBuckets, secs)
+	h.counts[i]++
+	h.exemplars[i] = exemplar{jobID: job.ID, value: secs, ts: now}
+	h.sum += secs
+	h.count++
+
+	if job.Bytes > 0 {
+		bl := labels{processor: l.processor, host: l.host}
+		b, ok := m.bytes[bl]
+		if !ok {
+			b = &counter{}
+			m.bytes[bl] = b
+		}
+		b.value += float64(job.Bytes)
+		b.exemplar = exemplar{jobID: job.ID, value: float64(job.Bytes), ts: now}
+	}
+}
+
+// DBRetry implements sqlite.RetryObserver.
+func (m *Metrics) DBRetry(op string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[op]++
+}
+
+// QueueDepth implements worker.QueueObserver.
+func (m *Metrics) QueueDepth(queue string, pending int64, running int) {
+	if queue == domain.DefaultQueue {
+		queue = defaultQueue
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queues[queue] = queueDepth{pending: pending, running: running}
+}
+
+// ServeHTTP writes OpenMetrics (with exemplars) when the client accepts it,
+// and Prometheus text format otherwise.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	}
+	m.write(w, openMetrics)
+}
+
+func (m *Metrics) write(w io.Writer, openMetrics bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// OpenMetrics names counter families without the _total suffix
+	family := "catcher_jobs_total"
+	if openMetrics {
+		family = "catcher_jobs"
+	}
+	fmt.Fprintf(w, "# HELP %s Jobs finished by processor, outcome, and host.\n", family)
+	fmt.Fprintf(w, "# TYPE %s counter\n", family)
+	for _, l := range sortedLabels(m.jobs) {
+		c := m.jobs[l]
+		fmt.Fprintf(w, "catcher_jobs_total{%s} %g", l.format(""), c.value)
+		writeExemplar(w, c.exemplar, openMetrics)
+	}
+
+	fmt.Fprintln(w, "# HELP catcher_job_duration_seconds Time spent in Process by processor, outcome, and host.")
+	fmt.Fprintln(w, "# TYPE catcher_job_duration_seconds histogram")
+	for _, l := range sortedLabels(m.durations) {
+		h := m.durations[l]
+		var cumulative uint64
+		for i, bound := range durationBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "catcher_job_duration_seconds_bucket{%s} %d", l.format(fmt.Sprintf("%g", bound)), cumulative)
+			writeExemplar(w, h.exemplars[i], openMetrics)
+		}
+		last := len(durationBuckets)
+		fmt.Fprintf(w, "catcher_job_duration_seconds_bucket{%s} %d", l.format("+Inf"), h.count)
+		writeExemplar(w, h.exemplars[last], openMetrics)
+		fmt.Fprintf(w, "catcher_job_duration_seconds_sum{%s} %g\n", l.format(""), h.sum)
+		fmt.Fprintf(w, "catcher_job_duration_seconds_count{%s} %d\n", l.format(""), h.count)
+	}
+
+	family = "catcher_downloaded_bytes_total"
+	if openMetrics {
+		family = "catcher_downloaded_bytes"
+	}
+	fmt.Fprintf(w, "# HELP %s Bytes produced by jobs, by processor and host.\n", family)
+	fmt.Fprintf(w, "# TYPE %s counter\n", family)
+	for _, l := range sortedLabels(m.bytes) {
+		c := m.bytes[l]
+		fmt.Fprintf(w, "catcher_downloaded_bytes_total{%s} %g", l.format(""), c.value)
+		writeExemplar(w, c.exemplar, openMetrics)
+	}
+
+	family = "catcher_db_retries_total"
+	if openMetrics {
+		family = "catcher_db_retries"
+	}
+	fmt.Fprintf(w, "# HELP %s Repository operations retried after a transient database error.\n", family)
+	fmt.Fprintf(w, "# TYPE %s counter\n", family)
+	ops := make([]string, 0, len(m.retries))
+	for op := range m.retries {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		fmt.Fprintf(w, "catcher_db_retries_total{op=\"%s\"} %g\n", labelEscaper.Replace(op), m.retries[op])
+	}
+
+	queues := make([]string, 0, len(m.queues))
+	for q := range m.queues {
+		queues = append(queues, q)
+	}
+	sort.Strings(queues)
+	fmt.Fprintln(w, "# HELP catcher_queue_pending_jobs Pending jobs by queue, held ones included.")
+	fmt.Fprintln(w, "# TYPE catcher_queue_pending_jobs gauge")
+	for _, q := range queues {
+		fmt.Fprintf(w, "catcher_queue_pending_jobs{queue=\"%s\"} %d\n", labelEscaper.Replace(q), m.queues[q].pending)
+	}
+	fmt.Fprintln(w, "# HELP catcher_queue_running_jobs Jobs being processed by queue.")
+	fmt.Fprintln(w, "# TYPE catcher_queue_running_jobs gauge")
+	for _, q := range queues {
+		fmt.Fprintf(w, "catcher_queue_running_jobs{queue=\"%s\"} %d\n", labelEscaper.Replace(q), m.queues[q].running)
+	}
+
+	if openMetrics {
+		fmt.Fprintln(w, "# EOF")
+	}
+}
+
+// writeExemplar ends a sample line, appending the exemplar in OpenMetrics mode.
+func writeExemplar(w io.Writer, e exemplar, openMetrics bool) {
+	if openMetrics && e.jobID != 0 {
+		fmt.Fprintf(w, ` # {job_id="%d"} %g %.3f`, e.jobID, e.value, float64(e.ts.UnixMilli())/1000)
+	}
+	fmt.Fprintln(w)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (l labels) format(le string) string {
+	s := `processor="` + labelEscaper.Replace(l.processor) + `"`
+	if l.status != "" {
+		s += `,status="` + labelEscaper.Replace(l.status) + `"`
+	}
+	s += `,host="` + labelEscaper.Replace(l.host) + `"`
+	if le != "" {
+		s += `,le="` + le + `"`
+	}
+	return s
+}
+
+func sortedLabels[V any](m map[labels]V) []labels {
+	keys := make([]labels, 0, len(m))
+	for l := range m {
+		keys = append(keys, l)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.processor != b.processor {
+			return a.processor < b.processor
+		}
+		if a.status != b.status {
+			return a.status < b.status
+		}
+		return a.host < b.host
+	})
+	return keys
+}
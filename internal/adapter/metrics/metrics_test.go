@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestMetrics_HostLabel(t *testing.T) {
+	m := New([]string{"youtube.com", ".Vimeo.com"})
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://youtube.com/watch?v=1", want: "youtube.com"},
+		{url: "https://www.youtube.com/watch?v=1", want: "youtube.com"},
+		{url: "https://player.vimeo.com/video/1", want: "vimeo.com"},
+		{url: "https://notyoutube.com/watch", want: OtherHost},
+		{url: "https://example.com", want: OtherHost},
+		{url: "::bad", want: OtherHost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := m.HostLabel(tt.url); got != tt.want {
+				t.Errorf("HostLabel(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetrics_PrometheusText(t *testing.T) {
+	m := New([]string{"youtube.com"})
+	m.JobFinished(&domain.Job{ID: 1, URL: "https://youtube.com/a", Bytes: 1000}, "youtube", "completed", 3*time.Second)
+	m.JobFinished(&domain.Job{ID: 2, URL: "https://youtube.com/b", Bytes: 500}, "youtube", "completed", 90*time.Second)
+	m.JobFinished(&domain.Job{ID: 3, URL: "https://example.com/c"}, "", "failed", 0)
+	m.DBRetry("complete")
+	m.DBRetry("complete")
+	m.QueueDepth("podcast", 120, 2)
+	m.QueueDepth(domain.DefaultQueue, 3, 1)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE catcher_jobs_total counter",
+		`catcher_jobs_total{processor="youtube",status="completed",host="youtube.com"} 2`,
+		`catcher_jobs_total{processor="none",status="failed",host="other"} 1`,
+		`catcher_job_duration_seconds_bucket{processor="youtube",status="completed",host="youtube.com",le="5"} 1`,
+		`catcher_job_duration_seconds_bucket{processor="youtube",status="completed",host="youtube.com",le="120"} 2`,
+		`catcher_job_duration_seconds_bucket{processor="youtube",status="completed",host="youtube.com",le="+Inf"} 2`,
+		`catcher_job_duration_seconds_sum{processor="youtube",status="completed",host="youtube.com"} 93`,
+		"# TYPE catcher_downloaded_bytes_total counter",
+		`catcher_downloaded_bytes_total{processor="youtube",host="youtube.com"} 1500`,
+		"# TYPE catcher_db_retries_total counter",
+		`catcher_db_retries_total{op="complete"} 2`,
+		"# TYPE catcher_queue_pending_jobs gauge",
+		`catcher_queue_pending_jobs{queue="podcast"} 120`,
+		`catcher_queue_pending_jobs{queue="default"} 3`,
+		`catcher_queue_running_jobs{queue="podcast"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("output missing %q\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "job_id") {
+		t.Error("Prometheus text output contains exemplars")
+	}
+	if strings.Contains(body, `catcher_downloaded_bytes_total{processor="none"`) {
+		t.Error("jobs without output must not report bytes")
+	}
+}
+
+func TestMetrics_OpenMetricsExemplars(t *testing.T) {
+	m := New(nil)
+	m.JobFinished(&domain.Job{ID: 42, URL: "https://example.com"}, "generic", "retry", 2*time.Second)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	body := rec.Body.String()
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/openmetrics-text") {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(body, "# TYPE catcher_jobs counter") {
+		t.Errorf("missing OpenMetrics counter family\n%s", body)
+	}
+	if !strings.Contains(body, `host="other",le="5"} 1 # {job_id="42"} 2 `) {
+		t.Errorf("missing bucket exemplar\n%s", body)
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("OpenMetrics output missing # EOF terminator")
+	}
+}
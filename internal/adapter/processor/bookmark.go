@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// SavesBookmarks implements domain.BookmarkSaver.
+func (p *CommandProcessor) SavesBookmarks() bool { return true }
+
+// writeBookmark records a bookmark job in targetDir instead of downloading
+// it: a .strm file holding the URL, which media servers such as Jellyfin
+// and Kodi stream on demand, and an .nfo naming it.
+func (p *CommandProcessor) writeBookmark(ctx context.Context, job *domain.Job, targetDir string) (*domain.ProcessResult, error) {
+	title := p.bookmarkTitle(ctx, job)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("create target dir: %w", err)
+	}
+
+	nfo, err := bookmarkNFO(title, job.Notes)
+	if err != nil {
+		return nil, err
+	}
+	// The ID keeps bookmarks with the same title apart
+	base := filepath.Join(targetDir, fmt.Sprintf("%s [%d]", sanitizePlaceholder(title), job.ID))
+	result := &domain.ProcessResult{Title: title}
+	for _, f := range []struct {
+		path string
+		data []byte
+	}{
+		{base + ".strm", []byte(job.URL + "\n")},
+		{base + ".nfo", nfo},
+	} {
+		if err := os.WriteFile(f.path, f.data, 0644); err != nil {
+			return nil, fmt.Errorf("write bookmark: %w", err)
+		}
+		result.Files = append(result.Files, domain.ResultFile{Path: f.path, Bytes: int64(len(f.data))})
+		result.Bytes += int64(len(f.data))
+	}
+	return result, nil
+}
+
+// bookmarkTitle names a bookmark: the title from the probe if the processor
+// has one, else the job's notes, else the last part of its URL.
+func (p *CommandProcessor) bookmarkTitle(ctx context.Context, job *domain.Job) string {
+	if len(p.probeArgs) > 0 {
+		info, err := p.probe(domain.WithUserAgent(ctx, job.UserAgent), job.URL)
+		if err == nil && info.Title != "" {
+			return info.Title
+		}
+		if err != nil {
+			log.Printf("job %d: probe for bookmark title failed: %v", job.ID, err)
+		}
+	}
+	if job.Notes != "" {
+		return job.Notes
+	}
+	if u, err := url.Parse(job.URL); err == nil {
+		if name := path.Base(u.Path); name != "/" && name != "." {
+			return u.Host + " " + name
+		}
+		return u.Host
+	}
+	return fmt.Sprintf("job %d", job.ID)
+}
+
+// bookmarkNFO returns a minimal Kodi-style movie NFO, which Jellyfin reads
+// too.
+func bookmarkNFO(title, plot string) ([]byte, error) {
+	nfo := struct {
+		XMLName xml.Name `xml:"movie"`
+		Title   string   `xml:"title"`
+		Plot    string   `xml:"plot,omitempty"`
+	}{Title: strings.TrimSpace(title), Plot: plot}
+	data, err := xml.MarshalIndent(nfo, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
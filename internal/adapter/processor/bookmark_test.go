@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestCommandProcessor_Bookmark(t *testing.T) {
+	tests := []struct {
+		name      string
+		probeArgs []string
+		notes     string
+		wantTitle string
+	}{
+		{"probed title", []string{"-c", `echo '{"title": "Keynote: Go & You"}'`}, "for mum", "Keynote: Go & You"},
+		{"probe fails", []string{"-c", "exit 1"}, "for mum", "for mum"},
+		{"notes", nil, "conference talk", "conference talk"},
+		{"url", nil, "", "example.com talk.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			p, err := NewCommandProcessor(config.ProcessorConfig{
+				Name:      "videos",
+				Pattern:   ".*",
+				Command:   "sh",
+				Args:      []string{"-c", "echo downloaded > should-not-exist"},
+				TargetDir: dir,
+				ProbeArgs: tt.probeArgs,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			job := &domain.Job{ID: 7, URL: "https://example.com/talk.html", Notes: tt.notes, Bookmark: true}
+			result, err := p.Process(context.Background(), job)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if result.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", result.Title, tt.wantTitle)
+			}
+			if len(result.Files) != 2 {
+				t.Fatalf("Files = %+v, want .strm and .nfo", result.Files)
+			}
+
+			strm := result.Files[0].Path
+			if filepath.Dir(strm) != dir || !strings.HasSuffix(strm, " [7].strm") {
+				t.Errorf("strm path = %q, want %q/<title> [7].strm", strm, dir)
+			}
+			if data, _ := os.ReadFile(strm); string(data) != job.URL+"\n" {
+				t.Errorf("strm = %q, want the URL", data)
+			}
+			nfo, _ := os.ReadFile(result.Files[1].Path)
+			if !strings.Contains(string(nfo), "<movie>") || !strings.Contains(string(nfo), "<title>") {
+				t.Errorf("nfo = %s, want a movie with a title", nfo)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "should-not-exist")); err == nil {
+				t.Error("bookmark ran the download command")
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_BookmarkURLInTargetDir(t *testing.T) {
+	base := t.TempDir()
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "videos",
+		Pattern:   ".*",
+		Command:   "true",
+		TargetDir: filepath.Join(base, "{url}"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &domain.Job{ID: 7, URL: "https://x/../../../../../../tmp/escape", Notes: "talk", Bookmark: true}
+	result, err := p.Process(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	want := filepath.Join(base, "https___x_.._.._.._.._.._.._tmp_escape")
+	if len(result.Files) == 0 || filepath.Dir(result.Files[0].Path) != want {
+		t.Errorf("Files = %+v, want them in %s", result.Files, want)
+	}
+}
+
+func TestBookmarkNFO(t *testing.T) {
+	got, err := bookmarkNFO("Tom & Jerry <3", "for mum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<movie>
+  <title>Tom &amp; Jerry &lt;3</title>
+  <plot>for mum</plot>
+</movie>
+`
+	if string(got) != want {
+		t.Errorf("bookmarkNFO() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,35 +1,91 @@
 package processor
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/cwygoda/catcher/internal/config"
 	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/logging"
+	"github.com/cwygoda/catcher/internal/netaddr"
 )
 
+// stopGrace is how long a cancelled command has to exit after SIGTERM
+// before it is killed.
+const stopGrace = 10 * time.Second
+
 // CommandProcessor runs an external command for matching URLs.
 type CommandProcessor struct {
-	name      string
-	pattern   *regexp.Regexp
-	command   string
-	args      []string
-	targetDir string
-	isolate   bool
+	name           string
+	patterns       []urlPattern
+	exclude        []*regexp.Regexp
+	priority       int
+	command        string
+	args           []string
+	targetDir      string
+	workDir        string
+	isolate        bool
+	quota          int64 // bytes a run's work dir may hold, 0 for no limit
+	resubmit       domain.ResubmitPolicy
+	probeArgs      []string
+	probeCache     domain.ProbeCache // nil unless probe results are cached
+	probeTTL       time.Duration
+	maxSize        int64
+	oversize       domain.OversizePolicy
+	success        []int // non-zero exit codes that count as success
+	rateLimitDelay time.Duration
+	concurrency    int
+	cost           int
+	userAgent      string // default for jobs that don't set their own
+	sourceAddress  string // IP address or interface to download from
+	forceIP        string // "4" or "6" to connect over that IP version only
+	tuning         tuning
+	dns            config.DNSConfig
+	fake           *fakeRun // set for fake processors, which run no command
+	library        *library // nil without a configured library
+	masker         *logging.Masker
+	clock          domain.Clock
 }
 
 // NewCommandProcessor creates a processor from config.
-// Uses default ~/Videos if target_dir not set, isolate defaults to true.
+// Uses config.DefaultTargetDir if target_dir not set, isolate defaults to true.
 func NewCommandProcessor(pc config.ProcessorConfig) (*CommandProcessor, error) {
-	re, err := regexp.Compile(pc.Pattern)
-	if err != nil {
-		return nil, fmt.Errorf("invalid pattern %q: %w", pc.Pattern, err)
+	sources := pc.AllPatterns()
+	if len(sources) == 0 {
+		sources = []string{""}
+	}
+	patterns := make([]urlPattern, len(sources))
+	for i, src := range sources {
+		re, err := regexp.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", src, err)
+		}
+		patterns[i].re = re
+		if parsed, err := syntax.Parse(src, syntax.Perl); err == nil {
+			patterns[i].literals = literalLen(parsed)
+		}
+	}
+	exclude := make([]*regexp.Regexp, len(pc.Exclude))
+	for i, src := range pc.Exclude {
+		re, err := regexp.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", src, err)
+		}
+		exclude[i] = re
 	}
 
 	targetDir := pc.TargetDir
@@ -44,13 +100,94 @@ func NewCommandProcessor(pc config.ProcessorConfig) (*CommandProcessor, error) {
 		isolate = *pc.Isolate
 	}
 
+	resubmit, err := domain.ParseResubmitPolicy(pc.Resubmit)
+	if err != nil {
+		return nil, err
+	}
+
+	var maxSize int64
+	if pc.MaxSize != "" {
+		if maxSize, err = config.ParseSize(pc.MaxSize); err != nil {
+			return nil, err
+		}
+	}
+	var quota int64
+	if pc.WorkDirQuota != "" {
+		if quota, err = config.ParseSize(pc.WorkDirQuota); err != nil {
+			return nil, fmt.Errorf("work_dir_quota: %w", err)
+		}
+		if quota > 0 && !isolate {
+			return nil, fmt.Errorf("work_dir_quota needs isolate, as direct runs have no work dir")
+		}
+	}
+	oversize, err := domain.ParseOversizePolicy(pc.Oversize)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateUserAgent(pc.UserAgent); err != nil {
+		return nil, fmt.Errorf("user_agent %q: %w", pc.UserAgent, err)
+	}
+	if !netaddr.ValidSource(pc.SourceAddress) {
+		return nil, fmt.Errorf("invalid source_address %q: want an IP address or interface name", pc.SourceAddress)
+	}
+	if pc.ForceIP != "" && pc.ForceIP != "4" && pc.ForceIP != "6" {
+		return nil, fmt.Errorf("invalid force_ip %q: want \"4\" or \"6\"", pc.ForceIP)
+	}
+	if tool := config.ToolName(pc.Command); pc.ForceIP != "" && config.ForceIPFlags[tool] == nil {
+		return nil, fmt.Errorf("force_ip needs yt-dlp, youtube-dl, curl, or wget as the command, not %s", tool)
+	}
+	if ip := net.ParseIP(pc.SourceAddress); ip != nil && pc.ForceIP != "" && (ip.To4() != nil) != (pc.ForceIP == "4") {
+		return nil, fmt.Errorf("source_address %s is not an IPv%s address, as force_ip requires", pc.SourceAddress, pc.ForceIP)
+	}
+
+	tune, err := newTuning(pc)
+	if err != nil {
+		return nil, err
+	}
+
+	var lib *library
+	if len(pc.Library) > 0 {
+		if !slices.ContainsFunc(patterns, func(p urlPattern) bool { return slices.Contains(p.re.SubexpNames(), "id") }) {
+			return nil, fmt.Errorf("library needs a pattern with an (?P<id>...) group to look URLs up by")
+		}
+		lib = newLibrary(pc.Library, pc.LibraryRescan)
+	}
+
+	command := pc.Command
+	var fake *fakeRun
+	if pc.Fake != nil {
+		if fake, err = newFakeRun(*pc.Fake); err != nil {
+			return nil, err
+		}
+		command = fakeCommand
+	}
+
 	return &CommandProcessor{
-		name:      pc.Name,
-		pattern:   re,
-		command:   pc.Command,
-		args:      pc.Args,
-		targetDir: targetDir,
-		isolate:   isolate,
+		name:           pc.Name,
+		patterns:       patterns,
+		exclude:        exclude,
+		priority:       pc.Priority,
+		command:        command,
+		args:           pc.Args,
+		targetDir:      targetDir,
+		isolate:        isolate,
+		quota:          quota,
+		resubmit:       resubmit,
+		probeArgs:      pc.ProbeArgs,
+		maxSize:        maxSize,
+		oversize:       oversize,
+		success:        pc.SuccessExitCodes,
+		rateLimitDelay: pc.RateLimitDelay,
+		concurrency:    pc.Concurrency,
+		cost:           pc.Cost,
+		userAgent:      pc.UserAgent,
+		sourceAddress:  pc.SourceAddress,
+		forceIP:        pc.ForceIP,
+		tuning:         tune,
+		dns:            pc.DNS,
+		fake:           fake,
+		library:        lib,
+		clock:          domain.SystemClock,
 	}, nil
 }
 
@@ -58,66 +195,390 @@ func (p *CommandProcessor) Name() string {
 	return p.name
 }
 
+// TargetDir returns the configured target directory, before placeholders
+// are replaced for a job.
 func (p *CommandProcessor) TargetDir() string {
 	return p.targetDir
 }
 
+// SetClock makes how often work dirs are measured against the quota, and
+// the library index's age, which decides when the directories are walked
+// again, follow c.
+func (p *CommandProcessor) SetClock(c domain.Clock) {
+	p.clock = c
+	if p.library != nil {
+		p.library.clock = c
+	}
+}
+
+// SetWorkDir sets where test runs, and isolated runs without a work dir
+// from the worker, create their temp directories. Defaults to the system
+// temp directory.
+func (p *CommandProcessor) SetWorkDir(dir string) {
+	p.workDir = dir
+}
+
+// SetMasker sets the secrets hidden in rendered command lines and output
+// before they are logged or kept with the attempt.
+func (p *CommandProcessor) SetMasker(m *logging.Masker) {
+	p.masker = m
+}
+
+// tempDir creates a temp directory under the work directory.
+func (p *CommandProcessor) tempDir(pattern string) (string, error) {
+	if p.workDir != "" {
+		if err := os.MkdirAll(p.workDir, 0o755); err != nil {
+			return "", err
+		}
+	}
+	return os.MkdirTemp(p.workDir, pattern)
+}
+
 func (p *CommandProcessor) Match(url string) bool {
-	return p.pattern.MatchString(url)
+	return p.match(url) != nil
+}
+
+// urlPattern is one of a processor's patterns.
+type urlPattern struct {
+	re       *regexp.Regexp
+	literals int // characters a matching URL must contain, for MatchScore
+}
+
+// match returns the most specific pattern matching url, or nil if none
+// does or an exclude pattern matches.
+func (p *CommandProcessor) match(url string) *urlPattern {
+	var best *urlPattern
+	for i := range p.patterns {
+		if pat := &p.patterns[i]; pat.re.MatchString(url) && (best == nil || pat.literals > best.literals) {
+			best = pat
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	for _, re := range p.exclude {
+		if re.MatchString(url) {
+			return nil
+		}
+	}
+	return best
+}
+
+// MatchScore implements domain.MatchScorer. Patterns that pin down more of
+// the URL score higher, so "youtube\.com" beats a catch-all like "^https?://".
+// With several patterns, the most specific one matching counts.
+func (p *CommandProcessor) MatchScore(url string) int {
+	pat := p.match(url)
+	if pat == nil {
+		return 0
+	}
+	return 1 + pat.literals
+}
+
+// Priority implements domain.Prioritizer.
+func (p *CommandProcessor) Priority() int {
+	return p.priority
+}
+
+// Concurrency implements domain.Queuer.
+func (p *CommandProcessor) Concurrency() int {
+	return p.concurrency
+}
+
+// Cost implements domain.Coster.
+func (p *CommandProcessor) Cost() int {
+	return p.cost
 }
 
-func (p *CommandProcessor) Process(ctx context.Context, job *domain.Job) error {
-	// Build args with {url} placeholder replaced
-	args := make([]string, len(p.args))
-	for i, arg := range p.args {
-		args[i] = strings.ReplaceAll(arg, "{url}", job.URL)
+// ResubmitPolicy implements domain.ResubmitPolicer.
+func (p *CommandProcessor) ResubmitPolicy() domain.ResubmitPolicy {
+	return p.resubmit
+}
+
+// literalLen counts the literal characters every match of re contains,
+// taking the shortest alternative and skipping optional parts.
+func literalLen(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpCapture, syntax.OpPlus:
+		return literalLen(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min * literalLen(re.Sub[0])
+	case syntax.OpConcat:
+		n := 0
+		for _, sub := range re.Sub {
+			n += literalLen(sub)
+		}
+		return n
+	case syntax.OpAlternate:
+		n := -1
+		for _, sub := range re.Sub {
+			if l := literalLen(sub); n < 0 || l < n {
+				n = l
+			}
+		}
+		return max(n, 0)
+	}
+	return 0
+}
+
+func (p *CommandProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
+	vars := p.placeholders(job.URL)
+	if job.Bookmark {
+		return p.writeBookmark(ctx, job, p.targetDirFor(vars))
+	}
+	if p.fake != nil {
+		return p.processFake(ctx, job, p.targetDirFor(vars))
+	}
+	if err := p.addJobVars(vars, job.UserAgent); err != nil {
+		return nil, err
 	}
+	args := p.renderDownloadArgs(vars)
+	cmdline := p.masker.Mask(renderCommand(p.command, args))
+	domain.AttemptFrom(ctx).Command = cmdline
+	logging.Debugf("job %d: exec %s", job.ID, cmdline)
 
+	targetDir := p.targetDirFor(vars)
 	if p.isolate {
-		return p.processIsolated(ctx, job, args)
+		return p.processIsolated(ctx, job, args, targetDir)
 	}
-	return p.processDirect(ctx, args)
+	return p.processDirect(ctx, job, args, targetDir)
 }
 
-// processDirect runs command directly in target directory.
-func (p *CommandProcessor) processDirect(ctx context.Context, args []string) error {
-	if err := os.MkdirAll(p.targetDir, 0755); err != nil {
-		return fmt.Errorf("create target dir: %w", err)
+// renderArgs returns args with placeholders replaced.
+func renderArgs(args []string, vars map[string]string) []string {
+	rendered := make([]string, len(args))
+	for i, arg := range args {
+		rendered[i] = expand(arg, vars)
 	}
+	return rendered
+}
 
-	cmd := exec.CommandContext(ctx, p.command, args...)
-	cmd.Dir = p.targetDir
-	output, err := cmd.CombinedOutput()
+// Test runs the command for url in a throwaway directory, streaming its
+// output to out and listing the files it produced. Nothing is kept.
+func (p *CommandProcessor) Test(ctx context.Context, url string, out io.Writer) error {
+	if p.fake != nil {
+		return p.testFake(ctx, url, out)
+	}
+	vars := p.placeholders(url)
+	if err := p.addJobVars(vars, ""); err != nil {
+		return err
+	}
+	args := p.renderDownloadArgs(vars)
+	tempDir, err := p.tempDir("catcher-test-*")
 	if err != nil {
-		return fmt.Errorf("%s failed: %w: %s", p.command, err, string(output))
+		return fmt.Errorf("create temp dir: %w", err)
 	}
+	defer os.RemoveAll(tempDir)
+
+	fmt.Fprintf(out, "$ %s\n", p.masker.Mask(renderCommand(p.command, args)))
+	runCtx, unwatch := p.watchQuota(ctx, tempDir)
+	cmd := p.newCmd(runCtx, args)
+	cmd.Dir = tempDir
+	masked := p.masker.Writer(out)
+	cmd.Stdout = masked
+	cmd.Stderr = masked
+	terminate(cmd)
+	err = cmd.Run()
+	masked.Flush()
+	if qerr := unwatch(); qerr != nil {
+		return qerr
+	}
+	if err != nil {
+		code, ok := p.successExit(err)
+		if !ok {
+			return fmt.Errorf("%s failed: %w", p.command, err)
+		}
+		fmt.Fprintf(out, "exit status %d counts as success\n", code)
+	}
+
+	listFiles(out, tempDir)
 	return nil
 }
 
-// processIsolated runs in temp dir, moves files on success.
-func (p *CommandProcessor) processIsolated(ctx context.Context, job *domain.Job, args []string) error {
-	tempDir, err := os.MkdirTemp("", fmt.Sprintf("catcher-job-%d-*", job.ID))
+// testFake is Test for fake processors.
+func (p *CommandProcessor) testFake(ctx context.Context, url string, out io.Writer) error {
+	tempDir, err := p.tempDir("catcher-test-*")
 	if err != nil {
 		return fmt.Errorf("create temp dir: %w", err)
 	}
-	log.Printf("job %d: running isolated in %s", job.ID, tempDir)
 	defer os.RemoveAll(tempDir)
 
-	cmd := exec.CommandContext(ctx, p.command, args...)
+	fmt.Fprintf(out, "$ %s\n", p.fake)
+	if _, err := p.processFake(ctx, &domain.Job{URL: url}, tempDir); err != nil {
+		return err
+	}
+	listFiles(out, tempDir)
+	return nil
+}
+
+// listFiles writes the files in dir with their sizes to out.
+func listFiles(out io.Writer, dir string) {
+	sizes := fileSizes(dir)
+	names := make([]string, 0, len(sizes))
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(out, "produced %d file(s)\n", len(names))
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s (%d bytes)\n", name, sizes[name])
+	}
+}
+
+// run runs cmd and returns its combined output with secrets masked. Each
+// write of output beats the job's heartbeat, and cancelling ctx asks the
+// command to stop before killing it (see terminate).
+func (p *CommandProcessor) run(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	out := &beatWriter{heartbeat: domain.HeartbeatFrom(ctx)}
+	cmd.Stdout, cmd.Stderr = out, out
+	terminate(cmd)
+	err := cmd.Run()
+	output := out.buf.Bytes()
+	if p.masker != nil {
+		output = []byte(p.masker.Mask(string(output)))
+	}
+	return output, err
+}
+
+// beatWriter collects command output, beating a heartbeat on every write.
+type beatWriter struct {
+	buf       bytes.Buffer
+	heartbeat *domain.Heartbeat
+}
+
+func (w *beatWriter) Write(b []byte) (int, error) {
+	w.heartbeat.Beat()
+	return w.buf.Write(b)
+}
+
+// successExit reports whether err is the command exiting with one of the
+// configured success exit codes, and which.
+func (p *CommandProcessor) successExit(err error) (int, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+	code := exitErr.ExitCode()
+	return code, slices.Contains(p.success, code)
+}
+
+// checkExit returns err from running the command for a job, or nil if it
+// is a success exit code, which it reports along with the code.
+func (p *CommandProcessor) checkExit(jobID int64, err error) (int, error) {
+	if code, ok := p.successExit(err); ok {
+		log.Printf("job %d: %s exited with status %d, counted as success", jobID, p.command, code)
+		return code, nil
+	}
+	return 0, err
+}
+
+// alreadyDone marks res as fetching nothing new if the run exited with
+// success exit code code and made no files.
+func (p *CommandProcessor) alreadyDone(res *domain.ProcessResult, code int) {
+	if code == 0 || len(res.Files) > 0 {
+		return
+	}
+	res.AlreadyDone = true
+	res.Warnings = append(res.Warnings, fmt.Sprintf("%s exited with status %d and made no files, taken as already downloaded", p.command, code))
+}
+
+// failed returns the result and error for a run that failed with err,
+// carrying any retry hint found in its output.
+func (p *CommandProcessor) failed(err error, output []byte) (*domain.ProcessResult, error) {
+	err = fmt.Errorf("%s failed: %w: %s", p.command, err, string(output))
+	if after := p.retryAfter(output, time.Now()); after > 0 {
+		return &domain.ProcessResult{RetryAfter: after}, err
+	}
+	return nil, err
+}
+
+// processDirect runs command directly in target directory. Bytes are
+// measured as the growth of files in the target directory.
+func (p *CommandProcessor) processDirect(ctx context.Context, job *domain.Job, args []string, targetDir string) (*domain.ProcessResult, error) {
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("create target dir: %w", err)
+	}
+	before := fileStates(targetDir)
+
+	cmd := p.newCmd(ctx, args)
+	cmd.Dir = targetDir
+	output, err := p.run(ctx, cmd)
+	domain.AttemptFrom(ctx).SetOutput(output)
+	code, err := p.checkExit(job.ID, err)
+	if err != nil {
+		return p.failed(err, output)
+	}
+
+	after := fileStates(targetDir)
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := &domain.ProcessResult{}
+	for _, name := range names {
+		now, then := after[name], before[name]
+		if now == then {
+			continue
+		}
+		// A file that grew was written or resumed; one that changed
+		// otherwise was written over, as by a re-download
+		if grown := now.size - then.size; grown > 0 {
+			res.Bytes += grown
+		} else {
+			res.Bytes += now.size
+		}
+		res.Files = append(res.Files, domain.ResultFile{Path: filepath.Join(targetDir, name), Bytes: now.size})
+	}
+	p.alreadyDone(res, code)
+	return res, nil
+}
+
+// processIsolated runs in the job's work dir, moves files on success. The
+// work dir catcher manages survives a failed run, so the next attempt can
+// pick up partial downloads; without one, a temp dir is used per run.
+func (p *CommandProcessor) processIsolated(ctx context.Context, job *domain.Job, args []string, targetDir string) (*domain.ProcessResult, error) {
+	tempDir := domain.WorkDirFrom(ctx)
+	if tempDir == "" {
+		var err error
+		tempDir, err = p.tempDir(fmt.Sprintf("catcher-job-%d-*", job.ID))
+		if err != nil {
+			return nil, fmt.Errorf("create temp dir: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+	}
+	log.Printf("job %d: running isolated in %s", job.ID, tempDir)
+
+	runCtx, unwatch := p.watchQuota(ctx, tempDir)
+	cmd := p.newCmd(runCtx, args)
 	cmd.Dir = tempDir
-	output, err := cmd.CombinedOutput()
+	output, err := p.run(ctx, cmd)
+	domain.AttemptFrom(ctx).SetOutput(output)
+	if qerr := unwatch(); qerr != nil {
+		log.Printf("job %d: stopped %s: %v", job.ID, p.command, qerr)
+		return nil, qerr
+	}
+	code, err := p.checkExit(job.ID, err)
 	if err != nil {
-		return fmt.Errorf("%s failed: %w: %s", p.command, err, string(output))
+		return p.failed(err, output)
 	}
 
-	return p.moveFiles(job.ID, tempDir)
+	res, err := p.moveFiles(job.ID, tempDir, targetDir)
+	if err == nil {
+		p.alreadyDone(res, code)
+	}
+	return res, err
 }
 
-// moveFiles moves files from src to target, skipping existing.
-func (p *CommandProcessor) moveFiles(jobID int64, srcDir string) error {
+// moveFiles moves files from src to target and returns the files and number
+// of bytes moved. Existing files are skipped with a warning, or overwritten
+// under ResubmitReplace.
+func (p *CommandProcessor) moveFiles(jobID int64, srcDir, targetDir string) (*domain.ProcessResult, error) {
 	entries, err := os.ReadDir(srcDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Collect file names for logging
@@ -129,35 +590,100 @@ func (p *CommandProcessor) moveFiles(jobID int64, srcDir string) error {
 	}
 	log.Printf("job %d: found %d file(s): %v", jobID, len(files), files)
 
-	if err := os.MkdirAll(p.targetDir, 0755); err != nil {
-		return err
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, err
 	}
 
-	var moved []string
+	res := &domain.ProcessResult{}
+	skipped := 0
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 		src := filepath.Join(srcDir, entry.Name())
-		dst := filepath.Join(p.targetDir, entry.Name())
+		dst := filepath.Join(targetDir, entry.Name())
 
-		// Skip if destination exists (no overwrite)
-		if _, err := os.Stat(dst); err == nil {
+		// Skip if destination exists, unless replacing an earlier download
+		if _, err := os.Stat(dst); err == nil && p.resubmit != domain.ResubmitReplace {
 			log.Printf("job %d: skipped %s (exists)", jobID, entry.Name())
+			res.Warnings = append(res.Warnings, fmt.Sprintf("%s already exists in %s, not overwritten", entry.Name(), targetDir))
+			skipped++
 			continue
 		}
 
+		var size int64
+		if info, err := entry.Info(); err == nil {
+			size = info.Size()
+		}
 		if err := os.Rename(src, dst); err != nil {
 			// Cross-device fallback
 			if err := copyFile(src, dst); err != nil {
-				return err
+				return res, err
 			}
 			os.Remove(src)
 		}
-		moved = append(moved, entry.Name())
+		res.Files = append(res.Files, domain.ResultFile{Path: dst, Bytes: size})
+		res.Bytes += size
 	}
-	log.Printf("job %d: moved %d file(s) to %s", jobID, len(moved), p.targetDir)
-	return nil
+	res.AlreadyDone = len(res.Files) == 0 && skipped > 0
+	log.Printf("job %d: moved %d file(s) (%d bytes) to %s", jobID, len(res.Files), res.Bytes, targetDir)
+	return res, nil
+}
+
+// fileState is what processDirect compares to tell which files a run
+// wrote.
+type fileState struct {
+	size int64
+	mod  time.Time
+}
+
+// fileStates returns the state of each regular file directly in dir.
+func fileStates(dir string) map[string]fileState {
+	states := make(map[string]fileState)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return states
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			states[entry.Name()] = fileState{size: info.Size(), mod: info.ModTime()}
+		}
+	}
+	return states
+}
+
+// fileSizes returns the size of each regular file directly in dir.
+func fileSizes(dir string) map[string]int64 {
+	sizes := make(map[string]int64)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return sizes
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			sizes[entry.Name()] = info.Size()
+		}
+	}
+	return sizes
+}
+
+// renderCommand formats a command line for display, quoting arguments
+// that a shell would split or interpret.
+func renderCommand(command string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	for _, s := range append([]string{command}, args...) {
+		if s == "" || strings.ContainsAny(s, " \t\n'\"\\$`|&;<>()*?[]#~{}") {
+			s = "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
 }
 
 // copyFile copies a file from src to dst.
@@ -4,10 +4,13 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cwygoda/catcher/internal/config"
 	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/logging"
 )
 
 func boolPtr(b bool) *bool { return &b }
@@ -37,6 +40,89 @@ func TestNewCommandProcessor(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "user agent starting with a dash",
+			cfg: config.ProcessorConfig{
+				Name:      "bad",
+				Command:   "yt-dlp",
+				UserAgent: "--exec rm",
+			},
+			wantErr: true,
+		},
+		{
+			name: "source interface",
+			cfg: config.ProcessorConfig{
+				Name:          "archive",
+				Command:       "yt-dlp",
+				SourceAddress: "eth1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid force_ip",
+			cfg: config.ProcessorConfig{
+				Name:    "bad",
+				Command: "yt-dlp",
+				ForceIP: "ipv4",
+			},
+			wantErr: true,
+		},
+		{
+			name: "force_ip for a tool without a flag for it",
+			cfg: config.ProcessorConfig{
+				Name:    "bad",
+				Command: "gallery-dl",
+				ForceIP: "4",
+			},
+			wantErr: true,
+		},
+		{
+			name: "source address of the wrong IP version",
+			cfg: config.ProcessorConfig{
+				Name:          "bad",
+				Command:       "yt-dlp",
+				SourceAddress: "192.0.2.7",
+				ForceIP:       "6",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid source address",
+			cfg: config.ProcessorConfig{
+				Name:          "bad",
+				Command:       "yt-dlp",
+				SourceAddress: "--exec rm",
+			},
+			wantErr: true,
+		},
+		{
+			name: "work dir quota",
+			cfg: config.ProcessorConfig{
+				Name:         "mirror",
+				Command:      "wget",
+				WorkDirQuota: "20GB",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid work dir quota",
+			cfg: config.ProcessorConfig{
+				Name:         "bad",
+				Command:      "wget",
+				WorkDirQuota: "lots",
+			},
+			wantErr: true,
+		},
+		{
+			name: "work dir quota without isolation",
+			cfg: config.ProcessorConfig{
+				Name:         "bad",
+				Command:      "wget",
+				WorkDirQuota: "20GB",
+				Isolate:      boolPtr(false),
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -49,6 +135,44 @@ func TestNewCommandProcessor(t *testing.T) {
 	}
 }
 
+func TestCommandProcessor_MultiplePatterns(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:     "video",
+		Pattern:  `youtube\.com/watch`,
+		Patterns: []string{`youtu\.be/`, `vimeo\.com/\d+`},
+		Exclude:  []string{`/shorts/`, `[?&]list=`},
+		Command:  "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url       string
+		wantMatch bool
+		wantScore int
+	}{
+		{"https://www.youtube.com/watch?v=abc", true, 1 + len("youtube.com/watch")},
+		{"https://youtu.be/abc", true, 1 + len("youtu.be/")},
+		{"https://vimeo.com/123", true, 1 + len("vimeo.com/")},
+		{"https://youtu.be/shorts/abc", false, 0},
+		{"https://www.youtube.com/watch?v=abc&list=xyz", false, 0},
+		{"https://example.com/", false, 0},
+	}
+	for _, tt := range tests {
+		if got := p.Match(tt.url); got != tt.wantMatch {
+			t.Errorf("Match(%q) = %v, want %v", tt.url, got, tt.wantMatch)
+		}
+		if got := p.MatchScore(tt.url); got != tt.wantScore {
+			t.Errorf("MatchScore(%q) = %d, want %d", tt.url, got, tt.wantScore)
+		}
+	}
+
+	if _, err := NewCommandProcessor(config.ProcessorConfig{Name: "bad", Patterns: []string{"a"}, Exclude: []string{"["}}); err == nil {
+		t.Error("NewCommandProcessor() with an invalid exclude pattern succeeded")
+	}
+}
+
 func TestCommandProcessor_Name(t *testing.T) {
 	p, _ := NewCommandProcessor(config.ProcessorConfig{
 		Name:    "youtube",
@@ -102,14 +226,23 @@ func TestCommandProcessor_ProcessDirect(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// Left by an earlier run, as when re-downloading
+	path := filepath.Join(targetDir, "output.txt")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(path, old, old)
+
 	job := &domain.Job{ID: 1, URL: "https://example.com"}
-	if err := p.Process(context.Background(), job); err != nil {
-		t.Errorf("Process() error = %v", err)
+	res, err := p.Process(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
 	}
 
-	// Check file was created in target dir
-	if _, err := os.Stat(filepath.Join(targetDir, "output.txt")); os.IsNotExist(err) {
-		t.Error("expected output.txt to exist in target dir")
+	// Written over with the same name and size, it is still this run's file
+	if len(res.Files) != 1 || res.Files[0].Path != path {
+		t.Errorf("Files = %+v, want %s", res.Files, path)
 	}
 }
 
@@ -129,7 +262,7 @@ func TestCommandProcessor_ProcessIsolated(t *testing.T) {
 	}
 
 	job := &domain.Job{ID: 1, URL: "https://example.com"}
-	if err := p.Process(context.Background(), job); err != nil {
+	if _, err := p.Process(context.Background(), job); err != nil {
 		t.Errorf("Process() error = %v", err)
 	}
 
@@ -139,6 +272,119 @@ func TestCommandProcessor_ProcessIsolated(t *testing.T) {
 	}
 }
 
+func TestCommandProcessor_JobWorkDir(t *testing.T) {
+	targetDir := t.TempDir()
+	jobDir := t.TempDir()
+
+	// Fails leaving a partial file, then finishes it on the next attempt
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Pattern:   ".*",
+		Command:   "sh",
+		Args:      []string{"-c", "if [ -f video.part ]; then mv video.part video.mp4; else touch video.part; exit 1; fi"},
+		TargetDir: targetDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := domain.WithWorkDir(context.Background(), jobDir)
+	job := &domain.Job{ID: 1, URL: "https://example.com"}
+	if _, err := p.Process(ctx, job); err == nil {
+		t.Fatal("first Process() succeeded, want error")
+	}
+	if _, err := os.Stat(filepath.Join(jobDir, "video.part")); err != nil {
+		t.Fatalf("partial file not kept in job work dir: %v", err)
+	}
+
+	res, err := p.Process(ctx, job)
+	if err != nil {
+		t.Fatalf("second Process() error = %v", err)
+	}
+	if len(res.Files) != 1 || res.Files[0].Path != filepath.Join(targetDir, "video.mp4") {
+		t.Errorf("Files = %+v, want the resumed video", res.Files)
+	}
+	// The worker owns the job work dir and removes it
+	if _, err := os.Stat(jobDir); err != nil {
+		t.Errorf("job work dir removed by processor: %v", err)
+	}
+}
+
+func TestCommandProcessor_SuccessExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		isolate  bool
+		script   string
+		wantErr  bool
+		wantDone bool
+	}{
+		{"isolated, success code", true, "touch out.mp4; exit 101", false, false},
+		{"isolated, success code without files", true, "exit 101", false, true},
+		{"direct, success code", false, "exit 101", false, true},
+		{"direct, success code with files", false, "touch out.mp4; exit 101", false, false},
+		{"no files", true, "true", false, false},
+		{"isolated, every file exists", true, "touch old.mp4", false, true},
+		{"other code", true, "exit 1", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(targetDir, "old.mp4"), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			p, err := NewCommandProcessor(config.ProcessorConfig{
+				Name:             "test",
+				Pattern:          ".*",
+				Command:          "sh",
+				Args:             []string{"-c", tt.script},
+				TargetDir:        targetDir,
+				Isolate:          boolPtr(tt.isolate),
+				SuccessExitCodes: []int{101},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := p.Process(context.Background(), &domain.Job{ID: 1, URL: "https://example.com"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && res.AlreadyDone != tt.wantDone {
+				t.Errorf("AlreadyDone = %v, want %v", res.AlreadyDone, tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_WorkDir(t *testing.T) {
+	targetDir := t.TempDir()
+	workDir := filepath.Join(t.TempDir(), "work")
+
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Pattern:   ".*",
+		Command:   "pwd",
+		TargetDir: targetDir,
+		Isolate:   boolPtr(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetWorkDir(workDir)
+
+	attempt := &domain.Attempt{}
+	job := &domain.Job{ID: 1, URL: "https://example.com"}
+	if _, err := p.Process(domain.WithAttempt(context.Background(), attempt), job); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if !strings.HasPrefix(attempt.Output, workDir+string(filepath.Separator)) {
+		t.Errorf("ran in %q, want a directory under %q", strings.TrimSpace(attempt.Output), workDir)
+	}
+	if entries, _ := os.ReadDir(workDir); len(entries) != 0 {
+		t.Errorf("work dir not cleaned up: %v", entries)
+	}
+}
+
 func TestCommandProcessor_NoOverwrite(t *testing.T) {
 	targetDir := t.TempDir()
 
@@ -161,8 +407,9 @@ func TestCommandProcessor_NoOverwrite(t *testing.T) {
 	}
 
 	job := &domain.Job{ID: 1, URL: "https://example.com"}
-	if err := p.Process(context.Background(), job); err != nil {
-		t.Errorf("Process() error = %v", err)
+	res, err := p.Process(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
 	}
 
 	// Check original file unchanged
@@ -173,6 +420,108 @@ func TestCommandProcessor_NoOverwrite(t *testing.T) {
 	if string(content) != "original" {
 		t.Errorf("file was overwritten: got %q, want %q", string(content), "original")
 	}
+	if res.Bytes != 0 {
+		t.Errorf("res.Bytes = %d, want 0 for skipped files", res.Bytes)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "existing.txt") {
+		t.Errorf("res.Warnings = %v, want the skipped file reported", res.Warnings)
+	}
+}
+
+func TestCommandProcessor_ResubmitReplace(t *testing.T) {
+	targetDir := t.TempDir()
+	existingFile := filepath.Join(targetDir, "existing.txt")
+	if err := os.WriteFile(existingFile, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Pattern:   ".*",
+		Command:   "sh",
+		Args:      []string{"-c", "echo new > existing.txt"},
+		TargetDir: targetDir,
+		Resubmit:  "replace",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ResubmitPolicy() != domain.ResubmitReplace {
+		t.Errorf("ResubmitPolicy() = %q, want replace", p.ResubmitPolicy())
+	}
+
+	res, err := p.Process(context.Background(), &domain.Job{ID: 1, URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	content, _ := os.ReadFile(existingFile)
+	if string(content) != "new\n" {
+		t.Errorf("file content = %q, want it replaced", string(content))
+	}
+	if len(res.Files) != 1 || len(res.Warnings) != 0 {
+		t.Errorf("res = %+v, want the replaced file and no warnings", res)
+	}
+
+	if _, err := NewCommandProcessor(config.ProcessorConfig{Name: "bad", Pattern: ".*", Resubmit: "skip"}); err == nil {
+		t.Error("NewCommandProcessor() with unknown resubmit policy succeeded")
+	}
+}
+
+func TestCommandProcessor_Bytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		isolate   bool
+		existing  string // content of a.txt before the run
+		script    string
+		want      int64
+		wantFiles []string
+	}{
+		{name: "isolated", isolate: true, script: "printf hello > a.txt; printf abc > b.txt", want: 8, wantFiles: []string{"a.txt", "b.txt"}},
+		{name: "direct new files", isolate: false, script: "printf hello > a.txt; printf abc > b.txt", want: 8, wantFiles: []string{"a.txt", "b.txt"}},
+		{name: "direct appended file", isolate: false, existing: "he", script: "printf llo >> a.txt", want: 3, wantFiles: []string{"a.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			if tt.existing != "" {
+				if err := os.WriteFile(filepath.Join(targetDir, "a.txt"), []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			p, err := NewCommandProcessor(config.ProcessorConfig{
+				Name:      "test",
+				Pattern:   ".*",
+				Command:   "sh",
+				Args:      []string{"-c", tt.script},
+				TargetDir: targetDir,
+				Isolate:   boolPtr(tt.isolate),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			job := &domain.Job{ID: 1, URL: "https://example.com"}
+			res, err := p.Process(context.Background(), job)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if res.Bytes != tt.want {
+				t.Errorf("res.Bytes = %d, want %d", res.Bytes, tt.want)
+			}
+			var files []string
+			for _, f := range res.Files {
+				if filepath.Dir(f.Path) != targetDir {
+					t.Errorf("file %s is outside the target dir", f.Path)
+				}
+				files = append(files, filepath.Base(f.Path))
+			}
+			if strings.Join(files, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("res.Files = %v, want %v", files, tt.wantFiles)
+			}
+		})
+	}
 }
 
 func TestCommandProcessor_URLPlaceholder(t *testing.T) {
@@ -191,7 +540,7 @@ func TestCommandProcessor_URLPlaceholder(t *testing.T) {
 	}
 
 	job := &domain.Job{ID: 1, URL: "https://example.com/video"}
-	if err := p.Process(context.Background(), job); err != nil {
+	if _, err := p.Process(context.Background(), job); err != nil {
 		t.Errorf("Process() error = %v", err)
 	}
 
@@ -236,3 +585,163 @@ func TestCommandProcessor_DefaultTargetDir(t *testing.T) {
 		t.Errorf("TargetDir() = %q, want %q", p.TargetDir(), expected)
 	}
 }
+
+func TestRenderCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{command: "yt-dlp", args: []string{"-f", "best"}, want: "yt-dlp -f best"},
+		{command: "yt-dlp", args: []string{"-o", "%(title)s.%(ext)s", "https://x.com/w?v=1&t=2"}, want: "yt-dlp -o '%(title)s.%(ext)s' 'https://x.com/w?v=1&t=2'"},
+		{command: "sh", args: []string{"-c", "echo 'hi'"}, want: `sh -c 'echo '\''hi'\'''`},
+		{command: "cmd", args: []string{""}, want: "cmd ''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := renderCommand(tt.command, tt.args); got != tt.want {
+				t.Errorf("renderCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_RecordsAttempt(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Pattern:   ".*",
+		Command:   "sh",
+		Args:      []string{"-c", "echo fetching {url}; exit 3"},
+		TargetDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempt := &domain.Attempt{}
+	ctx := domain.WithAttempt(context.Background(), attempt)
+	if _, err := p.Process(ctx, &domain.Job{ID: 1, URL: "https://example.com"}); err == nil {
+		t.Fatal("Process() error = nil, want exit error")
+	}
+
+	if want := "sh -c 'echo fetching https://example.com; exit 3'"; attempt.Command != want {
+		t.Errorf("Command = %q, want %q", attempt.Command, want)
+	}
+	if attempt.Output != "fetching https://example.com\n" {
+		t.Errorf("Output = %q", attempt.Output)
+	}
+}
+
+func TestCommandProcessor_Heartbeat(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Pattern:   ".*",
+		Command:   "sh",
+		Args:      []string{"-c", "sleep 0.2; echo progress"},
+		TargetDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := domain.NewHeartbeat()
+	if _, err := p.Process(domain.WithHeartbeat(context.Background(), h), &domain.Job{ID: 1, URL: "https://example.com"}); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if got := h.Since(); got >= 200*time.Millisecond {
+		t.Errorf("heartbeat last beat %s ago, want when the command wrote output", got)
+	}
+}
+
+func TestCommandProcessor_CancelTerminates(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Pattern:   ".*",
+		Command:   "sh",
+		Args:      []string{"-c", "trap 'echo terminated; exit 1' TERM; while :; do sleep 0.05; done"},
+		TargetDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	attempt := &domain.Attempt{}
+	start := time.Now()
+	if _, err := p.Process(domain.WithAttempt(ctx, attempt), &domain.Job{ID: 1, URL: "https://example.com"}); err == nil {
+		t.Fatal("Process() error = nil, want the command stopped")
+	}
+	// The shell's own child gets SIGTERM too, which it may report
+	if !strings.HasSuffix(attempt.Output, "terminated\n") {
+		t.Errorf("Output = %q, want the command to have handled SIGTERM", attempt.Output)
+	}
+	if elapsed := time.Since(start); elapsed >= stopGrace {
+		t.Errorf("Process() took %s, want the command gone before being killed", elapsed)
+	}
+}
+
+func TestCommandProcessor_MasksSecrets(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Pattern:   ".*",
+		Command:   "sh",
+		Args:      []string{"-c", "echo using s3cr3t-token for {url}; exit 3"},
+		TargetDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetMasker(logging.NewMasker("s3cr3t-token"))
+
+	attempt := &domain.Attempt{}
+	ctx := domain.WithAttempt(context.Background(), attempt)
+	_, err = p.Process(ctx, &domain.Job{ID: 1, URL: "https://example.com"})
+	if err == nil {
+		t.Fatal("Process() error = nil, want exit error")
+	}
+
+	if want := "sh -c 'echo using [secret] for https://example.com; exit 3'"; attempt.Command != want {
+		t.Errorf("Command = %q, want %q", attempt.Command, want)
+	}
+	if attempt.Output != "using [secret] for https://example.com\n" {
+		t.Errorf("Output = %q", attempt.Output)
+	}
+	if strings.Contains(err.Error(), "s3cr3t-token") {
+		t.Errorf("error = %q, leaks the secret", err)
+	}
+
+	var out strings.Builder
+	if err := p.Test(context.Background(), "https://example.com", &out); err == nil {
+		t.Fatal("Test() error = nil, want exit error")
+	}
+	if strings.Contains(out.String(), "s3cr3t-token") || !strings.Contains(out.String(), "using [secret] for") {
+		t.Errorf("Test() output = %q, want the secret masked", out.String())
+	}
+}
+
+func TestCommandProcessor_MatchScore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		want    int
+	}{
+		{`youtube\.com`, "https://www.youtube.com/watch?v=1", 12},
+		{`^https?://`, "https://www.youtube.com/watch?v=1", 8},
+		{`youtube\.com|youtu\.be`, "https://youtu.be/1", 9},
+		{`.*`, "https://example.com", 1},
+		{`\.(mp4|webm)$`, "https://cdn.example.com/a.mp4", 5},
+		{`(ab){2}`, "https://abab.example", 5},
+		{`youtube\.com`, "https://vimeo.com/1", 0},
+	}
+	for _, tt := range tests {
+		p, err := NewCommandProcessor(config.ProcessorConfig{Name: "p", Pattern: tt.pattern, Command: "true"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.MatchScore(tt.url); got != tt.want {
+			t.Errorf("MatchScore(%q) with %q = %d, want %d", tt.url, tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"context"
+	"maps"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// newCmd returns the command running p.command with args, with the
+// processor's DNS settings exported by dnsEnv.
+func (p *CommandProcessor) newCmd(ctx context.Context, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, p.command, args...)
+	if env := p.dnsEnv(); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
+}
+
+// dnsEnv returns the variables exporting the processor's DNS settings to
+// its commands and their wrapper scripts: CATCHER_DNS_SERVERS, the name
+// servers, and CATCHER_HOSTS, the pinned hosts as host=ip pairs, both
+// comma-separated. It returns nil without DNS settings, so commands
+// inherit catcher's environment unchanged.
+func (p *CommandProcessor) dnsEnv() []string {
+	var env []string
+	if len(p.dns.Servers) > 0 {
+		env = append(env, "CATCHER_DNS_SERVERS="+strings.Join(p.dns.Servers, ","))
+	}
+	if len(p.dns.Hosts) > 0 {
+		pairs := make([]string, 0, len(p.dns.Hosts))
+		for _, host := range slices.Sorted(maps.Keys(p.dns.Hosts)) {
+			pairs = append(pairs, host+"="+p.dns.Hosts[host])
+		}
+		env = append(env, "CATCHER_HOSTS="+strings.Join(pairs, ","))
+	}
+	return env
+}
+
+// curlResolveFlags returns the curl flags pinning the processor's hosts,
+// on the HTTP and HTTPS ports.
+func (p *CommandProcessor) curlResolveFlags() []string {
+	var flags []string
+	for _, host := range slices.Sorted(maps.Keys(p.dns.Hosts)) {
+		addr := p.dns.Hosts[host]
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+			addr = "[" + addr + "]"
+		}
+		for _, port := range []string{"80", "443"} {
+			flags = append(flags, "--resolve", host+":"+port+":"+addr)
+		}
+	}
+	return flags
+}
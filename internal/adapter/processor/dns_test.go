@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestCommandProcessor_DNSEnv(t *testing.T) {
+	targetDir := t.TempDir()
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Command:   "sh",
+		Args:      []string{"-c", `printf '%s|%s|%s' "$CATCHER_DNS_SERVERS" "$CATCHER_HOSTS" "$1" > dns.txt`, "sh", "{dns_servers}"},
+		TargetDir: targetDir,
+		Isolate:   boolPtr(false),
+		DNS: config.DNSConfig{
+			Servers: []string{"1.1.1.1", "9.9.9.9:53"},
+			Hosts:   map[string]string{"cdn.example": "192.0.2.10", "a.example": "2001:db8::1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &domain.Job{ID: 1, URL: "https://example.com/v"}
+	if _, err := p.Process(context.Background(), job); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(targetDir, "dns.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1.1.1.1,9.9.9.9:53|a.example=2001:db8::1,cdn.example=192.0.2.10|1.1.1.1,9.9.9.9:53"
+	if string(content) != want {
+		t.Errorf("dns.txt = %q, want %q", content, want)
+	}
+}
+
+func TestCommandProcessor_NoDNSEnv(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{Name: "test", Command: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd := p.newCmd(context.Background(), nil); cmd.Env != nil {
+		t.Errorf("Env = %q, want inherited environment", cmd.Env)
+	}
+}
+
+func TestCommandProcessor_CurlResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		hosts   map[string]string
+		want    []string
+	}{
+		{name: "no hosts", command: "curl", want: []string{"-O", "https://example.com/v"}},
+		{
+			name:    "curl",
+			command: "/usr/bin/curl",
+			hosts:   map[string]string{"cdn.example": "192.0.2.10", "a.example": "2001:db8::1"},
+			want: []string{
+				"--resolve", "a.example:80:[2001:db8::1]", "--resolve", "a.example:443:[2001:db8::1]",
+				"--resolve", "cdn.example:80:192.0.2.10", "--resolve", "cdn.example:443:192.0.2.10",
+				"-O", "https://example.com/v",
+			},
+		},
+		{name: "other tool", command: "wget", hosts: map[string]string{"cdn.example": "192.0.2.10"}, want: []string{"-O", "https://example.com/v"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCommandProcessor(config.ProcessorConfig{Name: "test", Command: tt.command, Args: []string{"-O", "{url}"}, DNS: config.DNSConfig{Hosts: tt.hosts}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			vars := p.placeholders("https://example.com/v")
+			if err := p.addJobVars(vars, ""); err != nil {
+				t.Fatal(err)
+			}
+			if got := p.renderJobArgs(p.args, vars); !slices.Equal(got, tt.want) {
+				t.Errorf("renderJobArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// fakeCommand names fake processors' runs where a command would be.
+const fakeCommand = "fake"
+
+// fakeRun is what a fake processor does instead of running a command.
+type fakeRun struct {
+	delay       time.Duration
+	failureRate float64
+	size        int64
+	roll        func() float64 // uniform in [0, 1)
+}
+
+func newFakeRun(fc config.FakeConfig) (*fakeRun, error) {
+	if fc.Delay < 0 {
+		return nil, fmt.Errorf("fake delay %s is negative", fc.Delay)
+	}
+	if fc.FailureRate < 0 || fc.FailureRate > 1 {
+		return nil, fmt.Errorf("fake failure_rate %g out of range 0-1", fc.FailureRate)
+	}
+	var size int64
+	if fc.Size != "" {
+		var err error
+		if size, err = config.ParseSize(fc.Size); err != nil {
+			return nil, err
+		}
+	}
+	return &fakeRun{delay: fc.Delay, failureRate: fc.FailureRate, size: size, roll: rand.Float64}, nil
+}
+
+func (f *fakeRun) String() string {
+	return fmt.Sprintf("%s (delay %s, failure rate %g, size %d bytes)", fakeCommand, f.delay, f.failureRate, f.size)
+}
+
+// processFake waits the fake delay, then fails at the failure rate or
+// writes a file of the fake size to targetDir. Failures report HTTP 429
+// if the processor has a rate limit delay, so cooldowns can be tested.
+func (p *CommandProcessor) processFake(ctx context.Context, job *domain.Job, targetDir string) (*domain.ProcessResult, error) {
+	domain.AttemptFrom(ctx).Command = p.fake.String()
+	if err := p.fake.wait(ctx); err != nil {
+		return nil, err
+	}
+	if p.fake.roll() < p.fake.failureRate {
+		output := "simulated failure"
+		if p.rateLimitDelay > 0 {
+			output = "simulated HTTP Error 429: Too Many Requests"
+		}
+		return p.failed(errors.New("simulated failure"), []byte(output))
+	}
+
+	res := &domain.ProcessResult{Title: fmt.Sprintf("Fake job %d", job.ID)}
+	if p.fake.size == 0 {
+		return res, nil
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("create target dir: %w", err)
+	}
+	path := filepath.Join(targetDir, fmt.Sprintf("fake-%d.bin", job.ID))
+	if err := p.fake.writeFile(ctx, path); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	res.Bytes = p.fake.size
+	res.Files = []domain.ResultFile{{Path: path, Bytes: p.fake.size}}
+	return res, nil
+}
+
+// wait sleeps for the fake delay, beating the job's heartbeat every second
+// so the stall watchdog sees the run as alive.
+func (f *fakeRun) wait(ctx context.Context) error {
+	heartbeat := domain.HeartbeatFrom(ctx)
+	timer := time.NewTimer(f.delay)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return nil
+		case <-ticker.C:
+			heartbeat.Beat()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// fakeChunk is how much of a fake file is written at a time.
+const fakeChunk = 1 << 20
+
+// writeFile writes the fake size of zero bytes to path.
+func (f *fakeRun) writeFile(ctx context.Context, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	heartbeat := domain.HeartbeatFrom(ctx)
+	chunk := make([]byte, min(f.size, fakeChunk))
+	for left := f.size; left > 0; left -= int64(len(chunk)) {
+		if err := ctx.Err(); err != nil {
+			file.Close()
+			return err
+		}
+		chunk = chunk[:min(left, int64(len(chunk)))]
+		if _, err := file.Write(chunk); err != nil {
+			file.Close()
+			return err
+		}
+		heartbeat.Beat()
+	}
+	return file.Close()
+}
@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestNewCommandProcessor_Fake(t *testing.T) {
+	tests := []struct {
+		name    string
+		fake    config.FakeConfig
+		wantErr bool
+	}{
+		{name: "defaults", fake: config.FakeConfig{}},
+		{name: "full", fake: config.FakeConfig{Delay: time.Second, FailureRate: 0.5, Size: "1.5MiB"}},
+		{name: "negative delay", fake: config.FakeConfig{Delay: -time.Second}, wantErr: true},
+		{name: "failure rate over 1", fake: config.FakeConfig{FailureRate: 2}, wantErr: true},
+		{name: "bad size", fake: config.FakeConfig{Size: "huge"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCommandProcessor(config.ProcessorConfig{Name: "fake", Pattern: ".", Fake: &tt.fake})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCommandProcessor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (p.fake == nil || p.command != fakeCommand) {
+				t.Errorf("fake = %v, command = %q", p.fake, p.command)
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_ProcessFake(t *testing.T) {
+	tests := []struct {
+		name           string
+		fake           config.FakeConfig
+		rateLimitDelay time.Duration
+		roll           float64
+		wantBytes      int64
+		wantErr        string
+		wantRetryAfter time.Duration
+	}{
+		{name: "writes file", fake: config.FakeConfig{Size: "3MB"}, roll: 0.5, wantBytes: 3_000_000},
+		{name: "no file", fake: config.FakeConfig{FailureRate: 0.4}, roll: 0.5},
+		{name: "fails", fake: config.FakeConfig{FailureRate: 0.6, Size: "1KB"}, roll: 0.5, wantErr: "fake failed: simulated failure"},
+		{name: "rate limited", fake: config.FakeConfig{FailureRate: 1}, rateLimitDelay: time.Minute, roll: 0.5, wantErr: "429", wantRetryAfter: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			p, err := NewCommandProcessor(config.ProcessorConfig{Name: "fake", Pattern: ".", TargetDir: targetDir, RateLimitDelay: tt.rateLimitDelay, Fake: &tt.fake})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.fake.roll = func() float64 { return tt.roll }
+
+			attempt := &domain.Attempt{}
+			res, err := p.Process(domain.WithAttempt(context.Background(), attempt), &domain.Job{ID: 7, URL: "https://fake.test/a"})
+			if !strings.HasPrefix(attempt.Command, "fake (") {
+				t.Errorf("attempt command = %q", attempt.Command)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Process() error = %v, want %q", err, tt.wantErr)
+				}
+				if got := retryAfterOf(res); got != tt.wantRetryAfter {
+					t.Errorf("RetryAfter = %s, want %s", got, tt.wantRetryAfter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if res.Bytes != tt.wantBytes {
+				t.Errorf("Bytes = %d, want %d", res.Bytes, tt.wantBytes)
+			}
+			if tt.wantBytes == 0 {
+				if len(res.Files) != 0 {
+					t.Errorf("Files = %+v, want none", res.Files)
+				}
+				return
+			}
+			path := filepath.Join(targetDir, "fake-7.bin")
+			if len(res.Files) != 1 || res.Files[0].Path != path {
+				t.Fatalf("Files = %+v", res.Files)
+			}
+			if info, err := os.Stat(path); err != nil || info.Size() != tt.wantBytes {
+				t.Errorf("stat %s = %v, %v", path, info, err)
+			}
+		})
+	}
+}
+
+func retryAfterOf(res *domain.ProcessResult) time.Duration {
+	if res == nil {
+		return 0
+	}
+	return res.RetryAfter
+}
+
+func TestCommandProcessor_ProcessFake_Cancel(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{Name: "fake", Pattern: ".", TargetDir: t.TempDir(), Fake: &config.FakeConfig{Delay: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Process(ctx, &domain.Job{ID: 1, URL: "https://fake.test/a"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Process() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCommandProcessor_TestFake(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{Name: "fake", Pattern: ".", Fake: &config.FakeConfig{Size: "10"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := p.Test(context.Background(), "https://fake.test/a", &out); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	if got := out.String(); !strings.Contains(got, "$ fake (") || !strings.Contains(got, "fake-0.bin (10 bytes)") {
+		t.Errorf("output = %q", got)
+	}
+}
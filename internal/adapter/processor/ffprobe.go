@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// ffprobeTimeout bounds one file's check; ffprobe only reads headers and
+// the index, so a slow one is stuck.
+const ffprobeTimeout = 2 * time.Minute
+
+// mediaExts are the audio and video extensions FFProbe checks.
+var mediaExts = map[string]bool{
+	".mp4": true, ".m4v": true, ".mkv": true, ".webm": true, ".mov": true, ".avi": true,
+	".flv": true, ".ts": true, ".mpg": true, ".mpeg": true, ".3gp": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".opus": true, ".ogg": true, ".oga": true,
+	".flac": true, ".wav": true, ".wma": true, ".wmv": true,
+}
+
+// FFProbe is a domain.MediaVerifier that runs ffprobe on audio and video
+// files, by extension, and rejects those it can't read, with no streams,
+// or with no duration, as truncated downloads tend to be. Those errors wrap
+// domain.ErrBadMedia.
+type FFProbe struct {
+	command string
+	prefix  []string // arguments before ffprobe's own, for tests
+}
+
+// NewFFProbe returns a verifier running command, "ffprobe" if empty.
+func NewFFProbe(command string) *FFProbe {
+	if command == "" {
+		command = "ffprobe"
+	}
+	return &FFProbe{command: command}
+}
+
+// Command returns the ffprobe command, for checking it is installed.
+func (f *FFProbe) Command() string {
+	return f.command
+}
+
+// ffprobeOutput is the part of ffprobe's JSON output that is checked.
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// Verify implements domain.MediaVerifier.
+func (f *FFProbe) Verify(ctx context.Context, path string) error {
+	if !mediaExts[strings.ToLower(filepath.Ext(path))] {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, ffprobeTimeout)
+	defer cancel()
+
+	args := append(append([]string{}, f.prefix...),
+		"-v", "error", "-show_entries", "format=duration:stream=codec_type", "-of", "json", path)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, f.command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			return fmt.Errorf("%s: %w", f.command, err)
+		}
+		return fmt.Errorf("%w: %s is unreadable: %s", domain.ErrBadMedia, filepath.Base(path), firstLine(strings.TrimSpace(stderr.String())))
+	}
+
+	var out ffprobeOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return fmt.Errorf("parse %s output: %w", f.command, err)
+	}
+	media := false
+	for _, s := range out.Streams {
+		if s.CodecType == "video" || s.CodecType == "audio" {
+			media = true
+		}
+	}
+	if !media {
+		return fmt.Errorf("%w: %s has no audio or video streams", domain.ErrBadMedia, filepath.Base(path))
+	}
+	if d, err := strconv.ParseFloat(out.Format.Duration, 64); err != nil || d <= 0 {
+		return fmt.Errorf("%w: %s has no duration", domain.ErrBadMedia, filepath.Base(path))
+	}
+	return nil
+}
+
+// firstLine returns s up to its first line break.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestFFProbe_Verify(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		script  string
+		wantErr string // substring; empty for no error
+		wantBad bool
+	}{
+		{"playable", "clip.mp4", `echo '{"streams": [{"codec_type": "video"}, {"codec_type": "audio"}], "format": {"duration": "12.5"}}'`, "", false},
+		{"audio only", "song.OPUS", `echo '{"streams": [{"codec_type": "audio"}], "format": {"duration": "180"}}'`, "", false},
+		{"not media", "cover.jpg", `exit 1`, "", false},
+		{"unreadable", "clip.mp4", `echo 'clip.mp4: moov atom not found' >&2; exit 1`, "clip.mp4 is unreadable: clip.mp4: moov atom not found", true},
+		{"no streams", "clip.mkv", `echo '{"streams": [{"codec_type": "subtitle"}], "format": {"duration": "12"}}'`, "no audio or video streams", true},
+		{"no duration", "clip.webm", `echo '{"streams": [{"codec_type": "video"}], "format": {"duration": "N/A"}}'`, "no duration", true},
+		{"zero duration", "clip.mp4", `echo '{"streams": [{"codec_type": "video"}], "format": {"duration": "0.000000"}}'`, "no duration", true},
+		{"garbled output", "clip.mp4", `echo 'not json'`, "parse sh output", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &FFProbe{command: "sh", prefix: []string{"-c", tt.script, "ffprobe"}}
+			err := f.Verify(context.Background(), "/videos/"+tt.path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if errors.Is(err, domain.ErrBadMedia) != tt.wantBad {
+				t.Errorf("errors.Is(ErrBadMedia) = %v, want %v", !tt.wantBad, tt.wantBad)
+			}
+		})
+	}
+}
+
+func TestFFProbe_Missing(t *testing.T) {
+	err := NewFFProbe("catcher-no-such-ffprobe").Verify(context.Background(), "clip.mp4")
+	if err == nil || errors.Is(err, domain.ErrBadMedia) {
+		t.Errorf("Verify() error = %v, want a failure that doesn't blame the file", err)
+	}
+	if got := NewFFProbe("").Command(); got != "ffprobe" {
+		t.Errorf("Command() = %q, want ffprobe by default", got)
+	}
+}
@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/netaddr"
+)
+
+// ytdlpFlags are the yt-dlp options given the values of job placeholders
+// that the args don't place themselves, so they can be set without
+// spelling out the flags in every processor.
+var ytdlpFlags = []struct{ placeholder, flag string }{
+	{"user_agent", "--user-agent"},
+	{"source_address", "--source-address"},
+}
+
+// addJobVars adds the {user_agent}, {source_address}, and {dns_servers}
+// placeholders to vars: ua, or the processor's default if ua is empty, the
+// address to download from, and the comma-separated name servers to ask.
+// It fails if the source interface has no usable address.
+func (p *CommandProcessor) addJobVars(vars map[string]string, ua string) error {
+	if ua == "" {
+		ua = p.userAgent
+	}
+	addr, err := netaddr.Source(p.sourceAddress, p.forceIP)
+	if err != nil {
+		return err
+	}
+	vars["user_agent"] = ua
+	vars["source_address"] = addr
+	vars["dns_servers"] = strings.Join(p.dns.Servers, ",")
+	return nil
+}
+
+// renderJobArgs is renderArgs for vars from addJobVars, adding the
+// ytdlpFlags for yt-dlp, the flag forcing the IP version for the tools
+// config.ForceIPFlags knows, and for curl, the flags pinning the processor's hosts.
+func (p *CommandProcessor) renderJobArgs(args []string, vars map[string]string) []string {
+	rendered := renderArgs(args, vars)
+	tool := config.ToolName(p.command)
+	var flags []string
+	if f := config.ForceIPFlags[tool][p.forceIP]; f != "" && !slices.Contains(args, f) {
+		flags = append(flags, f)
+	}
+	switch tool {
+	case "yt-dlp", "youtube-dl":
+		for _, f := range ytdlpFlags {
+			placed := slices.ContainsFunc(args, func(arg string) bool { return strings.Contains(arg, "{"+f.placeholder+"}") })
+			if v := vars[f.placeholder]; v != "" && !placed {
+				flags = append(flags, f.flag, v)
+			}
+		}
+	case "curl":
+		flags = append(flags, p.curlResolveFlags()...)
+	}
+	return append(flags, rendered...)
+}
+
+// renderDownloadArgs is renderJobArgs for the download command, with the
+// tuning flags in front. Probes don't download, so they go without.
+func (p *CommandProcessor) renderDownloadArgs(vars map[string]string) []string {
+	return append(p.tuning.flags(config.ToolName(p.command), p.args), p.renderJobArgs(p.args, vars)...)
+}
@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestCommandProcessor_RenderJobArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		args      []string
+		defaultUA string
+		jobUA     string
+		source    string
+		forceIP   string
+		want      []string
+	}{
+		{name: "none", command: "/usr/local/bin/yt-dlp", args: []string{"{url}"}, want: []string{"https://example.com/v"}},
+		{name: "yt-dlp default", command: "/usr/local/bin/yt-dlp", args: []string{"{url}"}, defaultUA: "Default/1.0", want: []string{"--user-agent", "Default/1.0", "https://example.com/v"}},
+		{name: "yt-dlp job override", command: "yt-dlp", args: []string{"{url}"}, defaultUA: "Default/1.0", jobUA: "Job/2.0", want: []string{"--user-agent", "Job/2.0", "https://example.com/v"}},
+		{name: "placeholder", command: "yt-dlp", args: []string{"--add-header", "User-Agent:{user_agent}", "{url}"}, defaultUA: "Default/1.0", want: []string{"--add-header", "User-Agent:Default/1.0", "https://example.com/v"}},
+		{name: "other command", command: "curl", args: []string{"-O", "{url}"}, jobUA: "Job/2.0", want: []string{"-O", "https://example.com/v"}},
+		{name: "other command with placeholder", command: "curl", args: []string{"-A", "{user_agent}", "-O", "{url}"}, jobUA: "Job/2.0", want: []string{"-A", "Job/2.0", "-O", "https://example.com/v"}},
+		{name: "yt-dlp source address", command: "yt-dlp", args: []string{"{url}"}, source: "192.0.2.7", want: []string{"--source-address", "192.0.2.7", "https://example.com/v"}},
+		{name: "yt-dlp both", command: "yt-dlp", args: []string{"{url}"}, jobUA: "Job/2.0", source: "2001:db8::7", want: []string{"--user-agent", "Job/2.0", "--source-address", "2001:db8::7", "https://example.com/v"}},
+		{name: "yt-dlp IPv4", command: "yt-dlp", args: []string{"{url}"}, forceIP: "4", want: []string{"--force-ipv4", "https://example.com/v"}},
+		{name: "yt-dlp IPv6 already in args", command: "yt-dlp", args: []string{"--force-ipv6", "{url}"}, forceIP: "6", want: []string{"--force-ipv6", "https://example.com/v"}},
+		{name: "curl IPv6", command: "/usr/bin/curl", args: []string{"-O", "{url}"}, forceIP: "6", want: []string{"-6", "-O", "https://example.com/v"}},
+		{name: "source address placeholder", command: "curl", args: []string{"--interface", "{source_address}", "-O", "{url}"}, source: "192.0.2.7", want: []string{"--interface", "192.0.2.7", "-O", "https://example.com/v"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCommandProcessor(config.ProcessorConfig{Name: "test", Command: tt.command, Args: tt.args, UserAgent: tt.defaultUA, SourceAddress: tt.source, ForceIP: tt.forceIP})
+			if err != nil {
+				t.Fatal(err)
+			}
+			vars := p.placeholders("https://example.com/v")
+			if err := p.addJobVars(vars, tt.jobUA); err != nil {
+				t.Fatal(err)
+			}
+			if got := p.renderJobArgs(p.args, vars); !slices.Equal(got, tt.want) {
+				t.Errorf("renderJobArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_JobUserAgent(t *testing.T) {
+	targetDir := t.TempDir()
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "test",
+		Command:   "sh",
+		Args:      []string{"-c", `printf %s "$1" > ua.txt`, "sh", "{user_agent}"},
+		TargetDir: targetDir,
+		Isolate:   boolPtr(false),
+		UserAgent: "Default/1.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct{ jobUA, want string }{{"", "Default/1.0"}, {"Mozilla/5.0 (X11; Linux x86_64)", "Mozilla/5.0 (X11; Linux x86_64)"}} {
+		job := &domain.Job{ID: 1, URL: "https://example.com/v", UserAgent: tt.jobUA}
+		if _, err := p.Process(context.Background(), job); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		content, err := os.ReadFile(filepath.Join(targetDir, "ua.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != tt.want {
+			t.Errorf("user agent with job's %q = %q, want %q", tt.jobUA, content, tt.want)
+		}
+	}
+}
+
+func TestCommandProcessor_UnknownSourceInterface(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:          "test",
+		Command:       "true",
+		TargetDir:     t.TempDir(),
+		SourceAddress: "no-such-interface0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Process(context.Background(), &domain.Job{ID: 1, URL: "https://example.com/v"}); err == nil || !strings.Contains(err.Error(), "no-such-interface0") {
+		t.Errorf("Process() error = %v, want the unknown interface reported", err)
+	}
+}
@@ -0,0 +1,168 @@
+package processor
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// defaultLibraryRescan is how long a library index is used before the
+// directories are walked again.
+const defaultLibraryRescan = 10 * time.Minute
+
+// libraryID finds the IDs in a file name, bracketed as yt-dlp's default
+// output template writes them: "Title [dQw4w9WgXcQ].mp4".
+var libraryID = regexp.MustCompile(`\[([A-Za-z0-9_-]{1,64})\]`)
+
+// librarySkip are suffixes of files that aren't finished media: partial
+// downloads and sidecars.
+var librarySkip = []string{".part", ".ytdl", ".temp", ".tmp", ".sha256", ".provenance"}
+
+// library indexes the files under some directories by the IDs in their
+// names. The index is built on first use and rebuilt once older than
+// rescan, so files added or removed by hand are noticed. Files catcher
+// delivered itself are looked up in delivered, if set, by URL.
+type library struct {
+	dirs      []string
+	rescan    time.Duration
+	clock     domain.Clock
+	delivered domain.LibraryIndex
+
+	mu    sync.Mutex
+	built time.Time
+	byID  map[string][]string
+}
+
+func newLibrary(dirs []string, rescan time.Duration) *library {
+	expanded := make([]string, len(dirs))
+	for i, dir := range dirs {
+		expanded[i] = config.ExpandPath(dir)
+	}
+	if rescan <= 0 {
+		rescan = defaultLibraryRescan
+	}
+	return &library{dirs: expanded, rescan: rescan, clock: domain.SystemClock}
+}
+
+// lookup returns the paths of the files whose names carry id.
+func (l *library) lookup(id string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.byID == nil || l.clock.Now().Sub(l.built) >= l.rescan {
+		l.build()
+	}
+	return l.byID[id]
+}
+
+// lookupDelivered returns the files the library index holds for url that
+// are still on disk.
+func (l *library) lookupDelivered(ctx context.Context, url string) ([]domain.ResultFile, error) {
+	if l.delivered == nil {
+		return nil, nil
+	}
+	missing := false
+	indexed, err := l.delivered.SearchLibrary(ctx, domain.LibraryQuery{URL: url, Missing: &missing})
+	if err != nil {
+		return nil, err
+	}
+	var files []domain.ResultFile
+	seen := make(map[string]bool)
+	for _, f := range indexed {
+		if seen[f.Path] {
+			continue // delivered again by a later job
+		}
+		seen[f.Path] = true
+		info, err := os.Stat(f.Path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue // removed since the last rescan
+		}
+		files = append(files, domain.ResultFile{Path: f.Path, Bytes: info.Size()})
+	}
+	return files, nil
+}
+
+// build walks the directories into a new index. Unreadable directories are
+// logged and skipped.
+func (l *library) build() {
+	start := l.clock.Now()
+	byID := make(map[string][]string)
+	files := 0
+	for _, dir := range l.dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				log.Printf("library: %v", err)
+				if d != nil && d.IsDir() && path != dir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || hasAnySuffix(d.Name(), librarySkip) {
+				return nil
+			}
+			files++
+			for _, m := range libraryID.FindAllStringSubmatch(d.Name(), -1) {
+				byID[m[1]] = append(byID[m[1]], path)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("library: walk %s: %v", dir, err)
+		}
+	}
+	l.byID, l.built = byID, l.clock.Now()
+	log.Printf("library: indexed %d files under %s in %s", files, strings.Join(l.dirs, ", "), l.clock.Now().Sub(start).Round(time.Millisecond))
+}
+
+func hasAnySuffix(name string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLibraryIndex makes InLibrary also find the files catcher delivered
+// for a URL, as recorded in idx, wherever they are. It does nothing for
+// processors without a library.
+func (p *CommandProcessor) SetLibraryIndex(idx domain.LibraryIndex) {
+	if p.library != nil {
+		p.library.delivered = idx
+	}
+}
+
+// InLibrary implements domain.LibraryChecker. It looks up the files the
+// library index holds for url, then the ID captured by the matching
+// pattern's "id" group in the configured library directories.
+func (p *CommandProcessor) InLibrary(ctx context.Context, url string) ([]domain.ResultFile, error) {
+	if p.library == nil {
+		return nil, nil
+	}
+	if files, err := p.library.lookupDelivered(ctx, url); err != nil {
+		log.Printf("library: index lookup: %v", err)
+	} else if len(files) > 0 {
+		return files, nil
+	}
+	id := p.placeholders(url)["id"]
+	if id == "" {
+		return nil, nil
+	}
+	var files []domain.ResultFile
+	for _, path := range p.library.lookup(id) {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // removed since the last scan
+		}
+		files = append(files, domain.ResultFile{Path: path, Bytes: info.Size()})
+	}
+	return files, nil
+}
@@ -0,0 +1,158 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestCommandProcessor_InLibrary(t *testing.T) {
+	lib := t.TempDir()
+	for _, name := range []string{
+		"Music/Song [abc123].m4a",
+		"Clips/Clip [xyz789].mp4",
+		"Clips/Clip [xyz789].mp4.sha256",
+		"Partial [part42].mp4.part",
+	} {
+		path := filepath.Join(lib, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte("media"), 0o644)
+	}
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:    "video",
+		Pattern: `youtube\.com/watch\?v=(?P<id>[\w-]+)`,
+		Command: "true",
+		Library: []string{lib},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://youtube.com/watch?v=abc123", "Music/Song [abc123].m4a"},
+		{"https://youtube.com/watch?v=xyz789", "Clips/Clip [xyz789].mp4"},
+		{"https://youtube.com/watch?v=part42", ""},
+		{"https://youtube.com/watch?v=missing", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			files, err := p.InLibrary(context.Background(), tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if len(files) != 0 {
+					t.Errorf("InLibrary() = %+v, want none", files)
+				}
+				return
+			}
+			if len(files) != 1 || files[0].Path != filepath.Join(lib, tt.want) || files[0].Bytes != 5 {
+				t.Errorf("InLibrary() = %+v, want %s", files, tt.want)
+			}
+		})
+	}
+}
+
+// indexStub is a library index holding files, or failing with err.
+type indexStub struct {
+	files []domain.LibraryFile
+	err   error
+}
+
+func (s *indexStub) LibraryFiles(ctx context.Context) ([]domain.LibraryFile, error) {
+	return s.files, s.err
+}
+
+func (s *indexStub) SearchLibrary(ctx context.Context, q domain.LibraryQuery) ([]domain.LibraryFile, error) {
+	var found []domain.LibraryFile
+	for i := range s.files {
+		if q.Match(&s.files[i]) {
+			found = append(found, s.files[i])
+		}
+	}
+	return found, s.err
+}
+
+func (s *indexStub) UpdateLibraryFiles(ctx context.Context, files []domain.LibraryFile) error {
+	return nil
+}
+
+func TestCommandProcessor_InLibraryIndex(t *testing.T) {
+	lib, downloads := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(lib, "Clip [abc123].mp4"), []byte("media"), 0o644)
+	delivered := filepath.Join(downloads, "clip.mp4")
+	os.WriteFile(delivered, []byte("delivered"), 0o644)
+	const url = "https://youtube.com/watch?v=abc123"
+
+	tests := []struct {
+		name  string
+		index *indexStub
+		want  string
+	}{
+		{"delivered", &indexStub{files: []domain.LibraryFile{{ID: 1, URL: url, Path: delivered}, {ID: 2, URL: url, Path: delivered}}}, delivered},
+		{"delivered elsewhere", &indexStub{files: []domain.LibraryFile{{ID: 1, URL: "https://youtube.com/watch?v=other", Path: delivered}}}, filepath.Join(lib, "Clip [abc123].mp4")},
+		{"delivered, since deleted", &indexStub{files: []domain.LibraryFile{{ID: 1, URL: url, Path: filepath.Join(downloads, "gone.mp4")}}}, filepath.Join(lib, "Clip [abc123].mp4")},
+		{"delivered, found missing", &indexStub{files: []domain.LibraryFile{{ID: 1, URL: url, Path: delivered, Missing: true}}}, filepath.Join(lib, "Clip [abc123].mp4")},
+		{"index fails", &indexStub{err: errors.New("boom")}, filepath.Join(lib, "Clip [abc123].mp4")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCommandProcessor(config.ProcessorConfig{
+				Name:    "video",
+				Pattern: `youtube\.com/watch\?v=(?P<id>[\w-]+)`,
+				Command: "true",
+				Library: []string{lib},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.SetLibraryIndex(tt.index)
+			files, err := p.InLibrary(context.Background(), url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 || files[0].Path != tt.want {
+				t.Errorf("InLibrary() = %+v, want %s", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestLibrary_Rescan(t *testing.T) {
+	dir := t.TempDir()
+	clock := domain.NewManualClock(time.Now())
+	l := newLibrary([]string{dir}, time.Minute)
+	l.clock = clock
+
+	if got := l.lookup("abc123"); len(got) != 0 {
+		t.Fatalf("lookup() = %v before the file exists", got)
+	}
+	os.WriteFile(filepath.Join(dir, "Clip [abc123].mp4"), []byte("x"), 0o644)
+	if got := l.lookup("abc123"); len(got) != 0 {
+		t.Errorf("lookup() = %v, want the index reused within the rescan interval", got)
+	}
+	clock.Advance(time.Minute)
+	if got := l.lookup("abc123"); len(got) != 1 {
+		t.Errorf("lookup() = %v after rescanning, want the new file", got)
+	}
+}
+
+func TestNewCommandProcessor_LibraryNeedsID(t *testing.T) {
+	_, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:    "video",
+		Pattern: `youtube\.com/watch`,
+		Library: []string{t.TempDir()},
+	})
+	if err == nil {
+		t.Error("NewCommandProcessor() with a library and no id group succeeded")
+	}
+}
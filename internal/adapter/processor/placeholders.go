@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// maxPlaceholderLen caps a captured value, well under file name limits.
+const maxPlaceholderLen = 128
+
+// placeholders returns the values for {url} and for each named capture
+// group of the pattern that matched rawURL, sanitized.
+func (p *CommandProcessor) placeholders(rawURL string) map[string]string {
+	vars := map[string]string{"url": rawURL}
+	pat := p.match(rawURL)
+	if pat == nil {
+		return vars
+	}
+	m := pat.re.FindStringSubmatch(rawURL)
+	for i, name := range pat.re.SubexpNames() {
+		if name != "" && name != "url" {
+			vars[name] = sanitizePlaceholder(m[i])
+		}
+	}
+	return vars
+}
+
+// targetDirFor expands the target directory with vars. {url} is
+// sanitized like captured values, so it is one path segment there and
+// can't climb out of the directory.
+func (p *CommandProcessor) targetDirFor(vars map[string]string) string {
+	dirVars := make(map[string]string, len(vars))
+	for name, v := range vars {
+		dirVars[name] = v
+	}
+	dirVars["url"] = sanitizePlaceholder(vars["url"])
+	return expand(p.targetDir, dirVars)
+}
+
+// expand replaces {name} placeholders in s with their values. Unknown
+// placeholders are left as they are.
+func expand(s string, vars map[string]string) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	pairs := make([]string, 0, 2*len(vars))
+	for name, v := range vars {
+		pairs = append(pairs, "{"+name+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// sanitizePlaceholder makes a value captured from a URL safe as a file name
+// or argument. Percent-escapes are decoded, anything but letters, digits,
+// spaces, and "-_.@+" becomes "_", and leading dots and dashes are dropped
+// so the value can't climb directories or pass for a flag.
+func sanitizePlaceholder(s string) string {
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		s = unescaped
+	}
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" -_.@+", r) {
+			return r
+		}
+		return '_'
+	}, s)
+	s = strings.TrimLeft(strings.TrimSpace(s), ".-")
+	if len(s) > maxPlaceholderLen {
+		// Drop a rune cut in half
+		s = strings.ToValidUTF8(s[:maxPlaceholderLen], "")
+	}
+	return s
+}
@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestSanitizePlaceholder(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SomeChannel", "SomeChannel"},
+		{"Caf%C3%A9%20Talk", "Café Talk"},
+		{"../../etc", "_.._etc"},
+		{"..", ""},
+		{"-rf", "rf"},
+		{"a/b\\c;d", "a_b_c_d"},
+		{"bad%zzescape", "bad_zzescape"},
+		{strings.Repeat("é", 100), strings.Repeat("é", 64)},
+	}
+	for _, tt := range tests {
+		if got := sanitizePlaceholder(tt.in); got != tt.want {
+			t.Errorf("sanitizePlaceholder(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"url": "https://x", "channel": "news"}
+	tests := []struct {
+		in, want string
+	}{
+		{"{url}", "https://x"},
+		{"/videos/{channel}/{channel}", "/videos/news/news"},
+		{"{unknown}", "{unknown}"},
+		{"%(title)s.%(ext)s", "%(title)s.%(ext)s"},
+	}
+	for _, tt := range tests {
+		if got := expand(tt.in, vars); got != tt.want {
+			t.Errorf("expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCommandProcessor_Placeholders(t *testing.T) {
+	base := t.TempDir()
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "channels",
+		Pattern:   `^https://videos\.example/@(?P<channel>[^/]+)/(?P<id>\d+)`,
+		Command:   "sh",
+		Args:      []string{"-c", `touch "$0.mp4"`, "{id}"},
+		TargetDir: filepath.Join(base, "{channel}"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &domain.Job{ID: 1, URL: "https://videos.example/@..%2F..%2Fescape/42"}
+	res, err := p.Process(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	want := filepath.Join(base, "_.._escape", "42.mp4")
+	if len(res.Files) != 1 || res.Files[0].Path != want {
+		t.Fatalf("Files = %+v, want %s", res.Files, want)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("file not in the channel folder: %v", err)
+	}
+}
+
+func TestCommandProcessor_URLInTargetDir(t *testing.T) {
+	base := t.TempDir()
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "all",
+		Pattern:   `.*`,
+		Command:   "touch",
+		Args:      []string{"out.txt"},
+		TargetDir: filepath.Join(base, "{url}"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &domain.Job{ID: 1, URL: "https://x/../../../../../../tmp/escape"}
+	res, err := p.Process(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	want := filepath.Join(base, "https___x_.._.._.._.._.._.._tmp_escape", "out.txt")
+	if len(res.Files) != 1 || res.Files[0].Path != want {
+		t.Fatalf("Files = %+v, want %s", res.Files, want)
+	}
+}
+
+func TestCommandProcessor_Placeholders_MatchingPattern(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:     "channels",
+		Patterns: []string{`youtube\.com/@(?P<channel>[^/]+)`, `twitch\.tv/(?P<channel>\w+)`},
+		Command:  "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.placeholders("https://twitch.tv/speedrun")["channel"]; got != "speedrun" {
+		t.Errorf("channel = %q, want the group from the pattern that matched", got)
+	}
+}
@@ -0,0 +1,129 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/logging"
+)
+
+// SizeLimit implements domain.SizeProber.
+func (p *CommandProcessor) SizeLimit() (int64, domain.OversizePolicy) {
+	if len(p.probeArgs) == 0 {
+		return 0, p.oversize
+	}
+	return p.maxSize, p.oversize
+}
+
+// ProbeSize implements domain.SizeProber. It runs the command with the
+// probe args and reads the estimate from its output, which is expected to
+// be yt-dlp's -J JSON.
+func (p *CommandProcessor) ProbeSize(ctx context.Context, url string) (int64, error) {
+	info, err := p.probe(ctx, url)
+	if err != nil {
+		return 0, err
+	}
+	return int64(info.size()), nil
+}
+
+// SetProbeCache makes probes reuse results c cached for the same URL
+// within ttl, and cache their own. A zero ttl disables caching.
+func (p *CommandProcessor) SetProbeCache(c domain.ProbeCache, ttl time.Duration) {
+	if ttl <= 0 {
+		c = nil
+	}
+	p.probeCache, p.probeTTL = c, ttl
+}
+
+// probe returns what probing url finds, from the cache if it holds a
+// fresh result. It sends domain.UserAgentFrom(ctx), if set, as the user
+// agent. Cache errors are logged and otherwise ignored.
+func (p *CommandProcessor) probe(ctx context.Context, url string) (*probeInfo, error) {
+	if p.probeCache == nil {
+		return p.runProbe(ctx, url)
+	}
+	key := p.name + " " + domain.UserAgentFrom(ctx) + " " + domain.NormalizeURL(url)
+	data, ok, err := p.probeCache.CachedProbe(ctx, key, p.probeTTL)
+	if err != nil {
+		log.Printf("probe cache: %v", err)
+	}
+	if ok {
+		var info probeInfo
+		if err := json.Unmarshal(data, &info); err == nil {
+			logging.Debugf("probe: cached result for %s", p.masker.Mask(url))
+			return &info, nil
+		}
+	}
+
+	info, err := p.runProbe(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(info); err == nil {
+		if err := p.probeCache.CacheProbe(ctx, key, data); err != nil {
+			log.Printf("probe cache: %v", err)
+		}
+	}
+	return info, nil
+}
+
+// runProbe runs the command with the probe args and parses its output.
+func (p *CommandProcessor) runProbe(ctx context.Context, url string) (*probeInfo, error) {
+	vars := p.placeholders(url)
+	if err := p.addJobVars(vars, domain.UserAgentFrom(ctx)); err != nil {
+		return nil, err
+	}
+	args := p.renderJobArgs(p.probeArgs, vars)
+	logging.Debugf("probe: exec %s", p.masker.Mask(renderCommand(p.command, args)))
+
+	var stdout, stderr bytes.Buffer
+	cmd := p.newCmd(ctx, args)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", p.command, err, p.masker.Mask(strings.TrimSpace(stderr.String())))
+	}
+
+	var info probeInfo
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+		return nil, fmt.Errorf("parse probe output: %w", err)
+	}
+	return &info, nil
+}
+
+// probeInfo is the part of yt-dlp's -J output that tells the title and the
+// download size. yt-dlp reports null or leaves out sizes it can't tell.
+type probeInfo struct {
+	Title            string      `json:"title,omitempty"`
+	Filesize         float64     `json:"filesize,omitempty"`
+	FilesizeApprox   float64     `json:"filesize_approx,omitempty"`
+	RequestedFormats []probeInfo `json:"requested_formats,omitempty"`
+	Entries          []probeInfo `json:"entries,omitempty"`
+}
+
+// size returns the estimated bytes: the sum over a playlist's entries or
+// the formats merged into one video, else the exact size if known, else
+// the approximate one.
+func (i probeInfo) size() float64 {
+	parts := i.Entries
+	if len(parts) == 0 {
+		parts = i.RequestedFormats
+	}
+	if len(parts) > 0 {
+		var total float64
+		for _, part := range parts {
+			total += part.size()
+		}
+		return total
+	}
+	if i.Filesize > 0 {
+		return i.Filesize
+	}
+	return i.FilesizeApprox
+}
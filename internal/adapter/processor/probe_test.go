@@ -0,0 +1,158 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestCommandProcessor_ProbeSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    int64
+		wantErr bool
+	}{
+		{"exact size", `{"filesize": 1000, "filesize_approx": 900}`, 1000, false},
+		{"approximate size", `{"filesize": null, "filesize_approx": 2500.5}`, 2500, false},
+		{"merged formats", `{"requested_formats": [{"filesize": 700}, {"filesize_approx": 300}]}`, 1000, false},
+		{"playlist", `{"entries": [{"filesize": 100}, null, {"requested_formats": [{"filesize": 50}, {"filesize": 25}]}]}`, 175, false},
+		{"unknown", `{"title": "clip"}`, 0, false},
+		{"not JSON", `ERROR: unsupported URL`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCommandProcessor(config.ProcessorConfig{
+				Name:      "probe",
+				Pattern:   ".*",
+				Command:   "sh",
+				ProbeArgs: []string{"-c", "echo '" + tt.output + "'", "{url}"},
+				MaxSize:   "1KB",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.ProbeSize(context.Background(), "https://example.com/v")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProbeSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ProbeSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_ProbeSize_CommandFails(t *testing.T) {
+	p, _ := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "probe",
+		Pattern:   ".*",
+		Command:   "sh",
+		ProbeArgs: []string{"-c", "echo 'ERROR: private video' >&2; exit 1"},
+		MaxSize:   "1KB",
+	})
+	_, err := p.ProbeSize(context.Background(), "https://example.com/v")
+	if err == nil || !strings.Contains(err.Error(), "private video") {
+		t.Errorf("ProbeSize() error = %v, want the command's stderr", err)
+	}
+}
+
+// memProbeCache is a domain.ProbeCache in memory that ignores age.
+type memProbeCache struct {
+	results map[string][]byte
+	maxAge  time.Duration
+}
+
+func (c *memProbeCache) CachedProbe(ctx context.Context, key string, maxAge time.Duration) ([]byte, bool, error) {
+	c.maxAge = maxAge
+	data, ok := c.results[key]
+	return data, ok, nil
+}
+
+func (c *memProbeCache) CacheProbe(ctx context.Context, key string, data []byte) error {
+	c.results[key] = data
+	return nil
+}
+
+func (c *memProbeCache) PruneProbes(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestCommandProcessor_ProbeCache(t *testing.T) {
+	runs := filepath.Join(t.TempDir(), "runs")
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "probe",
+		Pattern:   ".*",
+		Command:   "sh",
+		ProbeArgs: []string{"-c", `echo run >> "$1"; echo '{"title": "clip", "filesize": 1000}'`, "sh", runs},
+		MaxSize:   "1KB",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := &memProbeCache{results: make(map[string][]byte)}
+	p.SetProbeCache(cache, time.Hour)
+	ctx := context.Background()
+
+	// The same URL written differently is probed once
+	for _, url := range []string{"https://Example.com/v?b=2&a=1", "https://example.com/v?a=1&b=2#t=10"} {
+		if got, err := p.ProbeSize(ctx, url); err != nil || got != 1000 {
+			t.Fatalf("ProbeSize(%s) = %d, %v, want 1000", url, got, err)
+		}
+	}
+	if data, _ := os.ReadFile(runs); strings.Count(string(data), "run") != 1 {
+		t.Errorf("probe ran %d times, want once", strings.Count(string(data), "run"))
+	}
+	if cache.maxAge != time.Hour {
+		t.Errorf("cache asked for results up to %s old, want 1h", cache.maxAge)
+	}
+	info, err := p.probe(ctx, "https://example.com/v?a=1&b=2")
+	if err != nil || info.Title != "clip" {
+		t.Errorf("probe() = %+v, %v, want the cached title", info, err)
+	}
+
+	// Another user agent may get another answer
+	if _, err := p.ProbeSize(domain.WithUserAgent(ctx, "Mozilla/5.0"), "https://example.com/v?a=1&b=2"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(runs); strings.Count(string(data), "run") != 2 {
+		t.Errorf("probe ran %d times, want twice", strings.Count(string(data), "run"))
+	}
+}
+
+func TestCommandProcessor_SizeLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.ProcessorConfig
+		wantLimit  int64
+		wantPolicy domain.OversizePolicy
+	}{
+		{"none", config.ProcessorConfig{}, 0, domain.OversizeHold},
+		{"hold by default", config.ProcessorConfig{ProbeArgs: []string{"-J", "{url}"}, MaxSize: "2GB"}, 2_000_000_000, domain.OversizeHold},
+		{"fail", config.ProcessorConfig{ProbeArgs: []string{"-J", "{url}"}, MaxSize: "1MiB", Oversize: "fail"}, 1 << 20, domain.OversizeFail},
+		{"no probe", config.ProcessorConfig{MaxSize: "2GB"}, 0, domain.OversizeHold},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Name, tt.cfg.Pattern, tt.cfg.Command = "dl", ".*", "yt-dlp"
+			p, err := NewCommandProcessor(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			limit, policy := p.SizeLimit()
+			if limit != tt.wantLimit || policy != tt.wantPolicy {
+				t.Errorf("SizeLimit() = %d, %s, want %d, %s", limit, policy, tt.wantLimit, tt.wantPolicy)
+			}
+		})
+	}
+
+	if _, err := NewCommandProcessor(config.ProcessorConfig{Name: "dl", Pattern: ".*", MaxSize: "huge"}); err == nil {
+		t.Error("NewCommandProcessor() with an invalid max_size succeeded")
+	}
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// quotaPoll is how often a run's work dir is measured against the
+// processor's quota.
+const quotaPoll = 5 * time.Second
+
+// watchQuota returns a context for a command running in dir, cancelled
+// once dir holds more than the processor's quota, and a function ending
+// the watch that returns the *domain.QuotaError if that happened. Without
+// a quota, ctx is returned as is.
+func (p *CommandProcessor) watchQuota(ctx context.Context, dir string) (context.Context, func() error) {
+	if p.quota <= 0 {
+		return ctx, func() error { return nil }
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := p.clock.NewTicker(quotaPoll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+			if used := dirUsage(dir); used > p.quota {
+				cancel(&domain.QuotaError{Used: used, Limit: p.quota})
+				return
+			}
+		}
+	}()
+	return ctx, func() error {
+		close(done)
+		<-stopped
+		defer cancel(nil)
+		if qerr := (*domain.QuotaError)(nil); errors.As(context.Cause(ctx), &qerr) {
+			return qerr
+		}
+		return nil
+	}
+}
+
+// dirUsage returns the total size of the regular files under dir. Files
+// vanishing while it walks, as temp files do, are skipped.
+func dirUsage(dir string) int64 {
+	var n int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			n += info.Size()
+		}
+		return nil
+	})
+	return n
+}
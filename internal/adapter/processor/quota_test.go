@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestCommandProcessor_WorkDirQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr bool
+	}{
+		{"under the quota", "head -c 1000 /dev/zero > small.bin", false},
+		{"runaway", "mkdir -p a/b; while :; do head -c 4096 /dev/zero >> a/b/big.bin; sleep 0.01; done", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			p, err := NewCommandProcessor(config.ProcessorConfig{
+				Name:         "test",
+				Command:      "sh",
+				Args:         []string{"-c", tt.script},
+				TargetDir:    targetDir,
+				WorkDirQuota: "64KB",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+			p.SetClock(clock)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			// Measure the work dir every 10ms of real time
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				for {
+					select {
+					case <-done:
+						return
+					case <-time.After(10 * time.Millisecond):
+						clock.Advance(quotaPoll)
+					}
+				}
+			}()
+			_, err = p.Process(domain.WithWorkDir(ctx, t.TempDir()), &domain.Job{ID: 1, URL: "https://example.com"})
+
+			var quota *domain.QuotaError
+			if got := errors.As(err, &quota); got != tt.wantErr {
+				t.Fatalf("Process() error = %v, want a QuotaError: %v", err, tt.wantErr)
+			}
+			if tt.wantErr && (quota.Limit != 64000 || quota.Used <= quota.Limit) {
+				t.Errorf("QuotaError = %+v, want usage over a 64000-byte limit", quota)
+			}
+			if !tt.wantErr {
+				if _, err := os.Stat(filepath.Join(targetDir, "small.bin")); err != nil {
+					t.Errorf("small.bin not moved to the target dir: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestDirUsage(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "a"), make([]byte, 100), 0o644)
+	os.WriteFile(filepath.Join(dir, "sub", "b"), make([]byte, 50), 0o644)
+	if got := dirUsage(dir); got != 150 {
+		t.Errorf("dirUsage() = %d, want 150", got)
+	}
+	if got := dirUsage(filepath.Join(dir, "missing")); got != 0 {
+		t.Errorf("dirUsage() of a missing dir = %d, want 0", got)
+	}
+}
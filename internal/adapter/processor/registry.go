@@ -1,9 +1,23 @@
 package processor
 
-import "github.com/cwygoda/catcher/internal/domain"
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
 
-// Registry holds registered URL processors.
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// Tester is implemented by processors that can run a URL without a job.
+type Tester interface {
+	Test(ctx context.Context, url string, out io.Writer) error
+}
+
+// Registry holds registered URL processors. It is safe for concurrent use,
+// so the processor set can be replaced while the worker is matching jobs.
 type Registry struct {
+	mu         sync.RWMutex
 	processors []domain.URLProcessor
 }
 
@@ -14,20 +28,153 @@ func NewRegistry() *Registry {
 
 // Register adds a processor to the registry.
 func (r *Registry) Register(p domain.URLProcessor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.processors = append(r.processors, p)
 }
 
-// Match returns the first processor that matches the URL, or nil.
+// Replace swaps in a new set of processors at once. Lookups in progress
+// finish against the old set; jobs already being processed keep the
+// processor they matched.
+func (r *Registry) Replace(processors []domain.URLProcessor) {
+	processors = slices.Clone(processors)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.processors = processors
+}
+
+// Match returns the processor that best matches the URL, or nil. The
+// highest match score wins, then the highest priority, then the processor
+// registered first.
 func (r *Registry) Match(url string) domain.URLProcessor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var best domain.URLProcessor
+	var bestScore, bestPriority int
+	for _, p := range r.processors {
+		score := matchScore(p, url)
+		if score <= 0 {
+			continue
+		}
+		priority := 0
+		if pr, ok := p.(domain.Prioritizer); ok {
+			priority = pr.Priority()
+		}
+		if best == nil || score > bestScore || score == bestScore && priority > bestPriority {
+			best, bestScore, bestPriority = p, score, priority
+		}
+	}
+	return best
+}
+
+// ResubmitPolicy returns the resubmit policy of the processor matching url,
+// or ResubmitAllow if none matches or it has no policy.
+func (r *Registry) ResubmitPolicy(url string) domain.ResubmitPolicy {
+	if p, ok := r.Match(url).(domain.ResubmitPolicer); ok {
+		return p.ResubmitPolicy()
+	}
+	return domain.ResubmitAllow
+}
+
+// SavesBookmarks reports whether the processor matching url saves bookmark
+// jobs. With no processor matching, it reports true, leaving the job to
+// fail like any other without one.
+func (r *Registry) SavesBookmarks(url string) bool {
+	p := r.Match(url)
+	if p == nil {
+		return true
+	}
+	s, ok := p.(domain.BookmarkSaver)
+	return ok && s.SavesBookmarks()
+}
+
+// ProcessorName returns the name of the processor matching url, or "" if
+// none does.
+func (r *Registry) ProcessorName(url string) string {
+	if p := r.Match(url); p != nil {
+		return p.Name()
+	}
+	return ""
+}
+
+// Queue returns the queue for url: the name of the processor matching it
+// if that has a queue of its own, or domain.DefaultQueue.
+func (r *Registry) Queue(url string) string {
+	p := r.Match(url)
+	if q, ok := p.(domain.Queuer); ok && q.Concurrency() > 0 {
+		return p.Name()
+	}
+	return domain.DefaultQueue
+}
+
+// Queues returns the concurrency of each processor with a queue of its own,
+// by queue name.
+func (r *Registry) Queues() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	queues := make(map[string]int)
 	for _, p := range r.processors {
-		if p.Match(url) {
+		if q, ok := p.(domain.Queuer); ok && q.Concurrency() > 0 {
+			queues[p.Name()] = q.Concurrency()
+		}
+	}
+	return queues
+}
+
+// Cost returns the cost of running url's job: that of the processor
+// matching it, if it declares one, or 1.
+func (r *Registry) Cost(url string) int {
+	if c, ok := r.Match(url).(domain.Coster); ok && c.Cost() > 0 {
+		return c.Cost()
+	}
+	return 1
+}
+
+// matchScore rates p against url, treating a plain Match as score 1.
+func matchScore(p domain.URLProcessor, url string) int {
+	if s, ok := p.(domain.MatchScorer); ok {
+		return s.MatchScore(url)
+	}
+	if p.Match(url) {
+		return 1
+	}
+	return 0
+}
+
+// Lookup returns the processor with the given name, or nil.
+func (r *Registry) Lookup(name string) domain.URLProcessor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.processors {
+		if p.Name() == name {
 			return p
 		}
 	}
 	return nil
 }
 
-// Processors returns all registered processors.
+// TestProcessor runs the named processor, or the one best matching url when
+// name is empty, without creating a job. It returns the processor's name.
+func (r *Registry) TestProcessor(ctx context.Context, name, url string, out io.Writer) (string, error) {
+	var p domain.URLProcessor
+	if name != "" {
+		if p = r.Lookup(name); p == nil {
+			return "", domain.ErrUnknownProcessor
+		}
+	} else if p = r.Match(url); p == nil {
+		return "", domain.ErrNoProcessor
+	}
+
+	t, ok := p.(Tester)
+	if !ok {
+		return p.Name(), domain.ErrNotTestable
+	}
+	return p.Name(), t.Test(ctx, url, out)
+}
+
+// Processors returns a snapshot of the registered processors.
 func (r *Registry) Processors() []domain.URLProcessor {
-	return r.processors
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.processors)
 }
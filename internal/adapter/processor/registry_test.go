@@ -1,9 +1,14 @@
 package processor
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/cwygoda/catcher/internal/config"
 	"github.com/cwygoda/catcher/internal/domain"
 )
 
@@ -12,10 +17,12 @@ type mockProcessor struct {
 	matcher func(string) bool
 }
 
-func (m *mockProcessor) Name() string                                       { return m.name }
-func (m *mockProcessor) TargetDir() string                                  { return "/tmp/test" }
-func (m *mockProcessor) Match(url string) bool                              { return m.matcher(url) }
-func (m *mockProcessor) Process(ctx context.Context, job *domain.Job) error { return nil }
+func (m *mockProcessor) Name() string          { return m.name }
+func (m *mockProcessor) TargetDir() string     { return "/tmp/test" }
+func (m *mockProcessor) Match(url string) bool { return m.matcher(url) }
+func (m *mockProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
+	return &domain.ProcessResult{}, nil
+}
 
 func TestRegistry_Register(t *testing.T) {
 	r := NewRegistry()
@@ -68,6 +75,92 @@ func TestRegistry_Match(t *testing.T) {
 	}
 }
 
+// scoredProcessor rates every URL the same, with a priority.
+type scoredProcessor struct {
+	mockProcessor
+	score    int
+	priority int
+}
+
+func (p *scoredProcessor) MatchScore(url string) int { return p.score }
+func (p *scoredProcessor) Priority() int             { return p.priority }
+
+func TestRegistry_Match_BestScore(t *testing.T) {
+	generic, err := NewCommandProcessor(config.ProcessorConfig{Name: "generic", Pattern: `^https?://`, Command: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	youtube, err := NewCommandProcessor(config.ProcessorConfig{Name: "youtube", Pattern: `youtube\.com|youtu\.be`, Command: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRegistry()
+	r.Register(generic) // registered first, but less specific
+	r.Register(youtube)
+
+	if p := r.Match("https://www.youtube.com/watch?v=1"); p == nil || p.Name() != "youtube" {
+		t.Errorf("Match() = %v, want youtube", p)
+	}
+	if p := r.Match("https://vimeo.com/1"); p == nil || p.Name() != "generic" {
+		t.Errorf("Match() = %v, want generic", p)
+	}
+}
+
+func TestRegistry_Match_Priority(t *testing.T) {
+	tests := []struct {
+		name  string
+		procs []*scoredProcessor
+		want  string
+	}{
+		{
+			name: "higher score wins over priority",
+			procs: []*scoredProcessor{
+				{mockProcessor: mockProcessor{name: "a"}, score: 5, priority: 10},
+				{mockProcessor: mockProcessor{name: "b"}, score: 9},
+			},
+			want: "b",
+		},
+		{
+			name: "priority breaks ties",
+			procs: []*scoredProcessor{
+				{mockProcessor: mockProcessor{name: "a"}, score: 5},
+				{mockProcessor: mockProcessor{name: "b"}, score: 5, priority: 1},
+			},
+			want: "b",
+		},
+		{
+			name: "registration order breaks full ties",
+			procs: []*scoredProcessor{
+				{mockProcessor: mockProcessor{name: "a"}, score: 5},
+				{mockProcessor: mockProcessor{name: "b"}, score: 5},
+			},
+			want: "a",
+		},
+		{
+			name: "zero score never matches",
+			procs: []*scoredProcessor{
+				{mockProcessor: mockProcessor{name: "a"}, score: 0, priority: 10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			for _, p := range tt.procs {
+				r.Register(p)
+			}
+			got := ""
+			if p := r.Match("https://example.com"); p != nil {
+				got = p.Name()
+			}
+			if got != tt.want {
+				t.Errorf("Match() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRegistry_Match_NoMatch(t *testing.T) {
 	r := NewRegistry()
 
@@ -83,6 +176,185 @@ func TestRegistry_Match_NoMatch(t *testing.T) {
 	}
 }
 
+func TestRegistry_Replace(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockProcessor{name: "old", matcher: func(string) bool { return true }})
+
+	procs := []domain.URLProcessor{
+		&mockProcessor{name: "new", matcher: func(string) bool { return true }},
+	}
+	r.Replace(procs)
+	procs[0] = nil // the registry keeps its own copy
+
+	if p := r.Match("https://example.com"); p == nil || p.Name() != "new" {
+		t.Errorf("Match() = %v, want new", p)
+	}
+	if p := r.Lookup("old"); p != nil {
+		t.Errorf("Lookup(old) = %v, want nil after Replace", p)
+	}
+	if got := r.Processors(); len(got) != 1 {
+		t.Errorf("Processors() len = %d, want 1", len(got))
+	}
+}
+
+// Run with -race to check that lookups and replacement don't race.
+func TestRegistry_ConcurrentReplace(t *testing.T) {
+	r := NewRegistry()
+	a := &mockProcessor{name: "a", matcher: func(string) bool { return true }}
+	b := &mockProcessor{name: "b", matcher: func(string) bool { return true }}
+	r.Register(a)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// Every lookup sees one complete set or the other
+				if p := r.Match("https://example.com"); p == nil {
+					t.Error("Match() = nil during Replace")
+					return
+				}
+				r.Lookup("a")
+				for _, p := range r.Processors() {
+					p.Name()
+				}
+			}
+		}()
+	}
+
+	for i := range 1000 {
+		if i%2 == 0 {
+			r.Replace([]domain.URLProcessor{b})
+		} else {
+			r.Replace([]domain.URLProcessor{a, b})
+		}
+	}
+	r.Register(a)
+	close(stop)
+	wg.Wait()
+}
+
+func TestRegistry_ResubmitPolicy(t *testing.T) {
+	r := NewRegistry()
+	rejecting, err := NewCommandProcessor(config.ProcessorConfig{Name: "yt", Pattern: `youtube\.com`, Command: "true", Resubmit: "reject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Register(rejecting)
+	r.Register(&mockProcessor{name: "native", matcher: func(s string) bool { return strings.Contains(s, "native") }})
+
+	tests := []struct {
+		url  string
+		want domain.ResubmitPolicy
+	}{
+		{"https://youtube.com/watch?v=1", domain.ResubmitReject},
+		{"https://native.example", domain.ResubmitAllow},
+		{"https://unmatched.example", domain.ResubmitAllow},
+	}
+	for _, tt := range tests {
+		if got := r.ResubmitPolicy(tt.url); got != tt.want {
+			t.Errorf("ResubmitPolicy(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRegistry_SavesBookmarks(t *testing.T) {
+	r := NewRegistry()
+	yt, err := NewCommandProcessor(config.ProcessorConfig{Name: "yt", Pattern: `youtube\.com`, Command: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Register(yt)
+	r.Register(&mockProcessor{name: "native", matcher: func(s string) bool { return strings.Contains(s, "native") }})
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://youtube.com/watch?v=1", true},
+		{"https://native.example", false},
+		{"https://unmatched.example", true},
+	}
+	for _, tt := range tests {
+		if got := r.SavesBookmarks(tt.url); got != tt.want {
+			t.Errorf("SavesBookmarks(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRegistry_Queues(t *testing.T) {
+	r := NewRegistry()
+	for _, pc := range []config.ProcessorConfig{
+		{Name: "podcast", Pattern: `\.mp3$`, Command: "true", Concurrency: 2},
+		{Name: "yt", Pattern: `youtube\.com`, Command: "true"},
+	} {
+		p, err := NewCommandProcessor(pc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Register(p)
+	}
+	r.Register(&mockProcessor{name: "native", matcher: func(s string) bool { return strings.Contains(s, "native") }})
+
+	if got := r.Queues(); len(got) != 1 || got["podcast"] != 2 {
+		t.Errorf("Queues() = %v, want podcast with 2", got)
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/ep1.mp3", "podcast"},
+		{"https://youtube.com/watch?v=1", domain.DefaultQueue},
+		{"https://native.example", domain.DefaultQueue},
+		{"https://unmatched.example", domain.DefaultQueue},
+	}
+	for _, tt := range tests {
+		if got := r.Queue(tt.url); got != tt.want {
+			t.Errorf("Queue(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+	for url, want := range map[string]string{"https://youtube.com/watch?v=1": "yt", "https://native.example": "native", "https://unmatched.example": ""} {
+		if got := r.ProcessorName(url); got != want {
+			t.Errorf("ProcessorName(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestRegistry_Cost(t *testing.T) {
+	r := NewRegistry()
+	for _, pc := range []config.ProcessorConfig{
+		{Name: "transcode", Pattern: `\.mkv$`, Command: "true", Cost: 4},
+		{Name: "meta", Pattern: `\.json$`, Command: "true"},
+	} {
+		p, err := NewCommandProcessor(pc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Register(p)
+	}
+
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://example.com/film.mkv", 4},
+		{"https://example.com/info.json", 1},
+		{"https://unmatched.example", 1},
+	}
+	for _, tt := range tests {
+		if got := r.Cost(tt.url); got != tt.want {
+			t.Errorf("Cost(%q) = %d, want %d", tt.url, got, tt.want)
+		}
+	}
+}
+
 func TestRegistry_Empty(t *testing.T) {
 	r := NewRegistry()
 
@@ -96,3 +368,55 @@ func TestRegistry_Empty(t *testing.T) {
 		t.Errorf("Match() = %v, want nil", p)
 	}
 }
+
+func TestRegistry_TestProcessor(t *testing.T) {
+	r := NewRegistry()
+	echo, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:      "echo",
+		Pattern:   `^https://`,
+		Command:   "sh",
+		Args:      []string{"-c", "echo got {url}; printf abc > out.txt"},
+		TargetDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Register(echo)
+	r.Register(&mockProcessor{name: "native", matcher: func(string) bool { return false }})
+
+	tests := []struct {
+		name     string
+		proc     string
+		url      string
+		wantName string
+		wantErr  error
+		wantOut  string
+	}{
+		{name: "by match", url: "https://example.com", wantName: "echo", wantOut: "$ sh -c 'echo got https://example.com; printf abc > out.txt'\ngot https://example.com\nproduced 1 file(s)\n  out.txt (3 bytes)\n"},
+		{name: "by name", proc: "echo", url: "http://plain", wantName: "echo", wantOut: "got http://plain\n"},
+		{name: "unknown name", proc: "nope", url: "https://example.com", wantErr: domain.ErrUnknownProcessor},
+		{name: "no match", url: "ftp://example.com", wantErr: domain.ErrNoProcessor},
+		{name: "not testable", proc: "native", url: "https://example.com", wantName: "native", wantErr: domain.ErrNotTestable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			name, err := r.TestProcessor(context.Background(), tt.proc, tt.url, &out)
+			if err != tt.wantErr {
+				t.Fatalf("TestProcessor() error = %v, want %v", err, tt.wantErr)
+			}
+			if name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+
+	// Test runs never touch the target directory
+	if entries, _ := os.ReadDir(echo.TargetDir()); len(entries) != 0 {
+		t.Errorf("target dir has %d entries, want 0", len(entries))
+	}
+}
@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter caps retry hints, so a site can't park a job indefinitely.
+const maxRetryAfter = 24 * time.Hour
+
+var (
+	// retryAfterHeader finds a Retry-After header in output from tools that
+	// print response headers, such as curl -i or wget -S.
+	retryAfterHeader = regexp.MustCompile(`(?im)^\s*Retry-After:\s*(.+?)\s*$`)
+	// rateLimited recognizes output reporting HTTP 429, as yt-dlp, curl,
+	// and gallery-dl word it.
+	rateLimited = regexp.MustCompile(`(?i)too many requests|\berror:? 429\b|\bstatus(?: code)?:? 429\b|HTTP/[\d.]+ 429\b`)
+)
+
+// retryAfter returns how long to wait before retrying a failed run with
+// output: the Retry-After header in it if there is one, or the configured
+// rate limit delay if it reports HTTP 429. Zero means no hint.
+func (p *CommandProcessor) retryAfter(output []byte, now time.Time) time.Duration {
+	if m := retryAfterHeader.FindSubmatch(output); m != nil {
+		if d, ok := parseRetryAfter(string(m[1]), now); ok {
+			return min(d, maxRetryAfter)
+		}
+	}
+	if p.rateLimitDelay > 0 && rateLimited.Match(output) {
+		return min(p.rateLimitDelay, maxRetryAfter)
+	}
+	return 0
+}
+
+// parseRetryAfter reads a Retry-After value, either delay seconds or an
+// HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(min(secs, int64(maxRetryAfter/time.Second))) * time.Second, true
+	}
+	at, err := http.ParseTime(strings.TrimSpace(v))
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestCommandProcessor_RetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		output string
+		delay  time.Duration
+		want   time.Duration
+	}{
+		{"seconds header", "HTTP/2 429\nretry-after: 120\n", 0, 2 * time.Minute},
+		{"date header", "HTTP/1.1 503 Service Unavailable\r\nRetry-After: Sun, 01 Mar 2026 12:10:00 GMT\r\n", 0, 10 * time.Minute},
+		{"past date", "Retry-After: Sun, 01 Mar 2026 11:00:00 GMT\n", time.Hour, 0},
+		{"capped", "Retry-After: 999999999\n", 0, maxRetryAfter},
+		{"yt-dlp 429", "ERROR: [youtube] abc: Unable to download webpage: HTTP Error 429: Too Many Requests", 15 * time.Minute, 15 * time.Minute},
+		{"curl 429", "curl: (22) The requested URL returned error: 429", time.Minute, time.Minute},
+		{"429 without delay", "HTTP Error 429: Too Many Requests", 0, 0},
+		{"unrelated 429", "downloaded 429 fragments, then ERROR: HTTP Error 403: Forbidden", time.Minute, 0},
+		{"bad header", "Retry-After: soon\nHTTP Error 429", time.Minute, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &CommandProcessor{rateLimitDelay: tt.delay}
+			if got := p.retryAfter([]byte(tt.output), now); got != tt.want {
+				t.Errorf("retryAfter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_Process_RetryHint(t *testing.T) {
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:           "dl",
+		Pattern:        ".*",
+		Command:        "sh",
+		Args:           []string{"-c", "echo 'HTTP Error 429: Too Many Requests'; exit 1"},
+		TargetDir:      t.TempDir(),
+		RateLimitDelay: 30 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.Process(context.Background(), &domain.Job{ID: 1, URL: "https://example.com/v"})
+	if err == nil {
+		t.Fatal("Process() succeeded, want an error")
+	}
+	if res == nil || res.RetryAfter != 30*time.Minute {
+		t.Errorf("Process() result = %+v, want RetryAfter 30m", res)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package processor
+
+import "os/exec"
+
+// terminate leaves cmd to be killed outright when its context is
+// cancelled, as there is no SIGTERM to ask it to stop.
+func terminate(cmd *exec.Cmd) {}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package processor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// terminate runs cmd in a process group of its own and makes cancelling
+// its context send SIGTERM to the whole group, so tools such as yt-dlp can
+// clean up and helpers they started, such as ffmpeg or aria2c, stop with
+// them. Whatever is still running stopGrace later gets SIGKILL.
+func terminate(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		group := -cmd.Process.Pid
+		if err := syscall.Kill(group, syscall.SIGTERM); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return os.ErrProcessDone
+			}
+			return err
+		}
+		time.AfterFunc(stopGrace, func() { syscall.Kill(group, syscall.SIGKILL) })
+		return nil
+	}
+	cmd.WaitDelay = stopGrace
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/config"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestCommandProcessor_CancelStopsChildren(t *testing.T) {
+	dir := t.TempDir()
+	ticks := filepath.Join(dir, "ticks")
+	p, err := NewCommandProcessor(config.ProcessorConfig{
+		Name:    "test",
+		Pattern: ".*",
+		Command: "sh",
+		// A helper the tool starts, like ffmpeg under yt-dlp
+		Args:      []string{"-c", `(while :; do echo x >> "$0"; sleep 0.02; done) & wait`, ticks},
+		TargetDir: dir,
+		Isolate:   boolPtr(false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := p.Process(ctx, &domain.Job{ID: 1, URL: "https://example.com"}); err == nil {
+		t.Fatal("Process() error = nil, want the command stopped")
+	}
+
+	size := func() int64 {
+		info, err := os.Stat(ticks)
+		if err != nil {
+			t.Fatalf("helper never ran: %v", err)
+		}
+		return info.Size()
+	}
+	before := size()
+	time.Sleep(200 * time.Millisecond)
+	if after := size(); after != before {
+		t.Errorf("helper still running after its command was cancelled (%d -> %d bytes)", before, after)
+	}
+}
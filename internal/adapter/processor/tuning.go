@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cwygoda/catcher/internal/config"
+)
+
+// tuning is how a processor's downloads run in parallel.
+type tuning struct {
+	fragments   int    // fragments yt-dlp downloads at once
+	downloader  string // external downloader yt-dlp hands downloads to
+	connections int    // connections aria2c opens per download
+}
+
+// newTuning checks the tuning settings of pc against its command, which
+// must be a tool they apply to, reporting the first problem.
+func newTuning(pc config.ProcessorConfig) (tuning, error) {
+	t := tuning{fragments: pc.Fragments, downloader: pc.Downloader, connections: pc.Connections}
+	if problems := config.TuningProblems(pc); len(problems) > 0 {
+		return t, errors.New(problems[0].Msg)
+	}
+	return t, nil
+}
+
+// flags returns the options tool is given for t, leaving out those args
+// already set.
+func (t tuning) flags(tool string, args []string) []string {
+	has := func(names ...string) bool {
+		return slices.ContainsFunc(args, func(arg string) bool {
+			return slices.ContainsFunc(names, func(name string) bool {
+				return arg == name || strings.HasPrefix(arg, name+"=")
+			})
+		})
+	}
+	n := strconv.Itoa(t.connections)
+	aria2cArgs := "-x " + n + " -s " + n
+
+	var flags []string
+	switch tool {
+	case "yt-dlp":
+		if t.fragments > 0 && !has("-N", "--concurrent-fragments") {
+			flags = append(flags, "--concurrent-fragments", strconv.Itoa(t.fragments))
+		}
+		if t.downloader != "" && !has("--downloader", "--external-downloader") {
+			flags = append(flags, "--downloader", t.downloader)
+			if t.connections > 0 {
+				flags = append(flags, "--downloader-args", t.downloader+":"+aria2cArgs)
+			}
+		}
+	case "youtube-dl":
+		if t.downloader != "" && !has("--external-downloader") {
+			flags = append(flags, "--external-downloader", t.downloader)
+			if t.connections > 0 {
+				flags = append(flags, "--external-downloader-args", aria2cArgs)
+			}
+		}
+	case "aria2c":
+		if t.connections > 0 && !has("-x", "--max-connection-per-server") {
+			flags = append(flags, "-x", n, "-s", n)
+		}
+	}
+	return flags
+}
@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/config"
+)
+
+func TestNewTuning(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.ProcessorConfig
+		wantErr bool
+	}{
+		{"none", config.ProcessorConfig{Command: "curl"}, false},
+		{"yt-dlp fragments", config.ProcessorConfig{Command: "/usr/bin/yt-dlp", Fragments: 8}, false},
+		{"too many fragments", config.ProcessorConfig{Command: "yt-dlp", Fragments: 64}, true},
+		{"negative fragments", config.ProcessorConfig{Command: "yt-dlp", Fragments: -1}, true},
+		{"fragments without yt-dlp", config.ProcessorConfig{Command: "youtube-dl", Fragments: 4}, true},
+		{"youtube-dl aria2c", config.ProcessorConfig{Command: "youtube-dl", Downloader: "aria2c", Connections: 16}, false},
+		{"unknown downloader", config.ProcessorConfig{Command: "yt-dlp", Downloader: "wget"}, true},
+		{"downloader without yt-dlp", config.ProcessorConfig{Command: "curl", Downloader: "aria2c"}, true},
+		{"too many connections", config.ProcessorConfig{Command: "yt-dlp", Downloader: "aria2c", Connections: 17}, true},
+		{"connections without aria2c", config.ProcessorConfig{Command: "yt-dlp", Connections: 4}, true},
+		{"aria2c command", config.ProcessorConfig{Command: "aria2c", Connections: 4}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTuning(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("newTuning() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_RenderDownloadArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ProcessorConfig
+		want []string
+	}{
+		{"yt-dlp fragments", config.ProcessorConfig{Command: "yt-dlp", Fragments: 4},
+			[]string{"--concurrent-fragments", "4", "{url}"}},
+		{"yt-dlp aria2c", config.ProcessorConfig{Command: "yt-dlp", Fragments: 4, Downloader: "aria2c", Connections: 8},
+			[]string{"--concurrent-fragments", "4", "--downloader", "aria2c", "--downloader-args", "aria2c:-x 8 -s 8", "{url}"}},
+		{"yt-dlp aria2c defaults", config.ProcessorConfig{Command: "yt-dlp", Downloader: "aria2c"},
+			[]string{"--downloader", "aria2c", "{url}"}},
+		{"already in args", config.ProcessorConfig{Command: "yt-dlp", Fragments: 4, Args: []string{"-N", "2"}},
+			[]string{"-N", "2", "{url}"}},
+		{"youtube-dl aria2c", config.ProcessorConfig{Command: "youtube-dl", Downloader: "aria2c", Connections: 8},
+			[]string{"--external-downloader", "aria2c", "--external-downloader-args", "-x 8 -s 8", "{url}"}},
+		{"aria2c command", config.ProcessorConfig{Command: "/usr/bin/aria2c", Connections: 8},
+			[]string{"-x", "8", "-s", "8", "{url}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Name = "test"
+			tt.cfg.Args = append(tt.cfg.Args, "{url}")
+			p, err := NewCommandProcessor(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := slices.Clone(tt.want)
+			want[len(want)-1] = "https://example.com/v"
+			vars := p.placeholders("https://example.com/v")
+			if err := p.addJobVars(vars, ""); err != nil {
+				t.Fatal(err)
+			}
+			if got := p.renderDownloadArgs(vars); !slices.Equal(got, want) {
+				t.Errorf("renderDownloadArgs() = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Package redirect follows HTTP redirects of shortened URLs to their
+// destination.
+package redirect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/logging"
+	"github.com/cwygoda/catcher/internal/netaddr"
+)
+
+// ErrForbiddenAddress is returned for hosts that resolve to a loopback,
+// private, or otherwise non-public address.
+var ErrForbiddenAddress = errors.New("address not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range, not covered by
+// netip.Addr.IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Resolver follows redirects with HEAD requests, falling back to GET for
+// servers that don't allow HEAD. It only connects to public addresses,
+// checked after DNS resolution, so a submitted URL can't make it probe the
+// local network.
+type Resolver struct {
+	client    *http.Client
+	dialer    *net.Dialer
+	hosts     map[string]string // pinned host names, lower-cased, to IPs
+	maxHops   int
+	userAgent string // for submissions without their own
+	source    string // local address or interface to connect from
+	forceIP   string // "4" or "6" to connect over that IP version only
+}
+
+// New creates a resolver that follows at most maxHops redirects, with each
+// request limited to timeout.
+func New(maxHops int, timeout time.Duration) *Resolver {
+	return newResolver(maxHops, timeout, publicAddr)
+}
+
+func newResolver(maxHops int, timeout time.Duration, allow func(netip.Addr) bool) *Resolver {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		// Control sees the address actually dialed, so names that resolve
+		// to internal addresses are refused too.
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !allow(addr.Unmap()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+	r := &Resolver{dialer: dialer, maxHops: maxHops}
+	r.client = &http.Client{
+		// No proxy: it would be the only address checked
+		Transport: &http.Transport{
+			DialContext:         r.dial,
+			TLSHandshakeTimeout: timeout,
+		},
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return r
+}
+
+// SetDNS makes the resolver look up host names with servers, as "ip" or
+// "ip:port", instead of the system's name servers, and connect to the
+// hosts pinned in hosts at their address without looking them up. The
+// servers may be private, but the addresses connected to are still
+// checked. Call it before the resolver is used.
+func (r *Resolver) SetDNS(servers []string, hosts map[string]string) {
+	r.hosts = make(map[string]string, len(hosts))
+	for host, ip := range hosts {
+		r.hosts[strings.ToLower(host)] = ip
+	}
+	r.dialer.Resolver = netaddr.NameResolver(servers, r.dialer.Timeout)
+}
+
+// SetUserAgent makes the resolver send ua as the User-Agent when the
+// submission doesn't set its own with domain.WithUserAgent. Call it
+// before the resolver is used.
+func (r *Resolver) SetUserAgent(ua string) {
+	r.userAgent = ua
+}
+
+// SetSourceAddress makes the resolver connect from source, an IP address
+// or the name of a network interface, looked up for every connection.
+// Call it before the resolver is used.
+func (r *Resolver) SetSourceAddress(source string) {
+	r.source = source
+}
+
+// SetForceIP makes the resolver connect over IP version "4" or "6" only,
+// and take a source interface's address of that version. Empty allows
+// both. Call it before the resolver is used.
+func (r *Resolver) SetForceIP(version string) {
+	r.forceIP = version
+}
+
+// dial connects to address, or to the pinned address of its host.
+func (r *Resolver) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		if ip, ok := r.hosts[strings.ToLower(host)]; ok {
+			address = net.JoinHostPort(ip, port)
+		}
+	}
+	if r.forceIP != "" {
+		network += r.forceIP
+	}
+	if r.source == "" {
+		return r.dialer.DialContext(ctx, network, address)
+	}
+	local, err := netaddr.Source(r.source, r.forceIP)
+	if err != nil {
+		return nil, err
+	}
+	// A copy, as connections may be dialed concurrently; the local
+	// address also limits the addresses dialed to its IP version
+	dialer := *r.dialer
+	dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(local)}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// publicAddr reports whether addr is a public unicast address.
+func publicAddr(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// Resolve returns the URL rawURL finally redirects to, or rawURL if it
+// doesn't redirect. Redirects to schemes other than http and https end the
+// chain at the last web URL.
+func (r *Resolver) Resolve(ctx context.Context, rawURL string) (string, error) {
+	resolved, err := r.resolve(ctx, rawURL)
+	if err != nil {
+		log.Printf("resolving redirects of %s: %v", logging.RedactURL(rawURL), err)
+		return "", err
+	}
+	if resolved != rawURL {
+		logging.Debugf("resolved %s to %s", logging.RedactURL(rawURL), logging.RedactURL(resolved))
+	}
+	return resolved, nil
+}
+
+func (r *Resolver) resolve(ctx context.Context, rawURL string) (string, error) {
+	current, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if !webURL(current) {
+		return "", fmt.Errorf("unsupported scheme %q", current.Scheme)
+	}
+	for hops := 0; ; hops++ {
+		next, err := r.next(ctx, current)
+		if err != nil {
+			return "", err
+		}
+		if next == nil || !webURL(next) {
+			return current.String(), nil
+		}
+		if hops == r.maxHops {
+			return "", fmt.Errorf("more than %d redirects", r.maxHops)
+		}
+		current = next
+	}
+}
+
+// next returns where u redirects to, or nil if it doesn't.
+func (r *Resolver) next(ctx context.Context, u *url.URL) (*url.URL, error) {
+	resp, err := r.do(ctx, http.MethodHead, u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		resp.Body.Close()
+		if resp, err = r.do(ctx, http.MethodGet, u); err != nil {
+			return nil, err
+		}
+	}
+	// Only the headers are needed; closing unread drops the connection
+	resp.Body.Close()
+
+	if resp.StatusCode < 300 || resp.StatusCode > 399 || resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	next, err := resp.Location()
+	if errors.Is(err, http.ErrNoLocation) {
+		return nil, nil
+	}
+	return next, err
+}
+
+func (r *Resolver) do(ctx context.Context, method string, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	ua := domain.UserAgentFrom(ctx)
+	if ua == "" {
+		ua = r.userAgent
+	}
+	if ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	return r.client.Do(req)
+}
+
+func webURL(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
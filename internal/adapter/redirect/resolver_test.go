@@ -0,0 +1,210 @@
+package redirect
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func anyAddr(netip.Addr) bool { return true }
+
+func TestResolver_Resolve(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/watch?v=abc", http.StatusFound)
+	})
+	mux.HandleFunc("/watch", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.Redirect(w, r, "/watch?v=get", http.StatusFound)
+	})
+	mux.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/app-link", http.StatusFound)
+	})
+	mux.HandleFunc("/app-link", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "vnd.app://open/abc")
+		w.WriteHeader(http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"no redirect", "/watch?v=abc", "/watch?v=abc", false},
+		{"relative hops", "/short", "/watch?v=abc", false},
+		{"HEAD not allowed", "/get-only", "/watch?v=get", false},
+		{"non-web destination", "/app", "/app-link", false},
+		{"too many hops", "/loop", "", true},
+	}
+	r := newResolver(3, time.Second, anyAddr)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Resolve(context.Background(), srv.URL+tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != srv.URL+tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, srv.URL+tt.want)
+			}
+		})
+	}
+}
+
+func TestResolver_RefusesInternalAddresses(t *testing.T) {
+	var hit bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	_, err := New(5, time.Second).Resolve(context.Background(), srv.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Resolve() error = %v, want ErrForbiddenAddress", err)
+	}
+	if hit {
+		t.Error("Resolve() reached a loopback server")
+	}
+
+	if _, err := New(5, time.Second).Resolve(context.Background(), "ftp://example.com/x"); err == nil {
+		t.Error("Resolve() accepted a non-web URL")
+	}
+}
+
+func TestResolver_PinnedHosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/short" {
+			http.Redirect(w, r, "/watch?v=abc", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	r := newResolver(3, time.Second, anyAddr)
+	r.SetDNS(nil, map[string]string{"Short.Invalid": "127.0.0.1"})
+	got, err := r.Resolve(context.Background(), "http://short.invalid:"+port+"/short")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if want := "http://short.invalid:" + port + "/watch?v=abc"; got != want {
+		t.Errorf("Resolve() = %q, want %q", got, want)
+	}
+
+	// Pinned addresses are still checked
+	r = New(3, time.Second)
+	r.SetDNS(nil, map[string]string{"short.invalid": "127.0.0.1"})
+	if _, err := r.Resolve(context.Background(), "http://short.invalid:"+port+"/short"); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Resolve() error = %v, want ErrForbiddenAddress", err)
+	}
+}
+
+func TestResolver_UserAgent(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.UserAgent())
+	}))
+	defer srv.Close()
+
+	r := newResolver(3, time.Second, anyAddr)
+	r.SetUserAgent("Default/1.0")
+	for _, ctx := range []context.Context{context.Background(), domain.WithUserAgent(context.Background(), "Job/2.0")} {
+		if _, err := r.Resolve(ctx, srv.URL); err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+	}
+	if want := []string{"Default/1.0", "Job/2.0"}; !slices.Equal(got, want) {
+		t.Errorf("user agents = %q, want %q", got, want)
+	}
+}
+
+func TestResolver_SourceAddress(t *testing.T) {
+	// Linux routes all of 127.0.0.0/8 to the loopback interface
+	probe, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("127.0.0.2 not usable: %v", err)
+	}
+	probe.Close()
+
+	var remote string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}))
+	defer srv.Close()
+
+	r := newResolver(3, time.Second, anyAddr)
+	r.SetSourceAddress("127.0.0.2")
+	if _, err := r.Resolve(context.Background(), srv.URL); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if remote != "127.0.0.2" {
+		t.Errorf("connected from %s, want 127.0.0.2", remote)
+	}
+
+	r = newResolver(3, time.Second, anyAddr)
+	r.SetSourceAddress("no-such-interface0")
+	if _, err := r.Resolve(context.Background(), srv.URL); err == nil {
+		t.Error("Resolve() from an unknown interface succeeded")
+	}
+}
+
+func TestResolver_ForceIP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		version string
+		wantErr bool
+	}{{"", false}, {"4", false}, {"6", true}} {
+		r := newResolver(3, time.Second, anyAddr)
+		r.SetForceIP(tt.version)
+		if _, err := r.Resolve(context.Background(), srv.URL); (err != nil) != tt.wantErr {
+			t.Errorf("Resolve() of an IPv4 server over IP version %q error = %v, wantErr %v", tt.version, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1::", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"192.168.0.1", false},
+		{"172.16.5.5", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
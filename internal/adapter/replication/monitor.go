@@ -0,0 +1,57 @@
+// Package replication watches continuous replication of the database by a
+// process running beside catcher, such as Litestream.
+package replication
+
+import (
+	"os"
+	"time"
+)
+
+// Monitor reads replication progress from marker files kept by the
+// replicator or a hook around it: one touched after every sync, and one
+// present while a backup is running.
+type Monitor struct {
+	syncFile   string
+	backupFile string
+	maxLag     time.Duration
+	now        func() time.Time
+}
+
+// New creates a Monitor. Either file may be empty to leave it unwatched.
+// Replication is lagging once the last sync is more than maxLag ago; zero
+// never flags it.
+func New(syncFile, backupFile string, maxLag time.Duration) *Monitor {
+	return &Monitor{syncFile: syncFile, backupFile: backupFile, maxLag: maxLag, now: time.Now}
+}
+
+// Lag returns how long ago the last sync was, or false if the sync file
+// isn't watched or doesn't exist yet.
+func (m *Monitor) Lag() (time.Duration, bool) {
+	if m.syncFile == "" {
+		return 0, false
+	}
+	info, err := os.Stat(m.syncFile)
+	if err != nil {
+		return 0, false
+	}
+	return max(m.now().Sub(info.ModTime()), 0), true
+}
+
+// Lagging reports whether the last sync is more than the allowed lag ago,
+// or there hasn't been one.
+func (m *Monitor) Lagging() bool {
+	if m.syncFile == "" || m.maxLag == 0 {
+		return false
+	}
+	lag, ok := m.Lag()
+	return !ok || lag > m.maxLag
+}
+
+// BackupInProgress reports whether the backup file exists.
+func (m *Monitor) BackupInProgress() bool {
+	if m.backupFile == "" {
+		return false
+	}
+	_, err := os.Stat(m.backupFile)
+	return err == nil
+}
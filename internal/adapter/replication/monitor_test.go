@@ -0,0 +1,59 @@
+package replication
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMonitor_Lag(t *testing.T) {
+	dir := t.TempDir()
+	syncFile := filepath.Join(dir, "synced")
+	m := New(syncFile, "", time.Minute)
+
+	if _, ok := m.Lag(); ok {
+		t.Error("Lag() known before the first sync")
+	}
+	if !m.Lagging() {
+		t.Error("Lagging() = false before the first sync")
+	}
+
+	if err := os.WriteFile(syncFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	synced := time.Now().Add(-30 * time.Second)
+	os.Chtimes(syncFile, synced, synced)
+	m.now = func() time.Time { return synced.Add(30 * time.Second) }
+	if lag, ok := m.Lag(); !ok || lag != 30*time.Second {
+		t.Errorf("Lag() = %v, %v, want 30s", lag, ok)
+	}
+	if m.Lagging() {
+		t.Error("Lagging() = true 30s after a sync, with a 1m limit")
+	}
+
+	m.now = func() time.Time { return synced.Add(2 * time.Minute) }
+	if !m.Lagging() {
+		t.Error("Lagging() = false 2m after a sync, with a 1m limit")
+	}
+	if New(syncFile, "", 0).Lagging() {
+		t.Error("Lagging() = true with no limit")
+	}
+}
+
+func TestMonitor_BackupInProgress(t *testing.T) {
+	backupFile := filepath.Join(t.TempDir(), "backup.lock")
+	m := New("", backupFile, 0)
+	if m.BackupInProgress() {
+		t.Error("BackupInProgress() = true without the file")
+	}
+	if err := os.WriteFile(backupFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !m.BackupInProgress() {
+		t.Error("BackupInProgress() = false with the file")
+	}
+	if New("", "", 0).BackupInProgress() {
+		t.Error("BackupInProgress() = true with no file watched")
+	}
+}
@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// storedAnnouncement is an announcement as kept in meta.
+type storedAnnouncement struct {
+	Message  string    `json:"message"`
+	ReadOnly bool      `json:"read_only,omitempty"`
+	Start    time.Time `json:"start,omitzero"`
+	End      time.Time `json:"end,omitzero"`
+	SetAt    time.Time `json:"set_at"`
+}
+
+// SaveAnnouncement implements domain.AnnouncementRepository.
+func (r *Repository) SaveAnnouncement(ctx context.Context, a *domain.Announcement) error {
+	value, err := json.Marshal(storedAnnouncement(*a))
+	if err != nil {
+		return err
+	}
+	return r.retry(ctx, "save_announcement", func() error {
+		_, err := r.stmtExec(ctx, nil,
+			`INSERT INTO meta (key, value) VALUES ('announcement', ?)
+			 ON CONFLICT (key) DO UPDATE SET value = excluded.value`, string(value),
+		)
+		return err
+	})
+}
+
+// Announcement implements domain.AnnouncementRepository.
+func (r *Repository) Announcement(ctx context.Context) (*domain.Announcement, error) {
+	var a *domain.Announcement
+	err := r.retry(ctx, "announcement", func() error {
+		var value string
+		err := r.stmtQueryRow(ctx, nil, `SELECT value FROM meta WHERE key = 'announcement'`).Scan(&value)
+		if err == sql.ErrNoRows {
+			return domain.ErrNoAnnouncement
+		}
+		if err != nil {
+			return err
+		}
+		var stored storedAnnouncement
+		if err := json.Unmarshal([]byte(value), &stored); err != nil {
+			return err
+		}
+		a = (*domain.Announcement)(&stored)
+		return nil
+	})
+	return a, err
+}
+
+// ClearAnnouncement implements domain.AnnouncementRepository.
+func (r *Repository) ClearAnnouncement(ctx context.Context) error {
+	return r.retry(ctx, "clear_announcement", func() error {
+		_, err := r.stmtExec(ctx, nil, `DELETE FROM meta WHERE key = 'announcement'`)
+		return err
+	})
+}
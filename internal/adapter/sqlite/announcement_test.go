@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_Announcement(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := repo.Announcement(ctx); !errors.Is(err, domain.ErrNoAnnouncement) {
+		t.Fatalf("Announcement() before any error = %v, want ErrNoAnnouncement", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	want := &domain.Announcement{Message: "NAS upgrade tonight", ReadOnly: true, Start: now.Add(time.Hour), End: now.Add(3 * time.Hour), SetAt: now}
+	for _, a := range []*domain.Announcement{{Message: "first", SetAt: now}, want} {
+		if err := repo.SaveAnnouncement(ctx, a); err != nil {
+			t.Fatalf("SaveAnnouncement() error = %v", err)
+		}
+	}
+	got, err := repo.Announcement(ctx)
+	if err != nil {
+		t.Fatalf("Announcement() error = %v", err)
+	}
+	if got.Message != want.Message || !got.ReadOnly || !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || !got.SetAt.Equal(want.SetAt) {
+		t.Errorf("Announcement() = %+v, want %+v", got, want)
+	}
+
+	if err := repo.ClearAnnouncement(ctx); err != nil {
+		t.Fatalf("ClearAnnouncement() error = %v", err)
+	}
+	if _, err := repo.Announcement(ctx); !errors.Is(err, domain.ErrNoAnnouncement) {
+		t.Errorf("Announcement() after clearing error = %v, want ErrNoAnnouncement", err)
+	}
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// AddAttempt implements domain.AttemptRepository.
+func (r *Repository) AddAttempt(ctx context.Context, jobID int64, a domain.Attempt) error {
+	_, err := r.exec(ctx, "add_attempt",
+		`INSERT INTO job_attempts (job_id, attempt, processor, command, output, error, started_at, finished_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		jobID, a.Number, a.Processor, r.encrypt(a.Command), r.encrypt(a.Output), r.encrypt(a.Error), a.StartedAt, a.FinishedAt,
+	)
+	return err
+}
+
+// Attempts implements domain.AttemptRepository.
+func (r *Repository) Attempts(ctx context.Context, jobID int64) ([]domain.Attempt, error) {
+	var attempts []domain.Attempt
+	err := r.retry(ctx, "attempts", func() error {
+		rows, err := r.stmtQuery(ctx, nil,
+			`SELECT attempt, processor, command, output, error, started_at, finished_at
+			 FROM job_attempts WHERE job_id = ? ORDER BY id ASC`, jobID,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		attempts = nil
+		for rows.Next() {
+			var a domain.Attempt
+			if err := rows.Scan(&a.Number, &a.Processor, &a.Command, &a.Output, &a.Error, &a.StartedAt, &a.FinishedAt); err != nil {
+				return err
+			}
+			for _, field := range []*string{&a.Command, &a.Output, &a.Error} {
+				if *field, err = r.decrypt(*field); err != nil {
+					return err
+				}
+			}
+			attempts = append(attempts, a)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_Attempts(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	job, _ := repo.Create(ctx, "https://example.com/video")
+	other, _ := repo.Create(ctx, "https://example.com/other")
+
+	start := time.Now().Truncate(time.Second)
+	for i, errMsg := range []string{"exit status 1", ""} {
+		a := domain.Attempt{
+			Number:     i + 1,
+			Processor:  "youtube",
+			Command:    "yt-dlp https://example.com/video",
+			Output:     "downloading...",
+			Error:      errMsg,
+			StartedAt:  start,
+			FinishedAt: start.Add(time.Minute),
+		}
+		if err := repo.AddAttempt(ctx, job.ID, a); err != nil {
+			t.Fatalf("AddAttempt() error = %v", err)
+		}
+	}
+	repo.AddAttempt(ctx, other.ID, domain.Attempt{Number: 1, Processor: "generic"})
+
+	attempts, err := repo.Attempts(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Attempts() error = %v", err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("Attempts() returned %d, want 2", len(attempts))
+	}
+	first := attempts[0]
+	if first.Number != 1 || first.Error != "exit status 1" || first.Command != "yt-dlp https://example.com/video" {
+		t.Errorf("attempts[0] = %+v", first)
+	}
+	if !first.StartedAt.Equal(start) || !first.FinishedAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("attempts[0] times = %v..%v, want %v..%v", first.StartedAt, first.FinishedAt, start, start.Add(time.Minute))
+	}
+	if attempts[1].Number != 2 || attempts[1].Error != "" {
+		t.Errorf("attempts[1] = %+v", attempts[1])
+	}
+}
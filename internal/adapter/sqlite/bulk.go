@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// RequeueFailed implements domain.BulkRepository.
+func (r *Repository) RequeueFailed(ctx context.Context, f domain.BulkFilter) (int64, error) {
+	return r.bulkTransition(ctx, "requeue_failed", domain.StatusFailed, f,
+		`UPDATE jobs SET status = ?, attempts = 0, error = NULL, not_before = 0, updated_at = ?`,
+		domain.StatusPending, r.clock.Now(),
+	)
+}
+
+// CancelPending implements domain.BulkRepository.
+func (r *Repository) CancelPending(ctx context.Context, f domain.BulkFilter, reason string) (int64, error) {
+	return r.bulkTransition(ctx, "cancel_pending", domain.StatusPending, f,
+		`UPDATE jobs SET status = ?, error = ?, updated_at = ?`,
+		domain.StatusFailed, r.encrypt(reason), r.clock.Now(),
+	)
+}
+
+// bulkTransition runs update, a SET clause and its arguments, on the jobs
+// in status from that f selects, and returns how many changed. URLs may be
+// encrypted, so a host filter is matched after decryption and the matching
+// IDs handed to the one UPDATE as a JSON array.
+func (r *Repository) bulkTransition(ctx context.Context, op string, from domain.JobStatus, f domain.BulkFilter, update string, args ...any) (int64, error) {
+	var n int64
+	err := r.retry(ctx, op, func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			query, qargs := update+` WHERE status = ?`, append(slices.Clone(args), from)
+			if f != (domain.BulkFilter{}) {
+				ids, err := r.matchingIDs(ctx, tx, from, f)
+				if err != nil {
+					return err
+				}
+				query += ` AND id IN (SELECT value FROM json_each(?))`
+				qargs = append(qargs, ids)
+			}
+			result, err := r.stmtExec(ctx, tx, query, qargs...)
+			if err != nil {
+				return err
+			}
+			n, err = result.RowsAffected()
+			return err
+		})
+	})
+	return n, err
+}
+
+// matchingIDs returns the IDs of jobs in status that f selects, as a JSON
+// array.
+func (r *Repository) matchingIDs(ctx context.Context, tx *sql.Tx, status domain.JobStatus, f domain.BulkFilter) (string, error) {
+	rows, err := r.stmtQuery(ctx, tx, `SELECT id, url FROM jobs WHERE status = ?`, status)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		var url string
+		if err := rows.Scan(&id, &url); err != nil {
+			return "", err
+		}
+		if url, err = r.decrypt(url); err != nil {
+			return "", err
+		}
+		if f.Match(url) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(ids)
+	return string(b), err
+}
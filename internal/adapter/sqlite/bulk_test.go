@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_BulkTransitions(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+			if encrypted {
+				if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			create := func(url string, status domain.JobStatus) int64 {
+				t.Helper()
+				job, err := repo.Create(ctx, url)
+				if err != nil {
+					t.Fatal(err)
+				}
+				switch status {
+				case domain.StatusFailed:
+					repo.Claim(ctx, job.ID)
+					repo.Fail(ctx, job.ID, "404")
+				case domain.StatusProcessing:
+					repo.Claim(ctx, job.ID)
+				}
+				return job.ID
+			}
+			failedA := create("https://a.example/1", domain.StatusFailed)
+			failedSubA := create("https://cdn.a.example/2", domain.StatusFailed)
+			failedB := create("https://b.example/3", domain.StatusFailed)
+			pendingA := create("https://a.example/4", domain.StatusPending)
+			heldB, _ := repo.CreateHeld(ctx, "https://b.example/5")
+			processingB := create("https://b.example/6", domain.StatusProcessing)
+
+			n, err := repo.RequeueFailed(ctx, domain.BulkFilter{Host: "a.example"})
+			if err != nil || n != 2 {
+				t.Fatalf("RequeueFailed(a.example) = %d, %v, want 2", n, err)
+			}
+			for _, id := range []int64{failedA, failedSubA} {
+				job, _ := repo.Get(ctx, id)
+				if job.Status != domain.StatusPending || job.Attempts != 0 || job.Error != "" {
+					t.Errorf("job %d = %s, %d attempt(s), error %q, want pending and reset", id, job.Status, job.Attempts, job.Error)
+				}
+			}
+
+			n, err = repo.CancelPending(ctx, domain.BulkFilter{Host: "b.example"}, "cancelled")
+			if err != nil || n != 1 {
+				t.Fatalf("CancelPending(b.example) = %d, %v, want 1", n, err)
+			}
+			if job, _ := repo.Get(ctx, heldB.ID); job.Status != domain.StatusFailed || job.Error != "cancelled" {
+				t.Errorf("held job = %s, %q, want failed as cancelled", job.Status, job.Error)
+			}
+			if job, _ := repo.Get(ctx, processingB); job.Status != domain.StatusProcessing {
+				t.Errorf("processing job = %s, want it left running", job.Status)
+			}
+
+			// No filter selects every job in the state
+			n, err = repo.RequeueFailed(ctx, domain.BulkFilter{})
+			if err != nil || n != 2 {
+				t.Fatalf("RequeueFailed() = %d, %v, want 2", n, err)
+			}
+			if job, _ := repo.Get(ctx, failedB); job.Status != domain.StatusPending {
+				t.Errorf("job %d = %s, want pending", failedB, job.Status)
+			}
+			n, err = repo.CancelPending(ctx, domain.BulkFilter{Host: "none.example"}, "cancelled")
+			if err != nil || n != 0 {
+				t.Errorf("CancelPending(none.example) = %d, %v, want 0", n, err)
+			}
+			if job, _ := repo.Get(ctx, pendingA); job.Status != domain.StatusPending {
+				t.Errorf("job %d = %s, want pending", pendingA, job.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// SetCooldown implements domain.CooldownRepository. Encrypted hosts can't
+// be looked up, so the host's earlier cooldown is found by reading them
+// all; there are only ever a few.
+func (r *Repository) SetCooldown(ctx context.Context, c domain.Cooldown) error {
+	return r.retry(ctx, "set_cooldown", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			if _, err := r.stmtExec(ctx, tx, `DELETE FROM host_cooldowns WHERE until <= ?`, r.clock.Now().UnixMilli()); err != nil {
+				return err
+			}
+			cooldowns, err := r.scanCooldowns(ctx, tx)
+			if err != nil {
+				return err
+			}
+			for _, old := range cooldowns {
+				if old.c.Host != c.Host {
+					continue
+				}
+				if _, err := r.stmtExec(ctx, tx, `DELETE FROM host_cooldowns WHERE id = ?`, old.id); err != nil {
+					return err
+				}
+			}
+			_, err = r.stmtExec(ctx, tx,
+				`INSERT INTO host_cooldowns (host, until, reason) VALUES (?, ?, ?)`,
+				r.encrypt(c.Host), c.Until.UnixMilli(), r.encrypt(c.Reason),
+			)
+			return err
+		})
+	})
+}
+
+// Cooldowns implements domain.CooldownRepository.
+func (r *Repository) Cooldowns(ctx context.Context) ([]domain.Cooldown, error) {
+	var cooldowns []domain.Cooldown
+	err := r.retry(ctx, "cooldowns", func() error {
+		stored, err := r.scanCooldowns(ctx, nil)
+		if err != nil {
+			return err
+		}
+		cooldowns = nil
+		now := r.clock.Now()
+		for _, s := range stored {
+			if s.c.Until.After(now) {
+				cooldowns = append(cooldowns, s.c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cooldowns, nil
+}
+
+type storedCooldown struct {
+	id int64
+	c  domain.Cooldown
+}
+
+// scanCooldowns reads and decrypts every stored cooldown, expired or not.
+func (r *Repository) scanCooldowns(ctx context.Context, tx *sql.Tx) ([]storedCooldown, error) {
+	rows, err := r.stmtQuery(ctx, tx, `SELECT id, host, until, reason FROM host_cooldowns ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cooldowns []storedCooldown
+	for rows.Next() {
+		var s storedCooldown
+		var until int64
+		if err := rows.Scan(&s.id, &s.c.Host, &until, &s.c.Reason); err != nil {
+			return nil, err
+		}
+		s.c.Until = time.UnixMilli(until)
+		for _, field := range []*string{&s.c.Host, &s.c.Reason} {
+			if *field, err = r.decrypt(*field); err != nil {
+				return nil, err
+			}
+		}
+		cooldowns = append(cooldowns, s)
+	}
+	return cooldowns, rows.Err()
+}
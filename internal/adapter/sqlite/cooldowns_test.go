@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_Cooldowns(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+			if encrypted {
+				if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			now := time.Now()
+			for _, c := range []domain.Cooldown{
+				{Host: "youtube.com", Until: now.Add(time.Minute), Reason: "HTTP Error 429"},
+				{Host: "vimeo.com", Until: now.Add(-time.Minute)},
+				{Host: "youtube.com", Until: now.Add(time.Hour), Reason: "Too Many Requests"},
+			} {
+				if err := repo.SetCooldown(ctx, c); err != nil {
+					t.Fatalf("SetCooldown(%s) error = %v", c.Host, err)
+				}
+			}
+
+			cooldowns, err := repo.Cooldowns(ctx)
+			if err != nil {
+				t.Fatalf("Cooldowns() error = %v", err)
+			}
+			if len(cooldowns) != 1 {
+				t.Fatalf("Cooldowns() = %+v, want only the latest youtube.com", cooldowns)
+			}
+			got := cooldowns[0]
+			if got.Host != "youtube.com" || got.Reason != "Too Many Requests" || got.Until.UnixMilli() != now.Add(time.Hour).UnixMilli() {
+				t.Errorf("Cooldowns()[0] = %+v, want the hour-long youtube.com cooldown", got)
+			}
+
+			var stored int
+			repo.db.QueryRow(`SELECT COUNT(*) FROM host_cooldowns`).Scan(&stored)
+			if stored != 1 {
+				t.Errorf("stored %d cooldowns, want 1 with the replaced and expired ones dropped", stored)
+			}
+			var host string
+			repo.db.QueryRow(`SELECT host FROM host_cooldowns ORDER BY id DESC LIMIT 1`).Scan(&host)
+			if encrypted == (host == "youtube.com") {
+				t.Errorf("stored host = %q with encryption %v", host, encrypted)
+			}
+		})
+	}
+}
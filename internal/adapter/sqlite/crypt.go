@@ -0,0 +1,296 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrKeyRequired = errors.New("database is encrypted; a key is required")
+	ErrWrongKey    = errors.New("database key does not match")
+)
+
+// encPrefix marks an encrypted value. Values without it are plaintext, so
+// databases written before encryption was enabled stay readable.
+const encPrefix = "enc:v1:"
+
+// keyCheck is encrypted into the meta table to detect a wrong key.
+const keyCheck = "catcher"
+
+// fieldCipher encrypts individual column values with AES-256-GCM, and
+// keys the hashes values are looked up by.
+type fieldCipher struct {
+	aead cipher.AEAD
+	mac  []byte
+}
+
+// newFieldCipher derives an AES-256 key from arbitrary key material.
+func newFieldCipher(key []byte) (*fieldCipher, error) {
+	derived, err := hkdf.Key(sha256.New, key, nil, "catcher sqlite fields v1", 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := hkdf.Key(sha256.New, key, nil, "catcher sqlite lookup v1", 32)
+	if err != nil {
+		return nil, err
+	}
+	return &fieldCipher{aead: aead, mac: mac}, nil
+}
+
+func (c *fieldCipher) seal(s string) string {
+	nonce := make([]byte, c.aead.NonceSize())
+	rand.Read(nonce)
+	sealed := c.aead.Seal(nonce, nonce, []byte(s), nil)
+	return encPrefix + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+func (c *fieldCipher) open(s string) (string, error) {
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(s, encPrefix))
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", ErrWrongKey
+	}
+	n := c.aead.NonceSize()
+	plain, err := c.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+	return string(plain), nil
+}
+
+// lookupHash hashes s so it can be found by equality without being stored
+// readable: HMAC-SHA256 when a key is set, so values can't be confirmed
+// by guessing, and plain SHA-256 otherwise.
+func (r *Repository) lookupHash(s string) string {
+	if r.cipher == nil {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	h := hmac.New(sha256.New, r.cipher.mac)
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashCheck is hashed into meta as url_hash_check, to tell whether the
+// stored URL hashes were made with the current key.
+const hashCheck = "catcher"
+
+// rehashURLs fills url_hash for every job and library file unless
+// url_hash_check shows they were already hashed with the current key, as
+// after a key is first set or a snapshot is imported. Cached probes are
+// keyed by the same hashes but can't be rehashed without their URLs, so
+// they are dropped.
+func (r *Repository) rehashURLs(ctx context.Context, tx *sql.Tx) error {
+	var check string
+	err := tx.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = 'url_hash_check'`).Scan(&check)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if check == r.lookupHash(hashCheck) {
+		return nil
+	}
+
+	for _, table := range []string{"jobs", "library_files"} {
+		if err := r.rehashTable(ctx, tx, table); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM probe_cache`); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT OR REPLACE INTO meta (key, value) VALUES ('url_hash_check', ?)`, r.lookupHash(hashCheck),
+	)
+	return err
+}
+
+// rehashTable fills url_hash from url for every row of table.
+func (r *Repository) rehashTable(ctx context.Context, tx *sql.Tx, table string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, url FROM `+table)
+	if err != nil {
+		return err
+	}
+	hashes := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var url string
+		if err := rows.Scan(&id, &url); err != nil {
+			rows.Close()
+			return err
+		}
+		if url, err = r.decrypt(url); err != nil {
+			rows.Close()
+			return err
+		}
+		hashes[id] = r.lookupHash(url)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET url_hash = ? WHERE id = ?`, hash, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encrypt seals s when a key is set. Empty values stay empty.
+func (r *Repository) encrypt(s string) string {
+	if r.cipher == nil || s == "" {
+		return s
+	}
+	return r.cipher.seal(s)
+}
+
+// decrypt opens s if it is encrypted.
+func (r *Repository) decrypt(s string) (string, error) {
+	if !strings.HasPrefix(s, encPrefix) {
+		return s, nil
+	}
+	if r.cipher == nil {
+		return "", ErrKeyRequired
+	}
+	return r.cipher.open(s)
+}
+
+// encryptedColumns lists the columns holding URLs, text derived from them,
+// or the user's own notes.
+var encryptedColumns = []struct{ table, column string }{
+	{"jobs", "url"},
+	{"jobs", "original_url"},
+	{"jobs", "error"},
+	{"jobs", "title"},
+	{"jobs", "notes"},
+	{"jobs_archive", "url"},
+	{"jobs_archive", "original_url"},
+	{"jobs_archive", "error"},
+	{"jobs_archive", "title"},
+	{"jobs_archive", "notes"},
+	{"job_attempts", "command"},
+	{"job_attempts", "output"},
+	{"job_attempts", "error"},
+	{"job_results", "path"},
+	{"host_cooldowns", "host"},
+	{"host_cooldowns", "reason"},
+	{"file_issues", "path"},
+	{"file_issues", "error"},
+	{"library_files", "url"},
+	{"library_files", "path"},
+	{"probe_cache", "info"},
+}
+
+// Unlock sets the key for column encryption. With a key, it is checked
+// against the one the database was first encrypted with, and values
+// written before encryption was enabled are encrypted. Either way, URL
+// hashes made without the current key are remade. Without a key, it
+// returns ErrKeyRequired if the database is encrypted.
+func (r *Repository) Unlock(ctx context.Context, key []byte) error {
+	var check string
+	err := r.db.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = 'key_check'`).Scan(&check)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	encrypted := err == nil
+
+	if len(key) == 0 {
+		if encrypted {
+			return ErrKeyRequired
+		}
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			return r.rehashURLs(ctx, tx)
+		})
+	}
+
+	c, err := newFieldCipher(key)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		if v, err := c.open(check); err != nil || v != keyCheck {
+			return ErrWrongKey
+		}
+	}
+
+	r.cipher = c
+	var rewritten int64
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		if !encrypted {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO meta (key, value) VALUES ('key_check', ?)`, c.seal(keyCheck),
+			); err != nil {
+				return err
+			}
+		}
+		for _, col := range encryptedColumns {
+			n, err := r.encryptColumn(ctx, tx, col.table, col.column)
+			if err != nil {
+				return err
+			}
+			rewritten += n
+		}
+		return r.rehashURLs(ctx, tx)
+	})
+	if err != nil || rewritten == 0 {
+		return err
+	}
+	// Drop the freed pages still holding the plaintext
+	_, err = r.db.ExecContext(ctx, `VACUUM`)
+	return err
+}
+
+// encryptColumn encrypts every plaintext value left in table.column,
+// returning how many it rewrote.
+func (r *Repository) encryptColumn(ctx context.Context, tx *sql.Tx, table, column string) (int64, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, `+column+` FROM `+table+`
+		 WHERE `+column+` != '' AND `+column+` NOT LIKE '`+encPrefix+`%'`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	type value struct {
+		id   int64
+		text string
+	}
+	var plain []value
+	for rows.Next() {
+		var v value
+		if err := rows.Scan(&v.id, &v.text); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		plain = append(plain, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, v := range plain {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE `+table+` SET `+column+` = ? WHERE id = ?`, r.encrypt(v.text), v.id,
+		); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(plain)), nil
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_Unlock(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	ctx := context.Background()
+	key := []byte("correct horse battery staple")
+
+	repo, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	// Written before encryption is enabled
+	archived, _ := repo.Create(domain.WithNotes(ctx, "secret-notes"), "https://example.com/secret-archived")
+	repo.Fail(ctx, archived.ID, "404 for https://example.com/secret-archived")
+	if _, err := repo.PruneJobs(ctx, time.Now().Add(time.Hour), true); err != nil {
+		t.Fatalf("PruneJobs() error = %v", err)
+	}
+	old, _ := repo.Create(domain.WithOriginalURL(ctx, "https://m.example.com/secret-old"), "https://example.com/secret-old")
+	repo.Fail(ctx, old.ID, "404 for https://example.com/secret-old")
+
+	if err := repo.Unlock(ctx, key); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	job, _ := repo.Create(ctx, "https://example.com/secret-new")
+	repo.AddAttempt(ctx, job.ID, domain.Attempt{
+		Number: 1, Processor: "yt", Command: "yt-dlp https://example.com/secret-new",
+		Output: "downloading", StartedAt: time.Now(), FinishedAt: time.Now(),
+	})
+
+	got, err := repo.Get(ctx, old.ID)
+	if err != nil || got.URL != "https://example.com/secret-old" || got.OriginalURL != "https://m.example.com/secret-old" || got.Error != "404 for https://example.com/secret-old" {
+		t.Errorf("Get(old) = %+v, %v", got, err)
+	}
+	attempts, err := repo.Attempts(ctx, job.ID)
+	if err != nil || len(attempts) != 1 || attempts[0].Command != "yt-dlp https://example.com/secret-new" {
+		t.Errorf("Attempts() = %+v, %v", attempts, err)
+	}
+	repo.Close()
+
+	// Nothing readable is left in the file
+	for _, suffix := range []string{"", "-wal"} {
+		data, _ := os.ReadFile(dbPath + suffix)
+		if bytes.Contains(data, []byte("secret-")) {
+			t.Errorf("plaintext URL found in %s", filepath.Base(dbPath+suffix))
+		}
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		wantErr error
+	}{
+		{"no key", nil, ErrKeyRequired},
+		{"wrong key", []byte("wrong"), ErrWrongKey},
+		{"right key", key, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := New(dbPath)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer repo.Close()
+
+			if err := repo.Unlock(ctx, tt.key); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unlock() error = %v, want %v", err, tt.wantErr)
+			}
+			_, err = repo.Get(ctx, job.ID)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+			if tt.wantErr == ErrKeyRequired && !errors.Is(err, ErrKeyRequired) {
+				t.Errorf("Get() without key error = %v, want ErrKeyRequired", err)
+			}
+		})
+	}
+}
+
+func TestRepository_UnlockWithoutKey(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := repo.Unlock(ctx, nil); err != nil {
+		t.Fatalf("Unlock(nil) on plaintext database error = %v", err)
+	}
+	job, _ := repo.Create(ctx, "https://example.com/a")
+	if got, err := repo.Get(ctx, job.ID); err != nil || got.URL != "https://example.com/a" {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+}
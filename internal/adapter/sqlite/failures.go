@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// failureQueries read failed attempts, then failed jobs, live and archived.
+// Each selects the job ID, error, time, and whether the job failed for good.
+var failureQueries = []string{
+	`SELECT job_id, error, finished_at, 0 FROM job_attempts WHERE error != ''`,
+	`SELECT id, COALESCE(error, ''), updated_at, 1 FROM jobs WHERE status = ?`,
+	`SELECT id, COALESCE(error, ''), updated_at, 1 FROM jobs_archive WHERE status = ?`,
+}
+
+// ListFailures implements domain.FailureLister. Times are compared once
+// scanned, as stored times don't all share a time zone, and errors may be
+// encrypted.
+func (r *Repository) ListFailures(ctx context.Context, since time.Time) ([]domain.Failure, error) {
+	var failures []domain.Failure
+	err := r.retry(ctx, "list_failures", func() error {
+		failures = nil
+		for i, query := range failureQueries {
+			var args []any
+			if i > 0 {
+				args = append(args, domain.StatusFailed)
+			}
+			if err := r.scanFailures(ctx, since, &failures, query, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// scanFailures appends the failures query returns from since on.
+func (r *Repository) scanFailures(ctx context.Context, since time.Time, failures *[]domain.Failure, query string, args ...any) error {
+	rows, err := r.readQuery(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f domain.Failure
+		if err := rows.Scan(&f.JobID, &f.Error, &f.At, &f.Final); err != nil {
+			return err
+		}
+		if f.At.Before(since) {
+			continue
+		}
+		if f.Error, err = r.decrypt(f.Error); err != nil {
+			return err
+		}
+		*failures = append(*failures, f)
+	}
+	return rows.Err()
+}
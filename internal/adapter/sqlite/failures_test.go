@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_ListFailures(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+			if encrypted {
+				if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			now := time.Now()
+			attempt := func(jobID int64, msg string, at time.Time) {
+				t.Helper()
+				a := domain.Attempt{Number: 1, Processor: "test", Error: msg, StartedAt: at, FinishedAt: at}
+				if err := repo.AddAttempt(ctx, jobID, a); err != nil {
+					t.Fatal(err)
+				}
+			}
+			retried, _ := repo.Create(ctx, "https://example.com/retried")
+			attempt(retried.ID, "connection reset by peer", now.Add(-time.Hour))
+			attempt(retried.ID, "", now) // succeeded
+			attempt(retried.ID, "stale: no space left on device", now.Add(-8*24*time.Hour))
+
+			archived, _ := repo.Create(ctx, "https://example.com/archived")
+			repo.Fail(ctx, archived.ID, "Unsupported URL")
+			if _, err := repo.PruneJobs(ctx, now.Add(time.Minute), true); err != nil {
+				t.Fatal(err)
+			}
+
+			failed, _ := repo.Create(ctx, "https://example.com/failed")
+			attempt(failed.ID, "Sign in to confirm your age", now)
+			repo.Fail(ctx, failed.ID, "Sign in to confirm your age")
+
+			repo.Create(ctx, "https://example.com/pending")
+
+			failures, err := repo.ListFailures(ctx, now.Add(-7*24*time.Hour))
+			if err != nil {
+				t.Fatalf("ListFailures() error = %v", err)
+			}
+			type key struct {
+				id    int64
+				err   string
+				final bool
+			}
+			var got []key
+			for _, f := range failures {
+				got = append(got, key{f.JobID, f.Error, f.Final})
+			}
+			want := []key{
+				{retried.ID, "connection reset by peer", false},
+				{failed.ID, "Sign in to confirm your age", false},
+				{failed.ID, "Sign in to confirm your age", true},
+				{archived.ID, "Unsupported URL", true},
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("ListFailures() = %v, want %v", got, want)
+			}
+		})
+	}
+}
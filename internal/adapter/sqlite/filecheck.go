@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// fileCheckSummary is the part of a file check kept in meta.
+type fileCheckSummary struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Since     time.Time `json:"since"`
+	Jobs      int       `json:"jobs"`
+	Files     int       `json:"files"`
+}
+
+// SaveFileCheck implements domain.FileCheckRepository.
+func (r *Repository) SaveFileCheck(ctx context.Context, c *domain.FileCheck) error {
+	summary, err := json.Marshal(fileCheckSummary{CheckedAt: c.CheckedAt, Since: c.Since, Jobs: c.Jobs, Files: c.Files})
+	if err != nil {
+		return err
+	}
+	return r.retry(ctx, "save_file_check", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			if _, err := r.stmtExec(ctx, tx, `DELETE FROM file_issues`); err != nil {
+				return err
+			}
+			for _, issue := range c.Issues {
+				_, err := r.stmtExec(ctx, tx,
+					`INSERT INTO file_issues (job_id, path, problem, want_bytes, got_bytes, error) VALUES (?, ?, ?, ?, ?, ?)`,
+					issue.JobID, r.encrypt(issue.Path), issue.Problem, issue.WantBytes, issue.GotBytes, r.encrypt(issue.Error),
+				)
+				if err != nil {
+					return err
+				}
+			}
+			_, err := r.stmtExec(ctx, tx,
+				`INSERT INTO meta (key, value) VALUES ('file_check', ?)
+				 ON CONFLICT (key) DO UPDATE SET value = excluded.value`, string(summary),
+			)
+			return err
+		})
+	})
+}
+
+// LastFileCheck implements domain.FileCheckRepository.
+func (r *Repository) LastFileCheck(ctx context.Context) (*domain.FileCheck, error) {
+	var c *domain.FileCheck
+	err := r.retry(ctx, "last_file_check", func() error {
+		var summary string
+		err := r.stmtQueryRow(ctx, nil, `SELECT value FROM meta WHERE key = 'file_check'`).Scan(&summary)
+		if err == sql.ErrNoRows {
+			return domain.ErrNoFileCheck
+		}
+		if err != nil {
+			return err
+		}
+		var s fileCheckSummary
+		if err := json.Unmarshal([]byte(summary), &s); err != nil {
+			return err
+		}
+		c = &domain.FileCheck{CheckedAt: s.CheckedAt, Since: s.Since, Jobs: s.Jobs, Files: s.Files}
+
+		rows, err := r.stmtQuery(ctx, nil, `SELECT job_id, path, problem, want_bytes, got_bytes, error FROM file_issues ORDER BY id`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var issue domain.FileIssue
+			if err := rows.Scan(&issue.JobID, &issue.Path, &issue.Problem, &issue.WantBytes, &issue.GotBytes, &issue.Error); err != nil {
+				return err
+			}
+			if issue.Path, err = r.decrypt(issue.Path); err != nil {
+				return err
+			}
+			if issue.Error, err = r.decrypt(issue.Error); err != nil {
+				return err
+			}
+			c.Issues = append(c.Issues, issue)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
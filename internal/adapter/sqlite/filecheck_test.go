@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_FileCheck(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+			if encrypted {
+				if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := repo.LastFileCheck(ctx); !errors.Is(err, domain.ErrNoFileCheck) {
+				t.Fatalf("LastFileCheck() before any check error = %v, want ErrNoFileCheck", err)
+			}
+
+			now := time.Now().UTC().Truncate(time.Second)
+			first := &domain.FileCheck{CheckedAt: now.Add(-time.Hour), Jobs: 1, Files: 1, Issues: []domain.FileIssue{
+				{JobID: 1, Path: "/v/old.mp4", Problem: domain.FileMissing, WantBytes: 10},
+			}}
+			second := &domain.FileCheck{CheckedAt: now, Since: now.Add(-24 * time.Hour), Jobs: 3, Files: 6, Issues: []domain.FileIssue{
+				{JobID: 2, Path: "/v/a.mp4", Problem: domain.FileMissing, WantBytes: 100},
+				{JobID: 3, Path: "/v/b.mkv", Problem: domain.FileSizeMismatch, WantBytes: 100, GotBytes: 40},
+				{JobID: 4, Path: "/v/c.mp4", Problem: domain.FileUnreadable, WantBytes: 100, Error: "stat /v/c.mp4: permission denied"},
+			}}
+			for _, c := range []*domain.FileCheck{first, second} {
+				if err := repo.SaveFileCheck(ctx, c); err != nil {
+					t.Fatalf("SaveFileCheck() error = %v", err)
+				}
+			}
+
+			got, err := repo.LastFileCheck(ctx)
+			if err != nil {
+				t.Fatalf("LastFileCheck() error = %v", err)
+			}
+			if !got.CheckedAt.Equal(second.CheckedAt) || !got.Since.Equal(second.Since) || got.Jobs != 3 || got.Files != 6 {
+				t.Errorf("LastFileCheck() = %+v, want the second check's summary", got)
+			}
+			if !slices.Equal(got.Issues, second.Issues) {
+				t.Errorf("LastFileCheck() issues = %+v, want %+v", got.Issues, second.Issues)
+			}
+		})
+	}
+}
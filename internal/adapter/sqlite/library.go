@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+const libraryColumns = `id, job_id, url, path, bytes, sha256, delivered_at, missing, checked_at`
+
+// LibraryFiles implements domain.LibraryIndex.
+func (r *Repository) LibraryFiles(ctx context.Context) ([]domain.LibraryFile, error) {
+	return r.libraryFiles(ctx, "library_files", nil,
+		`SELECT `+libraryColumns+` FROM library_files ORDER BY id`,
+	)
+}
+
+// SearchLibrary implements domain.LibraryIndex. Paths and URLs may be
+// encrypted, so text is matched after the other fields have narrowed the
+// files down in SQL, and URLs are looked up by their hash.
+func (r *Repository) SearchLibrary(ctx context.Context, q domain.LibraryQuery) ([]domain.LibraryFile, error) {
+	where := []string{"1 = 1"}
+	var args []any
+	if q.URL != "" {
+		where = append(where, "url_hash = ?")
+		args = append(args, r.lookupHash(q.URL))
+	}
+	if q.SHA256 != "" {
+		where = append(where, "sha256 = ?")
+		args = append(args, strings.ToLower(q.SHA256))
+	}
+	if q.JobID != 0 {
+		where = append(where, "job_id = ?")
+		args = append(args, q.JobID)
+	}
+	if q.Missing != nil {
+		where = append(where, "missing = ?")
+		args = append(args, *q.Missing)
+	}
+	query := `SELECT ` + libraryColumns + ` FROM library_files WHERE ` + strings.Join(where, " AND ") + ` ORDER BY id DESC`
+	if q.Text == "" && q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	files, err := r.libraryFiles(ctx, "search_library", func(f *domain.LibraryFile) bool {
+		return q.Match(f)
+	}, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if q.Limit > 0 && len(files) > q.Limit {
+		files = files[:q.Limit]
+	}
+	return files, nil
+}
+
+// libraryFiles runs query for library files, keeping those keep accepts,
+// or all if keep is nil.
+func (r *Repository) libraryFiles(ctx context.Context, op string, keep func(*domain.LibraryFile) bool, query string, args ...any) ([]domain.LibraryFile, error) {
+	var files []domain.LibraryFile
+	err := r.retry(ctx, op, func() error {
+		rows, err := r.stmtQuery(ctx, nil, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		files = nil
+		for rows.Next() {
+			var f domain.LibraryFile
+			var checked sql.NullTime
+			if err := rows.Scan(&f.ID, &f.JobID, &f.URL, &f.Path, &f.Bytes, &f.SHA256, &f.DeliveredAt, &f.Missing, &checked); err != nil {
+				return err
+			}
+			if f.URL, err = r.decrypt(f.URL); err != nil {
+				return err
+			}
+			if f.Path, err = r.decrypt(f.Path); err != nil {
+				return err
+			}
+			f.CheckedAt = checked.Time
+			if keep == nil || keep(&f) {
+				files = append(files, f)
+			}
+		}
+		return rows.Err()
+	})
+	return files, err
+}
+
+// UpdateLibraryFiles implements domain.LibraryIndex.
+func (r *Repository) UpdateLibraryFiles(ctx context.Context, files []domain.LibraryFile) error {
+	return r.retry(ctx, "update_library_files", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			for _, f := range files {
+				_, err := r.stmtExec(ctx, tx,
+					`UPDATE library_files SET bytes = ?, sha256 = ?, missing = ?, checked_at = ? WHERE id = ?`,
+					f.Bytes, f.SHA256, f.Missing, f.CheckedAt, f.ID,
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_Library(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+			if encrypted {
+				if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			job, _ := repo.Create(ctx, "https://example.com/video")
+			err := repo.Complete(ctx, job.ID, domain.Completion{Processor: "test", Files: []domain.ResultFile{
+				{Path: "/v/video.mp4", Bytes: 100, SHA256: "abc"},
+				{Path: "/v/video.en.vtt", Bytes: 10},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Pruning the job keeps its files in the index
+			if _, err := repo.PruneJobs(ctx, time.Now().Add(time.Hour), false); err != nil {
+				t.Fatal(err)
+			}
+
+			files, err := repo.LibraryFiles(ctx)
+			if err != nil {
+				t.Fatalf("LibraryFiles() error = %v", err)
+			}
+			if len(files) != 2 {
+				t.Fatalf("LibraryFiles() = %+v, want both files", files)
+			}
+			f := files[0]
+			if f.JobID != job.ID || f.URL != "https://example.com/video" || f.Path != "/v/video.mp4" || f.Bytes != 100 || f.SHA256 != "abc" {
+				t.Errorf("file = %+v", f)
+			}
+			if f.DeliveredAt.IsZero() || !f.CheckedAt.IsZero() || f.Missing {
+				t.Errorf("file = %+v, want delivered and never checked", f)
+			}
+
+			checked := time.Now().UTC().Truncate(time.Second)
+			f.Missing, f.CheckedAt = true, checked
+			if err := repo.UpdateLibraryFiles(ctx, []domain.LibraryFile{f}); err != nil {
+				t.Fatalf("UpdateLibraryFiles() error = %v", err)
+			}
+			files, _ = repo.LibraryFiles(ctx)
+			if !files[0].Missing || !files[0].CheckedAt.Equal(checked) || files[1].Missing {
+				t.Errorf("after update = %+v, want only the first missing, checked at %s", files, checked)
+			}
+		})
+	}
+}
+
+func TestRepository_SearchLibrary(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+
+			complete := func(url string, files ...domain.ResultFile) {
+				t.Helper()
+				job, _ := repo.Create(ctx, url)
+				if err := repo.Complete(ctx, job.ID, domain.Completion{Processor: "test", Files: files}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			complete("https://example.com/Cats", domain.ResultFile{Path: "/v/cats.mp4", SHA256: "abc"})
+			complete("https://example.com/dogs", domain.ResultFile{Path: "/v/dogs.mp4"})
+			// Hashes made before the key is set are remade with it
+			if encrypted {
+				if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			complete("https://example.com/Cats",
+				domain.ResultFile{Path: "/v/cats (2).mp4"},
+				domain.ResultFile{Path: "/library/cats.mp4", Library: true},
+			)
+			files, _ := repo.LibraryFiles(ctx)
+			files[1].Missing = true
+			if err := repo.UpdateLibraryFiles(ctx, files[1:2]); err != nil {
+				t.Fatal(err)
+			}
+
+			yes := true
+			tests := []struct {
+				name  string
+				query domain.LibraryQuery
+				want  []string
+			}{
+				{"everything, newest first", domain.LibraryQuery{}, []string{"/v/cats (2).mp4", "/v/dogs.mp4", "/v/cats.mp4"}},
+				{"URL", domain.LibraryQuery{URL: "https://example.com/Cats"}, []string{"/v/cats (2).mp4", "/v/cats.mp4"}},
+				{"text", domain.LibraryQuery{Text: "CATS"}, []string{"/v/cats (2).mp4", "/v/cats.mp4"}},
+				{"text and limit", domain.LibraryQuery{Text: "cats", Limit: 1}, []string{"/v/cats (2).mp4"}},
+				{"digest", domain.LibraryQuery{SHA256: "ABC"}, []string{"/v/cats.mp4"}},
+				{"job", domain.LibraryQuery{JobID: 2}, []string{"/v/dogs.mp4"}},
+				{"missing", domain.LibraryQuery{Missing: &yes}, []string{"/v/dogs.mp4"}},
+				{"limit", domain.LibraryQuery{Limit: 2}, []string{"/v/cats (2).mp4", "/v/dogs.mp4"}},
+				{"no match", domain.LibraryQuery{URL: "https://example.com/cats"}, nil},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					found, err := repo.SearchLibrary(ctx, tt.query)
+					if err != nil {
+						t.Fatalf("SearchLibrary() error = %v", err)
+					}
+					var got []string
+					for _, f := range found {
+						got = append(got, f.Path)
+					}
+					if !slices.Equal(got, tt.want) {
+						t.Errorf("SearchLibrary() = %q, want %q", got, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
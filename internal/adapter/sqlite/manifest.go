@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// ListCompleted implements domain.CompletedLister. Timestamps and hosts are
+// matched once scanned, as stored times don't all share a time zone and
+// URLs may be encrypted.
+func (r *Repository) ListCompleted(ctx context.Context, f domain.CompletedFilter) ([]domain.Job, error) {
+	jobs, err := r.collectMatching(ctx, "list_completed", func() (*sql.Rows, error) {
+		return r.readQuery(ctx,
+			`SELECT id, url, original_url, status, attempts, COALESCE(error, ''), title, bytes, duration_ms, held, approved, not_before, queue, source, uid, notes, bookmark, redownload_of, user_agent, created_at, updated_at, completed_at
+			 FROM jobs WHERE status = ?`, domain.StatusCompleted,
+		)
+	}, func(job *domain.Job) bool { return f.Match(job) }, 0)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(jobs, func(a, b domain.Job) int {
+		if c := a.CompletedAt.Compare(b.CompletedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	err = r.retry(ctx, "list_completed_files", func() error {
+		return r.attachResults(ctx, jobs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// attachResults sets the files of each of jobs, in one query.
+func (r *Repository) attachResults(ctx context.Context, jobs []domain.Job) error {
+	ids := make([]int64, len(jobs))
+	index := make(map[int64]int, len(jobs))
+	for i := range jobs {
+		ids[i], index[jobs[i].ID] = jobs[i].ID, i
+		jobs[i].Files = nil
+	}
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.readQuery(ctx,
+		`SELECT job_id, path, bytes, sha256, library FROM job_results WHERE job_id IN (SELECT value FROM json_each(?)) ORDER BY id ASC`, string(idsJSON),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var jobID int64
+		var f domain.ResultFile
+		if err := rows.Scan(&jobID, &f.Path, &f.Bytes, &f.SHA256, &f.Library); err != nil {
+			return err
+		}
+		if f.Path, err = r.decrypt(f.Path); err != nil {
+			return err
+		}
+		job := &jobs[index[jobID]]
+		job.Files = append(job.Files, f)
+	}
+	return rows.Err()
+}
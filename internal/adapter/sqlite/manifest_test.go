@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_ListCompleted(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+			if encrypted {
+				if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			complete := func(url string, paths ...string) int64 {
+				t.Helper()
+				job, _ := repo.Create(ctx, url)
+				repo.Claim(ctx, job.ID)
+				var files []domain.ResultFile
+				for _, p := range paths {
+					files = append(files, domain.ResultFile{Path: p, Bytes: 1})
+				}
+				if err := repo.Complete(ctx, job.ID, domain.Completion{Processor: "test", Files: files}); err != nil {
+					t.Fatal(err)
+				}
+				return job.ID
+			}
+			old := complete("https://youtube.com/old", "/v/old.mp4")
+			repo.db.Exec(`UPDATE jobs SET completed_at = ? WHERE id = ?`, time.Now().Add(-8*24*time.Hour), old)
+			pending, _ := repo.Create(ctx, "https://youtube.com/pending")
+			second := complete("https://vimeo.com/b", "/v/b.mp4")
+			first := complete("https://m.youtube.com/a", "/v/a.mp4", "/v/a.en.srt")
+			// Completion order, not ID order
+			repo.db.Exec(`UPDATE jobs SET completed_at = ? WHERE id = ?`, time.Now().Add(-time.Hour), first)
+			// Later edits don't move completion
+			repo.SetNotes(ctx, old, "seen")
+
+			ids := func(f domain.CompletedFilter) []int64 {
+				t.Helper()
+				jobs, err := repo.ListCompleted(ctx, f)
+				if err != nil {
+					t.Fatalf("ListCompleted() error = %v", err)
+				}
+				var ids []int64
+				for _, job := range jobs {
+					ids = append(ids, job.ID)
+				}
+				return ids
+			}
+			week := time.Now().Add(-7 * 24 * time.Hour)
+			tests := []struct {
+				name string
+				f    domain.CompletedFilter
+				want []int64
+			}{
+				{"all", domain.CompletedFilter{}, []int64{old, first, second}},
+				{"this week", domain.CompletedFilter{Since: week}, []int64{first, second}},
+				{"before this week", domain.CompletedFilter{Until: week}, []int64{old}},
+				{"host", domain.CompletedFilter{Since: week, Host: "youtube.com"}, []int64{first}},
+			}
+			for _, tt := range tests {
+				if got := ids(tt.f); !slices.Equal(got, tt.want) {
+					t.Errorf("%s: ListCompleted() = %v, want %v (pending job %d never)", tt.name, got, tt.want, pending.ID)
+				}
+			}
+
+			jobs, _ := repo.ListCompleted(ctx, domain.CompletedFilter{Host: "m.youtube.com"})
+			if len(jobs) != 1 || len(jobs[0].Files) != 2 || jobs[0].Files[1].Path != "/v/a.en.srt" {
+				t.Errorf("ListCompleted() = %+v, want job %d with both files in order", jobs, first)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations upgrade the base schema in order. The number applied is kept
+// in PRAGMA user_version, so append only; never edit a released entry.
+var migrations = []string{
+	// 1: bytes produced per job, and per-processor throughput
+	`ALTER TABLE jobs ADD COLUMN bytes INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE job_stats_v1 (
+	    period    TEXT NOT NULL,
+	    bucket    TEXT NOT NULL,
+	    processor TEXT NOT NULL DEFAULT '',
+	    submitted INTEGER NOT NULL DEFAULT 0,
+	    completed INTEGER NOT NULL DEFAULT 0,
+	    failed    INTEGER NOT NULL DEFAULT 0,
+	    bytes     INTEGER NOT NULL DEFAULT 0,
+	    PRIMARY KEY (period, bucket, processor)
+	);
+	INSERT INTO job_stats_v1 (period, bucket, submitted, completed, failed, bytes)
+	    SELECT period, bucket, submitted, completed, failed, bytes FROM job_stats;
+	DROP TABLE job_stats;
+	ALTER TABLE job_stats_v1 RENAME TO job_stats;`,
+	// 2: per-attempt history for diagnostics
+	`CREATE TABLE job_attempts (
+	    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	    job_id      INTEGER NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+	    attempt     INTEGER NOT NULL,
+	    processor   TEXT NOT NULL,
+	    command     TEXT NOT NULL DEFAULT '',
+	    output      TEXT NOT NULL DEFAULT '',
+	    error       TEXT NOT NULL DEFAULT '',
+	    started_at  DATETIME NOT NULL,
+	    finished_at DATETIME NOT NULL
+	);
+	CREATE INDEX idx_job_attempts_job ON job_attempts(job_id);`,
+	// 3: database-wide settings, such as the encryption key check
+	`CREATE TABLE meta (
+	    key   TEXT PRIMARY KEY,
+	    value TEXT NOT NULL
+	);`,
+	// 4: result files and run time, written with the completion
+	`ALTER TABLE jobs ADD COLUMN duration_ms INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE job_results (
+	    id     INTEGER PRIMARY KEY AUTOINCREMENT,
+	    job_id INTEGER NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+	    path   TEXT NOT NULL,
+	    bytes  INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX idx_job_results_job ON job_results(job_id);`,
+	// 5: processor-reported title, and earliest retry time in unix millis
+	`ALTER TABLE jobs ADD COLUMN title TEXT NOT NULL DEFAULT '';
+	ALTER TABLE jobs ADD COLUMN not_before INTEGER NOT NULL DEFAULT 0;`,
+	// 6: jobs held back from the worker until released
+	`ALTER TABLE jobs ADD COLUMN held INTEGER NOT NULL DEFAULT 0;`,
+	// 7: jobs an admin approved, exempt from size limits
+	`ALTER TABLE jobs ADD COLUMN approved INTEGER NOT NULL DEFAULT 0;`,
+	// 8: URL as submitted, when rewrite rules changed it
+	`ALTER TABLE jobs ADD COLUMN original_url TEXT NOT NULL DEFAULT '';`,
+	// 9: per-processor queues
+	`ALTER TABLE jobs ADD COLUMN queue TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_jobs_queue ON jobs(status, queue);`,
+	// 10: submission source, for taking pending jobs in turn per source
+	`ALTER TABLE jobs ADD COLUMN source TEXT NOT NULL DEFAULT '';`,
+	// 11: pending jobs in each source's order, covering everything polls
+	// filter on, so they rank jobs from the index alone instead of sorting
+	// every pending row
+	`CREATE INDEX idx_jobs_pending ON jobs(status, held, source, created_at, id, not_before, queue);
+	DROP INDEX idx_jobs_queue;
+	CREATE INDEX idx_jobs_queue ON jobs(status, queue, held, source, created_at, id, not_before);`,
+	// 12: finished jobs moved out of the jobs table by retention. Their
+	// attempts and result files stay where they are, keyed by job ID.
+	`CREATE TABLE jobs_archive (
+	    id           INTEGER PRIMARY KEY,
+	    url          TEXT NOT NULL,
+	    original_url TEXT NOT NULL DEFAULT '',
+	    status       TEXT NOT NULL,
+	    attempts     INTEGER NOT NULL DEFAULT 0,
+	    error        TEXT,
+	    title        TEXT NOT NULL DEFAULT '',
+	    bytes        INTEGER NOT NULL DEFAULT 0,
+	    duration_ms  INTEGER NOT NULL DEFAULT 0,
+	    approved     INTEGER NOT NULL DEFAULT 0,
+	    queue        TEXT NOT NULL DEFAULT '',
+	    source       TEXT NOT NULL DEFAULT '',
+	    created_at   DATETIME,
+	    updated_at   DATETIME,
+	    archived_at  DATETIME NOT NULL
+	);`,
+	// 13: random UUIDs, so exposed jobs can't be found by counting IDs.
+	// Existing jobs get version 4 UUIDs made in SQL.
+	`ALTER TABLE jobs ADD COLUMN uid TEXT NOT NULL DEFAULT '';
+	UPDATE jobs SET uid = ` + uuidSQL + `;
+	CREATE UNIQUE INDEX idx_jobs_uid ON jobs(uid);
+	ALTER TABLE jobs_archive ADD COLUMN uid TEXT NOT NULL DEFAULT '';
+	UPDATE jobs_archive SET uid = ` + uuidSQL + `;`,
+	// 14: listings, newest first and paged by (created_at, id), read from
+	// an index rather than sorting every job
+	`CREATE INDEX idx_jobs_created ON jobs(created_at, id);
+	CREATE INDEX idx_jobs_status_created ON jobs(status, created_at, id);`,
+	// 15: free-text notes to remember jobs by
+	`ALTER TABLE jobs ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+	ALTER TABLE jobs_archive ADD COLUMN notes TEXT NOT NULL DEFAULT '';`,
+	// 16: saved job filters
+	`CREATE TABLE views (
+	    name   TEXT PRIMARY KEY,
+	    status TEXT NOT NULL DEFAULT '',
+	    host   TEXT NOT NULL DEFAULT ''
+	);`,
+	// 17: bookmark jobs, streamed from their URL rather than downloaded
+	`ALTER TABLE jobs ADD COLUMN bookmark INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE jobs_archive ADD COLUMN bookmark INTEGER NOT NULL DEFAULT 0;`,
+	// 18: hosts that rate limited us, kept waiting across restarts. Hosts
+	// come from job URLs and may be encrypted, so they are matched once
+	// read; until is in Unix milliseconds, like not_before.
+	`CREATE TABLE host_cooldowns (
+	    id     INTEGER PRIMARY KEY,
+	    host   TEXT NOT NULL,
+	    until  INTEGER NOT NULL,
+	    reason TEXT NOT NULL DEFAULT ''
+	);`,
+	// 19: result files found missing or changed by the latest file check,
+	// whose summary is kept in meta as file_check
+	`CREATE TABLE file_issues (
+	    id         INTEGER PRIMARY KEY,
+	    job_id     INTEGER NOT NULL,
+	    path       TEXT NOT NULL,
+	    problem    TEXT NOT NULL,
+	    want_bytes INTEGER NOT NULL,
+	    got_bytes  INTEGER NOT NULL
+	);`,
+	// 20: re-downloads of completed jobs, linked to the job they clone
+	`ALTER TABLE jobs ADD COLUMN redownload_of INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE jobs_archive ADD COLUMN redownload_of INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX idx_jobs_redownload_of ON jobs(redownload_of) WHERE redownload_of != 0;`,
+	// 21: per-job User-Agent overriding the processor's default
+	`ALTER TABLE jobs ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+	ALTER TABLE jobs_archive ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';`,
+	// 22: SHA-256 digests of result files
+	`ALTER TABLE job_results ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';`,
+	// 23: index of every file delivered, kept when its job is pruned, and
+	// filled with the results of jobs still here. The URL is copied from
+	// the job, encrypted or not.
+	`CREATE TABLE library_files (
+	    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	    job_id       INTEGER NOT NULL,
+	    url          TEXT NOT NULL,
+	    path         TEXT NOT NULL,
+	    bytes        INTEGER NOT NULL DEFAULT 0,
+	    sha256       TEXT NOT NULL DEFAULT '',
+	    delivered_at DATETIME NOT NULL,
+	    missing      INTEGER NOT NULL DEFAULT 0,
+	    checked_at   DATETIME
+	);
+	CREATE INDEX idx_library_files_job ON library_files(job_id);
+	INSERT INTO library_files (job_id, url, path, bytes, sha256, delivered_at)
+	SELECT r.job_id, j.url, r.path, r.bytes, r.sha256, j.updated_at
+	FROM job_results r JOIN jobs j ON j.id = r.job_id ORDER BY r.id;`,
+	// 24: cached probe results. Keys are hashed, as they hold URLs, and
+	// probed_at is in Unix milliseconds.
+	`CREATE TABLE probe_cache (
+	    id        INTEGER PRIMARY KEY,
+	    key       TEXT NOT NULL UNIQUE,
+	    info      TEXT NOT NULL,
+	    probed_at INTEGER NOT NULL
+	);
+	CREATE INDEX idx_probe_cache_probed ON probe_cache(probed_at);`,
+	// 25: hashed job URLs, so jobs can be found by URL without decrypting
+	// every one. Existing jobs are hashed by Unlock, which has the key.
+	`ALTER TABLE jobs ADD COLUMN url_hash TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_jobs_url_hash ON jobs(url_hash, status, id);`,
+	// 26: when jobs completed, which later edits don't move like
+	// updated_at. Jobs completed before are taken to have completed when
+	// last updated.
+	`ALTER TABLE jobs ADD COLUMN completed_at DATETIME;
+	UPDATE jobs SET completed_at = updated_at WHERE status = 'completed';
+	ALTER TABLE jobs_archive ADD COLUMN completed_at DATETIME;
+	UPDATE jobs_archive SET completed_at = updated_at WHERE status = 'completed';`,
+	// 27: why a file could not be checked.
+	`ALTER TABLE file_issues ADD COLUMN error TEXT NOT NULL DEFAULT '';`,
+	// 28: result files a library hit pointed at, which the job doesn't own.
+	`ALTER TABLE job_results ADD COLUMN library INTEGER NOT NULL DEFAULT 0;`,
+	// 29: look library files up by URL. Clearing the check has Unlock hash
+	// the existing ones.
+	`ALTER TABLE library_files ADD COLUMN url_hash TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_library_files_url_hash ON library_files(url_hash);
+	DELETE FROM meta WHERE key = 'url_hash_check';`,
+	// 30: jobs that failed during a network outage. listed is set until
+	// they are requeued after it; requeues counts the outages they were
+	// requeued after.
+	`CREATE TABLE outage_jobs (
+	    job_id   INTEGER PRIMARY KEY REFERENCES jobs(id) ON DELETE CASCADE,
+	    listed   INTEGER NOT NULL DEFAULT 1,
+	    requeues INTEGER NOT NULL DEFAULT 0
+	);`,
+	// 31: cached probes were keyed by plain SHA-256 even with a key set,
+	// so their URLs could be confirmed by guessing. They are keyed by
+	// lookup hash now; drop the old ones.
+	`DELETE FROM probe_cache;`,
+}
+
+// uuidSQL makes a random version 4 UUID for each row, like domain.NewUID.
+const uuidSQL = `lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+	substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))`
+
+// migrate applies pending migrations, each in its own transaction.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestNew_MigratesExistingDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// Database as created before any migrations existed
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("schema error = %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO jobs (url, status) VALUES ('https://example.com', 'completed'), ('https://example.org', 'pending')`); err != nil {
+		t.Fatalf("seed job error = %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO job_stats (period, bucket, submitted) VALUES ('day', '2026-01-10T00:00:00Z', 7)`); err != nil {
+		t.Fatalf("seed stats error = %v", err)
+	}
+	db.Close()
+
+	for range 2 { // reopening must not re-run migrations
+		repo, err := New(dbPath)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		var version int
+		repo.db.QueryRow(`PRAGMA user_version`).Scan(&version)
+		if version != len(migrations) {
+			t.Errorf("user_version = %d, want %d", version, len(migrations))
+		}
+
+		job, err := repo.Get(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if job.Bytes != 0 {
+			t.Errorf("job.Bytes = %d, want 0", job.Bytes)
+		}
+		other, _ := repo.Get(context.Background(), 2)
+		if !domain.IsUID(job.UID) || other == nil || !domain.IsUID(other.UID) || job.UID == other.UID {
+			t.Errorf("backfilled UIDs = %q, %+v, want two distinct UUIDs", job.UID, other)
+		}
+
+		var submitted int64
+		var processor string
+		err = repo.db.QueryRow(`SELECT processor, submitted FROM job_stats WHERE period = ?`, domain.PeriodDay).Scan(&processor, &submitted)
+		if err != nil || submitted != 7 || processor != "" {
+			t.Errorf("migrated stats = (%q, %d, %v), want (\"\", 7, nil)", processor, submitted, err)
+		}
+		repo.Close()
+	}
+}
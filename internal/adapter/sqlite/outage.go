@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// ListOutageFailed implements domain.OutageRepository.
+func (r *Repository) ListOutageFailed(ctx context.Context, ids []int64) error {
+	return r.retry(ctx, "list_outage_failed", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			for _, id := range ids {
+				if _, err := r.stmtExec(ctx, tx,
+					`INSERT INTO outage_jobs (job_id) VALUES (?)
+					 ON CONFLICT (job_id) DO UPDATE SET listed = 1`, id,
+				); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// OutageFailed implements domain.OutageRepository. Jobs deleted or
+// archived since are left out.
+func (r *Repository) OutageFailed(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	err := r.retry(ctx, "outage_failed", func() error {
+		rows, err := r.stmtQuery(ctx, nil,
+			`SELECT o.job_id FROM outage_jobs o JOIN jobs j ON j.id = o.job_id
+			 WHERE o.listed = 1 ORDER BY o.job_id`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		ids = nil
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return rows.Err()
+	})
+	return ids, err
+}
+
+// RequeueOutageFailed implements domain.OutageRepository.
+func (r *Repository) RequeueOutageFailed(ctx context.Context, id int64, max int) (bool, error) {
+	var requeued bool
+	err := r.retry(ctx, "requeue_outage_failed", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			requeued = false
+			var requeues int
+			err := r.stmtQueryRow(ctx, tx, `SELECT requeues FROM outage_jobs WHERE job_id = ? AND listed = 1`, id).Scan(&requeues)
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := r.stmtExec(ctx, tx, `UPDATE outage_jobs SET listed = 0 WHERE job_id = ?`, id); err != nil {
+				return err
+			}
+			if requeues >= max {
+				return nil
+			}
+			res, err := r.stmtExec(ctx, tx,
+				`UPDATE jobs SET status = ?, attempts = 0, error = NULL, not_before = 0, updated_at = ?
+				 WHERE id = ? AND status = ?`,
+				domain.StatusPending, r.clock.Now(), id, domain.StatusFailed,
+			)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil || n == 0 {
+				return err
+			}
+			requeued = true
+			_, err = r.stmtExec(ctx, tx, `UPDATE outage_jobs SET requeues = requeues + 1 WHERE job_id = ?`, id)
+			return err
+		})
+	})
+	return requeued, err
+}
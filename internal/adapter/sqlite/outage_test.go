@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_OutageFailed(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	fail := func(id int64) {
+		t.Helper()
+		if err := repo.Claim(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Fail(ctx, id, "dial tcp: connection refused"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ids []int64
+	for _, url := range []string{"https://a.example/1", "https://b.example/2", "https://c.example/3"} {
+		job, err := repo.Create(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fail(job.ID)
+		ids = append(ids, job.ID)
+	}
+	if err := repo.ListOutageFailed(ctx, []int64{ids[2], ids[0]}); err != nil {
+		t.Fatalf("ListOutageFailed() error = %v", err)
+	}
+	if err := repo.ListOutageFailed(ctx, ids[:2]); err != nil {
+		t.Fatalf("ListOutageFailed() error = %v", err)
+	}
+	listed, err := repo.OutageFailed(ctx)
+	if err != nil || !slices.Equal(listed, ids) {
+		t.Fatalf("OutageFailed() = %v, %v; want %v", listed, err, ids)
+	}
+
+	// Requeued once, then taken off the list
+	requeued, err := repo.RequeueOutageFailed(ctx, ids[0], 1)
+	if err != nil || !requeued {
+		t.Fatalf("RequeueOutageFailed() = %v, %v; want requeued", requeued, err)
+	}
+	if job, _ := repo.Get(ctx, ids[0]); job.Status != domain.StatusPending || job.Attempts != 0 {
+		t.Errorf("job = %s with %d attempt(s), want pending with none", job.Status, job.Attempts)
+	}
+	if requeued, err = repo.RequeueOutageFailed(ctx, ids[0], 1); err != nil || requeued {
+		t.Errorf("RequeueOutageFailed(unlisted) = %v, %v; want nothing done", requeued, err)
+	}
+
+	// After max outages, a job is left failed
+	fail(ids[0])
+	repo.ListOutageFailed(ctx, ids[:1])
+	if requeued, err = repo.RequeueOutageFailed(ctx, ids[0], 1); err != nil || requeued {
+		t.Errorf("RequeueOutageFailed(over max) = %v, %v; want it left", requeued, err)
+	}
+	if job, _ := repo.Get(ctx, ids[0]); job.Status != domain.StatusFailed {
+		t.Errorf("job = %s, want failed", job.Status)
+	}
+
+	// A job requeued by hand meanwhile is left alone
+	repo.RequeueFailed(ctx, domain.BulkFilter{Host: "b.example"})
+	repo.Claim(ctx, ids[1])
+	if requeued, err = repo.RequeueOutageFailed(ctx, ids[1], 1); err != nil || requeued {
+		t.Errorf("RequeueOutageFailed(processing) = %v, %v; want it left", requeued, err)
+	}
+	if job, _ := repo.Get(ctx, ids[1]); job.Status != domain.StatusProcessing {
+		t.Errorf("job = %s, want processing", job.Status)
+	}
+
+	listed, err = repo.OutageFailed(ctx)
+	if err != nil || !slices.Equal(listed, ids[2:]) {
+		t.Errorf("OutageFailed() = %v, %v; want %v", listed, err, ids[2:])
+	}
+}
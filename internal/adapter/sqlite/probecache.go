@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CachedProbe implements domain.ProbeCache. Keys hold URLs, so they are
+// stored as lookup hashes, like the URLs of jobs.
+func (r *Repository) CachedProbe(ctx context.Context, key string, maxAge time.Duration) ([]byte, bool, error) {
+	var info string
+	err := r.retry(ctx, "cached_probe", func() error {
+		return r.stmtQueryRow(ctx, nil,
+			`SELECT info FROM probe_cache WHERE key = ? AND probed_at >= ?`,
+			r.lookupHash(key), r.clock.Now().Add(-maxAge).UnixMilli(),
+		).Scan(&info)
+	})
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if info, err = r.decrypt(info); err != nil {
+		return nil, false, err
+	}
+	return []byte(info), true, nil
+}
+
+// CacheProbe implements domain.ProbeCache.
+func (r *Repository) CacheProbe(ctx context.Context, key string, data []byte) error {
+	return r.retry(ctx, "cache_probe", func() error {
+		_, err := r.stmtExec(ctx, nil,
+			`INSERT INTO probe_cache (key, info, probed_at) VALUES (?, ?, ?)
+			 ON CONFLICT (key) DO UPDATE SET info = excluded.info, probed_at = excluded.probed_at`,
+			r.lookupHash(key), r.encrypt(string(data)), r.clock.Now().UnixMilli(),
+		)
+		return err
+	})
+}
+
+// PruneProbes implements domain.ProbeCache.
+func (r *Repository) PruneProbes(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := r.retry(ctx, "prune_probes", func() error {
+		res, err := r.stmtExec(ctx, nil, `DELETE FROM probe_cache WHERE probed_at < ?`, before.UnixMilli())
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n, err
+}
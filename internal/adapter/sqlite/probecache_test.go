@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_ProbeCache(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo.SetClock(clock)
+	if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+		t.Fatal(err)
+	}
+	key := "yt https://example.com/secret-video"
+
+	if _, ok, err := repo.CachedProbe(ctx, key, time.Hour); ok || err != nil {
+		t.Fatalf("CachedProbe() before caching = %v, %v, want a miss", ok, err)
+	}
+	if err := repo.CacheProbe(ctx, key, []byte(`{"title":"old"}`)); err != nil {
+		t.Fatalf("CacheProbe() error = %v", err)
+	}
+	clock.Advance(time.Minute)
+	if err := repo.CacheProbe(ctx, key, []byte(`{"title":"new"}`)); err != nil {
+		t.Fatalf("CacheProbe() error = %v", err)
+	}
+	data, ok, err := repo.CachedProbe(ctx, key, time.Hour)
+	if err != nil || !ok || string(data) != `{"title":"new"}` {
+		t.Errorf("CachedProbe() = %s, %v, %v, want the latest result", data, ok, err)
+	}
+
+	// Neither the URL nor the result are stored readable
+	var storedKey, info string
+	repo.db.QueryRow(`SELECT key, info FROM probe_cache`).Scan(&storedKey, &info)
+	if strings.Contains(storedKey, "secret") || strings.Contains(info, "new") {
+		t.Errorf("stored %q, %q in plaintext", storedKey, info)
+	}
+
+	clock.Advance(time.Hour + time.Second)
+	if _, ok, _ := repo.CachedProbe(ctx, key, time.Hour); ok {
+		t.Error("CachedProbe() returned a result older than maxAge")
+	}
+	repo.CacheProbe(ctx, "yt https://example.com/fresh", []byte(`{}`))
+	n, err := repo.PruneProbes(ctx, clock.Now().Add(-time.Hour))
+	if err != nil || n != 1 {
+		t.Errorf("PruneProbes() = %d, %v, want 1", n, err)
+	}
+	if _, ok, _ := repo.CachedProbe(ctx, "yt https://example.com/fresh", time.Hour); !ok {
+		t.Error("PruneProbes() removed a fresh result")
+	}
+}
+
+func TestRepository_ProbeCacheKeyed(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	if err := repo.Unlock(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	key := "yt https://example.com/secret-video"
+	if err := repo.CacheProbe(ctx, key, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Setting a key drops results cached under the plain hash
+	if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	repo.db.QueryRow(`SELECT COUNT(*) FROM probe_cache`).Scan(&n)
+	if n != 0 {
+		t.Errorf("%d cached probes left after setting a key, want none", n)
+	}
+
+	// Cached again, the key is an HMAC, which can't be matched by hashing
+	// a guessed URL
+	if err := repo.CacheProbe(ctx, key, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(key))
+	var stored string
+	repo.db.QueryRow(`SELECT key FROM probe_cache`).Scan(&stored)
+	if stored == "" || stored == hex.EncodeToString(sum[:]) {
+		t.Errorf("stored key = %q, want an HMAC of the key", stored)
+	}
+	if _, ok, err := repo.CachedProbe(ctx, key, time.Hour); !ok || err != nil {
+		t.Errorf("CachedProbe() = %v, %v, want a hit", ok, err)
+	}
+}
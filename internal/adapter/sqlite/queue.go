@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// FindPendingIn implements domain.QueueRepository.
+func (r *Repository) FindPendingIn(ctx context.Context, queue string, active []string, limit int) ([]domain.Job, error) {
+	query, args := pendingInQuery(queue, active, limit, r.clock.Now())
+	return r.queryJobs(ctx, "find_pending_in", query, args...)
+}
+
+// pendingInQuery returns the query and arguments for FindPendingIn, with
+// jobs due by now.
+func pendingInQuery(queue string, active []string, limit int, now time.Time) (string, []any) {
+	filter, args := `queue = ?`, []any{queue}
+	if queue == domain.DefaultQueue && len(active) > 0 {
+		filter = `queue NOT IN (?` + strings.Repeat(`, ?`, len(active)-1) + `)`
+		args = args[:0]
+		for _, q := range active {
+			args = append(args, q)
+		}
+	}
+	args = append([]any{domain.StatusPending, now.UnixMilli()}, append(args, limit)...)
+	return pendingQuery(" AND " + filter), args
+}
+
+// PendingByQueue implements domain.QueueRepository.
+func (r *Repository) PendingByQueue(ctx context.Context) (map[string]int64, error) {
+	var counts map[string]int64
+	err := r.retry(ctx, "pending_by_queue", func() error {
+		rows, err := r.stmtQuery(ctx, nil, `SELECT queue, COUNT(*) FROM jobs WHERE status = ? GROUP BY queue`, domain.StatusPending)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		counts = make(map[string]int64)
+		for rows.Next() {
+			var queue string
+			var n int64
+			if err := rows.Scan(&queue, &n); err != nil {
+				return err
+			}
+			counts[queue] = n
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
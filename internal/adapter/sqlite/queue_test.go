@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_Queues(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	podcast := domain.WithQueue(ctx, "podcast")
+	first, _ := repo.Create(podcast, "https://example.com/ep1")
+	repo.Create(podcast, "https://example.com/ep2")
+	clip, _ := repo.Create(ctx, "https://example.com/clip")
+	stale, _ := repo.Create(domain.WithQueue(ctx, "removed"), "https://example.com/old")
+	repo.CreateHeld(podcast, "https://example.com/ep3")
+
+	got, err := repo.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Queue != "podcast" {
+		t.Errorf("Queue = %q, want podcast", got.Queue)
+	}
+
+	tests := []struct {
+		name   string
+		queue  string
+		active []string
+		want   []int64
+	}{
+		{"own queue", "podcast", []string{"podcast"}, []int64{first.ID, first.ID + 1}},
+		{"default with inactive queues", domain.DefaultQueue, []string{"podcast"}, []int64{clip.ID, stale.ID}},
+		{"default only", domain.DefaultQueue, nil, []int64{clip.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := repo.FindPendingIn(ctx, tt.queue, tt.active, 10)
+			if err != nil {
+				t.Fatalf("FindPendingIn() error = %v", err)
+			}
+			var ids []int64
+			for _, j := range jobs {
+				ids = append(ids, j.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("FindPendingIn() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	counts, err := repo.PendingByQueue(ctx)
+	if err != nil {
+		t.Fatalf("PendingByQueue() error = %v", err)
+	}
+	if want := map[string]int64{"podcast": 3, "": 1, "removed": 1}; !maps.Equal(counts, want) {
+		t.Errorf("PendingByQueue() = %v, want %v", counts, want)
+	}
+}
+
+func TestRepository_FindPendingIn_Plan(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	seedJobs(t, repo, 1000)
+
+	tests := []struct {
+		name   string
+		queue  string
+		active []string
+		search string
+	}{
+		{"own queue", "podcasts", []string{"podcasts"}, "SEARCH jobs USING COVERING INDEX idx_jobs_queue (status=? AND queue=? AND held=?)"},
+		{"default queue", domain.DefaultQueue, []string{"podcasts"}, "SEARCH jobs USING COVERING INDEX idx_jobs_pending (status=? AND held=?)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := pendingInQuery(tt.queue, tt.active, 10, time.Now())
+			assertPendingPlan(t, queryPlan(t, repo, query, args...), tt.search)
+		})
+	}
+
+	plan := queryPlan(t, repo, `SELECT queue, COUNT(*) FROM jobs WHERE status = ? GROUP BY queue`, domain.StatusPending)
+	if want := "SEARCH jobs USING COVERING INDEX idx_jobs_queue (status=?)"; !slices.Contains(plan, want) {
+		t.Errorf("PendingByQueue plan %q lacks %q", plan, want)
+	}
+}
+
+func BenchmarkRepository_FindPendingIn(b *testing.B) {
+	for _, n := range []int{10_000, 200_000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			repo, cleanup := setupTestRepo(b)
+			defer cleanup()
+			seedJobs(b, repo, n)
+			ctx := context.Background()
+			active := []string{"podcasts"}
+
+			for b.Loop() {
+				for _, queue := range []string{"podcasts", domain.DefaultQueue} {
+					if _, err := repo.FindPendingIn(ctx, queue, active, 10); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
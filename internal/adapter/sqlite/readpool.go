@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// EnableWAL switches the database to WAL mode, in which readers and a
+// writer don't block each other, and which replicators like Litestream
+// need. The mode is kept in the database file.
+func (r *Repository) EnableWAL() error {
+	var mode string
+	if err := r.db.QueryRow(`PRAGMA journal_mode = WAL`).Scan(&mode); err != nil {
+		return err
+	}
+	if mode != "wal" {
+		return fmt.Errorf("journal mode is %s, want wal", mode)
+	}
+	return nil
+}
+
+// OpenReadPool opens a second pool of up to size read-only connections for
+// listings, status counts, and stats, so heavy dashboard queries don't
+// queue behind the worker's writes. It enables WAL mode first. Call once,
+// before serving requests.
+func (r *Repository) OpenReadPool(size int) error {
+	if r.keep != nil {
+		return fmt.Errorf("in-memory databases have no read pool")
+	}
+	if err := r.EnableWAL(); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite", "file:"+r.path+"?_pragma=query_only(1)")
+	if err != nil {
+		return err
+	}
+	db.SetMaxOpenConns(size)
+	db.SetMaxIdleConns(size)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+	r.reads = newStmtCache(db)
+	return nil
+}
+
+// readQuery runs query as a cached statement on the read pool, or on the
+// main pool if none is open.
+func (r *Repository) readQuery(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if r.reads == nil {
+		return r.stmtQuery(ctx, nil, query, args...)
+	}
+	s, err := r.reads.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return s.QueryContext(ctx, args...)
+}
@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_ReadPool(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.OpenReadPool(2); err != nil {
+		t.Fatalf("OpenReadPool() error = %v", err)
+	}
+	repo.Create(ctx, "https://example.com/a")
+
+	// A write transaction left open doesn't hold up reads, which see the
+	// last committed state
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO jobs (url, status) VALUES ('uncommitted', 'pending')`); err != nil {
+		t.Fatal(err)
+	}
+	readCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	jobs, err := repo.List(readCtx, domain.JobFilter{Limit: 10})
+	if err != nil || len(jobs) != 1 || jobs[0].URL != "https://example.com/a" {
+		t.Fatalf("List() = %v, %v, want the committed job", jobs, err)
+	}
+	counts, err := repo.CountByStatus(readCtx)
+	if err != nil || counts[domain.StatusPending] != 1 {
+		t.Errorf("CountByStatus() = %v, %v, want 1 pending", counts, err)
+	}
+	buckets, err := repo.Stats(readCtx, domain.StatsQuery{Period: domain.PeriodHour})
+	if err != nil || len(buckets) != 1 || buckets[0].Submitted != 1 {
+		t.Errorf("Stats() = %v, %v, want 1 submission", buckets, err)
+	}
+	// Nor does a read in progress hold up the commit
+	rows, err := repo.readQuery(ctx, `SELECT id FROM jobs`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Next()
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() during a read error = %v", err)
+	}
+	rows.Close()
+
+	if jobs, _ := repo.List(ctx, domain.JobFilter{Limit: 10}); len(jobs) != 2 {
+		t.Errorf("List() after commit = %d job(s), want 2", len(jobs))
+	}
+	if _, err := repo.reads.db.Exec(`DELETE FROM jobs`); err == nil {
+		t.Error("write on the read pool succeeded")
+	}
+}
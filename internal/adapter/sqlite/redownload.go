@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// ActiveRedownload implements domain.RedownloadFinder.
+func (r *Repository) ActiveRedownload(ctx context.Context, id int64) (*domain.Job, error) {
+	var found *domain.Job
+	err := r.retry(ctx, "active_redownload", func() error {
+		job, err := r.scanJob(r.stmtQueryRow(ctx, nil,
+			`SELECT id, url, original_url, status, attempts, COALESCE(error, ''), title, bytes, duration_ms, held, approved, not_before, queue, source, uid, notes, bookmark, redownload_of, user_agent, created_at, updated_at, completed_at
+			 FROM jobs WHERE redownload_of = ? AND status IN (?, ?) ORDER BY id DESC LIMIT 1`,
+			id, domain.StatusPending, domain.StatusProcessing,
+		))
+		if errors.Is(err, domain.ErrJobNotFound) {
+			found = nil
+			return nil
+		}
+		found = job
+		return err
+	})
+	return found, err
+}
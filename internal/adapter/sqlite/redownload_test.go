@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_ActiveRedownload(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	orig, _ := repo.Create(ctx, "https://example.com/v")
+	repo.Claim(ctx, orig.ID)
+	repo.Complete(ctx, orig.ID, domain.Completion{})
+
+	if got, err := repo.ActiveRedownload(ctx, orig.ID); err != nil || got != nil {
+		t.Fatalf("ActiveRedownload() before any clone = %v, %v, want nil", got, err)
+	}
+
+	clone, err := repo.Create(domain.WithRedownloadOf(ctx, orig.ID), orig.URL)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if clone.RedownloadOf != orig.ID {
+		t.Errorf("Create() RedownloadOf = %d, want %d", clone.RedownloadOf, orig.ID)
+	}
+	if got, _ := repo.Get(ctx, clone.ID); got.RedownloadOf != orig.ID {
+		t.Errorf("Get() RedownloadOf = %d, want %d", got.RedownloadOf, orig.ID)
+	}
+	if got, _ := repo.Get(ctx, orig.ID); got.RedownloadOf != 0 {
+		t.Errorf("Get() of the original RedownloadOf = %d, want 0", got.RedownloadOf)
+	}
+
+	for _, claim := range []bool{false, true} {
+		if claim {
+			repo.Claim(ctx, clone.ID)
+		}
+		got, err := repo.ActiveRedownload(ctx, orig.ID)
+		if err != nil || got == nil || got.ID != clone.ID {
+			t.Fatalf("ActiveRedownload() (claimed %v) = %v, %v, want job %d", claim, got, err, clone.ID)
+		}
+	}
+
+	repo.Complete(ctx, clone.ID, domain.Completion{})
+	if got, err := repo.ActiveRedownload(ctx, orig.ID); err != nil || got != nil {
+		t.Errorf("ActiveRedownload() after the clone completed = %v, %v, want nil", got, err)
+	}
+	if got, err := repo.ActiveRedownload(ctx, clone.ID); err != nil || got != nil {
+		t.Errorf("ActiveRedownload() of a job without clones = %v, %v, want nil", got, err)
+	}
+}
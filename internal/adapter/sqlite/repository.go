@@ -3,8 +3,11 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cwygoda/catcher/internal/domain"
@@ -22,11 +25,28 @@ CREATE TABLE IF NOT EXISTS jobs (
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+CREATE TABLE IF NOT EXISTS job_stats (
+    period    TEXT NOT NULL,
+    bucket    TEXT NOT NULL,
+    submitted INTEGER NOT NULL DEFAULT 0,
+    completed INTEGER NOT NULL DEFAULT 0,
+    failed    INTEGER NOT NULL DEFAULT 0,
+    bytes     INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (period, bucket)
+);
 `
 
 // Repository implements domain.JobRepository using SQLite.
 type Repository struct {
-	db *sql.DB
+	db       *sql.DB
+	path     string
+	stmts    *stmtCache
+	reads    *stmtCache   // read-only pool for heavy queries, if opened
+	cipher   *fieldCipher // nil unless Unlock was given a key
+	policy   RetryPolicy
+	observer RetryObserver
+	clock    domain.Clock
+	keep     *sql.Conn // holds an in-memory database open, if NewMemory
 }
 
 // New creates a new SQLite repository, initializing the schema if needed.
@@ -41,87 +61,365 @@ func New(dbPath string) (*Repository, error) {
 	if err != nil {
 		return nil, err
 	}
+	return open(db, dbPath)
+}
 
-	// Initialize schema
+// NewMemory creates a repository on a fresh in-memory database, for tests
+// and throwaway instances. Its connections share the database, which is
+// dropped on Close. name tells databases open at once apart. It has no
+// read pool.
+func NewMemory(name string) (*Repository, error) {
+	db, err := sql.Open("sqlite", "file:"+url.PathEscape(name)+"?mode=memory&cache=shared")
+	if err != nil {
+		return nil, err
+	}
+	// The database goes away with its last connection, which the pool
+	// may close when idle
+	keep, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	r, err := open(db, "")
+	if err != nil {
+		keep.Close()
+		return nil, err
+	}
+	r.keep = keep
+	return r, nil
+}
+
+// open initializes the schema of db and returns a repository using it. It
+// closes db on failure.
+func open(db *sql.DB, path string) (*Repository, error) {
 	if _, err := db.Exec(schema); err != nil {
 		db.Close()
 		return nil, err
 	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Repository{db: db, path: path, stmts: newStmtCache(db), policy: DefaultRetryPolicy(), clock: domain.SystemClock}, nil
+}
 
-	return &Repository{db: db}, nil
+// SetClock makes timestamps, retry delays, and cooldown expiry read time
+// from c.
+func (r *Repository) SetClock(c domain.Clock) {
+	r.clock = c
 }
 
 // Close closes the database connection.
 func (r *Repository) Close() error {
+	r.stmts.close()
+	if r.reads != nil {
+		r.reads.close()
+		r.reads.db.Close()
+	}
+	if r.keep != nil {
+		r.keep.Close()
+	}
 	return r.db.Close()
 }
 
 // Create inserts a new job.
 func (r *Repository) Create(ctx context.Context, url string) (*domain.Job, error) {
-	now := time.Now()
-	result, err := r.db.ExecContext(ctx,
-		`INSERT INTO jobs (url, status, created_at, updated_at) VALUES (?, ?, ?, ?)`,
-		url, domain.StatusPending, now, now,
-	)
-	if err != nil {
-		return nil, err
-	}
+	return r.create(ctx, url, domain.StatusPending, false)
+}
+
+// CreateHeld implements domain.JobHolder.
+func (r *Repository) CreateHeld(ctx context.Context, url string) (*domain.Job, error) {
+	return r.create(ctx, url, domain.StatusPending, true)
+}
 
-	id, err := result.LastInsertId()
+// CreateForApproval implements domain.ApprovalRepository.
+func (r *Repository) CreateForApproval(ctx context.Context, url string) (*domain.Job, error) {
+	return r.create(ctx, url, domain.StatusNeedsApproval, false)
+}
+
+func (r *Repository) create(ctx context.Context, url string, status domain.JobStatus, held bool) (*domain.Job, error) {
+	now := r.clock.Now()
+	original, queue, source, notes := domain.OriginalURLFrom(ctx), domain.QueueFrom(ctx), domain.SourceFrom(ctx), domain.NotesFrom(ctx)
+	bookmark, redownloadOf, userAgent := domain.BookmarkFrom(ctx), domain.RedownloadOfFrom(ctx), domain.UserAgentFrom(ctx)
+	uid := domain.NewUID()
+	var id int64
+	err := r.retry(ctx, "create", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			result, err := r.stmtExec(ctx, tx,
+				`INSERT INTO jobs (uid, url, url_hash, original_url, status, held, queue, source, notes, bookmark, redownload_of, user_agent, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				uid, r.encrypt(url), r.lookupHash(url), r.encrypt(original), status, held, queue, source, r.encrypt(notes), bookmark, redownloadOf, userAgent, now, now,
+			)
+			if err != nil {
+				return err
+			}
+			if id, err = result.LastInsertId(); err != nil {
+				return err
+			}
+			return r.addStats(ctx, tx, domain.PeriodHour, domain.StatsBucket{Start: now, Submitted: 1})
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &domain.Job{
-		ID:        id,
-		URL:       url,
-		Status:    domain.StatusPending,
-		Attempts:  0,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:           id,
+		UID:          uid,
+		URL:          url,
+		OriginalURL:  original,
+		Status:       status,
+		Attempts:     0,
+		Held:         held,
+		Queue:        queue,
+		Source:       source,
+		Notes:        notes,
+		Bookmark:     bookmark,
+		RedownloadOf: redownloadOf,
+		UserAgent:    userAgent,
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}, nil
 }
 
-// Get retrieves a job by ID.
+// Get retrieves a job by ID, with its result files.
 func (r *Repository) Get(ctx context.Context, id int64) (*domain.Job, error) {
-	row := r.db.QueryRowContext(ctx,
-		`SELECT id, url, status, attempts, COALESCE(error, ''), created_at, updated_at
-		 FROM jobs WHERE id = ?`, id,
-	)
-	return scanJob(row)
+	var job *domain.Job
+	err := r.retry(ctx, "get", func() error {
+		var err error
+		job, err = r.scanJob(r.stmtQueryRow(ctx, nil,
+			`SELECT id, url, original_url, status, attempts, COALESCE(error, ''), title, bytes, duration_ms, held, approved, not_before, queue, source, uid, notes, bookmark, redownload_of, user_agent, created_at, updated_at, completed_at
+			 FROM jobs WHERE id = ?`, id,
+		))
+		if err != nil {
+			return err
+		}
+		job.Files, err = r.results(ctx, id)
+		return err
+	})
+	return job, err
 }
 
-// FindPending returns pending jobs up to limit.
-func (r *Repository) FindPending(ctx context.Context, limit int) ([]domain.Job, error) {
-	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, url, status, attempts, COALESCE(error, ''), created_at, updated_at
-		 FROM jobs WHERE status = ? ORDER BY created_at ASC LIMIT ?`,
-		domain.StatusPending, limit,
+// ResolveUID implements domain.UIDResolver.
+func (r *Repository) ResolveUID(ctx context.Context, uid string) (int64, error) {
+	var id int64
+	err := r.retry(ctx, "resolve_uid", func() error {
+		return r.stmtQueryRow(ctx, nil, `SELECT id FROM jobs WHERE uid = ?`, uid).Scan(&id)
+	})
+	if err == sql.ErrNoRows {
+		return 0, domain.ErrJobNotFound
+	}
+	return id, err
+}
+
+// results returns the files recorded for a job when it completed.
+func (r *Repository) results(ctx context.Context, jobID int64) ([]domain.ResultFile, error) {
+	rows, err := r.stmtQuery(ctx, nil,
+		`SELECT path, bytes, sha256, library FROM job_results WHERE job_id = ? ORDER BY id ASC`, jobID,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var jobs []domain.Job
+	var files []domain.ResultFile
 	for rows.Next() {
-		var job domain.Job
-		var status string
-		if err := rows.Scan(&job.ID, &job.URL, &status, &job.Attempts, &job.Error, &job.CreatedAt, &job.UpdatedAt); err != nil {
+		var f domain.ResultFile
+		if err := rows.Scan(&f.Path, &f.Bytes, &f.SHA256, &f.Library); err != nil {
 			return nil, err
 		}
-		job.Status = domain.JobStatus(status)
-		jobs = append(jobs, job)
+		if f.Path, err = r.decrypt(f.Path); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// FindPending returns pending jobs that are due, up to limit.
+func (r *Repository) FindPending(ctx context.Context, limit int) ([]domain.Job, error) {
+	return r.queryJobs(ctx, "find_pending", pendingQuery(""), domain.StatusPending, r.clock.Now().UnixMilli(), limit)
+}
+
+// pendingQuery selects due pending jobs, further limited by filter, taking
+// each source's oldest in turn. The turns are ranked by ID alone, from
+// idx_jobs_pending or idx_jobs_queue, and only the jobs taken are read. Its
+// arguments are the pending status, the current time in unix millis,
+// filter's arguments, and the limit.
+func pendingQuery(filter string) string {
+	return `SELECT id, url, original_url, status, attempts, COALESCE(error, ''), title, bytes, duration_ms, held, approved, not_before, queue, source, uid, notes, bookmark, redownload_of, user_agent, created_at, updated_at, completed_at
+		 FROM jobs JOIN (
+		     SELECT id AS due, created_at AS due_at, ROW_NUMBER() OVER (PARTITION BY source ORDER BY created_at, id) AS turn
+		     FROM jobs WHERE status = ? AND held = 0 AND not_before <= ?` + filter + `
+		     ORDER BY turn, due_at, due LIMIT ?
+		 ) ON id = due ORDER BY turn, due_at, due`
+}
+
+// FindRecent implements domain.DuplicateFinder. URLs may be encrypted, so
+// jobs are found by their URL hash.
+func (r *Repository) FindRecent(ctx context.Context, url string, since time.Time) (*domain.Job, error) {
+	found, err := r.findByURL(ctx, "find_recent",
+		`url_hash = ? AND status != ?`, r.lookupHash(url), domain.StatusFailed,
+	)
+	if err != nil || found == nil || found.CreatedAt.Before(since) {
+		return nil, err
+	}
+	return found, nil
+}
+
+// LastCompleted implements domain.CompletedFinder. Like FindRecent, it
+// finds jobs by their URL hash.
+func (r *Repository) LastCompleted(ctx context.Context, url string, beforeID int64) (*domain.Job, error) {
+	found, err := r.findByURL(ctx, "last_completed",
+		`url_hash = ? AND status = ? AND (? = 0 OR id < ?)`,
+		r.lookupHash(url), domain.StatusCompleted, beforeID, beforeID,
+	)
+	if err != nil || found == nil {
+		return found, err
+	}
+	if found.Files, err = r.results(ctx, found.ID); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// findByURL returns the newest job matching where, or nil if there is
+// none. where must filter on url_hash, which the index leads with.
+func (r *Repository) findByURL(ctx context.Context, op, where string, args ...any) (*domain.Job, error) {
+	var found *domain.Job
+	err := r.retry(ctx, op, func() error {
+		job, err := r.scanJob(r.stmtQueryRow(ctx, nil,
+			`SELECT id, url, original_url, status, attempts, COALESCE(error, ''), title, bytes, duration_ms, held, approved, not_before, queue, source, uid, notes, bookmark, redownload_of, user_agent, created_at, updated_at, completed_at
+			 FROM jobs WHERE `+where+` ORDER BY id DESC LIMIT 1`,
+			args...,
+		))
+		if errors.Is(err, domain.ErrJobNotFound) {
+			found = nil
+			return nil
+		}
+		found = job
+		return err
+	})
+	return found, err
+}
+
+// List returns jobs matching the filter, newest first.
+func (r *Repository) List(ctx context.Context, filter domain.JobFilter) ([]domain.Job, error) {
+	query, args := listQuery(filter)
+	var match func(*domain.Job) bool
+	if filter.Host != "" {
+		// URLs may be encrypted, so hosts are matched here rather than in SQL
+		host := domain.BulkFilter{Host: filter.Host}
+		match = func(job *domain.Job) bool { return host.Match(job.URL) }
+	}
+	return r.collectMatching(ctx, "list", func() (*sql.Rows, error) {
+		return r.readQuery(ctx, query, args...)
+	}, match, filter.Limit)
+}
+
+// listQuery builds the query for List.
+func listQuery(filter domain.JobFilter) (string, []any) {
+	query := `SELECT id, url, original_url, status, attempts, COALESCE(error, ''), title, bytes, duration_ms, held, approved, not_before, queue, source, uid, notes, bookmark, redownload_of, user_agent, created_at, updated_at, completed_at FROM jobs`
+	var conds []string
+	var args []any
+	if filter.Status != "" {
+		conds = append(conds, `status = ?`)
+		args = append(args, filter.Status)
+	}
+	if filter.After != 0 {
+		// Order against the cursor job's stored created_at; if it has been
+		// deleted since, IDs follow creation order closely enough
+		conds = append(conds, `COALESCE((created_at, id) < ((SELECT created_at FROM jobs WHERE id = ?), ?), id < ?)`)
+		args = append(args, filter.After, filter.After, filter.After)
+	}
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, ` AND `)
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	if filter.Host != "" {
+		// List stops reading once it has matched enough
+		return query, append(args, -1)
+	}
+	return query, append(args, filter.Limit)
+}
+
+// queryJobs runs a jobs query, retrying it whole on transient errors.
+func (r *Repository) queryJobs(ctx context.Context, op, query string, args ...any) ([]domain.Job, error) {
+	return r.collectJobs(ctx, op, func() (*sql.Rows, error) {
+		return r.stmtQuery(ctx, nil, query, args...)
+	})
+}
+
+// collectJobs scans the jobs query returns, retrying it whole on transient
+// errors.
+func (r *Repository) collectJobs(ctx context.Context, op string, query func() (*sql.Rows, error)) ([]domain.Job, error) {
+	return r.collectMatching(ctx, op, query, nil, 0)
+}
+
+// collectMatching is collectJobs keeping only the jobs match accepts, if
+// match is set, and stopping once it has limit of them, if limit is
+// positive.
+func (r *Repository) collectMatching(ctx context.Context, op string, query func() (*sql.Rows, error), match func(*domain.Job) bool, limit int) ([]domain.Job, error) {
+	var jobs []domain.Job
+	err := r.retry(ctx, op, func() error {
+		rows, err := query()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		jobs = nil
+		for rows.Next() && (limit <= 0 || len(jobs) < limit) {
+			job, err := r.scanJob(rows)
+			if err != nil {
+				return err
+			}
+			if match == nil || match(job) {
+				jobs = append(jobs, *job)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
-	return jobs, rows.Err()
+	return jobs, nil
 }
 
-// Claim atomically claims a pending job for processing.
+// CountByStatus implements domain.JobCounter.
+func (r *Repository) CountByStatus(ctx context.Context) (map[domain.JobStatus]int64, error) {
+	var counts map[domain.JobStatus]int64
+	err := r.retry(ctx, "count_by_status", func() error {
+		rows, err := r.readQuery(ctx, `SELECT status, COUNT(*) FROM jobs GROUP BY status`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		counts = make(map[domain.JobStatus]int64)
+		for rows.Next() {
+			var status domain.JobStatus
+			var n int64
+			if err := rows.Scan(&status, &n); err != nil {
+				return err
+			}
+			counts[status] = n
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// Claim atomically claims a pending job for processing. Held jobs can't
+// be claimed.
 func (r *Repository) Claim(ctx context.Context, id int64) error {
-	result, err := r.db.ExecContext(ctx,
+	result, err := r.exec(ctx, "claim",
 		`UPDATE jobs SET status = ?, attempts = attempts + 1, updated_at = ?
-		 WHERE id = ? AND status = ?`,
-		domain.StatusProcessing, time.Now(), id, domain.StatusPending,
+		 WHERE id = ? AND status = ? AND held = 0`,
+		domain.StatusProcessing, r.clock.Now(), id, domain.StatusPending,
 	)
 	if err != nil {
 		return err
@@ -137,39 +435,215 @@ func (r *Repository) Claim(ctx context.Context, id int64) error {
 	return nil
 }
 
-// Complete marks a job as completed.
-func (r *Repository) Complete(ctx context.Context, id int64) error {
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE jobs SET status = ?, updated_at = ? WHERE id = ?`,
-		domain.StatusCompleted, time.Now(), id,
+// Complete marks a job as completed, recording the bytes, files, and run
+// time it produced in the same transaction.
+func (r *Repository) Complete(ctx context.Context, id int64, c domain.Completion) error {
+	now := r.clock.Now()
+	return r.retry(ctx, "complete", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			result, err := r.stmtExec(ctx, tx,
+				`UPDATE jobs SET status = ?, title = ?, bytes = ?, duration_ms = ?, updated_at = ?, completed_at = ? WHERE id = ?`,
+				domain.StatusCompleted, r.encrypt(c.Title), c.Bytes, c.Duration.Milliseconds(), now, now, id,
+			)
+			if err != nil {
+				return err
+			}
+			if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+				return err
+			}
+			for _, table := range []string{"job_results", "library_files"} {
+				if _, err := r.stmtExec(ctx, tx, `DELETE FROM `+table+` WHERE job_id = ?`, id); err != nil {
+					return err
+				}
+			}
+			for _, f := range c.Files {
+				path := r.encrypt(f.Path)
+				if _, err := r.stmtExec(ctx, tx,
+					`INSERT INTO job_results (job_id, path, bytes, sha256, library) VALUES (?, ?, ?, ?, ?)`,
+					id, path, f.Bytes, f.SHA256, f.Library,
+				); err != nil {
+					return err
+				}
+				if f.Library {
+					continue // already the library's, not delivered by catcher
+				}
+				if _, err := r.stmtExec(ctx, tx,
+					`INSERT INTO library_files (job_id, url, url_hash, path, bytes, sha256, delivered_at)
+					 SELECT id, url, url_hash, ?, ?, ?, ? FROM jobs WHERE id = ?`,
+					path, f.Bytes, f.SHA256, now, id,
+				); err != nil {
+					return err
+				}
+			}
+			return r.addStats(ctx, tx, domain.PeriodHour, domain.StatsBucket{
+				Start: now, Processor: c.Processor, Completed: 1, Bytes: c.Bytes,
+			})
+		})
+	})
+}
+
+// CompleteManually implements domain.ManualCompleter. Jobs completed by
+// hand produced nothing through catcher, so they are left out of stats.
+func (r *Repository) CompleteManually(ctx context.Context, id int64) error {
+	now := r.clock.Now()
+	return r.transition(ctx, "complete_manually", id,
+		`UPDATE jobs SET status = ?, error = NULL, updated_at = ?, completed_at = ? WHERE id = ? AND status IN (?, ?, ?)`,
+		domain.StatusCompleted, now, now, id, domain.StatusPending, domain.StatusFailed, domain.StatusNeedsApproval,
+	)
+}
+
+// Approve implements domain.ApprovalRepository.
+func (r *Repository) Approve(ctx context.Context, id int64) error {
+	return r.transition(ctx, "approve", id,
+		`UPDATE jobs SET status = ?, approved = 1, error = NULL, updated_at = ? WHERE id = ? AND status = ?`,
+		domain.StatusPending, r.clock.Now(), id, domain.StatusNeedsApproval,
+	)
+}
+
+// RequestApproval implements domain.ApprovalRepository. The claim that
+// found the job needs approval is taken back off its attempts.
+func (r *Repository) RequestApproval(ctx context.Context, id int64, reason string) error {
+	return r.transition(ctx, "request_approval", id,
+		`UPDATE jobs SET status = ?, attempts = MAX(attempts - 1, 0), error = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		domain.StatusNeedsApproval, r.encrypt(reason), r.clock.Now(), id, domain.StatusProcessing,
+	)
+}
+
+// Reject implements domain.ApprovalRepository. Rejected jobs never ran, so
+// they are left out of the failure stats.
+func (r *Repository) Reject(ctx context.Context, id int64, reason string) error {
+	return r.transition(ctx, "reject", id,
+		`UPDATE jobs SET status = ?, error = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		domain.StatusFailed, r.encrypt(reason), r.clock.Now(), id, domain.StatusNeedsApproval,
 	)
-	return err
+}
+
+// transition runs update, which job id only matches in the states it may
+// leave, and tells a missing job (ErrJobNotFound) from one in another
+// state (ErrJobState).
+func (r *Repository) transition(ctx context.Context, op string, id int64, update string, args ...any) error {
+	return r.retry(ctx, op, func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			result, err := r.stmtExec(ctx, tx, update, args...)
+			if err != nil {
+				return err
+			}
+			affected, err := result.RowsAffected()
+			if err != nil || affected > 0 {
+				return err
+			}
+			var exists bool
+			if err := r.stmtQueryRow(ctx, tx, `SELECT EXISTS(SELECT 1 FROM jobs WHERE id = ?)`, id).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return domain.ErrJobNotFound
+			}
+			return domain.ErrJobState
+		})
+	})
+}
+
+// SetHeld implements domain.JobHolder.
+func (r *Repository) SetHeld(ctx context.Context, id int64, held bool) error {
+	return r.retry(ctx, "set_held", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			var status string
+			err := r.stmtQueryRow(ctx, tx, `SELECT status FROM jobs WHERE id = ?`, id).Scan(&status)
+			if err == sql.ErrNoRows {
+				return domain.ErrJobNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if domain.JobStatus(status) != domain.StatusPending {
+				return domain.ErrJobState
+			}
+			_, err = r.stmtExec(ctx, tx, `UPDATE jobs SET held = ?, updated_at = ? WHERE id = ?`, held, r.clock.Now(), id)
+			return err
+		})
+	})
+}
+
+// SetNotes replaces a job's notes.
+func (r *Repository) SetNotes(ctx context.Context, id int64, notes string) error {
+	return r.retry(ctx, "set_notes", func() error {
+		result, err := r.stmtExec(ctx, nil, `UPDATE jobs SET notes = ?, updated_at = ? WHERE id = ?`, r.encrypt(notes), r.clock.Now(), id)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return domain.ErrJobNotFound
+		}
+		return nil
+	})
 }
 
 // Fail marks a job as permanently failed.
 func (r *Repository) Fail(ctx context.Context, id int64, reason string) error {
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE jobs SET status = ?, error = ?, updated_at = ? WHERE id = ?`,
-		domain.StatusFailed, reason, time.Now(), id,
-	)
-	return err
+	now := r.clock.Now()
+	return r.retry(ctx, "fail", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			result, err := r.stmtExec(ctx, tx,
+				`UPDATE jobs SET status = ?, error = ?, updated_at = ? WHERE id = ?`,
+				domain.StatusFailed, r.encrypt(reason), now, id,
+			)
+			if err != nil {
+				return err
+			}
+			if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+				return err
+			}
+			return r.addStats(ctx, tx, domain.PeriodHour, domain.StatsBucket{Start: now, Failed: 1})
+		})
+	})
 }
 
 // Retry marks a job for retry (back to pending with error info).
 func (r *Repository) Retry(ctx context.Context, id int64, reason string) error {
-	_, err := r.db.ExecContext(ctx,
+	_, err := r.exec(ctx, "retry",
 		`UPDATE jobs SET status = ?, error = ?, updated_at = ? WHERE id = ?`,
-		domain.StatusPending, reason, time.Now(), id,
+		domain.StatusPending, r.encrypt(reason), r.clock.Now(), id,
+	)
+	return err
+}
+
+// RetryAt implements domain.RetryScheduler, keeping the job out of
+// FindPending until at.
+func (r *Repository) RetryAt(ctx context.Context, id int64, reason string, at time.Time) error {
+	_, err := r.exec(ctx, "retry",
+		`UPDATE jobs SET status = ?, error = ?, not_before = ?, updated_at = ? WHERE id = ?`,
+		domain.StatusPending, r.encrypt(reason), at.UnixMilli(), r.clock.Now(), id,
 	)
 	return err
 }
 
+// DelayPending implements domain.RetryScheduler.
+func (r *Repository) DelayPending(ctx context.Context, id int64, reason string, at time.Time) error {
+	return r.transition(ctx, "delay_pending", id,
+		`UPDATE jobs SET error = ?, not_before = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		r.encrypt(reason), at.UnixMilli(), r.clock.Now(), id, domain.StatusPending,
+	)
+}
+
+// Postpone implements domain.Postponer.
+func (r *Repository) Postpone(ctx context.Context, id int64, reason string, at time.Time) error {
+	return r.transition(ctx, "postpone", id,
+		`UPDATE jobs SET status = ?, attempts = MAX(attempts - 1, 0), error = ?, not_before = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		domain.StatusPending, r.encrypt(reason), at.UnixMilli(), r.clock.Now(), id, domain.StatusProcessing,
+	)
+}
+
 // RecoverStale resets all processing jobs back to pending (for crash recovery).
 func (r *Repository) RecoverStale(ctx context.Context) (int64, error) {
-	result, err := r.db.ExecContext(ctx,
-		`UPDATE jobs SET status = ?, error = 'recovered after crash', updated_at = ?
+	result, err := r.exec(ctx, "recover_stale",
+		`UPDATE jobs SET status = ?, error = ?, updated_at = ?
 		 WHERE status = ?`,
-		domain.StatusPending, time.Now(), domain.StatusProcessing,
+		domain.StatusPending, r.encrypt("recovered after crash"), r.clock.Now(), domain.StatusProcessing,
 	)
 	if err != nil {
 		return 0, err
@@ -177,20 +651,66 @@ func (r *Repository) RecoverStale(ctx context.Context) (int64, error) {
 	return result.RowsAffected()
 }
 
+// exec runs a single statement, retrying it on transient errors.
+func (r *Repository) exec(ctx context.Context, op, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := r.retry(ctx, op, func() error {
+		var err error
+		result, err = r.stmtExec(ctx, nil, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// withTx runs fn in a transaction, committing if it returns nil.
+func (r *Repository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 type scanner interface {
 	Scan(dest ...any) error
 }
 
-func scanJob(row scanner) (*domain.Job, error) {
+func (r *Repository) scanJob(row scanner) (*domain.Job, error) {
 	var job domain.Job
 	var status string
-	err := row.Scan(&job.ID, &job.URL, &status, &job.Attempts, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+	var durationMS, notBefore int64
+	var completedAt sql.NullTime
+	err := row.Scan(&job.ID, &job.URL, &job.OriginalURL, &status, &job.Attempts, &job.Error, &job.Title, &job.Bytes, &durationMS, &job.Held, &job.Approved, &notBefore, &job.Queue, &job.Source, &job.UID, &job.Notes, &job.Bookmark, &job.RedownloadOf, &job.UserAgent, &job.CreatedAt, &job.UpdatedAt, &completedAt)
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrJobNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
+	if job.URL, err = r.decrypt(job.URL); err != nil {
+		return nil, err
+	}
+	if job.OriginalURL, err = r.decrypt(job.OriginalURL); err != nil {
+		return nil, err
+	}
+	if job.Error, err = r.decrypt(job.Error); err != nil {
+		return nil, err
+	}
+	if job.Title, err = r.decrypt(job.Title); err != nil {
+		return nil, err
+	}
+	if job.Notes, err = r.decrypt(job.Notes); err != nil {
+		return nil, err
+	}
 	job.Status = domain.JobStatus(status)
+	job.Duration = time.Duration(durationMS) * time.Millisecond
+	if notBefore > 0 {
+		job.NotBefore = time.UnixMilli(notBefore)
+	}
+	job.CompletedAt = completedAt.Time
 	return &job, nil
 }
@@ -5,12 +5,17 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cwygoda/catcher/internal/domain"
 )
 
-func setupTestRepo(t *testing.T) (*Repository, func()) {
+func setupTestRepo(t testing.TB) (*Repository, func()) {
 	t.Helper()
 	dir := t.TempDir()
 	dbPath := filepath.Join(dir, "test.db")
@@ -27,6 +32,79 @@ func setupTestRepo(t *testing.T) (*Repository, func()) {
 	return repo, cleanup
 }
 
+// seedJobs inserts n jobs in one transaction, oldest first: one in a hundred
+// pending and the rest completed, spread over a few sources and queues.
+func seedJobs(tb testing.TB, repo *Repository, n int) {
+	tb.Helper()
+	tx, err := repo.db.Begin()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT INTO jobs (uid, url, status, queue, source, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer stmt.Close()
+
+	start := time.Now().Add(-time.Duration(n) * time.Second)
+	for i := range n {
+		status, queue := domain.StatusCompleted, domain.DefaultQueue
+		if i%100 == 0 {
+			status = domain.StatusPending
+		}
+		if i%3 == 0 {
+			queue = "podcasts"
+		}
+		at := start.Add(time.Duration(i) * time.Second)
+		if _, err := stmt.Exec(domain.NewUID(), "https://example.com/"+strconv.Itoa(i), status, queue, "user"+strconv.Itoa(i%5), at, at); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// queryPlan returns the EXPLAIN QUERY PLAN details of query.
+func queryPlan(t *testing.T, repo *Repository, query string, args ...any) []string {
+	t.Helper()
+	rows, err := repo.db.Query(`EXPLAIN QUERY PLAN `+query, args...)
+	if err != nil {
+		t.Fatalf("EXPLAIN error = %v", err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		plan = append(plan, detail)
+	}
+	return plan
+}
+
+// assertPendingPlan checks that a pending jobs query finds them with search,
+// from a covering index in each source's order, so the only sort is of
+// their turns.
+func assertPendingPlan(t *testing.T, plan []string, search string) {
+	t.Helper()
+	if !slices.Contains(plan, search) {
+		t.Errorf("plan %q lacks %q", plan, search)
+	}
+	sorts := 0
+	for _, step := range plan {
+		if strings.HasPrefix(step, "USE TEMP B-TREE") {
+			sorts++
+		}
+	}
+	if sorts != 1 {
+		t.Errorf("plan %q sorts %d time(s), want 1", plan, sorts)
+	}
+}
+
 func TestRepository_Create(t *testing.T) {
 	repo, cleanup := setupTestRepo(t)
 	defer cleanup()
@@ -106,6 +184,216 @@ func TestRepository_FindPending(t *testing.T) {
 	}
 }
 
+func TestRepository_FindPending_TakesSourcesInTurn(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	alice, bob := domain.WithSource(ctx, "alice"), domain.WithSource(ctx, "bob")
+	for i := 1; i <= 3; i++ {
+		repo.Create(alice, "https://example.com/playlist/"+strconv.Itoa(i))
+	}
+	repo.Create(bob, "https://example.com/urgent")
+	repo.Create(ctx, "https://example.com/anonymous")
+	want := []string{"playlist/1", "urgent", "anonymous", "playlist/2", "playlist/3"}
+
+	jobs, err := repo.FindPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("FindPending() error = %v", err)
+	}
+	var got []string
+	for _, job := range jobs {
+		got = append(got, strings.TrimPrefix(job.URL, "https://example.com/"))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindPending() order = %v, want %v", got, want)
+	}
+	if jobs[0].Source != "alice" || jobs[2].Source != "" {
+		t.Errorf("sources = %q, %q, want alice and none", jobs[0].Source, jobs[2].Source)
+	}
+}
+
+func TestRepository_FindPending_Plan(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	seedJobs(t, repo, 1000)
+
+	plan := queryPlan(t, repo, pendingQuery(""), domain.StatusPending, time.Now().UnixMilli(), 10)
+	assertPendingPlan(t, plan, "SEARCH jobs USING COVERING INDEX idx_jobs_pending (status=? AND held=?)")
+}
+
+func BenchmarkRepository_FindPending(b *testing.B) {
+	for _, n := range []int{10_000, 200_000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			repo, cleanup := setupTestRepo(b)
+			defer cleanup()
+			seedJobs(b, repo, n)
+			ctx := context.Background()
+
+			for b.Loop() {
+				if _, err := repo.FindPending(ctx, 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRepository_Get(b *testing.B) {
+	repo, cleanup := setupTestRepo(b)
+	defer cleanup()
+	seedJobs(b, repo, 1000)
+	ctx := context.Background()
+
+	for b.Loop() {
+		if _, err := repo.Get(ctx, 500); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRepository_Create(b *testing.B) {
+	repo, cleanup := setupTestRepo(b)
+	defer cleanup()
+	ctx := context.Background()
+
+	for b.Loop() {
+		if _, err := repo.Create(ctx, "https://example.com/video"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRepository_List(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	j1, _ := repo.Create(ctx, "https://example.com/1")
+	repo.Create(ctx, "https://example.com/2")
+	j3, _ := repo.Create(ctx, "https://example.com/3")
+	repo.Fail(ctx, j1.ID, "boom")
+
+	jobs, err := repo.List(ctx, domain.JobFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(jobs) != 3 {
+		t.Fatalf("List() returned %d jobs, want 3", len(jobs))
+	}
+	if jobs[0].ID != j3.ID {
+		t.Errorf("List() first job ID = %d, want %d (newest first)", jobs[0].ID, j3.ID)
+	}
+
+	failed, err := repo.List(ctx, domain.JobFilter{Status: domain.StatusFailed, Limit: 10})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(failed) != 1 || failed[0].ID != j1.ID {
+		t.Errorf("List(failed) = %v, want only job %d", failed, j1.ID)
+	}
+
+	limited, _ := repo.List(ctx, domain.JobFilter{Limit: 2})
+	if len(limited) != 2 {
+		t.Errorf("List(limit 2) returned %d jobs, want 2", len(limited))
+	}
+}
+
+func TestRepository_List_Cursor(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var want []int64
+	for i := range 5 {
+		job, _ := repo.Create(ctx, "https://example.com/"+strconv.Itoa(i))
+		want = append([]int64{job.ID}, want...)
+	}
+	// Jobs created in the same instant are ordered by ID
+	repo.db.Exec(`UPDATE jobs SET created_at = (SELECT created_at FROM jobs WHERE id = 2) WHERE id IN (3, 4)`)
+
+	var got []int64
+	var after int64
+	for page := 0; ; page++ {
+		jobs, err := repo.List(ctx, domain.JobFilter{Limit: 2, After: after})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(jobs) == 0 {
+			break
+		}
+		for _, job := range jobs {
+			got = append(got, job.ID)
+		}
+		after = jobs[len(jobs)-1].ID
+		// Jobs arriving mid-scroll belong before the first page
+		repo.Create(ctx, "https://example.com/new")
+		if page == 0 {
+			// The cursor job going away doesn't lose the place
+			repo.db.Exec(`DELETE FROM jobs WHERE id = ?`, after)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("paged IDs = %v, want %v", got, want)
+	}
+
+	failed, _ := repo.Create(ctx, "https://example.com/failed")
+	repo.Fail(ctx, failed.ID, "boom")
+	if jobs, _ := repo.List(ctx, domain.JobFilter{Status: domain.StatusFailed, Limit: 10, After: failed.ID}); len(jobs) != 0 {
+		t.Errorf("List(failed, after the only failed job) = %v, want none", jobs)
+	}
+}
+
+func TestRepository_List_Plan(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	seedJobs(t, repo, 1000)
+	if _, err := repo.db.Exec(`ANALYZE`); err != nil {
+		t.Fatal(err)
+	}
+
+	const after = 500
+	for _, filter := range []domain.JobFilter{
+		{Limit: 10, After: after},
+		{Status: domain.StatusCompleted, Limit: 10, After: after},
+	} {
+		query, args := listQuery(filter)
+		plan := queryPlan(t, repo, query, args...)
+		for _, step := range plan {
+			if strings.HasPrefix(step, "USE TEMP B-TREE") {
+				t.Errorf("List(%q) plan %q sorts", filter.Status, plan)
+			}
+		}
+	}
+}
+
+func TestRepository_CountByStatus(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	j1, _ := repo.Create(ctx, "https://example.com/1")
+	repo.Create(ctx, "https://example.com/2")
+	j3, _ := repo.Create(ctx, "https://example.com/3")
+	repo.Fail(ctx, j1.ID, "boom")
+	repo.Claim(ctx, j3.ID)
+
+	counts, err := repo.CountByStatus(ctx)
+	if err != nil {
+		t.Fatalf("CountByStatus() error = %v", err)
+	}
+	want := map[domain.JobStatus]int64{
+		domain.StatusPending:    1,
+		domain.StatusProcessing: 1,
+		domain.StatusFailed:     1,
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("CountByStatus() = %v, want %v", counts, want)
+	}
+}
+
 func TestRepository_Claim(t *testing.T) {
 	repo, cleanup := setupTestRepo(t)
 	defer cleanup()
@@ -145,7 +433,7 @@ func TestRepository_Complete(t *testing.T) {
 	job, _ := repo.Create(ctx, "https://example.com")
 	repo.Claim(ctx, job.ID)
 
-	err := repo.Complete(ctx, job.ID)
+	err := repo.Complete(ctx, job.ID, domain.Completion{Processor: "test", Bytes: 42})
 	if err != nil {
 		t.Fatalf("Complete() error = %v", err)
 	}
@@ -156,6 +444,117 @@ func TestRepository_Complete(t *testing.T) {
 	}
 }
 
+func TestRepository_CompleteWithResults(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	job, _ := repo.Create(ctx, "https://example.com")
+	repo.Claim(ctx, job.ID)
+
+	files := []domain.ResultFile{{Path: "/videos/a.mp4", Bytes: 40, SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"}, {Path: "/videos/a.jpg", Bytes: 2}, {Path: "/library/a.srt", Bytes: 3, Library: true}}
+	err := repo.Complete(ctx, job.ID, domain.Completion{Processor: "test", Title: "A", Bytes: 45, Files: files, Duration: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	completed, _ := repo.Get(ctx, job.ID)
+	if !reflect.DeepEqual(completed.Files, files) {
+		t.Errorf("Files = %+v, want %+v", completed.Files, files)
+	}
+	if completed.Duration != 1500*time.Millisecond {
+		t.Errorf("Duration = %s, want 1.5s", completed.Duration)
+	}
+	if completed.Title != "A" {
+		t.Errorf("Title = %q, want %q", completed.Title, "A")
+	}
+}
+
+func TestRepository_RetryAt(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	later, _ := repo.Create(ctx, "https://example.com/later")
+	soon, _ := repo.Create(ctx, "https://example.com/soon")
+	for _, job := range []*domain.Job{later, soon} {
+		repo.Claim(ctx, job.ID)
+	}
+	at := time.Now().Add(time.Hour)
+	if err := repo.RetryAt(ctx, later.ID, "rate limited", at); err != nil {
+		t.Fatalf("RetryAt() error = %v", err)
+	}
+	if err := repo.RetryAt(ctx, soon.ID, "rate limited", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("RetryAt() error = %v", err)
+	}
+
+	pending, err := repo.FindPending(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != soon.ID {
+		t.Errorf("FindPending() = %+v, want only the job that is due", pending)
+	}
+	got, _ := repo.Get(ctx, later.ID)
+	if got.Status != domain.StatusPending || got.Error != "rate limited" {
+		t.Errorf("job = %s %q, want pending with the reason", got.Status, got.Error)
+	}
+	if !got.NotBefore.Equal(at.Truncate(time.Millisecond)) {
+		t.Errorf("NotBefore = %s, want %s", got.NotBefore, at)
+	}
+	if got, _ := repo.Get(ctx, soon.ID); !got.NotBefore.Before(time.Now()) {
+		t.Errorf("NotBefore = %s, want in the past", got.NotBefore)
+	}
+}
+
+func TestRepository_RetryAtFollowsClock(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo.SetClock(clock)
+
+	ctx := context.Background()
+	job, _ := repo.Create(ctx, "https://example.com")
+	repo.Claim(ctx, job.ID)
+	if err := repo.RetryAt(ctx, job.ID, "rate limited", clock.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RetryAt() error = %v", err)
+	}
+
+	if pending, _ := repo.FindPending(ctx, 10); len(pending) != 0 {
+		t.Errorf("FindPending() = %+v, want none before the retry is due", pending)
+	}
+	clock.Advance(time.Hour)
+	if pending, _ := repo.FindPending(ctx, 10); len(pending) != 1 {
+		t.Errorf("FindPending() = %+v, want the job once the clock reaches its retry", pending)
+	}
+	if got, _ := repo.Get(ctx, job.ID); !got.UpdatedAt.Equal(clock.Now().Add(-time.Hour)) {
+		t.Errorf("UpdatedAt = %s, want the clock's time at the retry", got.UpdatedAt)
+	}
+}
+
+func TestRepository_CompleteIsAtomic(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	job, _ := repo.Create(ctx, "https://example.com")
+	repo.Claim(ctx, job.ID)
+
+	// Make recording the results fail after the status update
+	if _, err := repo.db.Exec(`CREATE TRIGGER no_results BEFORE INSERT ON job_results BEGIN SELECT RAISE(ABORT, 'disk full'); END`); err != nil {
+		t.Fatal(err)
+	}
+	err := repo.Complete(ctx, job.ID, domain.Completion{Processor: "test", Bytes: 1, Files: []domain.ResultFile{{Path: "/videos/a.mp4", Bytes: 1}}})
+	if err == nil {
+		t.Fatal("Complete() error = nil, want the results insert to fail")
+	}
+
+	got, _ := repo.Get(ctx, job.ID)
+	if got.Status != domain.StatusProcessing || got.Bytes != 0 {
+		t.Errorf("job = %s with %d bytes, want the completion rolled back", got.Status, got.Bytes)
+	}
+}
+
 func TestRepository_Fail(t *testing.T) {
 	repo, cleanup := setupTestRepo(t)
 	defer cleanup()
@@ -275,3 +674,424 @@ func TestRepository_RecoverStale(t *testing.T) {
 		t.Errorf("job1 error = %q, want %q", j1.Error, "recovered after crash")
 	}
 }
+
+func TestRepository_FindRecent(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	// URLs are compared after decryption
+	if err := repo.Unlock(ctx, []byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+
+	const url = "https://example.com/video"
+	since := time.Now().Add(-time.Minute)
+	first, _ := repo.Create(ctx, url)
+	repo.Create(ctx, "https://example.com/other")
+
+	got, err := repo.FindRecent(ctx, url, since)
+	if err != nil {
+		t.Fatalf("FindRecent() error = %v", err)
+	}
+	if got == nil || got.ID != first.ID {
+		t.Fatalf("FindRecent() = %+v, want job %d", got, first.ID)
+	}
+
+	// Failed jobs don't count
+	repo.Fail(ctx, first.ID, "boom")
+	if got, err := repo.FindRecent(ctx, url, since); got != nil || err != nil {
+		t.Errorf("FindRecent() = %+v, %v, want nil, nil after failure", got, err)
+	}
+
+	// Nor do jobs created before since
+	repo.Create(ctx, url)
+	if got, _ := repo.FindRecent(ctx, url, time.Now().Add(time.Second)); got != nil {
+		t.Errorf("FindRecent() = job %d, want nil outside the window", got.ID)
+	}
+}
+
+func TestRepository_LastCompleted(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	const url = "https://example.com/video"
+	first, _ := repo.Create(ctx, url)
+	repo.Complete(ctx, first.ID, domain.Completion{Files: []domain.ResultFile{{Path: "/videos/v1.mp4", Bytes: 1}}})
+	other, _ := repo.Create(ctx, "https://example.com/other")
+	repo.Complete(ctx, other.ID, domain.Completion{})
+	second, _ := repo.Create(ctx, url)
+
+	got, err := repo.LastCompleted(ctx, url, 0)
+	if err != nil {
+		t.Fatalf("LastCompleted() error = %v", err)
+	}
+	if got == nil || got.ID != first.ID {
+		t.Fatalf("LastCompleted() = %+v, want job %d", got, first.ID)
+	}
+	if len(got.Files) != 1 || got.Files[0].Path != "/videos/v1.mp4" {
+		t.Errorf("Files = %+v, want the completed job's files", got.Files)
+	}
+
+	repo.Complete(ctx, second.ID, domain.Completion{})
+	if got, _ := repo.LastCompleted(ctx, url, 0); got == nil || got.ID != second.ID {
+		t.Errorf("LastCompleted(any) = %+v, want job %d", got, second.ID)
+	}
+	if got, _ := repo.LastCompleted(ctx, url, second.ID); got == nil || got.ID != first.ID {
+		t.Errorf("LastCompleted(before %d) = %+v, want job %d", second.ID, got, first.ID)
+	}
+	if got, err := repo.LastCompleted(ctx, url, first.ID); got != nil || err != nil {
+		t.Errorf("LastCompleted(before %d) = %+v, %v, want nil, nil", first.ID, got, err)
+	}
+}
+
+func TestRepository_LastCompletedByHash(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Hashed without a key, then rehashed when one is set
+	const url = "https://example.com/video"
+	job, _ := repo.Create(ctx, url)
+	repo.Complete(ctx, job.ID, domain.Completion{})
+	if err := repo.Unlock(ctx, []byte("key")); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if got, err := repo.LastCompleted(ctx, url, 0); err != nil || got == nil || got.ID != job.ID {
+		t.Errorf("LastCompleted() after Unlock = %+v, %v, want job %d", got, err, job.ID)
+	}
+	if got, err := repo.FindRecent(ctx, url, time.Time{}); err != nil || got == nil || got.ID != job.ID {
+		t.Errorf("FindRecent() after Unlock = %+v, %v, want job %d", got, err, job.ID)
+	}
+
+	// The lookup reads one row from the index, not every job
+	var plan []string
+	rows, err := repo.db.QueryContext(ctx,
+		`EXPLAIN QUERY PLAN SELECT id FROM jobs WHERE url_hash = ? AND status = ? AND (? = 0 OR id < ?) ORDER BY id DESC LIMIT 1`,
+		"", domain.StatusCompleted, 0, 0,
+	)
+	if err != nil {
+		t.Fatalf("EXPLAIN error = %v", err)
+	}
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		rows.Scan(&id, &parent, &unused, &detail)
+		plan = append(plan, detail)
+	}
+	rows.Close()
+	if got := strings.Join(plan, "; "); !strings.Contains(got, "idx_jobs_url_hash") || strings.Contains(got, "TEMP B-TREE") {
+		t.Errorf("query plan = %q, want a search of idx_jobs_url_hash", got)
+	}
+}
+
+func TestRepository_CompleteManually(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	pending, _ := repo.Create(ctx, "https://example.com/1")
+	failed, _ := repo.Create(ctx, "https://example.com/2")
+	repo.Fail(ctx, failed.ID, "boom")
+	processing, _ := repo.Create(ctx, "https://example.com/3")
+	repo.Claim(ctx, processing.ID)
+
+	tests := []struct {
+		name string
+		id   int64
+		want error
+	}{
+		{"pending", pending.ID, nil},
+		{"failed", failed.ID, nil},
+		{"processing", processing.ID, domain.ErrJobState},
+		{"already completed", pending.ID, domain.ErrJobState},
+		{"missing", 999, domain.ErrJobNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.CompleteManually(ctx, tt.id); !errors.Is(err, tt.want) {
+				t.Fatalf("CompleteManually() error = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				return
+			}
+			job, _ := repo.Get(ctx, tt.id)
+			if job.Status != domain.StatusCompleted || job.Error != "" {
+				t.Errorf("job = %+v, want completed with no error", job)
+			}
+		})
+	}
+}
+
+func TestRepository_Hold(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	job, err := repo.CreateHeld(ctx, "https://example.com/big")
+	if err != nil {
+		t.Fatalf("CreateHeld() error = %v", err)
+	}
+	if got, _ := repo.Get(ctx, job.ID); !got.Held || got.Status != domain.StatusPending {
+		t.Fatalf("job = %+v, want pending and held", got)
+	}
+	if pending, _ := repo.FindPending(ctx, 10); len(pending) != 0 {
+		t.Errorf("FindPending() = %d job(s), want held job excluded", len(pending))
+	}
+	if err := repo.Claim(ctx, job.ID); err == nil {
+		t.Error("Claim() of a held job succeeded")
+	}
+
+	if err := repo.SetHeld(ctx, job.ID, false); err != nil {
+		t.Fatalf("SetHeld(false) error = %v", err)
+	}
+	if pending, _ := repo.FindPending(ctx, 10); len(pending) != 1 {
+		t.Errorf("FindPending() = %d job(s), want the released job", len(pending))
+	}
+
+	repo.Claim(ctx, job.ID)
+	if err := repo.SetHeld(ctx, job.ID, true); !errors.Is(err, domain.ErrJobState) {
+		t.Errorf("SetHeld() on a processing job error = %v, want ErrJobState", err)
+	}
+	if err := repo.SetHeld(ctx, 999, true); !errors.Is(err, domain.ErrJobNotFound) {
+		t.Errorf("SetHeld() on a missing job error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestRepository_Approval(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	approved, err := repo.CreateForApproval(ctx, "https://example.com/1")
+	if err != nil {
+		t.Fatalf("CreateForApproval() error = %v", err)
+	}
+	rejected, _ := repo.CreateForApproval(ctx, "https://example.com/2")
+	if pending, _ := repo.FindPending(ctx, 10); len(pending) != 0 {
+		t.Errorf("FindPending() = %d job(s), want jobs awaiting approval excluded", len(pending))
+	}
+	if err := repo.Claim(ctx, approved.ID); err == nil {
+		t.Error("Claim() of a job awaiting approval succeeded")
+	}
+
+	if err := repo.Approve(ctx, approved.ID); err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if err := repo.Reject(ctx, rejected.ID, "rejected: spam"); err != nil {
+		t.Fatalf("Reject() error = %v", err)
+	}
+	if got, _ := repo.Get(ctx, approved.ID); got.Status != domain.StatusPending {
+		t.Errorf("approved job status = %s, want pending", got.Status)
+	}
+	if got, _ := repo.Get(ctx, rejected.ID); got.Status != domain.StatusFailed || got.Error != "rejected: spam" {
+		t.Errorf("rejected job = %+v, want failed with the reason", got)
+	}
+
+	if err := repo.Approve(ctx, approved.ID); !errors.Is(err, domain.ErrJobState) {
+		t.Errorf("Approve() of a pending job error = %v, want ErrJobState", err)
+	}
+	if err := repo.Reject(ctx, approved.ID, ""); !errors.Is(err, domain.ErrJobState) {
+		t.Errorf("Reject() of a pending job error = %v, want ErrJobState", err)
+	}
+	if err := repo.Reject(ctx, 999, ""); !errors.Is(err, domain.ErrJobNotFound) {
+		t.Errorf("Reject() of a missing job error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestRepository_RequestApproval(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	job, _ := repo.Create(ctx, "https://example.com/big")
+	if err := repo.RequestApproval(ctx, job.ID, "too large"); !errors.Is(err, domain.ErrJobState) {
+		t.Errorf("RequestApproval() of a pending job error = %v, want ErrJobState", err)
+	}
+	repo.Claim(ctx, job.ID)
+	if err := repo.RequestApproval(ctx, job.ID, "too large"); err != nil {
+		t.Fatalf("RequestApproval() error = %v", err)
+	}
+	got, _ := repo.Get(ctx, job.ID)
+	if got.Status != domain.StatusNeedsApproval || got.Error != "too large" || got.Attempts != 0 || got.Approved {
+		t.Fatalf("job = %+v, want awaiting approval with the reason and no attempt used", got)
+	}
+
+	if err := repo.Approve(ctx, job.ID); err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	got, _ = repo.Get(ctx, job.ID)
+	if got.Status != domain.StatusPending || got.Error != "" || !got.Approved {
+		t.Errorf("job = %+v, want pending, approved, and no error", got)
+	}
+}
+
+func TestRepository_Postpone(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	job, _ := repo.Create(ctx, "https://example.com/a")
+	at := time.Now().Add(time.Minute)
+	if err := repo.Postpone(ctx, job.ID, "offline", at); !errors.Is(err, domain.ErrJobState) {
+		t.Errorf("Postpone() of a pending job error = %v, want ErrJobState", err)
+	}
+	repo.Claim(ctx, job.ID)
+	if err := repo.Postpone(ctx, job.ID, "offline", at); err != nil {
+		t.Fatalf("Postpone() error = %v", err)
+	}
+	got, _ := repo.Get(ctx, job.ID)
+	if got.Status != domain.StatusPending || got.Error != "offline" || got.Attempts != 0 || !got.NotBefore.Equal(at.Truncate(time.Millisecond)) {
+		t.Errorf("job = %+v, want pending until %s with the reason and no attempt used", got, at)
+	}
+}
+
+func TestRepository_DelayPending(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	job, _ := repo.Create(ctx, "https://example.com/a")
+	at := time.Now().Add(time.Minute)
+	if err := repo.DelayPending(ctx, job.ID, "cooling down", at); err != nil {
+		t.Fatalf("DelayPending() error = %v", err)
+	}
+	got, _ := repo.Get(ctx, job.ID)
+	if got.Status != domain.StatusPending || got.Error != "cooling down" || !got.NotBefore.Equal(at.Truncate(time.Millisecond)) {
+		t.Errorf("job = %+v, want pending until %s with the reason", got, at)
+	}
+
+	// Cancelled meanwhile, the job stays cancelled
+	repo.Fail(ctx, job.ID, "cancelled")
+	if err := repo.DelayPending(ctx, job.ID, "cooling down", at); !errors.Is(err, domain.ErrJobState) {
+		t.Errorf("DelayPending() of a failed job error = %v, want ErrJobState", err)
+	}
+	if got, _ := repo.Get(ctx, job.ID); got.Status != domain.StatusFailed || got.Error != "cancelled" {
+		t.Errorf("job = %+v, want it left failed", got)
+	}
+}
+
+func TestRepository_CreateOriginalURL(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := domain.WithOriginalURL(context.Background(), "https://m.example.com/v?utm_source=x")
+
+	job, err := repo.Create(ctx, "https://www.example.com/v")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	got, _ := repo.Get(ctx, job.ID)
+	if got.URL != "https://www.example.com/v" || got.OriginalURL != "https://m.example.com/v?utm_source=x" {
+		t.Errorf("job URL = %q, original %q", got.URL, got.OriginalURL)
+	}
+}
+
+func TestRepository_CreateBookmark(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	plain, _ := repo.Create(ctx, "https://example.com/a")
+	created, _ := repo.Create(domain.WithBookmark(ctx), "https://example.com/b")
+	if !created.Bookmark {
+		t.Error("Create() with WithBookmark returned a job that isn't a bookmark")
+	}
+	if got, _ := repo.Get(ctx, created.ID); !got.Bookmark {
+		t.Error("Get() lost the bookmark flag")
+	}
+	if got, _ := repo.Get(ctx, plain.ID); got.Bookmark {
+		t.Error("Get() of a plain job returned a bookmark")
+	}
+}
+
+func TestRepository_CreateUserAgent(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	plain, _ := repo.Create(ctx, "https://example.com/a")
+	created, _ := repo.Create(domain.WithUserAgent(ctx, "Mozilla/5.0 (Macintosh)"), "https://example.com/b")
+	if created.UserAgent != "Mozilla/5.0 (Macintosh)" {
+		t.Errorf("Create() UserAgent = %q", created.UserAgent)
+	}
+	if got, _ := repo.Get(ctx, created.ID); got.UserAgent != "Mozilla/5.0 (Macintosh)" {
+		t.Errorf("Get() UserAgent = %q", got.UserAgent)
+	}
+	if got, _ := repo.Get(ctx, plain.ID); got.UserAgent != "" {
+		t.Errorf("Get() of a plain job UserAgent = %q, want none", got.UserAgent)
+	}
+}
+
+func TestRepository_SetNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := domain.WithNotes(context.Background(), "for mum")
+
+	job, _ := repo.Create(ctx, "https://example.com/video")
+	if got, _ := repo.Get(ctx, job.ID); got.Notes != "for mum" {
+		t.Errorf("Get() notes = %q, want %q", got.Notes, "for mum")
+	}
+
+	repo.Fail(ctx, job.ID, "boom")
+	if err := repo.SetNotes(ctx, job.ID, "conference talk"); err != nil {
+		t.Fatalf("SetNotes() error = %v", err)
+	}
+	jobs, _ := repo.List(ctx, domain.JobFilter{Limit: 10})
+	if len(jobs) != 1 || jobs[0].Notes != "conference talk" {
+		t.Errorf("List() = %+v, want the edited notes", jobs)
+	}
+
+	if err := repo.SetNotes(ctx, 999, "x"); err != domain.ErrJobNotFound {
+		t.Errorf("SetNotes(missing) error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestRepository_ResolveUID(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	created, _ := repo.Create(ctx, "https://example.com/video")
+	if !domain.IsUID(created.UID) {
+		t.Fatalf("Create() UID = %q, want a UUID", created.UID)
+	}
+	if job, _ := repo.Get(ctx, created.ID); job.UID != created.UID {
+		t.Errorf("Get() UID = %q, want %q", job.UID, created.UID)
+	}
+
+	id, err := repo.ResolveUID(ctx, created.UID)
+	if err != nil || id != created.ID {
+		t.Errorf("ResolveUID() = %d, %v, want %d", id, err, created.ID)
+	}
+	if _, err := repo.ResolveUID(ctx, domain.NewUID()); err != domain.ErrJobNotFound {
+		t.Errorf("ResolveUID(unknown) error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestNewMemory(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewMemory(t.Name() + "-a")
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	defer a.Close()
+	b, err := NewMemory(t.Name() + "-b")
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	defer b.Close()
+
+	job, err := a.Create(ctx, "https://example.com/v")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got, err := a.Get(ctx, job.ID); err != nil || got.URL != job.URL {
+		t.Errorf("Get() = %+v, %v, want the created job", got, err)
+	}
+	if _, err := b.Get(ctx, job.ID); !errors.Is(err, domain.ErrJobNotFound) {
+		t.Errorf("Get() from another in-memory database error = %v, want ErrJobNotFound", err)
+	}
+	if err := a.OpenReadPool(2); err == nil {
+		t.Error("OpenReadPool() on an in-memory database succeeded")
+	}
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// archiveColumns are the job columns kept in jobs_archive.
+const archiveColumns = `id, url, original_url, status, attempts, error, title, bytes, duration_ms, approved, queue, source, uid, notes, bookmark, redownload_of, user_agent, created_at, updated_at, completed_at`
+
+// PruneJobs implements domain.JobPruner. Archived jobs go to the
+// jobs_archive table; deleted ones take their attempts and result files
+// with them.
+func (r *Repository) PruneJobs(ctx context.Context, before time.Time, archive bool) (int64, error) {
+	var n int64
+	err := r.retry(ctx, "prune_jobs", func() error {
+		return r.withTx(ctx, func(tx *sql.Tx) error {
+			ids, err := r.finishedBefore(ctx, tx, before)
+			if err != nil {
+				return err
+			}
+			if archive {
+				_, err = r.stmtExec(ctx, tx,
+					`INSERT INTO jobs_archive (`+archiveColumns+`, archived_at)
+					 SELECT `+archiveColumns+`, ? FROM jobs WHERE id IN (SELECT value FROM json_each(?))`,
+					r.clock.Now(), ids,
+				)
+				if err != nil {
+					return err
+				}
+			} else {
+				for _, table := range []string{"job_attempts", "job_results"} {
+					_, err := r.stmtExec(ctx, tx, `DELETE FROM `+table+` WHERE job_id IN (SELECT value FROM json_each(?))`, ids)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			result, err := r.stmtExec(ctx, tx, `DELETE FROM jobs WHERE id IN (SELECT value FROM json_each(?))`, ids)
+			if err != nil {
+				return err
+			}
+			if n, err = result.RowsAffected(); err != nil {
+				return err
+			}
+			// Foreign keys aren't enforced, so outage_jobs doesn't cascade
+			_, err = r.stmtExec(ctx, tx, `DELETE FROM outage_jobs WHERE job_id NOT IN (SELECT id FROM jobs)`)
+			return err
+		})
+	})
+	return n, err
+}
+
+// finishedBefore returns the IDs of completed and failed jobs last updated
+// before before, as a JSON array. Timestamps are compared once scanned, as
+// stored ones don't all share a time zone.
+func (r *Repository) finishedBefore(ctx context.Context, tx *sql.Tx, before time.Time) (string, error) {
+	rows, err := r.stmtQuery(ctx, tx,
+		`SELECT id, updated_at FROM jobs WHERE status IN (?, ?)`,
+		domain.StatusCompleted, domain.StatusFailed,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		var updated time.Time
+		if err := rows.Scan(&id, &updated); err != nil {
+			return "", err
+		}
+		if updated.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(ids)
+	return string(b), err
+}
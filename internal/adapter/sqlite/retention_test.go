@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_PruneJobs(t *testing.T) {
+	for _, archive := range []bool{false, true} {
+		name := "delete"
+		if archive {
+			name = "archive"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+			if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+				t.Fatal(err)
+			}
+
+			old := time.Now().Add(-48 * time.Hour)
+			create := func(status domain.JobStatus, updated time.Time) int64 {
+				t.Helper()
+				job, err := repo.Create(ctx, "https://example.com/video")
+				if err != nil {
+					t.Fatal(err)
+				}
+				repo.Claim(ctx, job.ID)
+				repo.AddAttempt(ctx, job.ID, domain.Attempt{Number: 1, Processor: "yt", StartedAt: updated, FinishedAt: updated})
+				switch status {
+				case domain.StatusCompleted:
+					repo.Complete(ctx, job.ID, domain.Completion{Files: []domain.ResultFile{{Path: "/tmp/video.mp4"}}})
+				case domain.StatusFailed:
+					repo.Fail(ctx, job.ID, "404")
+				case domain.StatusPending:
+					repo.Retry(ctx, job.ID, "timeout")
+				}
+				if _, err := repo.db.Exec(`UPDATE jobs SET updated_at = ? WHERE id = ?`, updated, job.ID); err != nil {
+					t.Fatal(err)
+				}
+				return job.ID
+			}
+			completed := create(domain.StatusCompleted, old)
+			failed := create(domain.StatusFailed, old)
+			pending := create(domain.StatusPending, old)
+			recent := create(domain.StatusCompleted, time.Now())
+
+			if err := repo.ListOutageFailed(ctx, []int64{failed, pending}); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := repo.PruneJobs(ctx, time.Now().Add(-24*time.Hour), archive)
+			if err != nil || n != 2 {
+				t.Fatalf("PruneJobs() = %d, %v, want 2", n, err)
+			}
+			for _, id := range []int64{completed, failed} {
+				if _, err := repo.Get(ctx, id); err != domain.ErrJobNotFound {
+					t.Errorf("Get(%d) error = %v, want ErrJobNotFound", id, err)
+				}
+			}
+			for _, id := range []int64{pending, recent} {
+				if _, err := repo.Get(ctx, id); err != nil {
+					t.Errorf("Get(%d) error = %v, want the job kept", id, err)
+				}
+			}
+
+			var outage []int64
+			rows, _ := repo.db.Query(`SELECT job_id FROM outage_jobs`)
+			for rows.Next() {
+				var id int64
+				rows.Scan(&id)
+				outage = append(outage, id)
+			}
+			rows.Close()
+			if len(outage) != 1 || outage[0] != pending {
+				t.Errorf("outage_jobs = %v, want [%d]", outage, pending)
+			}
+
+			var archived, attempts, results int
+			repo.db.QueryRow(`SELECT COUNT(*) FROM jobs_archive`).Scan(&archived)
+			repo.db.QueryRow(`SELECT COUNT(*) FROM job_attempts`).Scan(&attempts)
+			repo.db.QueryRow(`SELECT COUNT(*) FROM job_results`).Scan(&results)
+			wantArchived, wantAttempts, wantResults := 0, 2, 1
+			if archive {
+				wantArchived, wantAttempts, wantResults = 2, 4, 2
+			}
+			if archived != wantArchived || attempts != wantAttempts || results != wantResults {
+				t.Errorf("archived, attempts, results = %d, %d, %d, want %d, %d, %d",
+					archived, attempts, results, wantArchived, wantAttempts, wantResults)
+			}
+			if archive {
+				var url, status string
+				repo.db.QueryRow(`SELECT url, status FROM jobs_archive WHERE id = ?`, failed).Scan(&url, &status)
+				if got, _ := repo.decrypt(url); got != "https://example.com/video" || status != string(domain.StatusFailed) {
+					t.Errorf("archived job = %q, %s, want the failed job's URL", got, status)
+				}
+			}
+
+			// Stats outlive the jobs they count
+			buckets, _ := repo.Stats(ctx, domain.StatsQuery{Period: domain.PeriodHour})
+			var submitted int64
+			for _, b := range buckets {
+				submitted += b.Submitted
+			}
+			if submitted != 4 {
+				t.Errorf("submitted = %d after pruning, want 4", submitted)
+			}
+
+			if n, err := repo.PruneJobs(ctx, time.Now().Add(-24*time.Hour), archive); err != nil || n != 0 {
+				t.Errorf("second PruneJobs() = %d, %v, want 0", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/logging"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// RetryPolicy bounds how repository operations are retried after transient
+// errors such as a locked database.
+type RetryPolicy struct {
+	Attempts int           // tries in total, including the first
+	Base     time.Duration // backoff before the second try, doubling after
+	Max      time.Duration // cap on a single backoff
+}
+
+// DefaultRetryPolicy returns the policy used unless SetRetryPolicy is called:
+// up to five tries over roughly a second and a half at worst.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Attempts: 5, Base: 25 * time.Millisecond, Max: time.Second}
+}
+
+// backoff returns a jittered delay before try n+1, so writers that collided
+// once don't collide again in step.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.Base << (n - 1)
+	if d <= 0 || d > p.Max {
+		d = p.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// RetryObserver is told about each retried repository operation.
+type RetryObserver interface {
+	DBRetry(op string)
+}
+
+// SetRetryPolicy replaces the retry policy.
+func (r *Repository) SetRetryPolicy(p RetryPolicy) {
+	r.policy = p
+}
+
+// SetRetryObserver sets an observer for retries, e.g. metrics.
+func (r *Repository) SetRetryObserver(o RetryObserver) {
+	r.observer = o
+}
+
+// retry runs fn, trying again with backoff while it fails with a transient
+// error and the policy and ctx allow.
+func (r *Repository) retry(ctx context.Context, op string, fn func() error) error {
+	for n := 1; ; n++ {
+		err := fn()
+		if err == nil || !isTransient(err) || n >= r.policy.Attempts {
+			return err
+		}
+		delay := r.policy.backoff(n)
+		logging.Debugf("db %s: %v, retrying in %s", op, err, delay)
+		if r.observer != nil {
+			r.observer.DBRetry(op)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// isTransient reports whether err is worth retrying: the database was busy
+// or locked by another connection, or the connection went bad.
+func isTransient(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var se *sqlite.Error
+	if errors.As(err, &se) {
+		switch se.Code() & 0xff { // extended codes keep the primary in the low byte
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return true
+		}
+	}
+	return false
+}
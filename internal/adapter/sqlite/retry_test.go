@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+type retryCounter struct {
+	mu  sync.Mutex
+	ops []string
+}
+
+func (c *retryCounter) DBRetry(op string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ops = append(c.ops, op)
+}
+
+func TestRepository_RetriesLockedDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	repo, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer repo.Close()
+	var retries retryCounter
+	repo.SetRetryObserver(&retries)
+	repo.SetRetryPolicy(RetryPolicy{Attempts: 20, Base: 10 * time.Millisecond, Max: 50 * time.Millisecond})
+
+	ctx := context.Background()
+	job, err := repo.Create(ctx, "https://example.com/video")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another connection holds the write lock for a moment
+	other, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	conn, err := other.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		conn.ExecContext(ctx, `COMMIT`)
+	}()
+
+	if err := repo.Complete(ctx, job.ID, domain.Completion{Processor: "test", Bytes: 10}); err != nil {
+		t.Fatalf("Complete() error = %v, want it to wait out the lock", err)
+	}
+	got, _ := repo.Get(ctx, job.ID)
+	if got.Status != domain.StatusCompleted {
+		t.Errorf("Status = %s, want completed", got.Status)
+	}
+	if len(retries.ops) == 0 || retries.ops[0] != "complete" {
+		t.Errorf("retries = %v, want complete retried", retries.ops)
+	}
+}
+
+func TestRepository_RetryGivesUp(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	repo.SetRetryPolicy(RetryPolicy{Attempts: 3})
+
+	calls := 0
+	err := repo.retry(context.Background(), "test", func() error {
+		calls++
+		return driver.ErrBadConn
+	})
+	if !errors.Is(err, driver.ErrBadConn) || calls != 3 {
+		t.Errorf("retry() = %v after %d calls, want ErrBadConn after 3", err, calls)
+	}
+
+	calls = 0
+	permanent := errors.New("constraint failed")
+	if err := repo.retry(context.Background(), "test", func() error { calls++; return permanent }); err != permanent || calls != 1 {
+		t.Errorf("retry() = %v after %d calls, want permanent error without retrying", err, calls)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{sql.ErrNoRows, false},
+		{errors.New("database is locked"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{Attempts: 10, Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+	for n := 1; n < 10; n++ {
+		d := p.backoff(n)
+		ceil := min(p.Base<<(n-1), p.Max)
+		if d < ceil/2 || d > ceil {
+			t.Errorf("backoff(%d) = %s, want within [%s, %s]", n, d, ceil/2, ceil)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// StateFormat names the snapshots written by ExportState.
+const StateFormat = "catcher-state"
+
+// stateVersion is the snapshot layout; bump it on incompatible changes.
+const stateVersion = 1
+
+// ErrNotEmpty is returned by ImportState for a database that already
+// holds jobs or other state.
+var ErrNotEmpty = errors.New("database is not empty")
+
+// stateTables are the tables a snapshot carries, parents first. The meta
+// table is handled on its own, as its key_check belongs to the database.
+var stateTables = []string{
+	"jobs",
+	"outage_jobs",
+	"jobs_archive",
+	"job_attempts",
+	"job_results",
+	"job_stats",
+	"views",
+	"host_cooldowns",
+	"file_issues",
+	"library_files",
+}
+
+// stateSnapshot is the JSON document written by ExportState. Rows hold
+// their values as stored, so encrypted columns stay encrypted and times
+// keep their format.
+type stateSnapshot struct {
+	Format     string                `json:"format"`
+	Version    int                   `json:"version"`
+	Schema     int                   `json:"schema"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Tables     map[string]stateTable `json:"tables"`
+	Meta       map[string]string     `json:"meta"`
+	// Sequences are the AUTOINCREMENT counters, so IDs of deleted and
+	// archived jobs aren't handed out again.
+	Sequences map[string]int64 `json:"sequences"`
+}
+
+// stateTable is one table of a snapshot.
+type stateTable struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+}
+
+// ExportState writes every job, archived job, attempt, result, stat,
+// saved view, host cooldown, and library entry to w as one JSON document
+// for ImportState, read in one transaction. Media files are not included.
+func (r *Repository) ExportState(ctx context.Context, w io.Writer) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	snap := stateSnapshot{
+		Format:     StateFormat,
+		Version:    stateVersion,
+		ExportedAt: r.clock.Now().UTC(),
+		Tables:     make(map[string]stateTable, len(stateTables)),
+		Meta:       make(map[string]string),
+		Sequences:  make(map[string]int64),
+	}
+	if err := tx.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&snap.Schema); err != nil {
+		return err
+	}
+	for _, table := range stateTables {
+		t, err := exportTable(ctx, tx, table)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", table, err)
+		}
+		snap.Tables[table] = t
+	}
+	if err := queryPairs(ctx, tx, `SELECT key, value FROM meta`, func(k string, v string) { snap.Meta[k] = v }); err != nil {
+		return fmt.Errorf("export meta: %w", err)
+	}
+	if err := queryPairs(ctx, tx, `SELECT name, seq FROM sqlite_sequence`, func(k string, v int64) { snap.Sequences[k] = v }); err != nil {
+		return fmt.Errorf("export sequences: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	return enc.Encode(snap)
+}
+
+// exportTable reads every row of table in rowid order.
+func exportTable(ctx context.Context, tx *sql.Tx, table string) (stateTable, error) {
+	cols, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return stateTable{}, err
+	}
+	t := stateTable{Columns: make([]string, 0, len(cols)), Rows: [][]any{}}
+	exprs := make([]string, 0, len(cols))
+	for _, c := range cols {
+		t.Columns = append(t.Columns, c.name)
+		expr := c.name
+		if strings.EqualFold(c.typ, "DATETIME") {
+			// As stored, rather than parsed into a time.Time
+			expr = "CAST(" + c.name + " AS TEXT)"
+		}
+		exprs = append(exprs, expr)
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT `+strings.Join(exprs, ", ")+` FROM `+table+` ORDER BY rowid`)
+	if err != nil {
+		return stateTable{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		row := make([]any, len(t.Columns))
+		ptrs := make([]any, len(row))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return stateTable{}, err
+		}
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, rows.Err()
+}
+
+// queryPairs calls fn with each row of a two-column query.
+func queryPairs[V any](ctx context.Context, tx *sql.Tx, query string, fn func(string, V)) error {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k string
+		var v V
+		if err := rows.Scan(&k, &v); err != nil {
+			return err
+		}
+		fn(k, v)
+	}
+	return rows.Err()
+}
+
+// column is a table column as PRAGMA table_info reports it.
+type column struct {
+	name, typ string
+}
+
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) ([]column, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT name, type FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.name, &c.typ); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// ImportState loads a snapshot written by ExportState into this database,
+// which must hold no state yet, keeping job IDs and UIDs as they were. It
+// must come from the same schema version. An encrypted snapshot needs
+// the key it was encrypted with to have been given to Unlock; a plaintext
+// one is encrypted if a key was. It returns how many jobs it imported,
+// archived ones included.
+func (r *Repository) ImportState(ctx context.Context, rd io.Reader) (int64, error) {
+	dec := json.NewDecoder(rd)
+	dec.UseNumber()
+	var snap stateSnapshot
+	if err := dec.Decode(&snap); err != nil {
+		return 0, fmt.Errorf("read snapshot: %w", err)
+	}
+	if snap.Format != StateFormat || snap.Version != stateVersion {
+		return 0, fmt.Errorf("not a catcher state snapshot (format %q, version %d)", snap.Format, snap.Version)
+	}
+	if snap.Schema != len(migrations) {
+		return 0, fmt.Errorf("snapshot has schema version %d, this catcher %d: export it again with the same catcher version", snap.Schema, len(migrations))
+	}
+	if check, ok := snap.Meta["key_check"]; ok {
+		if r.cipher == nil {
+			return 0, ErrKeyRequired
+		}
+		if v, err := r.cipher.open(check); err != nil || v != keyCheck {
+			return 0, ErrWrongKey
+		}
+	}
+
+	var jobs int64
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range stateTables {
+			var n int
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n); err != nil {
+				return err
+			}
+			if n > 0 {
+				return fmt.Errorf("%w: %s has %d row(s)", ErrNotEmpty, table, n)
+			}
+		}
+		for _, table := range stateTables {
+			t, ok := snap.Tables[table]
+			if !ok {
+				continue
+			}
+			if err := importTable(ctx, tx, table, t); err != nil {
+				return fmt.Errorf("import %s: %w", table, err)
+			}
+			if table == "jobs" || table == "jobs_archive" {
+				jobs += int64(len(t.Rows))
+			}
+		}
+		for k, v := range snap.Meta {
+			if k == "key_check" {
+				continue // the database keeps its own, checked above
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)`, k, v); err != nil {
+				return err
+			}
+		}
+		for name, seq := range snap.Sequences {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM sqlite_sequence WHERE name = ?`, name); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO sqlite_sequence (name, seq) VALUES (?, ?)`, name, seq); err != nil {
+				return err
+			}
+		}
+		if r.cipher != nil {
+			for _, col := range encryptedColumns {
+				if _, err := r.encryptColumn(ctx, tx, col.table, col.column); err != nil {
+					return err
+				}
+			}
+		}
+		// The snapshot's hashes were made with its own key, if any
+		if _, err := tx.ExecContext(ctx, `DELETE FROM meta WHERE key = 'url_hash_check'`); err != nil {
+			return err
+		}
+		return r.rehashURLs(ctx, tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return jobs, nil
+}
+
+// importTable inserts the rows of t into table. Columns the table lacks
+// are rejected rather than dropped.
+func importTable(ctx context.Context, tx *sql.Tx, table string, t stateTable) error {
+	cols, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	for _, name := range t.Columns {
+		if !slices.ContainsFunc(cols, func(c column) bool { return c.name == name }) {
+			return fmt.Errorf("unknown column %q", name)
+		}
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO `+table+` (`+strings.Join(t.Columns, ", ")+`)
+		VALUES (`+strings.TrimSuffix(strings.Repeat("?, ", len(t.Columns)), ", ")+`)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return fmt.Errorf("row %d has %d values for %d columns", i+1, len(row), len(t.Columns))
+		}
+		args := make([]any, len(row))
+		for j, v := range row {
+			args[j] = stateValue(v)
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("row %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// stateValue converts a decoded JSON value back to what was stored,
+// turning numbers into int64 where they fit.
+func stateValue(v any) any {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
@@ -0,0 +1,157 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_ExportImportState(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := []byte("correct horse battery staple")
+			src, cleanup := setupTestRepo(t)
+			defer cleanup()
+			if encrypted {
+				if err := src.Unlock(ctx, key); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			old, _ := src.Create(ctx, "https://example.com/old")
+			src.Claim(ctx, old.ID)
+			src.AddAttempt(ctx, old.ID, domain.Attempt{Number: 1, Processor: "yt", Command: "yt-dlp https://example.com/old", StartedAt: time.Now(), FinishedAt: time.Now()})
+			src.Complete(ctx, old.ID, domain.Completion{Files: []domain.ResultFile{{Path: "/media/old.mp4", Bytes: 10}}})
+			if _, err := src.PruneJobs(ctx, time.Now().Add(time.Hour), true); err != nil {
+				t.Fatal(err)
+			}
+			deleted, _ := src.Create(ctx, "https://example.com/deleted")
+			src.db.Exec(`DELETE FROM jobs WHERE id = ?`, deleted.ID)
+			pending, _ := src.Create(domain.WithNotes(ctx, "for later"), "https://example.com/pending")
+			src.SaveView(ctx, domain.View{Name: "failed", Status: "failed"})
+			src.SetCooldown(ctx, domain.Cooldown{Host: "example.com", Until: time.Now().Add(time.Hour), Reason: "429"})
+			src.ListOutageFailed(ctx, []int64{pending.ID})
+
+			var buf bytes.Buffer
+			if err := src.ExportState(ctx, &buf); err != nil {
+				t.Fatalf("ExportState() error = %v", err)
+			}
+
+			dst, err := New(filepath.Join(t.TempDir(), "new.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dst.Close()
+			if encrypted {
+				if err := dst.Unlock(ctx, key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			snapshot := buf.Bytes()
+			n, err := dst.ImportState(ctx, bytes.NewReader(snapshot))
+			if err != nil || n != 2 {
+				t.Fatalf("ImportState() = %d, %v, want 2 jobs", n, err)
+			}
+
+			got, err := dst.Get(ctx, pending.ID)
+			if err != nil || got.URL != pending.URL || got.UID != pending.UID || got.Notes != "for later" || !got.CreatedAt.Equal(pending.CreatedAt) {
+				t.Errorf("Get(%d) = %+v, %v, want %+v", pending.ID, got, err, pending)
+			}
+			if attempts, err := dst.Attempts(ctx, old.ID); err != nil || len(attempts) != 1 || attempts[0].Command != "yt-dlp https://example.com/old" {
+				t.Errorf("Attempts(%d) = %+v, %v", old.ID, attempts, err)
+			}
+			if v, err := dst.View(ctx, "failed"); err != nil || v.Status != "failed" {
+				t.Errorf("View() = %+v, %v", v, err)
+			}
+			if cs, err := dst.Cooldowns(ctx); err != nil || len(cs) != 1 || cs[0].Host != "example.com" {
+				t.Errorf("Cooldowns() = %+v, %v", cs, err)
+			}
+			if ids, err := dst.OutageFailed(ctx); err != nil || len(ids) != 1 || ids[0] != pending.ID {
+				t.Errorf("OutageFailed() = %v, %v, want [%d]", ids, err, pending.ID)
+			}
+			// Times are stored as the repository writes them
+			var srcAt, dstAt string
+			src.db.QueryRow(`SELECT CAST(created_at AS TEXT) FROM jobs WHERE id = ?`, pending.ID).Scan(&srcAt)
+			dst.db.QueryRow(`SELECT CAST(created_at AS TEXT) FROM jobs WHERE id = ?`, pending.ID).Scan(&dstAt)
+			if srcAt != dstAt {
+				t.Errorf("created_at stored as %q, want %q", dstAt, srcAt)
+			}
+
+			// IDs carry on after the deleted job's
+			next, _ := dst.Create(ctx, "https://example.com/next")
+			if next.ID != pending.ID+1 {
+				t.Errorf("next job ID = %d, want %d", next.ID, pending.ID+1)
+			}
+
+			if _, err := dst.ImportState(ctx, bytes.NewReader(snapshot)); !errors.Is(err, ErrNotEmpty) {
+				t.Errorf("ImportState() into a used database error = %v, want ErrNotEmpty", err)
+			}
+
+			// A leftover outage list counts as state too
+			listed, err := New(filepath.Join(t.TempDir(), "listed.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer listed.Close()
+			if encrypted {
+				listed.Unlock(ctx, key)
+			}
+			listed.db.Exec(`INSERT INTO outage_jobs (job_id) VALUES (1)`)
+			if _, err := listed.ImportState(ctx, bytes.NewReader(snapshot)); !errors.Is(err, ErrNotEmpty) {
+				t.Errorf("ImportState() with outage_jobs rows error = %v, want ErrNotEmpty", err)
+			}
+		})
+	}
+}
+
+func TestRepository_ImportStateRejects(t *testing.T) {
+	ctx := context.Background()
+	src, cleanup := setupTestRepo(t)
+	defer cleanup()
+	src.Unlock(ctx, []byte("correct horse battery staple"))
+	src.Create(ctx, "https://example.com/a")
+	var buf bytes.Buffer
+	if err := src.ExportState(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		key      []byte
+		snapshot string
+		wantErr  error
+	}{
+		{"no key", nil, buf.String(), ErrKeyRequired},
+		{"wrong key", []byte("wrong"), buf.String(), ErrWrongKey},
+		{"not a snapshot", nil, `{"format":"other"}`, nil},
+		{"newer schema", nil, `{"format":"catcher-state","version":1,"schema":9999}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst, cleanup := setupTestRepo(t)
+			defer cleanup()
+			if tt.key != nil {
+				dst.Unlock(ctx, tt.key)
+			}
+			_, err := dst.ImportState(ctx, bytes.NewBufferString(tt.snapshot))
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("ImportState() error = %v, want %v", err, tt.wantErr)
+			}
+			var n int
+			dst.db.QueryRow(`SELECT COUNT(*) FROM jobs`).Scan(&n)
+			if n != 0 {
+				t.Errorf("%d job(s) imported, want none", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// bucketLayout keeps buckets in UTC with a fixed width so they sort as text.
+const bucketLayout = "2006-01-02T15:04:05Z"
+
+const day = 24 * time.Hour
+
+func bucketSize(period domain.StatsPeriod) time.Duration {
+	if period == domain.PeriodDay {
+		return day
+	}
+	return time.Hour
+}
+
+func bucketKey(t time.Time, size time.Duration) string {
+	return t.UTC().Truncate(size).Format(bucketLayout)
+}
+
+// addStats adds b's counters to the period bucket containing b.Start.
+func (r *Repository) addStats(ctx context.Context, tx *sql.Tx, period domain.StatsPeriod, b domain.StatsBucket) error {
+	_, err := r.stmtExec(ctx, tx,
+		`INSERT INTO job_stats (period, bucket, processor, submitted, completed, failed, bytes)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (period, bucket, processor) DO UPDATE SET
+		     submitted = submitted + excluded.submitted,
+		     completed = completed + excluded.completed,
+		     failed = failed + excluded.failed,
+		     bytes = bytes + excluded.bytes`,
+		period, bucketKey(b.Start, bucketSize(period)), b.Processor, b.Submitted, b.Completed, b.Failed, b.Bytes,
+	)
+	return err
+}
+
+// Stats implements domain.StatsRepository. Daily history includes hourly
+// buckets that have not been compacted yet.
+func (r *Repository) Stats(ctx context.Context, q domain.StatsQuery) ([]domain.StatsBucket, error) {
+	size := bucketSize(q.Period)
+	var buckets []domain.StatsBucket
+	err := r.retry(ctx, "stats", func() error {
+		rows, err := r.readQuery(ctx,
+			`SELECT bucket, processor, submitted, completed, failed, bytes FROM job_stats
+			 WHERE (period = ? OR period = ?) AND bucket >= ?`,
+			domain.PeriodHour, q.Period, bucketKey(q.Since, size),
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		buckets, err = collectBuckets(rows, size, q.ByProcessor)
+		return err
+	})
+	return buckets, err
+}
+
+// CompactStats implements domain.StatsRepository, returning the number of
+// hourly buckets folded into daily ones.
+func (r *Repository) CompactStats(ctx context.Context, before time.Time) (int64, error) {
+	cutoff := before.UTC().Format(bucketLayout)
+	var folded int64
+	compact := func(tx *sql.Tx) error {
+		rows, err := r.stmtQuery(ctx, tx,
+			`SELECT bucket, processor, submitted, completed, failed, bytes FROM job_stats
+			 WHERE period = ? AND bucket < ?`,
+			domain.PeriodHour, cutoff,
+		)
+		if err != nil {
+			return err
+		}
+		days, err := collectBuckets(rows, day, true)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, b := range days {
+			if err := r.addStats(ctx, tx, domain.PeriodDay, b); err != nil {
+				return err
+			}
+		}
+
+		result, err := r.stmtExec(ctx, tx,
+			`DELETE FROM job_stats WHERE period = ? AND bucket < ?`,
+			domain.PeriodHour, cutoff,
+		)
+		if err != nil {
+			return err
+		}
+		folded, err = result.RowsAffected()
+		return err
+	}
+	err := r.retry(ctx, "compact_stats", func() error { return r.withTx(ctx, compact) })
+	if err != nil {
+		return 0, err
+	}
+	return folded, nil
+}
+
+type bucketID struct {
+	start     time.Time
+	processor string
+}
+
+// collectBuckets sums rows into buckets of the given size, oldest first,
+// optionally keeping processors apart.
+func collectBuckets(rows *sql.Rows, size time.Duration, byProcessor bool) ([]domain.StatsBucket, error) {
+	var buckets []domain.StatsBucket
+	index := make(map[bucketID]int)
+	for rows.Next() {
+		var b domain.StatsBucket
+		var key string
+		if err := rows.Scan(&key, &b.Processor, &b.Submitted, &b.Completed, &b.Failed, &b.Bytes); err != nil {
+			return nil, err
+		}
+		start, err := time.Parse(bucketLayout, key)
+		if err != nil {
+			return nil, err
+		}
+		b.Start = start.Truncate(size)
+		if !byProcessor {
+			b.Processor = ""
+		}
+
+		id := bucketID{b.Start, b.Processor}
+		if i, ok := index[id]; ok {
+			dst := &buckets[i]
+			dst.Submitted += b.Submitted
+			dst.Completed += b.Completed
+			dst.Failed += b.Failed
+			dst.Bytes += b.Bytes
+			continue
+		}
+		index[id] = len(buckets)
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		if !buckets[i].Start.Equal(buckets[j].Start) {
+			return buckets[i].Start.Before(buckets[j].Start)
+		}
+		return buckets[i].Processor < buckets[j].Processor
+	})
+	return buckets, nil
+}
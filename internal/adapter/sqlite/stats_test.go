@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_StatsCountsTransitions(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := repo.Create(ctx, "https://example.com/video"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := repo.Complete(ctx, 1, domain.Completion{Processor: "youtube", Bytes: 1000}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if err := repo.Fail(ctx, 2, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	// Unknown jobs must not be counted
+	if err := repo.Complete(ctx, 99, domain.Completion{Processor: "youtube", Bytes: 1}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	buckets, err := repo.Stats(ctx, domain.StatsQuery{Period: domain.PeriodHour, Since: since})
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if len(buckets) != 1 {
+		t.Fatalf("Stats() returned %d buckets, want 1", len(buckets))
+	}
+	b := buckets[0]
+	if b.Submitted != 3 || b.Completed != 1 || b.Failed != 1 || b.Bytes != 1000 {
+		t.Errorf("bucket = %+v, want submitted=3 completed=1 failed=1 bytes=1000", b)
+	}
+	if want := time.Now().UTC().Truncate(time.Hour); !b.Start.Equal(want) {
+		t.Errorf("bucket start = %v, want %v", b.Start, want)
+	}
+
+	byProc, err := repo.Stats(ctx, domain.StatsQuery{Period: domain.PeriodHour, Since: since, ByProcessor: true})
+	if err != nil {
+		t.Fatalf("Stats(ByProcessor) error = %v", err)
+	}
+	if len(byProc) != 2 || byProc[0].Processor != "" || byProc[1].Processor != "youtube" || byProc[1].Bytes != 1000 {
+		t.Errorf("Stats(ByProcessor) = %+v, want unattributed and youtube buckets", byProc)
+	}
+
+	job, _ := repo.Get(ctx, 1)
+	if job.Bytes != 1000 {
+		t.Errorf("job.Bytes = %d, want 1000", job.Bytes)
+	}
+}
+
+func TestRepository_CompactStats(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	day1 := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	for _, hour := range []time.Time{day1.Add(3 * time.Hour), day1.Add(17 * time.Hour), day2.Add(2 * time.Hour)} {
+		if _, err := repo.db.Exec(
+			`INSERT INTO job_stats (period, bucket, processor, submitted, completed) VALUES ('hour', ?, '', 2, 1)`,
+			hour.Format(bucketLayout),
+		); err != nil {
+			t.Fatalf("seed error = %v", err)
+		}
+	}
+
+	folded, err := repo.CompactStats(ctx, day2)
+	if err != nil {
+		t.Fatalf("CompactStats() error = %v", err)
+	}
+	if folded != 2 {
+		t.Errorf("CompactStats() = %d, want 2", folded)
+	}
+
+	hourly, err := repo.Stats(ctx, domain.StatsQuery{Period: domain.PeriodHour, Since: day1})
+	if err != nil {
+		t.Fatalf("Stats(hour) error = %v", err)
+	}
+	if len(hourly) != 1 || !hourly[0].Start.Equal(day2.Add(2*time.Hour)) {
+		t.Errorf("hourly buckets = %+v, want only day2 02:00", hourly)
+	}
+
+	// Daily history merges compacted days with not-yet-compacted hours
+	daily, err := repo.Stats(ctx, domain.StatsQuery{Period: domain.PeriodDay, Since: day1})
+	if err != nil {
+		t.Fatalf("Stats(day) error = %v", err)
+	}
+	want := []domain.StatsBucket{
+		{Start: day1, Submitted: 4, Completed: 2},
+		{Start: day2, Submitted: 2, Completed: 1},
+	}
+	if len(daily) != len(want) {
+		t.Fatalf("daily buckets = %+v, want %+v", daily, want)
+	}
+	for i := range want {
+		if !daily[i].Start.Equal(want[i].Start) || daily[i].Submitted != want[i].Submitted || daily[i].Completed != want[i].Completed {
+			t.Errorf("daily[%d] = %+v, want %+v", i, daily[i], want[i])
+		}
+	}
+
+	// Compacting again is a no-op
+	if folded, err := repo.CompactStats(ctx, day2); err != nil || folded != 0 {
+		t.Errorf("second CompactStats() = %d, %v; want 0, nil", folded, err)
+	}
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// stmtCache prepares each query once and reuses it, so SQLite doesn't
+// parse the same SQL on every call. A *sql.Stmt prepares itself again on
+// any connection it hasn't run on, so cached statements keep working when
+// the pool replaces a bad or expired connection. Queries built at runtime
+// must come from a small, fixed set of shapes, as each is kept until Close.
+type stmtCache struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache(db *sql.DB) *stmtCache {
+	return &stmtCache{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// prepare returns the statement for query, preparing it on first use.
+func (c *stmtCache) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.stmts[query]; ok {
+		return s, nil
+	}
+	s, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = s
+	return s, nil
+}
+
+// close closes every cached statement.
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var first error
+	for query, s := range c.stmts {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+		delete(c.stmts, query)
+	}
+	return first
+}
+
+// stmt returns the cached statement for query, bound to tx unless it is
+// nil. Statements bound to tx are closed with it.
+func (r *Repository) stmt(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	s, err := r.stmts.prepare(ctx, query)
+	if err != nil || tx == nil {
+		return s, err
+	}
+	return tx.StmtContext(ctx, s), nil
+}
+
+// stmtExec runs query as a cached statement, in tx unless it is nil.
+func (r *Repository) stmtExec(ctx context.Context, tx *sql.Tx, query string, args ...any) (sql.Result, error) {
+	s, err := r.stmt(ctx, tx, query)
+	if err != nil {
+		return nil, err
+	}
+	return s.ExecContext(ctx, args...)
+}
+
+// stmtQuery runs query as a cached statement, in tx unless it is nil.
+func (r *Repository) stmtQuery(ctx context.Context, tx *sql.Tx, query string, args ...any) (*sql.Rows, error) {
+	s, err := r.stmt(ctx, tx, query)
+	if err != nil {
+		return nil, err
+	}
+	return s.QueryContext(ctx, args...)
+}
+
+// stmtQueryRow runs query as a cached statement, in tx unless it is nil,
+// for a single row.
+func (r *Repository) stmtQueryRow(ctx context.Context, tx *sql.Tx, query string, args ...any) scanner {
+	s, err := r.stmt(ctx, tx, query)
+	if err != nil {
+		return errRow{err}
+	}
+	return s.QueryRowContext(ctx, args...)
+}
+
+// errRow is a row that failed before it was queried.
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
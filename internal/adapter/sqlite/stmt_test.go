@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// useRepo runs a round of reads and writes, in and out of transactions.
+func useRepo(t *testing.T, repo *Repository) {
+	t.Helper()
+	ctx := context.Background()
+	job, err := repo.Create(ctx, "https://example.com/video")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Claim(ctx, job.ID); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	files := []domain.ResultFile{{Path: "/tmp/video.mp4", Bytes: 3}}
+	if err := repo.Complete(ctx, job.ID, domain.Completion{Bytes: 3, Files: files}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	got, err := repo.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Status != domain.StatusCompleted || len(got.Files) != 1 {
+		t.Errorf("Get() = %s with %d file(s), want completed with 1", got.Status, len(got.Files))
+	}
+	if _, err := repo.FindPending(ctx, 10); err != nil {
+		t.Fatalf("FindPending() error = %v", err)
+	}
+	if err := repo.SetHeld(ctx, job.ID, true); err != domain.ErrJobState {
+		t.Errorf("SetHeld() error = %v, want ErrJobState", err)
+	}
+}
+
+func TestStmtCache_Reuse(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	useRepo(t, repo)
+	n := len(repo.stmts.stmts)
+	if n == 0 {
+		t.Fatal("no statements cached")
+	}
+	useRepo(t, repo)
+	if got := len(repo.stmts.stmts); got != n {
+		t.Errorf("cached statements = %d after a second round, want %d", got, n)
+	}
+}
+
+func TestStmtCache_Reconnect(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Close every connection once it's released, so each use of a cached
+	// statement runs on a connection it wasn't prepared on
+	repo.db.SetMaxIdleConns(0)
+	for range 3 {
+		useRepo(t, repo)
+	}
+	repo.db.SetMaxIdleConns(2)
+	repo.db.SetConnMaxLifetime(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	useRepo(t, repo)
+}
+
+func TestStmtCache_Concurrent(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	job, _ := repo.Create(ctx, "https://example.com/video")
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 20 {
+				if _, err := repo.Get(ctx, job.ID); err != nil {
+					t.Errorf("Get() error = %v", err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestStmtCache_Close(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	job, _ := repo.Create(ctx, "https://example.com/video")
+
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(repo.stmts.stmts) != 0 {
+		t.Errorf("cached statements = %d after Close, want 0", len(repo.stmts.stmts))
+	}
+	if _, err := repo.Get(ctx, job.ID); err == nil {
+		t.Error("Get() after Close succeeded")
+	}
+}
@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// SaveView implements domain.ViewRepository.
+func (r *Repository) SaveView(ctx context.Context, v domain.View) error {
+	return r.retry(ctx, "save_view", func() error {
+		_, err := r.stmtExec(ctx, nil,
+			`INSERT INTO views (name, status, host) VALUES (?, ?, ?)
+			 ON CONFLICT (name) DO UPDATE SET status = excluded.status, host = excluded.host`,
+			v.Name, v.Status, v.Host,
+		)
+		return err
+	})
+}
+
+// View implements domain.ViewRepository.
+func (r *Repository) View(ctx context.Context, name string) (*domain.View, error) {
+	v := domain.View{Name: name}
+	err := r.retry(ctx, "view", func() error {
+		return r.stmtQueryRow(ctx, nil, `SELECT status, host FROM views WHERE name = ?`, name).Scan(&v.Status, &v.Host)
+	})
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrViewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Views implements domain.ViewRepository.
+func (r *Repository) Views(ctx context.Context) ([]domain.View, error) {
+	var views []domain.View
+	err := r.retry(ctx, "views", func() error {
+		rows, err := r.stmtQuery(ctx, nil, `SELECT name, status, host FROM views ORDER BY name`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		views = nil
+		for rows.Next() {
+			var v domain.View
+			if err := rows.Scan(&v.Name, &v.Status, &v.Host); err != nil {
+				return err
+			}
+			views = append(views, v)
+		}
+		return rows.Err()
+	})
+	return views, err
+}
+
+// DeleteView implements domain.ViewRepository.
+func (r *Repository) DeleteView(ctx context.Context, name string) error {
+	return r.retry(ctx, "delete_view", func() error {
+		result, err := r.stmtExec(ctx, nil, `DELETE FROM views WHERE name = ?`, name)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return domain.ErrViewNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRepository_Views(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := repo.View(ctx, "stuck-youtube"); err != domain.ErrViewNotFound {
+		t.Errorf("View(missing) error = %v, want ErrViewNotFound", err)
+	}
+	for _, v := range []domain.View{
+		{Name: "stuck-youtube", Status: domain.StatusPending, Host: "vimeo.com"},
+		{Name: "failed", Status: domain.StatusFailed},
+		{Name: "stuck-youtube", Status: domain.StatusProcessing, Host: "youtube.com"},
+	} {
+		if err := repo.SaveView(ctx, v); err != nil {
+			t.Fatalf("SaveView(%q) error = %v", v.Name, err)
+		}
+	}
+
+	want := domain.View{Name: "stuck-youtube", Status: domain.StatusProcessing, Host: "youtube.com"}
+	if v, err := repo.View(ctx, "stuck-youtube"); err != nil || *v != want {
+		t.Errorf("View() = %+v, %v, want the replacement %+v", v, err, want)
+	}
+	views, err := repo.Views(ctx)
+	if err != nil {
+		t.Fatalf("Views() error = %v", err)
+	}
+	var names []string
+	for _, v := range views {
+		names = append(names, v.Name)
+	}
+	if !slices.Equal(names, []string{"failed", "stuck-youtube"}) {
+		t.Errorf("Views() names = %v, want failed and stuck-youtube", names)
+	}
+
+	if err := repo.DeleteView(ctx, "failed"); err != nil {
+		t.Fatalf("DeleteView() error = %v", err)
+	}
+	if err := repo.DeleteView(ctx, "failed"); err != domain.ErrViewNotFound {
+		t.Errorf("DeleteView(again) error = %v, want ErrViewNotFound", err)
+	}
+}
+
+func TestRepository_List_Host(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plain"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			repo, cleanup := setupTestRepo(t)
+			defer cleanup()
+			ctx := context.Background()
+			if encrypted {
+				if err := repo.Unlock(ctx, []byte("correct horse battery staple")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var want []int64
+			for _, url := range []string{"https://youtube.com/1", "https://vimeo.com/2", "https://m.youtube.com/3", "https://youtube.com/4", "https://notyoutube.com/5"} {
+				job, _ := repo.Create(ctx, url)
+				if url != "https://vimeo.com/2" && url != "https://notyoutube.com/5" {
+					want = append([]int64{job.ID}, want...)
+				}
+			}
+
+			jobs, err := repo.List(ctx, domain.JobFilter{Host: "youtube.com", Limit: 2})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var got []int64
+			for _, job := range jobs {
+				got = append(got, job.ID)
+			}
+			if !slices.Equal(got, want[:2]) {
+				t.Errorf("List(youtube.com, limit 2) = %v, want %v", got, want[:2])
+			}
+
+			jobs, _ = repo.List(ctx, domain.JobFilter{Host: "youtube.com", Limit: 10, After: got[1]})
+			if len(jobs) != 1 || jobs[0].ID != want[2] {
+				t.Errorf("List(youtube.com, next page) = %+v, want job %d", jobs, want[2])
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+// Package watch submits the URLs in files dropped into a folder, such as
+// one synced from phones and laptops by Syncthing or Nextcloud. Handled
+// files are moved to its processed or failed subfolder.
+package watch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/urlscan"
+)
+
+// Subfolders of the watched folder that handled files are moved to.
+const (
+	ProcessedDir = "processed"
+	FailedDir    = "failed"
+)
+
+// ErrorSuffix is appended to a failed file's name to name the note saying
+// why it failed.
+const ErrorSuffix = ".error"
+
+// DefaultInterval is how often the folder is scanned by default.
+const DefaultInterval = 5 * time.Second
+
+// settle is how long a file must go unmodified before it is read, so one
+// still being written or synced isn't read half done.
+const settle = 2 * time.Second
+
+// maxFileSize caps the files read; a list of links is never this large.
+const maxFileSize = 64 << 10
+
+// Extensions of the files read. Others are moved to the failed folder.
+var extensions = map[string]bool{".url": true, ".webloc": true, ".txt": true, "": true}
+
+// SubmitFunc submits a URL, as domain.JobService.Submit does.
+type SubmitFunc func(ctx context.Context, url string) (*domain.Job, error)
+
+// Folder watches a folder for files of URLs.
+type Folder struct {
+	dir      string
+	submit   SubmitFunc
+	interval time.Duration
+	source   string
+	clock    domain.Clock
+	maxBatch int        // URLs one file may submit, 0 for any number
+	hold     SubmitFunc // submits the URLs past maxBatch; nil refuses the file
+
+	// stuck holds the files handled but not moved away, by name, so they
+	// aren't submitted again until they change
+	stuck map[string]fileState
+}
+
+// fileState tells whether a file changed since it was handled.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// New returns a Folder submitting the URLs in files in dir with submit,
+// scanning every interval, DefaultInterval if zero, as jobs of source,
+// "watch" if empty.
+func New(dir string, submit SubmitFunc, interval time.Duration, source string) *Folder {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if source == "" {
+		source = "watch"
+	}
+	return &Folder{dir: dir, submit: submit, interval: interval, source: source, clock: domain.SystemClock, stuck: map[string]fileState{}}
+}
+
+// SetClock replaces the clock that decides when files have settled and
+// when to scan, for tests. Call before Run.
+func (f *Folder) SetClock(c domain.Clock) {
+	f.clock = c
+}
+
+// SetMaxBatch limits how many URLs one file may submit, like
+// http.max_batch does for payloads. A larger file is moved to the failed
+// folder with nothing submitted, or with hold set, the URLs past max are
+// submitted with it, as held jobs. Zero allows any number. Call before Run.
+func (f *Folder) SetMaxBatch(max int, hold SubmitFunc) {
+	f.maxBatch = max
+	f.hold = hold
+}
+
+// Run creates the folder and its subfolders if needed, then scans it
+// until ctx is done.
+func (f *Folder) Run(ctx context.Context) error {
+	for _, dir := range []string{ProcessedDir, FailedDir} {
+		if err := os.MkdirAll(filepath.Join(f.dir, dir), 0o755); err != nil {
+			return err
+		}
+	}
+	log.Printf("watch: scanning %s every %s", f.dir, f.interval)
+	ticker := f.clock.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		if err := f.Scan(ctx); err != nil {
+			log.Printf("watch: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+		}
+	}
+}
+
+// Scan handles the files in the folder that have settled. Hidden files,
+// which sync tools write partial downloads to, and subfolders are left
+// alone, as are files that couldn't be moved away, until they change.
+func (f *Folder) Scan(ctx context.Context) error {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		present[name] = true
+		if !e.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || f.clock.Now().Sub(info.ModTime()) < settle {
+			continue
+		}
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if stuck, ok := f.stuck[name]; ok && stuck == state {
+			continue
+		}
+		delete(f.stuck, name)
+		if ctx.Err() != nil {
+			return nil
+		}
+		f.handle(ctx, name, state)
+	}
+	for name := range f.stuck {
+		if !present[name] {
+			delete(f.stuck, name) // moved or deleted by hand
+		}
+	}
+	return nil
+}
+
+// handle submits the URLs in the file name and moves it away.
+func (f *Folder) handle(ctx context.Context, name string, state fileState) {
+	path := filepath.Join(f.dir, name)
+	var problems []string
+	var urls []string
+	ext := strings.ToLower(filepath.Ext(name))
+	switch {
+	case !extensions[ext]:
+		problems = append(problems, fmt.Sprintf("unsupported file type %q: use .url, .webloc, or .txt", ext))
+	case state.size > maxFileSize:
+		problems = append(problems, fmt.Sprintf("file is over %d bytes", maxFileSize))
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("watch: read %s: %v", name, err)
+			return
+		}
+		urls = parse(ext, data)
+		switch {
+		case len(urls) == 0:
+			problems = append(problems, "no URL found")
+		case f.maxBatch > 0 && len(urls) > f.maxBatch && f.hold == nil:
+			problems = append(problems, fmt.Sprintf("file has %d URLs, more than the %d submitted at once: split it up", len(urls), f.maxBatch))
+			urls = nil
+		}
+	}
+
+	// Shortcuts are named after the page, which makes good notes
+	var notes string
+	if ext == ".url" || ext == ".webloc" {
+		notes = domain.TruncateNotes(strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	subCtx := domain.WithNotes(domain.WithSource(ctx, f.source), notes)
+	var ids []string
+	submit := f.submit
+	for i, u := range urls {
+		if f.maxBatch > 0 && i == f.maxBatch {
+			// The rest wait to be released, as for an oversized payload
+			submit = f.hold
+		}
+		job, err := submit(subCtx, u)
+		var de *domain.DuplicateError
+		switch {
+		case ctx.Err() != nil:
+			return // shutting down; handled again on the next start
+		case errors.As(err, &de):
+			ids = append(ids, fmt.Sprintf("%d (duplicate)", de.Job.ID))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", u, err))
+		default:
+			ids = append(ids, fmt.Sprint(job.ID))
+		}
+	}
+
+	dest := ProcessedDir
+	if len(problems) > 0 {
+		dest = FailedDir
+	}
+	moved, err := moveTo(path, filepath.Join(f.dir, dest))
+	if err != nil {
+		// Left in place, it would be submitted again on every scan
+		f.stuck[name] = state
+		log.Printf("watch: move %s to %s: %v; leaving it alone until it changes", name, dest, err)
+		return
+	}
+	if len(ids) > 0 {
+		log.Printf("watch: %s: submitted as job %s", name, strings.Join(ids, ", "))
+	}
+	if len(problems) > 0 {
+		log.Printf("watch: %s: moved to %s: %s", name, dest, strings.Join(problems, "; "))
+		note := strings.Join(problems, "\n") + "\n"
+		if err := os.WriteFile(moved+ErrorSuffix, []byte(note), 0o644); err != nil {
+			log.Printf("watch: %v", err)
+		}
+	}
+}
+
+// parse returns the URLs in a file with extension ext, in order and
+// without repeats. Internet shortcuts (.url) are read by their URL= line.
+func parse(ext string, data []byte) []string {
+	var found []string
+	if ext == ".url" {
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if len(line) > 4 && strings.EqualFold(line[:4], "URL=") {
+				found = append(found, line[4:])
+			}
+		}
+	} else {
+		// Property lists, XML or binary, hold their URLs as plain text
+		for _, u := range urlscan.Find(string(data)) {
+			if ext == ".webloc" {
+				u = html.UnescapeString(u)
+			}
+			found = append(found, u)
+		}
+	}
+	var urls []string
+	seen := map[string]bool{}
+	for _, u := range found {
+		if !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// moveTo moves the file at path into dir, numbering its name as
+// "name (2).txt" if dir already has one by that name, and returns its new
+// path.
+func moveTo(path, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	dest := filepath.Join(dir, base)
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(dest); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dest = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(base, ext), n, ext))
+	}
+	return dest, os.Rename(path, dest)
+}
@@ -0,0 +1,318 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// submitted records what a fake SubmitFunc was given.
+type submitted struct {
+	url, source, notes string
+}
+
+// fakeSubmit accepts every URL but those in fail and dup, returning
+// errors and duplicates for them.
+func fakeSubmit(got *[]submitted, fail, dup map[string]bool) SubmitFunc {
+	var id int64
+	return func(ctx context.Context, url string) (*domain.Job, error) {
+		*got = append(*got, submitted{url, domain.SourceFrom(ctx), domain.NotesFrom(ctx)})
+		id++
+		switch {
+		case fail[url]:
+			return nil, domain.ErrInvalidURL
+		case dup[url]:
+			return nil, &domain.DuplicateError{Job: &domain.Job{ID: 99}}
+		}
+		return &domain.Job{ID: id, URL: url}, nil
+	}
+}
+
+// dropFile writes a file into dir as settled long enough ago to be read.
+func dropFile(t *testing.T, dir, name, content string, mtime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		data string
+		want []string
+	}{
+		{
+			name: "internet shortcut",
+			ext:  ".url",
+			data: "[InternetShortcut]\r\nURL=https://example.com/watch?v=1\r\nIconIndex=0\r\n",
+			want: []string{"https://example.com/watch?v=1"},
+		},
+		{
+			name: "shortcut url key is case-insensitive",
+			ext:  ".url",
+			data: "[InternetShortcut]\nurl=https://example.com/a\n",
+			want: []string{"https://example.com/a"},
+		},
+		{
+			name: "webloc",
+			ext:  ".webloc",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>URL</key><string>https://example.com/v?a=1&amp;b=2</string></dict></plist>`,
+			want: []string{"https://example.com/v?a=1&b=2"},
+		},
+		{
+			name: "binary webloc",
+			ext:  ".webloc",
+			data: "bplist00\xd1\x01\x02SURL_\x10\x14https://example.com/b\x08\x0b",
+			want: []string{"https://example.com/b"},
+		},
+		{
+			name: "text with several urls",
+			ext:  ".txt",
+			data: "watch later: https://example.com/1.\nhttps://example.com/2\n(see https://example.com/1)\n",
+			want: []string{"https://example.com/1", "https://example.com/2"},
+		},
+		{
+			name: "no extension",
+			ext:  "",
+			data: "http://example.com/x",
+			want: []string{"http://example.com/x"},
+		},
+		{
+			name: "no url",
+			ext:  ".txt",
+			data: "just a note",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parse(tt.ext, []byte(tt.data))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-time.Minute)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ProcessedDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	dropFile(t, dir, "Some Video.url", "[InternetShortcut]\nURL=https://example.com/v\n", old)
+	dropFile(t, dir, "links.txt", "https://example.com/1\nhttps://example.com/dup\n", old)
+	dropFile(t, dir, "bad.txt", "https://example.com/ok https://example.com/bad", old)
+	dropFile(t, dir, "empty.txt", "nothing here", old)
+	dropFile(t, dir, "photo.jpg", "https://example.com/in-a-jpg", old)
+	dropFile(t, dir, "fresh.txt", "https://example.com/fresh", now.Add(-time.Second))
+	dropFile(t, dir, ".syncthing.partial.txt.tmp", "https://example.com/partial", old)
+	// Already processed once under the same name
+	dropFile(t, filepath.Join(dir, ProcessedDir), "links.txt", "", old)
+
+	var got []submitted
+	f := New(dir, fakeSubmit(&got, map[string]bool{"https://example.com/bad": true}, map[string]bool{"https://example.com/dup": true}), 0, "")
+	f.SetClock(domain.NewManualClock(now))
+	if err := f.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []submitted{
+		{"https://example.com/v", "watch", "Some Video"},
+		{"https://example.com/ok", "watch", ""},
+		{"https://example.com/bad", "watch", ""},
+		{"https://example.com/1", "watch", ""},
+		{"https://example.com/dup", "watch", ""},
+	}
+	slices.SortFunc(got, func(a, b submitted) int { return strings.Compare(a.url, b.url) })
+	slices.SortFunc(want, func(a, b submitted) int { return strings.Compare(a.url, b.url) })
+	if !slices.Equal(got, want) {
+		t.Errorf("submitted %v, want %v", got, want)
+	}
+
+	exists := map[string]bool{
+		"Some Video.url":                 false,
+		"fresh.txt":                      true,
+		".syncthing.partial.txt.tmp":     true,
+		"processed/Some Video.url":       true,
+		"processed/links.txt":            true,
+		"processed/links (2).txt":        true,
+		"failed/bad.txt":                 true,
+		"failed/bad.txt" + ErrorSuffix:   true,
+		"failed/empty.txt":               true,
+		"failed/empty.txt" + ErrorSuffix: true,
+		"failed/photo.jpg":               true,
+		"failed/photo.jpg" + ErrorSuffix: true,
+		"links.txt":                      false,
+		"bad.txt":                        false,
+		"processed/bad.txt":              false,
+	}
+	for name, want := range exists {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", name, got, want)
+		}
+	}
+
+	note, err := os.ReadFile(filepath.Join(dir, FailedDir, "bad.txt"+ErrorSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(note), "https://example.com/bad: ") {
+		t.Errorf("error note = %q, want the failed URL", note)
+	}
+	note, _ = os.ReadFile(filepath.Join(dir, FailedDir, "empty.txt"+ErrorSuffix))
+	if string(note) != "no URL found\n" {
+		t.Errorf("error note = %q, want no URL found", note)
+	}
+}
+
+func TestScanMaxBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		hold       bool
+		wantSubmit int
+		wantHeld   int
+		wantDir    string
+	}{
+		{"reject", false, 0, 0, FailedDir},
+		{"hold", true, 2, 1, ProcessedDir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+			dir := t.TempDir()
+			dropFile(t, dir, "channel.txt", "https://example.com/1\nhttps://example.com/2\nhttps://example.com/3\n", now.Add(-time.Minute))
+			dropFile(t, dir, "few.txt", "https://example.com/4\nhttps://example.com/5\n", now.Add(-time.Minute))
+
+			var got, held []submitted
+			f := New(dir, fakeSubmit(&got, nil, nil), 0, "")
+			f.SetClock(domain.NewManualClock(now))
+			var hold SubmitFunc
+			if tt.hold {
+				hold = fakeSubmit(&held, nil, nil)
+			}
+			f.SetMaxBatch(2, hold)
+			if err := f.Scan(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			// few.txt is within the limit either way
+			if len(got) != tt.wantSubmit+2 || len(held) != tt.wantHeld {
+				t.Errorf("submitted %v and held %v, want %d and %d", got, held, tt.wantSubmit+2, tt.wantHeld)
+			}
+			if tt.hold && (len(held) != 1 || held[0].url != "https://example.com/3") {
+				t.Errorf("held %v, want the URL past the limit", held)
+			}
+			if _, err := os.Stat(filepath.Join(dir, tt.wantDir, "channel.txt")); err != nil {
+				t.Errorf("channel.txt not moved to %s: %v", tt.wantDir, err)
+			}
+			if !tt.hold {
+				note, _ := os.ReadFile(filepath.Join(dir, FailedDir, "channel.txt"+ErrorSuffix))
+				if !strings.Contains(string(note), "file has 3 URLs, more than the 2") {
+					t.Errorf("error note = %q, want the limit", note)
+				}
+			}
+		})
+	}
+}
+
+func TestScanSource(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	dropFile(t, dir, "a.txt", "https://example.com/a", now.Add(-time.Minute))
+
+	var got []submitted
+	f := New(dir, fakeSubmit(&got, nil, nil), time.Second, "phone")
+	if err := f.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].source != "phone" {
+		t.Errorf("submitted %v, want one job from phone", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ProcessedDir, "a.txt")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScanCancelled(t *testing.T) {
+	dir := t.TempDir()
+	dropFile(t, dir, "a.txt", "https://example.com/a", time.Now().Add(-time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := New(dir, func(context.Context, string) (*domain.Job, error) {
+		cancel()
+		return nil, context.Canceled
+	}, 0, "")
+	if err := f.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Left for the next start rather than failed
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScanMissingDir(t *testing.T) {
+	f := New(filepath.Join(t.TempDir(), "missing"), nil, 0, "")
+	if err := f.Scan(context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Scan() = %v, want not exist", err)
+	}
+}
+
+func TestScanUnmovable(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	// A broken link where the folder should be, so nothing can be moved there
+	if err := os.Symlink("missing", filepath.Join(dir, ProcessedDir)); err != nil {
+		t.Fatal(err)
+	}
+	dropFile(t, dir, "a.txt", "https://example.com/a", now.Add(-time.Minute))
+
+	var got []submitted
+	f := New(dir, fakeSubmit(&got, nil, nil), 0, "")
+	f.SetClock(domain.NewManualClock(now))
+	scan := func() {
+		t.Helper()
+		if err := f.Scan(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scan()
+	scan()
+	if len(got) != 1 {
+		t.Fatalf("submitted %v, want the file handled once while it can't be moved", got)
+	}
+
+	// Changing the file has it handled again
+	dropFile(t, dir, "a.txt", "https://example.com/b", now.Add(-time.Minute/2))
+	scan()
+	if len(got) != 2 || got[1].url != "https://example.com/b" {
+		t.Errorf("submitted %v, want the changed file handled again", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatalf("a.txt moved: %v", err)
+	}
+
+	os.Remove(filepath.Join(dir, "a.txt"))
+	scan()
+	if len(f.stuck) != 0 {
+		t.Errorf("stuck = %v, want files gone from the folder forgotten", f.stuck)
+	}
+}
@@ -1,45 +1,547 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
-
-	"github.com/BurntSushi/toml"
 )
 
-// ProcessorConfig defines a URL processor from the config file.
+// ProcessorConfig defines a URL processor from the config file. Args,
+// ProbeArgs, and TargetDir may use {url} and {name} for each named group
+// in the matching pattern.
 type ProcessorConfig struct {
-	Name      string   `toml:"name"`
-	Pattern   string   `toml:"pattern"`
+	Name    string `toml:"name"`
+	Pattern string `toml:"pattern"`
+	// Patterns are matched alongside Pattern; a URL matching any of them
+	// and none of Exclude is handled.
+	Patterns  []string `toml:"patterns"`
+	Exclude   []string `toml:"exclude"`
 	Command   string   `toml:"command"`
 	Args      []string `toml:"args"`
 	TargetDir string   `toml:"target_dir"`
 	Isolate   *bool    `toml:"isolate"`
+	// WorkDirQuota is how much, e.g. "20GB", an isolated run's work dir
+	// may hold. A run going over it is stopped and its job failed without
+	// further attempts. Empty means no limit.
+	WorkDirQuota string `toml:"work_dir_quota"`
+	// Priority breaks ties when several processors match a URL equally
+	// well; higher wins.
+	Priority int `toml:"priority"`
+	// Resubmit is what to do with a URL that already completed: "allow"
+	// (the default), "reject", or "replace".
+	Resubmit string `toml:"resubmit"`
+	// ProbeArgs runs Command to estimate a URL's size before downloading
+	// it, e.g. ["-J", "{url}"] for yt-dlp; the output is read as yt-dlp
+	// JSON.
+	ProbeArgs []string `toml:"probe_args"`
+	// MaxSize is the estimated size, e.g. "2GB", above which Oversize
+	// applies. Requires ProbeArgs.
+	MaxSize string `toml:"max_size"`
+	// Oversize is what to do with a URL over MaxSize: "hold" it for
+	// approval (the default) or "fail" it.
+	Oversize string `toml:"oversize"`
+	// SuccessExitCodes are non-zero exit codes that count as success, e.g.
+	// 101 from yt-dlp when everything is already in its archive.
+	SuccessExitCodes []int `toml:"success_exit_codes"`
+	// RateLimitDelay is how long a job waits before its next attempt when
+	// a run fails with HTTP 429 and the output doesn't include a
+	// Retry-After header, which is always honored.
+	RateLimitDelay time.Duration `toml:"rate_limit_delay"`
+	// Concurrency gives the processor a queue of its own, running this many
+	// jobs at once. Zero shares the default queue, which runs one at a time.
+	Concurrency int `toml:"concurrency"`
+	// Cost is how much of the worker budget each job takes while running.
+	// Zero counts as 1.
+	Cost int `toml:"cost"`
+	// UserAgent is the User-Agent for jobs that don't set their own. It
+	// replaces {user_agent} in Args and ProbeArgs, and is passed to yt-dlp
+	// with --user-agent if they don't use the placeholder.
+	UserAgent string `toml:"user_agent"`
+	// SourceAddress is the local IP address, or the name of the network
+	// interface, to download from. It replaces {source_address} in Args
+	// and ProbeArgs, and is passed to yt-dlp with --source-address if they
+	// don't use the placeholder.
+	SourceAddress string `toml:"source_address"`
+	// ForceIP is "4" or "6" to connect over that IP version only, passed
+	// to the tools in ForceIPFlags with their flags for it. Other commands
+	// can't use it. Empty leaves the choice to the tool.
+	ForceIP string `toml:"force_ip"`
+	// Fragments is how many fragments of a DASH or HLS video yt-dlp
+	// downloads at once, passed as --concurrent-fragments; 1 to 16. Zero
+	// leaves yt-dlp's default of one.
+	Fragments int `toml:"fragments"`
+	// Downloader is "aria2c" to have yt-dlp or youtube-dl hand downloads
+	// to aria2c. Empty uses their own downloader.
+	Downloader string `toml:"downloader"`
+	// Connections is how many connections aria2c opens per download, 1
+	// to 16, with Downloader or when Command is aria2c. Zero leaves
+	// aria2c's default.
+	Connections int `toml:"connections"`
+	// DNS overrides the global DNS settings for this processor's commands:
+	// its servers replace the global ones, and its hosts are pinned too.
+	DNS DNSConfig `toml:"dns"`
+	// Library lists directories, searched recursively, whose files count
+	// as already downloaded: a URL whose pattern captures an "id" group
+	// is skipped if a file named with "[<id>]" is found, as yt-dlp names
+	// them.
+	Library []string `toml:"library"`
+	// LibraryRescan is how long the index of Library is used before the
+	// directories are walked again. Zero means ten minutes.
+	LibraryRescan time.Duration `toml:"library_rescan"`
+	// Fake makes this a fake processor for load testing, which runs no
+	// Command; see FakeConfig.
+	Fake *FakeConfig `toml:"fake"`
+}
+
+// FakeConfig sets up a fake processor. Instead of downloading, it waits
+// Delay, fails a FailureRate share of runs, and otherwise writes a file of
+// Size zero bytes, to load-test the queue, retries, and metrics without
+// touching real sites.
+type FakeConfig struct {
+	Delay time.Duration `toml:"delay"`
+	// FailureRate is the share of runs that fail, from 0 to 1.
+	FailureRate float64 `toml:"failure_rate"`
+	// Size is the size of the file written, e.g. "10MB". Empty or zero
+	// writes no file.
+	Size string `toml:"size"`
+}
+
+// AllPatterns returns Pattern, if set, followed by Patterns.
+func (pc ProcessorConfig) AllPatterns() []string {
+	if pc.Pattern == "" {
+		return pc.Patterns
+	}
+	return append([]string{pc.Pattern}, pc.Patterns...)
+}
+
+// RewriteConfig is a rule rewriting submitted URLs before validation and
+// processor matching. It sets exactly one of Replace, Unwrap, and
+// StripParams.
+type RewriteConfig struct {
+	// Pattern is what Replace replaces, and limits Unwrap and StripParams
+	// to matching URLs.
+	Pattern string `toml:"pattern"`
+	// Replace is the replacement for Pattern, which may use $1 or ${name}.
+	Replace *string `toml:"replace"`
+	// Unwrap names the query parameter holding the real URL of a redirector.
+	Unwrap string `toml:"unwrap"`
+	// StripParams removes query parameters by name; "*" matches any run of
+	// characters, as in "utm_*".
+	StripParams []string `toml:"strip_params"`
+}
+
+// TokenConfig is a named API token for submitting URLs, optionally limited
+// to some processors and hosts, such as a token handed to guests.
+type TokenConfig struct {
+	Name string `toml:"name"`
+	// Token is sent as a bearer token, basic-auth password, or token query
+	// parameter in place of the webhook secret.
+	Token string `toml:"token"`
+	// Processors names the processors the token may trigger; empty allows
+	// any.
+	Processors []string `toml:"processors"`
+	// Hosts limits the token to URLs on these hosts or their subdomains;
+	// empty allows any.
+	Hosts []string `toml:"hosts"`
+}
+
+// ValidationConfig defines checks applied to submitted URLs.
+type ValidationConfig struct {
+	AllowedSchemes []string      `toml:"allowed_schemes"`
+	MaxURLLength   int           `toml:"max_url_length"`
+	ResolveHosts   bool          `toml:"resolve_hosts"`
+	DedupeWindow   time.Duration `toml:"dedupe_window"` // 0 disables
+}
+
+// DefaultValidation returns the validation settings used when the config
+// file does not override them.
+func DefaultValidation() ValidationConfig {
+	return ValidationConfig{
+		AllowedSchemes: []string{"http", "https"},
+		MaxURLLength:   4096,
+	}
+}
+
+// HTTPConfig defines HTTP server timeouts and size limits.
+// Zero values keep the server defaults.
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration `toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `toml:"read_timeout"`
+	WriteTimeout      time.Duration `toml:"write_timeout"`
+	IdleTimeout       time.Duration `toml:"idle_timeout"`
+	MaxHeaderBytes    int           `toml:"max_header_bytes"`
+	MaxBodyBytes      int64         `toml:"max_body_bytes"`
+	// RequireUID makes /jobs/{id} routes accept only job UIDs, not
+	// numeric IDs, and the routes listing jobs need the admin token.
+	RequireUID bool `toml:"require_uid"`
+	// ShareTTL enables the QR share page at /share/qr, whose links stay
+	// valid this long. Zero disables it.
+	ShareTTL time.Duration `toml:"share_ttl"`
+	// MaxBatch is how many URLs one payload to POST /webhook/{format} may
+	// submit without confirm=true, and one file in the watch folder at
+	// all. Zero allows any number.
+	MaxBatch int `toml:"max_batch"`
+	// BatchOverflow is what to do with a payload over MaxBatch: "reject"
+	// it (the default), or "hold" the URLs past MaxBatch until released.
+	BatchOverflow string `toml:"batch_overflow"`
+}
+
+// HeadersConfig defines security headers sent with every response.
+// Empty values keep the server defaults.
+type HeadersConfig struct {
+	ContentSecurityPolicy string   `toml:"content_security_policy"`
+	FrameAncestors        []string `toml:"frame_ancestors"`
+	ReferrerPolicy        string   `toml:"referrer_policy"`
+}
+
+// MetricsConfig defines metric labelling.
+type MetricsConfig struct {
+	// Hosts are labelled individually; all other hosts share the "other" label.
+	Hosts []string `toml:"hosts"`
+}
+
+// LoggingConfig controls what log lines may contain.
+type LoggingConfig struct {
+	// RedactURLs strips credentials, query strings, and token-like path
+	// segments from URLs in logs and stored error messages.
+	RedactURLs bool `toml:"redact_urls"`
+}
+
+// ApprovalConfig defines which submissions wait for an admin to approve
+// them before they are processed.
+type ApprovalConfig struct {
+	// Hosts whose URLs, including those of their subdomains, need approval.
+	// "*" matches every host.
+	Hosts []string `toml:"hosts"`
+}
+
+// MDNSConfig advertises the API on the local network, so companion apps
+// can find it without being given its address.
+type MDNSConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Name is the instance name apps show. Empty means "catcher on <host>".
+	Name string `toml:"name"`
+}
+
+// WatchConfig submits the URLs in files dropped into a folder, such as
+// one synced from other devices, moving them to its processed or failed
+// subfolder once handled.
+type WatchConfig struct {
+	// Dir is the folder watched. Empty disables watching.
+	Dir string `toml:"dir"`
+	// Interval is how often Dir is scanned. Zero means every 5 seconds.
+	Interval time.Duration `toml:"interval"`
+	// Source is recorded on the jobs submitted. Empty means "watch".
+	Source string `toml:"source"`
+}
+
+// ConnectivityConfig checks the uplink by sending HEAD requests to a
+// known endpoint. While it is down, the worker starts no jobs, and jobs
+// failing with network errors don't use up their attempts.
+type ConnectivityConfig struct {
+	// URL is checked. Any response counts as online. Empty disables the
+	// check.
+	URL string `toml:"url"`
+	// Interval is how often URL is checked. Zero means every 30 seconds.
+	Interval time.Duration `toml:"interval"`
+	// Timeout is how long a check may take. Zero means 10 seconds.
+	Timeout time.Duration `toml:"timeout"`
+}
+
+// RedirectConfig defines which submissions have their redirects followed,
+// so shortened URLs are matched by their destination.
+type RedirectConfig struct {
+	// Hosts whose URLs, including those of their subdomains, are resolved.
+	// "*" matches every host.
+	Hosts   []string      `toml:"hosts"`
+	MaxHops int           `toml:"max_hops"`
+	Timeout time.Duration `toml:"timeout"`
+	// UserAgent is sent by submissions that don't set their own. Empty
+	// sends Go's default.
+	UserAgent string `toml:"user_agent"`
+	// SourceAddress is the local IP address, or the name of the network
+	// interface, to connect from. Empty lets the system choose.
+	SourceAddress string `toml:"source_address"`
+	// ForceIP is "4" or "6" to connect over that IP version only.
+	ForceIP string `toml:"force_ip"`
+}
+
+// DefaultRedirects returns the redirect settings used when the config file
+// does not override them.
+func DefaultRedirects() RedirectConfig {
+	return RedirectConfig{MaxHops: 5, Timeout: 5 * time.Second}
+}
+
+// DNSConfig overrides name resolution, for networks with broken DNS or to
+// pin a host to one CDN edge.
+type DNSConfig struct {
+	// Servers are the name servers to ask, as "ip" or "ip:port". Empty
+	// asks the system's.
+	Servers []string `toml:"servers"`
+	// Hosts pins host names to IP addresses, like /etc/hosts.
+	Hosts map[string]string `toml:"hosts"`
+}
+
+// Merge returns d overridden by over: over's servers, if it has any, and
+// the hosts of both, with over's address for a host pinned in both.
+func (d DNSConfig) Merge(over DNSConfig) DNSConfig {
+	merged := DNSConfig{Servers: d.Servers}
+	if len(over.Servers) > 0 {
+		merged.Servers = over.Servers
+	}
+	if len(d.Hosts)+len(over.Hosts) > 0 {
+		merged.Hosts = make(map[string]string, len(d.Hosts)+len(over.Hosts))
+		maps.Copy(merged.Hosts, d.Hosts)
+		maps.Copy(merged.Hosts, over.Hosts)
+	}
+	return merged
+}
+
+// WorkerConfig defines how the worker shares the machine between jobs.
+type WorkerConfig struct {
+	// Budget caps the total cost of the jobs running at once, across all
+	// queues. Zero leaves it to each queue's concurrency.
+	Budget int `toml:"budget"`
+	// StallTimeout is how long a running job may go without output before
+	// it is warned about; after twice as long it is stopped and retried.
+	// Zero disables the watchdog.
+	StallTimeout time.Duration `toml:"stall_timeout"`
+	// VerifyFiles checks on start that the files of jobs completed within
+	// this long are still on disk. Zero skips the check.
+	VerifyFiles time.Duration `toml:"verify_files"`
+	// Checksums records a SHA-256 digest of each file a job produces and
+	// writes it beside the file as <name>.sha256.
+	Checksums bool `toml:"checksums"`
+	// Provenance records the source URL, job ID, and download date on each
+	// file a job produces, as extended attributes or a hidden sidecar.
+	Provenance bool `toml:"provenance"`
+	// VerifyMedia runs ffprobe on audio and video results before a job
+	// completes, retrying jobs whose files are truncated or unreadable.
+	VerifyMedia bool `toml:"verify_media"`
+	// FFProbe is the ffprobe command for VerifyMedia; "ffprobe" if empty.
+	FFProbe string `toml:"ffprobe"`
+	// OutageAfter is how many jobs in a row, on more than one host, must
+	// fail to reach their sites for the network to count as down. No jobs
+	// are started until it is back, then those that failed meanwhile are
+	// requeued. Zero disables it.
+	OutageAfter int `toml:"outage_after"`
+	// OutageProbe is the host:port dialled to see whether the network is
+	// back. Empty means the host of the last URL that failed.
+	OutageProbe string `toml:"outage_probe"`
+	// OutageProbeInterval is how often OutageProbe is dialled while the
+	// network is down. Zero means every 30 seconds.
+	OutageProbeInterval time.Duration `toml:"outage_probe_interval"`
+	// OutageSweepRate is how many jobs that failed during an outage are
+	// requeued per minute after it. Zero means 10.
+	OutageSweepRate int `toml:"outage_sweep_rate"`
+	// OutageMaxRequeues is how many outages a job is requeued after at
+	// most. Zero means 3.
+	OutageMaxRequeues int `toml:"outage_max_requeues"`
+	// ProbeCacheTTL is how long the results of processors' probe_args runs
+	// are kept in the database and reused for the same URL. Zero probes
+	// every time.
+	ProbeCacheTTL time.Duration `toml:"probe_cache_ttl"`
+}
+
+// DatabaseConfig defines how the database is accessed.
+type DatabaseConfig struct {
+	// ReadPool is the number of read-only connections kept for listings,
+	// status counts, and stats. Zero runs them on the main connections.
+	ReadPool int `toml:"read_pool"`
+}
+
+// ReplicationConfig describes continuous replication of the database by a
+// process running beside catcher, such as Litestream.
+type ReplicationConfig struct {
+	// SyncFile is touched by the replicator after every sync; its age is
+	// the replication lag.
+	SyncFile string `toml:"sync_file"`
+	// BackupFile exists while a backup is running. Heavy maintenance waits
+	// for it to go away.
+	BackupFile string `toml:"backup_file"`
+	// MaxLag is the lag past which /health reports degraded. Zero never
+	// does.
+	MaxLag time.Duration `toml:"max_lag"`
+}
+
+// Enabled reports whether replication is being watched.
+func (rc ReplicationConfig) Enabled() bool {
+	return rc.SyncFile != "" || rc.BackupFile != ""
+}
+
+// MaintenanceConfig defines periodic housekeeping.
+type MaintenanceConfig struct {
+	Interval time.Duration `toml:"interval"`
+	// HourlyStatsRetention is how long hourly throughput buckets are kept
+	// before being folded into daily ones.
+	HourlyStatsRetention time.Duration `toml:"hourly_stats_retention"`
+	// JobRetention is how long completed and failed jobs are kept. Zero
+	// keeps them forever.
+	JobRetention time.Duration `toml:"job_retention"`
+	// ArchiveJobs moves jobs past JobRetention to an archive table instead
+	// of deleting them.
+	ArchiveJobs bool `toml:"archive_jobs"`
+}
+
+// DefaultMaintenance returns the maintenance settings used when the config
+// file does not override them.
+func DefaultMaintenance() MaintenanceConfig {
+	return MaintenanceConfig{
+		Interval:             time.Hour,
+		HourlyStatsRetention: 7 * 24 * time.Hour,
+	}
 }
 
 // fileConfig represents the TOML file structure.
 type fileConfig struct {
-	Secret     string            `toml:"secret"`
-	Processors []ProcessorConfig `toml:"processor"`
+	// ExpandEnv opts in to ${VAR} interpolation in string values.
+	ExpandEnv    bool               `toml:"expand_env"`
+	Secret       string             `toml:"secret"`
+	AdminToken   string             `toml:"admin_token"`
+	DBKeyFile    string             `toml:"db_key_file"`
+	BasePath     string             `toml:"base_path"`
+	HTTP         HTTPConfig         `toml:"http"`
+	Headers      HeadersConfig      `toml:"headers"`
+	Metrics      MetricsConfig      `toml:"metrics"`
+	Logging      LoggingConfig      `toml:"logging"`
+	Database     DatabaseConfig     `toml:"database"`
+	Replication  ReplicationConfig  `toml:"replication"`
+	Worker       WorkerConfig       `toml:"worker"`
+	Maintenance  MaintenanceConfig  `toml:"maintenance"`
+	Validation   ValidationConfig   `toml:"validation"`
+	Approval     ApprovalConfig     `toml:"approval"`
+	Redirects    RedirectConfig     `toml:"redirects"`
+	DNS          DNSConfig          `toml:"dns"`
+	MDNS         MDNSConfig         `toml:"mdns"`
+	Watch        WatchConfig        `toml:"watch"`
+	Connectivity ConnectivityConfig `toml:"connectivity"`
+	Rewrites     []RewriteConfig    `toml:"rewrite"`
+	Tokens       []TokenConfig      `toml:"token"`
+	Processors   []ProcessorConfig  `toml:"processor"`
+
+	interpolated []string // environment values substituted by expand_env
 }
 
+// Run modes select which components a process runs.
+const (
+	ModeAll    = "all"
+	ModeAPI    = "api"
+	ModeWorker = "worker"
+)
+
 // Config holds application configuration.
 type Config struct {
-	Port         int
-	DBPath       string
-	PollInterval time.Duration
-	MaxRetries   int
-	ConfigPath   string
-	Secret       string
-	Processors   []ProcessorConfig
+	Mode          string
+	Port          int
+	DBPath        string
+	PollInterval  time.Duration
+	MaxRetries    int
+	ShutdownGrace time.Duration
+	DBTimeout     time.Duration
+	Debug         bool
+	ConfigPath    string
+	Secret        string
+	AdminToken    string
+	DBKeyFile     string
+	BasePath      string
+	HTTP          HTTPConfig
+	Headers       HeadersConfig
+	Metrics       MetricsConfig
+	Logging       LoggingConfig
+	Database      DatabaseConfig
+	Replication   ReplicationConfig
+	Worker        WorkerConfig
+	Maintenance   MaintenanceConfig
+	Validation    ValidationConfig
+	Approval      ApprovalConfig
+	Redirects     RedirectConfig
+	DNS           DNSConfig
+	MDNS          MDNSConfig
+	Watch         WatchConfig
+	Connectivity  ConnectivityConfig
+	Rewrites      []RewriteConfig
+	Tokens        []TokenConfig
+	Processors    []ProcessorConfig
+
+	sources map[string]string // setting key to Source*, when not a default
+	dbKey   []byte            // from CATCHER_DB_KEY
+
+	interpolated []string // environment values substituted into the file
 }
 
-// DefaultDBPath returns the default database path using XDG_CACHE_HOME.
+// ValidMode reports whether mode is a known run mode.
+func ValidMode(mode string) bool {
+	switch mode {
+	case ModeAll, ModeAPI, ModeWorker:
+		return true
+	}
+	return false
+}
+
+// RunsAPI returns true if the HTTP server should be started.
+func (c *Config) RunsAPI() bool {
+	return c.Mode == ModeAll || c.Mode == ModeAPI
+}
+
+// RunsWorker returns true if the job poller should be started.
+func (c *Config) RunsWorker() bool {
+	return c.Mode == ModeAll || c.Mode == ModeWorker
+}
+
+// StateDir returns the directory holding runtime state: the database and
+// anything kept alongside it, such as processor work directories.
+func (c *Config) StateDir() string {
+	return filepath.Dir(ExpandPath(c.DBPath))
+}
+
+// WorkDir returns where isolated processor runs get their temp directories.
+func (c *Config) WorkDir() string {
+	return filepath.Join(c.StateDir(), "work")
+}
+
+// goos selects platform defaults; a variable so tests can switch it.
+var goos = runtime.GOOS
+
+// appSupportDir returns catcher's directory under the macOS Application
+// Support folder.
+func appSupportDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Library", "Application Support", "catcher")
+}
+
+// DefaultStateDir returns the default state directory using XDG_STATE_HOME,
+// or Application Support on macOS when that is unset.
+func DefaultStateDir() string {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		if goos == "darwin" {
+			return appSupportDir()
+		}
+		home, _ := os.UserHomeDir()
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateDir, "catcher")
+}
+
+// DefaultDBPath returns the default database path in DefaultStateDir.
 func DefaultDBPath() string {
+	return filepath.Join(DefaultStateDir(), "jobs.db")
+}
+
+// legacyDBPath returns where the database lived before it moved out of
+// XDG_CACHE_HOME, which cleanup tools are free to wipe.
+func legacyDBPath() string {
 	cacheDir := os.Getenv("XDG_CACHE_HOME")
 	if cacheDir == "" {
 		home, _ := os.UserHomeDir()
@@ -48,19 +550,98 @@ func DefaultDBPath() string {
 	return filepath.Join(cacheDir, "catcher", "jobs.db")
 }
 
+// migrateDB moves a database at from to to, along with its WAL and shared
+// memory files, unless to already exists. It reports whether anything
+// moved. Archived jobs live in the same file, so they move with it.
+func migrateDB(from, to string) (bool, error) {
+	if _, err := os.Stat(to); err == nil {
+		return false, nil
+	}
+	if _, err := os.Stat(from); err != nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return false, err
+	}
+	// The database goes first, so a failure leaves it where it was; if a
+	// sidecar then fails, what moved is put back to keep the set together
+	suffixes := []string{"", "-wal", "-shm"}
+	for i, suffix := range suffixes {
+		if err := moveFile(from+suffix, to+suffix); err != nil && !os.IsNotExist(err) {
+			for _, done := range suffixes[:i] {
+				moveFile(to+done, from+done)
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// rename is os.Rename, replaced in tests.
+var rename = os.Rename
+
+// moveFile renames from to to, or copies and removes it when they are on
+// different filesystems.
+func moveFile(from, to string) error {
+	err := rename(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+	return os.Remove(from)
+}
+
 // DefaultConfigPath returns the default config path using XDG_CONFIG_HOME.
+// On macOS with XDG_CONFIG_HOME unset it is in Application Support, unless
+// a config already exists in ~/.config.
 func DefaultConfigPath() string {
 	configDir := os.Getenv("XDG_CONFIG_HOME")
 	if configDir == "" {
 		home, _ := os.UserHomeDir()
 		configDir = filepath.Join(home, ".config")
+		if goos == "darwin" {
+			if _, err := os.Stat(filepath.Join(configDir, "catcher", "config.toml")); err != nil {
+				return filepath.Join(appSupportDir(), "config.toml")
+			}
+		}
 	}
 	return filepath.Join(configDir, "catcher", "config.toml")
 }
 
-// DefaultTargetDir returns the default download directory.
+// DefaultTargetDir returns the default download directory: ~/Movies on
+// macOS, ~/Videos elsewhere. Both are the platform's folder for videos,
+// which is what processors deliver; ~/Downloads is left to browsers.
 func DefaultTargetDir() string {
 	home, _ := os.UserHomeDir()
+	if goos == "darwin" {
+		return filepath.Join(home, "Movies")
+	}
 	return filepath.Join(home, "Videos")
 }
 
@@ -73,48 +654,182 @@ func ExpandPath(path string) string {
 	return path
 }
 
-// Load parses flags, config file, and environment to build Config.
-func Load() *Config {
-	cfg := &Config{}
+// Load parses args (without the program name), config file, and environment
+// to build Config. An invalid config file is an error, reported as a
+// *FileError.
+func Load(args []string) (*Config, error) {
+	cfg := &Config{
+		Validation:  DefaultValidation(),
+		Maintenance: DefaultMaintenance(),
+		Redirects:   DefaultRedirects(),
+		sources:     make(map[string]string),
+	}
 
-	flag.IntVar(&cfg.Port, "port", 8080, "HTTP server port")
-	flag.StringVar(&cfg.DBPath, "db", DefaultDBPath(), "SQLite database path")
-	flag.DurationVar(&cfg.PollInterval, "poll-interval", 5*time.Second, "Worker poll interval")
-	flag.IntVar(&cfg.MaxRetries, "max-retries", 3, "Maximum retry attempts")
-	flag.StringVar(&cfg.ConfigPath, "config", DefaultConfigPath(), "Config file path")
-	flag.Parse()
+	fs := flag.NewFlagSet("catcher", flag.ExitOnError)
+	fs.StringVar(&cfg.Mode, "mode", ModeAll, "Run mode: api, worker, or all")
+	fs.IntVar(&cfg.Port, "port", 8080, "HTTP server port")
+	fs.StringVar(&cfg.DBPath, "db", DefaultDBPath(), "SQLite database path")
+	fs.DurationVar(&cfg.PollInterval, "poll-interval", 5*time.Second, "Worker poll interval")
+	fs.IntVar(&cfg.MaxRetries, "max-retries", 3, "Maximum retry attempts")
+	fs.DurationVar(&cfg.ShutdownGrace, "shutdown-grace", 25*time.Second, "Time to let in-flight jobs finish on shutdown")
+	fs.DurationVar(&cfg.DBTimeout, "db-timeout", 30*time.Second, "Deadline for each database operation")
+	fs.BoolVar(&cfg.Debug, "debug", false, "Log debug output (toggle at runtime with SIGUSR2)")
+	fs.StringVar(&cfg.ConfigPath, "config", DefaultConfigPath(), "Config file path")
+	fs.Parse(args)
+	fs.Visit(func(f *flag.Flag) {
+		cfg.sources[strings.ReplaceAll(f.Name, "-", "_")] = SourceFlag
+	})
 
 	// Load TOML config file if exists
 	configPath := ExpandPath(cfg.ConfigPath)
 	if _, err := os.Stat(configPath); err == nil {
 		log.Printf("loading config from %s", configPath)
-		var fc fileConfig
-		if _, err := toml.DecodeFile(configPath, &fc); err == nil {
-			cfg.Secret = fc.Secret
-			cfg.Processors = fc.Processors
-			log.Printf("found %d processor(s) in config", len(cfg.Processors))
-		} else {
-			log.Printf("failed to parse config: %v", err)
+		fc := fileConfig{Validation: DefaultValidation(), Maintenance: DefaultMaintenance(), Redirects: DefaultRedirects()}
+		if err := decodeFile(configPath, &fc); err != nil {
+			return nil, err
+		}
+		cfg.Secret = fc.Secret
+		cfg.AdminToken = fc.AdminToken
+		cfg.DBKeyFile = fc.DBKeyFile
+		cfg.BasePath = fc.BasePath
+		cfg.HTTP = fc.HTTP
+		cfg.Headers = fc.Headers
+		cfg.Metrics = fc.Metrics
+		cfg.Logging = fc.Logging
+		cfg.Database = fc.Database
+		cfg.Replication = fc.Replication
+		cfg.Worker = fc.Worker
+		cfg.Maintenance = fc.Maintenance
+		cfg.Validation = fc.Validation
+		cfg.Approval = fc.Approval
+		cfg.Redirects = fc.Redirects
+		cfg.DNS = fc.DNS
+		cfg.MDNS = fc.MDNS
+		cfg.Watch = fc.Watch
+		cfg.Connectivity = fc.Connectivity
+		cfg.Rewrites = fc.Rewrites
+		cfg.Tokens = fc.Tokens
+		cfg.Processors = fc.Processors
+		cfg.interpolated = fc.interpolated
+		for key, v := range map[string]string{"secret": fc.Secret, "admin_token": fc.AdminToken, "db_key_file": fc.DBKeyFile, "base_path": fc.BasePath} {
+			if v != "" {
+				cfg.sources[key] = SourceFile
+			}
 		}
+		log.Printf("found %d processor(s) in config", len(cfg.Processors))
 	} else {
 		log.Printf("no config file at %s", configPath)
 	}
 
 	// Env overrides (runtime settings only)
+	if mode := os.Getenv("CATCHER_MODE"); mode != "" {
+		cfg.Mode = mode
+		cfg.sources["mode"] = SourceEnv
+		log.Printf("CATCHER_MODE override: %s", mode)
+	}
 	if port := os.Getenv("CATCHER_PORT"); port != "" {
 		if p, err := strconv.Atoi(port); err == nil {
 			cfg.Port = p
+			cfg.sources["port"] = SourceEnv
 			log.Printf("CATCHER_PORT override: %d", p)
 		}
 	}
 	if db := os.Getenv("CATCHER_DB"); db != "" {
 		cfg.DBPath = db
+		cfg.sources["db"] = SourceEnv
 		log.Printf("CATCHER_DB override: %s", db)
 	}
+	if grace := os.Getenv("CATCHER_SHUTDOWN_GRACE"); grace != "" {
+		if d, err := time.ParseDuration(grace); err == nil {
+			cfg.ShutdownGrace = d
+			cfg.sources["shutdown_grace"] = SourceEnv
+			log.Printf("CATCHER_SHUTDOWN_GRACE override: %s", d)
+		}
+	}
+	if timeout := os.Getenv("CATCHER_DB_TIMEOUT"); timeout != "" {
+		if d, err := time.ParseDuration(timeout); err == nil {
+			cfg.DBTimeout = d
+			cfg.sources["db_timeout"] = SourceEnv
+			log.Printf("CATCHER_DB_TIMEOUT override: %s", d)
+		}
+	}
+	if basePath := os.Getenv("CATCHER_BASE_PATH"); basePath != "" {
+		cfg.BasePath = basePath
+		cfg.sources["base_path"] = SourceEnv
+		log.Printf("CATCHER_BASE_PATH override: %s", basePath)
+	}
 	if secret := os.Getenv("CATCHER_SECRET"); secret != "" {
 		cfg.Secret = secret
+		cfg.sources["secret"] = SourceEnv
 		log.Println("CATCHER_SECRET override from environment")
 	}
+	if token := os.Getenv("CATCHER_ADMIN_TOKEN"); token != "" {
+		cfg.AdminToken = token
+		cfg.sources["admin_token"] = SourceEnv
+		log.Println("CATCHER_ADMIN_TOKEN override from environment")
+	}
 
-	return cfg
+	if keyFile := os.Getenv("CATCHER_DB_KEY_FILE"); keyFile != "" {
+		cfg.DBKeyFile = keyFile
+		cfg.sources["db_key_file"] = SourceEnv
+		log.Printf("CATCHER_DB_KEY_FILE override: %s", keyFile)
+	}
+	if key := os.Getenv("CATCHER_DB_KEY"); key != "" {
+		cfg.dbKey = []byte(key)
+		cfg.sources["db_key"] = SourceEnv
+		log.Println("CATCHER_DB_KEY override from environment")
+	}
+
+	return cfg, nil
+}
+
+// DBKey returns the database encryption key from CATCHER_DB_KEY or the key
+// file, or nil if encryption is not configured.
+func (c *Config) DBKey() ([]byte, error) {
+	if len(c.dbKey) > 0 {
+		return c.dbKey, nil
+	}
+	if c.DBKeyFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(ExpandPath(c.DBKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("read db key file: %w", err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("db key file %s is empty", c.DBKeyFile)
+	}
+	return key, nil
+}
+
+// Secrets returns the values that must never appear in logs or job
+// diagnostics: the webhook secret, admin token, API tokens, database key
+// from the environment or key file, and every value substituted by
+// expand_env.
+func (c *Config) Secrets() []string {
+	key, _ := c.DBKey() // an unreadable key file fails startup anyway
+	secrets := []string{c.Secret, c.AdminToken, string(key)}
+	for _, t := range c.Tokens {
+		secrets = append(secrets, t.Token)
+	}
+	return append(secrets, c.interpolated...)
+}
+
+// MigrateLegacyDB moves a database left in the old cache location to the
+// default state location, once. It only applies while the default path is
+// in use; if the move fails, the old database is used in place.
+func (c *Config) MigrateLegacyDB() {
+	if c.DBPath != DefaultDBPath() {
+		return
+	}
+	legacy := legacyDBPath()
+	moved, err := migrateDB(legacy, c.DBPath)
+	switch {
+	case err != nil:
+		log.Printf("warning: could not move database from %s: %v; using it in place", legacy, err)
+		c.DBPath = legacy
+	case moved:
+		log.Printf("moved database from %s to %s", legacy, c.DBPath)
+	}
 }
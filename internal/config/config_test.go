@@ -3,39 +3,151 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
 func TestDefaultDBPath(t *testing.T) {
-	// Test with XDG_CACHE_HOME set
-	t.Run("with XDG_CACHE_HOME", func(t *testing.T) {
-		original := os.Getenv("XDG_CACHE_HOME")
-		defer os.Setenv("XDG_CACHE_HOME", original)
-
-		os.Setenv("XDG_CACHE_HOME", "/custom/cache")
+	t.Run("with XDG_STATE_HOME", func(t *testing.T) {
+		t.Setenv("XDG_STATE_HOME", "/custom/state")
 		path := DefaultDBPath()
 
-		expected := "/custom/cache/catcher/jobs.db"
+		expected := "/custom/state/catcher/jobs.db"
 		if path != expected {
 			t.Errorf("DefaultDBPath() = %q, want %q", path, expected)
 		}
 	})
 
-	// Test without XDG_CACHE_HOME
-	t.Run("without XDG_CACHE_HOME", func(t *testing.T) {
-		original := os.Getenv("XDG_CACHE_HOME")
-		defer os.Setenv("XDG_CACHE_HOME", original)
-
-		os.Unsetenv("XDG_CACHE_HOME")
+	t.Run("without XDG_STATE_HOME", func(t *testing.T) {
+		t.Setenv("XDG_STATE_HOME", "")
 		path := DefaultDBPath()
 
-		if !strings.HasSuffix(path, filepath.Join(".cache", "catcher", "jobs.db")) {
-			t.Errorf("DefaultDBPath() = %q, want suffix .cache/catcher/jobs.db", path)
+		if !strings.HasSuffix(path, filepath.Join(".local", "state", "catcher", "jobs.db")) {
+			t.Errorf("DefaultDBPath() = %q, want suffix .local/state/catcher/jobs.db", path)
+		}
+	})
+}
+
+func TestLegacyDBPath(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/custom/cache")
+	if got, want := legacyDBPath(), "/custom/cache/catcher/jobs.db"; got != want {
+		t.Errorf("legacyDBPath() = %q, want %q", got, want)
+	}
+}
+
+func TestMigrateDB(t *testing.T) {
+	write := func(t *testing.T, path, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("moves database and sidecars", func(t *testing.T) {
+		dir := t.TempDir()
+		from := filepath.Join(dir, "cache", "catcher", "jobs.db")
+		to := filepath.Join(dir, "state", "catcher", "jobs.db")
+		write(t, from, "db")
+		write(t, from+"-wal", "wal")
+
+		moved, err := migrateDB(from, to)
+		if err != nil || !moved {
+			t.Fatalf("migrateDB() = %v, %v; want true, nil", moved, err)
+		}
+		if data, err := os.ReadFile(to); err != nil || string(data) != "db" {
+			t.Errorf("ReadFile(to) = %q, %v", data, err)
+		}
+		if _, err := os.Stat(to + "-wal"); err != nil {
+			t.Errorf("WAL not moved: %v", err)
+		}
+		if _, err := os.Stat(from); !os.IsNotExist(err) {
+			t.Errorf("legacy database still present: %v", err)
+		}
+	})
+
+	t.Run("keeps existing database", func(t *testing.T) {
+		dir := t.TempDir()
+		from, to := filepath.Join(dir, "old.db"), filepath.Join(dir, "new.db")
+		write(t, from, "old")
+		write(t, to, "new")
+
+		moved, err := migrateDB(from, to)
+		if err != nil || moved {
+			t.Fatalf("migrateDB() = %v, %v; want false, nil", moved, err)
+		}
+		if data, _ := os.ReadFile(to); string(data) != "new" {
+			t.Errorf("database overwritten: %q", data)
+		}
+	})
+
+	t.Run("copies across filesystems", func(t *testing.T) {
+		defer func(r func(string, string) error) { rename = r }(rename)
+		rename = func(string, string) error { return &os.LinkError{Op: "rename", Err: syscall.EXDEV} }
+		dir := t.TempDir()
+		from, to := filepath.Join(dir, "old", "jobs.db"), filepath.Join(dir, "new", "jobs.db")
+		write(t, from, "db")
+		write(t, from+"-wal", "wal")
+
+		moved, err := migrateDB(from, to)
+		if err != nil || !moved {
+			t.Fatalf("migrateDB() = %v, %v; want true, nil", moved, err)
+		}
+		for path, want := range map[string]string{to: "db", to + "-wal": "wal"} {
+			if data, err := os.ReadFile(path); err != nil || string(data) != want {
+				t.Errorf("ReadFile(%s) = %q, %v; want %q", path, data, err, want)
+			}
+		}
+		if _, err := os.Stat(from); !os.IsNotExist(err) {
+			t.Errorf("legacy database still present: %v", err)
+		}
+	})
+
+	t.Run("puts back the database when a sidecar fails", func(t *testing.T) {
+		dir := t.TempDir()
+		from, to := filepath.Join(dir, "old", "jobs.db"), filepath.Join(dir, "new", "jobs.db")
+		write(t, from, "db")
+		write(t, from+"-wal", "wal")
+		write(t, filepath.Join(to+"-wal", "blocker"), "") // a directory in the WAL's way
+
+		if moved, err := migrateDB(from, to); err == nil || moved {
+			t.Fatalf("migrateDB() = %v, %v; want an error", moved, err)
+		}
+		if data, err := os.ReadFile(from); err != nil || string(data) != "db" {
+			t.Errorf("ReadFile(from) = %q, %v; want the database back", data, err)
+		}
+		if _, err := os.Stat(to); !os.IsNotExist(err) {
+			t.Errorf("database left at the new path: %v", err)
+		}
+	})
+
+	t.Run("nothing to move", func(t *testing.T) {
+		dir := t.TempDir()
+		moved, err := migrateDB(filepath.Join(dir, "old.db"), filepath.Join(dir, "new.db"))
+		if err != nil || moved {
+			t.Errorf("migrateDB() = %v, %v; want false, nil", moved, err)
 		}
 	})
 }
 
+func TestConfig_StateDir(t *testing.T) {
+	cfg := &Config{DBPath: "/var/lib/catcher/jobs.db"}
+	if got := cfg.StateDir(); got != "/var/lib/catcher" {
+		t.Errorf("StateDir() = %q", got)
+	}
+	if got := cfg.WorkDir(); got != "/var/lib/catcher/work" {
+		t.Errorf("WorkDir() = %q", got)
+	}
+}
+
 func TestDefaultTargetDir(t *testing.T) {
 	path := DefaultTargetDir()
 	if !strings.HasSuffix(path, "Videos") {
@@ -57,3 +169,221 @@ func TestConfig_Defaults(t *testing.T) {
 		t.Errorf("MaxRetries = %d, want 3", cfg.MaxRetries)
 	}
 }
+
+func TestConfig_Mode(t *testing.T) {
+	tests := []struct {
+		mode       string
+		valid      bool
+		runsAPI    bool
+		runsWorker bool
+	}{
+		{mode: ModeAll, valid: true, runsAPI: true, runsWorker: true},
+		{mode: ModeAPI, valid: true, runsAPI: true, runsWorker: false},
+		{mode: ModeWorker, valid: true, runsAPI: false, runsWorker: true},
+		{mode: "bogus", valid: false, runsAPI: false, runsWorker: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := &Config{Mode: tt.mode}
+			if got := ValidMode(tt.mode); got != tt.valid {
+				t.Errorf("ValidMode(%q) = %v, want %v", tt.mode, got, tt.valid)
+			}
+			if got := cfg.RunsAPI(); got != tt.runsAPI {
+				t.Errorf("RunsAPI() = %v, want %v", got, tt.runsAPI)
+			}
+			if got := cfg.RunsWorker(); got != tt.runsWorker {
+				t.Errorf("RunsWorker() = %v, want %v", got, tt.runsWorker)
+			}
+		})
+	}
+}
+
+func TestFileConfig_HTTP(t *testing.T) {
+	data := `
+[http]
+read_header_timeout = "2s"
+idle_timeout = "1m"
+max_body_bytes = 2048
+share_ttl = "10m"
+`
+	var fc fileConfig
+	if _, err := toml.Decode(data, &fc); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if fc.HTTP.ReadHeaderTimeout != 2*time.Second {
+		t.Errorf("ReadHeaderTimeout = %v, want 2s", fc.HTTP.ReadHeaderTimeout)
+	}
+	if fc.HTTP.IdleTimeout != time.Minute {
+		t.Errorf("IdleTimeout = %v, want 1m", fc.HTTP.IdleTimeout)
+	}
+	if fc.HTTP.MaxBodyBytes != 2048 {
+		t.Errorf("MaxBodyBytes = %d, want 2048", fc.HTTP.MaxBodyBytes)
+	}
+	if fc.HTTP.ReadTimeout != 0 {
+		t.Errorf("ReadTimeout = %v, want 0 (server default)", fc.HTTP.ReadTimeout)
+	}
+	if fc.HTTP.ShareTTL != 10*time.Minute {
+		t.Errorf("ShareTTL = %v, want 10m", fc.HTTP.ShareTTL)
+	}
+}
+
+func TestFileConfig_MaintenanceDefaults(t *testing.T) {
+	data := `
+[maintenance]
+interval = "15m"
+`
+	fc := fileConfig{Maintenance: DefaultMaintenance()}
+	if _, err := toml.Decode(data, &fc); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if fc.Maintenance.Interval != 15*time.Minute {
+		t.Errorf("Interval = %v, want 15m", fc.Maintenance.Interval)
+	}
+	if fc.Maintenance.HourlyStatsRetention != 7*24*time.Hour {
+		t.Errorf("HourlyStatsRetention = %v, want default 168h", fc.Maintenance.HourlyStatsRetention)
+	}
+	if fc.Maintenance.JobRetention != 0 || fc.Maintenance.ArchiveJobs {
+		t.Errorf("JobRetention, ArchiveJobs = %v, %v, want jobs kept by default", fc.Maintenance.JobRetention, fc.Maintenance.ArchiveJobs)
+	}
+}
+
+func TestDefaults_Darwin(t *testing.T) {
+	defer func(old string) { goos = old }(goos)
+	goos = "darwin"
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	support := filepath.Join(home, "Library", "Application Support", "catcher")
+	if got := DefaultDBPath(); got != filepath.Join(support, "jobs.db") {
+		t.Errorf("DefaultDBPath() = %q", got)
+	}
+	if got := DefaultConfigPath(); got != filepath.Join(support, "config.toml") {
+		t.Errorf("DefaultConfigPath() = %q", got)
+	}
+	if got := DefaultTargetDir(); got != filepath.Join(home, "Movies") {
+		t.Errorf("DefaultTargetDir() = %q", got)
+	}
+
+	// An existing ~/.config setup keeps working
+	legacy := filepath.Join(home, ".config", "catcher", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := DefaultConfigPath(); got != legacy {
+		t.Errorf("DefaultConfigPath() with existing ~/.config = %q, want %q", got, legacy)
+	}
+
+	// XDG variables still win
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+	if got := DefaultDBPath(); got != "/xdg/state/catcher/jobs.db" {
+		t.Errorf("DefaultDBPath() with XDG_STATE_HOME = %q", got)
+	}
+}
+
+func TestConfig_DBKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "db.key")
+	if err := os.WriteFile(keyFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(t.TempDir(), "empty.key")
+	os.WriteFile(empty, []byte("\n"), 0o600)
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    string
+		wantErr bool
+	}{
+		{name: "not configured"},
+		{name: "key file", cfg: Config{DBKeyFile: keyFile}, want: "from-file"},
+		{name: "env wins over file", cfg: Config{DBKeyFile: keyFile, dbKey: []byte("from-env")}, want: "from-env"},
+		{name: "missing file", cfg: Config{DBKeyFile: keyFile + ".missing"}, wantErr: true},
+		{name: "empty file", cfg: Config{DBKeyFile: empty}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.DBKey()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DBKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("DBKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Secrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	data := "expand_env = true\nsecret = \"hook-secret\"\n\n[[token]]\nname = \"guest\"\ntoken = \"guest-token\"\n\n[[processor]]\nname = \"a\"\npattern = \"a\"\ncommand = \"a\"\nargs = [\"--token\", \"${CATCHER_TEST_TOKEN}\", \"${CATCHER_TEST_UNSET:-fallback}\"]\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CATCHER_TEST_TOKEN", "api-token-123")
+	t.Setenv("CATCHER_DB_KEY", "db-key")
+
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got := strings.Join(cfg.Secrets(), ",")
+	for _, want := range []string{"hook-secret", "guest-token", "api-token-123", "db-key"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Secrets() = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "fallback") {
+		t.Errorf("Secrets() = %q, includes a default that came from the file", got)
+	}
+}
+
+func TestConfig_Secrets_KeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "db.key")
+	if err := os.WriteFile(keyFile, []byte("file-db-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{DBKeyFile: keyFile}
+	if got := cfg.Secrets(); !slices.Contains(got, "file-db-key") {
+		t.Errorf("Secrets() = %q, missing the key from the key file", got)
+	}
+}
+
+func TestDNSConfig_Merge(t *testing.T) {
+	global := DNSConfig{
+		Servers: []string{"1.1.1.1"},
+		Hosts:   map[string]string{"a.example": "192.0.2.1", "b.example": "192.0.2.2"},
+	}
+	tests := []struct {
+		name string
+		over DNSConfig
+		want DNSConfig
+	}{
+		{name: "empty", want: global},
+		{
+			name: "override",
+			over: DNSConfig{Servers: []string{"9.9.9.9:53"}, Hosts: map[string]string{"b.example": "192.0.2.3", "c.example": "2001:db8::1"}},
+			want: DNSConfig{
+				Servers: []string{"9.9.9.9:53"},
+				Hosts:   map[string]string{"a.example": "192.0.2.1", "b.example": "192.0.2.3", "c.example": "2001:db8::1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := global.Merge(tt.over); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got := (DNSConfig{}).Merge(DNSConfig{}); got.Hosts != nil || got.Servers != nil {
+		t.Errorf("empty Merge() = %+v, want zero", got)
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"io"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Where a setting's effective value came from.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+)
+
+// redacted replaces secret values in the effective config.
+const redacted = "[redacted]"
+
+// Source reports where the effective value of a top-level setting came
+// from, e.g. Source("port"). Environment variables win over flags.
+func (c *Config) Source(key string) string {
+	if s, ok := c.sources[key]; ok {
+		return s
+	}
+	return SourceDefault
+}
+
+// effectiveConfig is the config file layout extended with the runtime
+// settings that only flags and the environment can set.
+type effectiveConfig struct {
+	Mode          string             `toml:"mode"`
+	Port          int                `toml:"port"`
+	DB            string             `toml:"db"`
+	PollInterval  string             `toml:"poll_interval"`
+	MaxRetries    int                `toml:"max_retries"`
+	ShutdownGrace string             `toml:"shutdown_grace"`
+	DBTimeout     string             `toml:"db_timeout"`
+	Debug         bool               `toml:"debug"`
+	Config        string             `toml:"config"`
+	Secret        string             `toml:"secret"`
+	AdminToken    string             `toml:"admin_token"`
+	DBKey         string             `toml:"db_key"`
+	DBKeyFile     string             `toml:"db_key_file"`
+	BasePath      string             `toml:"base_path"`
+	Sources       map[string]string  `toml:"sources"`
+	HTTP          HTTPConfig         `toml:"http"`
+	Headers       HeadersConfig      `toml:"headers"`
+	Metrics       MetricsConfig      `toml:"metrics"`
+	Logging       LoggingConfig      `toml:"logging"`
+	Database      DatabaseConfig     `toml:"database"`
+	Replication   ReplicationConfig  `toml:"replication"`
+	Worker        WorkerConfig       `toml:"worker"`
+	Maintenance   MaintenanceConfig  `toml:"maintenance"`
+	Validation    ValidationConfig   `toml:"validation"`
+	Approval      ApprovalConfig     `toml:"approval"`
+	Redirects     RedirectConfig     `toml:"redirects"`
+	DNS           DNSConfig          `toml:"dns"`
+	MDNS          MDNSConfig         `toml:"mdns"`
+	Watch         WatchConfig        `toml:"watch"`
+	Connectivity  ConnectivityConfig `toml:"connectivity"`
+	Rewrites      []RewriteConfig    `toml:"rewrite"`
+	Tokens        []TokenConfig      `toml:"token"`
+	Processors    []ProcessorConfig  `toml:"processor"`
+}
+
+// WriteEffective writes the merged configuration as TOML, with secrets
+// redacted and a [sources] table saying where each top-level setting came
+// from.
+func (c *Config) WriteEffective(w io.Writer) error {
+	e := effectiveConfig{
+		Mode:          c.Mode,
+		Port:          c.Port,
+		DB:            c.DBPath,
+		PollInterval:  c.PollInterval.String(),
+		MaxRetries:    c.MaxRetries,
+		ShutdownGrace: c.ShutdownGrace.String(),
+		DBTimeout:     c.DBTimeout.String(),
+		Debug:         c.Debug,
+		Config:        c.ConfigPath,
+		Secret:        redact(c.Secret),
+		AdminToken:    redact(c.AdminToken),
+		DBKey:         redact(string(c.dbKey)),
+		DBKeyFile:     c.DBKeyFile,
+		BasePath:      c.BasePath,
+		Sources:       make(map[string]string),
+		HTTP:          c.HTTP,
+		Headers:       c.Headers,
+		Metrics:       c.Metrics,
+		Logging:       c.Logging,
+		Database:      c.Database,
+		Replication:   c.Replication,
+		Worker:        c.Worker,
+		Maintenance:   c.Maintenance,
+		Validation:    c.Validation,
+		Approval:      c.Approval,
+		Redirects:     c.Redirects,
+		DNS:           c.DNS,
+		MDNS:          c.MDNS,
+		Watch:         c.Watch,
+		Connectivity:  c.Connectivity,
+		Rewrites:      c.Rewrites,
+		Processors:    make([]ProcessorConfig, len(c.Processors)),
+	}
+	for _, t := range c.Tokens {
+		t.Token = redact(t.Token)
+		e.Tokens = append(e.Tokens, t)
+	}
+	isolate := true
+	for i, pc := range c.Processors {
+		if pc.TargetDir == "" {
+			pc.TargetDir = DefaultTargetDir()
+		}
+		if pc.Isolate == nil {
+			pc.Isolate = &isolate
+		}
+		if pc.Oversize == "" && pc.MaxSize != "" {
+			pc.Oversize = "hold"
+		}
+		if pc.Resubmit == "" {
+			pc.Resubmit = "allow"
+		}
+		e.Processors[i] = pc
+	}
+	for _, key := range []string{
+		"mode", "port", "db", "poll_interval", "max_retries", "shutdown_grace",
+		"db_timeout", "debug", "config", "secret", "admin_token", "db_key", "db_key_file", "base_path",
+	} {
+		e.Sources[key] = c.Source(key)
+	}
+	return toml.NewEncoder(w).Encode(e)
+}
+
+// ServiceArgs returns flags reproducing the effective runtime settings in a
+// process that doesn't share this environment, such as a service. The
+// config and database paths are always included, made absolute.
+func (c *Config) ServiceArgs() ([]string, error) {
+	configPath, err := filepath.Abs(ExpandPath(c.ConfigPath))
+	if err != nil {
+		return nil, err
+	}
+	dbPath, err := filepath.Abs(ExpandPath(c.DBPath))
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"--config", configPath, "--db", dbPath}
+	for _, f := range []struct{ key, flag, value string }{
+		{"mode", "--mode", c.Mode},
+		{"port", "--port", strconv.Itoa(c.Port)},
+		{"poll_interval", "--poll-interval", c.PollInterval.String()},
+		{"max_retries", "--max-retries", strconv.Itoa(c.MaxRetries)},
+		{"shutdown_grace", "--shutdown-grace", c.ShutdownGrace.String()},
+		{"db_timeout", "--db-timeout", c.DBTimeout.String()},
+	} {
+		if c.Source(f.key) != SourceDefault {
+			args = append(args, f.flag, f.value)
+		}
+	}
+	if c.Debug {
+		args = append(args, "--debug")
+	}
+	return args, nil
+}
+
+// EnvOnlySettings lists settings taken from the environment that have no
+// flag, so ServiceArgs can't carry them.
+func (c *Config) EnvOnlySettings() []string {
+	var keys []string
+	for _, key := range []string{"secret", "admin_token", "db_key", "db_key_file", "base_path"} {
+		if c.Source(key) == SourceEnv {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestLoad_Sources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("secret = \"s\"\nbase_path = \"/c\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CATCHER_PORT", "9000")
+	t.Setenv("CATCHER_BASE_PATH", "/env")
+	t.Setenv("CATCHER_DB_TIMEOUT", "5s")
+
+	cfg, err := Load([]string{"--config", path, "--port", "8081", "--max-retries", "5"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want env to win over flag", cfg.Port)
+	}
+	if cfg.DBTimeout != 5*time.Second {
+		t.Errorf("DBTimeout = %s, want 5s from env", cfg.DBTimeout)
+	}
+	for key, want := range map[string]string{
+		"port":        SourceEnv,
+		"max_retries": SourceFlag,
+		"config":      SourceFlag,
+		"secret":      SourceFile,
+		"base_path":   SourceEnv,
+		"db_timeout":  SourceEnv,
+		"mode":        SourceDefault,
+		"admin_token": SourceDefault,
+	} {
+		if got := cfg.Source(key); got != want {
+			t.Errorf("Source(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestConfig_WriteEffective(t *testing.T) {
+	cfg := &Config{
+		Mode:        ModeAll,
+		Port:        8080,
+		DBPath:      "/var/lib/catcher/jobs.db",
+		Secret:      "webhook-secret",
+		AdminToken:  "admin-secret",
+		Validation:  DefaultValidation(),
+		Maintenance: DefaultMaintenance(),
+		Redirects:   RedirectConfig{Hosts: []string{"t.co"}, MaxHops: 3, Timeout: 2 * time.Second},
+		Tokens:      []TokenConfig{{Name: "guest", Token: "guest-secret", Processors: []string{"yt"}}},
+		Processors:  []ProcessorConfig{{Name: "yt", Pattern: "youtube", Command: "yt-dlp"}},
+		sources:     map[string]string{"port": SourceEnv},
+	}
+
+	var b strings.Builder
+	if err := cfg.WriteEffective(&b); err != nil {
+		t.Fatalf("WriteEffective() error = %v", err)
+	}
+	out := b.String()
+	if strings.Contains(out, "webhook-secret") || strings.Contains(out, "admin-secret") || strings.Contains(out, "guest-secret") {
+		t.Errorf("secrets not redacted:\n%s", out)
+	}
+
+	var got effectiveConfig
+	if _, err := toml.Decode(out, &got); err != nil {
+		t.Fatalf("output is not valid TOML: %v\n%s", err, out)
+	}
+	if got.Secret != redacted || got.AdminToken != redacted {
+		t.Errorf("Secret, AdminToken = %q, %q", got.Secret, got.AdminToken)
+	}
+	if len(got.Tokens) != 1 || got.Tokens[0].Token != redacted || got.Tokens[0].Name != "guest" {
+		t.Errorf("Tokens = %+v", got.Tokens)
+	}
+	if r := got.Redirects; len(r.Hosts) != 1 || r.Hosts[0] != "t.co" || r.MaxHops != 3 || r.Timeout != 2*time.Second {
+		t.Errorf("Redirects = %+v", r)
+	}
+	if got.Sources["port"] != SourceEnv || got.Sources["db"] != SourceDefault {
+		t.Errorf("Sources = %v", got.Sources)
+	}
+	p := got.Processors[0]
+	if p.TargetDir != DefaultTargetDir() || p.Isolate == nil || !*p.Isolate {
+		t.Errorf("processor defaults not applied: %+v", p)
+	}
+	if cfg.Processors[0].TargetDir != "" {
+		t.Error("WriteEffective modified the config")
+	}
+}
+
+func TestConfig_ServiceArgs(t *testing.T) {
+	cfg := &Config{
+		Mode:         ModeWorker,
+		Port:         8080,
+		DBPath:       "/var/lib/catcher/jobs.db",
+		ConfigPath:   "/etc/catcher/config.toml",
+		PollInterval: 5 * time.Second,
+		MaxRetries:   5,
+		Debug:        true,
+		sources: map[string]string{
+			"mode":        SourceEnv,
+			"max_retries": SourceFlag,
+			"secret":      SourceEnv,
+			"admin_token": SourceFile,
+		},
+	}
+
+	args, err := cfg.ServiceArgs()
+	if err != nil {
+		t.Fatalf("ServiceArgs() error = %v", err)
+	}
+	want := []string{
+		"--config", "/etc/catcher/config.toml",
+		"--db", "/var/lib/catcher/jobs.db",
+		"--mode", "worker",
+		"--max-retries", "5",
+		"--debug",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("ServiceArgs() = %q, want %q", args, want)
+	}
+	if got := cfg.EnvOnlySettings(); !reflect.DeepEqual(got, []string{"secret"}) {
+		t.Errorf("EnvOnlySettings() = %q, want [secret]", got)
+	}
+}
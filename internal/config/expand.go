@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// envRef matches ${VAR} and ${VAR:-default}; $${ escapes a literal ${.
+var envRef = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces environment references in s, returning the names of
+// referenced variables that are unset and have no default. A default also
+// replaces a variable that is set but empty.
+func expandEnv(s string, lookup func(string) (string, bool)) (string, []string) {
+	var missing []string
+	out := envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		m := envRef.FindStringSubmatch(ref)
+		v, ok := lookup(m[1])
+		if strings.Contains(ref, ":-") && v == "" {
+			// As in POSIX shells, :- also covers a variable set but empty
+			return m[2]
+		}
+		if ok {
+			return v
+		}
+		missing = append(missing, m[1])
+		return ""
+	})
+	return out, missing
+}
+
+// expandConfig expands environment references in every string value of fc,
+// reporting unset variables against the lines that use them. The values
+// substituted are kept in fc.interpolated so they can be masked in logs.
+func expandConfig(fc *fileConfig, loc keyLocations) []Problem {
+	var problems []Problem
+	lookup := func(name string) (string, bool) {
+		v, ok := os.LookupEnv(name)
+		if ok && v != "" {
+			fc.interpolated = append(fc.interpolated, v)
+		}
+		return v, ok
+	}
+	var walk func(v reflect.Value, path string)
+	walk = func(v reflect.Value, path string) {
+		switch v.Kind() {
+		case reflect.String:
+			expanded, missing := expandEnv(v.String(), lookup)
+			for _, name := range missing {
+				problems = append(problems, Problem{
+					Line: loc.line(path),
+					Msg:  fmt.Sprintf("%s: environment variable %q is not set", path, name),
+				})
+			}
+			v.SetString(expanded)
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				elem := path
+				if v.Index(i).Kind() == reflect.Struct {
+					elem = path + "." + strconv.Itoa(i)
+				}
+				walk(v.Index(i), elem)
+			}
+		case reflect.Struct:
+			t := v.Type()
+			for i := 0; i < t.NumField(); i++ {
+				name := t.Field(i).Tag.Get("toml")
+				if name == "" {
+					continue
+				}
+				if path != "" {
+					name = path + "." + name
+				}
+				walk(v.Field(i), name)
+			}
+		}
+	}
+	walk(reflect.ValueOf(fc).Elem(), "")
+	return problems
+}
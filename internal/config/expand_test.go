@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"HOME": "/home/u", "EMPTY": ""}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+
+	tests := []struct {
+		in          string
+		want        string
+		wantMissing []string
+	}{
+		{"${HOME}/Videos", "/home/u/Videos", nil},
+		{"$HOME/Videos", "$HOME/Videos", nil},
+		{"%(title)s.%(ext)s", "%(title)s.%(ext)s", nil},
+		{"$${HOME}", "${HOME}", nil},
+		{"${EMPTY:-x}", "x", nil},
+		{"${EMPTY}", "", nil},
+		{"${HOME:-/tmp}", "/home/u", nil},
+		{"${UNSET:-/tmp}", "/tmp", nil},
+		{"${UNSET}/a/${OTHER}", "/a/", []string{"UNSET", "OTHER"}},
+	}
+	for _, tt := range tests {
+		got, missing := expandEnv(tt.in, lookup)
+		if got != tt.want || !reflect.DeepEqual(missing, tt.wantMissing) {
+			t.Errorf("expandEnv(%q) = %q, %v; want %q, %v", tt.in, got, missing, tt.want, tt.wantMissing)
+		}
+	}
+}
+
+func TestDecodeConfig_ExpandEnv(t *testing.T) {
+	t.Setenv("CATCHER_TEST_DIR", "/srv/videos")
+	t.Setenv("CATCHER_TEST_TOKEN", "tok")
+	data := `
+expand_env = true
+admin_token = "${CATCHER_TEST_TOKEN}"
+
+[[processor]]
+name = "yt"
+pattern = "youtube"
+command = "yt-dlp"
+args = ["-o", "${CATCHER_TEST_DIR}/%(title)s.%(ext)s", "{url}"]
+target_dir = "${CATCHER_TEST_DIR}"
+`
+	var fc fileConfig
+	if err := decodeConfig("config.toml", []byte(data), &fc); err != nil {
+		t.Fatalf("decodeConfig() error = %v", err)
+	}
+	if fc.AdminToken != "tok" {
+		t.Errorf("AdminToken = %q", fc.AdminToken)
+	}
+	p := fc.Processors[0]
+	if p.TargetDir != "/srv/videos" || p.Args[1] != "/srv/videos/%(title)s.%(ext)s" {
+		t.Errorf("Processor = %+v", p)
+	}
+}
+
+func TestDecodeConfig_ExpandEnvOptIn(t *testing.T) {
+	t.Setenv("CATCHER_TEST_DIR", "/srv/videos")
+	data := "[[processor]]\nname = \"a\"\npattern = \"a\"\ncommand = \"a\"\ntarget_dir = \"${CATCHER_TEST_DIR}\"\n"
+	var fc fileConfig
+	if err := decodeConfig("config.toml", []byte(data), &fc); err != nil {
+		t.Fatalf("decodeConfig() error = %v", err)
+	}
+	if fc.Processors[0].TargetDir != "${CATCHER_TEST_DIR}" {
+		t.Errorf("TargetDir = %q, want unexpanded without expand_env", fc.Processors[0].TargetDir)
+	}
+}
+
+func TestDecodeConfig_ExpandEnvMissing(t *testing.T) {
+	data := "expand_env = true\n\n[[processor]]\nname = \"a\"\npattern = \"a\"\ncommand = \"a\"\ntarget_dir = \"${CATCHER_TEST_UNSET}\"\n"
+	var fc fileConfig
+	err := decodeConfig("config.toml", []byte(data), &fc)
+	var fe *FileError
+	if !errors.As(err, &fe) {
+		t.Fatalf("decodeConfig() error = %v, want *FileError", err)
+	}
+	want := []Problem{{Line: 7, Msg: `processor.0.target_dir: environment variable "CATCHER_TEST_UNSET" is not set`}}
+	if !reflect.DeepEqual(fe.Problems, want) {
+		t.Errorf("Problems = %+v, want %+v", fe.Problems, want)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"500MB", 500_000_000, false},
+		{"1.5 GiB", 1536 << 20, false},
+		{"2gb", 2_000_000_000, false},
+		{"10 B", 10, false},
+		{"", 0, true},
+		{"GB", 0, true},
+		{"5 parsecs", 0, true},
+		{"-1GB", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
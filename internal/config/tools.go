@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ForceIPFlags are the flags of known tools that make them connect over
+// IPv4 or IPv6 only, by tool and IP version. force_ip needs one of them.
+var ForceIPFlags = map[string]map[string]string{
+	"yt-dlp":     {"4": "--force-ipv4", "6": "--force-ipv6"},
+	"youtube-dl": {"4": "--force-ipv4", "6": "--force-ipv6"},
+	"curl":       {"4": "-4", "6": "-6"},
+	"wget":       {"4": "-4", "6": "-6"},
+}
+
+// ToolName returns the name of the program command runs.
+func ToolName(command string) string {
+	return strings.TrimSuffix(filepath.Base(command), ".exe")
+}
+
+// Bounds of the download tuning settings. Sites start throttling or
+// refusing clients well before yt-dlp runs 16 fragments at once, and
+// aria2c won't open more than 16 connections per server.
+const (
+	MaxFragments   = 16
+	MaxConnections = 16
+)
+
+// TuningProblem is what is wrong with one of a processor's download
+// tuning settings: Field is its key, and Msg starts with it.
+type TuningProblem struct {
+	Field string
+	Msg   string
+}
+
+// TuningProblems checks the fragments, downloader, and connections of pc
+// against its command, which must be a tool they apply to.
+func TuningProblems(pc ProcessorConfig) []TuningProblem {
+	var problems []TuningProblem
+	add := func(field, format string, args ...any) {
+		problems = append(problems, TuningProblem{Field: field, Msg: fmt.Sprintf(format, args...)})
+	}
+	tool := ToolName(pc.Command)
+	switch {
+	case pc.Fragments < 0 || pc.Fragments > MaxFragments:
+		add("fragments", "fragments %d out of range (want 1 to %d)", pc.Fragments, MaxFragments)
+	case pc.Fragments > 0 && tool != "yt-dlp":
+		add("fragments", "fragments needs yt-dlp as the command, not %s", tool)
+	}
+	switch pc.Downloader {
+	case "":
+	case "aria2c":
+		if tool != "yt-dlp" && tool != "youtube-dl" {
+			add("downloader", "downloader needs yt-dlp or youtube-dl as the command, not %s", tool)
+		}
+	default:
+		add("downloader", "unknown downloader %q (want aria2c)", pc.Downloader)
+	}
+	switch {
+	case pc.Connections < 0 || pc.Connections > MaxConnections:
+		add("connections", "connections %d out of range (want 1 to %d)", pc.Connections, MaxConnections)
+	case pc.Connections > 0 && pc.Downloader != "aria2c" && tool != "aria2c":
+		add("connections", "connections needs downloader = \"aria2c\", or aria2c as the command")
+	}
+	return problems
+}
@@ -0,0 +1,609 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/cwygoda/catcher/internal/netaddr"
+)
+
+// Problem is one config file error, located by line when known.
+type Problem struct {
+	Line int // 0 when the problem has no single location
+	Msg  string
+}
+
+// FileError reports every problem found in a config file.
+type FileError struct {
+	Path     string
+	Problems []Problem
+}
+
+func (e *FileError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d problem(s)", e.Path, len(e.Problems))
+	for _, p := range e.Problems {
+		if p.Line > 0 {
+			fmt.Fprintf(&b, "\n  line %d: %s", p.Line, p.Msg)
+		} else {
+			fmt.Fprintf(&b, "\n  %s", p.Msg)
+		}
+	}
+	return b.String()
+}
+
+// decodeFile decodes and validates a config file into fc, reporting all
+// problems at once so nothing is silently half-applied.
+func decodeFile(path string, fc *fileConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return decodeConfig(path, data, fc)
+}
+
+func decodeConfig(path string, data []byte, fc *fileConfig) error {
+	md, err := toml.Decode(string(data), fc)
+	if err != nil {
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			return &FileError{Path: path, Problems: []Problem{{Line: pe.Position.Line, Msg: pe.Message}}}
+		}
+		return &FileError{Path: path, Problems: []Problem{{Msg: err.Error()}}}
+	}
+
+	loc := locateKeys(data)
+	var problems []Problem
+	add := func(line int, format string, args ...any) {
+		problems = append(problems, Problem{Line: line, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if fc.ExpandEnv {
+		problems = append(problems, expandConfig(fc, loc)...)
+	}
+
+	// Unknown keys, matched to lines in file order
+	known := knownKeys(reflect.TypeOf(fileConfig{}), "")
+	seen := make(map[string]int)
+	for _, k := range md.Undecoded() {
+		key := strings.Join(k, ".")
+		lines := loc.unindexed[key]
+		line := 0
+		if n := seen[key]; n < len(lines) {
+			line = lines[n]
+		}
+		seen[key]++
+		msg := fmt.Sprintf("unknown key %q", key)
+		if s := suggest(key, known); s != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", s)
+		}
+		add(line, "%s", msg)
+	}
+
+	// Durations written as bare integers decode as nanoseconds
+	for _, key := range durationKeys(reflect.TypeOf(fileConfig{}), "") {
+		if md.Type(strings.Split(key, ".")...) == "Integer" {
+			add(loc.indexed[key], "%s must be a duration string like \"30s\", not a number", key)
+		}
+	}
+
+	// Processors
+	names := make(map[string]int)
+	// A direct run takes every file that changed in its target_dir as its
+	// own, so it can't share the directory with a run going at once
+	direct := func(pc ProcessorConfig) bool { return pc.Fake == nil && pc.Isolate != nil && !*pc.Isolate }
+	directDirs := make(map[string]int)
+	for _, pc := range fc.Processors {
+		if direct(pc) {
+			directDirs[pc.TargetDir]++
+		}
+	}
+	for i, pc := range fc.Processors {
+		at := func(field string) int {
+			if line := loc.indexed["processor."+strconv.Itoa(i)+"."+field]; line > 0 {
+				return line
+			}
+			return loc.indexed["processor."+strconv.Itoa(i)]
+		}
+		label := fmt.Sprintf("processor #%d", i+1)
+		if pc.Name != "" {
+			label = fmt.Sprintf("processor %q", pc.Name)
+		}
+
+		required := []struct{ field, value string }{
+			{"name", pc.Name}, {"pattern", strings.Join(pc.AllPatterns(), "")}, {"command", pc.Command},
+		}
+		if f := pc.Fake; f != nil {
+			required = required[:2]
+			if pc.Command != "" {
+				add(at("command"), "%s: fake processors run no command", label)
+			}
+			if f.Delay < 0 {
+				add(at("fake"), "%s: fake.delay must not be negative", label)
+			}
+			if f.FailureRate < 0 || f.FailureRate > 1 {
+				add(at("fake"), "%s: fake.failure_rate %g out of range 0-1", label, f.FailureRate)
+			}
+			if f.Size != "" {
+				if _, err := ParseSize(f.Size); err != nil {
+					add(at("fake"), "%s: fake.size: %v", label, err)
+				}
+			}
+		}
+		for _, req := range required {
+			if strings.TrimSpace(req.value) == "" {
+				add(at(req.field), "%s: missing required field %q", label, req.field)
+			}
+		}
+		if pc.Name != "" {
+			if first, dup := names[pc.Name]; dup {
+				add(at("name"), "duplicate processor name %q (first defined on line %d)", pc.Name, first)
+			} else {
+				names[pc.Name] = at("name")
+			}
+		}
+		var patterns []*regexp.Regexp
+		for j, src := range pc.AllPatterns() {
+			field := "patterns"
+			if j == 0 && pc.Pattern != "" {
+				field = "pattern"
+			}
+			re, err := regexp.Compile(src)
+			if err != nil {
+				add(at(field), "%s: invalid pattern: %v", label, err)
+				continue
+			}
+			patterns = append(patterns, re)
+		}
+		for _, src := range pc.Exclude {
+			if _, err := regexp.Compile(src); err != nil {
+				add(at("exclude"), "%s: invalid exclude pattern: %v", label, err)
+			}
+		}
+		if len(patterns) == len(pc.AllPatterns()) {
+			for _, m := range placeholder.FindAllStringSubmatch(pc.TargetDir, -1) {
+				if strings.HasPrefix(m[0], "$") || m[1] == "url" {
+					continue
+				}
+				for _, re := range patterns {
+					if !slices.Contains(re.SubexpNames(), m[1]) {
+						add(at("target_dir"), "%s: target_dir placeholder %s is not a named group in pattern %q", label, m[0], re)
+						break
+					}
+				}
+			}
+			if len(pc.Library) > 0 && !slices.ContainsFunc(patterns, func(re *regexp.Regexp) bool { return slices.Contains(re.SubexpNames(), "id") }) {
+				add(at("library"), "%s: library needs a pattern with an (?P<id>...) group", label)
+			}
+		}
+		if pc.LibraryRescan < 0 {
+			add(at("library_rescan"), "%s: library_rescan must not be negative", label)
+		}
+		switch pc.Resubmit {
+		case "", "allow", "reject", "replace":
+		default:
+			add(at("resubmit"), "%s: unknown resubmit policy %q (want allow, reject, or replace)", label, pc.Resubmit)
+		}
+		if pc.MaxSize != "" {
+			if _, err := ParseSize(pc.MaxSize); err != nil {
+				add(at("max_size"), "%s: %v", label, err)
+			} else if len(pc.ProbeArgs) == 0 {
+				add(at("max_size"), "%s: max_size needs probe_args to estimate sizes", label)
+			}
+		}
+		if pc.WorkDirQuota != "" {
+			if _, err := ParseSize(pc.WorkDirQuota); err != nil {
+				add(at("work_dir_quota"), "%s: work_dir_quota: %v", label, err)
+			} else if pc.Isolate != nil && !*pc.Isolate {
+				add(at("work_dir_quota"), "%s: work_dir_quota needs isolate, as direct runs have no work dir", label)
+			}
+		}
+		switch pc.Oversize {
+		case "", "hold", "fail":
+		default:
+			add(at("oversize"), "%s: unknown oversize policy %q (want hold or fail)", label, pc.Oversize)
+		}
+		if pc.Concurrency < 0 {
+			add(at("concurrency"), "%s: concurrency must not be negative", label)
+		} else if direct(pc) && pc.Concurrency > 1 {
+			add(at("concurrency"), "%s: concurrency above 1 needs isolate, as direct runs in one target_dir can't tell their files apart", label)
+		} else if direct(pc) && pc.Concurrency == 1 && directDirs[pc.TargetDir] > 1 {
+			add(at("concurrency"), "%s: concurrency needs isolate or a target_dir of its own, as direct runs in one target_dir can't tell their files apart", label)
+		}
+		if pc.Cost < 0 {
+			add(at("cost"), "%s: cost must not be negative", label)
+		}
+		if b := fc.Worker.Budget; b > 0 && pc.Cost > b {
+			add(at("cost"), "%s: cost %d exceeds worker.budget (%d)", label, pc.Cost, b)
+		}
+		if pc.RateLimitDelay < 0 {
+			add(at("rate_limit_delay"), "%s: rate_limit_delay must not be negative", label)
+		}
+		for _, code := range pc.SuccessExitCodes {
+			if code < 1 || code > 255 {
+				add(at("success_exit_codes"), "%s: success exit code %d out of range 1-255", label, code)
+			}
+		}
+		switch tool := ToolName(pc.Command); {
+		case pc.ForceIP == "":
+		case pc.ForceIP != "4" && pc.ForceIP != "6":
+			add(at("force_ip"), "%s: invalid force_ip %q (want \"4\" or \"6\")", label, pc.ForceIP)
+		case ForceIPFlags[tool] == nil:
+			add(at("force_ip"), "%s: force_ip needs yt-dlp, youtube-dl, curl, or wget as the command, not %q", label, tool)
+		}
+		for _, p := range TuningProblems(pc) {
+			add(at(p.Field), "%s: %s", label, p.Msg)
+		}
+		for _, msg := range dnsProblems(pc.DNS) {
+			add(at("dns"), "%s: dns.%s", label, msg)
+		}
+	}
+
+	// Rewrite rules
+	for i, rc := range fc.Rewrites {
+		at := func(field string) int {
+			if line := loc.indexed["rewrite."+strconv.Itoa(i)+"."+field]; line > 0 {
+				return line
+			}
+			return loc.indexed["rewrite."+strconv.Itoa(i)]
+		}
+		label := fmt.Sprintf("rewrite #%d", i+1)
+		actions := 0
+		for _, set := range []bool{rc.Replace != nil, rc.Unwrap != "", len(rc.StripParams) > 0} {
+			if set {
+				actions++
+			}
+		}
+		if actions != 1 {
+			add(at(""), "%s: needs exactly one of replace, unwrap, or strip_params", label)
+		}
+		if rc.Pattern == "" && (rc.Replace != nil || rc.Unwrap != "") {
+			add(at(""), "%s: missing required field \"pattern\"", label)
+		}
+		if _, err := regexp.Compile(rc.Pattern); err != nil {
+			add(at("pattern"), "%s: invalid pattern: %v", label, err)
+		}
+		for _, p := range rc.StripParams {
+			if _, err := filepath.Match(p, ""); err != nil {
+				add(at("strip_params"), "%s: invalid parameter pattern %q", label, p)
+			}
+		}
+	}
+
+	// API tokens
+	tokenNames := make(map[string]bool)
+	tokenValues := make(map[string]bool)
+	for i, tc := range fc.Tokens {
+		at := func(field string) int {
+			if line := loc.indexed["token."+strconv.Itoa(i)+"."+field]; line > 0 {
+				return line
+			}
+			return loc.indexed["token."+strconv.Itoa(i)]
+		}
+		label := fmt.Sprintf("token #%d", i+1)
+		if tc.Name != "" {
+			label = fmt.Sprintf("token %q", tc.Name)
+		}
+		switch {
+		case tc.Name == "":
+			add(at(""), "%s: missing required field \"name\"", label)
+		case tokenNames[tc.Name]:
+			add(at("name"), "%s: duplicate name", label)
+		}
+		tokenNames[tc.Name] = true
+		switch {
+		case tc.Token == "":
+			add(at(""), "%s: missing required field \"token\"", label)
+		case tokenValues[tc.Token]:
+			add(at("token"), "%s: token is already used by another token", label)
+		case tc.Token == fc.Secret || tc.Token == fc.AdminToken:
+			add(at("token"), "%s: token must differ from secret and admin_token", label)
+		}
+		tokenValues[tc.Token] = true
+		for _, name := range tc.Processors {
+			if !slices.ContainsFunc(fc.Processors, func(pc ProcessorConfig) bool { return pc.Name == name }) {
+				add(at("processors"), "%s: unknown processor %q", label, name)
+			}
+		}
+		if slices.Contains(tc.Hosts, "") {
+			add(at("hosts"), "%s: hosts must not contain an empty host", label)
+		}
+	}
+
+	// Conflicting or out-of-range options
+	h := fc.HTTP
+	if h.ReadHeaderTimeout > 0 && h.ReadTimeout > 0 && h.ReadHeaderTimeout > h.ReadTimeout {
+		add(loc.indexed["http.read_header_timeout"], "http.read_header_timeout (%s) exceeds http.read_timeout (%s)", h.ReadHeaderTimeout, h.ReadTimeout)
+	}
+	for key, v := range map[string]int64{
+		"http.read_header_timeout":           int64(h.ReadHeaderTimeout),
+		"http.read_timeout":                  int64(h.ReadTimeout),
+		"http.write_timeout":                 int64(h.WriteTimeout),
+		"http.idle_timeout":                  int64(h.IdleTimeout),
+		"http.max_header_bytes":              int64(h.MaxHeaderBytes),
+		"http.max_body_bytes":                h.MaxBodyBytes,
+		"http.share_ttl":                     int64(h.ShareTTL),
+		"http.max_batch":                     int64(h.MaxBatch),
+		"validation.max_url_length":          int64(fc.Validation.MaxURLLength),
+		"validation.dedupe_window":           int64(fc.Validation.DedupeWindow),
+		"maintenance.interval":               int64(fc.Maintenance.Interval),
+		"maintenance.hourly_stats_retention": int64(fc.Maintenance.HourlyStatsRetention),
+		"maintenance.job_retention":          int64(fc.Maintenance.JobRetention),
+		"redirects.max_hops":                 int64(fc.Redirects.MaxHops),
+		"redirects.timeout":                  int64(fc.Redirects.Timeout),
+		"worker.budget":                      int64(fc.Worker.Budget),
+		"worker.stall_timeout":               int64(fc.Worker.StallTimeout),
+		"worker.verify_files":                int64(fc.Worker.VerifyFiles),
+		"worker.outage_after":                int64(fc.Worker.OutageAfter),
+		"worker.outage_probe_interval":       int64(fc.Worker.OutageProbeInterval),
+		"worker.outage_sweep_rate":           int64(fc.Worker.OutageSweepRate),
+		"worker.outage_max_requeues":         int64(fc.Worker.OutageMaxRequeues),
+		"worker.probe_cache_ttl":             int64(fc.Worker.ProbeCacheTTL),
+		"database.read_pool":                 int64(fc.Database.ReadPool),
+		"replication.max_lag":                int64(fc.Replication.MaxLag),
+	} {
+		if v < 0 {
+			add(loc.indexed[key], "%s must not be negative", key)
+		}
+	}
+	switch h.BatchOverflow {
+	case "", "reject", "hold":
+	default:
+		add(loc.indexed["http.batch_overflow"], "unknown http.batch_overflow %q (want reject or hold)", h.BatchOverflow)
+	}
+	if probe := fc.Worker.OutageProbe; probe != "" {
+		if _, port, err := net.SplitHostPort(probe); err != nil || port == "" {
+			add(loc.indexed["worker.outage_probe"], "worker.outage_probe must be host:port, e.g. \"1.1.1.1:443\"")
+		}
+	}
+	if md.IsDefined("maintenance", "interval") && fc.Maintenance.Interval == 0 {
+		add(loc.indexed["maintenance.interval"], "maintenance.interval must be positive")
+	}
+	if md.IsDefined("validation", "allowed_schemes") && len(fc.Validation.AllowedSchemes) == 0 {
+		add(loc.indexed["validation.allowed_schemes"], "validation.allowed_schemes is empty, so every URL would be rejected")
+	}
+
+	if slices.Contains(fc.Approval.Hosts, "") {
+		add(loc.indexed["approval.hosts"], "approval.hosts must not contain an empty host")
+	}
+
+	if slices.Contains(fc.Redirects.Hosts, "") {
+		add(loc.indexed["redirects.hosts"], "redirects.hosts must not contain an empty host")
+	}
+	if md.IsDefined("redirects", "max_hops") && fc.Redirects.MaxHops == 0 {
+		add(loc.indexed["redirects.max_hops"], "redirects.max_hops must be positive")
+	}
+	if md.IsDefined("redirects", "timeout") && fc.Redirects.Timeout == 0 {
+		add(loc.indexed["redirects.timeout"], "redirects.timeout must be positive")
+	}
+	if strings.ContainsFunc(fc.Redirects.UserAgent, unicode.IsControl) {
+		add(loc.indexed["redirects.user_agent"], "redirects.user_agent must not contain control characters")
+	}
+	if !netaddr.ValidSource(fc.Redirects.SourceAddress) {
+		add(loc.indexed["redirects.source_address"], "redirects.source_address %q is not an IP address or interface name", fc.Redirects.SourceAddress)
+	}
+	if v := fc.Redirects.ForceIP; v != "" && v != "4" && v != "6" {
+		add(loc.indexed["redirects.force_ip"], "invalid redirects.force_ip %q (want \"4\" or \"6\")", v)
+	}
+
+	if len(fc.MDNS.Name) > 63 {
+		add(loc.indexed["mdns.name"], "mdns.name must be at most 63 bytes")
+	}
+
+	if fc.Watch.Interval < 0 {
+		add(loc.indexed["watch.interval"], "watch.interval must not be negative")
+	}
+	if fc.Watch.Source != "" && !watchSource.MatchString(fc.Watch.Source) {
+		add(loc.indexed["watch.source"], "watch.source must be 1-64 letters, digits, or ._@-")
+	}
+
+	if u := fc.Connectivity.URL; u != "" {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			add(loc.indexed["connectivity.url"], "connectivity.url must be an http or https URL")
+		}
+	}
+	if fc.Connectivity.Interval < 0 {
+		add(loc.indexed["connectivity.interval"], "connectivity.interval must not be negative")
+	}
+	if fc.Connectivity.Timeout < 0 {
+		add(loc.indexed["connectivity.timeout"], "connectivity.timeout must not be negative")
+	}
+
+	for _, msg := range dnsProblems(fc.DNS) {
+		key, _, _ := strings.Cut(msg, ":")
+		add(loc.line("dns."+key), "dns.%s", msg)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return &FileError{Path: path, Problems: problems}
+}
+
+// dnsProblems describes what is wrong with d's servers and hosts, as
+// messages starting with the offending key.
+func dnsProblems(d DNSConfig) []string {
+	var msgs []string
+	for _, server := range d.Servers {
+		if _, err := netaddr.DNSServer(server); err != nil {
+			msgs = append(msgs, "servers: "+err.Error())
+		}
+	}
+	hosts := slices.Sorted(maps.Keys(d.Hosts))
+	for _, host := range hosts {
+		switch {
+		case host == "" || strings.ContainsAny(host, " /:"):
+			msgs = append(msgs, fmt.Sprintf("hosts: invalid host name %q", host))
+		case net.ParseIP(d.Hosts[host]) == nil:
+			msgs = append(msgs, fmt.Sprintf("hosts: %s: %q is not an IP address", host, d.Hosts[host]))
+		}
+	}
+	return msgs
+}
+
+// watchSource matches the sources the API accepts.
+var watchSource = regexp.MustCompile(`^[A-Za-z0-9._@-]{1,64}$`)
+
+// keyLocations maps key paths to the lines defining them. indexed paths
+// number array-of-tables entries ("processor.0.name"); unindexed paths
+// don't ("processor.name") and list every occurrence in file order.
+type keyLocations struct {
+	indexed   map[string]int
+	unindexed map[string][]int
+}
+
+// placeholder matches a {name} placeholder in a processor template, or an
+// uninterpolated ${VAR} to skip.
+var placeholder = regexp.MustCompile(`\$?\{(\w+)\}`)
+
+var (
+	tableHeader = regexp.MustCompile(`^\[\s*([A-Za-z0-9_.-]+)\s*\]`)
+	arrayHeader = regexp.MustCompile(`^\[\[\s*([A-Za-z0-9_.-]+)\s*\]\]`)
+	keyLine     = regexp.MustCompile(`^("[^"]*"|[A-Za-z0-9_-]+)\s*=`)
+)
+
+// line returns where an indexed key path is defined, falling back to its
+// enclosing table, or 0 if unknown.
+func (l keyLocations) line(path string) int {
+	for path != "" {
+		if n, ok := l.indexed[path]; ok {
+			return n
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return 0
+}
+
+// locateKeys finds where keys are defined. It understands the subset of TOML
+// used by config files: tables, arrays of tables, and key/value lines.
+func locateKeys(data []byte) keyLocations {
+	loc := keyLocations{indexed: make(map[string]int), unindexed: make(map[string][]int)}
+	counts := make(map[string]int)
+	var prefix, plain string
+	for i, line := range strings.Split(string(data), "\n") {
+		n := i + 1
+		line = strings.TrimSpace(line)
+		if m := arrayHeader.FindStringSubmatch(line); m != nil {
+			plain = m[1]
+			prefix = m[1] + "." + strconv.Itoa(counts[m[1]])
+			counts[m[1]]++
+			loc.indexed[prefix] = n
+			continue
+		}
+		if m := tableHeader.FindStringSubmatch(line); m != nil {
+			prefix, plain = m[1], m[1]
+			loc.indexed[prefix] = n
+			continue
+		}
+		if m := keyLine.FindStringSubmatch(line); m != nil {
+			key := strings.Trim(m[1], `"`)
+			full, short := key, key
+			if prefix != "" {
+				full, short = prefix+"."+key, plain+"."+key
+			}
+			if _, ok := loc.indexed[full]; !ok {
+				loc.indexed[full] = n
+			}
+			loc.unindexed[short] = append(loc.unindexed[short], n)
+		}
+	}
+	return loc
+}
+
+// knownKeys lists the dotted TOML keys of a config struct.
+func knownKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("toml")
+		if name == "" {
+			continue
+		}
+		ft := f.Type
+		if (ft.Kind() == reflect.Slice || ft.Kind() == reflect.Pointer) && ft.Elem().Kind() == reflect.Struct {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Duration(0)) {
+			keys = append(keys, knownKeys(ft, prefix+name+".")...)
+			continue
+		}
+		keys = append(keys, prefix+name)
+	}
+	return keys
+}
+
+// durationKeys lists the dotted keys of time.Duration fields in plain tables.
+func durationKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("toml")
+		switch {
+		case name == "":
+		case f.Type == reflect.TypeOf(time.Duration(0)):
+			keys = append(keys, prefix+name)
+		case f.Type.Kind() == reflect.Struct:
+			keys = append(keys, durationKeys(f.Type, prefix+name+".")...)
+		}
+	}
+	return keys
+}
+
+// suggest returns the known key in the same table closest to key, if any
+// is within a small edit distance.
+func suggest(key string, known []string) string {
+	table := ""
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		table = key[:i+1]
+	}
+	best, bestDist := "", 3
+	for _, k := range known {
+		if !strings.HasPrefix(k, table) || strings.Contains(k[len(table):], ".") {
+			continue
+		}
+		if d := editDistance(key[len(table):], k[len(table):]); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,378 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeConfig_Valid(t *testing.T) {
+	data := `
+secret = "s"
+
+[http]
+read_timeout = "30s"
+
+[[processor]]
+name = "youtube"
+pattern = "youtube\\.com"
+command = "yt-dlp"
+args = [
+  "-f", "best[height<=720]",
+  "{url}",
+]
+`
+	fc := fileConfig{Validation: DefaultValidation(), Maintenance: DefaultMaintenance()}
+	if err := decodeConfig("config.toml", []byte(data), &fc); err != nil {
+		t.Fatalf("decodeConfig() error = %v", err)
+	}
+	if len(fc.Processors) != 1 || fc.Processors[0].Args[1] != "best[height<=720]" {
+		t.Errorf("Processors = %+v", fc.Processors)
+	}
+}
+
+func TestDecodeConfig_FakeProcessor(t *testing.T) {
+	data := `
+[[processor]]
+name = "fake"
+pattern = "^https://fake\\.test/"
+
+[processor.fake]
+delay = "2s"
+failure_rate = 0.25
+size = "1MB"
+`
+	fc := fileConfig{Validation: DefaultValidation(), Maintenance: DefaultMaintenance()}
+	if err := decodeConfig("config.toml", []byte(data), &fc); err != nil {
+		t.Fatalf("decodeConfig() error = %v", err)
+	}
+	want := FakeConfig{Delay: 2 * time.Second, FailureRate: 0.25, Size: "1MB"}
+	if f := fc.Processors[0].Fake; f == nil || *f != want {
+		t.Errorf("Fake = %+v, want %+v", f, want)
+	}
+}
+
+func TestDecodeConfig_Problems(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Problem
+	}{
+		{
+			name: "syntax error",
+			data: "secret = \"s\"\nport = = 1\n",
+			want: []Problem{{Line: 2}},
+		},
+		{
+			name: "unknown keys with suggestions",
+			data: "secrt = \"s\"\n[http]\nread_timout = \"5s\"\n[[processor]]\nname = \"a\"\npattern = \"a\"\ncommand = \"a\"\n[[processor]]\nname = \"b\"\npatern = \"b\"\ncommand = \"b\"\n",
+			want: []Problem{
+				{Line: 1, Msg: `unknown key "secrt" (did you mean "secret"?)`},
+				{Line: 3, Msg: `unknown key "http.read_timout" (did you mean "http.read_timeout"?)`},
+				{Line: 8, Msg: `processor "b": missing required field "pattern"`},
+				{Line: 10, Msg: `unknown key "processor.patern" (did you mean "processor.pattern"?)`},
+			},
+		},
+		{
+			name: "missing required fields and duplicates",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"a\"\ncommand = \"a\"\n\n[[processor]]\nname = \"dl\"\npattern = \"(\"\n",
+			want: []Problem{
+				{Line: 6, Msg: `processor "dl": missing required field "command"`},
+				{Line: 7, Msg: `duplicate processor name "dl" (first defined on line 2)`},
+				{Line: 8, Msg: "processor \"dl\": invalid pattern: error parsing regexp: missing closing ): `(`"},
+			},
+		},
+		{
+			name: "unknown resubmit policy",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"a\"\ncommand = \"a\"\nresubmit = \"skip\"\n",
+			want: []Problem{
+				{Line: 5, Msg: `processor "dl": unknown resubmit policy "skip" (want allow, reject, or replace)`},
+			},
+		},
+		{
+			name: "size limits",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"a\"\ncommand = \"a\"\nmax_size = \"2GB\"\noversize = \"skip\"\n[[processor]]\nname = \"b\"\npattern = \"b\"\ncommand = \"b\"\nprobe_args = [\"-J\"]\nmax_size = \"lots\"\n",
+			want: []Problem{
+				{Line: 5, Msg: `processor "dl": max_size needs probe_args to estimate sizes`},
+				{Line: 6, Msg: `processor "dl": unknown oversize policy "skip" (want hold or fail)`},
+				{Line: 12, Msg: `processor "b": invalid size "lots" (want e.g. "500MB" or "2GiB")`},
+			},
+		},
+		{
+			name: "work dir quota",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"a\"\ncommand = \"a\"\nisolate = false\nwork_dir_quota = \"20GB\"\n[[processor]]\nname = \"b\"\npattern = \"b\"\ncommand = \"b\"\nwork_dir_quota = \"lots\"\n",
+			want: []Problem{
+				{Line: 6, Msg: `processor "dl": work_dir_quota needs isolate, as direct runs have no work dir`},
+				{Line: 11, Msg: `processor "b": work_dir_quota: invalid size "lots" (want e.g. "500MB" or "2GiB")`},
+			},
+		},
+		{
+			name: "success exit codes out of range",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"a\"\ncommand = \"a\"\nsuccess_exit_codes = [101, 0, 256]\n",
+			want: []Problem{
+				{Line: 5, Msg: `processor "dl": success exit code 0 out of range 1-255`},
+				{Line: 5, Msg: `processor "dl": success exit code 256 out of range 1-255`},
+			},
+		},
+		{
+			name: "negative rate limit delay and concurrency",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"a\"\ncommand = \"a\"\nrate_limit_delay = \"-1m\"\nconcurrency = -1\n",
+			want: []Problem{
+				{Line: 5, Msg: `processor "dl": rate_limit_delay must not be negative`},
+				{Line: 6, Msg: `processor "dl": concurrency must not be negative`},
+			},
+		},
+		{
+			name: "concurrent direct runs",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"a\"\ncommand = \"a\"\nisolate = false\nconcurrency = 2\n",
+			want: []Problem{
+				{Line: 6, Msg: `processor "dl": concurrency above 1 needs isolate, as direct runs in one target_dir can't tell their files apart`},
+			},
+		},
+		{
+			name: "direct queue sharing a target dir",
+			data: "[[processor]]\nname = \"a\"\npattern = \"a\"\ncommand = \"a\"\nisolate = false\nconcurrency = 1\n" +
+				"[[processor]]\nname = \"b\"\npattern = \"b\"\ncommand = \"b\"\nisolate = false\n" +
+				"[[processor]]\nname = \"c\"\npattern = \"c\"\ncommand = \"c\"\nisolate = false\nconcurrency = 1\ntarget_dir = \"/c\"\n",
+			want: []Problem{
+				{Line: 6, Msg: `processor "a": concurrency needs isolate or a target_dir of its own, as direct runs in one target_dir can't tell their files apart`},
+			},
+		},
+		{
+			name: "library without an id group",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"a\"\ncommand = \"a\"\nlibrary = [\"~/Videos\"]\nlibrary_rescan = \"-1m\"\n",
+			want: []Problem{
+				{Line: 5, Msg: `processor "dl": library needs a pattern with an (?P<id>...) group`},
+				{Line: 6, Msg: `processor "dl": library_rescan must not be negative`},
+			},
+		},
+		{
+			name: "costs over budget",
+			data: "[worker]\nbudget = 4\n[[processor]]\nname = \"transcode\"\npattern = \"a\"\ncommand = \"a\"\ncost = 8\n[[processor]]\nname = \"meta\"\npattern = \"b\"\ncommand = \"b\"\ncost = -1\n",
+			want: []Problem{
+				{Line: 7, Msg: `processor "transcode": cost 8 exceeds worker.budget (4)`},
+				{Line: 12, Msg: `processor "meta": cost must not be negative`},
+			},
+		},
+		{
+			name: "unknown target_dir placeholder",
+			data: "[[processor]]\nname = \"dl\"\npattern = \"youtube\\\\.com/@(?P<channel>[^/]+)\"\ncommand = \"a\"\ntarget_dir = \"~/Videos/{chanel}/{channel}\"\n",
+			want: []Problem{
+				{Line: 5, Msg: `processor "dl": target_dir placeholder {chanel} is not a named group in pattern "youtube\\.com/@(?P<channel>[^/]+)"`},
+			},
+		},
+		{
+			name: "multiple patterns",
+			data: "[[processor]]\nname = \"dl\"\npatterns = [\"a/(?P<ch>\\\\w+)\", \"b/\\\\d+\", \"(\"]\nexclude = [\"[\"]\ncommand = \"a\"\n[[processor]]\nname = \"ch\"\npatterns = [\"a/(?P<ch>\\\\w+)\", \"b/(?P<id>\\\\d+)\"]\ncommand = \"a\"\ntarget_dir = \"/v/{ch}\"\n",
+			want: []Problem{
+				{Line: 3, Msg: "processor \"dl\": invalid pattern: error parsing regexp: missing closing ): `(`"},
+				{Line: 4, Msg: "processor \"dl\": invalid exclude pattern: error parsing regexp: missing closing ]: `[`"},
+				{Line: 10, Msg: `processor "ch": target_dir placeholder {ch} is not a named group in pattern "b/(?P<id>\\d+)"`},
+			},
+		},
+		{
+			name: "rewrite rules",
+			data: "[[rewrite]]\npattern = \"m\\\\.example\"\nreplace = \"www.example\"\n[[rewrite]]\nunwrap = \"u\"\n[[rewrite]]\npattern = \"(\"\nstrip_params = [\"utm_*\", \"[\"]\nreplace = \"\"\n",
+			want: []Problem{
+				{Line: 4, Msg: `rewrite #2: missing required field "pattern"`},
+				{Line: 6, Msg: "rewrite #3: needs exactly one of replace, unwrap, or strip_params"},
+				{Line: 7, Msg: "rewrite #3: invalid pattern: error parsing regexp: missing closing ): `(`"},
+				{Line: 8, Msg: `rewrite #3: invalid parameter pattern "["`},
+			},
+		},
+		{
+			name: "tokens",
+			data: "secret = \"s\"\n[[token]]\nname = \"guest\"\ntoken = \"g\"\nprocessors = [\"yt\", \"torrent\"]\nhosts = [\"\"]\n[[token]]\nname = \"guest\"\ntoken = \"g\"\n[[token]]\ntoken = \"s\"\n[[processor]]\nname = \"yt\"\npattern = \"y\"\ncommand = \"y\"\n",
+			want: []Problem{
+				{Line: 5, Msg: `token "guest": unknown processor "torrent"`},
+				{Line: 6, Msg: `token "guest": hosts must not contain an empty host`},
+				{Line: 8, Msg: `token "guest": duplicate name`},
+				{Line: 9, Msg: `token "guest": token is already used by another token`},
+				{Line: 10, Msg: `token #3: missing required field "name"`},
+				{Line: 11, Msg: `token #3: token must differ from secret and admin_token`},
+			},
+		},
+		{
+			name: "empty approval host",
+			data: "[approval]\nhosts = [\"example.com\", \"\"]\n",
+			want: []Problem{
+				{Line: 2, Msg: "approval.hosts must not contain an empty host"},
+			},
+		},
+		{
+			name: "redirect settings",
+			data: "[redirects]\nhosts = [\"\"]\nmax_hops = 0\ntimeout = \"-1s\"\nuser_agent = \"a\\nb\"\nsource_address = \"eth0/1\"\n",
+			want: []Problem{
+				{Line: 2, Msg: "redirects.hosts must not contain an empty host"},
+				{Line: 3, Msg: "redirects.max_hops must be positive"},
+				{Line: 4, Msg: "redirects.timeout must not be negative"},
+				{Line: 5, Msg: "redirects.user_agent must not contain control characters"},
+				{Line: 6, Msg: `redirects.source_address "eth0/1" is not an IP address or interface name`},
+			},
+		},
+		{
+			name: "negative job retention",
+			data: "[maintenance]\narchive_jobs = true\njob_retention = \"-720h\"\n",
+			want: []Problem{
+				{Line: 3, Msg: "maintenance.job_retention must not be negative"},
+			},
+		},
+		{
+			name: "negative share ttl",
+			data: "[http]\nshare_ttl = \"-10m\"\n",
+			want: []Problem{
+				{Line: 2, Msg: "http.share_ttl must not be negative"},
+			},
+		},
+		{
+			name: "batch limits",
+			data: "[http]\nmax_batch = -1\nbatch_overflow = \"split\"\n",
+			want: []Problem{
+				{Line: 2, Msg: "http.max_batch must not be negative"},
+				{Line: 3, Msg: `unknown http.batch_overflow "split" (want reject or hold)`},
+			},
+		},
+		{
+			name: "long mdns name",
+			data: "[mdns]\nenabled = true\nname = \"" + strings.Repeat("x", 64) + "\"\n",
+			want: []Problem{
+				{Line: 3, Msg: "mdns.name must be at most 63 bytes"},
+			},
+		},
+		{
+			name: "bad outage settings",
+			data: "[worker]\noutage_after = 3\noutage_probe = \"1.1.1.1\"\noutage_sweep_rate = -1\noutage_max_requeues = -1\n",
+			want: []Problem{
+				{Line: 3, Msg: "worker.outage_probe must be host:port, e.g. \"1.1.1.1:443\""},
+				{Line: 4, Msg: "worker.outage_sweep_rate must not be negative"},
+				{Line: 5, Msg: "worker.outage_max_requeues must not be negative"},
+			},
+		},
+		{
+			name: "negative probe cache ttl",
+			data: "[worker]\nprobe_cache_ttl = \"-1h\"\n",
+			want: []Problem{
+				{Line: 2, Msg: "worker.probe_cache_ttl must not be negative"},
+			},
+		},
+		{
+			name: "bad watch settings",
+			data: "[watch]\ndir = \"~/Sync/links\"\ninterval = \"-5s\"\nsource = \"my phone\"\n",
+			want: []Problem{
+				{Line: 3, Msg: "watch.interval must not be negative"},
+				{Line: 4, Msg: "watch.source must be 1-64 letters, digits, or ._@-"},
+			},
+		},
+		{
+			name: "bad connectivity settings",
+			data: "[connectivity]\nurl = \"1.1.1.1\"\ninterval = \"-30s\"\ntimeout = \"-1s\"\n",
+			want: []Problem{
+				{Line: 2, Msg: "connectivity.url must be an http or https URL"},
+				{Line: 3, Msg: "connectivity.interval must not be negative"},
+				{Line: 4, Msg: "connectivity.timeout must not be negative"},
+			},
+		},
+		{
+			name: "negative read pool",
+			data: "[database]\nread_pool = -2\n",
+			want: []Problem{
+				{Line: 2, Msg: "database.read_pool must not be negative"},
+			},
+		},
+		{
+			name: "negative replication lag",
+			data: "[replication]\nsync_file = \"/var/lib/catcher/synced\"\nmax_lag = \"-1m\"\n",
+			want: []Problem{
+				{Line: 3, Msg: "replication.max_lag must not be negative"},
+			},
+		},
+		{
+			name: "invalid fake processor",
+			data: "[[processor]]\nname = \"fake\"\npattern = \".\"\ncommand = \"yt-dlp\"\nfake = { delay = \"-1s\", failure_rate = 1.5, size = \"lots\" }\n",
+			want: []Problem{
+				{Line: 4, Msg: `processor "fake": fake processors run no command`},
+				{Line: 5, Msg: `processor "fake": fake.delay must not be negative`},
+				{Line: 5, Msg: `processor "fake": fake.failure_rate 1.5 out of range 0-1`},
+				{Line: 5},
+			},
+		},
+		{
+			name: "invalid dns",
+			data: "[dns]\nservers = [\"1.1.1.1\", \"9.9.9.9:53\", \"dns.example\", \"8.8.8.8:0\"]\n[dns.hosts]\n\"cdn.example\" = \"edge\"\n\n[[processor]]\nname = \"p\"\npattern = \".\"\ncommand = \"curl\"\ndns = { servers = [\"x\"] }\n",
+			want: []Problem{
+				{Line: 2, Msg: `dns.servers: "dns.example" is not an IP address or ip:port`},
+				{Line: 2, Msg: `dns.servers: invalid port in "8.8.8.8:0"`},
+				{Line: 3, Msg: `dns.hosts: cdn.example: "edge" is not an IP address`},
+				{Line: 10, Msg: `processor "p": dns.servers: "x" is not an IP address or ip:port`},
+			},
+		},
+		{
+			name: "force_ip",
+			data: "[redirects]\nforce_ip = \"ipv6\"\n\n[[processor]]\nname = \"a\"\npattern = \".\"\ncommand = \"/usr/bin/gallery-dl\"\nforce_ip = \"4\"\n\n[[processor]]\nname = \"b\"\npattern = \".\"\ncommand = \"curl\"\nforce_ip = \"ipv4\"\n\n[[processor]]\nname = \"c\"\npattern = \".\"\ncommand = \"yt-dlp\"\nforce_ip = \"6\"\n",
+			want: []Problem{
+				{Line: 2, Msg: `invalid redirects.force_ip "ipv6" (want "4" or "6")`},
+				{Line: 8, Msg: `processor "a": force_ip needs yt-dlp, youtube-dl, curl, or wget as the command, not "gallery-dl"`},
+				{Line: 14, Msg: `processor "b": invalid force_ip "ipv4" (want "4" or "6")`},
+			},
+		},
+		{
+			name: "download tuning",
+			data: "[[processor]]\nname = \"a\"\npattern = \".\"\ncommand = \"curl\"\nfragments = 4\ndownloader = \"wget\"\nconnections = 32\n\n[[processor]]\nname = \"b\"\npattern = \".\"\ncommand = \"yt-dlp\"\nconnections = 4\n",
+			want: []Problem{
+				{Line: 5, Msg: `processor "a": fragments needs yt-dlp as the command, not curl`},
+				{Line: 6, Msg: `processor "a": unknown downloader "wget" (want aria2c)`},
+				{Line: 7, Msg: `processor "a": connections 32 out of range (want 1 to 16)`},
+				{Line: 13, Msg: `processor "b": connections needs downloader = "aria2c", or aria2c as the command`},
+			},
+		},
+		{
+			name: "conflicting and numeric durations",
+			data: "[http]\nread_header_timeout = \"1m\"\nread_timeout = \"10s\"\nidle_timeout = 30\n[validation]\nallowed_schemes = []\n",
+			want: []Problem{
+				{Line: 2, Msg: "http.read_header_timeout (1m0s) exceeds http.read_timeout (10s)"},
+				{Line: 4, Msg: `http.idle_timeout must be a duration string like "30s", not a number`},
+				{Line: 6, Msg: "validation.allowed_schemes is empty, so every URL would be rejected"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := fileConfig{Validation: DefaultValidation(), Maintenance: DefaultMaintenance()}
+			err := decodeConfig("config.toml", []byte(tt.data), &fc)
+
+			var fe *FileError
+			if !errors.As(err, &fe) {
+				t.Fatalf("decodeConfig() error = %v, want *FileError", err)
+			}
+			if len(fe.Problems) != len(tt.want) {
+				t.Fatalf("problems = %v, want %d\n%v", fe.Problems, len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				got := fe.Problems[i]
+				if got.Line != want.Line || (want.Msg != "" && got.Msg != want.Msg) {
+					t.Errorf("problem[%d] = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileError_Error(t *testing.T) {
+	err := &FileError{Path: "/etc/catcher/config.toml", Problems: []Problem{
+		{Line: 3, Msg: `unknown key "secrt"`},
+		{Msg: "something global"},
+	}}
+	want := "/etc/catcher/config.toml: 2 problem(s)\n  line 3: unknown key \"secrt\"\n  something global"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestDecodeFile_Example(t *testing.T) {
+	fc := fileConfig{Validation: DefaultValidation(), Maintenance: DefaultMaintenance()}
+	if err := decodeFile("../../deploy/config.toml.example", &fc); err != nil {
+		t.Errorf("example config is invalid: %v", err)
+	}
+}
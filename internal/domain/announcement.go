@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxAnnouncementLength is the longest announcement message, in characters.
+const MaxAnnouncementLength = 500
+
+var (
+	// ErrNoAnnouncement reports that no announcement is set.
+	ErrNoAnnouncement = errors.New("no announcement")
+	// ErrInvalidAnnouncement is wrapped by the errors of Announce.
+	ErrInvalidAnnouncement = errors.New("invalid announcement")
+)
+
+// Announcement is a message for everyone using catcher, such as a planned
+// maintenance window. It is shown from when it is set until End, and is
+// active between Start and End; zero times leave that side open. While
+// active, ReadOnly refuses changes through the API.
+type Announcement struct {
+	Message  string
+	ReadOnly bool
+	Start    time.Time
+	End      time.Time
+	SetAt    time.Time
+}
+
+// Active reports whether the announced window has begun and not ended.
+func (a *Announcement) Active(now time.Time) bool {
+	return !now.Before(a.Start) && !a.Ended(now)
+}
+
+// Ended reports whether the announced window is over.
+func (a *Announcement) Ended(now time.Time) bool {
+	return !a.End.IsZero() && !now.Before(a.End)
+}
+
+// validate checks a before it is stored.
+func (a *Announcement) validate(now time.Time) error {
+	switch {
+	case a.Message == "":
+		return fmt.Errorf("%w: message is required", ErrInvalidAnnouncement)
+	case utf8.RuneCountInString(a.Message) > MaxAnnouncementLength:
+		return fmt.Errorf("%w: message must be at most %d characters", ErrInvalidAnnouncement, MaxAnnouncementLength)
+	case !a.End.IsZero() && !a.End.After(a.Start):
+		return fmt.Errorf("%w: end must be after start", ErrInvalidAnnouncement)
+	case a.Ended(now):
+		return fmt.Errorf("%w: end is in the past", ErrInvalidAnnouncement)
+	}
+	for _, r := range a.Message {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%w: message must be one line without control characters", ErrInvalidAnnouncement)
+		}
+	}
+	return nil
+}
+
+// SetAnnouncementRepository enables announcements.
+func (s *JobService) SetAnnouncementRepository(r AnnouncementRepository) {
+	s.announcements = r
+}
+
+// Announce stores a in place of any earlier announcement, stamping SetAt.
+func (s *JobService) Announce(ctx context.Context, a *Announcement) error {
+	if s.announcements == nil {
+		return errors.ErrUnsupported
+	}
+	now := s.clock.Now()
+	if err := a.validate(now); err != nil {
+		return err
+	}
+	a.SetAt = now
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.announcements.SaveAnnouncement(ctx, a)
+}
+
+// Announcement returns the announcement, or ErrNoAnnouncement if none is
+// set or its window has ended.
+func (s *JobService) Announcement(ctx context.Context) (*Announcement, error) {
+	if s.announcements == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	a, err := s.announcements.Announcement(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if a.Ended(s.clock.Now()) {
+		return nil, ErrNoAnnouncement
+	}
+	return a, nil
+}
+
+// ClearAnnouncement removes the announcement.
+func (s *JobService) ClearAnnouncement(ctx context.Context) error {
+	if s.announcements == nil {
+		return errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.announcements.ClearAnnouncement(ctx)
+}
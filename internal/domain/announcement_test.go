@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memAnnouncements keeps an announcement in memory.
+type memAnnouncements struct {
+	a *Announcement
+}
+
+func (m *memAnnouncements) SaveAnnouncement(ctx context.Context, a *Announcement) error {
+	m.a = a
+	return nil
+}
+
+func (m *memAnnouncements) Announcement(ctx context.Context) (*Announcement, error) {
+	if m.a == nil {
+		return nil, ErrNoAnnouncement
+	}
+	return m.a, nil
+}
+
+func (m *memAnnouncements) ClearAnnouncement(ctx context.Context) error {
+	m.a = nil
+	return nil
+}
+
+func TestAnnouncement_Active(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		start, end time.Time
+		active     bool
+		ended      bool
+	}{
+		{"open", time.Time{}, time.Time{}, true, false},
+		{"scheduled", now.Add(time.Hour), now.Add(2 * time.Hour), false, false},
+		{"started", now.Add(-time.Hour), now.Add(time.Hour), true, false},
+		{"starting now", now, time.Time{}, true, false},
+		{"ended", now.Add(-2 * time.Hour), now.Add(-time.Hour), false, true},
+		{"ending now", time.Time{}, now, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Announcement{Message: "m", Start: tt.start, End: tt.end}
+			if got := a.Active(now); got != tt.active {
+				t.Errorf("Active() = %v, want %v", got, tt.active)
+			}
+			if got := a.Ended(now); got != tt.ended {
+				t.Errorf("Ended() = %v, want %v", got, tt.ended)
+			}
+		})
+	}
+}
+
+func TestJobService_Announce(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	now := clock.Now()
+	svc := NewJobService(nil)
+	svc.SetClock(clock)
+
+	if err := svc.Announce(ctx, &Announcement{Message: "m"}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("Announce() without a repository error = %v, want ErrUnsupported", err)
+	}
+	svc.SetAnnouncementRepository(&memAnnouncements{})
+
+	for _, a := range []*Announcement{
+		{},
+		{Message: strings.Repeat("x", MaxAnnouncementLength+1)},
+		{Message: "two\nlines"},
+		{Message: "m", Start: now.Add(2 * time.Hour), End: now.Add(time.Hour)},
+		{Message: "m", End: now.Add(-time.Minute)},
+	} {
+		if err := svc.Announce(ctx, a); !errors.Is(err, ErrInvalidAnnouncement) {
+			t.Errorf("Announce(%+v) error = %v, want ErrInvalidAnnouncement", a, err)
+		}
+	}
+
+	if err := svc.Announce(ctx, &Announcement{Message: "NAS upgrade", ReadOnly: true, End: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Announce() error = %v", err)
+	}
+	got, err := svc.Announcement(ctx)
+	if err != nil || got.Message != "NAS upgrade" || !got.SetAt.Equal(now) {
+		t.Fatalf("Announcement() = %+v, %v", got, err)
+	}
+
+	clock.Advance(time.Hour)
+	if _, err := svc.Announcement(ctx); !errors.Is(err, ErrNoAnnouncement) {
+		t.Errorf("Announcement() after its end error = %v, want ErrNoAnnouncement", err)
+	}
+	if err := svc.ClearAnnouncement(ctx); err != nil {
+		t.Errorf("ClearAnnouncement() error = %v", err)
+	}
+}
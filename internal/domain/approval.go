@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MatchHosts returns an approval rule matching URLs whose host is one of
+// hosts or a subdomain of one. "*" matches every URL.
+func MatchHosts(hosts ...string) func(u *url.URL) bool {
+	return func(u *url.URL) bool {
+		host := strings.ToLower(u.Hostname())
+		for _, h := range hosts {
+			h = strings.ToLower(strings.TrimPrefix(h, "."))
+			if h == "*" || host == h || strings.HasSuffix(host, "."+h) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestMatchHosts(t *testing.T) {
+	tests := []struct {
+		hosts []string
+		url   string
+		want  bool
+	}{
+		{[]string{"example.com"}, "https://example.com/a", true},
+		{[]string{"example.com"}, "https://cdn.EXAMPLE.com:8443/a", true},
+		{[]string{"example.com"}, "https://notexample.com/a", false},
+		{[]string{".example.com"}, "https://example.com/a", true},
+		{[]string{"a.org", "b.org"}, "https://b.org/a", true},
+		{[]string{"*"}, "https://anything.net/a", true},
+		{nil, "https://example.com/a", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := MatchHosts(tt.hosts...)(u); got != tt.want {
+			t.Errorf("MatchHosts(%v)(%q) = %v, want %v", tt.hosts, tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Attempt records one run of a processor against a job.
+type Attempt struct {
+	Number     int
+	Processor  string
+	Command    string // rendered command line, if the processor runs one
+	Output     string // captured command output, truncated to MaxAttemptOutput
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// MaxAttemptOutput caps stored command output; the tail is kept since
+// that is where tools report what went wrong.
+const MaxAttemptOutput = 64 << 10
+
+// SetOutput stores out, keeping only its last MaxAttemptOutput bytes.
+func (a *Attempt) SetOutput(out []byte) {
+	if len(out) > MaxAttemptOutput {
+		out = out[len(out)-MaxAttemptOutput:]
+	}
+	a.Output = string(out)
+}
+
+type attemptKey struct{}
+
+// WithAttempt returns a context carrying a, for processors to fill in.
+func WithAttempt(ctx context.Context, a *Attempt) context.Context {
+	return context.WithValue(ctx, attemptKey{}, a)
+}
+
+// AttemptFrom returns the attempt being recorded in ctx. It returns a
+// throwaway Attempt when none is, so callers can always write to it.
+func AttemptFrom(ctx context.Context) *Attempt {
+	if a, ok := ctx.Value(attemptKey{}).(*Attempt); ok {
+		return a
+	}
+	return &Attempt{}
+}
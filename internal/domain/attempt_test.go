@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAttempt_SetOutputKeepsTail(t *testing.T) {
+	var a Attempt
+	out := strings.Repeat("x", MaxAttemptOutput) + "ERROR: unavailable"
+	a.SetOutput([]byte(out))
+
+	if len(a.Output) != MaxAttemptOutput {
+		t.Errorf("len(Output) = %d, want %d", len(a.Output), MaxAttemptOutput)
+	}
+	if !strings.HasSuffix(a.Output, "ERROR: unavailable") {
+		t.Error("Output lost the tail of the command output")
+	}
+}
+
+func TestAttemptFrom(t *testing.T) {
+	a := &Attempt{Number: 2}
+	if got := AttemptFrom(WithAttempt(context.Background(), a)); got != a {
+		t.Errorf("AttemptFrom() = %p, want %p", got, a)
+	}
+	// Without an attempt, writes go to a throwaway value
+	AttemptFrom(context.Background()).Command = "ignored"
+}
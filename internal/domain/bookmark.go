@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBookmarkUnsupported rejects a bookmark submission whose processor
+// can't save bookmarks.
+var ErrBookmarkUnsupported = errors.New("processor does not support bookmarks")
+
+// BookmarkSaver is implemented by processors that save bookmark jobs
+// instead of downloading them. Others would download them.
+type BookmarkSaver interface {
+	SavesBookmarks() bool
+}
+
+type bookmarkKey struct{}
+
+// WithBookmark returns a context submitting jobs as bookmarks: processors
+// record where the URL can be streamed from instead of downloading it.
+func WithBookmark(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bookmarkKey{}, true)
+}
+
+// BookmarkFrom reports whether the job being created is a bookmark.
+// Repositories store it with the job.
+func BookmarkFrom(ctx context.Context) bool {
+	b, _ := ctx.Value(bookmarkKey{}).(bool)
+	return b
+}
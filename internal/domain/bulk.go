@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// BulkFilter selects the jobs a bulk transition applies to. The zero value
+// selects every job in the state the transition leaves.
+type BulkFilter struct {
+	// Host selects URLs of the host and its subdomains.
+	Host string
+}
+
+// Match reports whether f selects a job for rawURL.
+func (f BulkFilter) Match(rawURL string) bool {
+	if f.Host == "" {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	return err == nil && MatchHosts(f.Host)(u)
+}
+
+// SetBulkRepository enables RequeueFailed and CancelPending.
+func (s *JobService) SetBulkRepository(r BulkRepository) {
+	s.bulk = r
+}
+
+// RequeueFailed makes the failed jobs f selects pending again, with their
+// attempts reset, and returns how many there were.
+func (s *JobService) RequeueFailed(ctx context.Context, f BulkFilter) (int64, error) {
+	if s.bulk == nil {
+		return 0, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.bulk.RequeueFailed(ctx, f)
+}
+
+// CancelPending fails the pending jobs f selects, held ones included,
+// without processing them, and returns how many there were. Jobs already
+// processing are left to finish.
+func (s *JobService) CancelPending(ctx context.Context, f BulkFilter) (int64, error) {
+	if s.bulk == nil {
+		return 0, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.bulk.CancelPending(ctx, f, "cancelled")
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBulkFilter_Match(t *testing.T) {
+	tests := []struct {
+		host string
+		url  string
+		want bool
+	}{
+		{"", "https://anything.net/a", true},
+		{"", "not a url", true},
+		{"example.com", "https://example.com/a", true},
+		{"example.com", "https://cdn.example.com/a", true},
+		{"example.com", "https://notexample.com/a", false},
+		{"example.com", "://bad", false},
+	}
+	for _, tt := range tests {
+		if got := (BulkFilter{Host: tt.host}).Match(tt.url); got != tt.want {
+			t.Errorf("BulkFilter{%q}.Match(%q) = %v, want %v", tt.host, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestJobService_Bulk_Unsupported(t *testing.T) {
+	svc := NewJobService(newMockRepo())
+	ctx := context.Background()
+	if _, err := svc.RequeueFailed(ctx, BulkFilter{}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("RequeueFailed() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := svc.CancelPending(ctx, BulkFilter{}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("CancelPending() error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewManualClock(start)
+	ticker := c.NewTicker(time.Minute)
+
+	c.Advance(30 * time.Second)
+	select {
+	case tick := <-ticker.C():
+		t.Fatalf("ticked at %s before the interval passed", tick)
+	default:
+	}
+
+	// A slow receiver misses ticks, like with time.Ticker
+	c.Advance(3 * time.Minute)
+	if tick := <-ticker.C(); !tick.Equal(start.Add(time.Minute)) {
+		t.Errorf("tick = %s, want %s", tick, start.Add(time.Minute))
+	}
+	select {
+	case tick := <-ticker.C():
+		t.Errorf("ticked again at %s, want later ticks dropped", tick)
+	default:
+	}
+	if got, want := c.Now(), start.Add(3*time.Minute+30*time.Second); !got.Equal(want) {
+		t.Errorf("Now() = %s, want %s", got, want)
+	}
+
+	c.Advance(30 * time.Second)
+	if tick := <-ticker.C(); !tick.Equal(start.Add(4 * time.Minute)) {
+		t.Errorf("tick = %s, want %s", tick, start.Add(4*time.Minute))
+	}
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	select {
+	case tick := <-ticker.C():
+		t.Errorf("stopped ticker ticked at %s", tick)
+	default:
+	}
+}
+
+// fakeRetryScheduler records the last RetryAt call.
+type fakeRetryScheduler struct {
+	at time.Time
+}
+
+func (s *fakeRetryScheduler) RetryAt(ctx context.Context, id int64, reason string, at time.Time) error {
+	s.at = at
+	return nil
+}
+
+func (s *fakeRetryScheduler) DelayPending(ctx context.Context, id int64, reason string, at time.Time) error {
+	s.at = at
+	return nil
+}
+
+func TestJobService_SetClock(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	scheduler := &fakeRetryScheduler{}
+	pruner := &fakePruner{}
+	svc := NewJobService(newMockRepo())
+	svc.SetClock(clock)
+	svc.SetRetryScheduler(scheduler)
+	svc.SetRetention(pruner, 24*time.Hour, false)
+
+	if err := svc.MarkRetryAfter(ctx, 1, "rate limited", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if want := clock.Now().Add(time.Hour); !scheduler.at.Equal(want) {
+		t.Errorf("retry at %s, want %s", scheduler.at, want)
+	}
+	if _, err := svc.Prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := clock.Now().Add(-24 * time.Hour); !pruner.before.Equal(want) {
+		t.Errorf("pruned before %s, want %s", pruner.before, want)
+	}
+}
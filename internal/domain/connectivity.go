@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Connectivity is whether the uplink is up, as last checked.
+type Connectivity struct {
+	Online    bool
+	Target    string    // the URL checked
+	Since     time.Time // when Online last changed, or the first check
+	CheckedAt time.Time // zero before the first check
+	Error     string    // why the last check failed
+}
+
+// ConnectivityMonitor is the driven port for checking the uplink.
+type ConnectivityMonitor interface {
+	// Connectivity returns the state as last checked.
+	Connectivity() Connectivity
+	// Check checks again now and returns the new state.
+	Check(ctx context.Context) Connectivity
+}
+
+// SetPostponer enables Postpone.
+func (s *JobService) SetPostponer(p Postponer) {
+	s.postponer = p
+}
+
+// Postpone makes a processing job pending again, to run no sooner than
+// after from now, without counting the run as one of its attempts.
+func (s *JobService) Postpone(ctx context.Context, id int64, reason string, after time.Duration) error {
+	if s.postponer == nil {
+		return errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.postponer.Postpone(ctx, id, reason, s.clock.Now().Add(after))
+}
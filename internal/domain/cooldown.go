@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Cooldown keeps the jobs for a host waiting after it rate limited us.
+type Cooldown struct {
+	Host   string // as returned by CooldownHost
+	Until  time.Time
+	Reason string
+}
+
+// CooldownHost returns the host a URL's cooldown is kept for: its
+// lower-case host name without a leading "www.", or "" if it has none.
+func CooldownHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// SetCooldownRepository makes host cooldowns survive restarts.
+func (s *JobService) SetCooldownRepository(r CooldownRepository) {
+	s.cooldowns = r
+}
+
+// CoolDown stores c, replacing any cooldown of its host. It is a no-op
+// without a cooldown repository.
+func (s *JobService) CoolDown(ctx context.Context, c Cooldown) error {
+	if s.cooldowns == nil {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.cooldowns.SetCooldown(ctx, c)
+}
+
+// Cooldowns returns the stored cooldowns that haven't expired, or none
+// without a cooldown repository.
+func (s *JobService) Cooldowns(ctx context.Context) ([]Cooldown, error) {
+	if s.cooldowns == nil {
+		return nil, nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.cooldowns.Cooldowns(ctx)
+}
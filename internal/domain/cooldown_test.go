@@ -0,0 +1,20 @@
+package domain
+
+import "testing"
+
+func TestCooldownHost(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.YouTube.com/watch?v=1", "youtube.com"},
+		{"https://m.youtube.com/watch?v=1", "m.youtube.com"},
+		{"https://example.com:8443/a", "example.com"},
+		{"not a url", ""},
+	}
+	for _, tt := range tests {
+		if got := CooldownHost(tt.url); got != tt.want {
+			t.Errorf("CooldownHost(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
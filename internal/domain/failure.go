@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FailureCause buckets a failure message by what would fix it.
+type FailureCause string
+
+const (
+	CauseNetwork   FailureCause = "network"
+	CauseExtractor FailureCause = "extractor"
+	CauseAuth      FailureCause = "auth"
+	CauseDisk      FailureCause = "disk"
+	CauseTimeout   FailureCause = "timeout"
+	CauseUnknown   FailureCause = "unknown"
+)
+
+// FailureCauses lists every cause, in the order ties are reported.
+var FailureCauses = []FailureCause{CauseNetwork, CauseExtractor, CauseAuth, CauseDisk, CauseTimeout, CauseUnknown}
+
+// failureKeywords maps lower-case message fragments to causes. They are
+// tried in order, so timeouts win over the network errors they wrap, and
+// HTTP 401 and 403 over other HTTP errors.
+var failureKeywords = []struct {
+	cause    FailureCause
+	keywords []string
+}{
+	{CauseTimeout, []string{"timed out", "timeout", "deadline exceeded", "stalled:"}},
+	{CauseAuth, []string{
+		"http error 401", "http error 403", "unauthorized", "forbidden", "sign in", "log in", "login",
+		"cookies", "private video", "members-only", "age-restricted", "authentication",
+	}},
+	{CauseDisk, []string{
+		"no space left", "disk quota", "read-only file system", "file name too long",
+		"permission denied", "create target dir", "too large:",
+	}},
+	{CauseExtractor, []string{
+		"unsupported url", "unable to extract", "extractor", "no video formats", "requested format",
+		"video unavailable", "not available", "parse probe output", "no processor for url",
+	}},
+	{CauseNetwork, []string{
+		"connection refused", "connection reset", "no such host", "network is unreachable", "name resolution",
+		"tls", "eof", "broken pipe", "http error 5", "http error 429", "unable to download", "getaddrinfo",
+	}},
+}
+
+// ClassifyFailure returns the cause of a failure from its message, or
+// CauseUnknown when no keyword matches.
+func ClassifyFailure(msg string) FailureCause {
+	msg = strings.ToLower(msg)
+	for _, group := range failureKeywords {
+		for _, keyword := range group.keywords {
+			if strings.Contains(msg, keyword) {
+				return group.cause
+			}
+		}
+	}
+	return CauseUnknown
+}
+
+// unreachableKeywords are lower-case fragments of the network errors that
+// mean a site could not be reached at all: its name did not resolve, no
+// route led to it, or nothing answered on its port.
+var unreachableKeywords = []string{
+	"connection refused", "no such host", "name resolution", "getaddrinfo", "name or service not known",
+	"nodename nor servname", "network is unreachable", "no route to host", "host is unreachable",
+}
+
+// Unreachable reports whether a failure message says the site could not be
+// reached. Unlike CauseNetwork, it leaves out errors from sites that
+// answered, such as HTTP 429 and 5xx or a reset connection.
+func Unreachable(msg string) bool {
+	msg = strings.ToLower(msg)
+	return slices.ContainsFunc(unreachableKeywords, func(keyword string) bool {
+		return strings.Contains(msg, keyword)
+	})
+}
+
+// Failure is a failed attempt, or a job that failed for good, as read by
+// a FailureLister.
+type Failure struct {
+	JobID int64
+	Error string
+	At    time.Time
+	Final bool // the job failed for good, rather than one attempt
+}
+
+// FailureCount sums the failures of one cause.
+type FailureCount struct {
+	Cause    FailureCause
+	Attempts int64  // failed attempts, whether retried or not
+	Jobs     int64  // jobs that failed for good
+	Example  string // the newest message of this cause
+}
+
+// FailureReport counts failures since a time by cause, most frequent first.
+type FailureReport struct {
+	Since  time.Time
+	Causes []FailureCount
+}
+
+// SetFailureLister enables FailureReport.
+func (s *JobService) SetFailureLister(l FailureLister) {
+	s.failures = l
+}
+
+// FailureReport classifies the failed attempts and jobs since since. Every
+// cause is reported, ordered by failed jobs and then failed attempts.
+func (s *JobService) FailureReport(ctx context.Context, since time.Time) (*FailureReport, error) {
+	if s.failures == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	failures, err := s.failures.ListFailures(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]FailureCount, len(FailureCauses))
+	index := make(map[FailureCause]int, len(FailureCauses))
+	for i, cause := range FailureCauses {
+		counts[i].Cause, index[cause] = cause, i
+	}
+	newest := make([]time.Time, len(counts))
+	for _, f := range failures {
+		i := index[ClassifyFailure(f.Error)]
+		if f.Final {
+			counts[i].Jobs++
+		} else {
+			counts[i].Attempts++
+		}
+		if !f.At.Before(newest[i]) {
+			counts[i].Example, newest[i] = f.Error, f.At
+		}
+	}
+	slices.SortStableFunc(counts, func(a, b FailureCount) int {
+		if c := cmp.Compare(b.Jobs, a.Jobs); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Attempts, a.Attempts)
+	})
+	return &FailureReport{Since: since, Causes: counts}, nil
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want FailureCause
+	}{
+		{"yt-dlp failed: exit status 1: ERROR: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>", CauseNetwork},
+		{"curl failed: exit status 56: Connection reset by peer", CauseNetwork},
+		{"yt-dlp failed: exit status 1: ERROR: unable to download video data: HTTP Error 503: Service Unavailable", CauseNetwork},
+		{"yt-dlp failed: exit status 1: ERROR: [generic] Unsupported URL: https://example.com/", CauseExtractor},
+		{"ERROR: [youtube] abc: Unable to extract uploader id", CauseExtractor},
+		{"no processor for URL", CauseExtractor},
+		{"ERROR: [youtube] abc: Sign in to confirm your age", CauseAuth},
+		{"unable to download video data: HTTP Error 403: Forbidden", CauseAuth},
+		{"create target dir: mkdir /media/v: permission denied", CauseDisk},
+		{"write: No space left on device", CauseDisk},
+		{"too large: estimated 3.1 GiB exceeds the limit of 1.9 GiB", CauseDisk},
+		{"Read timed out. (read timeout=20)", CauseTimeout},
+		{"dial tcp 10.0.0.1:443: i/o timeout", CauseTimeout},
+		{"stalled: no progress for 20m0s", CauseTimeout},
+		{"exit status 2", CauseUnknown},
+		{"", CauseUnknown},
+	}
+	for _, tt := range tests {
+		if got := ClassifyFailure(tt.msg); got != tt.want {
+			t.Errorf("ClassifyFailure(%q) = %s, want %s", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"dial tcp 10.0.0.1:443: connect: connection refused", true},
+		{"ERROR: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>", true},
+		{"dial tcp: lookup example.com: no such host", true},
+		{"dial tcp 10.0.0.1:443: connect: network is unreachable", true},
+		{"curl: (7) Failed to connect: No route to host", true},
+		{"unable to download video data: HTTP Error 429: Too Many Requests", false},
+		{"unable to download video data: HTTP Error 503: Service Unavailable", false},
+		{"curl failed: exit status 56: Connection reset by peer", false},
+		{"dial tcp 10.0.0.1:443: i/o timeout", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Unreachable(tt.msg); got != tt.want {
+			t.Errorf("Unreachable(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+type fakeFailureLister []Failure
+
+func (l fakeFailureLister) ListFailures(ctx context.Context, since time.Time) ([]Failure, error) {
+	return l, nil
+}
+
+func TestJobService_FailureReport(t *testing.T) {
+	svc := NewJobService(newMockRepo())
+	if _, err := svc.FailureReport(context.Background(), time.Time{}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("FailureReport() without lister error = %v, want ErrUnsupported", err)
+	}
+
+	now := time.Now()
+	svc.SetFailureLister(fakeFailureLister{
+		{JobID: 1, Error: "connection refused", At: now.Add(-2 * time.Hour)},
+		{JobID: 1, Error: "connection reset by peer", At: now.Add(-time.Hour)},
+		{JobID: 1, Error: "no such host", At: now.Add(-3 * time.Hour)},
+		{JobID: 2, Error: "Sign in to confirm your age", At: now},
+		{JobID: 2, Error: "Sign in to confirm your age", At: now, Final: true},
+	})
+	report, err := svc.FailureReport(context.Background(), now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("FailureReport() error = %v", err)
+	}
+	if len(report.Causes) != len(FailureCauses) {
+		t.Fatalf("FailureReport() has %d causes, want all %d", len(report.Causes), len(FailureCauses))
+	}
+	want := []FailureCount{
+		{Cause: CauseAuth, Attempts: 1, Jobs: 1, Example: "Sign in to confirm your age"},
+		{Cause: CauseNetwork, Attempts: 3, Example: "connection reset by peer"},
+		{Cause: CauseExtractor},
+	}
+	for i, w := range want {
+		if report.Causes[i] != w {
+			t.Errorf("Causes[%d] = %+v, want %+v", i, report.Causes[i], w)
+		}
+	}
+}
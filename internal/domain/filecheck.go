@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoFileCheck reports that no file check has been stored yet.
+var ErrNoFileCheck = errors.New("no file check has run")
+
+// FileProblem says how a recorded result file differs from the disk.
+type FileProblem string
+
+const (
+	FileMissing      FileProblem = "missing"
+	FileSizeMismatch FileProblem = "size_mismatch"
+	FileUnreadable   FileProblem = "unreadable" // could not be checked
+)
+
+// FileIssue is a result file of a completed job that no longer matches
+// what was recorded.
+type FileIssue struct {
+	JobID     int64
+	Path      string
+	Problem   FileProblem
+	WantBytes int64  // as recorded
+	GotBytes  int64  // on disk; zero when missing or unreadable
+	Error     string // why the file could not be checked, if unreadable
+}
+
+// FileCheck is the outcome of checking the files of the jobs completed
+// since Since against the disk.
+type FileCheck struct {
+	CheckedAt time.Time
+	Since     time.Time
+	Jobs      int // jobs whose files were checked
+	Files     int // files checked
+	Issues    []FileIssue
+}
+
+// SetFileCheckRepository enables storing file checks.
+func (s *JobService) SetFileCheckRepository(r FileCheckRepository) {
+	s.fileChecks = r
+}
+
+// SaveFileCheck stores c in place of the previous check. It is a no-op
+// without a file check repository.
+func (s *JobService) SaveFileCheck(ctx context.Context, c *FileCheck) error {
+	if s.fileChecks == nil {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.fileChecks.SaveFileCheck(ctx, c)
+}
+
+// LastFileCheck returns the stored file check, or ErrNoFileCheck.
+func (s *JobService) LastFileCheck(ctx context.Context) (*FileCheck, error) {
+	if s.fileChecks == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.fileChecks.LastFileCheck(ctx)
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// Grant limits what can be submitted with a named API token. Empty
+// Processors or Hosts allow any.
+type Grant struct {
+	Name       string
+	Processors []string // processors the token may trigger
+	Hosts      []string // hosts, with their subdomains, the token may submit
+}
+
+// PermissionError reports a submission its grant does not allow.
+type PermissionError struct {
+	Grant  string // the token's name
+	Reason string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("token %q may not submit this URL: %s", e.Grant, e.Reason)
+}
+
+type grantKey struct{}
+
+// WithGrant returns a context submitting jobs on behalf of a token limited
+// by g.
+func WithGrant(ctx context.Context, g *Grant) context.Context {
+	return context.WithValue(ctx, grantKey{}, g)
+}
+
+// GrantFrom returns the grant of the token submitting, or nil if the
+// submission is unrestricted.
+func GrantFrom(ctx context.Context) *Grant {
+	g, _ := ctx.Value(grantKey{}).(*Grant)
+	return g
+}
+
+// check returns a PermissionError unless g allows u to be handled by
+// processor, which is "" when no processor matches.
+func (g *Grant) check(u *url.URL, processor string) error {
+	if len(g.Hosts) > 0 && !MatchHosts(g.Hosts...)(u) {
+		return &PermissionError{Grant: g.Name, Reason: fmt.Sprintf("host %q not allowed", u.Hostname())}
+	}
+	if len(g.Processors) > 0 && !slices.Contains(g.Processors, processor) {
+		if processor == "" {
+			return &PermissionError{Grant: g.Name, Reason: "no allowed processor matches"}
+		}
+		return &PermissionError{Grant: g.Name, Reason: fmt.Sprintf("processor %q not allowed", processor)}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJobService_Submit_Grant(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.SetProcessorMatcher(func(url string) string {
+		switch {
+		case strings.Contains(url, "youtube"):
+			return "youtube"
+		case strings.HasPrefix(url, "magnet:"):
+			return "torrent"
+		}
+		return ""
+	})
+	guest := WithGrant(context.Background(), &Grant{Name: "guest", Processors: []string{"youtube"}, Hosts: []string{"youtube.com"}})
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		url    string
+		reason string // "" when allowed
+	}{
+		{"allowed", guest, "https://www.youtube.com/watch?v=1", ""},
+		{"processor not allowed", WithGrant(context.Background(), &Grant{Name: "guest", Processors: []string{"youtube"}}), "magnet:?xt=urn:btih:abc", `processor "torrent" not allowed`},
+		{"host not allowed", guest, "https://youtube.example.net/v", `host "youtube.example.net" not allowed`},
+		{"no processor", WithGrant(context.Background(), &Grant{Name: "guest", Processors: []string{"youtube"}}), "https://example.com/a", "no allowed processor matches"},
+		{"no grant", context.Background(), "magnet:?xt=urn:btih:abc", ""},
+		{"unrestricted grant", WithGrant(context.Background(), &Grant{Name: "ci"}), "magnet:?xt=urn:btih:abc", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Submit(tt.ctx, tt.url)
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("Submit() error = %v", err)
+				}
+				return
+			}
+			var pe *PermissionError
+			if !errors.As(err, &pe) || pe.Grant != "guest" || pe.Reason != tt.reason {
+				t.Errorf("Submit() error = %v, want *PermissionError %q", err, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStalled reports a job stopped by the worker's watchdog for showing no
+// progress.
+var ErrStalled = errors.New("stalled")
+
+// Heartbeat records the last sign of progress from a running job, such as
+// its command writing output, for the worker's watchdog.
+type Heartbeat struct {
+	last atomic.Int64 // Unix nanoseconds
+}
+
+// NewHeartbeat returns a Heartbeat that last beat now.
+func NewHeartbeat() *Heartbeat {
+	h := &Heartbeat{}
+	h.Beat()
+	return h
+}
+
+// Beat records progress now. It is safe for concurrent use.
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Since returns how long ago the last beat was.
+func (h *Heartbeat) Since() time.Duration {
+	return time.Since(time.Unix(0, h.last.Load()))
+}
+
+type heartbeatKey struct{}
+
+// WithHeartbeat returns a context carrying h, for processors to beat.
+func WithHeartbeat(ctx context.Context, h *Heartbeat) context.Context {
+	return context.WithValue(ctx, heartbeatKey{}, h)
+}
+
+// HeartbeatFrom returns the heartbeat of the job running in ctx. It
+// returns a throwaway Heartbeat when none is, so callers can always beat.
+func HeartbeatFrom(ctx context.Context) *Heartbeat {
+	if h, ok := ctx.Value(heartbeatKey{}).(*Heartbeat); ok {
+		return h
+	}
+	return NewHeartbeat()
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	h := NewHeartbeat()
+	h.last.Store(time.Now().Add(-time.Minute).UnixNano())
+	if got := h.Since(); got < time.Minute {
+		t.Errorf("Since() = %s, want at least a minute", got)
+	}
+	h.Beat()
+	if got := h.Since(); got >= time.Minute {
+		t.Errorf("Since() after Beat = %s, want under a minute", got)
+	}
+}
+
+func TestHeartbeatFrom(t *testing.T) {
+	h := NewHeartbeat()
+	if got := HeartbeatFrom(WithHeartbeat(context.Background(), h)); got != h {
+		t.Errorf("HeartbeatFrom() = %p, want %p", got, h)
+	}
+	// Without a heartbeat, beats go to a throwaway value
+	HeartbeatFrom(context.Background()).Beat()
+}
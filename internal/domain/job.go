@@ -10,17 +10,81 @@ const (
 	StatusProcessing JobStatus = "processing"
 	StatusCompleted  JobStatus = "completed"
 	StatusFailed     JobStatus = "failed"
+	// StatusNeedsApproval jobs wait for an approve or reject decision
+	// before they become pending.
+	StatusNeedsApproval JobStatus = "needs_approval"
 )
 
+// Valid reports whether s is one of the job statuses.
+func (s JobStatus) Valid() bool {
+	switch s {
+	case StatusPending, StatusProcessing, StatusCompleted, StatusFailed, StatusNeedsApproval:
+		return true
+	}
+	return false
+}
+
 // Job represents a URL processing job.
 type Job struct {
-	ID        int64
-	URL       string
-	Status    JobStatus
-	Attempts  int
-	Error     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID          int64
+	UID         string // random UUID, for exposing jobs without guessable IDs
+	URL         string
+	OriginalURL string // as submitted, if rewrite rules changed it
+	Status      JobStatus
+	Attempts    int
+	Error       string
+	Title       string       // as reported by the processor on success
+	Bytes       int64        // bytes produced, from the processor's result
+	Files       []ResultFile // files produced, from the processor's result
+	Duration    time.Duration
+	Held        bool      // pending but not to be processed until released
+	Approved    bool      // approved by an admin, so size limits no longer apply
+	NotBefore   time.Time // earliest next attempt, if a retry was delayed
+	Queue       string    // queue the job waits in, see Queuer
+	Source      string    // who submitted it, see WithSource
+	Notes       string    // free text to remember the job by, see WithNotes
+	Bookmark    bool      // stream from the URL rather than download, see WithBookmark
+	// RedownloadOf is the ID of the completed job this one downloads
+	// again, see JobService.Redownload.
+	RedownloadOf int64
+	UserAgent    string // sent instead of the processor's default, see WithUserAgent
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	CompletedAt  time.Time // when it last completed; zero if it never has
+}
+
+// ResultFile is a file a job produced.
+type ResultFile struct {
+	Path  string
+	Bytes int64
+	// SHA256 is the hex digest of the file's contents, when checksums are
+	// enabled.
+	SHA256 string
+	// Library marks a file found in the user's library rather than made
+	// by the job. catcher never removes it.
+	Library bool
+}
+
+// Completion records what a successful job produced. The repository stores
+// it in one transaction with the status change, so a completed job always
+// has its results.
+type Completion struct {
+	Processor string
+	Title     string
+	Bytes     int64
+	Files     []ResultFile
+	Duration  time.Duration // time spent in the successful run
+}
+
+// JobFilter narrows job listings.
+type JobFilter struct {
+	Status JobStatus // empty matches all statuses
+	Host   string    // URLs of the host and its subdomains; empty matches all
+	Limit  int
+	// After continues a listing, ordered newest first, past the job with
+	// this ID, so pages neither skip nor repeat jobs as new ones are
+	// added. Zero starts at the newest.
+	After int64
 }
 
 // CanRetry returns true if the job can be retried.
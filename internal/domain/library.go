@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// LibraryFile is a file a job delivered, as kept in the library index.
+// Entries outlive their jobs, so the index remembers every file catcher
+// ever delivered, even once its job is pruned.
+type LibraryFile struct {
+	ID          int64
+	JobID       int64
+	URL         string // of the job that delivered the file
+	Path        string
+	Bytes       int64
+	SHA256      string // empty unless recorded or rescanned with hashing
+	DeliveredAt time.Time
+	Missing     bool      // as of the last rescan
+	CheckedAt   time.Time // of the last rescan; zero before the first
+}
+
+// LibraryQuery selects library files. Empty fields match everything.
+type LibraryQuery struct {
+	Text    string // case-insensitive substring of the path or URL
+	URL     string // the whole URL of the job that delivered the file
+	SHA256  string
+	JobID   int64
+	Missing *bool
+	Limit   int // zero means no limit
+}
+
+// Match reports whether q selects f.
+func (q LibraryQuery) Match(f *LibraryFile) bool {
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(f.Path), text) && !strings.Contains(strings.ToLower(f.URL), text) {
+			return false
+		}
+	}
+	if q.URL != "" && q.URL != f.URL {
+		return false
+	}
+	if q.SHA256 != "" && !strings.EqualFold(q.SHA256, f.SHA256) {
+		return false
+	}
+	if q.JobID != 0 && q.JobID != f.JobID {
+		return false
+	}
+	return q.Missing == nil || *q.Missing == f.Missing
+}
+
+// LibraryRescan is the outcome of checking every indexed file against the
+// disk.
+type LibraryRescan struct {
+	CheckedAt time.Time
+	Files     int // files checked
+	Missing   int // files not on disk
+	Changed   int // files whose size or digest changed since recorded
+	Returned  int // files found again after being missing
+}
+
+// SetLibraryIndex enables the library index.
+func (s *JobService) SetLibraryIndex(l LibraryIndex) {
+	s.library = l
+}
+
+// LibraryFiles returns every indexed file, oldest delivery first.
+func (s *JobService) LibraryFiles(ctx context.Context) ([]LibraryFile, error) {
+	if s.library == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.library.LibraryFiles(ctx)
+}
+
+// SearchLibrary returns the indexed files q selects, newest delivery first.
+func (s *JobService) SearchLibrary(ctx context.Context, q LibraryQuery) ([]LibraryFile, error) {
+	if s.library == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.library.SearchLibrary(ctx, q)
+}
+
+// UpdateLibraryFiles stores the rescanned state of files.
+func (s *JobService) UpdateLibraryFiles(ctx context.Context, files []LibraryFile) error {
+	if s.library == nil {
+		return errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.library.UpdateLibraryFiles(ctx, files)
+}
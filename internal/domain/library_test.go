@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLibraryQuery_Match(t *testing.T) {
+	yes, no := true, false
+	files := []LibraryFile{
+		{ID: 1, JobID: 1, URL: "https://youtube.com/watch?v=a", Path: "/v/Cats [a].mp4", SHA256: "AAA"},
+		{ID: 2, JobID: 2, URL: "https://vimeo.com/2", Path: "/v/Dogs.mp4", Missing: true},
+		{ID: 3, JobID: 3, URL: "https://youtube.com/watch?v=c", Path: "/v/More cats [c].mp4"},
+	}
+	tests := []struct {
+		name  string
+		query LibraryQuery
+		want  []int64
+	}{
+		{"everything", LibraryQuery{}, []int64{1, 2, 3}},
+		{"text in path", LibraryQuery{Text: "CATS"}, []int64{1, 3}},
+		{"text in URL", LibraryQuery{Text: "vimeo"}, []int64{2}},
+		{"URL", LibraryQuery{URL: "https://youtube.com/watch?v=c"}, []int64{3}},
+		{"part of a URL", LibraryQuery{URL: "https://youtube.com/watch"}, nil},
+		{"digest", LibraryQuery{SHA256: "aaa"}, []int64{1}},
+		{"job", LibraryQuery{JobID: 3}, []int64{3}},
+		{"missing", LibraryQuery{Missing: &yes}, []int64{2}},
+		{"present", LibraryQuery{Missing: &no}, []int64{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			for i := range files {
+				if tt.query.Match(&files[i]) {
+					got = append(got, files[i].ID)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Match() selects %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobService_SearchLibrary_Unsupported(t *testing.T) {
+	if _, err := NewJobService(nil).SearchLibrary(context.Background(), LibraryQuery{}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("SearchLibrary() without an index error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// CompletedFilter selects completed jobs by when they completed.
+type CompletedFilter struct {
+	Since time.Time // completed at or after; zero for no bound
+	Until time.Time // completed before; zero for no bound
+	Host  string    // URLs of the host and its subdomains; empty matches all
+}
+
+// Match reports whether f selects a completed job.
+func (f CompletedFilter) Match(job *Job) bool {
+	if !f.Since.IsZero() && job.CompletedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !job.CompletedAt.Before(f.Until) {
+		return false
+	}
+	return BulkFilter{Host: f.Host}.Match(job.URL)
+}
+
+// SetCompletedLister enables Completed.
+func (s *JobService) SetCompletedLister(l CompletedLister) {
+	s.completedList = l
+}
+
+// Completed returns the completed jobs f selects, with the files they
+// produced, in the order they completed.
+func (s *JobService) Completed(ctx context.Context, f CompletedFilter) ([]Job, error) {
+	if s.completedList == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.completedList.ListCompleted(ctx, f)
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompletedFilter_Match(t *testing.T) {
+	now := time.Now()
+	job := &Job{URL: "https://www.youtube.com/watch?v=1", CompletedAt: now}
+	tests := []struct {
+		name string
+		f    CompletedFilter
+		want bool
+	}{
+		{"zero", CompletedFilter{}, true},
+		{"since before", CompletedFilter{Since: now.Add(-time.Hour)}, true},
+		{"since exactly", CompletedFilter{Since: now}, true},
+		{"since after", CompletedFilter{Since: now.Add(time.Second)}, false},
+		{"until after", CompletedFilter{Until: now.Add(time.Second)}, true},
+		{"until exactly", CompletedFilter{Until: now}, false},
+		{"host", CompletedFilter{Host: "youtube.com"}, true},
+		{"other host", CompletedFilter{Host: "vimeo.com"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Match(job); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
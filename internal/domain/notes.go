@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+)
+
+// MaxNotesLength is the most characters a job's notes may have.
+const MaxNotesLength = 1000
+
+type notesKey struct{}
+
+// WithNotes returns a context submitting jobs with notes, free text such as
+// why the job was queued.
+func WithNotes(ctx context.Context, notes string) context.Context {
+	return context.WithValue(ctx, notesKey{}, notes)
+}
+
+// NotesFrom returns the notes of the job being created, or "".
+// Repositories store them with the job.
+func NotesFrom(ctx context.Context) string {
+	n, _ := ctx.Value(notesKey{}).(string)
+	return n
+}
+
+func validNotes(notes string) bool {
+	return utf8.RuneCountInString(notes) <= MaxNotesLength
+}
+
+// TruncateNotes cuts notes to MaxNotesLength characters, for notes taken
+// from text catcher doesn't control, such as a payload's title.
+func TruncateNotes(notes string) string {
+	if validNotes(notes) {
+		return notes
+	}
+	return string([]rune(notes)[:MaxNotesLength])
+}
+
+// SetNoteEditor enables SetNotes.
+func (s *JobService) SetNoteEditor(e NoteEditor) {
+	s.notes = e
+}
+
+// SetNotes replaces a job's notes, in any state, and returns the updated
+// job. Empty notes clear them. It returns ErrNotesTooLong for notes over
+// MaxNotesLength characters.
+func (s *JobService) SetNotes(ctx context.Context, id int64, notes string) (*Job, error) {
+	if s.notes == nil {
+		return nil, errors.ErrUnsupported
+	}
+	if !validNotes(notes) {
+		return nil, ErrNotesTooLong
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	if err := s.notes.SetNotes(ctx, id, notes); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, id)
+}
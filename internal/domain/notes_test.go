@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJobService_Notes(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	ctx := context.Background()
+
+	job, err := svc.Submit(WithNotes(ctx, "for mum"), "https://example.com/video")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if job.Notes != "for mum" {
+		t.Errorf("Submit() notes = %q, want %q", job.Notes, "for mum")
+	}
+	long := strings.Repeat("é", MaxNotesLength+1)
+	if _, err := svc.Submit(WithNotes(ctx, long), "https://example.com/other"); err != ErrNotesTooLong {
+		t.Errorf("Submit(long notes) error = %v, want ErrNotesTooLong", err)
+	}
+
+	if _, err := svc.SetNotes(ctx, job.ID, "x"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("SetNotes() without editor error = %v, want ErrUnsupported", err)
+	}
+	svc.SetNoteEditor(repo)
+	if got, err := svc.SetNotes(ctx, job.ID, "conference talk"); err != nil || got.Notes != "conference talk" {
+		t.Errorf("SetNotes() = %+v, %v, want the new notes", got, err)
+	}
+	if _, err := svc.SetNotes(ctx, job.ID, long); err != ErrNotesTooLong {
+		t.Errorf("SetNotes(long) error = %v, want ErrNotesTooLong", err)
+	}
+	if _, err := svc.SetNotes(ctx, 999, ""); err != ErrJobNotFound {
+		t.Errorf("SetNotes(missing) error = %v, want ErrJobNotFound", err)
+	}
+	// Exactly the limit in characters is fine, however many bytes it takes
+	if _, err := svc.SetNotes(ctx, job.ID, long[len("é"):]); err != nil {
+		t.Errorf("SetNotes(at limit) error = %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// SetOutageRepository keeps the jobs that failed during a network outage
+// across restarts, and enables requeueing them after it.
+func (s *JobService) SetOutageRepository(r OutageRepository) {
+	s.outages = r
+}
+
+// ListOutageFailed adds jobs that failed during a network outage to those
+// requeued after it. It is a no-op without an outage repository.
+func (s *JobService) ListOutageFailed(ctx context.Context, ids []int64) error {
+	if s.outages == nil || len(ids) == 0 {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.outages.ListOutageFailed(ctx, ids)
+}
+
+// OutageFailed returns the listed jobs left to requeue after an outage,
+// oldest first, or none without an outage repository.
+func (s *JobService) OutageFailed(ctx context.Context) ([]int64, error) {
+	if s.outages == nil {
+		return nil, nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.outages.OutageFailed(ctx)
+}
+
+// RequeueOutageFailed requeues listed job id, unless it was already
+// requeued after max outages or is no longer failed, and takes it off the
+// list. It reports whether the job was requeued.
+func (s *JobService) RequeueOutageFailed(ctx context.Context, id int64, max int) (bool, error) {
+	if s.outages == nil {
+		return false, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.outages.RequeueOutageFailed(ctx, id, max)
+}
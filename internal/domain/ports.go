@@ -1,23 +1,269 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // JobRepository is the driven port for job persistence.
 type JobRepository interface {
+	// Create inserts a pending job with a fresh NewUID. It, and the other
+	// ports' create methods, store OriginalURLFrom(ctx), QueueFrom(ctx),
+	// SourceFrom(ctx), NotesFrom(ctx), BookmarkFrom(ctx),
+	// RedownloadOfFrom(ctx), and UserAgentFrom(ctx) with the job.
 	Create(ctx context.Context, url string) (*Job, error)
 	Get(ctx context.Context, id int64) (*Job, error)
+	// FindPending returns pending jobs that are due, taking each source's
+	// oldest in turn: the oldest job of every source, then the second
+	// oldest, and so on.
 	FindPending(ctx context.Context, limit int) ([]Job, error)
+	List(ctx context.Context, filter JobFilter) ([]Job, error)
 	Claim(ctx context.Context, id int64) error
-	Complete(ctx context.Context, id int64) error
+	Complete(ctx context.Context, id int64, c Completion) error
 	Fail(ctx context.Context, id int64, reason string) error
 	Retry(ctx context.Context, id int64, reason string) error
 	RecoverStale(ctx context.Context) (int64, error)
 }
 
+// StatsRepository is the driven port for persisted throughput history.
+type StatsRepository interface {
+	// Stats returns buckets starting at or after since, oldest first.
+	Stats(ctx context.Context, q StatsQuery) ([]StatsBucket, error)
+	// CompactStats folds hourly buckets older than before into daily buckets.
+	CompactStats(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AttemptRepository is the driven port for per-attempt job history.
+type AttemptRepository interface {
+	AddAttempt(ctx context.Context, jobID int64, a Attempt) error
+	// Attempts returns a job's attempts, oldest first.
+	Attempts(ctx context.Context, jobID int64) ([]Attempt, error)
+}
+
+// JobCounter is the driven port for queue size summaries.
+type JobCounter interface {
+	// CountByStatus returns the number of jobs in each status that has any.
+	CountByStatus(ctx context.Context) (map[JobStatus]int64, error)
+}
+
+// RetryScheduler is the driven port for delaying a job's next attempt.
+type RetryScheduler interface {
+	// RetryAt marks a job for retry no earlier than at.
+	RetryAt(ctx context.Context, id int64, reason string, at time.Time) error
+	// DelayPending keeps a pending job waiting until at, with reason as
+	// its error. It returns ErrJobNotFound, or ErrJobState for a job that
+	// is no longer pending, such as one cancelled meanwhile.
+	DelayPending(ctx context.Context, id int64, reason string, at time.Time) error
+}
+
+// Postponer is the driven port for sending a claimed job back to wait
+// without counting the claim as an attempt.
+type Postponer interface {
+	// Postpone makes a processing job pending, with reason as its error,
+	// no earlier than at, and takes its claim back off its attempts. It
+	// returns ErrJobNotFound, or ErrJobState for a job that isn't
+	// processing.
+	Postpone(ctx context.Context, id int64, reason string, at time.Time) error
+}
+
+// DuplicateFinder is the driven port for spotting repeated submissions.
+type DuplicateFinder interface {
+	// FindRecent returns the newest job for url created at or after since
+	// that hasn't failed, or nil if there is none.
+	FindRecent(ctx context.Context, url string, since time.Time) (*Job, error)
+}
+
+// CompletedFinder is the driven port for finding earlier downloads of a URL.
+type CompletedFinder interface {
+	// LastCompleted returns the newest completed job for url with an ID
+	// below beforeID, with its files, or nil. A beforeID of 0 matches any.
+	LastCompleted(ctx context.Context, url string, beforeID int64) (*Job, error)
+}
+
+// ManualCompleter is the driven port for closing jobs handled by hand.
+type ManualCompleter interface {
+	// CompleteManually marks a pending, failed, or unapproved job completed,
+	// with no results. It returns ErrJobNotFound, or ErrJobState for a job in
+	// any other state.
+	CompleteManually(ctx context.Context, id int64) error
+}
+
+// JobHolder is the driven port for holding jobs back from the worker.
+type JobHolder interface {
+	// CreateHeld inserts a new job that is already held.
+	CreateHeld(ctx context.Context, url string) (*Job, error)
+	// SetHeld holds or releases a pending job. It returns ErrJobNotFound,
+	// or ErrJobState for a job that isn't pending.
+	SetHeld(ctx context.Context, id int64, held bool) error
+}
+
+// ApprovalRepository is the driven port for the approval workflow. Approve
+// and Reject return ErrJobNotFound, or ErrJobState for a job that isn't
+// awaiting approval.
+type ApprovalRepository interface {
+	// CreateForApproval inserts a new job awaiting approval.
+	CreateForApproval(ctx context.Context, url string) (*Job, error)
+	// RequestApproval makes a processing job await approval, with reason
+	// as its error, without counting the claim as an attempt. It returns
+	// ErrJobNotFound, or ErrJobState for a job that isn't processing.
+	RequestApproval(ctx context.Context, id int64, reason string) error
+	// Approve makes a job awaiting approval pending and marks it approved.
+	Approve(ctx context.Context, id int64) error
+	// Reject fails a job awaiting approval with reason.
+	Reject(ctx context.Context, id int64, reason string) error
+}
+
+// BulkRepository is the driven port for moving many jobs between states
+// at once, each transition in a single statement rather than one per job.
+type BulkRepository interface {
+	// RequeueFailed makes failed jobs matching f pending, with attempts,
+	// error, and retry delay cleared, and returns how many.
+	RequeueFailed(ctx context.Context, f BulkFilter) (int64, error)
+	// CancelPending fails pending jobs matching f with reason, and returns
+	// how many. Like rejections, cancellations don't count as failures in
+	// stats.
+	CancelPending(ctx context.Context, f BulkFilter, reason string) (int64, error)
+}
+
+// UIDResolver is the driven port for looking up jobs by UID.
+type UIDResolver interface {
+	// ResolveUID returns the ID of the job with uid, or ErrJobNotFound.
+	ResolveUID(ctx context.Context, uid string) (int64, error)
+}
+
+// NoteEditor is the driven port for changing a job's notes.
+type NoteEditor interface {
+	// SetNotes replaces a job's notes. It returns ErrJobNotFound.
+	SetNotes(ctx context.Context, id int64, notes string) error
+}
+
+// ViewRepository is the driven port for saved job filters.
+type ViewRepository interface {
+	// SaveView stores v, replacing any view with the same name.
+	SaveView(ctx context.Context, v View) error
+	// View returns the view named name, or ErrViewNotFound.
+	View(ctx context.Context, name string) (*View, error)
+	// Views returns every view, by name.
+	Views(ctx context.Context) ([]View, error)
+	// DeleteView removes the view named name, or returns ErrViewNotFound.
+	DeleteView(ctx context.Context, name string) error
+}
+
+// CompletedLister is the driven port for listing finished downloads.
+type CompletedLister interface {
+	// ListCompleted returns the completed jobs f selects by completed_at,
+	// with their files, oldest first by completed_at.
+	ListCompleted(ctx context.Context, f CompletedFilter) ([]Job, error)
+}
+
+// CooldownRepository is the driven port for per-host cooldowns.
+type CooldownRepository interface {
+	// SetCooldown stores c, replacing any cooldown of the same host, and
+	// drops expired ones.
+	SetCooldown(ctx context.Context, c Cooldown) error
+	// Cooldowns returns the cooldowns that haven't expired.
+	Cooldowns(ctx context.Context) ([]Cooldown, error)
+}
+
+// OutageRepository is the driven port for the jobs that failed during a
+// network outage, kept until they are requeued after it.
+type OutageRepository interface {
+	// ListOutageFailed adds the jobs ids to the list.
+	ListOutageFailed(ctx context.Context, ids []int64) error
+	// OutageFailed returns the listed jobs, oldest first.
+	OutageFailed(ctx context.Context) ([]int64, error)
+	// RequeueOutageFailed takes job id off the list and requeues it with
+	// its attempts reset, if it is still failed and was requeued after
+	// fewer than max outages before. It reports whether it did.
+	RequeueOutageFailed(ctx context.Context, id int64, max int) (bool, error)
+}
+
+// ProbeCache is the driven port for remembering what probing a URL found,
+// so it isn't probed again while the result is fresh.
+type ProbeCache interface {
+	// CachedProbe returns the result stored for key, if it was stored no
+	// longer than maxAge ago.
+	CachedProbe(ctx context.Context, key string, maxAge time.Duration) ([]byte, bool, error)
+	// CacheProbe stores data as the result for key, replacing any other.
+	CacheProbe(ctx context.Context, key string, data []byte) error
+	// PruneProbes removes results stored before before and returns how
+	// many there were.
+	PruneProbes(ctx context.Context, before time.Time) (int64, error)
+}
+
+// FileCheckRepository is the driven port for the latest file check.
+type FileCheckRepository interface {
+	// SaveFileCheck replaces the stored check with c.
+	SaveFileCheck(ctx context.Context, c *FileCheck) error
+	// LastFileCheck returns the stored check, or ErrNoFileCheck.
+	LastFileCheck(ctx context.Context) (*FileCheck, error)
+}
+
+// AnnouncementRepository is the driven port for the announcement shown
+// to API clients.
+type AnnouncementRepository interface {
+	// SaveAnnouncement replaces the stored announcement with a.
+	SaveAnnouncement(ctx context.Context, a *Announcement) error
+	// Announcement returns the stored announcement, or ErrNoAnnouncement.
+	Announcement(ctx context.Context) (*Announcement, error)
+	// ClearAnnouncement removes the stored announcement, if any.
+	ClearAnnouncement(ctx context.Context) error
+}
+
+// LibraryIndex is the driven port for the index of delivered files. Files
+// are added to it as jobs complete.
+type LibraryIndex interface {
+	// LibraryFiles returns every indexed file, oldest delivery first.
+	LibraryFiles(ctx context.Context) ([]LibraryFile, error)
+	// SearchLibrary returns the indexed files q selects, newest delivery
+	// first, up to q.Limit.
+	SearchLibrary(ctx context.Context, q LibraryQuery) ([]LibraryFile, error)
+	// UpdateLibraryFiles stores the size, digest, missing flag, and check
+	// time of files, matched by ID.
+	UpdateLibraryFiles(ctx context.Context, files []LibraryFile) error
+}
+
+// RedownloadFinder is the driven port for spotting repeated re-downloads.
+type RedownloadFinder interface {
+	// ActiveRedownload returns the newest pending or processing job
+	// created as a re-download of the job with ID id, or nil.
+	ActiveRedownload(ctx context.Context, id int64) (*Job, error)
+}
+
+// FailureLister is the driven port for the failure report.
+type FailureLister interface {
+	// ListFailures returns the failed attempts, and the jobs that failed
+	// for good, since since, including archived jobs.
+	ListFailures(ctx context.Context, since time.Time) ([]Failure, error)
+}
+
+// JobPruner is the driven port for retiring finished jobs.
+type JobPruner interface {
+	// PruneJobs removes completed and failed jobs last updated before
+	// before, and returns how many. With archive, they are moved somewhere
+	// job queries don't look, keeping their history, instead of being
+	// deleted. Stats are kept separately and don't change.
+	PruneJobs(ctx context.Context, before time.Time, archive bool) (int64, error)
+}
+
+// QueueRepository is the driven port for per-processor queues.
+type QueueRepository interface {
+	// FindPendingIn is FindPending limited to jobs in queue. For
+	// DefaultQueue it also returns jobs in any queue not listed in active,
+	// such as those of a processor that no longer has its own.
+	FindPendingIn(ctx context.Context, queue string, active []string, limit int) ([]Job, error)
+	// PendingByQueue returns the number of pending jobs in each queue that
+	// has any, held jobs included.
+	PendingByQueue(ctx context.Context) (map[string]int64, error)
+}
+
 // URLProcessor is the driven port for URL processing.
 type URLProcessor interface {
 	Name() string
 	TargetDir() string
 	Match(url string) bool
-	Process(ctx context.Context, job *Job) error
+	// Process handles the job and reports what it produced. On failure it
+	// may still return a result carrying a RetryAfter hint.
+	// Details of the run can be recorded on AttemptFrom(ctx).
+	Process(ctx context.Context, job *Job) (*ProcessResult, error)
 }
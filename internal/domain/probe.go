@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NormalizeURL returns the form of rawURL that probe results are cached
+// by: scheme and host lowercased, default ports and the fragment dropped,
+// and query parameters sorted. URLs that don't parse are returned as is.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Fragment, u.RawFragment = "", ""
+	if u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
+
+// SetProbeCache enables PruneProbeCache, which removes probe results
+// cached longer than ttl ago.
+func (s *JobService) SetProbeCache(c ProbeCache, ttl time.Duration) {
+	s.probes, s.probeTTL = c, ttl
+}
+
+// PruneProbeCache removes expired probe results and returns how many there
+// were.
+func (s *JobService) PruneProbeCache(ctx context.Context) (int64, error) {
+	if s.probes == nil || s.probeTTL <= 0 {
+		return 0, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.probes.PruneProbes(ctx, s.clock.Now().Add(-s.probeTTL))
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"HTTPS://WWW.YouTube.com:443/watch?v=abc&t=10#top", "https://www.youtube.com/watch?t=10&v=abc"},
+		{"https://youtube.com/watch?t=10&v=abc", "https://youtube.com/watch?t=10&v=abc"},
+		{"http://example.com:80", "http://example.com/"},
+		{"http://example.com:8080/a", "http://example.com:8080/a"},
+		{"https://example.com/Case/Path", "https://example.com/Case/Path"},
+		{"not a url", "not a url"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.url); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+// fakeProbeCache records the last PruneProbes call.
+type fakeProbeCache struct {
+	before time.Time
+}
+
+func (c *fakeProbeCache) CachedProbe(ctx context.Context, key string, maxAge time.Duration) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (c *fakeProbeCache) CacheProbe(ctx context.Context, key string, data []byte) error {
+	return nil
+}
+
+func (c *fakeProbeCache) PruneProbes(ctx context.Context, before time.Time) (int64, error) {
+	c.before = before
+	return 2, nil
+}
+
+func TestJobService_PruneProbeCache(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	svc := NewJobService(newMockRepo())
+	svc.SetClock(clock)
+	if _, err := svc.PruneProbeCache(ctx); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("PruneProbeCache() without a cache error = %v, want ErrUnsupported", err)
+	}
+
+	c := &fakeProbeCache{}
+	svc.SetProbeCache(c, time.Hour)
+	n, err := svc.PruneProbeCache(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("PruneProbeCache() = %d, %v, want 2", n, err)
+	}
+	if want := clock.Now().Add(-time.Hour); !c.before.Equal(want) {
+		t.Errorf("pruned before %s, want %s", c.before, want)
+	}
+}
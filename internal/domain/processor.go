@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ProcessResult describes a processor run.
+type ProcessResult struct {
+	Files    []ResultFile
+	Title    string // human-readable name of what was fetched, if known
+	Bytes    int64
+	Warnings []string // problems that didn't fail the run, logged by the worker
+	// RetryAfter asks for the next attempt to wait at least this long, e.g.
+	// when a site rate-limited the run. Only used when Process fails.
+	RetryAfter time.Duration
+	// AlreadyDone marks a run that fetched nothing because the media is
+	// already downloaded: the tool exited with a success exit code, such
+	// as yt-dlp finding the URL in its archive, or every file it made
+	// already existed. Any other run without files has nothing to show.
+	AlreadyDone bool
+}
+
+// ErrBadMedia is wrapped by a MediaVerifier's error when the file itself
+// is broken, as opposed to the check failing to run.
+var ErrBadMedia = errors.New("media check failed")
+
+// LibraryChecker is implemented by processors that can tell a URL's media
+// is already somewhere in the user's library, so it needn't be downloaded.
+type LibraryChecker interface {
+	// InLibrary returns the library files holding the URL's media, or none
+	// if it isn't there or can't be told.
+	InLibrary(ctx context.Context, url string) ([]ResultFile, error)
+}
+
+// MediaVerifier checks that a file a job produced is intact, e.g. that a
+// video plays to its end, before the job is marked complete.
+type MediaVerifier interface {
+	// Verify returns an error describing what is wrong with the file at
+	// path, or nil if it is fine or not a kind the verifier checks.
+	Verify(ctx context.Context, path string) error
+}
+
+// MatchScorer is implemented by processors that rate how well they match a
+// URL, so that when several match, the most specific one wins. A score of
+// zero or less means no match. Processors without it score 1 on a match.
+type MatchScorer interface {
+	MatchScore(url string) int
+}
+
+// Prioritizer is implemented by processors with a configured priority,
+// which breaks ties between equal match scores; higher wins.
+type Prioritizer interface {
+	Priority() int
+}
+
+// ResubmitPolicy says what to do with a URL whose earlier job completed.
+type ResubmitPolicy string
+
+const (
+	// ResubmitAllow processes the URL again, keeping the earlier files.
+	ResubmitAllow ResubmitPolicy = "allow"
+	// ResubmitReject refuses the submission as a duplicate.
+	ResubmitReject ResubmitPolicy = "reject"
+	// ResubmitReplace processes the URL again and, once that succeeds,
+	// removes the earlier job's files that the new run didn't overwrite.
+	ResubmitReplace ResubmitPolicy = "replace"
+)
+
+// ParseResubmitPolicy parses a configured policy; empty means allow.
+func ParseResubmitPolicy(s string) (ResubmitPolicy, error) {
+	switch p := ResubmitPolicy(s); p {
+	case "":
+		return ResubmitAllow, nil
+	case ResubmitAllow, ResubmitReject, ResubmitReplace:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown resubmit policy %q (want allow, reject, or replace)", s)
+}
+
+// ResubmitPolicer is implemented by processors with a resubmit policy.
+// Processors without one allow resubmissions.
+type ResubmitPolicer interface {
+	ResubmitPolicy() ResubmitPolicy
+}
+
+// OversizePolicy says what to do with a job whose estimated size exceeds
+// its processor's limit.
+type OversizePolicy string
+
+const (
+	// OversizeHold sends the job back for approval. Once approved, it runs
+	// regardless of size.
+	OversizeHold OversizePolicy = "hold"
+	// OversizeFail fails the job, with a TooLargeError's message.
+	OversizeFail OversizePolicy = "fail"
+)
+
+// ParseOversizePolicy parses a configured policy; empty means hold.
+func ParseOversizePolicy(s string) (OversizePolicy, error) {
+	switch p := OversizePolicy(s); p {
+	case "":
+		return OversizeHold, nil
+	case OversizeHold, OversizeFail:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown oversize policy %q (want hold or fail)", s)
+}
+
+// SizeProber is implemented by processors that can estimate how much a URL
+// will download before processing it.
+type SizeProber interface {
+	// SizeLimit returns the estimated size above which policy applies; 0
+	// means no limit.
+	SizeLimit() (limit int64, policy OversizePolicy)
+	// ProbeSize returns the estimated size in bytes, or 0 if unknown.
+	ProbeSize(ctx context.Context, url string) (int64, error)
+}
+
+// TooLargeError reports a job whose estimated size exceeds its limit.
+type TooLargeError struct {
+	Size  int64
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("too large: estimated %s exceeds the limit of %s", FormatBytes(e.Size), FormatBytes(e.Limit))
+}
+
+// QuotaError reports a run stopped because its work dir outgrew the
+// processor's quota. Jobs failing with it are not retried, as another
+// attempt would most likely run away again.
+type QuotaError struct {
+	Used  int64
+	Limit int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("disk quota exceeded: work dir holds %s, over the limit of %s", FormatBytes(e.Used), FormatBytes(e.Limit))
+}
+
+// FormatBytes renders n bytes with a binary unit, e.g. "1.5 GiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 5; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+type workDirKey struct{}
+
+// WithWorkDir returns a context carrying the job's working directory.
+func WithWorkDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workDirKey{}, dir)
+}
+
+// WorkDirFrom returns the working directory catcher manages for the job
+// being processed, or "" if there is none. The directory exists, is kept
+// across retries of the same job so partial downloads can resume, and is
+// removed once the job completes or fails for good; processors should not
+// delete it themselves.
+func WorkDirFrom(ctx context.Context) string {
+	dir, _ := ctx.Value(workDirKey{}).(string)
+	return dir
+}
+
+// LegacyProcessor is the URLProcessor contract from before Process returned
+// a ProcessResult. Such processors report output by setting job.Bytes and
+// job.Files; wrap them with AdaptLegacy.
+type LegacyProcessor interface {
+	Name() string
+	TargetDir() string
+	Match(url string) bool
+	Process(ctx context.Context, job *Job) error
+}
+
+// AdaptLegacy makes a LegacyProcessor usable as a URLProcessor.
+func AdaptLegacy(p LegacyProcessor) URLProcessor {
+	return legacyProcessor{p}
+}
+
+type legacyProcessor struct {
+	p LegacyProcessor
+}
+
+func (l legacyProcessor) Name() string          { return l.p.Name() }
+func (l legacyProcessor) TargetDir() string     { return l.p.TargetDir() }
+func (l legacyProcessor) Match(url string) bool { return l.p.Match(url) }
+
+func (l legacyProcessor) Process(ctx context.Context, job *Job) (*ProcessResult, error) {
+	// Don't download what was only to be bookmarked
+	if job.Bookmark {
+		return nil, ErrBookmarkUnsupported
+	}
+	if err := l.p.Process(ctx, job); err != nil {
+		return nil, err
+	}
+	return &ProcessResult{Files: job.Files, Bytes: job.Bytes}, nil
+}
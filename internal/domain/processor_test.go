@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// legacyProc reports its output on the job, as processors did before
+// ProcessResult.
+type legacyProc struct {
+	err error
+}
+
+func (p *legacyProc) Name() string          { return "legacy" }
+func (p *legacyProc) TargetDir() string     { return "/videos" }
+func (p *legacyProc) Match(url string) bool { return true }
+func (p *legacyProc) Process(ctx context.Context, job *Job) error {
+	if p.err != nil {
+		return p.err
+	}
+	job.Bytes = 42
+	job.Files = []ResultFile{{Path: "/videos/a.mp4", Bytes: 42}}
+	return nil
+}
+
+func TestAdaptLegacy(t *testing.T) {
+	p := AdaptLegacy(&legacyProc{})
+	if p.Name() != "legacy" || p.TargetDir() != "/videos" || !p.Match("https://example.com") {
+		t.Errorf("adapter doesn't pass through Name, TargetDir, or Match")
+	}
+
+	res, err := p.Process(context.Background(), &Job{ID: 1})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if res.Bytes != 42 || len(res.Files) != 1 || res.Files[0].Path != "/videos/a.mp4" {
+		t.Errorf("Process() = %+v, want the job's bytes and files", res)
+	}
+}
+
+func TestAdaptLegacy_Error(t *testing.T) {
+	want := errors.New("boom")
+	res, err := AdaptLegacy(&legacyProc{err: want}).Process(context.Background(), &Job{ID: 1})
+	if err != want || res != nil {
+		t.Errorf("Process() = %+v, %v; want nil, %v", res, err, want)
+	}
+}
+
+func TestAdaptLegacy_Bookmark(t *testing.T) {
+	job := &Job{ID: 1, Bookmark: true}
+	res, err := AdaptLegacy(&legacyProc{}).Process(context.Background(), job)
+	if err != ErrBookmarkUnsupported || res != nil || job.Files != nil {
+		t.Errorf("Process() = %+v, %v; want ErrBookmarkUnsupported without running", res, err)
+	}
+}
+
+func TestWorkDirFrom(t *testing.T) {
+	if got := WorkDirFrom(context.Background()); got != "" {
+		t.Errorf("WorkDirFrom() = %q, want empty without a work dir", got)
+	}
+	ctx := WithWorkDir(context.Background(), "/work/job-7")
+	if got := WorkDirFrom(ctx); got != "/work/job-7" {
+		t.Errorf("WorkDirFrom() = %q, want /work/job-7", got)
+	}
+}
+
+func TestTooLargeError(t *testing.T) {
+	err := &TooLargeError{Size: 3 << 30, Limit: 2 << 30}
+	if got, want := err.Error(), "too large: estimated 3.0 GiB exceeds the limit of 2.0 GiB"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestQuotaError(t *testing.T) {
+	err := &QuotaError{Used: 21 << 30, Limit: 20 << 30}
+	if got, want := err.Error(), "disk quota exceeded: work dir holds 21.0 GiB, over the limit of 20.0 GiB"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got := ClassifyFailure(err.Error()); got != CauseDisk {
+		t.Errorf("ClassifyFailure() = %q, want %q", got, CauseDisk)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{1 << 40, "1.0 TiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
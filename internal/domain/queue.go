@@ -0,0 +1,36 @@
+package domain
+
+import "context"
+
+// DefaultQueue holds jobs for processors without a queue of their own.
+const DefaultQueue = ""
+
+// Queuer is implemented by processors that run their jobs in a queue of
+// their own, named after the processor, so a backlog for one processor
+// can't hold up another's jobs. Zero or less shares DefaultQueue.
+type Queuer interface {
+	// Concurrency is how many of the queue's jobs run at once.
+	Concurrency() int
+}
+
+// Coster is implemented by processors whose jobs weigh more than others
+// on the machine, such as transcodes against metadata fetches. A worker
+// with a budget runs jobs only while their total cost fits in it. Jobs of
+// other processors cost 1.
+type Coster interface {
+	Cost() int
+}
+
+type queueKey struct{}
+
+// WithQueue returns a context carrying the queue for the job being created.
+func WithQueue(ctx context.Context, queue string) context.Context {
+	return context.WithValue(ctx, queueKey{}, queue)
+}
+
+// QueueFrom returns the queue for the job being created, or DefaultQueue.
+// Repositories store it with the job.
+func QueueFrom(ctx context.Context) string {
+	q, _ := ctx.Value(queueKey{}).(string)
+	return q
+}
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+type redownloadKey struct{}
+
+// WithRedownloadOf returns a context creating jobs that download the job
+// with ID id again.
+func WithRedownloadOf(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, redownloadKey{}, id)
+}
+
+// RedownloadOfFrom returns the ID of the job the job being created
+// downloads again, or zero. Repositories store it with the job.
+func RedownloadOfFrom(ctx context.Context) int64 {
+	id, _ := ctx.Value(redownloadKey{}).(int64)
+	return id
+}
+
+// SetRedownloadFinder keeps Redownload from cloning a job that is already
+// being downloaded again.
+func (s *JobService) SetRedownloadFinder(f RedownloadFinder) {
+	s.redownloads = f
+}
+
+// Redownload clones a completed job into a new pending job linked to it
+// by RedownloadOf, e.g. to restore files deleted since. The clone keeps
+// the job's URL, source, notes, user agent, and bookmark flag, and skips
+// validation, approval, and duplicate checks, which the job already
+// passed. It returns ErrJobState for jobs that aren't completed, and
+// *DuplicateError if a clone is still pending or processing.
+func (s *JobService) Redownload(ctx context.Context, id int64) (*Job, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	job, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusCompleted {
+		return nil, ErrJobState
+	}
+
+	ctx = WithRedownloadOf(ctx, job.ID)
+	ctx = WithSource(ctx, job.Source)
+	ctx = WithNotes(ctx, job.Notes)
+	if job.OriginalURL != "" {
+		ctx = WithOriginalURL(ctx, job.OriginalURL)
+	}
+	if job.Bookmark {
+		ctx = WithBookmark(ctx)
+	}
+	if job.UserAgent != "" {
+		ctx = WithUserAgent(ctx, job.UserAgent)
+	}
+	queue := job.Queue
+	if s.assignQueue != nil {
+		queue = s.assignQueue(job.URL)
+	}
+	ctx = WithQueue(ctx, queue)
+
+	if s.redownloads == nil {
+		return s.repo.Create(ctx, job.URL)
+	}
+	s.submitMu.Lock()
+	defer s.submitMu.Unlock()
+	prev, err := s.redownloads.ActiveRedownload(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		return nil, &DuplicateError{Job: prev}
+	}
+	return s.repo.Create(ctx, job.URL)
+}
+
+// RedownloadMissing runs Redownload for every job the last file check
+// found files missing from, and returns the new jobs. Jobs no longer
+// completed or kept are skipped, as are, with SetRedownloadFinder, jobs
+// already being downloaded again, so running it again while earlier
+// clones are queued doesn't clone jobs twice.
+func (s *JobService) RedownloadMissing(ctx context.Context) ([]*Job, error) {
+	check, err := s.LastFileCheck(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int64]bool)
+	var jobs []*Job
+	for _, issue := range check.Issues {
+		if issue.Problem != FileMissing || seen[issue.JobID] {
+			continue
+		}
+		seen[issue.JobID] = true
+		job, err := s.Redownload(ctx, issue.JobID)
+		var de *DuplicateError
+		switch {
+		case errors.As(err, &de), errors.Is(err, ErrJobState), errors.Is(err, ErrJobNotFound):
+			continue
+		case err != nil:
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func (m *mockRepo) ActiveRedownload(ctx context.Context, id int64) (*Job, error) {
+	for i := m.nextID - 1; i > 0; i-- {
+		job, ok := m.jobs[i]
+		if ok && job.RedownloadOf == id && (job.Status == StatusPending || job.Status == StatusProcessing) {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+type fileCheckStub struct{ check *FileCheck }
+
+func (f *fileCheckStub) SaveFileCheck(ctx context.Context, c *FileCheck) error {
+	f.check = c
+	return nil
+}
+
+func (f *fileCheckStub) LastFileCheck(ctx context.Context) (*FileCheck, error) {
+	if f.check == nil {
+		return nil, ErrNoFileCheck
+	}
+	return f.check, nil
+}
+
+// completedJob creates a job and completes it.
+func completedJob(t *testing.T, ctx context.Context, repo *mockRepo, url string) *Job {
+	t.Helper()
+	job, err := repo.Create(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Complete(ctx, job.ID, Completion{})
+	return job
+}
+
+func TestJobService_Redownload(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.SetRedownloadFinder(repo)
+
+	orig := completedJob(t, WithUserAgent(WithBookmark(WithNotes(WithSource(WithQueue(ctx, "video"), "phone"), "for mum")), "Agent/1.0"), repo, "https://example.com/v")
+	pending, _ := repo.Create(ctx, "https://example.com/p")
+
+	if _, err := svc.Redownload(ctx, pending.ID); !errors.Is(err, ErrJobState) {
+		t.Errorf("Redownload() of a pending job error = %v, want ErrJobState", err)
+	}
+	if _, err := svc.Redownload(ctx, 99); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Redownload() of a missing job error = %v, want ErrJobNotFound", err)
+	}
+
+	clone, err := svc.Redownload(ctx, orig.ID)
+	if err != nil {
+		t.Fatalf("Redownload() error = %v", err)
+	}
+	if clone.ID == orig.ID || clone.Status != StatusPending || clone.RedownloadOf != orig.ID {
+		t.Errorf("Redownload() = job %d, %s, of %d, want a new pending job of %d", clone.ID, clone.Status, clone.RedownloadOf, orig.ID)
+	}
+	if clone.URL != orig.URL || clone.Queue != "video" || clone.Source != "phone" || clone.Notes != "for mum" || !clone.Bookmark || clone.UserAgent != "Agent/1.0" {
+		t.Errorf("Redownload() = %+v, want the original's URL, queue, source, notes, bookmark flag, and user agent", clone)
+	}
+
+	var de *DuplicateError
+	if _, err := svc.Redownload(ctx, orig.ID); !errors.As(err, &de) || de.Job.ID != clone.ID {
+		t.Errorf("Redownload() while a clone is pending error = %v, want *DuplicateError for job %d", err, clone.ID)
+	}
+	repo.Claim(ctx, clone.ID)
+	repo.Complete(ctx, clone.ID, Completion{})
+	if _, err := svc.Redownload(ctx, orig.ID); err != nil {
+		t.Errorf("Redownload() after the clone completed error = %v", err)
+	}
+}
+
+func TestJobService_RedownloadMissing(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.SetRedownloadFinder(repo)
+
+	if _, err := svc.RedownloadMissing(ctx); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("RedownloadMissing() without file checks error = %v, want ErrUnsupported", err)
+	}
+	checks := &fileCheckStub{}
+	svc.SetFileCheckRepository(checks)
+	if _, err := svc.RedownloadMissing(ctx); !errors.Is(err, ErrNoFileCheck) {
+		t.Errorf("RedownloadMissing() before any check error = %v, want ErrNoFileCheck", err)
+	}
+
+	a := completedJob(t, ctx, repo, "https://example.com/a")
+	b := completedJob(t, ctx, repo, "https://example.com/b")
+	c := completedJob(t, ctx, repo, "https://example.com/c")
+	checks.check = &FileCheck{Issues: []FileIssue{
+		{JobID: a.ID, Path: "/v/a.mp4", Problem: FileMissing},
+		{JobID: a.ID, Path: "/v/a.srt", Problem: FileMissing},
+		{JobID: b.ID, Path: "/v/b.mp4", Problem: FileSizeMismatch},
+		{JobID: c.ID, Path: "/v/c.mp4", Problem: FileMissing},
+		{JobID: 99, Path: "/v/pruned.mp4", Problem: FileMissing},
+	}}
+
+	jobs, err := svc.RedownloadMissing(ctx)
+	if err != nil {
+		t.Fatalf("RedownloadMissing() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].RedownloadOf != a.ID || jobs[1].RedownloadOf != c.ID {
+		t.Fatalf("RedownloadMissing() = %v, want clones of jobs %d and %d", jobs, a.ID, c.ID)
+	}
+
+	if jobs, err := svc.RedownloadMissing(ctx); err != nil || len(jobs) != 0 {
+		t.Errorf("RedownloadMissing() again = %v, %v, want no new jobs", jobs, err)
+	}
+}
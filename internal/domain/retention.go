@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SetRetention enables Prune, which retires jobs finished longer than
+// retention ago. With archive they are archived rather than deleted.
+func (s *JobService) SetRetention(p JobPruner, retention time.Duration, archive bool) {
+	s.pruner, s.retention, s.archive = p, retention, archive
+}
+
+// Prune retires jobs finished longer than the retention window ago and
+// returns how many there were.
+func (s *JobService) Prune(ctx context.Context) (int64, error) {
+	if s.pruner == nil || s.retention <= 0 {
+		return 0, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.pruner.PruneJobs(ctx, s.clock.Now().Add(-s.retention), s.archive)
+}
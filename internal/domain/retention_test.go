@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakePruner records the last PruneJobs call.
+type fakePruner struct {
+	before  time.Time
+	archive bool
+}
+
+func (p *fakePruner) PruneJobs(ctx context.Context, before time.Time, archive bool) (int64, error) {
+	p.before, p.archive = before, archive
+	return 3, nil
+}
+
+func TestJobService_Prune(t *testing.T) {
+	ctx := context.Background()
+	svc := NewJobService(newMockRepo())
+	if _, err := svc.Prune(ctx); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Prune() without a pruner error = %v, want ErrUnsupported", err)
+	}
+
+	p := &fakePruner{}
+	svc.SetRetention(p, 0, true)
+	if _, err := svc.Prune(ctx); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Prune() without retention error = %v, want ErrUnsupported", err)
+	}
+
+	svc.SetRetention(p, 30*24*time.Hour, true)
+	n, err := svc.Prune(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("Prune() = %d, %v, want 3", n, err)
+	}
+	want := time.Now().Add(-30 * 24 * time.Hour)
+	if d := want.Sub(p.before); d < 0 || d > time.Minute || !p.archive {
+		t.Errorf("PruneJobs(%v, %v), want %v and archive", p.before, p.archive, want)
+	}
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// URLRewriter rewrites a submitted URL before it is validated and matched
+// to a processor, such as unwrapping a redirector. It returns rawURL
+// unchanged if it doesn't apply.
+type URLRewriter func(rawURL string) string
+
+// RegexRewrite replaces matches of re with repl, which may refer to groups
+// as in regexp.Regexp.ReplaceAllString.
+func RegexRewrite(re *regexp.Regexp, repl string) URLRewriter {
+	return func(rawURL string) string {
+		return re.ReplaceAllString(rawURL, repl)
+	}
+}
+
+// UnwrapParam replaces a URL matching re with the absolute URL in its
+// query parameter param, e.g. the target of l.facebook.com/l.php?u=.
+func UnwrapParam(re *regexp.Regexp, param string) URLRewriter {
+	return func(rawURL string) string {
+		if !re.MatchString(rawURL) {
+			return rawURL
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return rawURL
+		}
+		target, err := url.Parse(u.Query().Get(param))
+		if err != nil || !target.IsAbs() {
+			return rawURL
+		}
+		return target.String()
+	}
+}
+
+// StripParams removes query parameters whose name matches one of patterns,
+// as in path.Match (e.g. "utm_*"), from URLs matching re, or from all URLs
+// if re is nil. The remaining parameters keep their order.
+func StripParams(re *regexp.Regexp, patterns ...string) URLRewriter {
+	strip := func(name string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return func(rawURL string) string {
+		if re != nil && !re.MatchString(rawURL) {
+			return rawURL
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || u.RawQuery == "" {
+			return rawURL
+		}
+		pairs := strings.Split(u.RawQuery, "&")
+		kept := make([]string, 0, len(pairs))
+		for _, pair := range pairs {
+			name, _, _ := strings.Cut(pair, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			if !strip(name) {
+				kept = append(kept, pair)
+			}
+		}
+		if len(kept) == len(pairs) {
+			return rawURL
+		}
+		u.RawQuery = strings.Join(kept, "&")
+		u.ForceQuery = false
+		return u.String()
+	}
+}
+
+// URLResolver returns the URL that rawURL finally redirects to, or rawURL
+// if it doesn't redirect.
+type URLResolver func(ctx context.Context, rawURL string) (string, error)
+
+type originalURLKey struct{}
+
+// WithOriginalURL returns a context carrying the URL as submitted, for a
+// job whose URL was rewritten.
+func WithOriginalURL(ctx context.Context, rawURL string) context.Context {
+	return context.WithValue(ctx, originalURLKey{}, rawURL)
+}
+
+// OriginalURLFrom returns the URL as submitted for the job being created,
+// or "" if it wasn't rewritten. Repositories store it with the job.
+func OriginalURLFrom(ctx context.Context) string {
+	u, _ := ctx.Value(originalURLKey{}).(string)
+	return u
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestURLRewriters(t *testing.T) {
+	tests := []struct {
+		name    string
+		rewrite URLRewriter
+		in      string
+		want    string
+	}{
+		{
+			name:    "regex",
+			rewrite: RegexRewrite(regexp.MustCompile(`^https://m\.youtube\.com/`), "https://www.youtube.com/"),
+			in:      "https://m.youtube.com/watch?v=abc",
+			want:    "https://www.youtube.com/watch?v=abc",
+		},
+		{
+			name:    "regex without match",
+			rewrite: RegexRewrite(regexp.MustCompile(`^https://m\.youtube\.com/`), "https://www.youtube.com/"),
+			in:      "https://vimeo.com/1",
+			want:    "https://vimeo.com/1",
+		},
+		{
+			name:    "unwrap",
+			rewrite: UnwrapParam(regexp.MustCompile(`^https://l\.facebook\.com/l\.php`), "u"),
+			in:      "https://l.facebook.com/l.php?u=https%3A%2F%2Fvimeo.com%2F1%3Fa%3Db&h=xyz",
+			want:    "https://vimeo.com/1?a=b",
+		},
+		{
+			name:    "unwrap relative target",
+			rewrite: UnwrapParam(regexp.MustCompile(`^https://l\.facebook\.com/l\.php`), "u"),
+			in:      "https://l.facebook.com/l.php?u=%2Fhome",
+			want:    "https://l.facebook.com/l.php?u=%2Fhome",
+		},
+		{
+			name:    "strip params",
+			rewrite: StripParams(nil, "utm_*", "fbclid"),
+			in:      "https://example.com/v?utm_source=x&id=2&fbclid=abc&utm_medium=y#t=10",
+			want:    "https://example.com/v?id=2#t=10",
+		},
+		{
+			name:    "strip all params",
+			rewrite: StripParams(nil, "utm_*"),
+			in:      "https://example.com/v?utm_source=x",
+			want:    "https://example.com/v",
+		},
+		{
+			name:    "strip params keeps order and encoding",
+			rewrite: StripParams(nil, "utm_*"),
+			in:      "https://example.com/v?z=1&a=%2F&utm_x=1",
+			want:    "https://example.com/v?z=1&a=%2F",
+		},
+		{
+			name:    "strip params outside pattern",
+			rewrite: StripParams(regexp.MustCompile(`youtube\.com`), "utm_*"),
+			in:      "https://example.com/v?utm_source=x",
+			want:    "https://example.com/v?utm_source=x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rewrite(tt.in); got != tt.want {
+				t.Errorf("rewrite(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOriginalURLFrom(t *testing.T) {
+	if got := OriginalURLFrom(context.Background()); got != "" {
+		t.Errorf("OriginalURLFrom() without one = %q, want empty", got)
+	}
+	ctx := WithOriginalURL(context.Background(), "https://m.example.com")
+	if got := OriginalURLFrom(ctx); got != "https://m.example.com" {
+		t.Errorf("OriginalURLFrom() = %q", got)
+	}
+}
@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+// Worker states reported in SchedulerState.
+const (
+	WorkerIdle     = "idle"     // not started
+	WorkerRunning  = "running"  // polling for jobs
+	WorkerDraining = "draining" // shutting down, finishing running jobs
+	WorkerStopped  = "stopped"
+)
+
+// SchedulerState is what the worker is doing at one moment.
+type SchedulerState struct {
+	State        string
+	PollInterval time.Duration
+	// Polling is set while a poll hands out jobs. A poll of the default
+	// queue alone lasts until its batch is done.
+	Polling  bool
+	LastPoll time.Time // zero before the first poll
+	NextPoll time.Time // zero unless running and not polling
+	Running  []RunningJob
+	// Cooldowns are the hosts cooling down, by host.
+	Cooldowns []Cooldown
+	// Budget is the worker budget, or nil if it is unlimited.
+	Budget *BudgetState
+	// Outage is set while the network seems down, and after, until the
+	// jobs that failed during it are requeued.
+	Outage *OutageState
+}
+
+// RunningJob is a job the worker is running.
+type RunningJob struct {
+	JobID     int64
+	URL       string
+	Processor string
+	Queue     string
+	StartedAt time.Time
+}
+
+// BudgetState is how much of the worker budget running jobs take.
+type BudgetState struct {
+	Total   int
+	Used    int
+	Waiting int // jobs waiting for their cost to be free
+}
+
+// OutageState is a network outage the worker waits out.
+type OutageState struct {
+	Down      bool      // no jobs are started until the probe answers
+	Since     time.Time // when the network was found down
+	Probe     string    // the host:port dialled to see whether it is back
+	NextProbe time.Time // zero unless down
+	// Failed are the jobs that failed for good during the outage so far;
+	// Requeueing are those left to requeue after it.
+	Failed     int
+	Requeueing int
+}
@@ -3,63 +3,540 @@ package domain
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
+	"sync"
+	"time"
 )
 
 var (
-	ErrInvalidURL = errors.New("invalid URL")
-	ErrJobNotFound = errors.New("job not found")
+	ErrInvalidURL       = errors.New("invalid URL")
+	ErrJobNotFound      = errors.New("job not found")
+	ErrNoProcessor      = errors.New("no processor for URL")
+	ErrUnknownProcessor = errors.New("unknown processor")
+	ErrNotTestable      = errors.New("processor does not support test runs")
+	ErrJobState         = errors.New("job state does not allow this")
+	ErrInvalidJobID     = errors.New("invalid job ID")
+	ErrNotesTooLong     = errors.New("notes too long")
+	ErrViewNotFound     = errors.New("view not found")
 )
 
+// ManualProcessor is the processor name recorded for jobs completed by hand.
+const ManualProcessor = "manual"
+
+// DuplicateError reports a URL already submitted within the dedupe window,
+// or already completed when its resubmit policy is ResubmitReject.
+type DuplicateError struct {
+	Job *Job // the earlier submission
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("URL already submitted as job %d", e.Job.ID)
+}
+
 // JobService orchestrates job operations.
 type JobService struct {
-	repo JobRepository
+	repo       JobRepository
+	attempts   AttemptRepository
+	scheduler  RetryScheduler
+	validators []URLValidator
+	rewriters  []URLRewriter
+	timeout    time.Duration
+	clock      Clock
+
+	resolver    URLResolver
+	resolveHost func(u *url.URL) bool
+
+	queues      QueueRepository
+	assignQueue func(url string) string
+
+	processorFor func(url string) string
+	bookmarks    func(url string) bool
+
+	dedupe        DuplicateFinder
+	dedupeWindow  time.Duration
+	completed     CompletedFinder
+	resubmit      func(url string) ResubmitPolicy
+	manual        ManualCompleter
+	holder        JobHolder
+	bulk          BulkRepository
+	uids          UIDResolver
+	notes         NoteEditor
+	views         ViewRepository
+	completedList CompletedLister
+	failures      FailureLister
+	cooldowns     CooldownRepository
+	postponer     Postponer
+	outages       OutageRepository
+	fileChecks    FileCheckRepository
+	announcements AnnouncementRepository
+	library       LibraryIndex
+	redownloads   RedownloadFinder
+	pruner        JobPruner
+	retention     time.Duration
+	archive       bool
+	probes        ProbeCache
+	probeTTL      time.Duration
+	approval      ApprovalRepository
+	needsApproval func(u *url.URL) bool
+	submitMu      sync.Mutex // makes the duplicate checks and create atomic
 }
 
 // NewJobService creates a new JobService.
 func NewJobService(repo JobRepository) *JobService {
-	return &JobService{repo: repo}
+	return &JobService{repo: repo, clock: SystemClock}
+}
+
+// AddValidator registers a hook run on every submission after parsing.
+// Validators run in registration order; the first error rejects the URL.
+func (s *JobService) AddValidator(v URLValidator) {
+	s.validators = append(s.validators, v)
+}
+
+// AddRewriter registers a rule applied to every submitted URL before it is
+// parsed and validated. Rewriters run in registration order, each on the
+// previous one's result. The job keeps the submitted URL as OriginalURL.
+func (s *JobService) AddRewriter(r URLRewriter) {
+	s.rewriters = append(s.rewriters, r)
+}
+
+// SetRedirectResolver makes submissions whose URL matches follow redirects
+// with resolve, after rewrite rules, so they are matched to a processor by
+// their destination; rewrite rules then apply to the destination too. If
+// resolve fails, the URL is kept as it was.
+func (s *JobService) SetRedirectResolver(resolve URLResolver, match func(u *url.URL) bool) {
+	s.resolver, s.resolveHost = resolve, match
+}
+
+// SetQueues enables per-processor queues. Each submission is put in the
+// queue assign returns for its URL, after rewrites.
+func (s *JobService) SetQueues(r QueueRepository, assign func(url string) string) {
+	s.queues, s.assignQueue = r, assign
+}
+
+// SetProcessorMatcher tells submissions which processor will handle a URL,
+// after rewrites, so grants limited to some processors can be enforced.
+// match returns "" when no processor matches. Without it, a grant that
+// lists processors allows nothing.
+func (s *JobService) SetProcessorMatcher(match func(url string) string) {
+	s.processorFor = match
+}
+
+// SetBookmarkSupport makes bookmark submissions fail with
+// ErrBookmarkUnsupported when saves returns false for their URL, after
+// rewrites.
+func (s *JobService) SetBookmarkSupport(saves func(url string) bool) {
+	s.bookmarks = saves
+}
+
+// SetAttemptRepository enables recording of per-attempt history.
+func (s *JobService) SetAttemptRepository(r AttemptRepository) {
+	s.attempts = r
+}
+
+// SetRetryScheduler enables delayed retries for MarkRetryAfter.
+func (s *JobService) SetRetryScheduler(r RetryScheduler) {
+	s.scheduler = r
+}
+
+// SetDedupeWindow rejects a URL submitted again within window of an
+// earlier job for it that hasn't failed, so a client firing twice doesn't
+// fetch twice. Zero disables the check.
+func (s *JobService) SetDedupeWindow(f DuplicateFinder, window time.Duration) {
+	s.dedupe, s.dedupeWindow = f, window
+}
+
+// SetResubmitPolicy applies policy, usually that of the processor matching
+// the URL, to submissions of URLs that already completed: with
+// ResubmitReject they fail with *DuplicateError.
+func (s *JobService) SetResubmitPolicy(f CompletedFinder, policy func(url string) ResubmitPolicy) {
+	s.completed, s.resubmit = f, policy
+}
+
+// SetManualCompleter enables CompleteManually.
+func (s *JobService) SetManualCompleter(m ManualCompleter) {
+	s.manual = m
 }
 
-// Submit creates a new job for the given URL.
+// SetJobHolder enables SubmitHeld, Hold, and Release.
+func (s *JobService) SetJobHolder(h JobHolder) {
+	s.holder = h
+}
+
+// SetApproval makes submissions for which needs returns true wait in
+// StatusNeedsApproval until Approve or Reject is called.
+func (s *JobService) SetApproval(r ApprovalRepository, needs func(u *url.URL) bool) {
+	s.approval, s.needsApproval = r, needs
+}
+
+// SetTimeout bounds each operation, so a wedged repository call fails
+// instead of hanging its caller. Zero leaves operations bounded only by the
+// caller's context.
+func (s *JobService) SetTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// SetClock makes time-based decisions, such as the dedupe window, retry
+// delays, and retention, read time from c.
+func (s *JobService) SetClock(c Clock) {
+	s.clock = c
+}
+
+// withTimeout limits ctx to d, if d is set.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// Submit creates a new job for the given URL, after rewrite rules. It
+// awaits approval if the approval rule says so.
+// Rejections by validators are returned as *ValidationError, and repeats
+// within the dedupe window or of a completed URL whose resubmit policy is
+// ResubmitReject as *DuplicateError.
 func (s *JobService) Submit(ctx context.Context, rawURL string) (*Job, error) {
-	if _, err := url.ParseRequestURI(rawURL); err != nil {
+	return s.submit(ctx, rawURL, s.repo.Create)
+}
+
+// SubmitHeld is like Submit, but the job is held until Release is called.
+// It needs SetJobHolder.
+func (s *JobService) SubmitHeld(ctx context.Context, rawURL string) (*Job, error) {
+	if s.holder == nil {
+		return nil, errors.ErrUnsupported
+	}
+	return s.submit(ctx, rawURL, s.holder.CreateHeld)
+}
+
+func (s *JobService) submit(ctx context.Context, rawURL string, create func(context.Context, string) (*Job, error)) (*Job, error) {
+	original := rawURL
+	rawURL = s.rewrite(rawURL)
+	if resolved := s.resolveRedirects(ctx, rawURL); resolved != rawURL {
+		rawURL = s.rewrite(resolved)
+	}
+	if rawURL != original {
+		ctx = WithOriginalURL(ctx, original)
+	}
+
+	if !validNotes(NotesFrom(ctx)) {
+		return nil, ErrNotesTooLong
+	}
+	if err := ValidateUserAgent(UserAgentFrom(ctx)); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
 		return nil, ErrInvalidURL
 	}
-	return s.repo.Create(ctx, rawURL)
+	for _, validate := range s.validators {
+		if err := validate(ctx, u); err != nil {
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				ve = &ValidationError{Reason: err.Error()}
+			}
+			return nil, ve
+		}
+	}
+	if g := GrantFrom(ctx); g != nil {
+		processor := ""
+		if s.processorFor != nil {
+			processor = s.processorFor(rawURL)
+		}
+		if err := g.check(u, processor); err != nil {
+			return nil, err
+		}
+	}
+	if BookmarkFrom(ctx) && s.bookmarks != nil && !s.bookmarks(rawURL) {
+		return nil, ErrBookmarkUnsupported
+	}
+	if s.approval != nil && s.needsApproval != nil && s.needsApproval(u) {
+		create = s.approval.CreateForApproval
+	}
+	if s.assignQueue != nil {
+		ctx = WithQueue(ctx, s.assignQueue(rawURL))
+	}
+	dedupe := s.dedupe != nil && s.dedupeWindow > 0
+	reject := s.completed != nil && s.resubmit != nil && s.resubmit(rawURL) == ResubmitReject
+	if !dedupe && !reject {
+		return create(ctx, rawURL)
+	}
+
+	s.submitMu.Lock()
+	defer s.submitMu.Unlock()
+	if dedupe {
+		prev, err := s.dedupe.FindRecent(ctx, rawURL, s.clock.Now().Add(-s.dedupeWindow))
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil {
+			return nil, &DuplicateError{Job: prev}
+		}
+	}
+	if reject {
+		prev, err := s.completed.LastCompleted(ctx, rawURL, 0)
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil {
+			return nil, &DuplicateError{Job: prev}
+		}
+	}
+	return create(ctx, rawURL)
+}
+
+// rewrite applies the rewrite rules to rawURL.
+func (s *JobService) rewrite(rawURL string) string {
+	for _, rewrite := range s.rewriters {
+		rawURL = rewrite(rawURL)
+	}
+	return rawURL
+}
+
+// resolveRedirects returns where rawURL redirects to, if its host is one
+// to resolve, or rawURL itself.
+func (s *JobService) resolveRedirects(ctx context.Context, rawURL string) string {
+	if s.resolver == nil {
+		return rawURL
+	}
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil || !s.resolveHost(u) {
+		return rawURL
+	}
+	resolved, err := s.resolver(ctx, rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return resolved
+}
+
+// Approve lets a job awaiting approval be processed. It returns
+// ErrJobState for jobs that aren't awaiting approval.
+func (s *JobService) Approve(ctx context.Context, id int64) (*Job, error) {
+	if s.approval == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	if err := s.approval.Approve(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, id)
+}
+
+// RequestApproval sends a claimed job back for approval, e.g. because it
+// turned out larger than its processor allows, with reason saying why.
+func (s *JobService) RequestApproval(ctx context.Context, id int64, reason string) error {
+	if s.approval == nil {
+		return errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.approval.RequestApproval(ctx, id, reason)
+}
+
+// Reject fails a job awaiting approval without processing it.
+func (s *JobService) Reject(ctx context.Context, id int64, reason string) (*Job, error) {
+	if s.approval == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	msg := "rejected"
+	if reason != "" {
+		msg += ": " + reason
+	}
+	if err := s.approval.Reject(ctx, id, msg); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, id)
+}
+
+// Hold keeps a pending job from being processed until it is released. It
+// returns ErrJobState for jobs that aren't pending.
+func (s *JobService) Hold(ctx context.Context, id int64) (*Job, error) {
+	return s.setHeld(ctx, id, true)
+}
+
+// Release lets a held job be processed.
+func (s *JobService) Release(ctx context.Context, id int64) (*Job, error) {
+	return s.setHeld(ctx, id, false)
+}
+
+func (s *JobService) setHeld(ctx context.Context, id int64, held bool) (*Job, error) {
+	if s.holder == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	if err := s.holder.SetHeld(ctx, id, held); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, id)
+}
+
+// LastCompleted returns the newest completed job for the URL of job from
+// before it, with its files, or nil. It needs SetResubmitPolicy.
+func (s *JobService) LastCompleted(ctx context.Context, job *Job) (*Job, error) {
+	if s.completed == nil {
+		return nil, nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.completed.LastCompleted(ctx, job.URL, job.ID)
 }
 
 // Get retrieves a job by ID.
 func (s *JobService) Get(ctx context.Context, id int64) (*Job, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
 	return s.repo.Get(ctx, id)
 }
 
 // GetPending retrieves pending jobs up to the limit.
 func (s *JobService) GetPending(ctx context.Context, limit int) ([]Job, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
 	return s.repo.FindPending(ctx, limit)
 }
 
+// GetPendingIn retrieves pending jobs in queue up to the limit; see
+// QueueRepository.FindPendingIn. It needs SetQueues.
+func (s *JobService) GetPendingIn(ctx context.Context, queue string, active []string, limit int) ([]Job, error) {
+	if s.queues == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.queues.FindPendingIn(ctx, queue, active, limit)
+}
+
+// PendingByQueue returns the number of pending jobs per queue. It needs
+// SetQueues.
+func (s *JobService) PendingByQueue(ctx context.Context) (map[string]int64, error) {
+	if s.queues == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.queues.PendingByQueue(ctx)
+}
+
+// List returns jobs matching the filter, newest first.
+func (s *JobService) List(ctx context.Context, filter JobFilter) ([]Job, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.repo.List(ctx, filter)
+}
+
 // MarkProcessing claims a job for processing.
 func (s *JobService) MarkProcessing(ctx context.Context, id int64) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
 	return s.repo.Claim(ctx, id)
 }
 
-// MarkComplete marks a job as completed.
-func (s *JobService) MarkComplete(ctx context.Context, id int64) error {
-	return s.repo.Complete(ctx, id)
+// MarkComplete marks a job as completed with what it produced.
+func (s *JobService) MarkComplete(ctx context.Context, id int64, c Completion) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.repo.Complete(ctx, id, c)
+}
+
+// CompleteManually marks a pending, failed, or unapproved job completed without
+// processing it, for URLs handled outside catcher. The action, who took
+// it, and the note are recorded in the job's attempt history. It returns
+// ErrJobState for jobs being processed or already completed.
+func (s *JobService) CompleteManually(ctx context.Context, id int64, by, note string) (*Job, error) {
+	if s.manual == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	if err := s.manual.CompleteManually(ctx, id); err != nil {
+		return nil, err
+	}
+	job, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := "completed manually by " + by
+	if note != "" {
+		detail += ": " + note
+	}
+	now := s.clock.Now()
+	if s.attempts != nil {
+		a := Attempt{Number: job.Attempts + 1, Processor: ManualProcessor, Output: detail, StartedAt: now, FinishedAt: now}
+		if err := s.attempts.AddAttempt(ctx, id, a); err != nil {
+			return job, err
+		}
+	}
+	return job, nil
 }
 
 // MarkFailed marks a job as permanently failed.
 func (s *JobService) MarkFailed(ctx context.Context, id int64, reason string) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
 	return s.repo.Fail(ctx, id, reason)
 }
 
 // MarkRetry marks a job for retry with error info.
 func (s *JobService) MarkRetry(ctx context.Context, id int64, reason string) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
 	return s.repo.Retry(ctx, id, reason)
 }
 
+// MarkRetryAfter marks a job for retry no sooner than after from now. Without
+// a retry scheduler, or with no delay, it behaves like MarkRetry.
+func (s *JobService) MarkRetryAfter(ctx context.Context, id int64, reason string, after time.Duration) error {
+	if s.scheduler == nil || after <= 0 {
+		return s.MarkRetry(ctx, id, reason)
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.scheduler.RetryAt(ctx, id, reason, s.clock.Now().Add(after))
+}
+
+// DelayPending keeps a pending job waiting for after from now, without
+// claiming it. It needs SetRetryScheduler.
+func (s *JobService) DelayPending(ctx context.Context, id int64, reason string, after time.Duration) error {
+	if s.scheduler == nil {
+		return errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.scheduler.DelayPending(ctx, id, reason, s.clock.Now().Add(after))
+}
+
+// RecordAttempt stores a processing attempt. It is a no-op without an
+// attempt repository.
+func (s *JobService) RecordAttempt(ctx context.Context, jobID int64, a Attempt) error {
+	if s.attempts == nil {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.attempts.AddAttempt(ctx, jobID, a)
+}
+
+// Attempts returns a job's recorded attempts, oldest first.
+func (s *JobService) Attempts(ctx context.Context, jobID int64) ([]Attempt, error) {
+	if s.attempts == nil {
+		return nil, nil
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.attempts.Attempts(ctx, jobID)
+}
+
 // RecoverStale resets stale processing jobs (crash recovery).
 func (s *JobService) RecoverStale(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
 	return s.repo.RecoverStale(ctx)
 }
@@ -3,6 +3,10 @@ package domain
 import (
 	"context"
 	"errors"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -26,11 +30,18 @@ func (m *mockRepo) Create(ctx context.Context, url string) (*Job, error) {
 		return nil, m.createErr
 	}
 	job := &Job{
-		ID:        m.nextID,
-		URL:       url,
-		Status:    StatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:           m.nextID,
+		URL:          url,
+		OriginalURL:  OriginalURLFrom(ctx),
+		Queue:        QueueFrom(ctx),
+		Source:       SourceFrom(ctx),
+		Notes:        NotesFrom(ctx),
+		Bookmark:     BookmarkFrom(ctx),
+		RedownloadOf: RedownloadOfFrom(ctx),
+		UserAgent:    UserAgentFrom(ctx),
+		Status:       StatusPending,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 	m.jobs[m.nextID] = job
 	m.nextID++
@@ -64,6 +75,17 @@ func (m *mockRepo) FindPending(ctx context.Context, limit int) ([]Job, error) {
 	return result, nil
 }
 
+func (m *mockRepo) List(ctx context.Context, filter JobFilter) ([]Job, error) {
+	var result []Job
+	for id := m.nextID - 1; id > 0 && len(result) < filter.Limit; id-- {
+		job, ok := m.jobs[id]
+		if ok && (filter.Status == "" || job.Status == filter.Status) {
+			result = append(result, *job)
+		}
+	}
+	return result, nil
+}
+
 func (m *mockRepo) Claim(ctx context.Context, id int64) error {
 	if m.claimErr != nil {
 		return m.claimErr
@@ -78,12 +100,16 @@ func (m *mockRepo) Claim(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (m *mockRepo) Complete(ctx context.Context, id int64) error {
+func (m *mockRepo) Complete(ctx context.Context, id int64, c Completion) error {
 	job, ok := m.jobs[id]
 	if !ok {
 		return ErrJobNotFound
 	}
 	job.Status = StatusCompleted
+	job.Bytes = c.Bytes
+	job.Title = c.Title
+	job.Files = c.Files
+	job.Duration = c.Duration
 	job.UpdatedAt = time.Now()
 	return nil
 }
@@ -121,6 +147,78 @@ func (m *mockRepo) RecoverStale(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+func (m *mockRepo) FindRecent(ctx context.Context, url string, since time.Time) (*Job, error) {
+	for id := m.nextID - 1; id > 0; id-- {
+		job, ok := m.jobs[id]
+		if !ok || job.CreatedAt.Before(since) {
+			continue
+		}
+		if job.Status != StatusFailed && job.URL == url {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) LastCompleted(ctx context.Context, url string, beforeID int64) (*Job, error) {
+	if beforeID == 0 {
+		beforeID = m.nextID
+	}
+	for id := beforeID - 1; id > 0; id-- {
+		if job, ok := m.jobs[id]; ok && job.Status == StatusCompleted && job.URL == url {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) SetNotes(ctx context.Context, id int64, notes string) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	job.Notes = notes
+	return nil
+}
+
+func (m *mockRepo) CreateForApproval(ctx context.Context, url string) (*Job, error) {
+	job, err := m.Create(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	job.Status = StatusNeedsApproval
+	return job, nil
+}
+
+func (m *mockRepo) Approve(ctx context.Context, id int64) error {
+	return m.decide(id, StatusPending, "")
+}
+
+func (m *mockRepo) RequestApproval(ctx context.Context, id int64, reason string) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	job.Status, job.Error = StatusNeedsApproval, reason
+	return nil
+}
+
+func (m *mockRepo) Reject(ctx context.Context, id int64, reason string) error {
+	return m.decide(id, StatusFailed, reason)
+}
+
+func (m *mockRepo) decide(id int64, status JobStatus, reason string) error {
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.Status != StatusNeedsApproval {
+		return ErrJobState
+	}
+	job.Status, job.Error = status, reason
+	return nil
+}
+
 func TestJobService_Submit(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -234,7 +332,7 @@ func TestJobService_MarkComplete(t *testing.T) {
 	job, _ := svc.Submit(ctx, "https://example.com")
 	svc.MarkProcessing(ctx, job.ID)
 
-	err := svc.MarkComplete(ctx, job.ID)
+	err := svc.MarkComplete(ctx, job.ID, Completion{Processor: "test", Bytes: 1024})
 	if err != nil {
 		t.Fatalf("MarkComplete() error = %v", err)
 	}
@@ -243,6 +341,9 @@ func TestJobService_MarkComplete(t *testing.T) {
 	if updated.Status != StatusCompleted {
 		t.Errorf("Status = %q, want %q", updated.Status, StatusCompleted)
 	}
+	if updated.Bytes != 1024 {
+		t.Errorf("Bytes = %d, want 1024", updated.Bytes)
+	}
 }
 
 func TestJobService_MarkFailed(t *testing.T) {
@@ -285,3 +386,288 @@ func TestJobService_MarkRetry(t *testing.T) {
 		t.Errorf("Status = %q, want %q", updated.Status, StatusPending)
 	}
 }
+
+func TestJobService_Submit_Validators(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.AddValidator(SchemeAllowlist("https"))
+	svc.AddValidator(func(ctx context.Context, u *url.URL) error {
+		if u.Host == "blocked.example.com" {
+			return errors.New("host is blocked")
+		}
+		return nil
+	})
+	ctx := context.Background()
+
+	if _, err := svc.Submit(ctx, "https://example.com/video"); err != nil {
+		t.Errorf("Submit() error = %v, want nil", err)
+	}
+
+	var ve *ValidationError
+	if _, err := svc.Submit(ctx, "http://example.com/video"); !errors.As(err, &ve) {
+		t.Errorf("Submit() error = %v, want *ValidationError", err)
+	}
+
+	// Plain errors from custom rules are wrapped as validation errors
+	_, err := svc.Submit(ctx, "https://blocked.example.com/video")
+	if !errors.As(err, &ve) {
+		t.Fatalf("Submit() error = %v, want *ValidationError", err)
+	}
+	if ve.Reason != "host is blocked" {
+		t.Errorf("Reason = %q, want %q", ve.Reason, "host is blocked")
+	}
+
+	if len(repo.jobs) != 1 {
+		t.Errorf("created %d jobs, want 1", len(repo.jobs))
+	}
+}
+
+func TestJobService_Submit_Rewriters(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.AddRewriter(RegexRewrite(regexp.MustCompile(`^https://m\.`), "https://www."))
+	svc.AddRewriter(StripParams(nil, "utm_*"))
+	svc.AddValidator(func(ctx context.Context, u *url.URL) error {
+		if u.Host == "m.example.com" {
+			return errors.New("validated before rewriting")
+		}
+		return nil
+	})
+	ctx := context.Background()
+
+	job, err := svc.Submit(ctx, "https://m.example.com/v?utm_source=x")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if job.URL != "https://www.example.com/v" || job.OriginalURL != "https://m.example.com/v?utm_source=x" {
+		t.Errorf("job URL = %q, original %q", job.URL, job.OriginalURL)
+	}
+
+	job, _ = svc.Submit(ctx, "https://www.example.com/w")
+	if job.OriginalURL != "" {
+		t.Errorf("OriginalURL = %q for an unchanged URL, want empty", job.OriginalURL)
+	}
+}
+
+func TestJobService_Submit_RedirectResolver(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.AddRewriter(StripParams(nil, "utm_*"))
+	var resolved []string
+	svc.SetRedirectResolver(func(ctx context.Context, rawURL string) (string, error) {
+		resolved = append(resolved, rawURL)
+		if rawURL == "https://t.co/broken" {
+			return "", errors.New("timeout")
+		}
+		return "https://www.example.com/v?utm_medium=social", nil
+	}, MatchHosts("t.co"))
+	ctx := context.Background()
+
+	job, err := svc.Submit(ctx, "https://t.co/abc?utm_source=x")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if job.URL != "https://www.example.com/v" || job.OriginalURL != "https://t.co/abc?utm_source=x" {
+		t.Errorf("job URL = %q, original %q", job.URL, job.OriginalURL)
+	}
+
+	// Failures keep the URL; other hosts aren't resolved
+	job, _ = svc.Submit(ctx, "https://t.co/broken")
+	if job.URL != "https://t.co/broken" || job.OriginalURL != "" {
+		t.Errorf("after a failed resolve, job URL = %q, original %q", job.URL, job.OriginalURL)
+	}
+	svc.Submit(ctx, "https://example.com/w")
+	if want := []string{"https://t.co/abc", "https://t.co/broken"}; !slices.Equal(resolved, want) {
+		t.Errorf("resolved %v, want %v", resolved, want)
+	}
+}
+
+func TestJobService_Submit_Dedupe(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.SetDedupeWindow(repo, 10*time.Minute)
+	ctx := context.Background()
+
+	first, err := svc.Submit(ctx, "https://example.com/video")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	var de *DuplicateError
+	if _, err := svc.Submit(ctx, "https://example.com/video"); !errors.As(err, &de) {
+		t.Fatalf("Submit() error = %v, want *DuplicateError", err)
+	}
+	if de.Job.ID != first.ID {
+		t.Errorf("duplicate of job %d, want %d", de.Job.ID, first.ID)
+	}
+
+	if _, err := svc.Submit(ctx, "https://example.com/other"); err != nil {
+		t.Errorf("Submit() of a different URL error = %v", err)
+	}
+
+	// Outside the window, or once the earlier job failed, it's accepted
+	first.CreatedAt = time.Now().Add(-11 * time.Minute)
+	second, err := svc.Submit(ctx, "https://example.com/video")
+	if err != nil {
+		t.Fatalf("Submit() after the window error = %v", err)
+	}
+	repo.Fail(ctx, second.ID, "boom")
+	if _, err := svc.Submit(ctx, "https://example.com/video"); err != nil {
+		t.Errorf("Submit() after a failure error = %v", err)
+	}
+
+	// Zero disables the check
+	svc.SetDedupeWindow(repo, 0)
+	if _, err := svc.Submit(ctx, "https://example.com/video"); err != nil {
+		t.Errorf("Submit() with dedupe off error = %v", err)
+	}
+}
+
+func TestJobService_Approval(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	ctx := context.Background()
+
+	if _, err := svc.Approve(ctx, 1); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Approve() without approval error = %v, want ErrUnsupported", err)
+	}
+
+	svc.SetApproval(repo, MatchHosts("untrusted.example"))
+	trusted, _ := svc.Submit(ctx, "https://example.com/a")
+	if trusted.Status != StatusPending {
+		t.Errorf("trusted host status = %s, want pending", trusted.Status)
+	}
+	first, _ := svc.Submit(ctx, "https://untrusted.example/a")
+	second, _ := svc.Submit(ctx, "https://cdn.untrusted.example/b")
+	if first.Status != StatusNeedsApproval || second.Status != StatusNeedsApproval {
+		t.Fatalf("statuses = %s, %s, want needs_approval", first.Status, second.Status)
+	}
+
+	if job, err := svc.Approve(ctx, first.ID); err != nil || job.Status != StatusPending {
+		t.Errorf("Approve() = %+v, %v, want a pending job", job, err)
+	}
+	if job, err := svc.Reject(ctx, second.ID, "spam"); err != nil || job.Error != "rejected: spam" {
+		t.Errorf("Reject() = %+v, %v, want the reason recorded", job, err)
+	}
+	if _, err := svc.Reject(ctx, trusted.ID, ""); !errors.Is(err, ErrJobState) {
+		t.Errorf("Reject() of a pending job error = %v, want ErrJobState", err)
+	}
+}
+
+func TestJobService_Submit_BookmarkSupport(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.SetBookmarkSupport(func(url string) bool { return !strings.Contains(url, "legacy") })
+	ctx := context.Background()
+
+	if _, err := svc.Submit(WithBookmark(ctx), "https://legacy.example/a"); err != ErrBookmarkUnsupported {
+		t.Errorf("Submit(bookmark) error = %v, want ErrBookmarkUnsupported", err)
+	}
+	if _, err := svc.Submit(ctx, "https://legacy.example/a"); err != nil {
+		t.Errorf("Submit() error = %v, want downloads allowed", err)
+	}
+	if job, err := svc.Submit(WithBookmark(ctx), "https://example.com/a"); err != nil || !job.Bookmark {
+		t.Errorf("Submit(bookmark) = %+v, %v, want a bookmark job", job, err)
+	}
+}
+
+func TestJobService_Submit_ResubmitPolicy(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	svc.SetResubmitPolicy(repo, func(url string) ResubmitPolicy {
+		if url == "https://example.com/once" {
+			return ResubmitReject
+		}
+		return ResubmitAllow
+	})
+	ctx := context.Background()
+
+	once, _ := svc.Submit(ctx, "https://example.com/once")
+	again, _ := svc.Submit(ctx, "https://example.com/again")
+
+	// Not yet completed, so not a duplicate
+	if _, err := svc.Submit(ctx, "https://example.com/once"); err != nil {
+		t.Fatalf("Submit() before completion error = %v", err)
+	}
+
+	repo.Complete(ctx, once.ID, Completion{})
+	repo.Complete(ctx, again.ID, Completion{})
+
+	var de *DuplicateError
+	if _, err := svc.Submit(ctx, "https://example.com/once"); !errors.As(err, &de) || de.Job.ID != once.ID {
+		t.Errorf("Submit() error = %v, want *DuplicateError for job %d", err, once.ID)
+	}
+	if _, err := svc.Submit(ctx, "https://example.com/again"); err != nil {
+		t.Errorf("Submit() with allow error = %v", err)
+	}
+
+	latest, _ := svc.Submit(ctx, "https://example.com/again")
+	prev, err := svc.LastCompleted(ctx, latest)
+	if err != nil || prev == nil || prev.ID != again.ID {
+		t.Errorf("LastCompleted() = %v, %v, want job %d", prev, err, again.ID)
+	}
+}
+
+// blockingRepo is a JobRepository whose calls hang until their context ends,
+// like a database wedged on a lock.
+type blockingRepo struct {
+	mockRepo
+}
+
+func (b *blockingRepo) block(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *blockingRepo) Create(ctx context.Context, url string) (*Job, error) {
+	return nil, b.block(ctx)
+}
+func (b *blockingRepo) Get(ctx context.Context, id int64) (*Job, error) { return nil, b.block(ctx) }
+func (b *blockingRepo) FindPending(ctx context.Context, limit int) ([]Job, error) {
+	return nil, b.block(ctx)
+}
+func (b *blockingRepo) Claim(ctx context.Context, id int64) error { return b.block(ctx) }
+func (b *blockingRepo) Complete(ctx context.Context, id int64, c Completion) error {
+	return b.block(ctx)
+}
+func (b *blockingRepo) RecoverStale(ctx context.Context) (int64, error) { return 0, b.block(ctx) }
+
+func TestJobService_Timeout(t *testing.T) {
+	svc := NewJobService(&blockingRepo{})
+	svc.SetTimeout(20 * time.Millisecond)
+	ctx := context.Background()
+
+	calls := map[string]func() error{
+		"Submit":         func() error { _, err := svc.Submit(ctx, "https://example.com"); return err },
+		"Get":            func() error { _, err := svc.Get(ctx, 1); return err },
+		"GetPending":     func() error { _, err := svc.GetPending(ctx, 10); return err },
+		"MarkProcessing": func() error { return svc.MarkProcessing(ctx, 1) },
+		"MarkComplete":   func() error { return svc.MarkComplete(ctx, 1, Completion{}) },
+		"RecoverStale":   func() error { _, err := svc.RecoverStale(ctx); return err },
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() { done <- call() }()
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("error = %v, want DeadlineExceeded", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("call hung past its deadline")
+			}
+		})
+	}
+}
+
+func TestJobService_NoTimeout(t *testing.T) {
+	svc := NewJobService(&blockingRepo{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Without a timeout, only the caller's context bounds the call
+	if _, err := svc.Get(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want the caller's deadline", err)
+	}
+}
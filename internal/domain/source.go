@@ -0,0 +1,20 @@
+package domain
+
+import "context"
+
+type sourceKey struct{}
+
+// WithSource returns a context submitting jobs on behalf of source, such as
+// a user or device. Pending jobs are taken in turn from each source, so one
+// source's backlog doesn't hold up another's jobs.
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom returns the source of the job being created, or "" for
+// submissions without one, which share a turn. Repositories store it with
+// the job.
+func SourceFrom(ctx context.Context) string {
+	s, _ := ctx.Value(sourceKey{}).(string)
+	return s
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// StatsPeriod is the granularity of throughput history.
+type StatsPeriod string
+
+const (
+	PeriodHour StatsPeriod = "hour"
+	PeriodDay  StatsPeriod = "day"
+)
+
+var ErrInvalidPeriod = errors.New("invalid stats period")
+
+// StatsQuery selects throughput history.
+type StatsQuery struct {
+	Period StatsPeriod
+	Since  time.Time
+	// ByProcessor splits buckets per processor. Submissions and failures are
+	// not attributed to a processor and are reported with an empty name.
+	ByProcessor bool
+}
+
+// StatsBucket holds job throughput for one hour or day.
+type StatsBucket struct {
+	Start     time.Time
+	Processor string // set only for StatsQuery.ByProcessor
+	Submitted int64
+	Completed int64
+	Failed    int64
+	Bytes     int64
+}
+
+// StatsService exposes throughput history.
+type StatsService struct {
+	repo            StatsRepository
+	hourlyRetention time.Duration
+	timeout         time.Duration
+	clock           Clock
+}
+
+// NewStatsService creates a StatsService keeping hourly detail for
+// hourlyRetention before compacting it into daily buckets.
+func NewStatsService(repo StatsRepository, hourlyRetention time.Duration) *StatsService {
+	return &StatsService{repo: repo, hourlyRetention: hourlyRetention, clock: SystemClock}
+}
+
+// SetTimeout bounds each operation, like JobService.SetTimeout.
+func (s *StatsService) SetTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// SetClock makes compaction read time from c, like JobService.SetClock.
+func (s *StatsService) SetClock(c Clock) {
+	s.clock = c
+}
+
+// History returns throughput buckets matching q, oldest first.
+func (s *StatsService) History(ctx context.Context, q StatsQuery) ([]StatsBucket, error) {
+	if q.Period != PeriodHour && q.Period != PeriodDay {
+		return nil, ErrInvalidPeriod
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.repo.Stats(ctx, q)
+}
+
+// Compact folds hourly buckets past the retention window into daily buckets.
+// Compaction only happens at day boundaries, so a day is never split.
+func (s *StatsService) Compact(ctx context.Context) (int64, error) {
+	before := s.clock.Now().UTC().Add(-s.hourlyRetention).Truncate(24 * time.Hour)
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.repo.CompactStats(ctx, before)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type mockStatsRepo struct {
+	query   StatsQuery
+	before  time.Time
+	buckets []StatsBucket
+}
+
+func (m *mockStatsRepo) Stats(ctx context.Context, q StatsQuery) ([]StatsBucket, error) {
+	m.query = q
+	return m.buckets, nil
+}
+
+func (m *mockStatsRepo) CompactStats(ctx context.Context, before time.Time) (int64, error) {
+	m.before = before
+	return 0, nil
+}
+
+func TestStatsService_History(t *testing.T) {
+	tests := []struct {
+		name    string
+		period  StatsPeriod
+		wantErr error
+	}{
+		{name: "hour", period: PeriodHour},
+		{name: "day", period: PeriodDay},
+		{name: "invalid", period: "week", wantErr: ErrInvalidPeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockStatsRepo{}
+			svc := NewStatsService(repo, time.Hour)
+			q := StatsQuery{Period: tt.period, Since: time.Now().Add(-time.Hour), ByProcessor: true}
+
+			_, err := svc.History(context.Background(), q)
+			if err != tt.wantErr {
+				t.Fatalf("History() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && repo.query != q {
+				t.Errorf("repo called with %+v, want %+v", repo.query, q)
+			}
+		})
+	}
+}
+
+func TestStatsService_CompactAlignsToDays(t *testing.T) {
+	repo := &mockStatsRepo{}
+	svc := NewStatsService(repo, 48*time.Hour)
+
+	if _, err := svc.Compact(context.Background()); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	if !repo.before.Equal(repo.before.Truncate(24 * time.Hour)) {
+		t.Errorf("before = %v, want a day boundary", repo.before)
+	}
+	if age := time.Since(repo.before); age < 48*time.Hour || age > 72*time.Hour {
+		t.Errorf("before is %v ago, want between 48h and 72h", age)
+	}
+}
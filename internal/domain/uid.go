@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// validUID matches the canonical, lowercase form of a UUID.
+var validUID = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// NewUID returns a random (version 4) UUID for a new job.
+func NewUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// IsUID reports whether s is a job UID.
+func IsUID(s string) bool {
+	return validUID.MatchString(s)
+}
+
+// SetUIDResolver lets ResolveID look jobs up by UID.
+func (s *JobService) SetUIDResolver(r UIDResolver) {
+	s.uids = r
+}
+
+// ResolveID returns the ID of the job ref names, by its numeric ID or its
+// UID. Without numeric, only UIDs are accepted, so jobs can't be found by
+// counting. A ref that is neither returns ErrInvalidJobID.
+func (s *JobService) ResolveID(ctx context.Context, ref string, numeric bool) (int64, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		if !numeric {
+			return 0, ErrJobNotFound
+		}
+		return id, nil
+	}
+	if !IsUID(ref) {
+		return 0, ErrInvalidJobID
+	}
+	if s.uids == nil {
+		return 0, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.uids.ResolveUID(ctx, ref)
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewUID(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		uid := NewUID()
+		if !IsUID(uid) || uid[14] != '4' || !strings.ContainsRune("89ab", rune(uid[19])) {
+			t.Fatalf("NewUID() = %q, want a version 4 UUID", uid)
+		}
+		if seen[uid] {
+			t.Fatalf("NewUID() repeated %q", uid)
+		}
+		seen[uid] = true
+	}
+}
+
+// uidMap resolves the UIDs it holds.
+type uidMap map[string]int64
+
+func (m uidMap) ResolveUID(ctx context.Context, uid string) (int64, error) {
+	if id, ok := m[uid]; ok {
+		return id, nil
+	}
+	return 0, ErrJobNotFound
+}
+
+func TestJobService_ResolveID(t *testing.T) {
+	const uid = "0f8fad5b-d9cb-469f-a165-70867728950e"
+	svc := NewJobService(newMockRepo())
+	svc.SetUIDResolver(uidMap{uid: 7})
+
+	tests := []struct {
+		ref     string
+		numeric bool
+		want    int64
+		err     error
+	}{
+		{"42", true, 42, nil},
+		{"42", false, 0, ErrJobNotFound},
+		{uid, true, 7, nil},
+		{uid, false, 7, nil},
+		{"0f8fad5b-d9cb-469f-a165-000000000000", false, 0, ErrJobNotFound},
+		{"0F8FAD5B-D9CB-469F-A165-70867728950E", true, 0, ErrInvalidJobID},
+		{"abc", true, 0, ErrInvalidJobID},
+	}
+	for _, tt := range tests {
+		id, err := svc.ResolveID(context.Background(), tt.ref, tt.numeric)
+		if id != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ResolveID(%q, %v) = %d, %v, want %d, %v", tt.ref, tt.numeric, id, err, tt.want, tt.err)
+		}
+	}
+
+	if _, err := NewJobService(newMockRepo()).ResolveID(context.Background(), uid, true); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ResolveID() without a resolver error = %v, want ErrUnsupported", err)
+	}
+}
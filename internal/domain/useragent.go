@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxUserAgentLength is the most characters a job's user agent may have.
+const MaxUserAgentLength = 512
+
+// ErrInvalidUserAgent reports a user agent that is too long, has control
+// characters, or starts with "-", which a command could take for a flag.
+var ErrInvalidUserAgent = errors.New("invalid user agent")
+
+type userAgentKey struct{}
+
+// WithUserAgent returns a context submitting jobs whose processors should
+// send ua as the User-Agent instead of their default. Size probes read it
+// too.
+func WithUserAgent(ctx context.Context, ua string) context.Context {
+	return context.WithValue(ctx, userAgentKey{}, ua)
+}
+
+// UserAgentFrom returns the user agent of the job being created or
+// probed, or "" for the processor's default. Repositories store it with
+// the job.
+func UserAgentFrom(ctx context.Context) string {
+	ua, _ := ctx.Value(userAgentKey{}).(string)
+	return ua
+}
+
+// ValidateUserAgent returns ErrInvalidUserAgent if ua can't be passed to
+// a command as a User-Agent. The empty user agent is valid.
+func ValidateUserAgent(ua string) error {
+	if utf8.RuneCountInString(ua) > MaxUserAgentLength || strings.HasPrefix(ua, "-") ||
+		strings.IndexFunc(ua, unicode.IsControl) >= 0 {
+		return ErrInvalidUserAgent
+	}
+	return nil
+}
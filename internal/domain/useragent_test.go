@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateUserAgent(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want error
+	}{
+		{"", nil},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15", nil},
+		{strings.Repeat("é", MaxUserAgentLength), nil},
+		{strings.Repeat("a", MaxUserAgentLength+1), ErrInvalidUserAgent},
+		{"-o /etc/passwd", ErrInvalidUserAgent},
+		{"Agent\r\nX-Injected: 1", ErrInvalidUserAgent},
+		{"Agent\x00", ErrInvalidUserAgent},
+	}
+	for _, tt := range tests {
+		if err := ValidateUserAgent(tt.ua); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateUserAgent(%q) = %v, want %v", tt.ua, err, tt.want)
+		}
+	}
+}
+
+func TestJobService_Submit_UserAgent(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewJobService(repo)
+	ctx := context.Background()
+
+	if _, err := svc.Submit(WithUserAgent(ctx, "-x"), "https://example.com/a"); !errors.Is(err, ErrInvalidUserAgent) {
+		t.Errorf("Submit() with an invalid user agent error = %v, want ErrInvalidUserAgent", err)
+	}
+	job, err := svc.Submit(WithUserAgent(ctx, "Agent/1.0"), "https://example.com/a")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if job.UserAgent != "Agent/1.0" {
+		t.Errorf("Submit() UserAgent = %q, want Agent/1.0", job.UserAgent)
+	}
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// URLValidator checks a submitted URL before a job is created.
+// A non-nil error rejects the submission.
+type URLValidator func(ctx context.Context, u *url.URL) error
+
+// ValidationError reports a URL rejected by a validator.
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return "URL rejected: " + e.Reason
+}
+
+// SchemeAllowlist rejects URLs whose scheme is not in schemes.
+func SchemeAllowlist(schemes ...string) URLValidator {
+	return func(ctx context.Context, u *url.URL) error {
+		if !slices.Contains(schemes, strings.ToLower(u.Scheme)) {
+			return &ValidationError{Reason: fmt.Sprintf("scheme %q not allowed", u.Scheme)}
+		}
+		return nil
+	}
+}
+
+// MaxLength rejects URLs longer than n bytes.
+func MaxLength(n int) URLValidator {
+	return func(ctx context.Context, u *url.URL) error {
+		if l := len(u.String()); l > n {
+			return &ValidationError{Reason: fmt.Sprintf("length %d exceeds maximum %d", l, n)}
+		}
+		return nil
+	}
+}
+
+// HostResolves rejects URLs whose host cannot be resolved with lookup.
+func HostResolves(lookup func(ctx context.Context, host string) ([]string, error)) URLValidator {
+	return func(ctx context.Context, u *url.URL) error {
+		host := u.Hostname()
+		if host == "" {
+			return &ValidationError{Reason: "missing host"}
+		}
+		if _, err := lookup(ctx, host); err != nil {
+			return &ValidationError{Reason: fmt.Sprintf("host %q does not resolve", host)}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSchemeAllowlist(t *testing.T) {
+	validate := SchemeAllowlist("http", "https")
+
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://example.com", wantErr: false},
+		{url: "HTTP://example.com", wantErr: false},
+		{url: "javascript:alert(1)", wantErr: true},
+		{url: "file:///etc/passwd", wantErr: true},
+		{url: "data:text/html,hi", wantErr: true},
+		{url: "/relative/path", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.ParseRequestURI(tt.url)
+			if err != nil {
+				t.Fatalf("ParseRequestURI() error = %v", err)
+			}
+			err = validate(context.Background(), u)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SchemeAllowlist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	validate := MaxLength(30)
+
+	short, _ := url.Parse("https://example.com/a")
+	if err := validate(context.Background(), short); err != nil {
+		t.Errorf("MaxLength() error = %v, want nil", err)
+	}
+
+	long, _ := url.Parse("https://example.com/" + strings.Repeat("a", 30))
+	var ve *ValidationError
+	if err := validate(context.Background(), long); !errors.As(err, &ve) {
+		t.Errorf("MaxLength() error = %v, want *ValidationError", err)
+	}
+}
+
+func TestHostResolves(t *testing.T) {
+	lookup := func(ctx context.Context, host string) ([]string, error) {
+		if host == "example.com" {
+			return []string{"93.184.216.34"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	validate := HostResolves(lookup)
+
+	ok, _ := url.Parse("https://example.com:8443/video")
+	if err := validate(context.Background(), ok); err != nil {
+		t.Errorf("HostResolves() error = %v, want nil", err)
+	}
+
+	bad, _ := url.Parse("https://nonexistent.invalid/video")
+	if err := validate(context.Background(), bad); err == nil {
+		t.Error("HostResolves() error = nil, want error")
+	}
+}
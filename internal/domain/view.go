@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// View is a saved, named job filter, so a listing that is checked often
+// doesn't have to be spelled out each time.
+type View struct {
+	Name   string
+	Status JobStatus // empty matches all statuses
+	Host   string    // URLs of the host and its subdomains; empty matches all
+}
+
+// validViewName limits view names to ones safe in URLs and on the command
+// line.
+var validViewName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// InvalidViewError reports a view that can't be saved.
+type InvalidViewError struct {
+	Reason string
+}
+
+func (e *InvalidViewError) Error() string {
+	return "invalid view: " + e.Reason
+}
+
+// Filter returns the job filter v stands for, listing up to limit jobs.
+func (v View) Filter(limit int) JobFilter {
+	return JobFilter{Status: v.Status, Host: v.Host, Limit: limit}
+}
+
+func (v View) validate() error {
+	if !validViewName.MatchString(v.Name) {
+		return &InvalidViewError{Reason: "name must be 1-64 lowercase letters, digits, - or _, starting with a letter or digit"}
+	}
+	if v.Status != "" && !v.Status.Valid() {
+		return &InvalidViewError{Reason: fmt.Sprintf("unknown status %q", v.Status)}
+	}
+	return nil
+}
+
+// SetViewRepository enables saved views.
+func (s *JobService) SetViewRepository(r ViewRepository) {
+	s.views = r
+}
+
+// SaveView stores v under its name, replacing any view already saved
+// there. It returns an InvalidViewError for a bad name or status.
+func (s *JobService) SaveView(ctx context.Context, v View) error {
+	if s.views == nil {
+		return errors.ErrUnsupported
+	}
+	if err := v.validate(); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.views.SaveView(ctx, v)
+}
+
+// View returns the view named name, or ErrViewNotFound.
+func (s *JobService) View(ctx context.Context, name string) (*View, error) {
+	if s.views == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.views.View(ctx, name)
+}
+
+// Views returns every saved view, by name.
+func (s *JobService) Views(ctx context.Context) ([]View, error) {
+	if s.views == nil {
+		return nil, errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.views.Views(ctx)
+}
+
+// DeleteView removes the view named name, or returns ErrViewNotFound.
+func (s *JobService) DeleteView(ctx context.Context, name string) error {
+	if s.views == nil {
+		return errors.ErrUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.views.DeleteView(ctx, name)
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// mockViews is an in-memory ViewRepository.
+type mockViews map[string]View
+
+func (m mockViews) SaveView(ctx context.Context, v View) error {
+	m[v.Name] = v
+	return nil
+}
+
+func (m mockViews) View(ctx context.Context, name string) (*View, error) {
+	v, ok := m[name]
+	if !ok {
+		return nil, ErrViewNotFound
+	}
+	return &v, nil
+}
+
+func (m mockViews) Views(ctx context.Context) ([]View, error) {
+	var views []View
+	for _, v := range m {
+		views = append(views, v)
+	}
+	return views, nil
+}
+
+func (m mockViews) DeleteView(ctx context.Context, name string) error {
+	if _, ok := m[name]; !ok {
+		return ErrViewNotFound
+	}
+	delete(m, name)
+	return nil
+}
+
+func TestJobService_SaveView(t *testing.T) {
+	tests := []struct {
+		name    string
+		view    View
+		wantErr bool
+	}{
+		{"status and host", View{Name: "stuck-youtube", Status: StatusProcessing, Host: "youtube.com"}, false},
+		{"everything", View{Name: "all"}, false},
+		{"underscore", View{Name: "big_ones"}, false},
+		{"empty name", View{}, true},
+		{"uppercase", View{Name: "Stuck"}, true},
+		{"leading dash", View{Name: "-x"}, true},
+		{"slash", View{Name: "a/b"}, true},
+		{"unknown status", View{Name: "odd", Status: "stuck"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewJobService(newMockRepo())
+			views := mockViews{}
+			svc.SetViewRepository(views)
+
+			err := svc.SaveView(context.Background(), tt.view)
+			var ive *InvalidViewError
+			if tt.wantErr != errors.As(err, &ive) {
+				t.Fatalf("SaveView(%+v) error = %v, wantErr %v", tt.view, err, tt.wantErr)
+			}
+			if _, saved := views[tt.view.Name]; saved == tt.wantErr {
+				t.Errorf("SaveView(%+v) saved = %v, want %v", tt.view, saved, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJobService_Views_Unsupported(t *testing.T) {
+	svc := NewJobService(newMockRepo())
+	ctx := context.Background()
+	if err := svc.SaveView(ctx, View{Name: "x"}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("SaveView() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := svc.Views(ctx); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Views() error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestView_Filter(t *testing.T) {
+	v := View{Name: "stuck-youtube", Status: StatusProcessing, Host: "youtube.com"}
+	want := JobFilter{Status: StatusProcessing, Host: "youtube.com", Limit: 50}
+	if got := v.Filter(50); got != want {
+		t.Errorf("Filter() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+// Package event defines the JSON representation of jobs shared by the API
+// and anything that publishes job changes, so consumers handle one shape.
+package event
+
+import (
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// SchemaVersion is the version of the Event and Job shapes. Adding a field
+// keeps it; removing, renaming, or changing the meaning of one bumps it.
+const SchemaVersion = 1
+
+// Type identifies what happened to a job.
+type Type string
+
+const (
+	JobCreated   Type = "job.created"
+	JobCompleted Type = "job.completed"
+	JobFailed    Type = "job.failed"
+)
+
+// Event is a change to a job, as published to consumers.
+type Event struct {
+	Schema int    `json:"schema"`
+	Type   Type   `json:"type"`
+	Time   string `json:"time"`
+	Job    Job    `json:"job"`
+}
+
+// Job is the JSON form of a job.
+type Job struct {
+	ID int64 `json:"id"`
+	// UID is the job's unguessable ID; consumers should prefer it to ID.
+	UID         string `json:"uid,omitempty"`
+	URL         string `json:"url"`
+	OriginalURL string `json:"original_url,omitempty"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	Error       string `json:"error,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Bytes       int64  `json:"bytes"`
+	DurationMS  int64  `json:"duration_ms,omitempty"`
+	Files       []File `json:"files,omitempty"`
+	Held        bool   `json:"held,omitempty"`
+	Approved    bool   `json:"approved,omitempty"`
+	Queue       string `json:"queue,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Notes       string `json:"notes,omitempty"`
+	Bookmark    bool   `json:"bookmark,omitempty"`
+	// RedownloadOf is the ID of the completed job this one downloads again.
+	RedownloadOf int64 `json:"redownload_of,omitempty"`
+	// UserAgent is sent instead of the processor's default.
+	UserAgent string `json:"user_agent,omitempty"`
+	// NextAttemptAt is set on pending jobs whose retry was delayed.
+	NextAttemptAt string `json:"next_attempt_at,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// File is a file a job produced.
+type File struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	// SHA256 is the hex digest of the file, if the worker computed one.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// timeFormat is used for every timestamp in the schema, all in UTC.
+const timeFormat = "2006-01-02T15:04:05Z"
+
+// New returns an event of type typ for job, which happened at at.
+func New(typ Type, job *domain.Job, at time.Time) Event {
+	return Event{Schema: SchemaVersion, Type: typ, Time: at.UTC().Format(timeFormat), Job: FromJob(job)}
+}
+
+// FromJob returns the JSON form of job.
+func FromJob(job *domain.Job) Job {
+	j := Job{
+		ID:           job.ID,
+		UID:          job.UID,
+		URL:          job.URL,
+		OriginalURL:  job.OriginalURL,
+		Status:       string(job.Status),
+		Attempts:     job.Attempts,
+		Error:        job.Error,
+		Title:        job.Title,
+		Bytes:        job.Bytes,
+		DurationMS:   job.Duration.Milliseconds(),
+		Held:         job.Held,
+		Approved:     job.Approved,
+		Queue:        job.Queue,
+		Source:       job.Source,
+		Notes:        job.Notes,
+		Bookmark:     job.Bookmark,
+		RedownloadOf: job.RedownloadOf,
+		UserAgent:    job.UserAgent,
+		CreatedAt:    job.CreatedAt.UTC().Format(timeFormat),
+		UpdatedAt:    job.UpdatedAt.UTC().Format(timeFormat),
+	}
+	if job.Status == domain.StatusPending && !job.NotBefore.IsZero() {
+		j.NextAttemptAt = job.NotBefore.UTC().Format(timeFormat)
+	}
+	for _, f := range job.Files {
+		j.Files = append(j.Files, File{Path: f.Path, Bytes: f.Bytes, SHA256: f.SHA256})
+	}
+	return j
+}
@@ -0,0 +1,96 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestEvent_Golden(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		typ  Type
+		job  domain.Job
+		at   time.Time
+	}{
+		{
+			name: "job_created",
+			typ:  JobCreated,
+			job: domain.Job{
+				ID: 7, UID: "0f8fad5b-d9cb-469f-a165-70867728950e", URL: "https://www.youtube.com/watch?v=abc", OriginalURL: "https://youtu.be/abc",
+				Status: domain.StatusPending, CreatedAt: created, UpdatedAt: created,
+			},
+			at: created,
+		},
+		{
+			name: "job_completed",
+			typ:  JobCompleted,
+			job: domain.Job{
+				ID: 7, UID: "0f8fad5b-d9cb-469f-a165-70867728950e", URL: "https://www.youtube.com/watch?v=abc", Status: domain.StatusCompleted,
+				Attempts: 1, Title: "A clip", Bytes: 3072, Duration: 1500 * time.Millisecond,
+				Files:     []domain.ResultFile{{Path: "/videos/A clip.mp4", Bytes: 3072}},
+				CreatedAt: created, UpdatedAt: created.Add(time.Minute),
+			},
+			// Not in UTC, to check timestamps are converted
+			at: created.Add(time.Minute).In(time.FixedZone("CET", 3600)),
+		},
+		{
+			name: "job_failed",
+			typ:  JobFailed,
+			job: domain.Job{
+				ID: 8, UID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", URL: "https://example.com/v", Status: domain.StatusFailed, Attempts: 3,
+				Error: "exit status 1", Approved: true, CreatedAt: created, UpdatedAt: created.Add(time.Hour),
+			},
+			at: created.Add(time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.MarshalIndent(New(tt.typ, &tt.job, tt.at), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := filepath.Join("testdata", tt.name+".json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("event differs from %s (run with -update if intended):\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestFromJob_NextAttemptAt(t *testing.T) {
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		job  domain.Job
+		want string
+	}{
+		{"delayed retry", domain.Job{Status: domain.StatusPending, NotBefore: at}, "2026-03-01T10:00:00Z"},
+		{"no delay", domain.Job{Status: domain.StatusPending}, ""},
+		{"no longer pending", domain.Job{Status: domain.StatusCompleted, NotBefore: at}, ""},
+	}
+	for _, tt := range tests {
+		if got := FromJob(&tt.job).NextAttemptAt; got != tt.want {
+			t.Errorf("%s: NextAttemptAt = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
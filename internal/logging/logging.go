@@ -0,0 +1,37 @@
+// Package logging adds runtime-switchable debug output to the standard logger.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+var debug atomic.Bool
+
+// SetDebug turns debug output on or off.
+func SetDebug(on bool) {
+	debug.Store(on)
+}
+
+// Debug reports whether debug output is on.
+func Debug() bool {
+	return debug.Load()
+}
+
+// ToggleDebug flips debug output and returns the new state.
+func ToggleDebug() bool {
+	for {
+		old := debug.Load()
+		if debug.CompareAndSwap(old, !old) {
+			return !old
+		}
+	}
+}
+
+// Debugf logs like log.Printf, but only while debug output is on.
+func Debugf(format string, args ...any) {
+	if debug.Load() {
+		log.Output(2, "debug: "+fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestDebugf(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+	defer SetDebug(false)
+
+	SetDebug(false)
+	Debugf("hidden %d", 1)
+	if buf.Len() != 0 {
+		t.Errorf("logged while debug off: %q", buf.String())
+	}
+
+	if !ToggleDebug() || !Debug() {
+		t.Fatal("ToggleDebug() did not turn debug on")
+	}
+	Debugf("shown %d", 2)
+	if !strings.Contains(buf.String(), "debug: shown 2") {
+		t.Errorf("output = %q, want debug line", buf.String())
+	}
+
+	if ToggleDebug() || Debug() {
+		t.Error("ToggleDebug() did not turn debug off")
+	}
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// masked replaces secret values in masked text.
+const masked = "[secret]"
+
+// minSecretLen is the shortest value a Masker hides; shorter ones would
+// mangle unrelated text.
+const minSecretLen = 4
+
+// Masker hides known secret values, such as config settings and
+// interpolated environment variables, in text about to be logged or stored.
+// A nil Masker leaves text unchanged.
+type Masker struct {
+	r *strings.Replacer
+}
+
+// NewMasker creates a Masker for secrets. Empty and very short values are
+// ignored.
+func NewMasker(secrets ...string) *Masker {
+	var vals []string
+	for _, s := range secrets {
+		if len(s) >= minSecretLen {
+			vals = append(vals, s)
+		}
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	// Longest first, so a secret containing another is masked whole
+	sort.Slice(vals, func(i, j int) bool { return len(vals[i]) > len(vals[j]) })
+	pairs := make([]string, 0, 2*len(vals))
+	for _, s := range vals {
+		pairs = append(pairs, s, masked)
+	}
+	return &Masker{r: strings.NewReplacer(pairs...)}
+}
+
+// Mask returns s with every secret replaced.
+func (m *Masker) Mask(s string) string {
+	if m == nil {
+		return s
+	}
+	return m.r.Replace(s)
+}
+
+var secrets atomic.Pointer[Masker]
+
+// SetMasker sets the secrets hidden in all log output and by Mask, so logs
+// and job diagnostics are masked alike.
+func SetMasker(m *Masker) {
+	secrets.Store(m)
+}
+
+// Mask returns s with the secrets of the Masker set by SetMasker replaced.
+func Mask(s string) string {
+	return secrets.Load().Mask(s)
+}
+
+// Writer returns a writer passing text on to w with secrets masked. It
+// masks whole lines, so a secret split across writes is still caught;
+// Flush writes out a last line without a newline.
+func (m *Masker) Writer(w io.Writer) *MaskWriter {
+	return &MaskWriter{m: m, w: w}
+}
+
+// MaskWriter is a Masker's Writer.
+type MaskWriter struct {
+	m   *Masker
+	w   io.Writer
+	buf []byte
+}
+
+func (mw *MaskWriter) Write(p []byte) (int, error) {
+	mw.buf = append(mw.buf, p...)
+	if i := bytes.LastIndexByte(mw.buf, '\n'); i >= 0 {
+		if _, err := io.WriteString(mw.w, mw.m.Mask(string(mw.buf[:i+1]))); err != nil {
+			return 0, err
+		}
+		mw.buf = mw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes out what is left after the last newline.
+func (mw *MaskWriter) Flush() error {
+	if len(mw.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(mw.w, mw.m.Mask(string(mw.buf)))
+	mw.buf = nil
+	return err
+}
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestMasker_Mask(t *testing.T) {
+	m := NewMasker("tok3n-abc", "tok3n-abc-extended", "", "ab")
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"yt-dlp --password tok3n-abc {url}", "yt-dlp --password [secret] {url}"},
+		{"key=tok3n-abc-extended", "key=[secret]"},
+		{"ab stays, too short to mask", "ab stays, too short to mask"},
+		{"nothing to hide", "nothing to hide"},
+	}
+	for _, tt := range tests {
+		if got := m.Mask(tt.in); got != tt.want {
+			t.Errorf("Mask(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMasker_Nil(t *testing.T) {
+	m := NewMasker("", "x")
+	if m != nil {
+		t.Fatalf("NewMasker() = %v, want nil with no usable secrets", m)
+	}
+	if got := m.Mask("x y"); got != "x y" {
+		t.Errorf("nil Mask() = %q, want input unchanged", got)
+	}
+}
+
+func TestMasker_Writer(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewMasker("tok3n-abc").Writer(&buf)
+	for _, chunk := range []string{"--password tok", "3n-abc\nnext ", "line tok3n-", "abc"} {
+		w.Write([]byte(chunk))
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "--password [secret]\nnext line [secret]"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestSetMasker(t *testing.T) {
+	defer SetMasker(nil)
+	SetMasker(NewMasker("tok3n-abc"))
+
+	if got := Mask("key tok3n-abc"); got != "key [secret]" {
+		t.Errorf("Mask() = %q, want the secret masked", got)
+	}
+	var buf bytes.Buffer
+	log.New(Writer(&buf), "", 0).Printf("job 1: exec yt-dlp --password %s", "tok3n-abc")
+	if strings.Contains(buf.String(), "tok3n-abc") {
+		t.Errorf("log output = %q, want the secret masked", buf.String())
+	}
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+var redactURLs atomic.Bool
+
+// SetRedactURLs turns URL redaction in logs and error messages on or off.
+func SetRedactURLs(on bool) {
+	redactURLs.Store(on)
+}
+
+// RedactURLs reports whether URL redaction is on.
+func RedactURLs() bool {
+	return redactURLs.Load()
+}
+
+var (
+	// urlPattern finds URLs in free text such as command output.
+	urlPattern = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^\s"'<>]+`)
+	// tokenSegment matches path segments that look like keys or signatures
+	// rather than names: long, with no extension, mixing letters and digits.
+	tokenSegment = regexp.MustCompile(`^[A-Za-z0-9_-]{20,}$`)
+)
+
+// Redact replaces each URL in s with RedactURL's form while redaction is on.
+func Redact(s string) string {
+	if !redactURLs.Load() {
+		return s
+	}
+	return urlPattern.ReplaceAllStringFunc(s, func(u string) string {
+		// Leave sentence punctuation after the URL alone
+		trimmed := strings.TrimRight(u, ".,;:!?)]}")
+		return RedactURL(trimmed) + u[len(trimmed):]
+	})
+}
+
+// RedactURL returns raw without credentials, query, fragment, or token-like
+// path segments, tagged with a short hash of the original so log lines about
+// the same URL can still be matched up.
+func RedactURL(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	tag := "#" + hex.EncodeToString(sum[:4])
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "[url]" + tag
+	}
+	redacted := u.User != nil || u.RawQuery != "" || u.Fragment != ""
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, seg := range segments {
+		if tokenSegment.MatchString(seg) && strings.ContainsAny(seg, "0123456789") &&
+			strings.IndexFunc(seg, isLetter) >= 0 {
+			segments[i] = "…"
+			redacted = true
+		}
+	}
+
+	out := u.Scheme + "://" + u.Host + strings.Join(segments, "/")
+	if redacted {
+		out += "?…"
+	}
+	return out + tag
+}
+
+func isLetter(r rune) bool {
+	return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z'
+}
+
+// Writer filters log output through Mask and Redact. The log package
+// writes each entry in one call, so secrets and URLs are never split
+// across writes.
+func Writer(w io.Writer) io.Writer {
+	return redactWriter{w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (r redactWriter) Write(p []byte) (int, error) {
+	if !redactURLs.Load() && secrets.Load() == nil {
+		return r.w.Write(p)
+	}
+	if _, err := io.WriteString(r.w, Redact(Mask(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
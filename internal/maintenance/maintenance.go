@@ -0,0 +1,87 @@
+// Package maintenance runs periodic housekeeping tasks.
+package maintenance
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// Task is a named housekeeping step.
+type Task struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// Heavy tasks write enough to force large WAL checkpoints, and are
+	// skipped while the runner is paused.
+	Heavy bool
+}
+
+// Runner runs its tasks once at start and then on every interval.
+type Runner struct {
+	interval time.Duration
+	tasks    []Task
+	paused   func() bool
+	clock    domain.Clock
+}
+
+// New creates a runner that fires every interval.
+func New(interval time.Duration) *Runner {
+	return &Runner{interval: interval, clock: domain.SystemClock}
+}
+
+// Add registers a task. Call before Run.
+func (r *Runner) Add(name string, fn func(ctx context.Context) error) {
+	r.tasks = append(r.tasks, Task{Name: name, Run: fn})
+}
+
+// AddHeavy registers a heavy task. Call before Run.
+func (r *Runner) AddHeavy(name string, fn func(ctx context.Context) error) {
+	r.tasks = append(r.tasks, Task{Name: name, Run: fn, Heavy: true})
+}
+
+// SetPause skips heavy tasks whenever paused returns true, such as while
+// the database is being backed up. Call before Run.
+func (r *Runner) SetPause(paused func() bool) {
+	r.paused = paused
+}
+
+// SetClock makes the interval follow c. Call before Run.
+func (r *Runner) SetClock(c domain.Clock) {
+	r.clock = c
+}
+
+// Run executes all tasks until ctx is cancelled. A failing task is logged
+// and does not stop the others.
+func (r *Runner) Run(ctx context.Context) {
+	log.Printf("maintenance started, running %d task(s) every %s", len(r.tasks), r.interval)
+	ticker := r.clock.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.runOnce(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("maintenance stopped")
+			return
+		case <-ticker.C():
+			r.runOnce(ctx)
+		}
+	}
+}
+
+func (r *Runner) runOnce(ctx context.Context) {
+	for _, t := range r.tasks {
+		if ctx.Err() != nil {
+			return
+		}
+		if t.Heavy && r.paused != nil && r.paused() {
+			log.Printf("maintenance task %s skipped while paused", t.Name)
+			continue
+		}
+		if err := t.Run(ctx); err != nil {
+			log.Printf("maintenance task %s failed: %v", t.Name, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestRunner_RunsTasksImmediatelyAndOnInterval(t *testing.T) {
+	r := New(10 * time.Millisecond)
+
+	var ok, failing atomic.Int32
+	r.Add("failing", func(ctx context.Context) error {
+		failing.Add(1)
+		return errors.New("boom")
+	})
+	r.Add("ok", func(ctx context.Context) error {
+		ok.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 35*time.Millisecond)
+	defer cancel()
+	r.Run(ctx)
+
+	if ok.Load() < 2 {
+		t.Errorf("ok task ran %d times, want at least 2", ok.Load())
+	}
+	if failing.Load() != ok.Load() {
+		t.Errorf("failing task ran %d times, ok task %d; a failure must not skip later tasks", failing.Load(), ok.Load())
+	}
+}
+
+func TestRunner_FollowsClock(t *testing.T) {
+	r := New(time.Hour)
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.SetClock(clock)
+	runs := make(chan struct{}, 1)
+	r.Add("count", func(ctx context.Context) error {
+		runs <- struct{}{}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	wait := func(what string) {
+		t.Helper()
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatalf("task did not run %s", what)
+		}
+	}
+	wait("at start")
+	clock.Advance(time.Hour)
+	wait("once the clock passed the interval")
+}
+
+func TestRunner_StopsOnCancel(t *testing.T) {
+	r := New(time.Hour)
+	var runs atomic.Int32
+	r.Add("count", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	if runs.Load() != 1 {
+		t.Errorf("runs = %d, want 1", runs.Load())
+	}
+}
+
+func TestRunner_PauseSkipsHeavyTasks(t *testing.T) {
+	r := New(10 * time.Millisecond)
+	var paused atomic.Bool
+	paused.Store(true)
+	r.SetPause(paused.Load)
+
+	var light, heavy atomic.Int32
+	r.Add("light", func(ctx context.Context) error {
+		light.Add(1)
+		return nil
+	})
+	r.AddHeavy("heavy", func(ctx context.Context) error {
+		heavy.Add(1)
+		return nil
+	})
+
+	ctx := context.Background()
+	r.runOnce(ctx)
+	if light.Load() != 1 || heavy.Load() != 0 {
+		t.Errorf("while paused: light ran %d, heavy %d times, want 1 and 0", light.Load(), heavy.Load())
+	}
+	paused.Store(false)
+	r.runOnce(ctx)
+	if light.Load() != 2 || heavy.Load() != 1 {
+		t.Errorf("after pause: light ran %d, heavy %d times, want 2 and 1", light.Load(), heavy.Load())
+	}
+}
@@ -0,0 +1,109 @@
+// Package netaddr interprets the network addresses in catcher's
+// settings: the local addresses that downloads and catcher's own requests
+// connect from, and the name servers they ask.
+package netaddr
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Source returns source if it is an IP address, or else the address of
+// the network interface it names: of IP version forceIP if set, else IPv4
+// preferred. The interface is looked up on every call, so callers that
+// call it per connection or run follow an address that changes, e.g. by
+// DHCP. An empty source stays empty.
+func Source(source, forceIP string) (string, error) {
+	if source == "" || net.ParseIP(source) != nil {
+		return source, nil
+	}
+	iface, err := net.InterfaceByName(source)
+	if err != nil {
+		return "", fmt.Errorf("source interface %s: %w", source, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("source interface %s: %w", source, err)
+	}
+	var v6 net.IP
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
+			if forceIP != "6" {
+				return ipnet.IP.String(), nil
+			}
+		} else if v6 == nil {
+			v6 = ipnet.IP
+		}
+	}
+	if v6 == nil || forceIP == "4" {
+		return "", fmt.Errorf("source interface %s has no usable %saddress", source, ipVersionLabel(forceIP))
+	}
+	return v6.String(), nil
+}
+
+// ipVersionLabel names IP version forceIP in messages, with a trailing
+// space, or returns "" if none is forced.
+func ipVersionLabel(forceIP string) string {
+	if forceIP == "" {
+		return ""
+	}
+	return "IPv" + forceIP + " "
+}
+
+// ValidSource reports whether source is empty, an IP address, or
+// could name a network interface.
+func ValidSource(source string) bool {
+	if source == "" || net.ParseIP(source) != nil {
+		return true
+	}
+	return !strings.HasPrefix(source, "-") && !strings.ContainsFunc(source, func(r rune) bool {
+		return r <= ' ' || r == '/' || r == 0x7f
+	})
+}
+
+// DNSServer returns server, an IP address with or without a port, as
+// ip:port, with port 53 if it has none.
+func DNSServer(server string) (string, error) {
+	host, port := server, "53"
+	if h, p, err := net.SplitHostPort(server); err == nil {
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid port in %q", server)
+		}
+		host, port = h, p
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("%q is not an IP address or ip:port", server)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// NameResolver returns a resolver asking servers, as DNSServer reads them,
+// in turn, so its retries move on to the next server. Servers DNSServer
+// rejects are skipped, and without any left it returns nil, the system's
+// resolver.
+func NameResolver(servers []string, timeout time.Duration) *net.Resolver {
+	var addrs []string
+	for _, s := range servers {
+		if addr, err := DNSServer(s); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+	var next atomic.Uint32
+	dialer := &net.Dialer{Timeout: timeout}
+	return &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		addr := addrs[int(next.Add(1)-1)%len(addrs)]
+		return dialer.DialContext(ctx, network, addr)
+	}}
+}
@@ -0,0 +1,88 @@
+package netaddr
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSource(t *testing.T) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loopback := ""
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			loopback = iface.Name
+			break
+		}
+	}
+
+	for _, source := range []string{"", "192.0.2.7", "2001:db8::7"} {
+		if got, err := Source(source, ""); err != nil || got != source {
+			t.Errorf("Source(%q) = %q, %v, want it unchanged", source, got, err)
+		}
+	}
+	if _, err := Source("no-such-interface0", ""); err == nil {
+		t.Error("Source() of an unknown interface succeeded")
+	}
+	if loopback == "" {
+		t.Skip("no loopback interface")
+	}
+	got, err := Source(loopback, "4")
+	if err != nil || !net.ParseIP(got).IsLoopback() || net.ParseIP(got).To4() == nil {
+		t.Errorf("Source(%q, 4) = %q, %v, want an IPv4 loopback address", loopback, got, err)
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	tests := []struct {
+		server  string
+		want    string
+		wantErr bool
+	}{
+		{server: "1.1.1.1", want: "1.1.1.1:53"},
+		{server: "9.9.9.9:5353", want: "9.9.9.9:5353"},
+		{server: "2606:4700::1111", want: "[2606:4700::1111]:53"},
+		{server: "[2606:4700::1111]:853", want: "[2606:4700::1111]:853"},
+		{server: "dns.example", wantErr: true},
+		{server: "8.8.8.8:0", wantErr: true},
+		{server: "[::1]", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := DNSServer(tt.server)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("DNSServer(%q) = %q, %v, want %q (error %v)", tt.server, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNameResolver(t *testing.T) {
+	var addrs []string
+	for range 2 {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		addrs = append(addrs, ln.Addr().String())
+	}
+
+	resolver := NameResolver(append([]string{"not-an-ip"}, addrs...), time.Second)
+	for i, want := range []string{addrs[0], addrs[1], addrs[0]} {
+		conn, err := resolver.Dial(context.Background(), "tcp", "192.0.2.1:53")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := conn.RemoteAddr().String(); got != want {
+			t.Errorf("dial %d connected to %s, want %s", i, got, want)
+		}
+		conn.Close()
+	}
+
+	if NameResolver([]string{"not-an-ip"}, time.Second) != nil {
+		t.Error("NameResolver() without valid servers isn't the system's")
+	}
+}
@@ -0,0 +1,233 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" at version 1-M, from the QR code specification's
+	// worked example
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !slices.Equal(got, want) {
+		t.Errorf("rsRemainder() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	want := []string{
+		"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+		"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+	}
+	for mask, w := range want {
+		if got := fmt.Sprintf("%015b", formatBits(mask)); got != w {
+			t.Errorf("formatBits(%d) = %s, want %s", mask, got, w)
+		}
+	}
+}
+
+func TestVersionBits(t *testing.T) {
+	tests := []struct {
+		version int
+		want    string
+	}{
+		{7, "000111110010010100"},
+		{8, "001000010110111100"},
+		{9, "001001101010011001"},
+		{10, "001010010011010011"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf("%018b", versionBits(tt.version)); got != tt.want {
+			t.Errorf("versionBits(%d) = %s, want %s", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	want := []int{14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+	for v := 1; v <= maxVersion; v++ {
+		if got := (8*dataCodewords(v) - 4 - countBits(v)) / 8; got != want[v-1] {
+			t.Errorf("version %d holds %d bytes, want %d", v, got, want[v-1])
+		}
+	}
+	if got := alignmentPositions(7); !slices.Equal(got, []int{6, 22, 38}) {
+		t.Errorf("alignmentPositions(7) = %v, want [6 22 38]", got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{1, 1},
+		{14, 1},
+		{15, 2},
+		{62, 4},
+		{100, 6},
+		{150, 8},
+		{213, 10},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.length), func(t *testing.T) {
+			text := strings.Repeat("https://catcher.test/share?token=", 7)[:tt.length]
+			c, err := Encode(text)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if want := tt.version*4 + 17; c.Size() != want {
+				t.Errorf("Size() = %d, want %d (version %d)", c.Size(), want, tt.version)
+			}
+			if got := decode(t, c); got != text {
+				t.Errorf("decoded %q, want %q", got, text)
+			}
+		})
+	}
+
+	if _, err := Encode(strings.Repeat("a", 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode() of 214 bytes error = %v, want ErrTooLong", err)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, err := Encode("https://catcher.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := c.SVG(4)
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 33 33" width="132" height="132"`) {
+		t.Errorf("SVG() = %.100s..., want a 33-module square at 4px with the quiet zone", svg)
+	}
+	if !strings.Contains(svg, "M4 4h1v1h-1z") {
+		t.Error("SVG() lacks the top-left finder's corner module")
+	}
+}
+
+// decode reads text back from c the way a scanner would: the mask from the
+// format bits, then the codewords in placement order, checking every
+// block's error correction.
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	version := (c.Size() - 17) / 4
+
+	bits := 0
+	read := func(x, y, i int) {
+		if c.Dark(x, y) {
+			bits |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		read(8, i, i)
+	}
+	read(8, 7, 6)
+	read(8, 8, 7)
+	read(7, 8, 8)
+	for i := 9; i < 15; i++ {
+		read(14-i, 8, i)
+	}
+	mask := slices.IndexFunc([]int{0, 1, 2, 3, 4, 5, 6, 7}, func(m int) bool { return formatBits(m) == bits })
+	if mask < 0 {
+		t.Fatalf("format bits %015b match no mask at level M", bits)
+	}
+
+	// Every function pattern, the second copy of the format bits included,
+	// must be where a fresh code of the version puts it
+	ref := newCode(version)
+	ref.drawFunctionPatterns()
+	ref.drawFormatBits(mask)
+	for y := range c.Size() {
+		for x := range c.Size() {
+			if ref.function[y][x] && c.Dark(x, y) != ref.modules[y][x] {
+				t.Fatalf("function module at %d,%d is wrong", x, y)
+			}
+		}
+	}
+
+	unmasked := newCode(version)
+	unmasked.function = ref.function
+	for y := range c.Size() {
+		copy(unmasked.modules[y], c.modules[y])
+	}
+	unmasked.applyMask(mask)
+
+	var codewords []byte
+	var cur byte
+	n := 0
+	for right := c.Size() - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size() {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size() - 1 - vert
+			}
+			for _, x := range []int{right, right - 1} {
+				if ref.function[y][x] {
+					continue
+				}
+				cur <<= 1
+				if unmasked.modules[y][x] {
+					cur |= 1
+				}
+				if n++; n%8 == 0 {
+					codewords = append(codewords, cur)
+				}
+			}
+		}
+	}
+	raw := rawModules(version) / 8
+	codewords = codewords[:raw]
+
+	// De-interleave: data codewords round-robin across blocks, the longer
+	// blocks last, then error correction codewords likewise
+	blocks, eccLen := eccBlocks[version], eccPerBlock[version]
+	short := blocks - raw%blocks
+	shortData := raw/blocks - eccLen
+	data := make([][]byte, blocks)
+	ecc := make([][]byte, blocks)
+	k := 0
+	for i := range shortData + 1 {
+		for j := range blocks {
+			if i < shortData || j >= short {
+				data[j] = append(data[j], codewords[k])
+				k++
+			}
+		}
+	}
+	for range eccLen {
+		for j := range blocks {
+			ecc[j] = append(ecc[j], codewords[k])
+			k++
+		}
+	}
+	divisor := rsDivisor(eccLen)
+	for j := range blocks {
+		if !slices.Equal(rsRemainder(data[j], divisor), ecc[j]) {
+			t.Fatalf("block %d fails its error correction check", j)
+		}
+	}
+
+	stream := slices.Concat(data...)
+	bit := func(i int) int { return int(stream[i/8]>>(7-i%8)) & 1 }
+	take := func(at, n int) int {
+		v := 0
+		for i := range n {
+			v = v<<1 | bit(at+i)
+		}
+		return v
+	}
+	if mode := take(0, 4); mode != 0b0100 {
+		t.Fatalf("mode = %04b, want byte mode", mode)
+	}
+	length := take(4, countBits(version))
+	text := make([]byte, length)
+	for i := range text {
+		text[i] = byte(take(4+countBits(version)+8*i, 8))
+	}
+	return string(text)
+}
@@ -0,0 +1,100 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/event"
+)
+
+// Submission is a URL to queue, with the webhook's optional fields.
+type Submission struct {
+	URL    string `json:"url"`
+	Hold   bool   `json:"hold,omitempty"`
+	Source string `json:"source,omitempty"`
+	Notes  string `json:"notes,omitempty"`
+}
+
+// APIError is an error response from the server.
+type APIError struct {
+	Status  int
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned %d", e.Status)
+	}
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+}
+
+// Client sends requests to the server of a context.
+type Client struct {
+	ctx  Context
+	http *http.Client
+	now  func() time.Time
+}
+
+// NewClient creates a client for ctx.
+func NewClient(ctx Context) *Client {
+	return &Client{ctx: ctx, http: &http.Client{Timeout: 30 * time.Second}, now: time.Now}
+}
+
+// Submit queues s.URL through POST /webhook, signed with the context's
+// secret. An empty s.Source takes the context's.
+func (c *Client) Submit(ctx context.Context, s Submission) (*event.Job, error) {
+	if s.Source == "" {
+		s.Source = c.ctx.Source
+	}
+	body, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.ctx.URL+"/webhook", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.ctx.Secret != "" {
+		timestamp := c.now().UTC().Format(time.RFC3339)
+		req.Header.Set("X-Timestamp", timestamp)
+		req.Header.Set("X-Signature", sign(timestamp, body, c.ctx.Secret))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		var er struct {
+			Error APIError `json:"error"`
+		}
+		json.Unmarshal(data, &er)
+		er.Error.Status = resp.StatusCode
+		return nil, &er.Error
+	}
+	var job event.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &job, nil
+}
+
+// sign returns the webhook signature: SHA256("${timestamp}\n${body}\n${secret}").
+func sign(timestamp string, body []byte, secret string) string {
+	hash := sha256.Sum256([]byte(timestamp + "\n" + string(body) + "\n" + secret))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,63 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	httpAdapter "github.com/cwygoda/catcher/internal/adapter/http"
+	"github.com/cwygoda/catcher/internal/adapter/sqlite"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func newTestServer(t *testing.T, secret string) (*httptest.Server, *domain.JobService) {
+	t.Helper()
+	repo, err := sqlite.New(filepath.Join(t.TempDir(), "catcher.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	svc := domain.NewJobService(repo)
+	srv := httpAdapter.NewServer(svc, ":0", secret)
+	srv.SetBasePath("/catcher")
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts, svc
+}
+
+func TestClient_Submit(t *testing.T) {
+	ts, svc := newTestServer(t, "webhook-secret")
+	ctx := context.Background()
+
+	c := NewClient(Context{URL: ts.URL + "/catcher", Secret: "webhook-secret", Source: "nas-cli"})
+	job, err := c.Submit(ctx, Submission{URL: "https://example.com/a", Notes: "later"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if job.URL != "https://example.com/a" || job.Status != string(domain.StatusPending) || job.Source != "nas-cli" {
+		t.Errorf("Submit() = %+v, want a pending job from the context's source", job)
+	}
+	if stored, err := svc.Get(ctx, job.ID); err != nil || stored.Notes != "later" {
+		t.Errorf("stored job = %+v, %v; want the notes", stored, err)
+	}
+
+	job, err = c.Submit(ctx, Submission{URL: "https://example.com/b", Source: "override"})
+	if err != nil || job.Source != "override" {
+		t.Errorf("Submit() with a source = %+v, %v; want it over the context's", job, err)
+	}
+
+	var apiErr *APIError
+	_, err = c.Submit(ctx, Submission{URL: "not a url"})
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest || apiErr.Code != httpAdapter.CodeInvalidURL {
+		t.Errorf("invalid Submit() error = %v, want a 400 %s", err, httpAdapter.CodeInvalidURL)
+	}
+
+	wrong := NewClient(Context{URL: ts.URL + "/catcher", Secret: "other"})
+	_, err = wrong.Submit(ctx, Submission{URL: "https://example.com/c"})
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Errorf("Submit() with the wrong secret error = %v, want a 401", err)
+	}
+}
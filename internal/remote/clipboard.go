@@ -0,0 +1,147 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/urlscan"
+)
+
+// ErrNoClipboard is returned when no clipboard command is installed.
+var ErrNoClipboard = errors.New("no clipboard command found: install wl-clipboard, xclip, or xsel")
+
+// ReadFunc returns the clipboard's text.
+type ReadFunc func(ctx context.Context) (string, error)
+
+// clipboardCommands are the commands that print the clipboard, by platform,
+// in order of preference.
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		return [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}}
+	}
+	var cmds [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmds = append(cmds, []string{"wl-paste", "--no-newline", "--type", "text"})
+	}
+	return append(cmds,
+		[]string{"xclip", "-selection", "clipboard", "-out"},
+		[]string{"xsel", "--clipboard", "--output"})
+}
+
+// SystemClipboard returns a ReadFunc for the first installed clipboard
+// command: pbpaste on macOS, PowerShell on Windows, and wl-paste, xclip, or
+// xsel elsewhere. A command that fails, as wl-paste and xclip do on an
+// empty or non-text clipboard, returns an error with what it printed.
+func SystemClipboard() (ReadFunc, error) {
+	for _, args := range clipboardCommands() {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		return func(ctx context.Context) (string, error) {
+			out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+					return "", fmt.Errorf("%s: %w: %s", args[0], err, msg)
+				}
+				return "", fmt.Errorf("%s: %w", args[0], err)
+			}
+			if err != nil {
+				return "", err
+			}
+			return string(out), nil
+		}, nil
+	}
+	return nil, ErrNoClipboard
+}
+
+// ClipboardWatcher polls the clipboard for URLs. What is on the clipboard
+// when it starts is ignored, as is a URL already found, so copying it
+// again doesn't submit it twice.
+type ClipboardWatcher struct {
+	read     ReadFunc
+	pattern  *regexp.Regexp // nil matches every URL
+	interval time.Duration
+	seen     map[string]bool
+}
+
+// NewClipboardWatcher returns a watcher reading the clipboard with read
+// every interval for URLs matching pattern, or every http and https URL if
+// pattern is empty.
+func NewClipboardWatcher(read ReadFunc, interval time.Duration, pattern string) (*ClipboardWatcher, error) {
+	w := &ClipboardWatcher{read: read, interval: interval, seen: map[string]bool{}}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		w.pattern = re
+	}
+	return w, nil
+}
+
+// Watch calls found with each new URL copied, until ctx is done. found
+// runs on Watch's goroutine, so the clipboard isn't read while it, say,
+// asks the user. Failed reads are retried, and passed to onError once
+// until a read succeeds or fails differently, so an empty clipboard that
+// the command fails on isn't reported on every poll.
+func (w *ClipboardWatcher) Watch(ctx context.Context, found func(url string), onError func(error)) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	var reported string
+	read := func() (string, error) {
+		text, err := w.read(ctx)
+		switch {
+		case err == nil:
+			reported = ""
+		case ctx.Err() == nil && err.Error() != reported:
+			reported = err.Error()
+			onError(err)
+		}
+		return text, err
+	}
+	last, _ := read()
+	w.Match(last)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		text, err := read()
+		if err != nil {
+			continue
+		}
+		if text == last {
+			continue
+		}
+		last = text
+		for _, url := range w.Match(text) {
+			found(url)
+		}
+	}
+}
+
+// Match returns the URLs in text that match the watcher's pattern and
+// haven't been matched before, and remembers them.
+func (w *ClipboardWatcher) Match(text string) []string {
+	var urls []string
+	for _, url := range urlscan.Find(text) {
+		if w.seen[url] || (w.pattern != nil && !w.pattern.MatchString(url)) {
+			continue
+		}
+		w.seen[url] = true
+		urls = append(urls, url)
+	}
+	return urls
+}
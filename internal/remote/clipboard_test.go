@@ -0,0 +1,135 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClipboardWatcher_Match(t *testing.T) {
+	w, err := NewClipboardWatcher(nil, time.Second, `^https://(www\.)?youtube\.com/watch`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := w.Match("see https://www.youtube.com/watch?v=1, and https://example.com/x (https://youtube.com/watch?v=2)")
+	if want := []string{"https://www.youtube.com/watch?v=1", "https://youtube.com/watch?v=2"}; !slices.Equal(got, want) {
+		t.Errorf("Match() = %q, want %q", got, want)
+	}
+	if got := w.Match("https://youtube.com/watch?v=2"); got != nil {
+		t.Errorf("Match() of a URL seen before = %q, want none", got)
+	}
+
+	all, _ := NewClipboardWatcher(nil, time.Second, "")
+	if got := all.Match("ftp://a http://example.com/a"); !slices.Equal(got, []string{"http://example.com/a"}) {
+		t.Errorf("Match() without a pattern = %q, want every http URL", got)
+	}
+
+	if _, err := NewClipboardWatcher(nil, time.Second, "("); err == nil {
+		t.Error("NewClipboardWatcher() with an invalid pattern succeeded")
+	}
+}
+
+func TestClipboardWatcher_Watch(t *testing.T) {
+	var mu sync.Mutex
+	clips := []string{"https://example.com/before", "https://example.com/before", "", "read error", "https://example.com/a", "https://example.com/a", "text", "https://example.com/a https://example.com/b"}
+	read := func(context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(clips) == 0 {
+			return "", nil
+		}
+		clip := clips[0]
+		clips = clips[1:]
+		if clip == "read error" {
+			return "", errors.New("clipboard unavailable")
+		}
+		return clip, nil
+	}
+	w, err := NewClipboardWatcher(read, time.Millisecond, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var found []string
+	var errs int
+	w.Watch(ctx, func(url string) {
+		found = append(found, url)
+		if len(found) == 2 {
+			cancel()
+		}
+	}, func(error) { errs++ })
+
+	// What was copied before watching started is left alone
+	if want := []string{"https://example.com/a", "https://example.com/b"}; !slices.Equal(found, want) {
+		t.Errorf("found %q, want %q", found, want)
+	}
+	if errs != 1 {
+		t.Errorf("%d read errors reported, want 1", errs)
+	}
+}
+
+func TestClipboardWatcher_WatchRepeatedErrors(t *testing.T) {
+	var mu sync.Mutex
+	reads := []string{"", "empty", "empty", "empty", "", "empty", "gone", "gone", "https://example.com/a"}
+	read := func(context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(reads) == 0 {
+			return "", nil
+		}
+		r := reads[0]
+		reads = reads[1:]
+		switch r {
+		case "empty":
+			return "", errors.New("wl-paste: exit status 1: Nothing is copied")
+		case "gone":
+			return "", errors.New("wl-paste: exit status 1: Wayland connection lost")
+		}
+		return r, nil
+	}
+	w, _ := NewClipboardWatcher(read, time.Millisecond, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var errs []string
+	w.Watch(ctx, func(string) { cancel() }, func(err error) { errs = append(errs, err.Error()) })
+
+	want := []string{
+		"wl-paste: exit status 1: Nothing is copied",
+		"wl-paste: exit status 1: Nothing is copied",
+		"wl-paste: exit status 1: Wayland connection lost",
+	}
+	if !slices.Equal(errs, want) {
+		t.Errorf("reported %q, want each failure once until a read succeeds or fails differently", errs)
+	}
+}
+
+func TestSystemClipboard_Fails(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("uses wl-paste")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho 'Nothing is copied' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "wl-paste"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+
+	read, err := SystemClipboard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := read(context.Background())
+	if err == nil || err.Error() != "wl-paste: exit status 1: Nothing is copied" || text != "" {
+		t.Errorf("read() = %q, %v; want the command's failure", text, err)
+	}
+}
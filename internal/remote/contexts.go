@@ -0,0 +1,154 @@
+// Package remote talks to catcher servers over HTTP, for the CLI's remote
+// commands, and keeps the named contexts that say which server to use.
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/cwygoda/catcher/internal/config"
+)
+
+var (
+	// ErrNoContext is returned when no context is named and none is
+	// current.
+	ErrNoContext = errors.New("no context selected")
+	// ErrUnknownContext is returned for a context name not in the file.
+	ErrUnknownContext = errors.New("unknown context")
+)
+
+// Context is a server to send commands to.
+type Context struct {
+	// URL is the server's base URL, base path included, e.g.
+	// "https://nas.lan/catcher".
+	URL string `toml:"url"`
+	// Secret signs webhook submissions; empty for servers without one.
+	Secret string `toml:"secret,omitempty"`
+	// Source is the default source of jobs submitted through the context.
+	Source string `toml:"source,omitempty"`
+	// Clipboard is a regular expression for the copied URLs that
+	// "catcher watch-clipboard" submits; empty means every URL.
+	Clipboard string `toml:"clipboard,omitempty"`
+}
+
+// Contexts is the contexts file: the named contexts and the one used when
+// none is named.
+type Contexts struct {
+	Current  string             `toml:"current,omitempty"`
+	Contexts map[string]Context `toml:"context"`
+}
+
+// DefaultPath returns the contexts file's path, next to the default config.
+func DefaultPath() string {
+	return filepath.Join(filepath.Dir(config.DefaultConfigPath()), "contexts.toml")
+}
+
+// Load reads the contexts file at path. A missing file holds no contexts.
+func Load(path string) (*Contexts, error) {
+	c := &Contexts{Contexts: map[string]Context{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(string(data), c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Contexts == nil {
+		c.Contexts = map[string]Context{}
+	}
+	return c, nil
+}
+
+// Save writes the contexts to path, readable only by the owner since they
+// hold secrets.
+func (c *Contexts) Save(path string) error {
+	var b bytes.Buffer
+	if err := toml.NewEncoder(&b).Encode(c); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Set adds or replaces the context name. The first context added becomes
+// current.
+func (c *Contexts) Set(name string, ctx Context) error {
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("invalid context name %q", name)
+	}
+	if !strings.HasPrefix(ctx.URL, "http://") && !strings.HasPrefix(ctx.URL, "https://") {
+		return fmt.Errorf("context %s: url must start with http:// or https://", name)
+	}
+	if _, err := regexp.Compile(ctx.Clipboard); err != nil {
+		return fmt.Errorf("context %s: clipboard pattern: %w", name, err)
+	}
+	ctx.URL = strings.TrimRight(ctx.URL, "/")
+	c.Contexts[name] = ctx
+	if c.Current == "" {
+		c.Current = name
+	}
+	return nil
+}
+
+// Use makes name the current context.
+func (c *Contexts) Use(name string) error {
+	if _, ok := c.Contexts[name]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownContext, name)
+	}
+	c.Current = name
+	return nil
+}
+
+// Remove deletes the context name, clearing the current context if it was.
+func (c *Contexts) Remove(name string) error {
+	if _, ok := c.Contexts[name]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownContext, name)
+	}
+	delete(c.Contexts, name)
+	if c.Current == name {
+		c.Current = ""
+	}
+	return nil
+}
+
+// Names returns the context names, sorted.
+func (c *Contexts) Names() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Resolve returns the context name, or the current one when name is empty.
+func (c *Contexts) Resolve(name string) (string, Context, error) {
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		return "", Context{}, ErrNoContext
+	}
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return "", Context{}, fmt.Errorf("%w %q", ErrUnknownContext, name)
+	}
+	return name, ctx, nil
+}
@@ -0,0 +1,81 @@
+package remote
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestContexts_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catcher", "contexts.toml")
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of a missing file error = %v", err)
+	}
+	if _, _, err := c.Resolve(""); !errors.Is(err, ErrNoContext) {
+		t.Errorf("Resolve() with no contexts error = %v, want ErrNoContext", err)
+	}
+
+	if err := c.Set("nas", Context{URL: "https://nas.lan/catcher/", Secret: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("laptop", Context{URL: "http://localhost:8080", Source: "me"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("saved file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	c, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := c.Names(); !slices.Equal(got, []string{"laptop", "nas"}) {
+		t.Errorf("Names() = %v, want [laptop nas]", got)
+	}
+	// The first context added is current, its URL without the trailing slash
+	name, ctx, err := c.Resolve("")
+	if err != nil || name != "nas" || ctx != (Context{URL: "https://nas.lan/catcher", Secret: "s1"}) {
+		t.Errorf("Resolve(\"\") = %q, %+v, %v; want nas", name, ctx, err)
+	}
+	if name, _, _ := c.Resolve("laptop"); name != "laptop" {
+		t.Errorf("Resolve(laptop) = %q, want the named context over the current one", name)
+	}
+
+	if err := c.Use("laptop"); err != nil || c.Current != "laptop" {
+		t.Errorf("Use(laptop) = %v, current %q", err, c.Current)
+	}
+	if err := c.Remove("laptop"); err != nil || c.Current != "" {
+		t.Errorf("Remove(current) = %v, current %q; want none current", err, c.Current)
+	}
+	if _, _, err := c.Resolve("laptop"); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("Resolve(removed) error = %v, want ErrUnknownContext", err)
+	}
+}
+
+func TestContexts_SetInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  Context
+	}{
+		{"", Context{URL: "http://a"}},
+		{"two words", Context{URL: "http://a"}},
+		{"nas", Context{URL: "nas.lan:8080"}},
+		{"nas", Context{URL: "http://a", Clipboard: "youtube\\.com/(watch"}},
+	}
+	for _, tt := range tests {
+		c := &Contexts{Contexts: map[string]Context{}}
+		if err := c.Set(tt.name, tt.ctx); err == nil {
+			t.Errorf("Set(%q, %+v) succeeded, want an error", tt.name, tt.ctx)
+		}
+	}
+	c := &Contexts{Contexts: map[string]Context{}}
+	if err := c.Use("nas"); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("Use(unknown) error = %v, want ErrUnknownContext", err)
+	}
+}
@@ -0,0 +1,225 @@
+package setup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ErrUnsupported is returned on platforms without a supported service manager.
+var ErrUnsupported = errors.New("no supported service manager")
+
+// Service managers.
+const (
+	Systemd = "systemd"
+	Launchd = "launchd"
+)
+
+// serviceLabel names the launchd job, matching the system LaunchDaemon.
+const serviceLabel = "com.cwygoda.catcher"
+
+// Service is a per-user background service running catcher.
+type Service struct {
+	Manager string            // Systemd or Launchd
+	Path    string            // unit or plist file
+	Args    []string          // catcher binary and flags
+	Env     map[string]string // environment for the service
+	LogDir  string            // launchd only; systemd logs go to the journal
+}
+
+// NewService describes the user service for goos running args.
+func NewService(goos string, args []string, env map[string]string) (*Service, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	switch goos {
+	case "linux":
+		configDir := os.Getenv("XDG_CONFIG_HOME")
+		if configDir == "" {
+			configDir = filepath.Join(home, ".config")
+		}
+		return &Service{
+			Manager: Systemd,
+			Path:    filepath.Join(configDir, "systemd", "user", "catcher.service"),
+			Args:    args,
+			Env:     env,
+		}, nil
+	case "darwin":
+		return &Service{
+			Manager: Launchd,
+			Path:    filepath.Join(home, "Library", "LaunchAgents", serviceLabel+".plist"),
+			Args:    args,
+			Env:     env,
+			LogDir:  filepath.Join(home, "Library", "Logs", "catcher"),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w on %s", ErrUnsupported, goos)
+}
+
+// envVar is one environment entry, for templates that need a stable order.
+type envVar struct{ Key, Value string }
+
+func (s *Service) sortedEnv() []envVar {
+	vars := make([]envVar, 0, len(s.Env))
+	for k, v := range s.Env {
+		vars = append(vars, envVar{k, v})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Key < vars[j].Key })
+	return vars
+}
+
+var unitTemplate = template.Must(template.New("unit").Funcs(template.FuncMap{"arg": unitArg}).Parse(`# Generated by "catcher install-service".
+[Unit]
+Description=Catcher URL processing queue
+After=network-online.target
+
+[Service]
+ExecStart={{range $i, $a := .Args}}{{if $i}} {{end}}{{arg $a}}{{end}}
+{{- range .Env}}
+Environment={{arg (print .Key "=" .Value)}}
+{{- end}}
+Restart=on-failure
+TimeoutStopSec=30
+
+[Install]
+WantedBy=default.target
+`))
+
+var plistTemplate = template.Must(template.New("plist").Funcs(template.FuncMap{"xml": xmlText}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<!-- Generated by "catcher install-service". -->
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>{{.Label}}</string>
+
+    <key>ProgramArguments</key>
+    <array>
+{{- range .Args}}
+        <string>{{xml .}}</string>
+{{- end}}
+    </array>
+{{- if .Env}}
+
+    <key>EnvironmentVariables</key>
+    <dict>
+{{- range .Env}}
+        <key>{{xml .Key}}</key>
+        <string>{{xml .Value}}</string>
+{{- end}}
+    </dict>
+{{- end}}
+
+    <key>RunAtLoad</key>
+    <true/>
+
+    <key>KeepAlive</key>
+    <dict>
+        <key>SuccessfulExit</key>
+        <false/>
+    </dict>
+
+    <key>ExitTimeOut</key>
+    <integer>30</integer>
+
+    <key>StandardOutPath</key>
+    <string>{{xml .LogDir}}/catcher.log</string>
+
+    <key>StandardErrorPath</key>
+    <string>{{xml .LogDir}}/catcher.err</string>
+</dict>
+</plist>
+`))
+
+// Render returns the unit or plist file contents.
+func (s *Service) Render() []byte {
+	data := struct {
+		Label  string
+		Args   []string
+		Env    []envVar
+		LogDir string
+	}{serviceLabel, s.Args, s.sortedEnv(), s.LogDir}
+
+	var b bytes.Buffer
+	if s.Manager == Launchd {
+		plistTemplate.Execute(&b, data)
+	} else {
+		unitTemplate.Execute(&b, data)
+	}
+	return b.Bytes()
+}
+
+// Write writes the service file, replacing any previous one, and creates
+// the log directory.
+func (s *Service) Write() error {
+	if s.LogDir != "" {
+		if err := os.MkdirAll(s.LogDir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.Path, s.Render(), 0o644)
+}
+
+// StartCommands returns the commands that (re)load and start the service.
+func (s *Service) StartCommands() [][]string {
+	if s.Manager == Launchd {
+		domain := "gui/" + strconv.Itoa(os.Getuid())
+		return [][]string{
+			{"launchctl", "bootout", domain + "/" + serviceLabel},
+			{"launchctl", "bootstrap", domain, s.Path},
+		}
+	}
+	return [][]string{
+		{"systemctl", "--user", "daemon-reload"},
+		{"systemctl", "--user", "enable", "catcher.service"},
+		{"systemctl", "--user", "restart", "catcher.service"},
+	}
+}
+
+// Start runs StartCommands. Unloading a launchd job that isn't loaded yet
+// is expected to fail and is ignored.
+func (s *Service) Start() error {
+	for i, args := range s.StartCommands() {
+		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil && !(s.Manager == Launchd && i == 0) {
+			return fmt.Errorf("%s: %w: %s", strings.Join(args, " "), err, bytes.TrimSpace(out))
+		}
+	}
+	return nil
+}
+
+// LogHint tells the user where the service logs.
+func (s *Service) LogHint() string {
+	if s.Manager == Launchd {
+		return "logs: " + filepath.Join(s.LogDir, "catcher.err")
+	}
+	return "logs: journalctl --user -u catcher"
+}
+
+// unitArg quotes s for a systemd unit line when it needs it, and escapes
+// specifiers.
+func unitArg(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package setup
+
+import (
+	"encoding/xml"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewService(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	tests := []struct {
+		goos        string
+		wantManager string
+		wantPath    string
+		wantErr     error
+	}{
+		{"linux", Systemd, "/home/u/.config/systemd/user/catcher.service", nil},
+		{"darwin", Launchd, "/home/u/Library/LaunchAgents/com.cwygoda.catcher.plist", nil},
+		{"windows", "", "", ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			svc, err := NewService(tt.goos, []string{"catcher"}, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewService() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if svc.Manager != tt.wantManager || svc.Path != filepath.FromSlash(tt.wantPath) {
+				t.Errorf("NewService() = %s at %s, want %s at %s", svc.Manager, svc.Path, tt.wantManager, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestService_RenderSystemd(t *testing.T) {
+	svc := &Service{
+		Manager: Systemd,
+		Args:    []string{"/usr/local/bin/catcher", "--config", "/home/u/my config.toml", "--db", "/home/u/50%/jobs.db"},
+		Env:     map[string]string{"PATH": "/usr/bin:/bin"},
+	}
+	out := string(svc.Render())
+	for _, want := range []string{
+		`ExecStart=/usr/local/bin/catcher --config "/home/u/my config.toml" --db /home/u/50%%/jobs.db` + "\n",
+		"Environment=PATH=/usr/bin:/bin\n",
+		"WantedBy=default.target\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("unit missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestService_RenderLaunchd(t *testing.T) {
+	svc := &Service{
+		Manager: Launchd,
+		Args:    []string{"/usr/local/bin/catcher", "--config", "/Users/u/Library/Application Support/catcher/config.toml", "--mode", "a&b"},
+		Env:     map[string]string{"PATH": "/opt/homebrew/bin:/usr/bin"},
+		LogDir:  "/Users/u/Library/Logs/catcher",
+	}
+	out := svc.Render()
+
+	// Decode as generic XML to prove it is well-formed
+	var doc struct {
+		Dict struct {
+			Keys    []string `xml:"key"`
+			Strings []string `xml:"string"`
+			Array   struct {
+				Strings []string `xml:"string"`
+			} `xml:"array"`
+		} `xml:"dict"`
+	}
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("plist is not valid XML: %v\n%s", err, out)
+	}
+	if got := doc.Dict.Array.Strings; len(got) != 5 || got[4] != "a&b" || got[2] != svc.Args[2] {
+		t.Errorf("ProgramArguments = %q", got)
+	}
+	for _, want := range []string{
+		"<string>com.cwygoda.catcher</string>",
+		"<key>PATH</key>\n        <string>/opt/homebrew/bin:/usr/bin</string>",
+		"<string>/Users/u/Library/Logs/catcher/catcher.err</string>",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("plist missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package setup generates a starter configuration for first-time users.
+package setup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ErrExists is returned when a file would be overwritten without Force.
+var ErrExists = errors.New("already exists")
+
+// Options describes the setup to write.
+type Options struct {
+	ConfigPath string // where to write config.toml
+	StateDir   string // created for the database
+	TargetDir  string // where the YouTube processor saves videos
+	Command    string // yt-dlp command
+	Secret     string // webhook secret; generated when empty
+	Force      bool   // overwrite existing files
+}
+
+// GenerateSecret returns a random 32-byte hex webhook secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+var configTemplate = template.Must(template.New("config").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(`# Generated by "catcher init". See the README for every option.
+
+# Webhook signing secret; requests must carry X-Timestamp and X-Signature
+secret = {{quote .Secret}}
+
+[[processor]]
+name = "youtube"
+pattern = "youtube\\.com|youtu\\.be"
+command = {{quote .Command}}
+args = ["-o", "%(title)s.%(ext)s", "{url}"]
+target_dir = {{quote .TargetDir}}
+# fragments = 4   # with yt-dlp, DASH and HLS fragments downloaded at once
+`))
+
+// RenderConfig returns the starter config for o.
+func RenderConfig(o Options) []byte {
+	var b bytes.Buffer
+	configTemplate.Execute(&b, o)
+	return b.Bytes()
+}
+
+// Write creates the directories and config file described by o, generating
+// a secret if none is set. It refuses to overwrite the config unless o.Force
+// is set.
+func Write(o *Options) error {
+	if o.Secret == "" {
+		secret, err := GenerateSecret()
+		if err != nil {
+			return fmt.Errorf("generate secret: %w", err)
+		}
+		o.Secret = secret
+	}
+
+	if !o.Force {
+		if _, err := os.Stat(o.ConfigPath); err == nil {
+			return fmt.Errorf("%s %w (use --force to overwrite)", o.ConfigPath, ErrExists)
+		}
+	}
+	for _, dir := range []string{o.StateDir, o.TargetDir, filepath.Dir(o.ConfigPath)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	// The config holds the secret, so only its owner may read it
+	return os.WriteFile(o.ConfigPath, RenderConfig(*o), 0o600)
+}
+
+// Prompter asks questions with defaults on a line-based terminal.
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter creates a Prompter reading answers from in.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// Ask prints question with def and returns the answer, or def if the answer
+// is empty or input has ended.
+func (p *Prompter) Ask(question, def string) string {
+	fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	line, _ := p.in.ReadString('\n')
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// Confirm asks a yes/no question.
+func (p *Prompter) Confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	switch strings.ToLower(p.Ask(question, hint)) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return def
+}
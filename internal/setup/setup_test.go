@@ -0,0 +1,97 @@
+package setup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestRenderConfig(t *testing.T) {
+	out := RenderConfig(Options{Secret: "s3cret", Command: `C:\yt-dlp "x"`, TargetDir: "/home/u/My Videos"})
+
+	var got struct {
+		Secret     string `toml:"secret"`
+		Processors []struct {
+			Name      string   `toml:"name"`
+			Pattern   string   `toml:"pattern"`
+			Command   string   `toml:"command"`
+			Args      []string `toml:"args"`
+			TargetDir string   `toml:"target_dir"`
+		} `toml:"processor"`
+	}
+	if _, err := toml.Decode(string(out), &got); err != nil {
+		t.Fatalf("rendered config is not valid TOML: %v\n%s", err, out)
+	}
+	if got.Secret != "s3cret" || len(got.Processors) != 1 {
+		t.Fatalf("decoded = %+v", got)
+	}
+	p := got.Processors[0]
+	if p.Command != `C:\yt-dlp "x"` || p.TargetDir != "/home/u/My Videos" || p.Pattern != `youtube\.com|youtu\.be` {
+		t.Errorf("processor = %+v", p)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	o := Options{
+		ConfigPath: filepath.Join(dir, "config", "catcher", "config.toml"),
+		StateDir:   filepath.Join(dir, "state", "catcher"),
+		TargetDir:  filepath.Join(dir, "Videos"),
+		Command:    "yt-dlp",
+	}
+
+	if err := Write(&o); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(o.Secret) != 64 {
+		t.Errorf("Secret = %q, want a generated 64-char hex secret", o.Secret)
+	}
+	for _, d := range []string{o.StateDir, o.TargetDir} {
+		if fi, err := os.Stat(d); err != nil || !fi.IsDir() {
+			t.Errorf("directory %s not created: %v", d, err)
+		}
+	}
+	fi, err := os.Stat(o.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("config mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	// A second run must not clobber the config and its secret
+	again := o
+	again.Secret = ""
+	if err := Write(&again); !errors.Is(err, ErrExists) {
+		t.Errorf("Write() again error = %v, want ErrExists", err)
+	}
+	again.Force = true
+	if err := Write(&again); err != nil {
+		t.Errorf("Write() with Force error = %v", err)
+	}
+}
+
+func TestPrompter(t *testing.T) {
+	var out strings.Builder
+	p := NewPrompter(strings.NewReader("/srv/videos\n\nyes\n"), &out)
+
+	if got := p.Ask("Save videos to", "~/Videos"); got != "/srv/videos" {
+		t.Errorf("Ask() = %q", got)
+	}
+	if got := p.Ask("yt-dlp command", "yt-dlp"); got != "yt-dlp" {
+		t.Errorf("Ask() with empty answer = %q, want default", got)
+	}
+	if !p.Confirm("Install systemd user unit", false) {
+		t.Error("Confirm() = false, want true")
+	}
+	if p.Confirm("Again", true) != true {
+		t.Error("Confirm() at end of input should return the default")
+	}
+	if !strings.Contains(out.String(), "Save videos to [~/Videos]: ") {
+		t.Errorf("prompt output = %q", out.String())
+	}
+}
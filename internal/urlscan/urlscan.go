@@ -0,0 +1,26 @@
+// Package urlscan finds http and https URLs in free text, such as a
+// notification's message, a file of links, or the clipboard.
+package urlscan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pattern matches a URL up to whitespace, a quote, an angle bracket, or a
+// control character, so URLs in binary files such as property lists are
+// found too.
+var pattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `\x00-\x1f\x7f]+`)
+
+// trailing is the punctuation that tends to follow a URL in a sentence.
+const trailing = ".,;:!?)]}"
+
+// Find returns the URLs in text in order, repeats included, without the
+// punctuation that tends to follow them in a sentence.
+func Find(text string) []string {
+	var urls []string
+	for _, u := range pattern.FindAllString(text, -1) {
+		urls = append(urls, strings.TrimRight(u, trailing))
+	}
+	return urls
+}
@@ -0,0 +1,29 @@
+package urlscan
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "nothing to see", nil},
+		{"sentence", "watch https://example.com/v?id=1, then http://example.org/a.", []string{"https://example.com/v?id=1", "http://example.org/a"}},
+		{"in parentheses", "(see https://example.com/x)", []string{"https://example.com/x"}},
+		{"quoted", `<a href="https://example.com/q">`, []string{"https://example.com/q"}},
+		{"repeated", "https://example.com/a https://example.com/a", []string{"https://example.com/a", "https://example.com/a"}},
+		{"binary", "\x00\x05https://example.com/plist\x08\x00", []string{"https://example.com/plist"}},
+		{"other schemes", "ftp://example.com magnet:?xt=1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Find(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Find() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// budget limits the total cost of running jobs. Jobs take their turn in
+// the order they ask, so a costly job isn't starved by a stream of cheap
+// ones that would each fit.
+type budget struct {
+	mu      sync.Mutex
+	total   int
+	used    int
+	waiting []*claim
+}
+
+// claim is a job waiting for part of the budget; ready is closed once it
+// has been granted.
+type claim struct {
+	cost  int
+	ready chan struct{}
+}
+
+func newBudget(total int) *budget {
+	return &budget{total: total}
+}
+
+// acquire takes cost from the budget, waiting until it's free or ctx is
+// done. A cost over the whole budget is cut to it, so the job runs alone.
+func (b *budget) acquire(ctx context.Context, cost int) error {
+	cost = min(cost, b.total)
+	b.mu.Lock()
+	if len(b.waiting) == 0 && b.used+cost <= b.total {
+		b.used += cost
+		b.mu.Unlock()
+		return nil
+	}
+	c := &claim{cost: cost, ready: make(chan struct{})}
+	b.waiting = append(b.waiting, c)
+	b.mu.Unlock()
+
+	select {
+	case <-c.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-c.ready:
+		// Granted while giving up: hand it on
+		b.used -= cost
+	default:
+		b.waiting = slices.DeleteFunc(b.waiting, func(w *claim) bool { return w == c })
+	}
+	b.grant()
+	return ctx.Err()
+}
+
+// release returns cost taken by acquire.
+func (b *budget) release(cost int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= min(cost, b.total)
+	b.grant()
+}
+
+// state returns how much of the budget is taken and how many jobs wait.
+func (b *budget) state() *domain.BudgetState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &domain.BudgetState{Total: b.total, Used: b.used, Waiting: len(b.waiting)}
+}
+
+// grant hands out budget to waiting claims in order, as far as it goes.
+// b.mu must be held.
+func (b *budget) grant() {
+	for len(b.waiting) > 0 && b.used+b.waiting[0].cost <= b.total {
+		c := b.waiting[0]
+		b.waiting = b.waiting[1:]
+		b.used += c.cost
+		close(c.ready)
+	}
+}
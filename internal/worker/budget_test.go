@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// granted reports whether acquire's result arrived within a short wait.
+func granted(t *testing.T, ch <-chan error) bool {
+	t.Helper()
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatalf("acquire() error = %v", err)
+		}
+		return true
+	case <-time.After(20 * time.Millisecond):
+		return false
+	}
+}
+
+func TestBudget_TakesTurns(t *testing.T) {
+	b := newBudget(4)
+	ctx := context.Background()
+	if err := b.acquire(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	heavy, light := make(chan error, 1), make(chan error, 1)
+	go func() { heavy <- b.acquire(ctx, 2) }()
+	if granted(t, heavy) {
+		t.Fatal("cost 2 granted with 1 left")
+	}
+	// The light job would fit, but waits behind the heavy one
+	go func() { light <- b.acquire(ctx, 1) }()
+	if granted(t, light) {
+		t.Fatal("cost 1 jumped the queue")
+	}
+
+	b.release(3)
+	if !granted(t, heavy) || !granted(t, light) {
+		t.Fatal("waiting jobs not granted after release")
+	}
+	if b.used != 3 {
+		t.Errorf("used = %d, want 3", b.used)
+	}
+}
+
+func TestBudget_CostOverTotal(t *testing.T) {
+	b := newBudget(2)
+	if err := b.acquire(context.Background(), 8); err != nil {
+		t.Fatal(err)
+	}
+	if b.used != 2 {
+		t.Errorf("used = %d, want the whole budget", b.used)
+	}
+	b.release(8)
+	if b.used != 0 {
+		t.Errorf("used = %d after release, want 0", b.used)
+	}
+}
+
+func TestBudget_Cancel(t *testing.T) {
+	b := newBudget(2)
+	bg := context.Background()
+	b.acquire(bg, 2)
+
+	ctx, cancel := context.WithCancel(bg)
+	cancelled, next := make(chan error, 1), make(chan error, 1)
+	go func() { cancelled <- b.acquire(ctx, 2) }()
+	time.Sleep(10 * time.Millisecond)
+	go func() { next <- b.acquire(bg, 1) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Fatalf("acquire() error = %v, want context.Canceled", err)
+	}
+
+	// The cancelled claim no longer holds up the one behind it
+	b.release(2)
+	if !granted(t, next) {
+		t.Fatal("claim behind a cancelled one not granted")
+	}
+	if b.used != 1 {
+		t.Errorf("used = %d, want 1", b.used)
+	}
+}
@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// ChecksumSuffix is appended to a result file's path to name its checksum
+// sidecar.
+const ChecksumSuffix = ".sha256"
+
+// SetChecksums makes the worker hash each file a job produces, recording
+// the digest with the job's result and writing it next to the file in a
+// sidecar that "sha256sum -c" reads, e.g. video.mp4.sha256. Call before
+// Run.
+func (w *Worker) SetChecksums(enabled bool) {
+	w.checksums = enabled
+}
+
+// writeChecksums hashes the files of a finished job, setting their SHA256
+// and writing sidecars. A file that can't be hashed is logged and left
+// without one rather than failing the job, whose download succeeded.
+func writeChecksums(jobID int64, files []domain.ResultFile) {
+	for i, f := range files {
+		sum, err := hashFile(f.Path)
+		if err == nil {
+			files[i].SHA256 = sum
+			line := sum + "  " + filepath.Base(f.Path) + "\n"
+			err = os.WriteFile(f.Path+ChecksumSuffix, []byte(line), 0o644)
+		}
+		if err != nil {
+			log.Printf("job %d: checksum %s: %v", jobID, f.Path, err)
+		}
+	}
+}
+
+// hashFile returns the hex SHA-256 digest of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// removeFile removes a result file and its checksum and provenance
+// sidecars, if any.
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	for _, sidecar := range []string{path + ChecksumSuffix, provenanceSidecar(path)} {
+		if err := os.Remove(sidecar); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
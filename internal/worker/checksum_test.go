@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestWorker_Checksums(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(video, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	svc.SetResubmitPolicy(repo, registry.ResubmitPolicy)
+	proc := &replacingProcessor{mockProcessor: mockProcessor{name: "replace"}}
+	registry.Register(proc)
+	w := New(svc, registry, time.Second, 3)
+	w.SetChecksums(true)
+	ctx := context.Background()
+
+	// A file that vanished before it could be hashed doesn't fail the job
+	job, _ := repo.Create(ctx, "https://example.com/video")
+	proc.files = []domain.ResultFile{{Path: video}, {Path: filepath.Join(dir, "gone.vtt")}}
+	w.processJob(ctx, job)
+
+	got, _ := repo.Get(ctx, job.ID)
+	if got.Status != domain.StatusCompleted {
+		t.Fatalf("status = %s, want completed", got.Status)
+	}
+	const sum = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" // sha256 of "hello\n"
+	if got.Files[0].SHA256 != sum || got.Files[1].SHA256 != "" {
+		t.Errorf("files = %+v, want the digest of the file that exists only", got.Files)
+	}
+	sidecar, err := os.ReadFile(video + ChecksumSuffix)
+	if err != nil || string(sidecar) != sum+"  video.mp4\n" {
+		t.Errorf("sidecar = %q, %v; want sha256sum's format", sidecar, err)
+	}
+
+	// Replacing the download removes the old file's sidecar with it
+	next, _ := repo.Create(ctx, "https://example.com/video")
+	proc.files = []domain.ResultFile{{Path: filepath.Join(dir, "video2.mp4")}}
+	os.WriteFile(proc.files[0].Path, []byte("v2"), 0o644)
+	w.processJob(ctx, next)
+	if _, err := os.Stat(video + ChecksumSuffix); !os.IsNotExist(err) {
+		t.Errorf("replaced file's sidecar still exists: %v", err)
+	}
+	if _, err := os.Stat(proc.files[0].Path + ChecksumSuffix); err != nil {
+		t.Errorf("new file has no sidecar: %v", err)
+	}
+}
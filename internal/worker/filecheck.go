@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// VerifyFiles checks that the files recorded for jobs completed since
+// since are still on disk with their recorded sizes, stores the result for
+// domain.JobService.LastFileCheck, and returns it, even if storing fails.
+// Of jobs for the same URL, only the last to complete is checked, since it
+// may have replaced the files of earlier ones.
+func (w *Worker) VerifyFiles(ctx context.Context, since time.Time) (*domain.FileCheck, error) {
+	jobs, err := w.svc.Completed(ctx, domain.CompletedFilter{Since: since})
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]int64, len(jobs)) // job ID by URL
+	for _, job := range jobs {
+		latest[job.URL] = job.ID
+	}
+
+	check := &domain.FileCheck{CheckedAt: w.clock.Now(), Since: since}
+	for _, job := range jobs {
+		if latest[job.URL] != job.ID {
+			continue
+		}
+		check.Jobs++
+		for _, f := range job.Files {
+			check.Files++
+			info, err := os.Stat(f.Path)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				check.Issues = append(check.Issues, domain.FileIssue{JobID: job.ID, Path: f.Path, Problem: domain.FileMissing, WantBytes: f.Bytes})
+			case err != nil:
+				check.Issues = append(check.Issues, domain.FileIssue{JobID: job.ID, Path: f.Path, Problem: domain.FileUnreadable, WantBytes: f.Bytes, Error: err.Error()})
+			case f.Bytes > 0 && info.Size() != f.Bytes:
+				check.Issues = append(check.Issues, domain.FileIssue{JobID: job.ID, Path: f.Path, Problem: domain.FileSizeMismatch, WantBytes: f.Bytes, GotBytes: info.Size()})
+			}
+		}
+	}
+
+	return check, w.svc.SaveFileCheck(ctx, check)
+}
+
+// VerifyFilesAtStart runs VerifyFiles over the jobs completed within
+// window, logging a summary and each discrepancy.
+func (w *Worker) VerifyFilesAtStart(ctx context.Context, window time.Duration) {
+	check, err := w.VerifyFiles(ctx, w.clock.Now().Add(-window))
+	if check == nil {
+		log.Printf("file check failed: %v", err)
+		return
+	}
+	if err != nil {
+		log.Printf("file check: store result: %v", err)
+	}
+	log.Printf("file check: %d file(s) of %d job(s) completed within %s, %d problem(s)",
+		check.Files, check.Jobs, window, len(check.Issues))
+	for _, issue := range check.Issues {
+		switch issue.Problem {
+		case domain.FileMissing:
+			log.Printf("file check: job %d: %s is missing", issue.JobID, issue.Path)
+		case domain.FileUnreadable:
+			log.Printf("file check: job %d: %s can't be checked: %s", issue.JobID, issue.Path, issue.Error)
+		default:
+			log.Printf("file check: job %d: %s is %d bytes, recorded as %d", issue.JobID, issue.Path, issue.GotBytes, issue.WantBytes)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// completedStub lists fixed completed jobs and keeps the last file check.
+type completedStub struct {
+	jobs  []domain.Job
+	check *domain.FileCheck
+}
+
+func (s *completedStub) ListCompleted(ctx context.Context, f domain.CompletedFilter) ([]domain.Job, error) {
+	return s.jobs, nil
+}
+
+func (s *completedStub) SaveFileCheck(ctx context.Context, c *domain.FileCheck) error {
+	s.check = c
+	return nil
+}
+
+func (s *completedStub) LastFileCheck(ctx context.Context) (*domain.FileCheck, error) {
+	if s.check == nil {
+		return nil, domain.ErrNoFileCheck
+	}
+	return s.check, nil
+}
+
+func TestWorker_VerifyFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	intact := write("intact.mp4", "12345")
+	shrunk := write("shrunk.mp4", "12")
+	replaced := write("replaced.mp4", "new run")
+	gone := filepath.Join(dir, "gone.mp4")
+	unreadable := filepath.Join(intact, "nested.mp4") // under a file: ENOTDIR
+
+	stub := &completedStub{jobs: []domain.Job{
+		{ID: 1, URL: "https://example.com/r", Files: []domain.ResultFile{{Path: replaced, Bytes: 3}, {Path: gone, Bytes: 3}}},
+		{ID: 2, URL: "https://example.com/a", Files: []domain.ResultFile{{Path: intact, Bytes: 5}, {Path: shrunk, Bytes: 5}}},
+		{ID: 3, URL: "https://example.com/b", Files: []domain.ResultFile{{Path: gone, Bytes: 9}}},
+		{ID: 4, URL: "https://example.com/r", Files: []domain.ResultFile{{Path: replaced, Bytes: 7}}},
+		{ID: 5, URL: "https://example.com/c", Files: []domain.ResultFile{{Path: unreadable, Bytes: 4}}},
+	}}
+	svc := domain.NewJobService(newMockRepo())
+	svc.SetCompletedLister(stub)
+	svc.SetFileCheckRepository(stub)
+	w := New(svc, processor.NewRegistry(), time.Second, 3)
+
+	check, err := w.VerifyFiles(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("VerifyFiles() error = %v", err)
+	}
+	if check.Jobs != 4 || check.Files != 5 {
+		t.Errorf("checked %d file(s) of %d job(s), want 5 of 4 (job 1 replaced by job 4)", check.Files, check.Jobs)
+	}
+	_, statErr := os.Stat(unreadable)
+	want := []domain.FileIssue{
+		{JobID: 2, Path: shrunk, Problem: domain.FileSizeMismatch, WantBytes: 5, GotBytes: 2},
+		{JobID: 3, Path: gone, Problem: domain.FileMissing, WantBytes: 9},
+		{JobID: 5, Path: unreadable, Problem: domain.FileUnreadable, WantBytes: 4, Error: statErr.Error()},
+	}
+	if !slices.Equal(check.Issues, want) {
+		t.Errorf("issues = %+v, want %+v", check.Issues, want)
+	}
+	if stub.check != check {
+		t.Error("VerifyFiles() didn't store its check")
+	}
+}
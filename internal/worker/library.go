@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// checkLibrary completes a job whose media its processor finds already in
+// the library, without downloading it again. The job's files are the ones
+// in the library, and its attempt says where they are. It reports whether
+// to go on processing. Redownloads, which ask for a fresh copy, and
+// bookmarks go on, as does a job whose lookup fails.
+func (w *Worker) checkLibrary(ctx context.Context, job *domain.Job, proc domain.URLProcessor) bool {
+	lc, ok := proc.(domain.LibraryChecker)
+	if !ok || job.RedownloadOf != 0 || job.Bookmark {
+		return true
+	}
+	start := w.clock.Now()
+	files, err := lc.InLibrary(ctx, job.URL)
+	if err != nil {
+		log.Printf("job %d: library lookup failed, processing anyway: %v", job.ID, err)
+		return true
+	}
+	if len(files) == 0 {
+		return true
+	}
+
+	var bytes int64
+	output := "already in library:"
+	for i, f := range files {
+		bytes += f.Bytes
+		output += "\n" + f.Path
+		// The user's files, which a later run replacing this one must keep
+		files[i].Library = true
+	}
+	attempt := domain.Attempt{Number: job.Attempts, Processor: proc.Name(), Output: output, StartedAt: start, FinishedAt: w.clock.Now()}
+	if err := w.svc.RecordAttempt(ctx, job.ID, attempt); err != nil {
+		log.Printf("job %d: record attempt failed: %v", job.ID, err)
+	}
+	job.Bytes, job.Files = bytes, files
+	log.Printf("job %d: already in library at %s, not downloading", job.ID, files[0].Path)
+	err = w.svc.MarkComplete(ctx, job.ID, domain.Completion{
+		Processor: proc.Name(),
+		Bytes:     bytes,
+		Files:     files,
+		Duration:  attempt.FinishedAt.Sub(start),
+	})
+	if err != nil {
+		log.Printf("job %d: mark complete failed: %v", job.ID, err)
+	}
+	// Replaced jobs' files are left alone: they may be these very files
+	w.observe(job, proc.Name(), OutcomeCompleted, w.clock.Now().Sub(start))
+	return false
+}
+
+// RescanLibrary checks every file in the library index against the disk,
+// recording which are missing and the size of the rest. With hash, it
+// also computes their digests, which finds files changed in place. Files
+// that can't be checked, say on an unmounted drive that leaves a
+// permission error, are logged and keep their state. The scan is dated by
+// clock.
+func RescanLibrary(ctx context.Context, svc *domain.JobService, clock domain.Clock, hash bool) (*domain.LibraryRescan, error) {
+	files, err := svc.LibraryFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type state struct {
+		size int64
+		sum  string
+		err  error
+	}
+	seen := make(map[string]state) // by path, which re-downloads repeat
+
+	scan := &domain.LibraryRescan{CheckedAt: clock.Now()}
+	var checked []domain.LibraryFile
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		st, ok := seen[f.Path]
+		if !ok {
+			var info os.FileInfo
+			if info, st.err = os.Stat(f.Path); st.err == nil {
+				st.size = info.Size()
+				if hash {
+					st.sum, st.err = hashFile(f.Path)
+				}
+			}
+			seen[f.Path] = st
+		}
+		scan.Files++
+		switch {
+		case errors.Is(st.err, os.ErrNotExist):
+			f.Missing = true
+			scan.Missing++
+		case st.err != nil:
+			log.Printf("library rescan: %s: %v", f.Path, st.err)
+			continue
+		default:
+			if f.Missing {
+				scan.Returned++
+			}
+			f.Missing = false
+			changed := (f.Bytes > 0 && st.size != f.Bytes) || (hash && f.SHA256 != "" && st.sum != f.SHA256)
+			if changed {
+				scan.Changed++
+				f.SHA256 = "" // of the old content
+			}
+			f.Bytes = st.size
+			if hash {
+				f.SHA256 = st.sum
+			}
+		}
+		f.CheckedAt = scan.CheckedAt
+		checked = append(checked, f)
+	}
+	return scan, svc.UpdateLibraryFiles(ctx, checked)
+}
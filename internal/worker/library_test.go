@@ -0,0 +1,203 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// libraryProcessor finds the files in have in the library.
+type libraryProcessor struct {
+	mockProcessor
+	have      []domain.ResultFile
+	lookupErr error
+}
+
+func (p *libraryProcessor) InLibrary(ctx context.Context, url string) ([]domain.ResultFile, error) {
+	return p.have, p.lookupErr
+}
+
+func TestWorker_Library(t *testing.T) {
+	inLibrary := []domain.ResultFile{{Path: "/library/Clip [abc123].mp4", Bytes: 100}}
+	tests := []struct {
+		name       string
+		proc       *libraryProcessor
+		redownload bool
+		wantRun    bool
+	}{
+		{"not in library", &libraryProcessor{}, false, true},
+		{"in library", &libraryProcessor{have: inLibrary}, false, false},
+		{"lookup fails", &libraryProcessor{lookupErr: errors.New("boom")}, false, true},
+		{"redownload", &libraryProcessor{have: inLibrary}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			attempts := &mockAttempts{attempts: make(map[int64][]domain.Attempt)}
+			svc.SetAttemptRepository(attempts)
+			registry := processor.NewRegistry()
+			tt.proc.name = "library"
+			registry.Register(tt.proc)
+			w := New(svc, registry, time.Second, 3)
+			ctx := context.Background()
+
+			job, _ := repo.Create(ctx, "https://example.com/watch?v=abc123")
+			if tt.redownload {
+				job.RedownloadOf = 1
+			}
+			w.processJob(ctx, job)
+
+			got := repo.getJob(job.ID)
+			if got.Status != domain.StatusCompleted {
+				t.Fatalf("status = %s, want completed", got.Status)
+			}
+			if ran := len(tt.proc.processed) > 0; ran != tt.wantRun {
+				t.Errorf("processed = %v, want %v", ran, tt.wantRun)
+			}
+			if tt.wantRun {
+				return
+			}
+			if len(got.Files) != 1 || got.Files[0].Path != inLibrary[0].Path || got.Bytes != 100 {
+				t.Errorf("files = %+v (%d bytes), want the library's", got.Files, got.Bytes)
+			}
+			recorded, _ := attempts.Attempts(ctx, job.ID)
+			if len(recorded) != 1 || !strings.Contains(recorded[0].Output, "already in library:\n"+inLibrary[0].Path) {
+				t.Errorf("attempts = %+v, want one saying the media is already in the library", recorded)
+			}
+		})
+	}
+}
+
+// replacingLibraryProcessor finds have in the library, and replaces
+// earlier downloads with files.
+type replacingLibraryProcessor struct {
+	replacingProcessor
+	have []domain.ResultFile
+}
+
+func (p *replacingLibraryProcessor) InLibrary(ctx context.Context, url string) ([]domain.ResultFile, error) {
+	return p.have, nil
+}
+
+func TestWorker_LibraryHitThenRedownload(t *testing.T) {
+	dir := t.TempDir()
+	libraryFile := filepath.Join(dir, "library", "Clip [abc123].mp4")
+	os.MkdirAll(filepath.Dir(libraryFile), 0o755)
+	if err := os.WriteFile(libraryFile, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	svc.SetResubmitPolicy(repo, registry.ResubmitPolicy)
+	proc := &replacingLibraryProcessor{
+		replacingProcessor: replacingProcessor{
+			mockProcessor: mockProcessor{name: "library"},
+			files:         []domain.ResultFile{{Path: filepath.Join(dir, "downloads", "clip.mp4")}},
+		},
+		have: []domain.ResultFile{{Path: libraryFile, Bytes: 1}},
+	}
+	registry.Register(proc)
+	w := New(svc, registry, time.Second, 3)
+	ctx := context.Background()
+
+	first, _ := repo.Create(ctx, "https://example.com/watch?v=abc123")
+	w.processJob(ctx, first)
+	if got := repo.getJob(first.ID); len(got.Files) != 1 || !got.Files[0].Library {
+		t.Fatalf("files = %+v, want the library file marked as such", got.Files)
+	}
+
+	second, _ := repo.Create(ctx, "https://example.com/watch?v=abc123")
+	second.RedownloadOf = first.ID
+	w.processJob(ctx, second)
+	if got := repo.getJob(second.ID); len(got.Files) != 1 || got.Files[0].Library {
+		t.Fatalf("redownload files = %+v, want its own download", got.Files)
+	}
+	if _, err := os.Stat(libraryFile); err != nil {
+		t.Errorf("library file removed by the redownload replacing the hit: %v", err)
+	}
+}
+
+// mockLibrary implements domain.LibraryIndex for testing.
+type mockLibrary struct {
+	files []domain.LibraryFile
+}
+
+func (m *mockLibrary) LibraryFiles(ctx context.Context) ([]domain.LibraryFile, error) {
+	return append([]domain.LibraryFile(nil), m.files...), nil
+}
+
+func (m *mockLibrary) SearchLibrary(ctx context.Context, q domain.LibraryQuery) ([]domain.LibraryFile, error) {
+	var found []domain.LibraryFile
+	for i := range m.files {
+		if q.Match(&m.files[i]) {
+			found = append(found, m.files[i])
+		}
+	}
+	return found, nil
+}
+
+func (m *mockLibrary) UpdateLibraryFiles(ctx context.Context, files []domain.LibraryFile) error {
+	for _, f := range files {
+		for i := range m.files {
+			if m.files[i].ID == f.ID {
+				m.files[i] = f
+			}
+		}
+	}
+	return nil
+}
+
+func TestRescanLibrary(t *testing.T) {
+	dir := t.TempDir()
+	same, grown, back := filepath.Join(dir, "same.mp4"), filepath.Join(dir, "grown.mp4"), filepath.Join(dir, "back.mp4")
+	os.WriteFile(same, []byte("12345"), 0o644)
+	os.WriteFile(grown, []byte("1234567890"), 0o644)
+	os.WriteFile(back, []byte("x"), 0o644)
+	sameSum, _ := hashFile(same)
+
+	lib := &mockLibrary{files: []domain.LibraryFile{
+		{ID: 1, Path: same, Bytes: 5, SHA256: sameSum},
+		{ID: 2, Path: grown, Bytes: 5, SHA256: "stale"},
+		{ID: 3, Path: filepath.Join(dir, "gone.mp4"), Bytes: 5},
+		{ID: 4, Path: back, Bytes: 1, Missing: true},
+	}}
+	svc := domain.NewJobService(newMockRepo())
+	svc.SetLibraryIndex(lib)
+
+	clock := domain.NewManualClock(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	scan, err := RescanLibrary(context.Background(), svc, clock, true)
+	if err != nil {
+		t.Fatalf("RescanLibrary() error = %v", err)
+	}
+	if !scan.CheckedAt.Equal(clock.Now()) {
+		t.Errorf("rescan checked at %s, want the clock's %s", scan.CheckedAt, clock.Now())
+	}
+	if scan.Files != 4 || scan.Missing != 1 || scan.Changed != 1 || scan.Returned != 1 {
+		t.Errorf("rescan = %+v, want 4 files, 1 missing, 1 changed, 1 returned", scan)
+	}
+	got := lib.files
+	if got[0].Missing || got[0].SHA256 != sameSum {
+		t.Errorf("unchanged file = %+v", got[0])
+	}
+	if got[1].Bytes != 10 || got[1].SHA256 == "stale" || got[1].SHA256 == "" {
+		t.Errorf("grown file = %+v, want its new size and digest", got[1])
+	}
+	if !got[2].Missing || got[3].Missing {
+		t.Errorf("missing flags = %v, %v, want only the deleted file missing", got[2].Missing, got[3].Missing)
+	}
+	for _, f := range got {
+		if !f.CheckedAt.Equal(scan.CheckedAt) {
+			t.Errorf("%s checked at %s, want %s", f.Path, f.CheckedAt, scan.CheckedAt)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// Outage defaults, used for zero OutagePolicy fields.
+const (
+	defaultProbeInterval = 30 * time.Second
+	defaultSweepRate     = 10
+	defaultMaxRequeues   = 3
+	probeTimeout         = 10 * time.Second
+)
+
+// OutagePolicy says when the network counts as down and how to recover.
+type OutagePolicy struct {
+	// After is how many jobs in a row must fail to reach their sites (see
+	// domain.Unreachable), on more than one host, for the network to count
+	// as down. Zero disables outage detection.
+	After int
+	// Probe is the host:port dialled to see whether the network is back.
+	// Empty means the host of the last URL that failed.
+	Probe string
+	// ProbeInterval is how often Probe is dialled while the network is
+	// down; 30 seconds if zero.
+	ProbeInterval time.Duration
+	// SweepRate is how many jobs that failed during the outage are
+	// requeued per minute once it is over; 10 if zero.
+	SweepRate int
+	// MaxRequeues is how many outages a job is requeued after at most, so
+	// one that can't be reached for good is left failed; 3 if zero.
+	MaxRequeues int
+}
+
+// outage tracks network failures across jobs. Once After jobs in a row
+// on more than one host fail to reach their sites, the worker starts no
+// jobs until a probe gets through, then requeues the jobs that failed for
+// good meanwhile, SweepRate a minute, rather than leaving them failed. The
+// jobs to requeue are listed in the service's OutageRepository, so they
+// are requeued after a restart too.
+type outage struct {
+	policy OutagePolicy
+	dial   func(ctx context.Context, addr string) error
+
+	mu        sync.Mutex
+	streak    int             // jobs in a row that failed to reach their sites
+	hosts     map[string]bool // the hosts of those jobs
+	failed    []int64         // of those and later ones, the jobs that failed for good
+	lastAddr  string          // host:port of the last URL that failed
+	down      bool
+	since     time.Time
+	nextProbe time.Time
+	sweep     []int64 // failed jobs left to requeue after the outage
+	nextSweep time.Time
+}
+
+// SetOutagePolicy enables outage detection as p says; p.After of zero
+// disables it. Requeueing jobs after an outage needs the service's
+// OutageRepository. Call before Run.
+func (w *Worker) SetOutagePolicy(p OutagePolicy) {
+	if p.After <= 0 {
+		w.outage = nil
+		return
+	}
+	if p.ProbeInterval <= 0 {
+		p.ProbeInterval = defaultProbeInterval
+	}
+	if p.SweepRate <= 0 {
+		p.SweepRate = defaultSweepRate
+	}
+	if p.MaxRequeues <= 0 {
+		p.MaxRequeues = defaultMaxRequeues
+	}
+	w.outage = &outage{policy: p, dial: dialProbe, hosts: make(map[string]bool)}
+}
+
+// SetConnectivity makes the worker start no jobs while m reports the
+// uplink down, and send back jobs that fail with network errors or
+// timeouts meanwhile without counting the attempt. Call before Run.
+func (w *Worker) SetConnectivity(m domain.ConnectivityMonitor) {
+	w.connectivity = m
+}
+
+// offline reports whether a job that failed with reason did so because the
+// uplink is down, checking it again to be sure.
+func (w *Worker) offline(ctx context.Context, reason string) bool {
+	if w.connectivity == nil {
+		return false
+	}
+	switch domain.ClassifyFailure(reason) {
+	case domain.CauseNetwork, domain.CauseTimeout:
+		return !w.connectivity.Check(ctx).Online
+	}
+	return false
+}
+
+// dialProbe connects to addr over TCP and hangs up.
+func dialProbe(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeAddr returns the host:port to probe for rawURL, by its scheme's
+// port if it has none.
+func probeAddr(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// jobFinished records how a job ended: reason is empty if it completed,
+// and final is set if it failed for good. Jobs that failed for good during
+// an outage are listed to be requeued after it.
+func (w *Worker) jobFinished(ctx context.Context, job *domain.Job, reason string, final bool) {
+	o := w.outage
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	var list []int64
+	switch {
+	case reason == "":
+		if o.down {
+			// A job got through, so the network is back
+			o.recover(w.clock.Now(), fmt.Sprintf("job %d completed", job.ID))
+		}
+		o.reset()
+	case domain.Unreachable(reason):
+		o.streak++
+		o.hosts[domain.CooldownHost(job.URL)] = true
+		if final {
+			o.failed = append(o.failed, job.ID)
+			if o.down {
+				list = []int64{job.ID}
+			}
+		}
+		if addr := probeAddr(job.URL); addr != "" {
+			o.lastAddr = addr
+		}
+		if !o.down && o.streak >= o.policy.After && len(o.hosts) > 1 {
+			now := w.clock.Now()
+			o.down, o.since, o.nextProbe = true, now, now.Add(o.policy.ProbeInterval)
+			list = slices.Clone(o.failed)
+			log.Printf("network seems down: %d jobs in a row on %d hosts could not reach their sites; starting no jobs until %s answers",
+				o.streak, len(o.hosts), o.probe())
+		}
+	case !o.down:
+		// Failing for another reason, the job reached its site
+		o.reset()
+	}
+	o.mu.Unlock()
+
+	if err := w.svc.ListOutageFailed(ctx, list); err != nil {
+		log.Printf("list jobs that failed during the outage: %v", err)
+	}
+}
+
+// reset forgets the failures in a row. Call with o.mu held.
+func (o *outage) reset() {
+	o.streak, o.failed = 0, nil
+	clear(o.hosts)
+}
+
+// loadOutage resumes requeueing the jobs listed as failed during an outage
+// before a restart.
+func (w *Worker) loadOutage(ctx context.Context) {
+	o := w.outage
+	if o == nil {
+		return
+	}
+	ids, err := w.svc.OutageFailed(ctx)
+	if err != nil {
+		log.Printf("load jobs that failed during an outage: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	log.Printf("requeueing %d job(s) that failed during an outage before the restart", len(ids))
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sweep = append(o.sweep, ids...)
+	slices.Sort(o.sweep)
+	o.sweep = slices.Compact(o.sweep)
+	o.nextSweep = w.clock.Now()
+}
+
+// probe returns the address to probe.
+func (o *outage) probe() string {
+	if o.policy.Probe != "" {
+		return o.policy.Probe
+	}
+	return o.lastAddr
+}
+
+// recover ends the outage, queueing the jobs that failed during it to be
+// requeued from now on, oldest first. Call with o.mu held.
+func (o *outage) recover(now time.Time, why string) {
+	log.Printf("network is back after %s (%s); requeueing %d job(s) that failed meanwhile",
+		now.Sub(o.since).Round(time.Second), why, len(o.failed))
+	o.down = false
+	o.sweep = append(o.sweep, o.failed...)
+	slices.Sort(o.sweep)
+	o.sweep = slices.Compact(o.sweep)
+	o.reset()
+	if o.nextSweep.Before(now) {
+		o.nextSweep = now
+	}
+}
+
+// holding reports whether the network is down, so poll should start no
+// jobs: the uplink is offline, or an outage was detected. While one is,
+// its probe is dialled every ProbeInterval. Once it is over, jobs that
+// failed during it are requeued at SweepRate.
+func (w *Worker) holding(ctx context.Context) bool {
+	if w.connectivity != nil && !w.connectivity.Connectivity().Online {
+		return true
+	}
+	o := w.outage
+	if o == nil {
+		return false
+	}
+	now := w.clock.Now()
+	o.mu.Lock()
+	if o.down && !now.Before(o.nextProbe) {
+		addr := o.probe()
+		o.nextProbe = now.Add(o.policy.ProbeInterval)
+		o.mu.Unlock()
+		err := o.dial(ctx, addr)
+		o.mu.Lock()
+		switch {
+		case !o.down:
+		case err == nil:
+			o.recover(w.clock.Now(), addr+" answered")
+		default:
+			log.Printf("network still down: %v", err)
+		}
+	}
+	down := o.down
+	var due []int64
+	for len(o.sweep) > 0 && !now.Before(o.nextSweep) {
+		due = append(due, o.sweep[0])
+		o.sweep = o.sweep[1:]
+		o.nextSweep = o.nextSweep.Add(time.Minute / time.Duration(o.policy.SweepRate))
+	}
+	o.mu.Unlock()
+
+	for _, id := range due {
+		requeued, err := w.svc.RequeueOutageFailed(ctx, id, o.policy.MaxRequeues)
+		switch {
+		case err != nil:
+			log.Printf("job %d: requeue after outage: %v", id, err)
+		case requeued:
+			log.Printf("job %d: requeued after outage", id)
+		default:
+			log.Printf("job %d: not requeued after outage: no longer failed, or requeued after %d outages already", id, o.policy.MaxRequeues)
+		}
+	}
+	return down
+}
+
+// networkDown reports whether the network counts as down.
+func (w *Worker) networkDown() bool {
+	if w.connectivity != nil && !w.connectivity.Connectivity().Online {
+		return true
+	}
+	o := w.outage
+	if o == nil {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.down
+}
+
+// outageState returns the outage for State, or nil if there is none and
+// no jobs are left to requeue.
+func (w *Worker) outageState() *domain.OutageState {
+	o := w.outage
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.down && len(o.sweep) == 0 {
+		return nil
+	}
+	st := &domain.OutageState{Down: o.down, Since: o.since, Probe: o.probe(), Requeueing: len(o.sweep)}
+	if o.down {
+		st.NextProbe = o.nextProbe
+		st.Failed = len(o.failed)
+	}
+	return st
+}
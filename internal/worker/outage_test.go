@@ -0,0 +1,391 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// mockOutages lists jobs that failed during outages and requeues them,
+// like the sqlite repository.
+type mockOutages struct {
+	*mockRepo
+	listed   map[int64]bool
+	requeues map[int64]int
+}
+
+func newMockOutages(repo *mockRepo) *mockOutages {
+	return &mockOutages{mockRepo: repo, listed: make(map[int64]bool), requeues: make(map[int64]int)}
+}
+
+func (m *mockOutages) ListOutageFailed(ctx context.Context, ids []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		m.listed[id] = true
+	}
+	return nil
+}
+
+func (m *mockOutages) OutageFailed(ctx context.Context) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []int64
+	for id, listed := range m.listed {
+		if listed {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (m *mockOutages) RequeueOutageFailed(ctx context.Context, id int64, max int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.listed[id] {
+		return false, nil
+	}
+	m.listed[id] = false
+	job, ok := m.jobs[id]
+	if !ok || job.Status != domain.StatusFailed || m.requeues[id] >= max {
+		return false, nil
+	}
+	job.Status, job.Attempts, job.Error = domain.StatusPending, 0, ""
+	m.requeues[id]++
+	return true, nil
+}
+
+func TestProbeAddr(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://youtube.com/watch?v=1", "youtube.com:443"},
+		{"http://example.com/a", "example.com:80"},
+		{"http://[::1]:8080/a", "[::1]:8080"},
+		{"not a url", ""},
+	}
+	for _, tt := range tests {
+		if got := probeAddr(tt.url); got != tt.want {
+			t.Errorf("probeAddr(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestWorker_Outage(t *testing.T) {
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetOutageRepository(newMockOutages(repo))
+	proc := &mockProcessor{name: "test", processErr: errors.New("dial tcp: connection refused")}
+	registry := processor.NewRegistry()
+	registry.Register(proc)
+
+	w := New(svc, registry, time.Second, 1)
+	w.SetClock(clock)
+	w.SetOutagePolicy(OutagePolicy{After: 2, ProbeInterval: time.Minute, SweepRate: 60})
+	var mu sync.Mutex
+	var probed []string
+	var probeErr = errors.New("network is unreachable")
+	w.outage.dial = func(ctx context.Context, addr string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		probed = append(probed, addr)
+		return probeErr
+	}
+	ctx := context.Background()
+
+	first, _ := repo.Create(ctx, "https://example.com/1")
+	second, _ := repo.Create(ctx, "https://example.org/2")
+	w.poll(ctx)
+	if repo.status(first.ID) != domain.StatusFailed || repo.status(second.ID) != domain.StatusFailed {
+		t.Fatalf("jobs = %s, %s; want both failed", repo.status(first.ID), repo.status(second.ID))
+	}
+	// The mock hands out pending jobs in no set order, so either may have
+	// failed last
+	st := w.State().Outage
+	if st == nil || !st.Down || st.Failed != 2 || !st.NextProbe.Equal(clock.Now().Add(time.Minute)) ||
+		(st.Probe != "example.com:443" && st.Probe != "example.org:443") {
+		t.Fatalf("outage = %+v, want down with 2 failed jobs", st)
+	}
+
+	// Held while down, and probed every ProbeInterval
+	third, _ := repo.Create(ctx, "https://example.com/3")
+	w.poll(ctx)
+	clock.Advance(time.Minute)
+	w.poll(ctx)
+	if repo.status(third.ID) != domain.StatusPending || len(proc.processed) != 2 {
+		t.Errorf("job %d = %s, processed %v; want it held", third.ID, repo.status(third.ID), proc.processed)
+	}
+	if !slices.Equal(probed, []string{st.Probe}) {
+		t.Errorf("probed %v, want %s once", probed, st.Probe)
+	}
+
+	// Back: the failed jobs are requeued one a second, at SweepRate
+	mu.Lock()
+	probeErr = nil
+	mu.Unlock()
+	proc.processErr = nil
+	clock.Advance(time.Minute)
+	w.poll(ctx)
+	if repo.status(third.ID) != domain.StatusCompleted || repo.status(first.ID) != domain.StatusCompleted {
+		t.Errorf("jobs %d, %d = %s, %s; want completed", first.ID, third.ID, repo.status(first.ID), repo.status(third.ID))
+	}
+	if repo.status(second.ID) != domain.StatusFailed {
+		t.Errorf("job %d = %s, want it left for the next requeue", second.ID, repo.status(second.ID))
+	}
+	if st := w.State().Outage; st == nil || st.Down || st.Requeueing != 1 {
+		t.Errorf("outage = %+v, want over with 1 job left to requeue", st)
+	}
+
+	clock.Advance(time.Second)
+	w.poll(ctx)
+	if repo.status(second.ID) != domain.StatusCompleted {
+		t.Errorf("job %d = %s, want completed", second.ID, repo.status(second.ID))
+	}
+	if st := w.State().Outage; st != nil {
+		t.Errorf("outage = %+v, want none", st)
+	}
+}
+
+func TestWorker_OutageDetection(t *testing.T) {
+	type failure struct{ url, err string }
+	tests := []struct {
+		name     string
+		failures []failure
+		want     bool
+	}{
+		{"unreachable on two hosts", []failure{
+			{"https://a.example/1", "dial tcp: connection refused"},
+			{"https://b.example/2", "dial tcp: lookup b.example: no such host"},
+		}, true},
+		{"one host", []failure{
+			{"https://a.example/1", "dial tcp: connection refused"},
+			{"https://www.a.example/2", "connect: network is unreachable"},
+			{"https://a.example/3", "dial tcp: connection refused"},
+		}, false},
+		{"rate limited", []failure{
+			{"https://a.example/1", "HTTP Error 429: Too Many Requests"},
+			{"https://b.example/2", "HTTP Error 429: Too Many Requests"},
+		}, false},
+		{"server errors", []failure{
+			{"https://a.example/1", "HTTP Error 503: Service Unavailable"},
+			{"https://b.example/2", "connection reset by peer"},
+		}, false},
+		{"not in a row", []failure{
+			{"https://a.example/1", "dial tcp: connection refused"},
+			{"https://b.example/2", "Unsupported URL"},
+			{"https://c.example/3", "dial tcp: connection refused"},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			proc := &mockProcessor{name: "test"}
+			registry := processor.NewRegistry()
+			registry.Register(proc)
+			w := New(svc, registry, time.Second, 1)
+			w.SetOutagePolicy(OutagePolicy{After: 2})
+			ctx := context.Background()
+
+			for _, f := range tt.failures {
+				proc.processErr = errors.New(f.err)
+				job, _ := repo.Create(ctx, f.url)
+				w.processJob(ctx, job)
+			}
+			if got := w.networkDown(); got != tt.want {
+				t.Errorf("network down = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorker_OutageDisabled(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	proc := &mockProcessor{name: "test", processErr: errors.New("dial tcp: connection refused")}
+	registry := processor.NewRegistry()
+	registry.Register(proc)
+	w := New(svc, registry, time.Second, 1)
+	w.SetOutagePolicy(OutagePolicy{})
+	ctx := context.Background()
+
+	for _, url := range []string{"https://a.example/1", "https://b.example/2", "https://c.example/3"} {
+		job, _ := repo.Create(ctx, url)
+		w.processJob(ctx, job)
+	}
+	if w.networkDown() || w.State().Outage != nil {
+		t.Error("network down with outage detection disabled")
+	}
+}
+
+func TestWorker_OutageRequeueLimit(t *testing.T) {
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	outages := newMockOutages(repo)
+	svc.SetOutageRepository(outages)
+	proc := &mockProcessor{name: "test", processErr: errors.New("dial tcp: connection refused")}
+	registry := processor.NewRegistry()
+	registry.Register(proc)
+	w := New(svc, registry, time.Second, 1)
+	w.SetClock(clock)
+	w.SetOutagePolicy(OutagePolicy{After: 2, SweepRate: 60, MaxRequeues: 1})
+	w.outage.dial = func(ctx context.Context, addr string) error { return nil }
+	ctx := context.Background()
+
+	// Requeued after the first outage, the jobs fail again and start a
+	// second one, after which they are left failed
+	first, _ := repo.Create(ctx, "https://a.example/1")
+	second, _ := repo.Create(ctx, "https://b.example/2")
+	w.poll(ctx)
+	for range 3 {
+		clock.Advance(defaultProbeInterval)
+		w.poll(ctx)
+		clock.Advance(time.Second)
+		w.poll(ctx)
+	}
+	for _, id := range []int64{first.ID, second.ID} {
+		if got := repo.status(id); got != domain.StatusFailed {
+			t.Errorf("job %d = %s, want failed", id, got)
+		}
+		if n := outages.requeues[id]; n != 1 {
+			t.Errorf("job %d requeued after %d outages, want 1", id, n)
+		}
+	}
+	if len(proc.processed) != 4 {
+		t.Errorf("processed %v, want each job twice", proc.processed)
+	}
+}
+
+func TestWorker_OutageResumedAfterRestart(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	outages := newMockOutages(repo)
+	svc.SetOutageRepository(outages)
+	proc := &mockProcessor{name: "test", processErr: errors.New("dial tcp: connection refused")}
+	registry := processor.NewRegistry()
+	registry.Register(proc)
+	ctx := context.Background()
+
+	w := New(svc, registry, time.Second, 1)
+	w.SetOutagePolicy(OutagePolicy{After: 2})
+	first, _ := repo.Create(ctx, "https://a.example/1")
+	second, _ := repo.Create(ctx, "https://b.example/2")
+	w.processJob(ctx, first)
+	w.processJob(ctx, second)
+	if ids, _ := outages.OutageFailed(ctx); !slices.Equal(ids, []int64{first.ID, second.ID}) {
+		t.Fatalf("listed %v, want both jobs", ids)
+	}
+
+	// A new worker requeues them once it starts, as if the outage were over
+	proc.processErr = nil
+	w = New(svc, registry, time.Second, 1)
+	w.SetOutagePolicy(OutagePolicy{After: 2, SweepRate: 60})
+	w.loadOutage(ctx)
+	if st := w.State().Outage; st == nil || st.Down || st.Requeueing != 2 {
+		t.Fatalf("outage = %+v, want 2 jobs to requeue", st)
+	}
+	w.poll(ctx)
+	if got := repo.status(first.ID); got != domain.StatusCompleted {
+		t.Errorf("job %d = %s, want completed", first.ID, got)
+	}
+}
+
+// fakeUplink is a domain.ConnectivityMonitor that is up or down as told.
+type fakeUplink struct {
+	mu     sync.Mutex
+	online bool
+	checks int
+}
+
+func (f *fakeUplink) Connectivity() domain.Connectivity {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return domain.Connectivity{Online: f.online}
+}
+
+func (f *fakeUplink) Check(ctx context.Context) domain.Connectivity {
+	f.mu.Lock()
+	f.checks++
+	f.mu.Unlock()
+	return f.Connectivity()
+}
+
+func (f *fakeUplink) set(online bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.online = online
+}
+
+// mockPostponer sends jobs back like the sqlite repository.
+type mockPostponer struct{ *mockRepo }
+
+func (m mockPostponer) Postpone(ctx context.Context, id int64, reason string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[id]
+	job.Status, job.Error = domain.StatusPending, reason
+	job.Attempts--
+	m.retryAt[id] = at
+	return nil
+}
+
+func TestWorker_Connectivity(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetPostponer(mockPostponer{repo})
+	proc := &mockProcessor{name: "test", processErr: errors.New("dial tcp: connection refused")}
+	registry := processor.NewRegistry()
+	registry.Register(proc)
+	uplink := &fakeUplink{online: true}
+
+	w := New(svc, registry, time.Second, 1)
+	w.SetConnectivity(uplink)
+	ctx := context.Background()
+
+	// Online when it failed: an attempt like any other
+	job, _ := repo.Create(ctx, "https://example.com/a")
+	w.poll(ctx)
+	if got := repo.getJob(job.ID); got.Status != domain.StatusFailed {
+		t.Errorf("job = %s, want failed while online", got.Status)
+	}
+
+	// Offline: sent back without using up its only attempt, then held
+	uplink.set(false)
+	job, _ = repo.Create(ctx, "https://example.com/b")
+	w.processJob(ctx, job)
+	if got := repo.getJob(job.ID); got.Status != domain.StatusPending || got.Attempts != 0 {
+		t.Errorf("job = %s with %d attempt(s), want pending with none used", got.Status, got.Attempts)
+	}
+	if uplink.checks != 2 {
+		t.Errorf("%d connectivity checks, want one per network failure", uplink.checks)
+	}
+	w.poll(ctx)
+	if len(proc.processed) != 2 {
+		t.Errorf("processed %v while offline, want nothing new", proc.processed)
+	}
+
+	// Other failures count even while offline
+	proc.processErr = errors.New("Unsupported URL")
+	other, _ := repo.Create(ctx, "https://example.com/c")
+	w.processJob(ctx, other)
+	if got := repo.getJob(other.ID); got.Status != domain.StatusFailed {
+		t.Errorf("job = %s, want failed for a non-network error", got.Status)
+	}
+
+	uplink.set(true)
+	proc.processErr = nil
+	w.poll(ctx)
+	if got := repo.getJob(job.ID); got.Status != domain.StatusCompleted {
+		t.Errorf("job = %s, want completed once back online", got.Status)
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// ProvenanceSuffix is appended to a hidden copy of a result file's name,
+// ".video.mp4.provenance", to name its provenance sidecar, written where
+// extended attributes can't be.
+const ProvenanceSuffix = ".provenance"
+
+// provenanceAttr prefixes the extended attributes provenance is written
+// to. Linux only allows unprivileged attributes in the user namespace.
+const provenanceAttr = "user.catcher."
+
+// provenance is where a file came from, as written beside it.
+type provenance struct {
+	URL          string `json:"url"`
+	OriginalURL  string `json:"original_url,omitempty"`
+	JobID        int64  `json:"job_id"`
+	DownloadedAt string `json:"downloaded_at"`
+}
+
+// SetProvenance makes the worker record on each file a job produces the
+// URL it came from, the job ID, and the download date: as extended
+// attributes where the file system supports them, and in a hidden sidecar
+// otherwise. Call before Run.
+func (w *Worker) SetProvenance(enabled bool) {
+	w.provenance = enabled
+}
+
+// writeProvenance records where the files of job came from. A file whose
+// provenance can't be written is logged and left without, rather than
+// failing the job, whose download succeeded.
+func writeProvenance(job *domain.Job, files []domain.ResultFile, at time.Time) {
+	p := provenance{URL: job.URL, JobID: job.ID, DownloadedAt: at.UTC().Format(time.RFC3339)}
+	if job.OriginalURL != job.URL {
+		p.OriginalURL = job.OriginalURL
+	}
+	attrs := map[string]string{
+		"url":           p.URL,
+		"job_id":        strconv.FormatInt(p.JobID, 10),
+		"downloaded_at": p.DownloadedAt,
+	}
+	if p.OriginalURL != "" {
+		attrs["original_url"] = p.OriginalURL
+	}
+	for _, f := range files {
+		err := setXattrs(f.Path, provenanceAttr, attrs)
+		if errors.Is(err, errors.ErrUnsupported) {
+			err = writeProvenanceSidecar(f.Path, p)
+		}
+		if err != nil {
+			log.Printf("job %d: provenance %s: %v", job.ID, f.Path, err)
+		}
+	}
+}
+
+// writeProvenanceSidecar writes p as JSON to the hidden sidecar of the
+// file at path.
+func writeProvenanceSidecar(path string, p provenance) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(provenanceSidecar(path), append(data, '\n'), 0o644)
+}
+
+// provenanceSidecar returns the path of the provenance sidecar of the file
+// at path.
+func provenanceSidecar(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+ProvenanceSuffix)
+}
@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestWorker_Provenance(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(video, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	registry.Register(&replacingProcessor{mockProcessor: mockProcessor{name: "test"}, files: []domain.ResultFile{
+		{Path: video},
+		{Path: filepath.Join(dir, "gone.vtt")}, // vanished; mustn't fail the job
+	}})
+	w := New(svc, registry, time.Second, 3)
+	w.SetProvenance(true)
+	ctx := context.Background()
+
+	job, _ := repo.Create(ctx, "https://example.com/video")
+	w.processJob(ctx, job)
+
+	if got := repo.getJob(job.ID); got.Status != domain.StatusCompleted {
+		t.Fatalf("status = %s, want completed", got.Status)
+	}
+	url, ok := readXattr(t, video, provenanceAttr+"url")
+	if !ok {
+		data, err := os.ReadFile(filepath.Join(dir, ".video.mp4"+ProvenanceSuffix))
+		if err != nil {
+			t.Fatalf("no provenance attribute or sidecar: %v", err)
+		}
+		var p provenance
+		if err := json.Unmarshal(data, &p); err != nil {
+			t.Fatalf("sidecar %q: %v", data, err)
+		}
+		url = p.URL
+	}
+	if url != "https://example.com/video" {
+		t.Errorf("provenance URL = %q, want the job's", url)
+	}
+}
+
+func TestWriteProvenanceSidecar(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "video.mp4")
+	os.WriteFile(video, []byte("x"), 0o644)
+
+	p := provenance{URL: "https://example.com/v", JobID: 7, DownloadedAt: "2026-10-17T08:00:00Z"}
+	if err := writeProvenanceSidecar(video, p); err != nil {
+		t.Fatal(err)
+	}
+	var got provenance
+	data, _ := os.ReadFile(filepath.Join(dir, ".video.mp4.provenance"))
+	if err := json.Unmarshal(data, &got); err != nil || got != p {
+		t.Errorf("sidecar = %s (%v), want %+v", data, err, p)
+	}
+
+	// Removing the file takes its sidecar with it
+	if err := removeFile(video); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(provenanceSidecar(video)); !os.IsNotExist(err) {
+		t.Errorf("sidecar still exists: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// State returns what the worker is doing right now: its running jobs,
+// when it polls, the hosts cooling down, the budget, and any outage.
+func (w *Worker) State() domain.SchedulerState {
+	now := w.clock.Now()
+	st := domain.SchedulerState{PollInterval: w.pollInterval}
+
+	w.mu.Lock()
+	started := w.done != nil
+	w.mu.Unlock()
+	w.stateMu.Lock()
+	switch {
+	case !started:
+		st.State = domain.WorkerIdle
+	case w.finished:
+		st.State = domain.WorkerStopped
+	case w.stopping():
+		st.State = domain.WorkerDraining
+	default:
+		st.State = domain.WorkerRunning
+	}
+	st.Polling, st.LastPoll = w.polling, w.lastPoll
+	if st.State == domain.WorkerRunning && !w.polling && !w.tickStart.IsZero() {
+		ticks := now.Sub(w.tickStart) / w.pollInterval
+		st.NextPoll = w.tickStart.Add((ticks + 1) * w.pollInterval)
+	}
+	st.Running = slices.SortedFunc(maps.Values(w.jobs), func(a, b domain.RunningJob) int {
+		return cmp.Or(a.StartedAt.Compare(b.StartedAt), cmp.Compare(a.JobID, b.JobID))
+	})
+	w.stateMu.Unlock()
+
+	w.cooldownMu.Lock()
+	for _, c := range w.cooldowns {
+		if c.Until.After(now) {
+			st.Cooldowns = append(st.Cooldowns, c)
+		}
+	}
+	w.cooldownMu.Unlock()
+	slices.SortFunc(st.Cooldowns, func(a, b domain.Cooldown) int { return cmp.Compare(a.Host, b.Host) })
+
+	if w.budget != nil {
+		st.Budget = w.budget.state()
+	}
+	st.Outage = w.outageState()
+	return st
+}
+
+// setPolling records that a poll started or ended.
+func (w *Worker) setPolling(polling bool) {
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+	w.polling = polling
+	if polling {
+		w.lastPoll = w.clock.Now()
+	}
+}
+
+// track records that job started running with processor, until untrack.
+func (w *Worker) track(job *domain.Job, processor string) {
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+	w.jobs[job.ID] = domain.RunningJob{
+		JobID:     job.ID,
+		URL:       job.URL,
+		Processor: processor,
+		Queue:     job.Queue,
+		StartedAt: w.clock.Now(),
+	}
+}
+
+func (w *Worker) untrack(id int64) {
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+	delete(w.jobs, id)
+}
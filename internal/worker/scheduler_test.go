@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+func TestWorker_State(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	proc := &blockingProcessor{started: make(chan struct{}), release: make(chan struct{})}
+	registry.Register(proc)
+
+	w := New(svc, registry, 10*time.Millisecond, 3)
+	w.SetBudget(2)
+	w.cooldowns["example.org"] = domain.Cooldown{Host: "example.org", Until: time.Now().Add(time.Hour), Reason: "HTTP 429"}
+	w.cooldowns["example.net"] = domain.Cooldown{Host: "example.net", Until: time.Now().Add(-time.Minute)}
+
+	if st := w.State(); st.State != domain.WorkerIdle || !st.NextPoll.IsZero() || len(st.Running) != 0 {
+		t.Errorf("before Run: State() = %+v", st)
+	}
+
+	job, _ := repo.Create(context.Background(), "https://example.com/v")
+	done := make(chan struct{})
+	go func() {
+		w.Run(context.Background())
+		close(done)
+	}()
+	<-proc.started
+
+	st := w.State()
+	if st.State != domain.WorkerRunning || st.PollInterval != 10*time.Millisecond {
+		t.Errorf("State = %q, PollInterval = %s", st.State, st.PollInterval)
+	}
+	// The default queue alone is processed within the poll
+	if !st.Polling || st.LastPoll.IsZero() || !st.NextPoll.IsZero() {
+		t.Errorf("Polling = %v, LastPoll = %s, NextPoll = %s, want polling", st.Polling, st.LastPoll, st.NextPoll)
+	}
+	if len(st.Running) != 1 || st.Running[0].JobID != job.ID || st.Running[0].Processor != "blocking" || st.Running[0].StartedAt.IsZero() {
+		t.Errorf("Running = %+v", st.Running)
+	}
+	if len(st.Cooldowns) != 1 || st.Cooldowns[0].Host != "example.org" {
+		t.Errorf("Cooldowns = %+v, want only example.org", st.Cooldowns)
+	}
+	if st.Budget == nil || st.Budget.Total != 2 || st.Budget.Used != 1 {
+		t.Errorf("Budget = %+v, want 1 of 2 used", st.Budget)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- w.Shutdown(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+	if st := w.State(); st.State != domain.WorkerDraining {
+		t.Errorf("during Shutdown: State = %q, want %q", st.State, domain.WorkerDraining)
+	}
+	close(proc.release)
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if st := w.State(); st.State != domain.WorkerStopped || len(st.Running) != 0 || st.Budget.Used != 0 {
+		t.Errorf("after Shutdown: State() = %+v", st)
+	}
+}
+
+func TestWorker_State_NextPoll(t *testing.T) {
+	w := New(domain.NewJobService(newMockRepo()), processor.NewRegistry(), time.Hour, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for w.State().NextPoll.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	st := w.State()
+	if st.State != domain.WorkerRunning || st.Polling || !st.LastPoll.IsZero() {
+		t.Errorf("State() = %+v, want running before the first poll", st)
+	}
+	if wait := time.Until(st.NextPoll); wait <= 59*time.Minute || wait > time.Hour {
+		t.Errorf("next poll in %s, want about an hour", wait)
+	}
+}
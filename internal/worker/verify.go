@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// SetVerifier makes the worker check each file a successful run produced
+// with v before completing the job. A run with a broken file fails like
+// any other, and is retried; the broken files are removed first, so the
+// retry fetches them again rather than skipping them as existing. So does
+// a run that made no files, unless it found the media already downloaded.
+// Call before Run.
+func (w *Worker) SetVerifier(v domain.MediaVerifier) {
+	w.verifier = v
+}
+
+// verifyFiles checks the files res holds with the verifier, returning the
+// first problem. A run without files fails, unless it reported the media
+// already downloaded.
+func (w *Worker) verifyFiles(ctx context.Context, jobID int64, res *domain.ProcessResult) error {
+	if len(res.Files) == 0 {
+		if res.AlreadyDone {
+			log.Printf("job %d: nothing to verify, the media was already downloaded", jobID)
+			return nil
+		}
+		return fmt.Errorf("%w: the run made no files", domain.ErrBadMedia)
+	}
+	var first error
+	for _, f := range res.Files {
+		err := w.verifier.Verify(ctx, f.Path)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, domain.ErrBadMedia) {
+			log.Printf("job %d: removing %s: %v", jobID, f.Path, err)
+			if rerr := removeFile(f.Path); rerr != nil && !errors.Is(rerr, os.ErrNotExist) {
+				log.Printf("job %d: remove broken file: %v", jobID, rerr)
+			}
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwygoda/catcher/internal/adapter/processor"
+	"github.com/cwygoda/catcher/internal/domain"
+)
+
+// fakeVerifier fails the files named in bad as broken, and every file with
+// err if set.
+type fakeVerifier struct {
+	bad     map[string]bool
+	err     error
+	checked []string
+}
+
+func (v *fakeVerifier) Verify(ctx context.Context, path string) error {
+	v.checked = append(v.checked, filepath.Base(path))
+	if v.err != nil {
+		return v.err
+	}
+	if v.bad[filepath.Base(path)] {
+		return fmt.Errorf("%w: %s has no duration", domain.ErrBadMedia, filepath.Base(path))
+	}
+	return nil
+}
+
+func TestWorker_Verifier(t *testing.T) {
+	tests := []struct {
+		name        string
+		verifier    *fakeVerifier
+		wantStatus  domain.JobStatus
+		wantError   string
+		wantRemoved []string
+	}{
+		{"all good", &fakeVerifier{}, domain.StatusCompleted, "", nil},
+		{"truncated", &fakeVerifier{bad: map[string]bool{"video.mp4": true}}, domain.StatusPending, "media check failed: video.mp4 has no duration", []string{"video.mp4"}},
+		{"check fails to run", &fakeVerifier{err: errors.New("ffprobe: not found")}, domain.StatusPending, "ffprobe: not found", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var files []domain.ResultFile
+			for _, name := range []string{"video.mp4", "video.en.vtt"} {
+				path := filepath.Join(dir, name)
+				os.WriteFile(path, []byte("x"), 0o644)
+				files = append(files, domain.ResultFile{Path: path, Bytes: 1})
+			}
+
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			registry := processor.NewRegistry()
+			registry.Register(&replacingProcessor{mockProcessor: mockProcessor{name: "test"}, files: files})
+			w := New(svc, registry, time.Second, 3)
+			w.SetVerifier(tt.verifier)
+			ctx := context.Background()
+
+			job, _ := repo.Create(ctx, "https://example.com/video")
+			w.processJob(ctx, job)
+
+			got := repo.getJob(job.ID)
+			if got.Status != tt.wantStatus || !strings.Contains(got.Error, tt.wantError) {
+				t.Errorf("job = %s %q, want %s %q", got.Status, got.Error, tt.wantStatus, tt.wantError)
+			}
+			if len(tt.verifier.checked) != 2 {
+				t.Errorf("checked %v, want every file", tt.verifier.checked)
+			}
+			var removed []string
+			for _, f := range files {
+				if _, err := os.Stat(f.Path); os.IsNotExist(err) {
+					removed = append(removed, filepath.Base(f.Path))
+				}
+			}
+			if fmt.Sprint(removed) != fmt.Sprint(tt.wantRemoved) {
+				t.Errorf("removed %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+// resultProcessor returns res from every run.
+type resultProcessor struct {
+	mockProcessor
+	res domain.ProcessResult
+}
+
+func (p *resultProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
+	res := p.res
+	return &res, nil
+}
+
+func TestWorker_VerifierWithoutFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		res        domain.ProcessResult
+		wantStatus domain.JobStatus
+		wantError  string
+	}{
+		{"already downloaded", domain.ProcessResult{AlreadyDone: true}, domain.StatusCompleted, ""},
+		{"nothing made", domain.ProcessResult{}, domain.StatusPending, "media check failed: the run made no files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			registry := processor.NewRegistry()
+			registry.Register(&resultProcessor{mockProcessor: mockProcessor{name: "test"}, res: tt.res})
+			w := New(svc, registry, time.Second, 3)
+			w.SetVerifier(&fakeVerifier{})
+			ctx := context.Background()
+
+			job, _ := repo.Create(ctx, "https://example.com/video")
+			w.processJob(ctx, job)
+
+			got := repo.getJob(job.ID)
+			if got.Status != tt.wantStatus || got.Error != tt.wantError {
+				t.Errorf("job = %s %q, want %s %q", got.Status, got.Error, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
@@ -2,19 +2,83 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cwygoda/catcher/internal/adapter/processor"
 	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/logging"
 )
 
-// Worker polls for pending jobs and processes them.
+// Job outcomes reported to an Observer.
+const (
+	OutcomeCompleted = "completed"
+	OutcomeRetry     = "retry"
+	OutcomeFailed    = "failed"
+)
+
+// Observer receives the outcome of every job the worker handles.
+// processor is empty when no processor matched the URL.
+type Observer interface {
+	JobFinished(job *domain.Job, processor, outcome string, elapsed time.Duration)
+}
+
+// QueueObserver is implemented by observers that track queues. Each poll
+// reports every queue's pending jobs, held ones included, and how many of
+// its jobs are running.
+type QueueObserver interface {
+	QueueDepth(queue string, pending int64, running int)
+}
+
+// Worker polls for pending jobs and processes them. Jobs in the default
+// queue run one at a time; a processor with a queue of its own (see
+// domain.Queuer) runs its jobs alongside, as many at once as it allows.
 type Worker struct {
 	svc          *domain.JobService
 	registry     *processor.Registry
 	pollInterval time.Duration
 	maxRetries   int
+	observer     Observer
+	workDir      string
+	budget       *budget // nil when unlimited
+	stallTimeout time.Duration
+	checksums    bool
+	provenance   bool
+	verifier     domain.MediaVerifier
+	clock        domain.Clock
+	outage       *outage // nil unless outages are detected
+	connectivity domain.ConnectivityMonitor
+
+	inFlight atomic.Int64
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	lanes   map[string]*lane // by queue, only touched by poll
+	running sync.WaitGroup   // lanes processing a batch
+
+	cooldownMu sync.Mutex
+	cooldowns  map[string]domain.Cooldown // by host
+
+	stateMu   sync.Mutex
+	jobs      map[int64]domain.RunningJob // running, by ID
+	tickStart time.Time                   // when polling started
+	lastPoll  time.Time
+	polling   bool
+	finished  bool // Run returned
+
+	mu         sync.Mutex
+	done       chan struct{}
+	cancelJobs context.CancelFunc
 }
 
 // New creates a new worker.
@@ -24,38 +88,582 @@ func New(svc *domain.JobService, registry *processor.Registry, pollInterval time
 		registry:     registry,
 		pollInterval: pollInterval,
 		maxRetries:   maxRetries,
+		stop:         make(chan struct{}),
+		lanes:        make(map[string]*lane),
+		cooldowns:    make(map[string]domain.Cooldown),
+		jobs:         make(map[int64]domain.RunningJob),
+		clock:        domain.SystemClock,
 	}
 }
 
-// Run starts the worker loop until context is cancelled.
+// lane processes the jobs of one queue.
+type lane struct {
+	busy    atomic.Bool  // processing a batch
+	running atomic.Int64 // jobs in progress
+}
+
+// SetObserver registers an observer for job outcomes. Call before Run.
+func (w *Worker) SetObserver(o Observer) {
+	w.observer = o
+}
+
+// SetWorkDir sets the directory under which each job gets its own working
+// directory, handed to processors via domain.WorkDirFrom. Without one,
+// processors manage their scratch space themselves. Call before Run.
+func (w *Worker) SetWorkDir(dir string) {
+	w.workDir = dir
+}
+
+// SetBudget caps the total cost of the jobs running at once, across all
+// queues, where each job costs what its processor declares (see
+// domain.Coster). Zero or less leaves it to each queue's concurrency. Call
+// before Run.
+func (w *Worker) SetBudget(total int) {
+	w.budget = nil
+	if total > 0 {
+		w.budget = newBudget(total)
+	}
+}
+
+// SetStallTimeout enables the watchdog for running jobs: one whose
+// heartbeat (see domain.HeartbeatFrom) hasn't beaten for d is warned about,
+// and once it has been quiet for twice d its context is cancelled, which
+// stops its command, and it is retried. Zero disables it. Call before Run.
+func (w *Worker) SetStallTimeout(d time.Duration) {
+	w.stallTimeout = d
+}
+
+// SetClock makes polling, cooldowns, and job timings follow c. The stall
+// watchdog and shutdown drain keep to the wall clock, as they measure
+// progress of real commands. Call before Run.
+func (w *Worker) SetClock(c domain.Clock) {
+	w.clock = c
+}
+
+// jobDirPrefix names per-job working directories, followed by the job ID.
+const jobDirPrefix = "job-"
+
+// jobDir returns the job's working directory, creating it if needed. A
+// directory left by an earlier attempt is reused so processors can resume.
+func (w *Worker) jobDir(id int64) (string, error) {
+	if w.workDir == "" {
+		return "", nil
+	}
+	dir := filepath.Join(w.workDir, jobDirPrefix+strconv.FormatInt(id, 10))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create work dir: %w", err)
+	}
+	if err := clearLeftovers(dir); err != nil {
+		return "", fmt.Errorf("clear work dir: %w", err)
+	}
+	return dir, nil
+}
+
+// partialSuffixes mark the partial downloads of yt-dlp, curl, wget, and
+// aria2, which a retry resumes.
+var partialSuffixes = []string{".part", ".ytdl", ".aria2"}
+
+// clearLeftovers removes what an earlier attempt left in dir, so it isn't
+// delivered as this attempt's output, except partial downloads: files
+// with a partial suffix, and the files aria2 keeps a control file for.
+func clearLeftovers(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if slices.ContainsFunc(partialSuffixes, func(suffix string) bool { return strings.HasSuffix(path, suffix) }) {
+			return nil
+		}
+		if _, err := os.Stat(path + ".aria2"); err == nil {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return err
+	}
+	// Then the directories left empty, deepest first
+	var dirs []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != dir {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for _, d := range slices.Backward(dirs) {
+		os.Remove(d) // fails, as wanted, unless empty
+	}
+	return nil
+}
+
+// removeJobDir deletes a job's working directory once the job is done.
+func (w *Worker) removeJobDir(id int64, dir string) {
+	if dir == "" {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("job %d: remove work dir: %v", id, err)
+	}
+}
+
+// removeReplaced deletes the files of the job's previous completed run of
+// the same URL that the new run didn't overwrite, except those the run
+// found in the library.
+func (w *Worker) removeReplaced(ctx context.Context, job *domain.Job) {
+	prev, err := w.svc.LastCompleted(ctx, job)
+	if err != nil {
+		log.Printf("job %d: find replaced job: %v", job.ID, err)
+		return
+	}
+	if prev == nil {
+		return
+	}
+	// The new run may have written over the old files rather than beside
+	// them; those are its own now
+	keep := make(map[string]bool, len(job.Files))
+	for _, f := range job.Files {
+		keep[filepath.Clean(f.Path)] = true
+	}
+	removed := 0
+	for _, f := range prev.Files {
+		if f.Library || keep[filepath.Clean(f.Path)] {
+			continue
+		}
+		if err := removeFile(f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("job %d: remove replaced file: %v", job.ID, err)
+			continue
+		}
+		removed++
+	}
+	log.Printf("job %d: replaced job %d, removed %d old file(s)", job.ID, prev.ID, removed)
+}
+
+// CleanWorkDirs removes working directories whose job has completed,
+// failed, or no longer exists, such as those left behind by a crash. It
+// returns the number removed.
+func (w *Worker) CleanWorkDirs(ctx context.Context) (int, error) {
+	if w.workDir == "" {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(w.workDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), jobDirPrefix)
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		job, err := w.svc.Get(ctx, id)
+		switch {
+		case errors.Is(err, domain.ErrJobNotFound):
+		case err != nil:
+			return removed, err
+		case job.Status == domain.StatusPending || job.Status == domain.StatusProcessing:
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(w.workDir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (w *Worker) observe(job *domain.Job, processor, outcome string, elapsed time.Duration) {
+	if w.observer != nil {
+		w.observer.JobFinished(job, processor, outcome, elapsed)
+	}
+}
+
+// Run starts the worker loop until context is cancelled or Shutdown is called.
 func (w *Worker) Run(ctx context.Context) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+	defer w.running.Wait()
+	w.mu.Lock()
+	w.done = done
+	w.cancelJobs = cancel
+	w.mu.Unlock()
+
+	w.loadCooldowns(ctx)
+	w.loadOutage(ctx)
 	log.Printf("worker started, polling every %s", w.pollInterval)
-	ticker := time.NewTicker(w.pollInterval)
+	ticker := w.clock.NewTicker(w.pollInterval)
 	defer ticker.Stop()
+	w.stateMu.Lock()
+	w.tickStart = w.clock.Now()
+	w.stateMu.Unlock()
+	defer func() {
+		w.stateMu.Lock()
+		w.finished = true
+		w.stateMu.Unlock()
+	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("worker shutting down")
 			return
+		case <-w.stop:
+			log.Println("worker stopped polling")
+			return
+		case <-ticker.C():
+			w.setPolling(true)
+			w.poll(jobCtx)
+			w.setPolling(false)
+		}
+	}
+}
+
+// Shutdown stops polling and waits for in-flight jobs to finish. If ctx
+// expires first, in-flight jobs are cancelled and ctx.Err() is returned.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	w.mu.Lock()
+	done, cancelJobs := w.done, w.cancelJobs
+	w.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+
+	if n := w.InFlight(); n > 0 {
+		log.Printf("draining: %d job(s) in flight", n)
+	}
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
 		case <-ticker.C:
-			w.poll(ctx)
+			log.Printf("draining: %d job(s) in flight", w.InFlight())
+		case <-ctx.Done():
+			log.Printf("shutdown grace expired, cancelling %d job(s)", w.InFlight())
+			cancelJobs()
+			<-done
+			return ctx.Err()
 		}
 	}
 }
 
+// InFlight returns the number of jobs currently being processed.
+func (w *Worker) InFlight() int {
+	return int(w.inFlight.Load())
+}
+
+func (w *Worker) stopping() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// pollBatch is how many jobs a queue takes per poll, at least.
+const pollBatch = 10
+
 func (w *Worker) poll(ctx context.Context) {
-	jobs, err := w.svc.GetPending(ctx, 10)
-	if err != nil {
-		log.Printf("poll error: %v", err)
+	queues := w.registry.Queues()
+	w.reportQueues(ctx, queues)
+	if w.holding(ctx) {
+		return
+	}
+	if len(queues) == 0 {
+		// Only the default queue: process it right here, as a batch
+		jobs, err := w.svc.GetPending(ctx, pollBatch)
+		if err != nil {
+			log.Printf("poll error: %v", err)
+			return
+		}
+		logging.Debugf("poll: %d pending job(s)", len(jobs))
+		w.process(ctx, w.lane(domain.DefaultQueue), jobs, 1)
 		return
 	}
 
-	for _, job := range jobs {
-		if ctx.Err() != nil {
+	active := slices.Sorted(maps.Keys(queues))
+	for _, queue := range append(active, domain.DefaultQueue) {
+		if ctx.Err() != nil || w.stopping() {
 			return
 		}
-		w.processJob(ctx, &job)
+		l := w.lane(queue)
+		if l.busy.Load() {
+			continue
+		}
+		slots := max(queues[queue], 1)
+		jobs, err := w.svc.GetPendingIn(ctx, queue, active, max(pollBatch, slots))
+		if err != nil {
+			log.Printf("poll error: %v", err)
+			return
+		}
+		if len(jobs) == 0 {
+			continue
+		}
+		logging.Debugf("poll: %d pending job(s) in queue %s", len(jobs), queueLabel(queue))
+		l.busy.Store(true)
+		w.running.Add(1)
+		go func() {
+			defer w.running.Done()
+			defer l.busy.Store(false)
+			w.process(ctx, l, jobs, slots)
+		}()
+	}
+}
+
+// lane returns the lane for queue, creating it on first use.
+func (w *Worker) lane(queue string) *lane {
+	l, ok := w.lanes[queue]
+	if !ok {
+		l = &lane{}
+		w.lanes[queue] = l
+	}
+	return l
+}
+
+// process runs a batch of jobs from l's queue, slots at a time, stopping
+// early on shutdown or once the network is found down.
+func (w *Worker) process(ctx context.Context, l *lane, jobs []domain.Job, slots int) {
+	next := make(chan *domain.Job)
+	var wg sync.WaitGroup
+	for range min(slots, len(jobs)) {
+		wg.Go(func() {
+			for job := range next {
+				release, ok := w.acquire(ctx, job)
+				if !ok {
+					continue
+				}
+				l.running.Add(1)
+				w.processJob(ctx, job)
+				l.running.Add(-1)
+				release()
+			}
+		})
+	}
+	for i := range jobs {
+		if ctx.Err() != nil || w.stopping() || w.networkDown() {
+			break
+		}
+		next <- &jobs[i]
+	}
+	close(next)
+	wg.Wait()
+}
+
+// acquire takes the job's cost from the budget, if there is one, and
+// returns a func giving it back. It reports false, holding nothing, if the
+// worker stopped while the job waited.
+func (w *Worker) acquire(ctx context.Context, job *domain.Job) (func(), bool) {
+	if w.budget == nil {
+		return func() {}, true
+	}
+	cost := w.registry.Cost(job.URL)
+	if err := w.budget.acquire(ctx, cost); err != nil {
+		return nil, false
+	}
+	if w.stopping() {
+		w.budget.release(cost)
+		return nil, false
+	}
+	return func() { w.budget.release(cost) }, true
+}
+
+// reportQueues passes each queue's depth to the observer, if it tracks
+// queues. Jobs left in queues no processor has anymore count toward the
+// default queue, which processes them.
+func (w *Worker) reportQueues(ctx context.Context, queues map[string]int) {
+	qo, ok := w.observer.(QueueObserver)
+	if !ok {
+		return
+	}
+	pending, err := w.svc.PendingByQueue(ctx)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return
+	}
+	if err != nil {
+		log.Printf("queue depth: %v", err)
+		return
+	}
+	depth := make(map[string]int64, len(queues)+1)
+	depth[domain.DefaultQueue] = 0
+	for queue := range queues {
+		depth[queue] = 0
+	}
+	for queue, n := range pending {
+		if _, ok := depth[queue]; !ok {
+			queue = domain.DefaultQueue
+		}
+		depth[queue] += n
+	}
+	for queue, n := range depth {
+		var running int
+		if l, ok := w.lanes[queue]; ok {
+			running = int(l.running.Load())
+		}
+		qo.QueueDepth(queue, n, running)
+	}
+}
+
+// queueLabel names a queue in logs.
+func queueLabel(queue string) string {
+	if queue == domain.DefaultQueue {
+		return "default"
+	}
+	return queue
+}
+
+// checkSize probes the size of a job whose processor has a size limit and,
+// if it's over, sends the job back for approval or fails it as the
+// processor's policy says. It reports whether to go on processing. Jobs an
+// admin approved, bookmarks, which download nothing, and jobs whose size
+// can't be probed, go on.
+func (w *Worker) checkSize(ctx context.Context, job *domain.Job, proc domain.URLProcessor) bool {
+	p, ok := proc.(domain.SizeProber)
+	if !ok || job.Approved || job.Bookmark {
+		return true
+	}
+	limit, policy := p.SizeLimit()
+	if limit <= 0 {
+		return true
+	}
+	start := w.clock.Now()
+	size, err := p.ProbeSize(domain.WithUserAgent(ctx, job.UserAgent), job.URL)
+	if err != nil {
+		log.Printf("job %d: size probe failed, processing anyway: %v", job.ID, err)
+		return true
+	}
+	logging.Debugf("job %d: estimated size %d bytes, limit %d", job.ID, size, limit)
+	if size <= limit {
+		return true
+	}
+
+	reason := (&domain.TooLargeError{Size: size, Limit: limit}).Error()
+	if policy == domain.OversizeHold {
+		err := w.svc.RequestApproval(ctx, job.ID, reason)
+		if err == nil {
+			log.Printf("job %d: %s, awaiting approval", job.ID, reason)
+			return false
+		}
+		log.Printf("job %d: request approval failed, failing instead: %v", job.ID, err)
+	}
+	log.Printf("job %d: %s", job.ID, reason)
+	w.svc.MarkFailed(ctx, job.ID, reason)
+	w.observe(job, proc.Name(), OutcomeFailed, w.clock.Now().Sub(start))
+	return false
+}
+
+// loadCooldowns resumes the host cooldowns stored before a restart.
+func (w *Worker) loadCooldowns(ctx context.Context) {
+	cooldowns, err := w.svc.Cooldowns(ctx)
+	if err != nil {
+		log.Printf("load host cooldowns: %v", err)
+		return
+	}
+	w.cooldownMu.Lock()
+	defer w.cooldownMu.Unlock()
+	for _, c := range cooldowns {
+		w.cooldowns[c.Host] = c
+		log.Printf("host %s cooling down until %s", c.Host, c.Until.Format(time.DateTime))
+	}
+}
+
+// cooldown returns the cooldown of the URL's host, if it has one.
+func (w *Worker) cooldown(url string) (domain.Cooldown, bool) {
+	host := domain.CooldownHost(url)
+	w.cooldownMu.Lock()
+	defer w.cooldownMu.Unlock()
+	c, ok := w.cooldowns[host]
+	if ok && !c.Until.After(w.clock.Now()) {
+		delete(w.cooldowns, host)
+		return c, false
+	}
+	return c, ok
+}
+
+// coolDown keeps jobs for the host of job's URL waiting for after, when
+// its processor hinted at a retry delay, unless the host already cools
+// down for longer. The cooldown is stored so it survives a restart.
+func (w *Worker) coolDown(ctx context.Context, job *domain.Job, after time.Duration, reason string) {
+	host := domain.CooldownHost(job.URL)
+	if host == "" {
+		return
+	}
+	c := domain.Cooldown{Host: host, Until: w.clock.Now().Add(after), Reason: reason}
+	w.cooldownMu.Lock()
+	if prev, ok := w.cooldowns[host]; ok && !prev.Until.Before(c.Until) {
+		w.cooldownMu.Unlock()
+		return
+	}
+	w.cooldowns[host] = c
+	w.cooldownMu.Unlock()
+
+	log.Printf("job %d: host %s cooling down for %s", job.ID, host, after)
+	if err := w.svc.CoolDown(ctx, c); err != nil {
+		log.Printf("job %d: store host cooldown: %v", job.ID, err)
+	}
+}
+
+// watch runs the stall watchdog over a job's Process call. The returned
+// context is cancelled once heartbeat has been quiet for twice the stall
+// timeout; unwatch stops watching and returns the domain.ErrStalled the
+// context was cancelled with, if it was.
+func (w *Worker) watch(ctx context.Context, jobID int64, heartbeat *domain.Heartbeat) (context.Context, func() error) {
+	if w.stallTimeout <= 0 {
+		return ctx, func() error { return nil }
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(w.stallTimeout / 10)
+		defer ticker.Stop()
+		warned := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			quiet := heartbeat.Since()
+			switch {
+			case quiet >= 2*w.stallTimeout:
+				log.Printf("job %d: no progress for %s, stopping it", jobID, quiet.Round(time.Second))
+				cancel(fmt.Errorf("%w: no progress for %s", domain.ErrStalled, quiet.Round(time.Second)))
+				return
+			case quiet >= w.stallTimeout && !warned:
+				log.Printf("job %d: no progress for %s, stopping it unless there is some within %s",
+					jobID, quiet.Round(time.Second), (2*w.stallTimeout - quiet).Round(time.Second))
+				warned = true
+			case quiet < w.stallTimeout && warned:
+				log.Printf("job %d: progressing again", jobID)
+				warned = false
+			}
+		}
+	}()
+	return ctx, func() error {
+		close(done)
+		<-exited
+		err := context.Cause(ctx)
+		cancel(nil)
+		if errors.Is(err, domain.ErrStalled) {
+			return err
+		}
+		return nil
 	}
 }
 
@@ -63,7 +671,17 @@ func (w *Worker) processJob(ctx context.Context, job *domain.Job) {
 	proc := w.registry.Match(job.URL)
 	if proc == nil {
 		log.Printf("job %d: no processor for URL %s", job.ID, job.URL)
-		w.svc.MarkFailed(ctx, job.ID, "no processor for URL")
+		w.svc.MarkFailed(ctx, job.ID, domain.ErrNoProcessor.Error())
+		w.observe(job, "", OutcomeFailed, 0)
+		return
+	}
+
+	if c, ok := w.cooldown(job.URL); ok {
+		log.Printf("job %d: host %s cooling down until %s, postponed", job.ID, c.Host, c.Until.Format(time.DateTime))
+		reason := fmt.Sprintf("postponed: host %s is cooling down until %s", c.Host, c.Until.Format(time.RFC3339))
+		if err := w.svc.DelayPending(ctx, job.ID, reason, c.Until.Sub(w.clock.Now())); err != nil {
+			log.Printf("job %d: postpone: %v", job.ID, err)
+		}
 		return
 	}
 
@@ -72,6 +690,11 @@ func (w *Worker) processJob(ctx context.Context, job *domain.Job) {
 		return
 	}
 
+	w.inFlight.Add(1)
+	defer w.inFlight.Add(-1)
+	w.track(job, proc.Name())
+	defer w.untrack(job.ID)
+
 	log.Printf("job %d: processing with %s -> %s", job.ID, proc.Name(), proc.TargetDir())
 
 	// Refresh job to get updated attempts count
@@ -81,16 +704,107 @@ func (w *Worker) processJob(ctx context.Context, job *domain.Job) {
 		return
 	}
 
-	if err := proc.Process(ctx, job); err != nil {
+	if !w.checkLibrary(ctx, job, proc) {
+		return
+	}
+	if !w.checkSize(ctx, job, proc) {
+		return
+	}
+
+	start := w.clock.Now()
+	attempt := &domain.Attempt{Number: job.Attempts, Processor: proc.Name(), StartedAt: start}
+	var res *domain.ProcessResult
+	dir, err := w.jobDir(job.ID)
+	if err == nil {
+		heartbeat := domain.NewHeartbeat()
+		procCtx, unwatch := w.watch(ctx, job.ID, heartbeat)
+		procCtx = domain.WithHeartbeat(domain.WithWorkDir(domain.WithAttempt(procCtx, attempt), dir), heartbeat)
+		res, err = proc.Process(procCtx, job)
+		if stalled := unwatch(); stalled != nil {
+			// Whatever the command made of being stopped, the run is incomplete
+			res, err = nil, stalled
+		}
+		if err == nil && res != nil && w.verifier != nil {
+			err = w.verifyFiles(ctx, job.ID, res)
+		}
+	}
+	attempt.FinishedAt = w.clock.Now()
+	if res == nil {
+		res = &domain.ProcessResult{}
+	}
+	for _, warning := range res.Warnings {
+		log.Printf("job %d: warning: %s", job.ID, logging.Redact(warning))
+	}
+	var reason string
+	if err != nil {
+		reason = logging.Redact(logging.Mask(err.Error()))
+		attempt.Error = reason
+	}
+	// Processors mask what they record, but not all processors are ours
+	attempt.Command = logging.Mask(attempt.Command)
+	attempt.Output = logging.Mask(attempt.Output)
+	// Record even if ctx was cancelled mid-run so the history shows why
+	if rerr := w.svc.RecordAttempt(context.WithoutCancel(ctx), job.ID, *attempt); rerr != nil {
+		log.Printf("job %d: record attempt failed: %v", job.ID, rerr)
+	}
+
+	if err != nil {
 		log.Printf("job %d: process error: %v", job.ID, err)
-		if job.CanRetry(w.maxRetries) {
-			w.svc.MarkRetry(ctx, job.ID, err.Error())
+		if res.RetryAfter > 0 {
+			w.coolDown(ctx, job, res.RetryAfter, reason)
+		}
+		if w.offline(ctx, reason) {
+			err := w.svc.Postpone(ctx, job.ID, reason, 0)
+			if err == nil {
+				log.Printf("job %d: uplink is down, retrying once it is back without counting this attempt", job.ID)
+				w.observe(job, proc.Name(), OutcomeRetry, w.clock.Now().Sub(start))
+				return
+			}
+			log.Printf("job %d: postpone failed, retrying as usual: %v", job.ID, err)
+		}
+		var quota *domain.QuotaError
+		if errors.As(err, &quota) {
+			log.Printf("job %d: over its work dir quota, failing without further attempts", job.ID)
+		}
+		if job.CanRetry(w.maxRetries) && quota == nil {
+			if res.RetryAfter > 0 {
+				log.Printf("job %d: processor asked to retry after %s", job.ID, res.RetryAfter)
+			}
+			w.svc.MarkRetryAfter(ctx, job.ID, reason, res.RetryAfter)
+			w.jobFinished(ctx, job, reason, false)
+			w.observe(job, proc.Name(), OutcomeRetry, w.clock.Now().Sub(start))
 		} else {
-			w.svc.MarkFailed(ctx, job.ID, err.Error())
+			w.svc.MarkFailed(ctx, job.ID, reason)
+			w.jobFinished(ctx, job, reason, true)
+			w.removeJobDir(job.ID, dir)
+			w.observe(job, proc.Name(), OutcomeFailed, w.clock.Now().Sub(start))
 		}
 		return
 	}
 
-	log.Printf("job %d: completed with %s for %s", job.ID, proc.Name(), job.URL)
-	w.svc.MarkComplete(ctx, job.ID)
+	if w.checksums {
+		writeChecksums(job.ID, res.Files)
+	}
+	if w.provenance {
+		writeProvenance(job, res.Files, attempt.FinishedAt)
+	}
+	job.Title, job.Bytes, job.Files = res.Title, res.Bytes, res.Files
+	log.Printf("job %d: completed with %s for %s (%d bytes)", job.ID, proc.Name(), job.URL, job.Bytes)
+	err = w.svc.MarkComplete(ctx, job.ID, domain.Completion{
+		Processor: proc.Name(),
+		Title:     job.Title,
+		Bytes:     job.Bytes,
+		Files:     job.Files,
+		Duration:  attempt.FinishedAt.Sub(start),
+	})
+	if err != nil {
+		log.Printf("job %d: mark complete failed: %v", job.ID, err)
+	} else {
+		w.jobFinished(ctx, job, "", false)
+		w.removeJobDir(job.ID, dir)
+		if p, ok := proc.(domain.ResubmitPolicer); ok && p.ResubmitPolicy() == domain.ResubmitReplace {
+			w.removeReplaced(ctx, job)
+		}
+	}
+	w.observe(job, proc.Name(), OutcomeCompleted, w.clock.Now().Sub(start))
 }
@@ -3,23 +3,32 @@ package worker
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/cwygoda/catcher/internal/adapter/processor"
 	"github.com/cwygoda/catcher/internal/domain"
+	"github.com/cwygoda/catcher/internal/logging"
 )
 
 // mockRepo implements domain.JobRepository for testing.
 type mockRepo struct {
-	mu     sync.Mutex
-	jobs   map[int64]*domain.Job
-	nextID int64
+	mu      sync.Mutex
+	jobs    map[int64]*domain.Job
+	nextID  int64
+	retryAt map[int64]time.Time
 }
 
 func newMockRepo() *mockRepo {
-	return &mockRepo{jobs: make(map[int64]*domain.Job), nextID: 1}
+	return &mockRepo{jobs: make(map[int64]*domain.Job), nextID: 1, retryAt: make(map[int64]time.Time)}
 }
 
 func (m *mockRepo) Create(ctx context.Context, url string) (*domain.Job, error) {
@@ -29,6 +38,7 @@ func (m *mockRepo) Create(ctx context.Context, url string) (*domain.Job, error)
 		ID:        m.nextID,
 		URL:       url,
 		Status:    domain.StatusPending,
+		Queue:     domain.QueueFrom(ctx),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -64,6 +74,36 @@ func (m *mockRepo) FindPending(ctx context.Context, limit int) ([]domain.Job, er
 	return result, nil
 }
 
+func (m *mockRepo) FindPendingIn(ctx context.Context, queue string, active []string, limit int) ([]domain.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []domain.Job
+	for id := int64(1); id < m.nextID && len(result) < limit; id++ {
+		job := m.jobs[id]
+		in := job.Queue == queue || queue == domain.DefaultQueue && !slices.Contains(active, job.Queue)
+		if job.Status == domain.StatusPending && in {
+			result = append(result, *job)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRepo) PendingByQueue(ctx context.Context) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[string]int64)
+	for _, job := range m.jobs {
+		if job.Status == domain.StatusPending {
+			counts[job.Queue]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *mockRepo) List(ctx context.Context, filter domain.JobFilter) ([]domain.Job, error) {
+	return nil, nil
+}
+
 func (m *mockRepo) Claim(ctx context.Context, id int64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -77,7 +117,7 @@ func (m *mockRepo) Claim(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (m *mockRepo) Complete(ctx context.Context, id int64) error {
+func (m *mockRepo) Complete(ctx context.Context, id int64, c domain.Completion) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	job, ok := m.jobs[id]
@@ -85,6 +125,10 @@ func (m *mockRepo) Complete(ctx context.Context, id int64) error {
 		return domain.ErrJobNotFound
 	}
 	job.Status = domain.StatusCompleted
+	job.Bytes = c.Bytes
+	job.Title = c.Title
+	job.Files = c.Files
+	job.Duration = c.Duration
 	job.UpdatedAt = time.Now()
 	return nil
 }
@@ -115,6 +159,31 @@ func (m *mockRepo) Retry(ctx context.Context, id int64, reason string) error {
 	return nil
 }
 
+func (m *mockRepo) RetryAt(ctx context.Context, id int64, reason string, at time.Time) error {
+	if err := m.Retry(ctx, id, reason); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retryAt[id] = at
+	return nil
+}
+
+func (m *mockRepo) DelayPending(ctx context.Context, id int64, reason string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	if job.Status != domain.StatusPending {
+		return domain.ErrJobState
+	}
+	job.Error = reason
+	m.retryAt[id] = at
+	return nil
+}
+
 func (m *mockRepo) RecoverStale(ctx context.Context) (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -128,18 +197,65 @@ func (m *mockRepo) RecoverStale(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+func (m *mockRepo) LastCompleted(ctx context.Context, url string, beforeID int64) (*domain.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id := beforeID - 1; id > 0; id-- {
+		if job, ok := m.jobs[id]; ok && job.Status == domain.StatusCompleted && job.URL == url {
+			copy := *job
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) CreateForApproval(ctx context.Context, url string) (*domain.Job, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (m *mockRepo) RequestApproval(ctx context.Context, id int64, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[id]
+	job.Status, job.Error = domain.StatusNeedsApproval, reason
+	job.Attempts--
+	return nil
+}
+
+func (m *mockRepo) Approve(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[id]
+	job.Status, job.Error, job.Approved = domain.StatusPending, "", true
+	return nil
+}
+
+func (m *mockRepo) Reject(ctx context.Context, id int64, reason string) error {
+	return errors.ErrUnsupported
+}
+
 func (m *mockRepo) getJob(id int64) *domain.Job {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	return m.jobs[id]
 }
 
+// status returns a job's status, safe while the worker runs.
+func (m *mockRepo) status(id int64) domain.JobStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs[id].Status
+}
+
 // mockProcessor implements domain.URLProcessor for testing.
 type mockProcessor struct {
 	name       string
 	matchFunc  func(string) bool
 	processErr error
+	retryAfter time.Duration
+	bytes      int64
 	processed  []int64
+	workDir    string // as seen by the last Process call
 	mu         sync.Mutex
 }
 
@@ -151,11 +267,19 @@ func (p *mockProcessor) Match(url string) bool {
 	}
 	return true
 }
-func (p *mockProcessor) Process(ctx context.Context, job *domain.Job) error {
+func (p *mockProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
 	p.mu.Lock()
 	p.processed = append(p.processed, job.ID)
+	p.workDir = domain.WorkDirFrom(ctx)
 	p.mu.Unlock()
-	return p.processErr
+	if p.processErr != nil {
+		return &domain.ProcessResult{RetryAfter: p.retryAfter}, p.processErr
+	}
+	return &domain.ProcessResult{
+		Title: "Test Video",
+		Bytes: p.bytes,
+		Files: []domain.ResultFile{{Path: "/tmp/test/out.mp4", Bytes: p.bytes}},
+	}, nil
 }
 
 func TestWorker_ProcessJob_Success(t *testing.T) {
@@ -163,7 +287,7 @@ func TestWorker_ProcessJob_Success(t *testing.T) {
 	svc := domain.NewJobService(repo)
 	registry := processor.NewRegistry()
 
-	proc := &mockProcessor{name: "test"}
+	proc := &mockProcessor{name: "test", bytes: 2048}
 	registry.Register(proc)
 
 	w := New(svc, registry, 100*time.Millisecond, 3)
@@ -180,6 +304,15 @@ func TestWorker_ProcessJob_Success(t *testing.T) {
 	if updated.Status != domain.StatusCompleted {
 		t.Errorf("status = %q, want %q", updated.Status, domain.StatusCompleted)
 	}
+	if updated.Bytes != 2048 {
+		t.Errorf("bytes = %d, want 2048", updated.Bytes)
+	}
+	if len(updated.Files) != 1 || updated.Files[0].Path != "/tmp/test/out.mp4" {
+		t.Errorf("files = %+v, want the processor's result recorded", updated.Files)
+	}
+	if updated.Title != "Test Video" {
+		t.Errorf("title = %q, want the processor's title recorded", updated.Title)
+	}
 }
 
 func TestWorker_ProcessJob_NoProcessor(t *testing.T) {
@@ -228,6 +361,143 @@ func TestWorker_ProcessJob_Retry(t *testing.T) {
 	}
 }
 
+func TestWorker_ProcessJob_QuotaNotRetried(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	quota := &domain.QuotaError{Used: 3 << 30, Limit: 2 << 30}
+	registry.Register(&mockProcessor{name: "test", processErr: fmt.Errorf("run: %w", quota)})
+
+	w := New(svc, registry, 100*time.Millisecond, 3)
+	job, _ := repo.Create(context.Background(), "https://example.com")
+	w.processJob(context.Background(), job)
+
+	updated := repo.getJob(job.ID)
+	if updated.Status != domain.StatusFailed || updated.Attempts != 1 {
+		t.Errorf("job = %s after %d attempt(s), want failed after 1", updated.Status, updated.Attempts)
+	}
+	if !strings.Contains(updated.Error, "disk quota exceeded") {
+		t.Errorf("error = %q, want the quota error", updated.Error)
+	}
+}
+
+func TestWorker_ProcessJob_RetryAfter(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetRetryScheduler(repo)
+	registry := processor.NewRegistry()
+	registry.Register(&mockProcessor{name: "test", processErr: errors.New("429 too many requests"), retryAfter: time.Hour})
+
+	w := New(svc, registry, 100*time.Millisecond, 3)
+	job, _ := repo.Create(context.Background(), "https://example.com")
+	w.processJob(context.Background(), job)
+
+	if got := repo.getJob(job.ID).Status; got != domain.StatusPending {
+		t.Errorf("status = %q, want %q (retry)", got, domain.StatusPending)
+	}
+	if at := repo.retryAt[job.ID]; time.Until(at) < 59*time.Minute {
+		t.Errorf("retry at %v, want about an hour from now", at)
+	}
+}
+
+// mockCooldowns stores cooldowns like the sqlite repository.
+type mockCooldowns struct {
+	mu        sync.Mutex
+	cooldowns map[string]domain.Cooldown
+}
+
+func (m *mockCooldowns) SetCooldown(ctx context.Context, c domain.Cooldown) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cooldowns[c.Host] = c
+	return nil
+}
+
+func (m *mockCooldowns) Cooldowns(ctx context.Context) ([]domain.Cooldown, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Collect(maps.Values(m.cooldowns)), nil
+}
+
+func TestWorker_HostCooldown(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetRetryScheduler(repo)
+	store := &mockCooldowns{cooldowns: make(map[string]domain.Cooldown)}
+	svc.SetCooldownRepository(store)
+	proc := &mockProcessor{name: "test", processErr: errors.New("429 too many requests"), retryAfter: time.Hour}
+	registry := processor.NewRegistry()
+	registry.Register(proc)
+
+	w := New(svc, registry, 100*time.Millisecond, 3)
+	first, _ := repo.Create(context.Background(), "https://www.example.com/a")
+	w.processJob(context.Background(), first)
+	if c, ok := store.cooldowns["example.com"]; !ok || time.Until(c.Until) < 59*time.Minute {
+		t.Fatalf("stored cooldowns = %v, want example.com for about an hour", store.cooldowns)
+	}
+
+	// A restarted worker picks the stored cooldown up again
+	w = New(svc, registry, 100*time.Millisecond, 3)
+	w.loadCooldowns(context.Background())
+	second, _ := repo.Create(context.Background(), "https://example.com/b")
+	other, _ := repo.Create(context.Background(), "https://vimeo.com/c")
+	w.processJob(context.Background(), second)
+	w.processJob(context.Background(), other)
+
+	if !slices.Equal(proc.processed, []int64{first.ID, other.ID}) {
+		t.Errorf("processed %v, want %d and %d but not %d", proc.processed, first.ID, other.ID, second.ID)
+	}
+	postponed := repo.getJob(second.ID)
+	if postponed.Status != domain.StatusPending || postponed.Attempts != 0 {
+		t.Errorf("postponed job = %s with %d attempt(s), want pending with none", postponed.Status, postponed.Attempts)
+	}
+	if at := repo.retryAt[second.ID]; time.Until(at) < 59*time.Minute {
+		t.Errorf("postponed until %v, want about an hour from now", at)
+	}
+}
+
+func TestWorker_HostCooldown_CancelledMeanwhile(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetRetryScheduler(repo)
+	proc := &mockProcessor{name: "test"}
+	registry := processor.NewRegistry()
+	registry.Register(proc)
+	w := New(svc, registry, 100*time.Millisecond, 3)
+	w.cooldowns["example.com"] = domain.Cooldown{Host: "example.com", Until: time.Now().Add(time.Hour)}
+
+	// Found pending, then cancelled before the worker postpones it
+	job, _ := repo.Create(context.Background(), "https://example.com/a")
+	repo.Fail(context.Background(), job.ID, "cancelled")
+	w.processJob(context.Background(), job)
+
+	if got := repo.getJob(job.ID); got.Status != domain.StatusFailed || got.Error != "cancelled" {
+		t.Errorf("job = %s %q, want it left cancelled", got.Status, got.Error)
+	}
+	if len(proc.processed) != 0 {
+		t.Errorf("processed %v, want nothing during the cooldown", proc.processed)
+	}
+}
+
+func TestWorker_ProcessJob_RedactsError(t *testing.T) {
+	logging.SetRedactURLs(true)
+	defer logging.SetRedactURLs(false)
+
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	registry.Register(&mockProcessor{name: "test", processErr: errors.New("403 for https://cdn.example.com/v.mp4?sig=s3cret")})
+
+	w := New(svc, registry, 100*time.Millisecond, 3)
+	job, _ := repo.Create(context.Background(), "https://cdn.example.com/v.mp4?sig=s3cret")
+	w.processJob(context.Background(), job)
+
+	updated := repo.getJob(job.ID)
+	if strings.Contains(updated.Error, "s3cret") || !strings.HasPrefix(updated.Error, "403 for https://cdn.example.com/v.mp4?…#") {
+		t.Errorf("Error = %q, want the URL redacted", updated.Error)
+	}
+}
+
 func TestWorker_ProcessJob_MaxRetriesExceeded(t *testing.T) {
 	repo := newMockRepo()
 	svc := domain.NewJobService(repo)
@@ -255,6 +525,40 @@ func TestWorker_ProcessJob_MaxRetriesExceeded(t *testing.T) {
 	}
 }
 
+func TestWorker_HostCooldownFollowsClock(t *testing.T) {
+	clock := domain.NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetRetryScheduler(repo)
+	svc.SetClock(clock)
+	proc := &mockProcessor{name: "test", processErr: errors.New("429 too many requests"), retryAfter: time.Hour}
+	registry := processor.NewRegistry()
+	registry.Register(proc)
+
+	w := New(svc, registry, 100*time.Millisecond, 3)
+	w.SetClock(clock)
+	first, _ := repo.Create(context.Background(), "https://example.com/a")
+	w.processJob(context.Background(), first)
+	if want := clock.Now().Add(time.Hour); !repo.retryAt[first.ID].Equal(want) {
+		t.Errorf("retry at %v, want %v", repo.retryAt[first.ID], want)
+	}
+
+	clock.Advance(30 * time.Minute)
+	second, _ := repo.Create(context.Background(), "https://example.com/b")
+	w.processJob(context.Background(), second)
+	if want := clock.Now().Add(30 * time.Minute); !repo.retryAt[second.ID].Equal(want) {
+		t.Errorf("postponed until %v, want %v", repo.retryAt[second.ID], want)
+	}
+
+	clock.Advance(30 * time.Minute)
+	proc.processErr = nil
+	third, _ := repo.Create(context.Background(), "https://example.com/c")
+	w.processJob(context.Background(), third)
+	if !slices.Equal(proc.processed, []int64{first.ID, third.ID}) {
+		t.Errorf("processed %v, want %d and %d once the cooldown is over", proc.processed, first.ID, third.ID)
+	}
+}
+
 func TestWorker_Run_Cancellation(t *testing.T) {
 	repo := newMockRepo()
 	svc := domain.NewJobService(repo)
@@ -309,3 +613,613 @@ func TestWorker_Poll_ProcessesJobs(t *testing.T) {
 		t.Errorf("processed %d jobs, want 2", processedCount)
 	}
 }
+
+// blockingProcessor blocks in Process until released or cancelled.
+type blockingProcessor struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingProcessor) Name() string          { return "blocking" }
+func (p *blockingProcessor) TargetDir() string     { return "/tmp/test" }
+func (p *blockingProcessor) Match(url string) bool { return true }
+func (p *blockingProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
+	close(p.started)
+	select {
+	case <-p.release:
+		return &domain.ProcessResult{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// queuedProcessor handles URLs containing "slow" in a queue of its own,
+// blocking in Process until released or cancelled.
+type queuedProcessor struct {
+	concurrency int
+	cost        int
+	started     chan int64
+	release     chan struct{}
+}
+
+func (p *queuedProcessor) Name() string          { return "slow" }
+func (p *queuedProcessor) TargetDir() string     { return "/tmp/test" }
+func (p *queuedProcessor) Match(url string) bool { return strings.Contains(url, "slow") }
+func (p *queuedProcessor) Concurrency() int      { return p.concurrency }
+func (p *queuedProcessor) Cost() int             { return p.cost }
+func (p *queuedProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
+	p.started <- job.ID
+	select {
+	case <-p.release:
+		return &domain.ProcessResult{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// queueObserver records the last reported depth of each queue.
+type queueObserver struct {
+	mu      sync.Mutex
+	pending map[string]int64
+}
+
+func (o *queueObserver) JobFinished(*domain.Job, string, string, time.Duration) {}
+func (o *queueObserver) QueueDepth(queue string, pending int64, running int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending[queue] = pending
+}
+
+func TestWorker_Queues(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	svc.SetQueues(repo, registry.Queue)
+
+	slow := &queuedProcessor{concurrency: 2, started: make(chan int64, 3), release: make(chan struct{})}
+	quick := &mockProcessor{name: "quick", matchFunc: func(url string) bool { return strings.Contains(url, "quick") }}
+	registry.Register(slow)
+	registry.Register(quick)
+
+	ctx := context.Background()
+	for _, u := range []string{"https://example.com/slow1", "https://example.com/slow2", "https://example.com/slow3", "https://example.com/quick"} {
+		if _, err := svc.Submit(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job := repo.getJob(1); job.Queue != "slow" {
+		t.Fatalf("Queue = %q, want slow", job.Queue)
+	}
+
+	obs := &queueObserver{pending: make(map[string]int64)}
+	w := New(svc, registry, 10*time.Millisecond, 3)
+	w.SetObserver(obs)
+	go w.Run(ctx)
+
+	// Two slow jobs run at once, and the quick job isn't stuck behind them
+	for range 2 {
+		select {
+		case <-slow.started:
+		case <-time.After(time.Second):
+			t.Fatal("slow queue didn't run two jobs at once")
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for repo.status(4) != domain.StatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatal("quick job wasn't processed while the slow queue was busy")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Depth is reported at the start of each poll
+	for {
+		obs.mu.Lock()
+		got := obs.pending["slow"]
+		obs.mu.Unlock()
+		if got == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pending in slow queue = %d, want 1", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(slow.release)
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := w.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if w.InFlight() != 0 {
+		t.Errorf("InFlight() = %d after shutdown", w.InFlight())
+	}
+}
+
+func TestWorker_Budget(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	svc.SetQueues(repo, registry.Queue)
+
+	slow := &queuedProcessor{concurrency: 2, cost: 2, started: make(chan int64, 2), release: make(chan struct{})}
+	registry.Register(slow)
+
+	ctx := context.Background()
+	for _, u := range []string{"https://example.com/slow1", "https://example.com/slow2"} {
+		if _, err := svc.Submit(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New(svc, registry, 10*time.Millisecond, 3)
+	w.SetBudget(3)
+	go w.Run(ctx)
+
+	// The queue allows two at once, but the budget only fits one
+	select {
+	case <-slow.started:
+	case <-time.After(time.Second):
+		t.Fatal("first job didn't start")
+	}
+	select {
+	case <-slow.started:
+		t.Fatal("second job started over budget")
+	case <-time.After(50 * time.Millisecond):
+	}
+	slow.release <- struct{}{}
+	select {
+	case <-slow.started:
+	case <-time.After(time.Second):
+		t.Fatal("second job didn't start once the first finished")
+	}
+
+	close(slow.release)
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := w.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+}
+
+// beatingProcessor beats its heartbeat every 10ms for beatFor, then goes
+// quiet until its context is cancelled, unless it is done by then.
+type beatingProcessor struct {
+	mockProcessor
+	beatFor time.Duration
+	done    time.Duration // return success after this long; zero never
+}
+
+func (p *beatingProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
+	start := time.Now()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		if time.Since(start) < p.beatFor {
+			domain.HeartbeatFrom(ctx).Beat()
+		}
+		if p.done > 0 && time.Since(start) >= p.done {
+			return &domain.ProcessResult{}, nil
+		}
+	}
+}
+
+func TestWorker_StallWatchdog(t *testing.T) {
+	tests := []struct {
+		name       string
+		proc       *beatingProcessor
+		wantStatus domain.JobStatus
+	}{
+		{"progressing", &beatingProcessor{beatFor: 300 * time.Millisecond, done: 300 * time.Millisecond}, domain.StatusCompleted},
+		{"quiet from the start", &beatingProcessor{}, domain.StatusPending},
+		{"quiet after a while", &beatingProcessor{beatFor: 100 * time.Millisecond}, domain.StatusPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			registry := processor.NewRegistry()
+			tt.proc.name = "test"
+			registry.Register(tt.proc)
+
+			w := New(svc, registry, 100*time.Millisecond, 3)
+			w.SetStallTimeout(50 * time.Millisecond)
+			job, _ := repo.Create(context.Background(), "https://example.com")
+			w.processJob(context.Background(), job)
+
+			updated := repo.getJob(job.ID)
+			if updated.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q", updated.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == domain.StatusPending && !strings.HasPrefix(updated.Error, "stalled: no progress for") {
+				t.Errorf("error = %q, want the job stopped as stalled", updated.Error)
+			}
+		})
+	}
+}
+
+func TestWorker_Shutdown_DrainsInFlight(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+
+	proc := &blockingProcessor{started: make(chan struct{}), release: make(chan struct{})}
+	registry.Register(proc)
+
+	w := New(svc, registry, 10*time.Millisecond, 3)
+	job, _ := repo.Create(context.Background(), "https://example.com")
+
+	go w.Run(context.Background())
+	<-proc.started
+
+	if got := w.InFlight(); got != 1 {
+		t.Errorf("InFlight() = %d, want 1", got)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- w.Shutdown(context.Background()) }()
+
+	// Shutdown must wait for the running job
+	select {
+	case <-errCh:
+		t.Fatal("Shutdown returned before in-flight job finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(proc.release)
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Shutdown() error = %v, want nil", err)
+	}
+	if updated := repo.getJob(job.ID); updated.Status != domain.StatusCompleted {
+		t.Errorf("status = %q, want %q", updated.Status, domain.StatusCompleted)
+	}
+	if got := w.InFlight(); got != 0 {
+		t.Errorf("InFlight() = %d, want 0", got)
+	}
+}
+
+func TestWorker_Shutdown_GraceExpired(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+
+	proc := &blockingProcessor{started: make(chan struct{}), release: make(chan struct{})}
+	registry.Register(proc)
+
+	w := New(svc, registry, 10*time.Millisecond, 3)
+	repo.Create(context.Background(), "https://example.com")
+
+	go w.Run(context.Background())
+	<-proc.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := w.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWorker_Shutdown_NotRunning(t *testing.T) {
+	w := New(domain.NewJobService(newMockRepo()), processor.NewRegistry(), time.Second, 3)
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v, want nil", err)
+	}
+}
+
+// recordingObserver captures outcomes reported by the worker.
+type recordingObserver struct {
+	mu       sync.Mutex
+	outcomes []string
+}
+
+func (o *recordingObserver) JobFinished(job *domain.Job, processor, outcome string, elapsed time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.outcomes = append(o.outcomes, processor+":"+outcome)
+}
+
+func TestWorker_Observer(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	registry.Register(&mockProcessor{name: "ok", matchFunc: func(u string) bool { return u == "https://example.com/ok" }})
+	registry.Register(&mockProcessor{name: "flaky", processErr: errors.New("boom"), matchFunc: func(u string) bool { return u == "https://example.com/flaky" }})
+
+	w := New(svc, registry, time.Second, 3)
+	obs := &recordingObserver{}
+	w.SetObserver(obs)
+
+	ctx := context.Background()
+	for _, u := range []string{"https://example.com/ok", "https://example.com/flaky", "https://example.com/none"} {
+		job, _ := repo.Create(ctx, u)
+		w.processJob(ctx, job)
+	}
+
+	want := []string{"ok:completed", "flaky:retry", ":failed"}
+	if len(obs.outcomes) != len(want) {
+		t.Fatalf("outcomes = %v, want %v", obs.outcomes, want)
+	}
+	for i := range want {
+		if obs.outcomes[i] != want[i] {
+			t.Errorf("outcome[%d] = %q, want %q", i, obs.outcomes[i], want[i])
+		}
+	}
+}
+
+// mockAttempts implements domain.AttemptRepository for testing.
+type mockAttempts struct {
+	mu       sync.Mutex
+	attempts map[int64][]domain.Attempt
+}
+
+func (m *mockAttempts) AddAttempt(ctx context.Context, jobID int64, a domain.Attempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts[jobID] = append(m.attempts[jobID], a)
+	return nil
+}
+
+func (m *mockAttempts) Attempts(ctx context.Context, jobID int64) ([]domain.Attempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.attempts[jobID], nil
+}
+
+func TestWorker_RecordsAttempts(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	attempts := &mockAttempts{attempts: make(map[int64][]domain.Attempt)}
+	svc.SetAttemptRepository(attempts)
+	registry := processor.NewRegistry()
+	registry.Register(&mockProcessor{name: "flaky", processErr: errors.New("boom")})
+
+	w := New(svc, registry, time.Second, 3)
+	ctx := context.Background()
+	job, _ := repo.Create(ctx, "https://example.com")
+	w.processJob(ctx, job)
+	w.processJob(ctx, repo.getJob(job.ID))
+
+	got, _ := svc.Attempts(ctx, job.ID)
+	if len(got) != 2 {
+		t.Fatalf("recorded %d attempts, want 2", len(got))
+	}
+	for i, a := range got {
+		if a.Number != i+1 || a.Processor != "flaky" || a.Error != "boom" {
+			t.Errorf("attempt[%d] = %+v", i, a)
+		}
+		if a.FinishedAt.Before(a.StartedAt) {
+			t.Errorf("attempt[%d] finished before it started", i)
+		}
+	}
+}
+
+func TestWorker_JobWorkDir(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	proc := &mockProcessor{name: "test", processErr: errors.New("interrupted")}
+	registry.Register(proc)
+
+	root := t.TempDir()
+	w := New(svc, registry, time.Second, 3)
+	w.SetWorkDir(root)
+
+	ctx := context.Background()
+	job, _ := repo.Create(ctx, "https://example.com")
+	w.processJob(ctx, job)
+
+	want := filepath.Join(root, "job-1")
+	if proc.workDir != want {
+		t.Fatalf("processor work dir = %q, want %q", proc.workDir, want)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Fatalf("work dir not kept for retry: %v", err)
+	}
+	// What the failed run left: a partial download to resume, an aria2
+	// download with its control file, and a finished file to discard
+	for _, name := range []string{"video.mp4.part", "big.iso", "big.iso.aria2", "sub/stale.mp4"} {
+		path := filepath.Join(want, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proc.processErr = errors.New("interrupted again")
+	w.processJob(ctx, repo.getJob(job.ID))
+	for name, kept := range map[string]bool{"video.mp4.part": true, "big.iso": true, "big.iso.aria2": true, "sub/stale.mp4": false, "sub": false} {
+		if _, err := os.Stat(filepath.Join(want, name)); (err == nil) != kept {
+			t.Errorf("%s: kept = %v, want %v", name, err == nil, kept)
+		}
+	}
+
+	proc.processErr = nil
+	w.processJob(ctx, repo.getJob(job.ID))
+	if repo.getJob(job.ID).Status != domain.StatusCompleted {
+		t.Fatalf("status = %q, want completed", repo.getJob(job.ID).Status)
+	}
+	if _, err := os.Stat(want); !os.IsNotExist(err) {
+		t.Errorf("work dir not removed after completion: %v", err)
+	}
+}
+
+func TestWorker_CleanWorkDirs(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	ctx := context.Background()
+	pending, _ := repo.Create(ctx, "https://example.com/1")
+	done, _ := repo.Create(ctx, "https://example.com/2")
+	repo.Complete(ctx, done.ID, domain.Completion{})
+
+	root := t.TempDir()
+	for _, name := range []string{"job-1", "job-2", "job-99", "catcher-test-123"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New(svc, processor.NewRegistry(), time.Second, 3)
+	w.SetWorkDir(root)
+	removed, err := w.CleanWorkDirs(ctx)
+	if err != nil {
+		t.Fatalf("CleanWorkDirs() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed = %d, want 2", removed)
+	}
+
+	entries, _ := os.ReadDir(root)
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	if want := []string{"catcher-test-123", "job-" + strconv.FormatInt(pending.ID, 10)}; !slices.Equal(left, want) {
+		t.Errorf("left = %v, want %v", left, want)
+	}
+}
+
+// replacingProcessor produces files and replaces earlier downloads.
+type replacingProcessor struct {
+	mockProcessor
+	files []domain.ResultFile
+}
+
+func (p *replacingProcessor) ResubmitPolicy() domain.ResubmitPolicy { return domain.ResubmitReplace }
+func (p *replacingProcessor) Process(ctx context.Context, job *domain.Job) (*domain.ProcessResult, error) {
+	return &domain.ProcessResult{Files: p.files}, nil
+}
+
+func TestWorker_ResubmitReplace(t *testing.T) {
+	dir := t.TempDir()
+	oldFile, shared := filepath.Join(dir, "v1.mp4"), filepath.Join(dir, "video.info.json")
+	for _, path := range []string{oldFile, shared} {
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	svc.SetResubmitPolicy(repo, registry.ResubmitPolicy)
+	proc := &replacingProcessor{mockProcessor: mockProcessor{name: "replace"}}
+	registry.Register(proc)
+	w := New(svc, registry, time.Second, 3)
+	ctx := context.Background()
+
+	first, _ := repo.Create(ctx, "https://example.com/video")
+	proc.files = []domain.ResultFile{{Path: oldFile}, {Path: shared}}
+	w.processJob(ctx, first)
+
+	second, _ := repo.Create(ctx, "https://example.com/video")
+	// The same file, named as the tool printed it
+	proc.files = []domain.ResultFile{{Path: filepath.Join(dir, "v2.mp4")}, {Path: dir + "/./video.info.json"}}
+	w.processJob(ctx, second)
+
+	if _, err := os.Stat(oldFile); !os.IsNotExist(err) {
+		t.Errorf("replaced file still exists: %v", err)
+	}
+	if _, err := os.Stat(shared); err != nil {
+		t.Errorf("file overwritten by the new run was removed: %v", err)
+	}
+}
+
+type sizedProcessor struct {
+	mockProcessor
+	size, limit int64
+	policy      domain.OversizePolicy
+	probeErr    error
+}
+
+func (p *sizedProcessor) SizeLimit() (int64, domain.OversizePolicy) { return p.limit, p.policy }
+func (p *sizedProcessor) ProbeSize(ctx context.Context, url string) (int64, error) {
+	return p.size, p.probeErr
+}
+
+func TestWorker_SizeLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		proc       *sizedProcessor
+		wantStatus domain.JobStatus
+		wantRun    bool
+	}{
+		{"under limit", &sizedProcessor{size: 100, limit: 1000, policy: domain.OversizeHold}, domain.StatusCompleted, true},
+		{"no limit", &sizedProcessor{size: 5000, policy: domain.OversizeFail}, domain.StatusCompleted, true},
+		{"probe fails", &sizedProcessor{limit: 1000, policy: domain.OversizeFail, probeErr: errors.New("boom")}, domain.StatusCompleted, true},
+		{"over limit, hold", &sizedProcessor{size: 5000, limit: 1000, policy: domain.OversizeHold}, domain.StatusNeedsApproval, false},
+		{"over limit, fail", &sizedProcessor{size: 5000, limit: 1000, policy: domain.OversizeFail}, domain.StatusFailed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepo()
+			svc := domain.NewJobService(repo)
+			svc.SetApproval(repo, nil)
+			registry := processor.NewRegistry()
+			tt.proc.name = "sized"
+			registry.Register(tt.proc)
+			w := New(svc, registry, time.Second, 3)
+			ctx := context.Background()
+
+			job, _ := repo.Create(ctx, "https://example.com/big")
+			w.processJob(ctx, job)
+
+			got := repo.getJob(job.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if ran := len(tt.proc.processed) > 0; ran != tt.wantRun {
+				t.Errorf("processed = %v, want %v", ran, tt.wantRun)
+			}
+			if !tt.wantRun && !strings.Contains(got.Error, "too large") {
+				t.Errorf("error = %q, want it to say the job is too large", got.Error)
+			}
+		})
+	}
+}
+
+func TestWorker_SizeLimit_Approved(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	svc.SetApproval(repo, nil)
+	registry := processor.NewRegistry()
+	proc := &sizedProcessor{mockProcessor: mockProcessor{name: "sized"}, size: 5000, limit: 1000, policy: domain.OversizeHold}
+	registry.Register(proc)
+	w := New(svc, registry, time.Second, 3)
+	ctx := context.Background()
+
+	job, _ := repo.Create(ctx, "https://example.com/big")
+	w.processJob(ctx, job)
+	if got := repo.getJob(job.ID); got.Status != domain.StatusNeedsApproval || got.Attempts != 0 {
+		t.Fatalf("job = %+v, want awaiting approval with no attempt used", got)
+	}
+
+	if _, err := svc.Approve(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	w.processJob(ctx, repo.getJob(job.ID))
+	if got := repo.getJob(job.ID); got.Status != domain.StatusCompleted {
+		t.Errorf("approved job status = %s, want completed despite its size", got.Status)
+	}
+}
+
+func TestWorker_SizeLimit_NoApproval(t *testing.T) {
+	repo := newMockRepo()
+	svc := domain.NewJobService(repo)
+	registry := processor.NewRegistry()
+	registry.Register(&sizedProcessor{mockProcessor: mockProcessor{name: "sized"}, size: 5000, limit: 1000, policy: domain.OversizeHold})
+	w := New(svc, registry, time.Second, 3)
+	ctx := context.Background()
+
+	job, _ := repo.Create(ctx, "https://example.com/big")
+	w.processJob(ctx, job)
+	if got := repo.getJob(job.ID); got.Status != domain.StatusFailed {
+		t.Errorf("status = %s, want failed when approval isn't available", got.Status)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package worker
+
+import "errors"
+
+// setXattrs reports extended attributes as unsupported, so provenance goes
+// to sidecars.
+func setXattrs(path, prefix string, attrs map[string]string) error {
+	return errors.ErrUnsupported
+}
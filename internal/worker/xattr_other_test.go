@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package worker
+
+import "testing"
+
+// readXattr reports extended attributes as unsupported.
+func readXattr(t *testing.T, path, name string) (string, bool) {
+	return "", false
+}
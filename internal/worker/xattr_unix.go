@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package worker
+
+import "golang.org/x/sys/unix"
+
+// setXattrs sets the extended attribute prefix+name to each value of attrs
+// on the file at path. File systems without them, such as FAT or some
+// network shares, make it fail with an error matching
+// errors.ErrUnsupported.
+func setXattrs(path, prefix string, attrs map[string]string) error {
+	for name, value := range attrs {
+		if err := unix.Setxattr(path, prefix+name, []byte(value), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin
+
+package worker
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// readXattr returns the extended attribute name of the file at path, and
+// whether the file system supports them.
+func readXattr(t *testing.T, path, name string) (string, bool) {
+	t.Helper()
+	buf := make([]byte, 1024)
+	n, err := unix.Getxattr(path, name, buf)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return "", false
+	}
+	if err != nil {
+		t.Fatalf("get %s: %v", name, err)
+	}
+	return string(buf[:n]), true
+}